@@ -158,6 +158,7 @@ var httpRequestMapping = map[string]string{
 	"":       READ,
 	"get":    READ,
 	"put":    UPDATE,
+	"patch":  UPDATE,
 	"post":   CREATE,
 	"delete": DELETE,
 }
@@ -252,7 +253,7 @@ var CallbackUnauthorized = func(w http.ResponseWriter, r *http.Request) {
 	LogAccess("Unauthorized request to ", r.URL.Path,
 		" from ", r.RemoteAddr, " (", r.UserAgent(), " Cookies: ", r.Cookies(), ")")
 
-	if strings.HasPrefix(r.URL.Path, api.APIRoot) {
+	if strings.HasPrefix(r.URL.Path, api.APIRootPath) {
 
 		// No redirect for REST clients
 
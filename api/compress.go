@@ -0,0 +1,146 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+/*
+CompressionMinSize is the minimum size in bytes of a response body before it
+is gzip compressed. A negative value disables compression.
+*/
+var CompressionMinSize = 1024
+
+/*
+acceptsGzip checks if a request accepts gzip compressed responses. Websocket
+handshakes are never compressed as the connection is taken over by the handler.
+*/
+func acceptsGzip(r *http.Request) bool {
+
+	if CompressionMinSize < 0 || r.Header.Get("Upgrade") != "" {
+		return false
+	}
+
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(enc, ";")
+
+		if strings.TrimSpace(parts[0]) == "gzip" {
+			return len(parts) < 2 || strings.TrimSpace(parts[1]) != "q=0"
+		}
+	}
+
+	return false
+}
+
+/*
+gzipResponseWriter compresses a response once its body exceeds
+CompressionMinSize. Smaller responses are sent uncompressed. The status code
+is held back until it is known if the body is compressed so the handler can
+set all headers as usual.
+*/
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status int          // Status code which was set by the handler
+	buf    bytes.Buffer // Body which was written before the threshold was reached
+	gz     *gzip.Writer // Compressing writer once the threshold was reached
+}
+
+/*
+WriteHeader records the status code of the response.
+*/
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+/*
+Write writes (part of) the response body.
+*/
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+
+	w.buf.Write(b)
+
+	if w.buf.Len() >= CompressionMinSize {
+
+		// Threshold is reached - compress the rest of the response
+
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+		}
+
+		w.writeStatus()
+
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+
+		if _, err := w.gz.Write(w.buf.Bytes()); err != nil {
+			return 0, err
+		}
+
+		w.buf.Reset()
+	}
+
+	return len(b), nil
+}
+
+/*
+Flush sends all data which was written so far to the client.
+*/
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+/*
+Close finishes the response. Responses which did not reach the threshold are
+written uncompressed.
+*/
+func (w *gzipResponseWriter) Close() error {
+
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+
+	w.writeStatus()
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+
+	return err
+}
+
+/*
+writeStatus writes the recorded status code to the underlying writer.
+*/
+func (w *gzipResponseWriter) writeStatus() {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
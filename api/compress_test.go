@@ -0,0 +1,131 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+
+	for enc, expected := range map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"deflate, gzip;q=1": true,
+		"deflate, gzip;q=0": false,
+		"deflate":           false,
+	} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", enc)
+
+		if res := acceptsGzip(r); res != expected {
+			t.Error("Unexpected result for", enc, ":", res)
+			return
+		}
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Upgrade", "websocket")
+
+	if acceptsGzip(r) {
+		t.Error("Websocket handshakes should not be compressed")
+		return
+	}
+
+	r.Header.Del("Upgrade")
+
+	oldMinSize := CompressionMinSize
+	CompressionMinSize = -1
+	defer func() {
+		CompressionMinSize = oldMinSize
+	}()
+
+	if acceptsGzip(r) {
+		t.Error("Compression should be disabled")
+		return
+	}
+}
+
+func TestGzipResponseWriter(t *testing.T) {
+
+	oldMinSize := CompressionMinSize
+	CompressionMinSize = 10
+	defer func() {
+		CompressionMinSize = oldMinSize
+	}()
+
+	// Small responses are not compressed
+
+	rec := httptest.NewRecorder()
+	w := &gzipResponseWriter{ResponseWriter: rec}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("[1,2]"))
+	w.Close()
+
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Encoding") != "" ||
+		rec.Body.String() != "[1,2]" {
+		t.Error("Unexpected result:", rec.Code, rec.Header(), rec.Body.String())
+		return
+	}
+
+	// Large responses are compressed - headers are kept
+
+	rec = httptest.NewRecorder()
+	w = &gzipResponseWriter{ResponseWriter: rec}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", "42")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("[1,2,"))
+	w.Write([]byte("3,4,5,6,7"))
+	w.Write([]byte(",8]"))
+	w.Flush()
+	w.Close()
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" ||
+		rec.Header().Get("Content-Type") != "application/json" ||
+		rec.Header().Get("X-Total-Count") != "42" {
+		t.Error("Unexpected result:", rec.Code, rec.Header())
+		return
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := ioutil.ReadAll(gz); err != nil || string(res) != "[1,2,3,4,5,6,7,8]" {
+		t.Error("Unexpected result:", string(res), err)
+		return
+	}
+
+	// Content type is detected if not set
+
+	rec = httptest.NewRecorder()
+	w = &gzipResponseWriter{ResponseWriter: rec}
+
+	w.Write([]byte(strings.Repeat("a", 20)))
+	w.Close()
+
+	if rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Error("Unexpected result:", rec.Header())
+		return
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"devt.de/krotik/common/stringutil"
+)
+
+/*
+CORSAllowedOrigins is a list of origins which are allowed to make cross-origin
+requests. A * allows all origins. CORS handling is disabled if the list is empty.
+*/
+var CORSAllowedOrigins []string
+
+/*
+CORSAllowedMethods is a list of methods which are allowed in cross-origin requests.
+*/
+var CORSAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+/*
+CORSAllowedHeaders is a list of request headers which are allowed in
+cross-origin requests.
+*/
+var CORSAllowedHeaders = []string{"Content-Type", "Authorization"}
+
+/*
+CORSExposedHeaders is a list of response headers which can be read by
+cross-origin clients.
+*/
+var CORSExposedHeaders []string
+
+/*
+CORSAllowCredentials is a flag if cross-origin requests may include
+credentials such as cookies.
+*/
+var CORSAllowCredentials = false
+
+/*
+CORSHandleFunc wraps a given HandleFunc so all registered handlers answer
+CORS preflight requests and add CORS headers to their responses. Preflight
+requests are answered before the wrapped handler is called - they never
+carry credentials and would otherwise be rejected by access control.
+*/
+func CORSHandleFunc(handleFunc func(pattern string, handler func(http.ResponseWriter, *http.Request))) func(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+
+	return func(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+
+		handleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if !handleCORS(w, r) {
+				handler(w, r)
+			}
+		})
+	}
+}
+
+/*
+handleCORS adds CORS headers to a response if the request origin is allowed.
+Returns true if the request was a preflight request which has been answered.
+*/
+func handleCORS(w http.ResponseWriter, r *http.Request) bool {
+
+	origin := r.Header.Get("Origin")
+
+	if len(CORSAllowedOrigins) == 0 || origin == "" {
+		return false
+	}
+
+	allowOrigin := ""
+
+	if stringutil.IndexOf(origin, CORSAllowedOrigins) != -1 {
+		allowOrigin = origin
+
+	} else if stringutil.IndexOf("*", CORSAllowedOrigins) != -1 {
+
+		// Credentialed requests must not be answered with a wildcard
+
+		allowOrigin = "*"
+		if CORSAllowCredentials {
+			allowOrigin = origin
+		}
+	}
+
+	if allowOrigin == "" {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+
+	if allowOrigin != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+
+	if CORSAllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(CORSAllowedMethods, ", "))
+
+		if len(CORSAllowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(CORSAllowedHeaders, ", "))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+		return true
+	}
+
+	if len(CORSExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(CORSExposedHeaders, ", "))
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSHandleFunc(t *testing.T) {
+
+	var registered func(http.ResponseWriter, *http.Request)
+	called := false
+
+	CORSHandleFunc(func(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+		registered = handler
+	})("/foo", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("foo"))
+	})
+
+	oldOrigins := CORSAllowedOrigins
+	oldExposed := CORSExposedHeaders
+	oldCredentials := CORSAllowCredentials
+	defer func() {
+		CORSAllowedOrigins = oldOrigins
+		CORSExposedHeaders = oldExposed
+		CORSAllowCredentials = oldCredentials
+	}()
+
+	sendRequest := func(method string, origin string, preflight bool) *httptest.ResponseRecorder {
+		called = false
+
+		r, _ := http.NewRequest(method, "/foo", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if preflight {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+
+		rec := httptest.NewRecorder()
+		registered(rec, r)
+
+		return rec
+	}
+
+	// CORS is disabled by default
+
+	CORSAllowedOrigins = nil
+
+	if rec := sendRequest("GET", "http://a.com", false); !called ||
+		rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Unexpected result:", called, rec.Header())
+		return
+	}
+
+	// Explicit allow-list
+
+	CORSAllowedOrigins = []string{"http://a.com"}
+	CORSExposedHeaders = []string{"X-Total-Count"}
+
+	if rec := sendRequest("GET", "http://a.com", false); !called || rec.Body.String() != "foo" ||
+		rec.Header().Get("Access-Control-Allow-Origin") != "http://a.com" ||
+		rec.Header().Get("Access-Control-Expose-Headers") != "X-Total-Count" ||
+		rec.Header().Get("Vary") != "Origin" {
+		t.Error("Unexpected result:", called, rec.Header())
+		return
+	}
+
+	if rec := sendRequest("GET", "http://b.com", false); !called ||
+		rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Unexpected result:", called, rec.Header())
+		return
+	}
+
+	// Preflight requests are answered without calling the handler
+
+	if rec := sendRequest("OPTIONS", "http://a.com", true); called || rec.Code != http.StatusNoContent ||
+		rec.Header().Get("Access-Control-Allow-Origin") != "http://a.com" ||
+		rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST, PUT, PATCH, DELETE" ||
+		rec.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("Unexpected result:", called, rec.Code, rec.Header())
+		return
+	}
+
+	// Preflight requests of unknown origins are passed on
+
+	if sendRequest("OPTIONS", "http://b.com", true); !called {
+		t.Error("Handler should have been called")
+		return
+	}
+
+	// Wildcard
+
+	CORSAllowedOrigins = []string{"*"}
+
+	if rec := sendRequest("GET", "http://b.com", false); rec.Header().Get("Access-Control-Allow-Origin") != "*" ||
+		rec.Header().Get("Vary") != "" {
+		t.Error("Unexpected result:", rec.Header())
+		return
+	}
+
+	// Credentialed requests are never answered with a wildcard
+
+	CORSAllowCredentials = true
+
+	if rec := sendRequest("OPTIONS", "http://b.com", true); called ||
+		rec.Header().Get("Access-Control-Allow-Origin") != "http://b.com" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		rec.Header().Get("Vary") != "Origin" {
+		t.Error("Unexpected result:", rec.Header())
+		return
+	}
+
+	// Requests without origin are not CORS requests
+
+	if rec := sendRequest("GET", "", false); !called ||
+		rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("Unexpected result:", rec.Header())
+		return
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+RateLimitRead is the RateLimiter which limits read (GET) requests of clients.
+Read requests are not limited if no limiter is set.
+*/
+var RateLimitRead *RateLimiter
+
+/*
+RateLimitWrite is the RateLimiter which limits all other requests of clients.
+Write requests are not limited if no limiter is set.
+*/
+var RateLimitWrite *RateLimiter
+
+/*
+RateLimitClientHeader is a request header which identifies the client of a
+request (e.g. X-Forwarded-For behind a reverse proxy). The first value of the
+header is used. Clients are identified by their IP address if no header is
+set or if a request does not contain it.
+*/
+var RateLimitClientHeader = ""
+
+/*
+RateLimiter limits the number of requests of clients with a token bucket for
+each client. Every request takes a token from the bucket of its client and
+buckets are refilled at a fixed rate up to a maximum size. The number of
+tracked clients is bounded - idle clients are evicted once the limit is
+reached. A RateLimiter can be used by several goroutines.
+*/
+type RateLimiter struct {
+	rate       float64                 // Tokens which are added to a bucket per second
+	burst      float64                 // Maximum number of tokens in a bucket
+	maxClients int                     // Maximum number of tracked clients
+	buckets    map[string]*tokenBucket // Token buckets of tracked clients
+	mutex      *sync.Mutex             // Mutex to protect the buckets
+	now        func() time.Time        // Function which returns the current time
+}
+
+/*
+tokenBucket holds the tokens of a single client.
+*/
+type tokenBucket struct {
+	tokens float64   // Tokens in the bucket at the time of the last update
+	last   time.Time // Time of the last update
+}
+
+/*
+NewRateLimiter creates a new RateLimiter which allows rate requests per
+second for each client. Clients can make up to burst requests at once.
+At most maxClients clients are tracked (at least one).
+*/
+func NewRateLimiter(rate float64, burst int, maxClients int) *RateLimiter {
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	if maxClients < 1 {
+		maxClients = 1
+	}
+
+	return &RateLimiter{rate, float64(burst), maxClients,
+		make(map[string]*tokenBucket), &sync.Mutex{}, time.Now}
+}
+
+/*
+Allow takes a token from the bucket of a given client. Returns false and the
+time until the next token is available if the bucket is empty.
+*/
+func (rl *RateLimiter) Allow(client string) (bool, time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+
+	bucket, ok := rl.buckets[client]
+
+	if !ok {
+
+		if len(rl.buckets) >= rl.maxClients {
+			rl.evict(now)
+		}
+
+		bucket = &tokenBucket{rl.burst, now}
+		rl.buckets[client] = bucket
+
+	} else {
+
+		bucket.tokens = rl.tokens(bucket, now)
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second))
+}
+
+/*
+Clients returns the number of tracked clients.
+*/
+func (rl *RateLimiter) Clients() int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	return len(rl.buckets)
+}
+
+/*
+tokens returns the number of tokens in a given bucket at a given time.
+*/
+func (rl *RateLimiter) tokens(bucket *tokenBucket, now time.Time) float64 {
+	return math.Min(rl.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rl.rate)
+}
+
+/*
+evict removes all clients whose buckets are full again - a full bucket
+behaves like a bucket of an unknown client. The client which was seen the
+longest time ago is removed if all clients are still active.
+*/
+func (rl *RateLimiter) evict(now time.Time) {
+	var oldest string
+	var oldestBucket *tokenBucket
+
+	for client, bucket := range rl.buckets {
+
+		if rl.tokens(bucket, now) >= rl.burst {
+			delete(rl.buckets, client)
+		} else if oldestBucket == nil || bucket.last.Before(oldestBucket.last) {
+			oldest = client
+			oldestBucket = bucket
+		}
+	}
+
+	if len(rl.buckets) >= rl.maxClients {
+		delete(rl.buckets, oldest)
+	}
+}
+
+/*
+checkRateLimit checks if the client of a request has exceeded its rate limit.
+Writes an error response with a Retry-After header and returns false if the
+request should be rejected.
+*/
+func checkRateLimit(w http.ResponseWriter, r *http.Request) bool {
+
+	limiter := RateLimitWrite
+
+	if r.Method == "GET" {
+		limiter = RateLimitRead
+	}
+
+	if limiter == nil {
+		return true
+	}
+
+	if ok, wait := limiter.Allow(rateLimitClient(r)); !ok {
+		retryAfter := int(math.Ceil(wait.Seconds()))
+
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+
+		return false
+	}
+
+	return true
+}
+
+/*
+rateLimitClient returns the client of a request for rate limiting.
+*/
+func rateLimitClient(r *http.Request) string {
+
+	if RateLimitClientHeader != "" {
+		if val := r.Header.Get(RateLimitClientHeader); val != "" {
+			return strings.TrimSpace(strings.Split(val, ",")[0])
+		}
+	}
+
+	return remoteHost(r)
+}
+
+/*
+remoteHost returns the IP address of the client of a request.
+*/
+func remoteHost(r *http.Request) string {
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
@@ -0,0 +1,225 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	now := time.Unix(1000, 0)
+
+	rl := NewRateLimiter(2, 3, 2)
+	rl.now = func() time.Time {
+		return now
+	}
+
+	// A client can make burst requests at once
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Error("Request should be allowed:", i)
+			return
+		}
+	}
+
+	if ok, wait := rl.Allow("a"); ok || wait != 500*time.Millisecond {
+		t.Error("Unexpected result:", ok, wait)
+		return
+	}
+
+	// Other clients are not affected
+
+	if ok, _ := rl.Allow("b"); !ok {
+		t.Error("Request should be allowed")
+		return
+	}
+
+	// Buckets are refilled over time
+
+	now = now.Add(time.Second)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Error("Request should be allowed:", i)
+			return
+		}
+	}
+
+	if ok, _ := rl.Allow("a"); ok {
+		t.Error("Request should not be allowed")
+		return
+	}
+
+	// Buckets never hold more than burst tokens
+
+	now = now.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		rl.Allow("a")
+	}
+
+	if ok, _ := rl.Allow("a"); ok {
+		t.Error("Request should not be allowed")
+		return
+	}
+
+	// Idle clients are evicted first - client b is idle
+
+	if ok, _ := rl.Allow("c"); !ok || rl.Clients() != 2 {
+		t.Error("Unexpected result:", ok, rl.Clients())
+		return
+	}
+
+	if _, ok := rl.buckets["b"]; ok {
+		t.Error("Client b should have been evicted")
+		return
+	}
+
+	// The client which was seen the longest time ago is evicted if all
+	// clients are active
+
+	now = now.Add(time.Millisecond)
+	rl.Allow("c")
+
+	if ok, _ := rl.Allow("d"); !ok || rl.Clients() != 2 {
+		t.Error("Unexpected result:", ok, rl.Clients())
+		return
+	}
+
+	if _, ok := rl.buckets["a"]; ok {
+		t.Error("Client a should have been evicted")
+		return
+	}
+
+	// Limiters can be used concurrently
+
+	rl = NewRateLimiter(1, 50, 10)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+
+	allowed := 0
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if ok, _ := rl.Allow("a"); ok {
+				mutex.Lock()
+				allowed++
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if allowed < 50 || allowed > 51 {
+		t.Error("Unexpected number of allowed requests:", allowed)
+		return
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+
+	oldRead := RateLimitRead
+	oldWrite := RateLimitWrite
+	oldHeader := RateLimitClientHeader
+	defer func() {
+		RateLimitRead = oldRead
+		RateLimitWrite = oldWrite
+		RateLimitClientHeader = oldHeader
+	}()
+
+	sendRequest := func(method string, remoteAddr string, client string) (int, string, string) {
+		r, _ := http.NewRequest(method, "/foo", nil)
+		r.RemoteAddr = remoteAddr
+		if client != "" {
+			r.Header.Set("X-Forwarded-For", client)
+		}
+
+		rec := httptest.NewRecorder()
+
+		if checkRateLimit(rec, r) {
+			rec.Write([]byte("ok"))
+		}
+
+		return rec.Code, rec.Header().Get("Retry-After"), strings.TrimSpace(rec.Body.String())
+	}
+
+	// Without limiters all requests are allowed
+
+	RateLimitRead = nil
+	RateLimitWrite = nil
+
+	for i := 0; i < 10; i++ {
+		if code, _, res := sendRequest("POST", "127.0.0.1:1234", ""); code != 200 || res != "ok" {
+			t.Error("Unexpected result:", code, res)
+			return
+		}
+	}
+
+	// Reads and writes are limited separately
+
+	RateLimitRead = NewRateLimiter(1, 2, 10)
+	RateLimitWrite = NewRateLimiter(0.1, 1, 10)
+
+	if code, _, res := sendRequest("POST", "127.0.0.1:1234", ""); code != 200 || res != "ok" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	if code, retry, res := sendRequest("DELETE", "127.0.0.1:1235", ""); code != 429 ||
+		retry != "10" || res != "Too many requests" {
+		t.Error("Unexpected result:", code, retry, res)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		if code, _, res := sendRequest("GET", "127.0.0.1:1234", ""); code != 200 || res != "ok" {
+			t.Error("Unexpected result:", code, res)
+			return
+		}
+	}
+
+	if code, retry, _ := sendRequest("GET", "127.0.0.1:1234", ""); code != 429 || retry != "1" {
+		t.Error("Unexpected result:", code, retry)
+		return
+	}
+
+	// Clients can be identified by a header
+
+	RateLimitClientHeader = "X-Forwarded-For"
+
+	if code, _, _ := sendRequest("GET", "127.0.0.1:1234", "10.0.0.1, 127.0.0.1"); code != 200 {
+		t.Error("Unexpected result:", code)
+		return
+	}
+
+	if code, _, _ := sendRequest("GET", "127.0.0.1:1234", ""); code != 429 {
+		t.Error("Unexpected result:", code)
+		return
+	}
+
+	if _, ok := RateLimitRead.buckets["10.0.0.1"]; !ok {
+		t.Error("Client should be identified by the header:", RateLimitRead.buckets)
+		return
+	}
+}
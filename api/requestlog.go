@@ -0,0 +1,297 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+RequestLogger is called with a log entry after each REST API request has been
+handled. Request logging is disabled if no logger is set.
+*/
+var RequestLogger func(entry *RequestLogEntry)
+
+/*
+RequestLogBodies is a flag if the bodies of requests should be included in
+request log entries. Request bodies may contain sensitive data.
+*/
+var RequestLogBodies = false
+
+/*
+RequestLogMaxBodySize is the maximum number of bytes of a request body which
+are included in a request log entry.
+*/
+var RequestLogMaxBodySize = 4096
+
+/*
+RequestLogEntry contains information about a single REST API request.
+*/
+type RequestLogEntry struct {
+	Time     time.Time              // Time when the request was received
+	Method   string                 // Request method
+	Path     string                 // Request path
+	Client   string                 // Address of the client
+	Status   int                    // Status code of the response
+	Bytes    int                    // Number of bytes in the response body
+	Duration time.Duration          // Time it took to handle the request
+	Body     string                 // Request body (only if RequestLogBodies is set)
+	Fields   map[string]interface{} // Additional fields which were set by the endpoint
+}
+
+/*
+String returns the log entry as a line of key=value pairs.
+*/
+func (e *RequestLogEntry) String() string {
+	var buf bytes.Buffer
+
+	writeValue := func(key string, val interface{}) {
+		s := fmt.Sprint(val)
+
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			s = strconv.Quote(s)
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString(" ")
+		}
+
+		buf.WriteString(key)
+		buf.WriteString("=")
+		buf.WriteString(s)
+	}
+
+	writeValue("time", e.Time.Format(time.RFC3339))
+	writeValue("method", e.Method)
+	writeValue("path", e.Path)
+	writeValue("client", e.Client)
+	writeValue("status", e.Status)
+	writeValue("bytes", e.Bytes)
+	writeValue("duration", e.Duration)
+
+	for _, key := range e.fieldKeys() {
+		writeValue(key, e.Fields[key])
+	}
+
+	if RequestLogBodies {
+		writeValue("body", e.Body)
+	}
+
+	return buf.String()
+}
+
+/*
+MarshalJSON returns the log entry as a JSON object. The duration is given in
+milliseconds.
+*/
+func (e *RequestLogEntry) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, len(e.Fields)+8)
+
+	for key, val := range e.Fields {
+		data[key] = val
+	}
+
+	data["time"] = e.Time.Format(time.RFC3339)
+	data["method"] = e.Method
+	data["path"] = e.Path
+	data["client"] = e.Client
+	data["status"] = e.Status
+	data["bytes"] = e.Bytes
+	data["duration_ms"] = float64(e.Duration) / float64(time.Millisecond)
+
+	if RequestLogBodies {
+		data["body"] = e.Body
+	}
+
+	return json.Marshal(data)
+}
+
+/*
+fieldKeys returns the sorted keys of all additional fields.
+*/
+func (e *RequestLogEntry) fieldKeys() []string {
+	keys := make([]string, 0, len(e.Fields))
+
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+/*
+NewRequestLogWriter returns a RequestLogger which writes each log entry as a
+single line to a given writer. Entries are either written as key=value pairs
+or as JSON objects.
+*/
+func NewRequestLogWriter(out io.Writer, asJSON bool) func(entry *RequestLogEntry) {
+	mutex := &sync.Mutex{}
+
+	return func(entry *RequestLogEntry) {
+		var line string
+
+		if asJSON {
+			res, _ := json.Marshal(entry)
+			line = string(res)
+		} else {
+			line = entry.String()
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		fmt.Fprintln(out, line)
+	}
+}
+
+/*
+requestLogKey is the context key of the log entry of a request.
+*/
+type requestLogKey struct{}
+
+/*
+SetRequestLogField sets an additional field in the log entry of a given
+request. Nothing happens if request logging is disabled.
+*/
+func SetRequestLogField(r *http.Request, key string, val interface{}) {
+	if entry, ok := r.Context().Value(requestLogKey{}).(*RequestLogEntry); ok {
+		entry.Fields[key] = val
+	}
+}
+
+/*
+newRequestLog starts the log entry of a given request. Returns a response
+writer and a request which must be used to handle the request.
+*/
+func newRequestLog(w http.ResponseWriter, r *http.Request) (*requestLogResponseWriter, *http.Request) {
+
+	entry := &RequestLogEntry{
+		Time:   time.Now(),
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Client: remoteHost(r),
+		Fields: make(map[string]interface{}),
+	}
+
+	if RequestLogBodies && r.Body != nil {
+		body, _ := ioutil.ReadAll(io.LimitReader(r.Body, int64(RequestLogMaxBodySize)))
+		entry.Body = string(body)
+
+		// Put the read part of the body back in front of the rest
+
+		r.Body = &requestLogBody{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), requestLogKey{}, entry))
+
+	return &requestLogResponseWriter{ResponseWriter: w, entry: entry}, r
+}
+
+/*
+requestLogBody is a request body which was partially read for a log entry.
+*/
+type requestLogBody struct {
+	io.Reader
+	body io.Closer // Original request body
+}
+
+/*
+Close closes the original request body.
+*/
+func (b *requestLogBody) Close() error {
+	return b.body.Close()
+}
+
+/*
+requestLogResponseWriter records the status code and the size of a response
+for the log entry of a request.
+*/
+type requestLogResponseWriter struct {
+	http.ResponseWriter
+	entry *RequestLogEntry // Log entry of the request
+}
+
+/*
+WriteHeader writes the status code of the response.
+*/
+func (w *requestLogResponseWriter) WriteHeader(status int) {
+	if w.entry.Status == 0 {
+		w.entry.Status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+/*
+Write writes (part of) the response body.
+*/
+func (w *requestLogResponseWriter) Write(b []byte) (int, error) {
+	if w.entry.Status == 0 {
+		w.entry.Status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.entry.Bytes += n
+
+	return n, err
+}
+
+/*
+Flush sends all data which was written so far to the client.
+*/
+func (w *requestLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+/*
+Hijack lets the handler take over the connection (e.g. for websockets).
+*/
+func (w *requestLogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.entry.Status = http.StatusSwitchingProtocols
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("Connection cannot be hijacked")
+}
+
+/*
+Close finishes the log entry and passes it to the RequestLogger.
+*/
+func (w *requestLogResponseWriter) Close() {
+	w.entry.Duration = time.Since(w.entry.Time)
+
+	if w.entry.Status == 0 {
+		w.entry.Status = http.StatusOK
+	}
+
+	if RequestLogger != nil {
+		RequestLogger(w.entry)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestLogEntry(t *testing.T) {
+
+	oldBodies := RequestLogBodies
+	defer func() {
+		RequestLogBodies = oldBodies
+	}()
+
+	entry := &RequestLogEntry{
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Method:   "GET",
+		Path:     "/db/v1/query/main",
+		Client:   "127.0.0.1",
+		Status:   200,
+		Bytes:    42,
+		Duration: 1500 * time.Microsecond,
+		Body:     `{"secret" : 1}`,
+		Fields: map[string]interface{}{
+			"rows":  5,
+			"query": "get Song",
+		},
+	}
+
+	RequestLogBodies = false
+
+	if res := entry.String(); res != `time=2020-01-02T03:04:05Z method=GET path=/db/v1/query/main `+
+		`client=127.0.0.1 status=200 bytes=42 duration=1.5ms query="get Song" rows=5` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	NewRequestLogWriter(&buf, true)(entry)
+
+	if res := buf.String(); res != `{"bytes":42,"client":"127.0.0.1","duration_ms":1.5,"method":"GET",`+
+		`"path":"/db/v1/query/main","query":"get Song","rows":5,"status":200,"time":"2020-01-02T03:04:05Z"}`+"\n" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Bodies are only included if requested
+
+	RequestLogBodies = true
+
+	if res := entry.String(); !strings.HasSuffix(res, ` rows=5 body="{\"secret\" : 1}"`) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	buf.Reset()
+
+	NewRequestLogWriter(&buf, false)(entry)
+
+	if res := buf.String(); res != entry.String()+"\n" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+
+	oldLogger := RequestLogger
+	oldBodies := RequestLogBodies
+	oldMaxBodySize := RequestLogMaxBodySize
+	defer func() {
+		RequestLogger = oldLogger
+		RequestLogBodies = oldBodies
+		RequestLogMaxBodySize = oldMaxBodySize
+	}()
+
+	var entries []*RequestLogEntry
+
+	RequestLogger = func(entry *RequestLogEntry) {
+		entries = append(entries, entry)
+	}
+
+	handle := func(method string, body string, handler func(w http.ResponseWriter, r *http.Request)) {
+		r, _ := http.NewRequest(method, "/foo/bar?q=1", strings.NewReader(body))
+		r.RemoteAddr = "10.0.0.1:1234"
+
+		w, r := newRequestLog(httptest.NewRecorder(), r)
+		handler(w, r)
+		w.Close()
+	}
+
+	// Status, size and fields of a response are recorded
+
+	handle("GET", "", func(w http.ResponseWriter, r *http.Request) {
+		SetRequestLogField(r, "rows", 2)
+		w.Write([]byte("[1,"))
+		w.Write([]byte("2]"))
+	})
+
+	if e := entries[0]; e.Method != "GET" || e.Path != "/foo/bar" || e.Client != "10.0.0.1" ||
+		e.Status != 200 || e.Bytes != 5 || e.Duration <= 0 || e.Fields["rows"] != 2 || e.Body != "" {
+		t.Error("Unexpected result:", e)
+		return
+	}
+
+	handle("DELETE", "", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not found", http.StatusNotFound)
+	})
+
+	if e := entries[1]; e.Status != 404 || e.Bytes != 10 {
+		t.Error("Unexpected result:", e)
+		return
+	}
+
+	// Bodies are recorded up to a maximum size - the handler can still read
+	// the whole body
+
+	RequestLogBodies = true
+	RequestLogMaxBodySize = 5
+
+	var handlerBody string
+
+	handle("POST", "abcdefgh", func(w http.ResponseWriter, r *http.Request) {
+		res, _ := ioutil.ReadAll(r.Body)
+		handlerBody = string(res)
+		r.Body.Close()
+	})
+
+	if e := entries[2]; e.Body != "abcde" || e.Status != 200 || handlerBody != "abcdefgh" {
+		t.Error("Unexpected result:", e, handlerBody)
+		return
+	}
+
+	// Fields cannot be set without a log entry
+
+	r, _ := http.NewRequest("GET", "/foo", nil)
+	SetRequestLogField(r, "rows", 1)
+}
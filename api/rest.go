@@ -30,6 +30,13 @@ APIRoot is the root directory for the REST API
 */
 const APIRoot = "/db"
 
+/*
+APIRootPath is the path under which all REST API endpoints are registered.
+Endpoint URLs are defined relative to APIRoot which is replaced by this path
+on registration.
+*/
+var APIRootPath = APIRoot
+
 /*
 APISchemes is a list of supported protocol schemes
 */
@@ -73,6 +80,11 @@ type RestEndpointHandler interface {
 	*/
 	HandlePUT(w http.ResponseWriter, r *http.Request, resources []string)
 
+	/*
+		HandlePATCH handles a PATCH request.
+	*/
+	HandlePATCH(w http.ResponseWriter, r *http.Request, resources []string)
+
 	/*
 		HandleDELETE handles a DELETE request.
 	*/
@@ -116,7 +128,17 @@ HandleFunc to use for registering handlers
 
 Should be of type: func(pattern string, handler func(http.ResponseWriter, *http.Request))
 */
-var HandleFunc = http.HandleFunc
+var HandleFunc = CORSHandleFunc(http.HandleFunc)
+
+/*
+EndpointPath returns the path under which a given endpoint URL is registered.
+*/
+func EndpointPath(url string) string {
+	if strings.HasPrefix(url, APIRoot) {
+		return APIRootPath + url[len(APIRoot):]
+	}
+	return url
+}
 
 /*
 RegisterRestEndpoints registers all given REST endpoint handlers.
@@ -126,17 +148,42 @@ func RegisterRestEndpoints(endpointInsts map[string]RestEndpointInst) {
 	for url, endpointInst := range endpointInsts {
 		registered[url] = endpointInst
 
-		HandleFunc(url, func() func(w http.ResponseWriter, r *http.Request) {
+		HandleFunc(EndpointPath(url), func() func(w http.ResponseWriter, r *http.Request) {
 
-			var handlerURL = url
+			var handlerURL = EndpointPath(url)
 			var handlerInst = endpointInst
 
 			return func(w http.ResponseWriter, r *http.Request) {
 
+				// Log the request once it was handled
+
+				if RequestLogger != nil {
+					lw, lr := newRequestLog(w, r)
+					defer lw.Close()
+
+					w, r = lw, lr
+				}
+
+				// Reject the request if its client made too many requests
+
+				if !checkRateLimit(w, r) {
+					return
+				}
+
 				// Create a new handler instance
 
 				handler := handlerInst()
 
+				// Compress the response if the client accepts it
+
+				if acceptsGzip(r) {
+					gzw := &gzipResponseWriter{ResponseWriter: w}
+					defer gzw.Close()
+
+					w.Header().Add("Vary", "Accept-Encoding")
+					w = gzw
+				}
+
 				// Handle request in appropriate method
 
 				res := strings.TrimSpace(r.URL.Path[len(handlerURL):])
@@ -161,6 +208,9 @@ func RegisterRestEndpoints(endpointInsts map[string]RestEndpointInst) {
 				case "PUT":
 					handler.HandlePUT(w, r, resources)
 
+				case "PATCH":
+					handler.HandlePATCH(w, r, resources)
+
 				case "DELETE":
 					handler.HandleDELETE(w, r, resources)
 
@@ -199,6 +249,13 @@ func (de *DefaultEndpointHandler) HandlePUT(w http.ResponseWriter, r *http.Reque
 	http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 }
 
+/*
+HandlePATCH is a method stub returning an error.
+*/
+func (de *DefaultEndpointHandler) HandlePATCH(w http.ResponseWriter, r *http.Request, resources []string) {
+	http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+}
+
 /*
 HandleDELETE is a method stub returning an error.
 */
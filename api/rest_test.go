@@ -107,6 +107,11 @@ func TestEndpointHandling(t *testing.T) {
 		return
 	}
 
+	if res := sendTestRequest(queryURL, "PATCH", nil); res != "Method Not Allowed" {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
 	if res := sendTestRequest(queryURL, "DELETE", nil); res != "Method Not Allowed" {
 		t.Error("Unexpected response:", res)
 		return
@@ -201,6 +206,35 @@ func TestEndpointHandling(t *testing.T) {
 		t.Error("Unexpected response:", res)
 		return
 	}
+
+	// Test endpoints under a different API root path
+
+	APIRootPath = "/mydb"
+	defer func() {
+		APIRootPath = APIRoot
+	}()
+
+	if res := EndpointPath(EndpointAbout); res != "/mydb/about/" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := EndpointPath("/foo/"); res != "/foo/" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	RegisterRestEndpoints(GeneralEndpointMap)
+
+	if res := sendTestRequest(queryURL+"/mydb/about", "GET", nil); !strings.Contains(res, `"product": "EliasDB"`) {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	if res := sendTestRequest(queryURL+"/mydb/swagger.json", "GET", nil); !strings.Contains(res, `"basePath": "/mydb"`) {
+		t.Error("Unexpected response:", res)
+		return
+	}
 }
 
 /*
@@ -103,7 +103,7 @@ func (a *swaggerEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, reso
 		"swagger":     "2.0",
 		"host":        APIHost,
 		"schemes":     APISchemes,
-		"basePath":    APIRoot,
+		"basePath":    APIRootPath,
 		"produces":    []string{"application/json"},
 		"paths":       map[string]interface{}{},
 		"definitions": map[string]interface{}{},
@@ -0,0 +1,169 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+/*
+TokenStore models a store of valid bearer tokens.
+*/
+type TokenStore interface {
+
+	/*
+		CheckToken checks if a given bearer token is valid.
+	*/
+	CheckToken(token string) bool
+}
+
+/*
+AuthTokenStore is the TokenStore which is used to check bearer tokens of
+requests. Token authentication is disabled if no store is set.
+*/
+var AuthTokenStore TokenStore
+
+/*
+AuthTokenOpenRead is a flag if GET requests can be made without a bearer token.
+*/
+var AuthTokenOpenRead = true
+
+/*
+StaticTokenStore is a TokenStore with a fixed set of tokens.
+*/
+type StaticTokenStore struct {
+	tokens []string
+}
+
+/*
+NewStaticTokenStore creates a new TokenStore from a list of tokens.
+*/
+func NewStaticTokenStore(tokens []string) *StaticTokenStore {
+	return &StaticTokenStore{tokens}
+}
+
+/*
+CheckToken checks if a given bearer token is valid.
+*/
+func (s *StaticTokenStore) CheckToken(token string) bool {
+	valid := false
+
+	// Compare against all tokens in constant time
+
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+/*
+TokenAuthEndpointMap wraps all endpoint handlers of a given map so that
+requests need a valid bearer token in their Authorization header. GET requests
+are only checked if AuthTokenOpenRead is not set.
+*/
+func TokenAuthEndpointMap(endpointInsts map[string]RestEndpointInst) map[string]RestEndpointInst {
+	ret := make(map[string]RestEndpointInst)
+
+	for url, endpointInst := range endpointInsts {
+		ret[url] = func(endpointInst RestEndpointInst) RestEndpointInst {
+			return func() RestEndpointHandler {
+				return &tokenAuthHandler{endpointInst()}
+			}
+		}(endpointInst)
+	}
+
+	return ret
+}
+
+/*
+tokenAuthHandler checks bearer tokens before passing requests on to an
+endpoint handler.
+*/
+type tokenAuthHandler struct {
+	RestEndpointHandler
+}
+
+/*
+HandleGET handles a GET request.
+*/
+func (h *tokenAuthHandler) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+	if AuthTokenOpenRead || checkBearerToken(w, r) {
+		h.RestEndpointHandler.HandleGET(w, r, resources)
+	}
+}
+
+/*
+HandlePOST handles a POST request.
+*/
+func (h *tokenAuthHandler) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
+	if checkBearerToken(w, r) {
+		h.RestEndpointHandler.HandlePOST(w, r, resources)
+	}
+}
+
+/*
+HandlePUT handles a PUT request.
+*/
+func (h *tokenAuthHandler) HandlePUT(w http.ResponseWriter, r *http.Request, resources []string) {
+	if checkBearerToken(w, r) {
+		h.RestEndpointHandler.HandlePUT(w, r, resources)
+	}
+}
+
+/*
+HandlePATCH handles a PATCH request.
+*/
+func (h *tokenAuthHandler) HandlePATCH(w http.ResponseWriter, r *http.Request, resources []string) {
+	if checkBearerToken(w, r) {
+		h.RestEndpointHandler.HandlePATCH(w, r, resources)
+	}
+}
+
+/*
+HandleDELETE handles a DELETE request.
+*/
+func (h *tokenAuthHandler) HandleDELETE(w http.ResponseWriter, r *http.Request, resources []string) {
+	if checkBearerToken(w, r) {
+		h.RestEndpointHandler.HandleDELETE(w, r, resources)
+	}
+}
+
+/*
+checkBearerToken checks the bearer token of a request. Writes an error
+response and returns false if the token is missing or invalid.
+*/
+func checkBearerToken(w http.ResponseWriter, r *http.Request) bool {
+
+	if AuthTokenStore == nil {
+		return true
+	}
+
+	auth := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(auth, "Bearer ") {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Bearer token required", http.StatusUnauthorized)
+		return false
+	}
+
+	if !AuthTokenStore.CheckToken(strings.TrimSpace(auth[len("Bearer "):])) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Invalid bearer token", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,135 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type tokenTestEndpoint struct {
+	*DefaultEndpointHandler
+}
+
+func (te *tokenTestEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+	w.Write([]byte("get"))
+}
+
+func (te *tokenTestEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
+	w.Write([]byte("post"))
+}
+
+func (te *tokenTestEndpoint) SwaggerDefs(s map[string]interface{}) {
+}
+
+func TestTokenAuth(t *testing.T) {
+
+	endpoints := TokenAuthEndpointMap(map[string]RestEndpointInst{
+		"/foo": func() RestEndpointHandler {
+			return &tokenTestEndpoint{}
+		},
+	})
+
+	oldStore := AuthTokenStore
+	oldOpenRead := AuthTokenOpenRead
+	defer func() {
+		AuthTokenStore = oldStore
+		AuthTokenOpenRead = oldOpenRead
+	}()
+
+	sendRequest := func(method string, auth string) (int, string, string) {
+		r, _ := http.NewRequest(method, "/foo", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+
+		rec := httptest.NewRecorder()
+		handler := endpoints["/foo"]()
+
+		switch method {
+		case "GET":
+			handler.HandleGET(rec, r, nil)
+		case "POST":
+			handler.HandlePOST(rec, r, nil)
+		case "PUT":
+			handler.HandlePUT(rec, r, nil)
+		case "PATCH":
+			handler.HandlePATCH(rec, r, nil)
+		case "DELETE":
+			handler.HandleDELETE(rec, r, nil)
+		}
+
+		return rec.Code, rec.Header().Get("WWW-Authenticate"), strings.TrimSpace(rec.Body.String())
+	}
+
+	// Without a token store all requests are allowed
+
+	AuthTokenStore = nil
+
+	if code, _, res := sendRequest("POST", ""); code != 200 || res != "post" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	AuthTokenStore = NewStaticTokenStore([]string{"secret1", "secret2"})
+	AuthTokenOpenRead = true
+
+	if code, _, res := sendRequest("GET", ""); code != 200 || res != "get" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		if code, wa, res := sendRequest(method, ""); code != 401 || wa != "Bearer" || res != "Bearer token required" {
+			t.Error("Unexpected result:", method, code, wa, res)
+			return
+		}
+	}
+
+	if code, wa, res := sendRequest("POST", "Bearer foo"); code != 401 ||
+		wa != `Bearer error="invalid_token"` || res != "Invalid bearer token" {
+		t.Error("Unexpected result:", code, wa, res)
+		return
+	}
+
+	if code, _, res := sendRequest("POST", "Basic secret1"); code != 401 || res != "Bearer token required" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	if code, _, res := sendRequest("POST", "Bearer secret2"); code != 200 || res != "post" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	// Token is passed on - the wrapped handler decides about the method
+
+	if code, _, res := sendRequest("PUT", "Bearer secret1"); code != 405 || res != "Method Not Allowed" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	// Reads can be protected as well
+
+	AuthTokenOpenRead = false
+
+	if code, _, res := sendRequest("GET", ""); code != 401 || res != "Bearer token required" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+
+	if code, _, res := sendRequest("GET", "Bearer secret1"); code != 200 || res != "get" {
+		t.Error("Unexpected result:", code, res)
+		return
+	}
+}
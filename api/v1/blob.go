@@ -642,7 +642,7 @@ func (be *blobEndpoint) SwaggerDefs(s map[string]interface{}) {
 						"properties": map[string]interface{}{
 							"id": map[string]interface{}{
 								"description": "The data ID which can be used to lookup the data.",
-								"type":        "number",
+								"type":        "integer",
 							},
 						},
 					},
@@ -52,6 +52,17 @@ func (e *eqlEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resourc
 		return
 	}
 
+	// Handle parse requests which only validate a query and format
+	// requests which produce a query from an AST
+
+	if len(resources) > 0 && resources[0] == "parse" {
+		e.handleParse(w, data)
+		return
+	} else if len(resources) > 0 && resources[0] == "format" {
+		e.handleFormat(w, data)
+		return
+	}
+
 	// Handle query and ast requests
 
 	query, ok1 := data["query"]
@@ -111,6 +122,83 @@ func (e *eqlEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resourc
 	http.Error(w, "Need either a query or an ast parameter", http.StatusBadRequest)
 }
 
+/*
+handleParse parses a given query and returns its AST without executing the
+query. A parse error is returned as a structured error object.
+*/
+func (e *eqlEndpoint) handleParse(w http.ResponseWriter, data map[string]interface{}) {
+
+	query, ok := data["query"]
+	if !ok {
+		http.Error(w, "Need a query parameter", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	ast, err := eql.ParseQuery("request", fmt.Sprint(query))
+
+	if err != nil {
+		errObj := map[string]interface{}{
+			"message": err.Error(),
+		}
+
+		if perr, ok := err.(*parser.Error); ok {
+			errObj["type"] = perr.Type.Error()
+			errObj["detail"] = perr.Detail
+			errObj["token"] = perr.Token
+			errObj["line"] = perr.Line
+			errObj["pos"] = perr.Pos
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": errObj,
+		})
+
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ast": ast.Plain(),
+	})
+}
+
+/*
+handleFormat produces a canonical query string from a given plain AST.
+*/
+func (e *eqlEndpoint) handleFormat(w http.ResponseWriter, data map[string]interface{}) {
+
+	ast, ok := data["ast"]
+	if !ok {
+		http.Error(w, "Need an ast parameter", http.StatusBadRequest)
+		return
+	}
+
+	astmap, ok := ast.(map[string]interface{})
+	if !ok {
+		http.Error(w, "Plain AST object expected as 'ast' value", http.StatusBadRequest)
+		return
+	}
+
+	astnode, err := parser.ASTFromPlain(astmap)
+
+	if err == nil {
+		var ppres string
+
+		if ppres, err = parser.PrettyPrint(astnode); err == nil {
+			w.Header().Set("content-type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"query": ppres,
+			})
+
+			return
+		}
+	}
+
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
 /*
 SwaggerDefs is used to describe the endpoint in swagger.
 */
@@ -175,4 +263,117 @@ func (e *eqlEndpoint) SwaggerDefs(s map[string]interface{}) {
 		},
 	}
 
+	s["paths"].(map[string]interface{})["/v1/eql/format"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary":     "EQL formatting endpoint.",
+			"description": "The format endpoint should be used to produce a canonical EQL query from a given Abstract Syntax Tree.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "data",
+					"in":          "body",
+					"description": "AST which should be formatted.",
+					"required":    true,
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"ast": map[string]interface{}{
+								"description": "Plain AST which should be formatted.",
+								"type":        "object",
+							},
+						},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The operation was successful.",
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"query": map[string]interface{}{
+								"description": "The formatted query.",
+								"type":        "string",
+							},
+						},
+					},
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+
+	s["paths"].(map[string]interface{})["/v1/eql/parse"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary":     "EQL validation endpoint.",
+			"description": "The parse endpoint should be used to validate a given EQL query and inspect its Abstract Syntax Tree. The query is not executed.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "data",
+					"in":          "body",
+					"description": "Query which should be parsed.",
+					"required":    true,
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"query": map[string]interface{}{
+								"description": "Query which should be parsed.",
+								"type":        "string",
+							},
+						},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The query could be parsed.",
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"ast": map[string]interface{}{
+								"description": "The resulting AST.",
+								"type":        "object",
+							},
+						},
+					},
+				},
+				"400": map[string]interface{}{
+					"description": "The query could not be parsed.",
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"error": map[string]interface{}{
+								"description": "Parse error with the type of the error, details, the value of the token where the error occurred and its line and position.",
+								"type":        "object",
+							},
+						},
+					},
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
 }
@@ -173,6 +173,167 @@ func TestEql(t *testing.T) {
 	}
 }
 
+func TestEqlParse(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointEql + "parse"
+
+	st, _, res := sendTestRequest(queryURL, "POST", []byte(`
+{
+    "foo" : "bar"
+}
+`[1:]))
+
+	if st != "400 Bad Request" || res != "Need a query parameter" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`
+{
+  "query": "get bla where isnull foo"
+}
+`[1:]))
+
+	if st != "200 OK" || res != `
+{
+  "ast": {
+    "children": [
+      {
+        "name": "value",
+        "value": "bla"
+      },
+      {
+        "children": [
+          {
+            "children": [
+              {
+                "name": "value",
+                "value": "foo"
+              }
+            ],
+            "name": "isnull",
+            "value": "isnull"
+          }
+        ],
+        "name": "where",
+        "value": "where"
+      }
+    ],
+    "name": "get",
+    "value": "get"
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Parse errors are returned as structured error objects
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`
+{
+  "query": "get bla\nwhere foo = = bar"
+}
+`[1:]))
+
+	if st != "400 Bad Request" || res != `
+{
+  "error": {
+    "detail": "=",
+    "line": 2,
+    "message": "Parse error in request: Term cannot start an expression (=) (Line:2 Pos:13)",
+    "pos": 13,
+    "token": "=",
+    "type": "Term cannot start an expression"
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`
+{
+  "query": "get =bla"
+}
+`[1:]))
+
+	if st != "400 Bad Request" || res != `
+{
+  "error": {
+    "detail": "Invalid node kind '=bla' - can only contain [a-zA-Z0-9_]",
+    "line": 1,
+    "message": "Parse error in request: Lexical error (Invalid node kind '=bla' - can only contain [a-zA-Z0-9_]) (Line:1 Pos:5)",
+    "pos": 5,
+    "token": "Invalid node kind '=bla' - can only contain [a-zA-Z0-9_]",
+    "type": "Lexical error"
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestEqlFormat(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointEql
+
+	st, _, res := sendTestRequest(queryURL+"format", "POST", []byte(`
+{
+    "query" : "get bla"
+}
+`[1:]))
+
+	if st != "400 Bad Request" || res != "Need an ast parameter" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	_, _, res = sendTestRequest(queryURL+"format", "POST", []byte(`
+{
+    "ast" : "foobar"
+}
+`[1:]))
+
+	if res != "Plain AST object expected as 'ast' value" {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	_, _, res = sendTestRequest(queryURL+"format", "POST", []byte(`
+{
+    "ast" : {
+		"foo" : "bar"
+	}
+}
+`[1:]))
+
+	if res != "Found plain ast node without a name: map[foo:bar]" {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	// A parsed query can be formatted again
+
+	_, _, res = sendTestRequest(queryURL+"parse", "POST", []byte(`
+{
+  "query": "GET distinct Song, Author WHERE isnull foo WITH ordering(ASCENDING name)"
+}
+`[1:]))
+
+	var astInput map[string]interface{}
+	var astText bytes.Buffer
+
+	json.NewDecoder(bytes.NewBufferString(res)).Decode(&astInput)
+	json.NewEncoder(&astText).Encode(astInput)
+
+	st, _, res = sendTestRequest(queryURL+"format", "POST", astText.Bytes())
+
+	if st != "200 OK" || res != `
+{
+  "query": "get distinct Song, Author where isnull foo \nwith\n  ordering(ascending name)"
+}`[1:] {
+		t.Error("Unexpected result:", st, res)
+		return
+	}
+}
+
 func TestEqlSpecial(t *testing.T) {
 	queryURL := "http://localhost" + TESTPORT + EndpointEql
 
@@ -0,0 +1,137 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"devt.de/krotik/common/stringutil"
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+)
+
+/*
+EndpointExport is the export endpoint URL (rooted). Handles everything under export/...
+*/
+const EndpointExport = api.APIRoot + APIv1 + "/export/"
+
+/*
+exportFormats maps supported export formats to their writer functions and
+content types.
+*/
+var exportFormats = map[string]struct {
+	export      func(io.Writer, string, *graph.Manager) error
+	contentType string
+}{
+	"graphml": {graph.ExportPartitionGraphML, "application/graphml+xml; charset=utf-8"},
+	"gexf":    {graph.ExportPartitionGEXF, "application/gexf+xml; charset=utf-8"},
+}
+
+/*
+ExportEndpointInst creates a new endpoint handler.
+*/
+func ExportEndpointInst() api.RestEndpointHandler {
+	return &exportEndpoint{}
+}
+
+/*
+Handler object for partition exports.
+*/
+type exportEndpoint struct {
+	*api.DefaultEndpointHandler
+}
+
+/*
+HandleGET handles a partition export REST call. The document is streamed
+to the client while nodes and edges are read.
+*/
+func (ee *exportEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if !checkResources(w, resources, 1, 1, "Need a partition") {
+		return
+	}
+
+	part := resources[0]
+
+	if stringutil.IndexOf(part, api.GM.Partitions()) == -1 {
+		http.Error(w, fmt.Sprintf("Partition %s does not exist", part), http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "graphml"
+	}
+
+	ef, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown export format %s - should be graphml or gexf", format),
+			http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("content-type", ef.contentType)
+	w.Header().Set("content-disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", part, format))
+
+	if err := ef.export(w, part, api.GM); err != nil {
+
+		// The error is appended to the document if it was already partially sent
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (ee *exportEndpoint) SwaggerDefs(s map[string]interface{}) {
+
+	s["paths"].(map[string]interface{})["/v1/export/{partition}"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "Export all nodes and edges of a partition.",
+			"description": "The export endpoint streams all nodes and edges of a partition as GraphML or GEXF document.",
+			"produces": []string{
+				"text/plain",
+				"application/graphml+xml",
+				"application/gexf+xml",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "path",
+					"description": "Partition to export.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "format",
+					"in":          "query",
+					"description": "Document format: graphml (default) or gexf.",
+					"required":    false,
+					"type":        "string",
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "GraphML or GEXF document.",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"testing"
+)
+
+func TestExportQuery(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointExport
+
+	st, _, res := sendTestRequest(queryURL, "GET", nil)
+	if st != "400 Bad Request" || res != "Need a partition" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"test?format=csv", "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown export format csv - should be graphml or gexf" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, header, res := sendTestRequest(queryURL+"test", "GET", nil)
+	if st != "200 OK" || header.Get("content-type") != "application/graphml+xml; charset=utf-8" || res != `
+<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="n0" for="node" attr.name="desc" attr.type="string"/>
+  <key id="n1" for="node" attr.name="key" attr.type="string"/>
+  <key id="n2" for="node" attr.name="kind" attr.type="string"/>
+  <key id="n3" for="node" attr.name="name" attr.type="string"/>
+  <graph edgedefault="undirected">
+    <node id="Author:000">
+      <data key="n0">One of the most popular acoustic artists of the decade and one of its best-selling artists.</data>
+      <data key="n1">000</data>
+      <data key="n2">Author</data>
+      <data key="n3">John</data>
+    </node>
+  </graph>
+</graphml>`[1:] {
+		t.Error("Unexpected response:", st, header, res)
+		return
+	}
+
+	st, header, res = sendTestRequest(queryURL+"test?format=gexf", "GET", nil)
+	if st != "200 OK" || header.Get("content-type") != "application/gexf+xml; charset=utf-8" || res != `
+<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://www.gexf.net/1.2draft" version="1.2">
+  <graph mode="static" defaultedgetype="undirected">
+    <attributes class="node">
+      <attribute id="n0" title="desc" type="string"/>
+      <attribute id="n1" title="key" type="string"/>
+      <attribute id="n2" title="kind" type="string"/>
+      <attribute id="n3" title="name" type="string"/>
+    </attributes>
+    <attributes class="edge">
+    </attributes>
+    <nodes>
+      <node id="Author:000" label="000">
+        <attvalues>
+          <attvalue for="n0" value="One of the most popular acoustic artists of the decade and one of its best-selling artists."/>
+          <attvalue for="n1" value="000"/>
+          <attvalue for="n2" value="Author"/>
+          <attvalue for="n3" value="John"/>
+        </attvalues>
+      </node>
+    </nodes>
+    <edges>
+    </edges>
+  </graph>
+</gexf>`[1:] {
+		t.Error("Unexpected response:", st, header, res)
+		return
+	}
+}
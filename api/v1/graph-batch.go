@@ -0,0 +1,246 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+/*
+EndpointGraphBatch is the graph batch endpoint URL (rooted). Handles batch
+requests which can write to several partitions in a single transaction.
+*/
+const EndpointGraphBatch = api.APIRoot + APIv1 + "/graph-batch/"
+
+/*
+GraphBatchEndpointInst creates a new endpoint handler.
+*/
+func GraphBatchEndpointInst() api.RestEndpointHandler {
+	return &graphBatchEndpoint{}
+}
+
+/*
+Handler object for graph batch operations.
+*/
+type graphBatchEndpoint struct {
+	*api.DefaultEndpointHandler
+}
+
+/*
+batchOperation is a single operation of a batch request.
+*/
+type batchOperation struct {
+	Op        string                 `json:"op"`        // Operation (store or delete)
+	Type      string                 `json:"type"`      // Entity type (n or e)
+	Partition string                 `json:"partition"` // Partition of the entity
+	Data      map[string]interface{} `json:"data"`      // Data of the entity
+}
+
+/*
+HandlePOST handles a list of store and delete operations on nodes and
+edges in several partitions. All operations are executed in a single
+transaction - either all operations are applied or none. The graph manager's
+write lock is held while the whole transaction is written so concurrent
+requests cannot observe or interleave with a partially applied batch. The
+response contains a status code and a message for each operation.
+*/
+func (be *graphBatchEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
+	var ops []batchOperation
+
+	if len(resources) > 0 {
+		http.Error(w, "Invalid resource specification: "+strings.Join(resources, "/"), http.StatusBadRequest)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+
+	if err := dec.Decode(&ops); err != nil {
+		http.Error(w, "Could not decode request body as list of operations: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, op := range ops {
+		if op.Partition != "" && !checkWritable(w, op.Partition) {
+			return
+		}
+	}
+
+	res := make([]map[string]interface{}, len(ops))
+	status := http.StatusOK
+
+	trans := graph.NewGraphTrans(api.GM)
+
+	for i, op := range ops {
+		err := be.addBatchOperation(trans, op)
+
+		res[i] = map[string]interface{}{
+			"op":          op.Op,
+			"entity_type": op.Type,
+			"partition":   op.Partition,
+			data.NodeKey:  op.Data[data.NodeKey],
+			data.NodeKind: op.Data[data.NodeKind],
+			"status":      http.StatusOK,
+			"message":     "",
+		}
+
+		if err != nil {
+			status = http.StatusBadRequest
+			res[i]["status"] = http.StatusBadRequest
+			res[i]["message"] = err.Error()
+		}
+	}
+
+	if status == http.StatusOK {
+		if err := trans.Commit(); err != nil {
+			status = commitErrorStatus(err)
+
+			for _, r := range res {
+				r["status"] = status
+				r["message"] = err.Error()
+			}
+		}
+
+	} else {
+
+		// Valid operations are not applied if any operation failed
+
+		for _, r := range res {
+			if r["status"] == http.StatusOK {
+				r["status"] = http.StatusFailedDependency
+				r["message"] = "Operation was not applied"
+			}
+		}
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(res)
+}
+
+/*
+addBatchOperation adds a single batch operation to a given transaction.
+*/
+func (be *graphBatchEndpoint) addBatchOperation(trans graph.Trans, op batchOperation) error {
+
+	if op.Partition == "" {
+		return fmt.Errorf("Operation needs a partition")
+	} else if op.Type != "n" && op.Type != "e" {
+		return fmt.Errorf("Entity type must be n (nodes) or e (edges)")
+	}
+
+	node := data.NewGraphNodeFromMap(inputAttrs(op.Data))
+
+	switch op.Op {
+	case "store":
+		if err := checkAttributeValueSize(node); err != nil {
+			return err
+		}
+
+		if op.Type == "n" {
+			removeDerivedAttributes(node)
+			return trans.StoreNode(op.Partition, node)
+		}
+
+		return trans.StoreEdge(op.Partition, data.NewGraphEdgeFromNode(node))
+
+	case "delete":
+		if node.Key() == "" || node.Kind() == "" {
+			return fmt.Errorf("Data must contain a key and a kind")
+		}
+
+		if op.Type == "n" {
+			return trans.RemoveNode(op.Partition, node.Key(), node.Kind())
+		}
+
+		return trans.RemoveEdge(op.Partition, node.Key(), node.Kind())
+	}
+
+	return fmt.Errorf("Unknown operation %v - must be store or delete", op.Op)
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (be *graphBatchEndpoint) SwaggerDefs(s map[string]interface{}) {
+
+	s["paths"].(map[string]interface{})["/v1/graph-batch"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary": "A batch of operations can be send by using POST requests.",
+			"description": "A list of store and delete operations on nodes and edges in different " +
+				"partitions is executed in a single transaction. Either all operations are applied or none.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "operations",
+					"in":          "body",
+					"description": "Operations which should be executed",
+					"required":    true,
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"op": map[string]interface{}{
+									"description": "Operation: store or delete.",
+									"type":        "string",
+								},
+								"type": map[string]interface{}{
+									"description": "Entity type: n for nodes or e for edges.",
+									"type":        "string",
+								},
+								"partition": map[string]interface{}{
+									"description": "Partition of the entity.",
+									"type":        "string",
+								},
+								"data": map[string]interface{}{
+									"description": "Node or edge data (only key and kind are required for delete).",
+									"type":        "object",
+								},
+							},
+						},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Status code and message of each operation.",
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+						},
+					},
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"devt.de/krotik/eliasdb/api"
+)
+
+func TestGraphBatch(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraphBatch
+
+	st, _, res := sendTestRequest(queryURL, "POST", []byte(`
+[
+	{ "op" : "store", "type" : "n", "partition" : "main", "data" : { "key" : "batch1", "kind" : "BatchTest", "name" : "foo" }},
+	{ "op" : "store", "type" : "n", "partition" : "batchpart", "data" : { "key" : "batch2", "kind" : "BatchTest" }},
+	{ "op" : "store", "type" : "e", "partition" : "main", "data" : {
+		"key" : "batchedge", "kind" : "BatchEdge",
+		"end1key" : "batch1", "end1kind" : "BatchTest", "end1role" : "a", "end1cascading" : false,
+		"end2key" : "000", "end2kind" : "Author", "end2role" : "b", "end2cascading" : false
+	}}
+]`[1:]))
+
+	if st != "200 OK" || res != `
+[
+  {
+    "entity_type": "n",
+    "key": "batch1",
+    "kind": "BatchTest",
+    "message": "",
+    "op": "store",
+    "partition": "main",
+    "status": 200
+  },
+  {
+    "entity_type": "n",
+    "key": "batch2",
+    "kind": "BatchTest",
+    "message": "",
+    "op": "store",
+    "partition": "batchpart",
+    "status": 200
+  },
+  {
+    "entity_type": "e",
+    "key": "batchedge",
+    "kind": "BatchEdge",
+    "message": "",
+    "op": "store",
+    "partition": "main",
+    "status": 200
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("batchpart", "batch2", "BatchTest"); n == nil {
+		t.Error("Node was not stored")
+		return
+	}
+
+	if e, _ := api.GM.FetchEdge("main", "batchedge", "BatchEdge"); e == nil {
+		t.Error("Edge was not stored")
+		return
+	}
+
+	// A failing operation prevents all other operations
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`
+[
+	{ "op" : "delete", "type" : "n", "partition" : "batchpart", "data" : { "key" : "batch2", "kind" : "BatchTest" }},
+	{ "op" : "store", "type" : "n", "partition" : "main", "data" : { "key" : "batch3", "kind" : "BatchTest" }},
+	{ "op" : "update", "type" : "n", "partition" : "main", "data" : { "key" : "batch4", "kind" : "BatchTest" }},
+	{ "op" : "delete", "type" : "x", "partition" : "main", "data" : { "key" : "batch4", "kind" : "BatchTest" }},
+	{ "op" : "delete", "type" : "e", "partition" : "", "data" : { "key" : "batch4", "kind" : "BatchTest" }},
+	{ "op" : "delete", "type" : "e", "partition" : "main", "data" : { "key" : "batch4" }},
+	{ "op" : "store", "type" : "n", "partition" : "main", "data" : { "key" : "batch5" }}
+]`[1:]))
+
+	var ret []map[string]interface{}
+	json.Unmarshal([]byte(res), &ret)
+
+	if st != "400 Bad Request" || len(ret) != 7 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	for i, msg := range []string{
+		"424 Operation was not applied",
+		"424 Operation was not applied",
+		"400 Unknown operation update - must be store or delete",
+		"400 Entity type must be n (nodes) or e (edges)",
+		"400 Operation needs a partition",
+		"400 Data must contain a key and a kind",
+		"400 GraphError: Invalid data (Node is missing a kind value)",
+	} {
+		if res := fmt.Sprint(ret[i]["status"], " ", ret[i]["message"]); res != msg {
+			t.Error("Unexpected result:", i, res)
+			return
+		}
+	}
+
+	if n, _ := api.GM.FetchNode("batchpart", "batch2", "BatchTest"); n == nil {
+		t.Error("Node should not have been deleted")
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "batch3", "BatchTest"); n != nil {
+		t.Error("Node should not have been stored")
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{}`))
+
+	if st != "400 Bad Request" || res != "Could not decode request body as list of operations: "+
+		"json: cannot unmarshal object into Go value of type []v1.batchOperation" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Extra resources are not allowed
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte("[]"))
+
+	if st != "400 Bad Request" || res != "Invalid resource specification: main" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// A partition with the name _batch can be used with the graph endpoint
+
+	graphURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	st, _, res = sendTestRequest(graphURL+"_batch", "POST", []byte(`{ "nodes" : [{ "key" : "b1", "kind" : "BatchTest" }] }`))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("_batch", "b1", "BatchTest"); err != nil || n == nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+}
@@ -11,15 +11,22 @@
 package v1
 
 import (
+	"container/heap"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 
+	"devt.de/krotik/common/stringutil"
 	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/eql"
 	"devt.de/krotik/eliasdb/graph"
 	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
 )
 
 /*
@@ -27,6 +34,74 @@ EndpointGraph is the graph endpoint URL (rooted). Handles everything under graph
 */
 const EndpointGraph = api.APIRoot + APIv1 + "/graph/"
 
+/*
+GraphDeleteResource is the resource name for requests which delete all nodes
+which are selected by an EQL query (e.g. POST /v1/graph/main/_delete).
+*/
+const GraphDeleteResource = "_delete"
+
+/*
+GraphCascadePreviewResource is the resource name for previews of cascading
+deletes which is given instead of a traversal spec
+(e.g. GET /v1/graph/main/n/Author/123/cascade-preview).
+*/
+const GraphCascadePreviewResource = "cascade-preview"
+
+/*
+GraphDegreeResource is the resource name for the edge counts of a node which
+is given instead of a traversal spec (e.g. GET /v1/graph/main/n/Author/123/degree).
+*/
+const GraphDegreeResource = "degree"
+
+/*
+MaxAttributeValueSize is the maximum size in bytes of a single attribute value
+which can be stored via the graph endpoint. A value of 0 or less disables the check.
+*/
+var MaxAttributeValueSize int64 = 10485760
+
+/*
+MaxPathKeyLength is the maximum length of a node or edge key which is given
+as part of the request path. Longer keys must be given via the key query
+parameter. A value of 0 or less disables the check.
+*/
+var MaxPathKeyLength = 1024
+
+/*
+EmptyListNoContent is a flag if the graph endpoint should return 204 No Content
+with an empty body instead of 200 and an empty list if a node listing has no
+results.
+*/
+var EmptyListNoContent = false
+
+/*
+MaxShortestPathDepth is the maximum number of traversal steps of a shortest
+path query. It is also the default if a query does not specify a maximum depth.
+*/
+var MaxShortestPathDepth = 10
+
+/*
+DerivedAttributes maps node kinds to derived attributes and the EQL expressions
+which compute them (e.g. "Song" : { "score" : "ranking * 10" }). Derived
+attributes are computed when nodes are read and are never stored.
+*/
+var DerivedAttributes = make(map[string]map[string]string)
+
+/*
+AttributeNameMapping maps stored attribute names to the names which are used
+in requests and responses of the graph endpoint (e.g. "release_date" :
+"releaseDate"). Names in requests are mapped back to the stored names.
+*/
+var AttributeNameMapping = make(map[string]string)
+
+/*
+AttributeNameCasing is the casing policy (camel or snake) of attribute names
+in requests and responses of the graph endpoint. It applies to all attributes
+which are not in AttributeNameMapping. Names in requests are converted into
+the opposite casing before they are stored (e.g. with the camel policy
+releaseDate is stored as release_date). An empty value disables the policy.
+*/
+var AttributeNameCasing = ""
+
 /*
 GraphEndpointInst creates a new endpoint handler.
 */
@@ -43,11 +118,25 @@ type graphEndpoint struct {
 
 /*
 HandleGET handles REST calls to retrieve data from the graph database.
+Responses carry an entity tag and are only sent if the entity tag does not
+match the If-None-Match header of the request.
 */
 func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+	handleConditionalGET(w, r, resources, ge.handleGET)
+}
+
+/*
+handleGET handles REST calls to retrieve data from the graph database.
+*/
+func (ge *graphEndpoint) handleGET(w http.ResponseWriter, r *http.Request, resources []string) {
 
 	// Check parameters
 
+	if len(resources) > 1 && resources[1] == "path" {
+		ge.handleShortestPath(w, r, resources)
+		return
+	}
+
 	if !checkResources(w, resources, 3, 5, "Need a partition, entity type (n or e) and a kind; optional key and traversal spec") {
 		return
 	}
@@ -57,6 +146,25 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 		return
 	}
 
+	// The key of a specific node or edge can be given as query parameter
+	// instead of a path element
+
+	if key := r.URL.Query().Get("key"); key != "" {
+
+		if len(resources) > 4 {
+			http.Error(w, "Key must not be given as path element and query parameter", http.StatusBadRequest)
+			return
+		}
+
+		resources = append(resources[:3], append([]string{key}, resources[3:]...)...)
+
+	} else if len(resources) > 3 && MaxPathKeyLength > 0 && len(resources[3]) > MaxPathKeyLength {
+
+		http.Error(w, fmt.Sprintf("Key in request path exceeds maximum length of %v characters - use the key query parameter instead",
+			MaxPathKeyLength), http.StatusBadRequest)
+		return
+	}
+
 	if len(resources) == 3 {
 
 		// Iterate over a list of nodes
@@ -77,6 +185,54 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 				return
 			}
 
+			// Get sort parameters; the result is only sorted if an attribute is given
+			// (sortby and sortdir can be used instead of sort and dir but not together
+			// with them)
+
+			sortAttr, _, ok := queryParamAlias(w, r, "sort", "sortby")
+			if !ok {
+				return
+			} else if sortAttr != "" {
+				sortAttr = storedAttrName(sortAttr)
+			}
+
+			sortDir, sortDirParam, ok := queryParamAlias(w, r, "dir", "sortdir")
+			if !ok {
+				return
+			} else if sortDir != "" && sortDir != "asc" && sortDir != "desc" {
+				http.Error(w, "Invalid parameter value: "+sortDirParam+" should be asc or desc", http.StatusBadRequest)
+				return
+			}
+
+			// Get filter parameters; all filters must match for a node to be returned
+
+			filters, err := parseNodeFilters(r.URL.Query()["filter"])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Cursor based paging is used if an after parameter is given
+			// (an empty value requests the first page)
+
+			if after, ok := r.URL.Query()["after"]; ok {
+
+				if offset != -1 || sortAttr != "" || len(filters) > 0 {
+					http.Error(w, "Parameter after cannot be used together with offset, sort or filter",
+						http.StatusBadRequest)
+					return
+				}
+
+				ge.handleNodeListAfter(w, resources[0], resources[2], after[0], limit, acceptsCSV(r))
+				return
+			}
+
+			if sortAttr != "" || len(filters) > 0 {
+				ge.handleNodeList(w, resources[0], resources[2], filters, sortAttr, sortDir != "desc",
+					offset, limit, acceptsCSV(r))
+				return
+			}
+
 			it, err := api.GM.NodeKeyIterator(resources[0], resources[2])
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -132,24 +288,30 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 
 				node, err := api.GM.FetchNode(resources[0], key, resources[2])
 
+				if err == nil {
+					err = addDerivedAttributes(resources[0], node)
+				}
+
 				if err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
 
-				data = append(data, node.Data())
+				data = append(data, outputAttrs(node.Data()))
 			}
 
 			// Set total count header
 
 			w.Header().Add(HTTPHeaderTotalCount, strconv.FormatUint(api.GM.NodeCount(resources[2]), 10))
 
-			// Write data
+			if len(data) == 0 && EmptyListNoContent {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
 
-			w.Header().Set("content-type", "application/json; charset=utf-8")
+			// Write data
 
-			ret := json.NewEncoder(w)
-			ret.Encode(data)
+			writeEntityList(w, acceptsCSV(r), data)
 
 		} else {
 			http.Error(w, "Entity type must be n (nodes) when requesting all items", http.StatusBadRequest)
@@ -158,14 +320,25 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 
 	} else if len(resources) == 4 {
 
-		// Fetch a specific node or relationship
+		// Fetch a specific node or relationship - the version is read first so
+		// the returned entity tag is never newer than the returned data
 
 		var data map[string]interface{}
 
 		if resources[1] == "n" {
 
+			version, err := api.GM.NodeVersion(resources[0], resources[3], resources[2])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
 			node, err := api.GM.FetchNode(resources[0], resources[3], resources[2])
 
+			if err == nil && node != nil {
+				err = addDerivedAttributes(resources[0], node)
+			}
+
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -174,10 +347,17 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 				return
 			}
 
-			data = node.Data()
+			data = outputAttrs(node.Data())
+			setETag(w, version)
 
 		} else {
 
+			version, err := api.GM.EdgeVersion(resources[0], resources[3], resources[2])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
 			edge, err := api.GM.FetchEdge(resources[0], resources[3], resources[2])
 
 			if err != nil {
@@ -188,15 +368,39 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 				return
 			}
 
-			data = edge.Data()
+			data = outputAttrs(edge.Data())
+			setETag(w, version)
 		}
 
 		// Write data
 
+		if acceptsCSV(r) {
+			writeCSV(w, []interface{}{data})
+			return
+		}
+
 		w.Header().Set("content-type", "application/json; charset=utf-8")
 
 		ret := json.NewEncoder(w)
-		ret.Encode(data)
+		ret.Encode(formatOutputFloats(data))
+
+	} else if resources[4] == GraphCascadePreviewResource {
+
+		if resources[1] != "n" {
+			http.Error(w, "Entity type must be n (nodes) when requesting a cascade preview", http.StatusBadRequest)
+			return
+		}
+
+		ge.handleCascadePreview(w, resources[0], resources[3], resources[2])
+
+	} else if resources[4] == GraphDegreeResource {
+
+		if resources[1] != "n" {
+			http.Error(w, "Entity type must be n (nodes) when requesting a degree", http.StatusBadRequest)
+			return
+		}
+
+		ge.handleDegree(w, resources[0], resources[3], resources[2])
 
 	} else {
 
@@ -212,41 +416,127 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 				return
 			}
 
-			nodes, edges, err := api.GM.TraverseMulti(resources[0], resources[3],
-				resources[2], resources[4], true)
+			// Get limit parameter; -1 if not set
+
+			limit, ok := queryParamPosNum(w, r, "limit")
+			if !ok {
+				return
+			}
+
+			// Get offset parameter; -1 if not set
+
+			offset, ok := queryParamPosNum(w, r, "offset")
+			if !ok {
+				return
+			}
+
+			// Get direction parameter; all edges are followed if not set
+
+			direction := r.URL.Query().Get("direction")
+			if !checkDirection(w, direction) {
+				return
+			}
+
+			// Get edge filter parameters; all filters must match for an edge to be followed
 
+			edgeFilters, err := parseNodeFilters(r.URL.Query()["edgefilter"])
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
 
-			data := make([][]map[string]interface{}, 2)
+			var edgeFilter graph.EdgeFilter
 
-			dataNodes := make([]map[string]interface{}, 0, len(nodes))
-			dataEdges := make([]map[string]interface{}, 0, len(edges))
+			if len(edgeFilters) > 0 {
+				edgeFilter = func(edge data.Edge) bool {
+					return matchNodeFilters(edgeFilters, edge.Data())
+				}
+			}
+
+			// Results are sorted by node key before the requested window is cut
+
+			window := newTraversalWindow(offset, limit)
+
+			if stringutil.IsTrueValue(r.URL.Query().Get("roundtrip")) {
+
+				// Traverse to the given spec and back to the kind of the start node
+
+				if direction != graph.DirectionAny {
+					http.Error(w, "Direction cannot be used with round trip traversals", http.StatusBadRequest)
+					return
+				} else if edgeFilter != nil {
+					http.Error(w, "Edge filters cannot be used with round trip traversals", http.StatusBadRequest)
+					return
+				}
+
+				nodes, edges, err := api.GM.TraverseRoundTrip(resources[0], resources[3],
+					resources[2], resources[4], true)
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
 
-			if nodes != nil && edges != nil {
 				for i, n := range nodes {
-					e := edges[i]
+					if n != nil {
+						window.add(n, edges[i])
+					}
+				}
+
+			} else {
+
+				// Stream the traversal result so only the requested window is read
+
+				it, err := api.GM.TraverseMultiIterFilter(resources[0], resources[3],
+					resources[2], resources[4], direction, edgeFilter)
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				for it.HasNext() {
+
+					n, e, err := it.Next()
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					} else if n != nil {
+						window.add(n, e)
+					}
+				}
 
-					dataNodes = append(dataNodes, n.Data())
-					dataEdges = append(dataEdges, e.Data())
+				if err := it.Error(); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
 				}
 			}
 
-			data[0] = dataNodes
-			data[1] = dataEdges
+			data := make([][]map[string]interface{}, 2)
+
+			dataNodes := make([]map[string]interface{}, 0)
+			dataEdges := make([]map[string]interface{}, 0)
+
+			for _, entry := range window.page() {
+
+				if err := addDerivedAttributes(resources[0], entry.node); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
 
-			// Sort the result
+				dataNodes = append(dataNodes, outputAttrs(entry.node.Data()))
+				dataEdges = append(dataEdges, outputAttrs(entry.edge.Data()))
+			}
 
-			sort.Stable(&traversalResultComparator{data})
+			data[0] = dataNodes
+			data[1] = dataEdges
 
 			// Write data
 
 			w.Header().Set("content-type", "application/json; charset=utf-8")
 
 			ret := json.NewEncoder(w)
-			ret.Encode(data)
+			ret.Encode(formatOutputFloats(data))
 
 		} else {
 			http.Error(w, "Entity type must be n (nodes) when requesting traversal results", http.StatusBadRequest)
@@ -256,491 +546,2187 @@ func (ge *graphEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 }
 
 /*
-HandlePUT handles a REST call to insert new elements into the graph or update
-existing elements. Nodes are updated if they already exist. Edges are replaced
-if they already exist.
-*/
-func (ge *graphEndpoint) HandlePUT(w http.ResponseWriter, r *http.Request, resources []string) {
-	ge.handleGraphRequest(w, r, resources,
-		func(trans graph.Trans, part string, node data.Node) error {
-			return trans.UpdateNode(part, node)
-		},
-		func(trans graph.Trans, part string, edge data.Edge) error {
-			return trans.StoreEdge(part, edge)
-		})
-}
-
-/*
-HandlePOST handles a REST call to insert new elements into the graph or update
-existing elements. Nodes and edges are replaced if they already exist.
+handleNodeListAfter handles a REST call to retrieve a page of nodes which
+follows a given cursor. The cursor of the last returned node is sent in the
+X-Next-Cursor header if more nodes follow. Cursors stay valid if nodes are
+stored or removed between requests.
 */
-func (ge *graphEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
-	ge.handleGraphRequest(w, r, resources,
-		func(trans graph.Trans, part string, node data.Node) error {
-			return trans.StoreNode(part, node)
-		},
-		func(trans graph.Trans, part string, edge data.Edge) error {
-			return trans.StoreEdge(part, edge)
-		})
-}
+func (ge *graphEndpoint) handleNodeListAfter(w http.ResponseWriter, part string, kind string,
+	cursor string, limit int, csvOutput bool) {
 
-/*
-HandleDELETE handles a REST call to delete elements from the graph.
-*/
-func (ge *graphEndpoint) HandleDELETE(w http.ResponseWriter, r *http.Request, resources []string) {
-	ge.handleGraphRequest(w, r, resources,
-		func(trans graph.Trans, part string, node data.Node) error {
-			return trans.RemoveNode(part, node.Key(), node.Kind())
-		},
-		func(trans graph.Trans, part string, edge data.Edge) error {
-			return trans.RemoveEdge(part, edge.Key(), edge.Kind())
-		})
-}
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 
-/*
-handleGraphRequest handles a graph query REST call.
-*/
-func (ge *graphEndpoint) handleGraphRequest(w http.ResponseWriter, r *http.Request, resources []string,
-	transFuncNode func(trans graph.Trans, part string, node data.Node) error,
-	transFuncEdge func(trans graph.Trans, part string, edge data.Edge) error) {
+	// Request one more key than needed to find out if another page follows
 
-	var nDataList []map[string]interface{}
-	var eDataList []map[string]interface{}
+	fetchLimit := 0
 
-	// Check parameters
+	if limit != -1 {
+		fetchLimit = limit + 1
+	}
 
-	if !checkResources(w, resources, 1, 2, "Need a partition; optional entity type (n or e)") {
+	keys, err := api.GM.NodeKeysAfter(part, kind, string(after), fetchLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if keys == nil {
+		http.Error(w, "Unknown partition or node kind", http.StatusBadRequest)
 		return
 	}
 
-	dec := json.NewDecoder(r.Body)
+	if limit != -1 && len(keys) > limit {
+		keys = keys[:limit]
 
-	if len(resources) == 1 {
+		if limit > 0 {
+			w.Header().Add(HTTPHeaderNextCursor, base64.RawURLEncoding.EncodeToString([]byte(keys[limit-1])))
+		} else {
+			w.Header().Add(HTTPHeaderNextCursor, cursor)
+		}
+	}
 
-		// No explicit type given - expecting a graph
+	data := make([]interface{}, 0, len(keys))
 
-		gdata := make(map[string][]map[string]interface{})
+	for _, key := range keys {
 
-		if err := dec.Decode(&gdata); err != nil {
-			http.Error(w, "Could not decode request body as object with list of nodes and/or edges: "+err.Error(), http.StatusBadRequest)
-			return
-		}
+		node, err := api.GM.FetchNode(part, key, kind)
 
-		nDataList = gdata["nodes"]
-		eDataList = gdata["edges"]
+		if err == nil && node == nil {
 
-	} else if resources[1] == "n" {
+			// Skip nodes which were removed after their key was read
 
-		nDataList = make([]map[string]interface{}, 1)
+			continue
 
-		if err := dec.Decode(&nDataList); err != nil {
-			http.Error(w, "Could not decode request body as list of nodes: "+err.Error(), http.StatusBadRequest)
-			return
+		} else if err == nil {
+			err = addDerivedAttributes(part, node)
 		}
-	} else if resources[1] == "e" {
 
-		eDataList = make([]map[string]interface{}, 1)
-
-		if err := dec.Decode(&eDataList); err != nil {
-			http.Error(w, "Could not decode request body as list of edges: "+err.Error(), http.StatusBadRequest)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		data = append(data, outputAttrs(node.Data()))
 	}
 
-	// Create a transaction
+	// Set total count header
 
-	trans := graph.NewGraphTrans(api.GM)
+	w.Header().Add(HTTPHeaderTotalCount, strconv.FormatUint(api.GM.NodeCount(kind), 10))
 
-	if nDataList != nil {
+	if len(data) == 0 && EmptyListNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 
-		// Store nodes in transaction
+	// Write data
 
-		for _, ndata := range nDataList {
-			node := data.NewGraphNodeFromMap(ndata)
+	writeEntityList(w, csvOutput, data)
+}
 
-			if err := transFuncNode(trans, resources[0], node); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
+/*
+handleNodeList handles a REST call to retrieve a filtered and/or sorted list
+of nodes. All nodes of the requested kind are filtered and sorted before offset
+and limit are applied. The list is not sorted if no sort attribute is given.
+*/
+func (ge *graphEndpoint) handleNodeList(w http.ResponseWriter, part string, kind string,
+	filters []*nodeFilter, sortAttr string, ascending bool, offset int, limit int, csvOutput bool) {
+
+	it, err := api.GM.NodeKeyIterator(part, kind)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if it == nil {
+		http.Error(w, "Unknown partition or node kind", http.StatusBadRequest)
+		return
+	}
+
+	if sortAttr != "" && !isKnownNodeAttr(kind, sortAttr) {
+		http.Error(w, "Unknown sort attribute "+sortAttr, http.StatusBadRequest)
+		return
+	}
+
+	data := make([]interface{}, 0)
+
+	for it.HasNext() {
+
+		key := it.Next()
+
+		if it.LastError != nil {
+			http.Error(w, it.LastError.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		node, err := api.GM.FetchNode(part, key, kind)
+
+		if err == nil {
+			err = addDerivedAttributes(part, node)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if nodeData := node.Data(); matchNodeFilters(filters, nodeData) {
+			data = append(data, nodeData)
 		}
 	}
 
-	if eDataList != nil {
+	if sortAttr != "" {
+		sort.Stable(&nodeListComparator{sortAttr, ascending, data})
+	}
 
-		// Store edges in transaction
+	// The total count is the number of nodes which matched the filters
 
-		for _, edata := range eDataList {
-			edge := data.NewGraphEdgeFromNode(data.NewGraphNodeFromMap(edata))
+	totalCount := api.GM.NodeCount(kind)
 
-			if err := transFuncEdge(trans, resources[0], edge); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
+	if len(filters) > 0 {
+		totalCount = uint64(len(data))
+	}
+
+	// Apply offset and limit after filtering and sorting
+
+	if offset != -1 {
+
+		if offset > len(data) {
+			http.Error(w, "Offset exceeds available nodes", http.StatusInternalServerError)
+			return
 		}
+
+		data = data[offset:]
 	}
 
-	// Commit transaction
+	if limit != -1 && limit < len(data) {
+		data = data[:limit]
+	}
 
-	if err := trans.Commit(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Filters and sorting use the stored attribute names
+
+	for i, d := range data {
+		data[i] = outputAttrs(d.(map[string]interface{}))
+	}
+
+	// Set total count header
+
+	w.Header().Add(HTTPHeaderTotalCount, strconv.FormatUint(totalCount, 10))
+
+	if len(data) == 0 && EmptyListNoContent {
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+
+	// Write data
+
+	writeEntityList(w, csvOutput, data)
 }
 
 /*
-SwaggerDefs is used to describe the endpoint in swagger.
+handleCascadePreview handles a REST call to preview a cascading delete of a
+node (e.g. /main/n/Author/123/cascade-preview). The result contains all nodes
+and edges which would be removed together with the node. Nothing is removed.
 */
-func (ge *graphEndpoint) SwaggerDefs(s map[string]interface{}) {
+func (ge *graphEndpoint) handleCascadePreview(w http.ResponseWriter, part string, key string, kind string) {
 
-	partitionParams := []map[string]interface{}{
-		{
-			"name":        "partition",
-			"in":          "path",
-			"description": "Partition to select.",
-			"required":    true,
-			"type":        "string",
-		},
+	nodes, edges, err := api.GM.CascadePreview(part, key, kind)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if nodes == nil {
+		http.Error(w, "Unknown partition or node kind", http.StatusBadRequest)
+		return
 	}
 
-	entityParams := []map[string]interface{}{
-		{
-			"name": "entity_type",
-			"in":   "path",
-			"description": "Datastore entity type which should selected. " +
-				"Either n for nodes or e for edges.",
-			"required": true,
-			"type":     "string",
-		},
+	data := make([][]map[string]interface{}, 2)
+
+	data[0] = make([]map[string]interface{}, 0, len(nodes))
+	data[1] = make([]map[string]interface{}, 0, len(edges))
+
+	for _, n := range nodes {
+		data[0] = append(data[0], outputAttrs(n.Data()))
 	}
 
-	defaultParams := []map[string]interface{}{
-		{
-			"name":        "kind",
-			"in":          "path",
-			"description": "Node or edge kind to be queried.",
-			"required":    true,
-			"type":        "string",
-		},
+	for _, e := range edges {
+		data[1] = append(data[1], outputAttrs(e.Data()))
 	}
-	defaultParams = append(defaultParams, partitionParams...)
-	defaultParams = append(defaultParams, entityParams...)
 
-	optionalQueryParams := []map[string]interface{}{
-		{
-			"name":        "limit",
-			"in":          "query",
-			"description": "How many list items to return.",
-			"required":    false,
-			"type":        "number",
-			"format":      "integer",
-		},
-		{
-			"name":        "offset",
-			"in":          "query",
-			"description": "Offset in the dataset.",
-			"required":    false,
-			"type":        "number",
-			"format":      "integer",
-		},
+	// Write data
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	ret := json.NewEncoder(w)
+	ret.Encode(formatOutputFloats(data))
+}
+
+/*
+handleDegree handles a REST call to count the edges of a node
+(e.g. /main/n/Author/123/degree). The result contains the number of incoming,
+outgoing and all edges of the node in total and for each edge kind. Connected
+nodes are not fetched.
+*/
+func (ge *graphEndpoint) handleDegree(w http.ResponseWriter, part string, key string, kind string) {
+
+	degrees, err := api.GM.NodeDegree(part, key, kind)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if degrees == nil {
+		http.Error(w, "Unknown partition or node kind", http.StatusBadRequest)
+		return
 	}
 
-	keyParam := []map[string]interface{}{
-		{
-			"name":        "key",
-			"in":          "path",
-			"description": "Node or edge key to be queried.",
-			"required":    true,
-			"type":        "string",
-		},
+	degreeData := func(in int, out int) map[string]interface{} {
+		return map[string]interface{}{
+			"in":    in,
+			"out":   out,
+			"total": in + out,
+		}
 	}
 
-	travParam := []map[string]interface{}{
-		{
-			"name":        "traversal_spec",
-			"in":          "path",
-			"description": "Traversal to be followed from a single node.",
-			"required":    true,
-			"type":        "string",
-		},
+	in, out := 0, 0
+	kinds := make(map[string]interface{})
+
+	for edgeKind, degree := range degrees {
+		in += degree.In
+		out += degree.Out
+		kinds[edgeKind] = degreeData(degree.In, degree.Out)
 	}
 
-	graphPost := []map[string]interface{}{
-		{
-			"name":        "entities",
-			"in":          "body",
-			"description": "Nodes and Edges which should be stored",
-			"required":    true,
-			"schema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"nodes": map[string]interface{}{
-						"description": "List of nodes to be inserted / updated.",
-						"type":        "array",
-						"items": map[string]interface{}{
-							"description": "Node to be inserted / updated.",
-							"type":        "object",
-						},
-					},
-					"edges": map[string]interface{}{
-						"description": "List of edges to be inserted / updated.",
-						"type":        "array",
-						"items": map[string]interface{}{
-							"description": "Edge to be inserted / updated.",
-							"type":        "object",
-						},
-					},
-				},
-			},
-		},
+	data := degreeData(in, out)
+	data["kinds"] = kinds
+
+	// Write data
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	ret := json.NewEncoder(w)
+	ret.Encode(data)
+}
+
+/*
+handleShortestPath handles a REST call to find the shortest path between two
+nodes (e.g. /main/path/n/Author/123/n/Song/LoveSong3). The path is found with
+a breadth-first search which visits every node at most once and stops after a
+maximum number of traversal steps. If a weight attribute is given then the
+path with the lowest total weight is found instead. The result contains the
+nodes and edges along the path or two empty lists if the target node cannot
+be reached (followed by the total weight for weighted paths).
+*/
+func (ge *graphEndpoint) handleShortestPath(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if !checkResources(w, resources, 8, 8, "Need a partition, start node (n, kind and key) and target node (n, kind and key)") {
+		return
 	}
 
-	entitiesPost := []map[string]interface{}{
-		{
-			"name":        "entities",
-			"in":          "body",
-			"description": "Nodes or Edges which should be stored",
-			"required":    true,
-			"schema": map[string]interface{}{
-				"type": "array",
-				"items": map[string]interface{}{
-					"description": "Node or edge to be inserted / updated.",
-					"type":        "object",
-				},
-			},
-		},
+	if resources[2] != "n" || resources[5] != "n" {
+		http.Error(w, "Entity type must be n (nodes) for path queries", http.StatusBadRequest)
+		return
 	}
 
-	defaultError := map[string]interface{}{
-		"description": "Error response",
-		"schema": map[string]interface{}{
-			"$ref": "#/definitions/Error",
-		},
+	part := resources[0]
+
+	maxDepth, ok := queryParamPosNum(w, r, "maxdepth")
+	if !ok {
+		return
+	} else if maxDepth == -1 {
+		maxDepth = MaxShortestPathDepth
+	} else if maxDepth > MaxShortestPathDepth {
+		http.Error(w, fmt.Sprintf("Maximum depth exceeds limit of %v", MaxShortestPathDepth), http.StatusBadRequest)
+		return
 	}
 
-	// Add endpoint to insert a graph with nodes and edges
+	spec := r.URL.Query().Get("spec")
+	if spec == "" {
+		spec = ":::"
+	}
 
-	s["paths"].(map[string]interface{})["/v1/graph/{partition}"] = map[string]interface{}{
-		"post": map[string]interface{}{
-			"summary": "Data can be send by using POST requests.",
-			"description": "A whole graph can be send. " +
-				"POST will store data in the datastore and always overwrite any existing data.",
-			"consumes": []string{
-				"application/json",
-			},
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(partitionParams, graphPost...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "No data is returned when data is created.",
-				},
-				"default": defaultError,
-			},
-		},
-		"put": map[string]interface{}{
-			"summary": "Data can be send by using PUT requests.",
-			"description": "A whole graph can be send. " +
-				"PUT will store data in the datastore and update existing data.",
-			"consumes": []string{
-				"application/json",
-			},
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(partitionParams, graphPost...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "No data is returned when data is created.",
-				},
-				"default": defaultError,
-			},
-		},
-		"delete": map[string]interface{}{
-			"summary": "Data can be send by using DELETE requests.",
-			"description": "A whole graph can be send. " +
-				"DELETE will delete data in the datastore - only key and kind are required.",
-			"consumes": []string{
-				"application/json",
-			},
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(partitionParams, graphPost...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "No data is returned when data is created.",
-				},
-				"default": defaultError,
-			},
-		},
+	start, err := api.GM.FetchNode(part, resources[4], resources[3])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if start == nil {
+		http.Error(w, "Unknown start node", http.StatusBadRequest)
+		return
 	}
 
-	// Add endpoint to insert nodes / edges
+	target, err := api.GM.FetchNode(part, resources[7], resources[6])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if target == nil {
+		http.Error(w, "Unknown target node", http.StatusBadRequest)
+		return
+	}
 
-	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}"] = map[string]interface{}{
-		"post": map[string]interface{}{
-			"summary": "Data can be send by using POST requests.",
-			"description": "A list of nodes / edges can be send. " +
-				"POST will store data in the datastore and always overwrite any existing data.",
-			"consumes": []string{
-				"application/json",
-			},
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(append(partitionParams, entityParams...), entitiesPost...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "No data is returned when data is created.",
-				},
-				"default": defaultError,
-			},
-		},
-		"put": map[string]interface{}{
-			"summary": "Data can be send by using PUT requests.",
-			"description": "A list of nodes / edges can be send. " +
-				"PUT will store data in the datastore and update existing data.",
-			"consumes": []string{
-				"application/json",
-			},
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(append(partitionParams, entityParams...), entitiesPost...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "No data is returned when data is created.",
-				},
-				"default": defaultError,
-			},
-		},
-		"delete": map[string]interface{}{
-			"summary": "Data can be send by using DELETE requests.",
-			"description": "A list of nodes / edges can be send. " +
-				"DELETE will delete data in the datastore - only key and kind are required.",
-			"consumes": []string{
-				"application/json",
-			},
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(append(partitionParams, entityParams...), entitiesPost...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "No data is returned when data is created.",
-				},
-				"default": defaultError,
-			},
-		},
+	var nodes []data.Node
+	var edges []data.Edge
+	var cost float64
+
+	weight := r.URL.Query().Get("weight")
+
+	if weight != "" {
+
+		// Weighted paths are found with Dijkstra's algorithm which is
+		// not limited by a number of traversal steps
+
+		if r.URL.Query().Get("maxdepth") != "" {
+			http.Error(w, "Maximum depth cannot be used for weighted paths", http.StatusBadRequest)
+			return
+		}
+
+		nodes, edges, cost, err = api.GM.ShortestPathWeighted(part, start.Key(), start.Kind(),
+			target.Key(), target.Kind(), spec, storedAttrName(weight))
+
+	} else {
+
+		nodes, edges, err = api.GM.ShortestPath(r.Context(), part, start.Key(), start.Kind(),
+			target.Key(), target.Kind(), spec, maxDepth)
 	}
 
-	// Add endpoint to query nodes for a specific node kind
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}/{kind}"] = map[string]interface{}{
-		"get": map[string]interface{}{
-			"summary": "The graph endpoint is the main entry point to request data.",
-			"description": "GET requests can be used to query a series of nodes. " +
-				"The X-Total-Count header contains the total number of nodes which were found.",
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(defaultParams, optionalQueryParams...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "The return data is a list of objects",
-					"schema": map[string]interface{}{
-						"type": "array",
-						"items": map[string]interface{}{
-							"type": "object",
-						},
-					},
-				},
-				"default": defaultError,
-			},
-		},
+	pathNodes := make([]map[string]interface{}, 0, len(nodes))
+	pathEdges := make([]map[string]interface{}, 0, len(edges))
+
+	for _, n := range nodes {
+		if err := addDerivedAttributes(part, n); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		pathNodes = append(pathNodes, outputAttrs(n.Data()))
 	}
 
-	// Add endpoint to query/create a specific node
+	for _, e := range edges {
+		pathEdges = append(pathEdges, outputAttrs(e.Data()))
+	}
 
-	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}/{kind}/{key}"] = map[string]interface{}{
-		"get": map[string]interface{}{
-			"summary":     "The graph endpoint is the main entry point to request data.",
-			"description": "GET requests can be used to query a single node.",
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(append(defaultParams, keyParam...), optionalQueryParams...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "The return data is a single object",
-					"schema": map[string]interface{}{
-						"type": "object",
-					},
-				},
-				"default": defaultError,
-			},
-		},
+	data := []interface{}{pathNodes, pathEdges}
+
+	// Weighted paths also return their total weight
+
+	if weight != "" {
+		data = append(data, cost)
 	}
 
-	// Add endpoint to traverse from a single node
+	// Write data
 
-	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}/{kind}/{key}/{traversal_spec}"] = map[string]interface{}{
-		"get": map[string]interface{}{
-			"summary":     "The graph endpoint is the main entry point to request data.",
-			"description": "GET requests can be used to query a single node and then traverse to its neighbours.",
-			"produces": []string{
-				"text/plain",
-				"application/json",
-			},
-			"parameters": append(append(defaultParams, keyParam...), travParam...),
-			"responses": map[string]interface{}{
-				"200": map[string]interface{}{
-					"description": "The return data are two lists containing traversed nodes and edges. " +
-						"The traversal endpoint does NOT support limit and offset parameters. " +
-						"Also the X-Total-Count header is not set.",
-					"schema": map[string]interface{}{
-						"type": "array",
-						"items": map[string]interface{}{
-							"type": "array",
-							"items": map[string]interface{}{
-								"type": "object",
-							},
-						},
-					},
-				},
-				"default": defaultError,
-			},
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	ret := json.NewEncoder(w)
+	ret.Encode(formatOutputFloats(data))
+}
+
+/*
+HandlePUT handles a REST call to insert new elements into the graph or update
+existing elements. Nodes are updated if they already exist. Edges are replaced
+if they already exist. A request with an If-Match header updates a single
+element only if its stored version matches.
+*/
+func (ge *graphEndpoint) HandlePUT(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		ge.handleConditionalUpdate(w, r, resources, ifMatch)
+		return
+	}
+
+	ge.handleGraphRequest(w, r, resources, true,
+		func(trans graph.Trans, part string, node data.Node) error {
+			removeDerivedAttributes(node)
+			if err := checkAttributeValueSize(node); err != nil {
+				return err
+			}
+			return trans.UpdateNode(part, node)
 		},
+		func(trans graph.Trans, part string, edge data.Edge) error {
+			if err := checkAttributeValueSize(edge); err != nil {
+				return err
+			}
+			return trans.StoreEdge(part, edge)
+		})
+}
+
+/*
+HandlePOST handles a REST call to insert new elements into the graph or update
+existing elements. Nodes and edges are replaced if they already exist.
+*/
+func (ge *graphEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if len(resources) == 2 && resources[1] == GraphDeleteResource {
+		ge.handleDeleteByQuery(w, r, resources[0])
+		return
 	}
+
+	ge.handleGraphRequest(w, r, resources, true,
+		func(trans graph.Trans, part string, node data.Node) error {
+			removeDerivedAttributes(node)
+			if err := checkAttributeValueSize(node); err != nil {
+				return err
+			}
+			return trans.StoreNode(part, node)
+		},
+		func(trans graph.Trans, part string, edge data.Edge) error {
+			if err := checkAttributeValueSize(edge); err != nil {
+				return err
+			}
+			return trans.StoreEdge(part, edge)
+		})
 }
 
-// Comparator object to sort traversal results
+/*
+HandlePATCH handles a REST call to update existing elements in the graph. Only
+the given attributes of nodes and edges are updated - all other attributes
+are kept. Unlike PUT elements which do not exist are not created.
+*/
+func (ge *graphEndpoint) HandlePATCH(w http.ResponseWriter, r *http.Request, resources []string) {
+	ge.handleGraphRequest(w, r, resources, true,
+		func(trans graph.Trans, part string, node data.Node) error {
+			removeDerivedAttributes(node)
+			if err := checkAttributeValueSize(node); err != nil {
+				return err
+			}
+
+			existing, err := api.GM.FetchNodePart(part, node.Key(), node.Kind(), []string{data.NodeKey})
+			if err != nil {
+				return err
+			} else if existing == nil {
+				return fmt.Errorf("Unknown node %v of kind %v in partition %v", node.Key(), node.Kind(), part)
+			}
+
+			return trans.UpdateNode(part, node)
+		},
+		func(trans graph.Trans, part string, edge data.Edge) error {
+			if err := checkAttributeValueSize(edge); err != nil {
+				return err
+			}
+
+			existing, err := api.GM.FetchEdge(part, edge.Key(), edge.Kind())
+			if err != nil {
+				return err
+			} else if existing == nil {
+				return fmt.Errorf("Unknown edge %v of kind %v in partition %v", edge.Key(), edge.Kind(), part)
+			}
+
+			// Merge the given attributes into the existing edge
+
+			for attr, val := range edge.Data() {
+				existing.SetAttr(attr, val)
+			}
 
-type traversalResultComparator struct {
-	Data [][]map[string]interface{} // Data to sort
+			return trans.StoreEdge(part, existing)
+		})
 }
 
-func (c traversalResultComparator) Len() int {
-	return len(c.Data[0])
+/*
+HandleDELETE handles a REST call to delete elements from the graph.
+*/
+func (ge *graphEndpoint) HandleDELETE(w http.ResponseWriter, r *http.Request, resources []string) {
+	ge.handleGraphRequest(w, r, resources, false,
+		func(trans graph.Trans, part string, node data.Node) error {
+			return trans.RemoveNode(part, node.Key(), node.Kind())
+		},
+		func(trans graph.Trans, part string, edge data.Edge) error {
+			return trans.RemoveEdge(part, edge.Key(), edge.Kind())
+		})
 }
 
-func (c traversalResultComparator) Less(i, j int) bool {
-	c1 := c.Data[0][i]
-	c2 := c.Data[0][j]
+/*
+handleConditionalUpdate handles a PUT request with an If-Match header. The
+request must contain a single node or edge which is only written if its stored
+version matches the version in the header. The version is checked and increased
+atomically by the graph manager - a mismatch results in 412 Precondition Failed.
+*/
+func (ge *graphEndpoint) handleConditionalUpdate(w http.ResponseWriter, r *http.Request,
+	resources []string, ifMatch string) {
+
+	if !checkResources(w, resources, 2, 2, "Need a partition and an entity type (n or e) for conditional updates") ||
+		!checkWritable(w, resources[0]) {
+		return
+	}
+
+	version, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+	if err != nil {
+		http.Error(w, "If-Match header must contain a version: "+ifMatch, http.StatusBadRequest)
+		return
+	}
+
+	var dataList []map[string]interface{}
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+
+	if err := dec.Decode(&dataList); err != nil {
+		http.Error(w, "Could not decode request body as list of nodes or edges: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if len(dataList) != 1 {
+		http.Error(w, "Conditional updates need exactly one node or edge", http.StatusBadRequest)
+		return
+	}
+
+	node := data.NewGraphNodeFromMap(inputAttrs(dataList[0]))
+
+	if err := checkAttributeValueSize(node); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if resources[1] == "n" {
+		removeDerivedAttributes(node)
+
+		if err = api.GM.UpdateNodeIfVersion(resources[0], node, version); err == nil {
+			version, err = api.GM.NodeVersion(resources[0], node.Key(), node.Kind())
+		}
+
+	} else if resources[1] == "e" {
+
+		if err = api.GM.StoreEdgeIfVersion(resources[0], data.NewGraphEdgeFromNode(node), version); err == nil {
+			version, err = api.GM.EdgeVersion(resources[0], node.Key(), node.Kind())
+		}
+
+	} else {
+		http.Error(w, "Entity type must be n (nodes) or e (edges)", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		status := http.StatusInternalServerError
 
-	return fmt.Sprintf("%v", c1[data.NodeKey]) < fmt.Sprintf("%v", c2[data.NodeKey])
+		if gerr, ok := err.(*util.GraphError); ok && gerr.Type == util.ErrVersionConflict {
+			status = http.StatusPreconditionFailed
+		} else if ok && (gerr.Type == util.ErrInvalidData || gerr.Type == util.ErrSchemaViolation) {
+			status = http.StatusBadRequest
+		}
+
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	setETag(w, version)
+}
+
+/*
+setETag sets the entity tag of a response to a given node or edge version.
+*/
+func setETag(w http.ResponseWriter, version uint64) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%v"`, version))
 }
 
-func (c traversalResultComparator) Swap(i, j int) {
-	c.Data[0][i], c.Data[0][j] = c.Data[0][j], c.Data[0][i]
-	c.Data[1][i], c.Data[1][j] = c.Data[1][j], c.Data[1][i]
+/*
+handleGraphRequest handles a graph query REST call. If multistatus is allowed
+the request can ask for a multistatus response with the multistatus parameter.
+*/
+func (ge *graphEndpoint) handleGraphRequest(w http.ResponseWriter, r *http.Request, resources []string,
+	allowMultiStatus bool,
+	transFuncNode func(trans graph.Trans, part string, node data.Node) error,
+	transFuncEdge func(trans graph.Trans, part string, edge data.Edge) error) {
+
+	var nDataList []map[string]interface{}
+	var eDataList []map[string]interface{}
+
+	// Check parameters
+
+	if !checkResources(w, resources, 1, 2, "Need a partition; optional entity type (n or e)") ||
+		!checkWritable(w, resources[0]) {
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+
+	if len(resources) == 1 {
+
+		// No explicit type given - expecting a graph
+
+		gdata := make(map[string][]map[string]interface{})
+
+		if err := dec.Decode(&gdata); err != nil {
+			http.Error(w, "Could not decode request body as object with list of nodes and/or edges: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		nDataList = gdata["nodes"]
+		eDataList = gdata["edges"]
+
+	} else if resources[1] == "n" {
+
+		nDataList = make([]map[string]interface{}, 1)
+
+		if err := dec.Decode(&nDataList); err != nil {
+			http.Error(w, "Could not decode request body as list of nodes: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if resources[1] == "e" {
+
+		eDataList = make([]map[string]interface{}, 1)
+
+		if err := dec.Decode(&eDataList); err != nil {
+			http.Error(w, "Could not decode request body as list of edges: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if allowMultiStatus && stringutil.IsTrueValue(r.URL.Query().Get("multistatus")) {
+		ge.handleMultiStatusRequest(w, resources[0], nDataList, eDataList, transFuncNode, transFuncEdge)
+		return
+	}
+
+	// Create a transaction
+
+	trans := graph.NewGraphTrans(api.GM)
+
+	if nDataList != nil {
+
+		// Store nodes in transaction
+
+		for _, ndata := range nDataList {
+			node := data.NewGraphNodeFromMap(inputAttrs(ndata))
+
+			if err := transFuncNode(trans, resources[0], node); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if eDataList != nil {
+
+		// Store edges in transaction
+
+		for _, edata := range eDataList {
+			edge := data.NewGraphEdgeFromNode(data.NewGraphNodeFromMap(inputAttrs(edata)))
+
+			if err := transFuncEdge(trans, resources[0], edge); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	// Commit transaction
+
+	if err := trans.Commit(); err != nil {
+		http.Error(w, err.Error(), commitErrorStatus(err))
+		return
+	}
+}
+
+/*
+commitErrorStatus returns the status code for an error of a transaction
+commit. Data which does not match a node schema is a bad request.
+*/
+func commitErrorStatus(err error) int {
+	if gerr, ok := err.(*util.GraphError); ok && gerr.Type == util.ErrSchemaViolation {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+/*
+handleMultiStatusRequest stores each given node and edge in its own transaction.
+Failing items do not affect other items. The response contains a status code
+and a message for each item (nodes first then edges in request order).
+*/
+func (ge *graphEndpoint) handleMultiStatusRequest(w http.ResponseWriter, part string,
+	nDataList []map[string]interface{}, eDataList []map[string]interface{},
+	transFuncNode func(trans graph.Trans, part string, node data.Node) error,
+	transFuncEdge func(trans graph.Trans, part string, edge data.Edge) error) {
+
+	res := make([]map[string]interface{}, 0, len(nDataList)+len(eDataList))
+
+	// runItem runs a single item in its own transaction and records the result
+
+	runItem := func(entityType string, item data.Node, transFunc func(trans graph.Trans) error) {
+		status := http.StatusOK
+		msg := ""
+
+		trans := graph.NewGraphTrans(api.GM)
+
+		if err := transFunc(trans); err != nil {
+			status = http.StatusBadRequest
+			msg = err.Error()
+		} else if err := trans.Commit(); err != nil {
+			status = commitErrorStatus(err)
+			msg = err.Error()
+		}
+
+		res = append(res, map[string]interface{}{
+			"entity_type": entityType,
+			data.NodeKey:  item.Key(),
+			data.NodeKind: item.Kind(),
+			"status":      status,
+			"message":     msg,
+		})
+	}
+
+	for _, ndata := range nDataList {
+		node := data.NewGraphNodeFromMap(inputAttrs(ndata))
+
+		runItem("n", node, func(trans graph.Trans) error {
+			return transFuncNode(trans, part, node)
+		})
+	}
+
+	for _, edata := range eDataList {
+		edge := data.NewGraphEdgeFromNode(data.NewGraphNodeFromMap(inputAttrs(edata)))
+
+		runItem("e", edge, func(trans graph.Trans) error {
+			return transFuncEdge(trans, part, edge)
+		})
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+
+	json.NewEncoder(w).Encode(res)
+}
+
+/*
+deleteByQueryRequest is a request to delete all nodes which are selected by
+an EQL query.
+*/
+type deleteByQueryRequest struct {
+	Query   string `json:"query"`   // Query which selects the nodes
+	Confirm bool   `json:"confirm"` // Flag which confirms the delete
+	Force   bool   `json:"force"`   // Flag which allows deleting all nodes of a kind
+}
+
+/*
+handleDeleteByQuery handles a REST call to delete all nodes which are selected
+by an EQL query. The primary nodes of the result rows are removed (together
+with their edges and all nodes which are connected via cascading edges) in a
+single transaction. The request must be confirmed and deleting all nodes of a
+kind needs an additional force flag. The response contains the number of
+removed nodes.
+*/
+func (ge *graphEndpoint) handleDeleteByQuery(w http.ResponseWriter, r *http.Request, part string) {
+	var req deleteByQueryRequest
+
+	if !checkWritable(w, part) {
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Could not decode request body: "+err.Error(), http.StatusBadRequest)
+		return
+	} else if req.Query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	} else if !req.Confirm {
+		http.Error(w, "Deleting nodes by query must be confirmed (confirm flag)", http.StatusBadRequest)
+		return
+	}
+
+	res, err := eql.RunQueryContext(r.Context(), stringutil.CreateDisplayString(part)+" query",
+		part, req.Query, api.GM)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer res.Close()
+
+	// Collect the distinct primary nodes of the result
+
+	var keys, kinds []string
+
+	selected := make(map[string]bool)
+	kindCounts := make(map[string]uint64)
+
+	if res.RowCount() > 0 {
+		sres := &APISearchResult{res, nil}
+
+		col, err := sres.GetPrimaryNodeColumn()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for i := 0; i < res.RowCount(); i++ {
+			src := strings.Split(res.RowSource(i)[col], ":")
+
+			if id := src[1] + "#" + src[2]; !selected[id] {
+				selected[id] = true
+				keys = append(keys, src[2])
+				kinds = append(kinds, src[1])
+				kindCounts[src[1]]++
+			}
+		}
+	}
+
+	// Refuse to delete all nodes of a kind unless forced
+
+	if !req.Force {
+
+		for kind, count := range kindCounts {
+			total := 0
+
+			it, err := api.GM.NodeKeyIterator(part, kind)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			for it != nil && it.HasNext() {
+				it.Next()
+				total++
+			}
+
+			if uint64(total) <= count {
+				http.Error(w, fmt.Sprintf("Query selects all nodes of kind %v - use the force flag to delete them",
+					kind), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	trans := graph.NewGraphTrans(api.GM)
+
+	for i, key := range keys {
+		if err := trans.RemoveNode(part, key, kinds[i]); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := trans.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": len(keys),
+	})
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (ge *graphEndpoint) SwaggerDefs(s map[string]interface{}) {
+
+	partitionParams := []map[string]interface{}{
+		{
+			"name":        "partition",
+			"in":          "path",
+			"description": "Partition to select.",
+			"required":    true,
+			"type":        "string",
+		},
+	}
+
+	entityParams := []map[string]interface{}{
+		{
+			"name": "entity_type",
+			"in":   "path",
+			"description": "Datastore entity type which should selected. " +
+				"Either n for nodes or e for edges.",
+			"required": true,
+			"type":     "string",
+		},
+	}
+
+	defaultParams := []map[string]interface{}{
+		{
+			"name":        "kind",
+			"in":          "path",
+			"description": "Node or edge kind to be queried.",
+			"required":    true,
+			"type":        "string",
+		},
+	}
+	defaultParams = append(defaultParams, partitionParams...)
+	defaultParams = append(defaultParams, entityParams...)
+
+	optionalQueryParams := []map[string]interface{}{
+		{
+			"name":        "limit",
+			"in":          "query",
+			"description": "How many list items to return.",
+			"required":    false,
+			"type":        "integer",
+		},
+		{
+			"name":        "offset",
+			"in":          "query",
+			"description": "Offset in the dataset.",
+			"required":    false,
+			"type":        "integer",
+		},
+		{
+			"name": "after",
+			"in":   "query",
+			"description": "Cursor of the last node of the previous page (an empty value requests the first page). " +
+				"The cursor of the next page is returned in the X-Next-Cursor header if more nodes follow. " +
+				"Cannot be used together with offset, sort or filter.",
+			"required": false,
+			"type":     "string",
+		},
+		{
+			"name":        "sort",
+			"in":          "query",
+			"description": "Attribute to sort the list by (sorting is done before applying offset and limit).",
+			"required":    false,
+			"type":        "string",
+		},
+		{
+			"name":        "dir",
+			"in":          "query",
+			"description": "Sort direction: asc (default) or desc.",
+			"required":    false,
+			"type":        "string",
+		},
+		{
+			"name":        "sortby",
+			"in":          "query",
+			"description": "Alternative name for the sort parameter (cannot be used together with sort). Nodes with equal values are ordered by key.",
+			"required":    false,
+			"type":        "string",
+		},
+		{
+			"name":        "sortdir",
+			"in":          "query",
+			"description": "Alternative name for the dir parameter (cannot be used together with dir).",
+			"required":    false,
+			"type":        "string",
+		},
+		{
+			"name": "filter",
+			"in":   "query",
+			"description": "Filter for the list in the form <attr>:<op>:<value> where op is one of " +
+				"eq, neq, lt, lte, gt, gte or contains (filtering is done before applying offset and limit). " +
+				"The parameter can be given multiple times - all filters must match.",
+			"required":         false,
+			"type":             "array",
+			"items":            map[string]interface{}{"type": "string"},
+			"collectionFormat": "multi",
+		},
+		{
+			"name": "key",
+			"in":   "query",
+			"description": "Key of a single node or edge to be queried. " +
+				"Alternative to giving a (long) key as part of the path.",
+			"required": false,
+			"type":     "string",
+		},
+	}
+
+	keyParam := []map[string]interface{}{
+		{
+			"name":        "key",
+			"in":          "path",
+			"description": "Node or edge key to be queried.",
+			"required":    true,
+			"type":        "string",
+		},
+	}
+
+	travParam := []map[string]interface{}{
+		{
+			"name":        "traversal_spec",
+			"in":          "path",
+			"description": "Traversal to be followed from a single node.",
+			"required":    true,
+			"type":        "string",
+		},
+		{
+			"name": "direction",
+			"in":   "query",
+			"description": "Only follow outgoing (out) or incoming (in) edges. An edge is outgoing " +
+				"if the start node is its first end. If both ends of an edge have the same kind and role " +
+				"the direction is the only way to tell them apart. Edges which loop back to the start " +
+				"node are followed in both directions.",
+			"required": false,
+			"type":     "string",
+		},
+		{
+			"name": "roundtrip",
+			"in":   "query",
+			"description": "Follow the traversal spec and then the inverse spec back to nodes of the " +
+				"start node's kind (e.g. from an author to all authors who wrote a song together with it). " +
+				"Each node is returned once with the edge of the return hop. The start node is never returned.",
+			"required": false,
+			"type":     "boolean",
+		},
+		{
+			"name": "edgefilter",
+			"in":   "query",
+			"description": "Only follow edges which match a filter of the form <attr>:<op>:<value> where op " +
+				"is one of eq, neq, lt, lte, gt, gte or contains. The parameter can be given multiple times - " +
+				"all filters must match. Each node is returned once with the first matching edge.",
+			"required":         false,
+			"type":             "array",
+			"items":            map[string]interface{}{"type": "string"},
+			"collectionFormat": "multi",
+		},
+	}
+
+	graphPost := []map[string]interface{}{
+		{
+			"name":        "entities",
+			"in":          "body",
+			"description": "Nodes and Edges which should be stored",
+			"required":    true,
+			"schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"nodes": map[string]interface{}{
+						"description": "List of nodes to be inserted / updated.",
+						"type":        "array",
+						"items": map[string]interface{}{
+							"$ref": "#/definitions/Node",
+						},
+					},
+					"edges": map[string]interface{}{
+						"description": "List of edges to be inserted / updated.",
+						"type":        "array",
+						"items": map[string]interface{}{
+							"$ref": "#/definitions/Edge",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	entitiesPost := []map[string]interface{}{
+		{
+			"name":        "entities",
+			"in":          "body",
+			"description": "Nodes or Edges which should be stored",
+			"required":    true,
+			"schema": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"description": "Node or edge to be inserted / updated.",
+					"type":        "object",
+				},
+			},
+		},
+	}
+
+	multiStatusParams := []map[string]interface{}{
+		{
+			"name": "multistatus",
+			"in":   "query",
+			"description": "Store each node and edge in its own transaction and " +
+				"return a status for each item instead of failing the whole request.",
+			"required": false,
+			"type":     "boolean",
+		},
+	}
+
+	conditionalParams := []map[string]interface{}{
+		{
+			"name": "If-Match",
+			"in":   "header",
+			"description": "Version (entity tag) which a single node or edge must have " +
+				"to be updated. The update fails with 412 if the stored version differs.",
+			"required": false,
+			"type":     "string",
+		},
+	}
+
+	notModifiedResponse := map[string]interface{}{
+		"description": "The data did not change since it was last requested (only if the " +
+			"If-None-Match header contains the entity tag of the data).",
+	}
+
+	multiStatusResponse := map[string]interface{}{
+		"description": "Status of each stored item (only if multistatus was requested).",
+		"schema": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"description": "Status code and message of a single node or edge.",
+				"type":        "object",
+			},
+		},
+	}
+
+	defaultError := map[string]interface{}{
+		"description": "Error response",
+		"schema": map[string]interface{}{
+			"$ref": "#/definitions/Error",
+		},
+	}
+
+	// Add endpoint to find the shortest path between two nodes
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/path/n/{start_kind}/{start_key}/n/{target_kind}/{target_key}"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary": "The graph endpoint can find the shortest path between two nodes.",
+			"description": "GET requests can be used to find the shortest path between two nodes. " +
+				"The path is found with a breadth-first search which visits every node at most once or " +
+				"with Dijkstra's algorithm if a weight attribute is given.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append([]map[string]interface{}{
+				{
+					"name":        "start_kind",
+					"in":          "path",
+					"description": "Node kind of the start node.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "start_key",
+					"in":          "path",
+					"description": "Node key of the start node.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "target_kind",
+					"in":          "path",
+					"description": "Node kind of the target node.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "target_key",
+					"in":          "path",
+					"description": "Node key of the target node.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "maxdepth",
+					"in":          "query",
+					"description": "Maximum number of traversal steps (the default is the configured maximum).",
+					"required":    false,
+					"type":        "integer",
+				},
+				{
+					"name":        "spec",
+					"in":          "query",
+					"description": "Traversal spec which is used for each step (the default is :::).",
+					"required":    false,
+					"type":        "string",
+				},
+				{
+					"name": "weight",
+					"in":   "query",
+					"description": "Numeric edge attribute which contains the weight of an edge. If given the path " +
+						"with the lowest total weight is found (cannot be used with maxdepth).",
+					"required": false,
+					"type":     "string",
+				},
+			}, partitionParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The return data are two lists containing the nodes and edges along the path. " +
+						"Both lists are empty if the target node cannot be reached. Weighted paths are followed " +
+						"by the total weight of the path.",
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+							},
+						},
+					},
+				},
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to preview a cascading delete
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/n/{kind}/{key}/"+GraphCascadePreviewResource] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary": "The graph endpoint can preview a cascading delete of a node.",
+			"description": "GET requests can be used to find all nodes and edges which would be removed " +
+				"together with a node. Nothing is removed.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append([]map[string]interface{}{
+				{
+					"name":        "kind",
+					"in":          "path",
+					"description": "Node kind of the node.",
+					"required":    true,
+					"type":        "string",
+				},
+			}, keyParam...), partitionParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The return data are two lists containing the nodes and edges which would be removed. " +
+						"The nodes include the requested node. Nodes and edges contain only key, kind and edge end attributes.",
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+							},
+						},
+					},
+				},
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to count the edges of a node
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/n/{kind}/{key}/"+GraphDegreeResource] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary": "The graph endpoint can count the edges of a node.",
+			"description": "GET requests can be used to count the incoming and outgoing edges of a node. " +
+				"Connected nodes are not fetched.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append([]map[string]interface{}{
+				{
+					"name":        "kind",
+					"in":          "path",
+					"description": "Node kind of the node.",
+					"required":    true,
+					"type":        "string",
+				},
+			}, keyParam...), partitionParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The return data contains the in-degree, out-degree and total degree of the node " +
+						"and the same counts for each edge kind. An edge which connects a node with itself " +
+						"is counted as incoming and outgoing edge.",
+					"schema": map[string]interface{}{
+						"type": "object",
+					},
+				},
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to delete all nodes which are selected by a query
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/"+GraphDeleteResource] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary": "Nodes which are selected by an EQL query can be deleted by using POST requests.",
+			"description": "The primary nodes of all result rows of the query are removed in a single " +
+				"transaction. Edges and nodes which are connected via cascading edges are removed as well. " +
+				"The request must be confirmed. Queries which select all nodes of a kind need the force flag.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append([]map[string]interface{}{
+				{
+					"name":        "request",
+					"in":          "body",
+					"description": "Query which selects the nodes and flags of the request",
+					"required":    true,
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"query": map[string]interface{}{
+								"description": "EQL query which selects the nodes.",
+								"type":        "string",
+							},
+							"confirm": map[string]interface{}{
+								"description": "Flag which confirms the delete (must be true).",
+								"type":        "boolean",
+							},
+							"force": map[string]interface{}{
+								"description": "Flag which allows deleting all nodes of a kind.",
+								"type":        "boolean",
+							},
+						},
+					},
+				},
+			}, partitionParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Number of deleted nodes (excluding nodes which were removed by cascading edges).",
+					"schema": map[string]interface{}{
+						"type": "object",
+					},
+				},
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to insert a graph with nodes and edges
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary": "Data can be send by using POST requests.",
+			"description": "A whole graph can be send. " +
+				"POST will store data in the datastore and always overwrite any existing data.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(partitionParams, graphPost...), multiStatusParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is created.",
+				},
+				"207":     multiStatusResponse,
+				"default": defaultError,
+			},
+		},
+		"put": map[string]interface{}{
+			"summary": "Data can be send by using PUT requests.",
+			"description": "A whole graph can be send. " +
+				"PUT will store data in the datastore and update existing data.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(partitionParams, graphPost...), multiStatusParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is created.",
+				},
+				"207":     multiStatusResponse,
+				"default": defaultError,
+			},
+		},
+		"patch": map[string]interface{}{
+			"summary": "Data can be send by using PATCH requests.",
+			"description": "A whole graph can be send. " +
+				"PATCH will only update the given attributes of existing data. " +
+				"Nodes and edges which do not exist are not created.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(partitionParams, graphPost...), multiStatusParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is updated.",
+				},
+				"207":     multiStatusResponse,
+				"default": defaultError,
+			},
+		},
+		"delete": map[string]interface{}{
+			"summary": "Data can be send by using DELETE requests.",
+			"description": "A whole graph can be send. " +
+				"DELETE will delete data in the datastore - only key and kind are required.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(partitionParams, graphPost...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is created.",
+				},
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to insert nodes / edges
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary": "Data can be send by using POST requests.",
+			"description": "A list of nodes / edges can be send. " +
+				"POST will store data in the datastore and always overwrite any existing data.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(append(partitionParams, entityParams...), entitiesPost...), multiStatusParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is created.",
+				},
+				"207":     multiStatusResponse,
+				"default": defaultError,
+			},
+		},
+		"put": map[string]interface{}{
+			"summary": "Data can be send by using PUT requests.",
+			"description": "A list of nodes / edges can be send. " +
+				"PUT will store data in the datastore and update existing data.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(append(append(partitionParams, entityParams...), entitiesPost...),
+				multiStatusParams...), conditionalParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is created.",
+				},
+				"207": multiStatusResponse,
+				"412": map[string]interface{}{
+					"description": "The stored version does not match the If-Match header.",
+				},
+				"default": defaultError,
+			},
+		},
+		"patch": map[string]interface{}{
+			"summary": "Data can be send by using PATCH requests.",
+			"description": "A list of nodes / edges can be send. " +
+				"PATCH will only update the given attributes of existing data. " +
+				"Nodes and edges which do not exist are not created.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(append(partitionParams, entityParams...), entitiesPost...), multiStatusParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is updated.",
+				},
+				"207":     multiStatusResponse,
+				"default": defaultError,
+			},
+		},
+		"delete": map[string]interface{}{
+			"summary": "Data can be send by using DELETE requests.",
+			"description": "A list of nodes / edges can be send. " +
+				"DELETE will delete data in the datastore - only key and kind are required.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(partitionParams, entityParams...), entitiesPost...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "No data is returned when data is created.",
+				},
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to query nodes for a specific node kind
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}/{kind}"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary": "The graph endpoint is the main entry point to request data.",
+			"description": "GET requests can be used to query a series of nodes. " +
+				"The X-Total-Count header contains the total number of nodes which were found. " +
+				"The X-Next-Cursor header contains the cursor for the next page if the after parameter is used. " +
+				"The nodes are returned as CSV if the Accept header asks for text/csv.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+				"text/csv",
+			},
+			"parameters": append(defaultParams, optionalQueryParams...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The return data is a list of objects",
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+						},
+					},
+				},
+				"204": map[string]interface{}{
+					"description": "No nodes were found (only if configured)",
+				},
+				"304":     notModifiedResponse,
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to query/create a specific node
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}/{kind}/{key}"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary": "The graph endpoint is the main entry point to request data.",
+			"description": "GET requests can be used to query a single node. " +
+				"The ETag header contains the version of the node. " +
+				"The node is returned as CSV if the Accept header asks for text/csv.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+				"text/csv",
+			},
+			"parameters": append(defaultParams, keyParam...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The return data is a single object",
+					"schema": map[string]interface{}{
+						"type": "object",
+					},
+				},
+				"304":     notModifiedResponse,
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add endpoint to traverse from a single node
+
+	s["paths"].(map[string]interface{})["/v1/graph/{partition}/{entity_type}/{kind}/{key}/{traversal_spec}"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "The graph endpoint is the main entry point to request data.",
+			"description": "GET requests can be used to query a single node and then traverse to its neighbours.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": append(append(append(defaultParams, keyParam...), travParam...),
+				optionalQueryParams[:2]...),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The return data are two lists containing traversed nodes and edges. " +
+						"Limit and offset are applied in traversal order before the result is sorted. " +
+						"The X-Total-Count header is not set.",
+					"schema": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+							},
+						},
+					},
+				},
+				"304":     notModifiedResponse,
+				"default": defaultError,
+			},
+		},
+	}
+
+	// Add node and edge objects to definition
+
+	nodeProperties := map[string]interface{}{
+		data.NodeKey: map[string]interface{}{
+			"description": "Unique key of the node or edge within its kind.",
+			"type":        "string",
+		},
+		data.NodeKind: map[string]interface{}{
+			"description": "Kind of the node or edge.",
+			"type":        "string",
+		},
+	}
+
+	s["definitions"].(map[string]interface{})["Node"] = map[string]interface{}{
+		"description":          "A node with a key, a kind and any number of further attributes.",
+		"type":                 "object",
+		"required":             []string{data.NodeKey, data.NodeKind},
+		"properties":           nodeProperties,
+		"additionalProperties": true,
+	}
+
+	edgeProperties := map[string]interface{}{
+		data.NodeKey:  nodeProperties[data.NodeKey],
+		data.NodeKind: nodeProperties[data.NodeKind],
+	}
+
+	for _, end := range []string{"end1", "end2"} {
+		edgeProperties[end+"key"] = map[string]interface{}{
+			"description": "Key of the node at this end of the edge.",
+			"type":        "string",
+		}
+		edgeProperties[end+"kind"] = map[string]interface{}{
+			"description": "Kind of the node at this end of the edge.",
+			"type":        "string",
+		}
+		edgeProperties[end+"role"] = map[string]interface{}{
+			"description": "Role of the node at this end of the edge.",
+			"type":        "string",
+		}
+		edgeProperties[end+"cascading"] = map[string]interface{}{
+			"description": "Flag if deleting the node at this end of the edge deletes the node at the other end.",
+			"type":        "boolean",
+		}
+	}
+
+	s["definitions"].(map[string]interface{})["Edge"] = map[string]interface{}{
+		"description": "An edge between two nodes with a key, a kind and any number of further attributes.",
+		"type":        "object",
+		"required": []string{data.NodeKey, data.NodeKind, data.EdgeEnd1Key, data.EdgeEnd1Kind,
+			data.EdgeEnd1Role, data.EdgeEnd1Cascading, data.EdgeEnd2Key, data.EdgeEnd2Kind,
+			data.EdgeEnd2Role, data.EdgeEnd2Cascading},
+		"properties":           edgeProperties,
+		"additionalProperties": true,
+	}
+}
+
+/*
+checkAttributeValueSize checks that no attribute value of a given node exceeds
+MaxAttributeValueSize. String values are measured by their length, all other
+values by the length of their JSON representation.
+*/
+func checkAttributeValueSize(node data.Node) error {
+
+	if MaxAttributeValueSize <= 0 {
+		return nil
+	}
+
+	for attr, val := range node.Data() {
+		var size int64
+
+		if s, ok := val.(string); ok {
+			size = int64(len(s))
+		} else if b, err := json.Marshal(val); err == nil {
+			size = int64(len(b))
+		}
+
+		if size > MaxAttributeValueSize {
+			return fmt.Errorf("Value of attribute %v exceeds maximum size of %v bytes",
+				attr, MaxAttributeValueSize)
+		}
+	}
+
+	return nil
+}
+
+/*
+addDerivedAttributes computes all derived attributes of a given node. All
+expressions are evaluated on the stored attributes of the node. A derived
+attribute is omitted if its expression cannot be evaluated.
+*/
+func addDerivedAttributes(part string, node data.Node) error {
+
+	derived := DerivedAttributes[node.Kind()]
+
+	if len(derived) == 0 {
+		return nil
+	}
+
+	vals := make(map[string]interface{}, len(derived))
+
+	for attr, expr := range derived {
+
+		e, err := eql.ParseExpression("derived attribute "+attr, part, expr, api.GM)
+		if err != nil {
+			return err
+		}
+
+		if val, err := e.Eval(node); err == nil {
+			vals[attr] = val
+		}
+	}
+
+	for attr := range derived {
+		node.SetAttr(attr, vals[attr])
+	}
+
+	return nil
+}
+
+/*
+reservedAttrs are attributes which are never renamed by the attribute name
+mapping or the casing policy.
+*/
+var reservedAttrs = []string{data.NodeKey, data.NodeKind,
+	data.EdgeEnd1Key, data.EdgeEnd1Kind, data.EdgeEnd1Role, data.EdgeEnd1Cascading, data.EdgeEnd1CascadingLast,
+	data.EdgeEnd2Key, data.EdgeEnd2Kind, data.EdgeEnd2Role, data.EdgeEnd2Cascading, data.EdgeEnd2CascadingLast}
+
+/*
+outputAttrName returns the name of a stored attribute in responses.
+*/
+func outputAttrName(attr string) string {
+
+	if stringutil.IndexOf(attr, reservedAttrs) != -1 {
+		return attr
+	} else if name, ok := AttributeNameMapping[attr]; ok {
+		return name
+	} else if convert, ok := util.CaseConverters[AttributeNameCasing]; ok {
+		return convert(attr)
+	}
+
+	return attr
+}
+
+/*
+storedAttrName returns the stored name of an attribute name in a request.
+*/
+func storedAttrName(name string) string {
+
+	if stringutil.IndexOf(name, reservedAttrs) != -1 {
+		return name
+	}
+
+	for attr, mapped := range AttributeNameMapping {
+		if mapped == name {
+			return attr
+		}
+	}
+
+	if AttributeNameCasing == util.CasingCamel {
+		return util.SnakeCase(name)
+	} else if AttributeNameCasing == util.CasingSnake {
+		return util.CamelCase(name)
+	}
+
+	return name
+}
+
+/*
+outputAttrs returns the attributes of a node or edge with the attribute names
+of responses. The given map is returned if no names need to be changed.
+*/
+func outputAttrs(attrs map[string]interface{}) map[string]interface{} {
+	return renameAttrs(attrs, outputAttrName)
+}
+
+/*
+inputAttrs returns the attributes of a node or edge in a request with their
+stored names. Numbers which were decoded as json.Number are stored as int64
+if they are integers and as float64 otherwise.
+*/
+func inputAttrs(attrs map[string]interface{}) map[string]interface{} {
+	attrs = renameAttrs(attrs, storedAttrName)
+
+	for attr, val := range attrs {
+		attrs[attr] = inputValue(val)
+	}
+
+	return attrs
+}
+
+/*
+inputValue converts all json.Number values in a given attribute value into
+int64 or float64 values. Integers which do not fit into an int64 are stored
+as float64.
+*/
+func inputValue(val interface{}) interface{} {
+
+	switch v := val.(type) {
+
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+
+	case map[string]interface{}:
+		for mk, mv := range v {
+			v[mk] = inputValue(mv)
+		}
+
+	case []interface{}:
+		for i, lv := range v {
+			v[i] = inputValue(lv)
+		}
+	}
+
+	return val
+}
+
+/*
+renameAttrs renames all attributes of a node or edge with a given function.
+*/
+func renameAttrs(attrs map[string]interface{}, rename func(string) string) map[string]interface{} {
+
+	if len(AttributeNameMapping) == 0 && AttributeNameCasing == "" {
+		return attrs
+	}
+
+	ret := make(map[string]interface{}, len(attrs))
+
+	for attr, val := range attrs {
+		ret[rename(attr)] = val
+	}
+
+	return ret
+}
+
+/*
+acceptsCSV checks if a request asks for CSV output in its Accept header.
+*/
+func acceptsCSV(r *http.Request) bool {
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+
+		if strings.ToLower(mediaType) == "text/csv" {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+writeEntityList writes a list of node or edge data either as CSV or as JSON.
+*/
+func writeEntityList(w http.ResponseWriter, csvOutput bool, data []interface{}) {
+
+	if csvOutput {
+		writeCSV(w, data)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	ret := json.NewEncoder(w)
+	ret.Encode(formatOutputFloats(data))
+}
+
+/*
+writeCSV writes a list of node or edge data as CSV. The columns are the union
+of all attributes of the given data (key and kind first, all other attributes
+in alphabetical order). Nested values are JSON encoded.
+*/
+func writeCSV(w http.ResponseWriter, entities []interface{}) {
+	var cols []string
+
+	known := map[string]bool{data.NodeKey: true, data.NodeKind: true}
+
+	for _, d := range entities {
+		for attr := range d.(map[string]interface{}) {
+			if !known[attr] {
+				known[attr] = true
+				cols = append(cols, attr)
+			}
+		}
+	}
+
+	sort.Strings(cols)
+	cols = append([]string{data.NodeKey, data.NodeKind}, cols...)
+
+	w.Header().Set("content-type", "text/csv; charset=utf-8")
+
+	cw := csv.NewWriter(w)
+	cw.Write(cols)
+
+	for _, d := range formatOutputFloats(entities).([]interface{}) {
+		row := make([]string, len(cols))
+
+		for i, col := range cols {
+			row[i] = csvValue(d.(map[string]interface{})[col])
+		}
+
+		cw.Write(row)
+	}
+
+	cw.Flush()
+}
+
+/*
+csvValue converts an attribute value into a CSV cell value.
+*/
+func csvValue(val interface{}) string {
+
+	switch val.(type) {
+	case nil:
+		return ""
+	case string, bool, json.Number, int, int64, float64:
+		return fmt.Sprint(val)
+	}
+
+	if res, err := json.Marshal(val); err == nil {
+		return string(res)
+	}
+
+	return fmt.Sprint(val)
+}
+
+/*
+isKnownNodeAttr checks if a given attribute is a stored or derived attribute
+of a node kind.
+*/
+func isKnownNodeAttr(kind string, attr string) bool {
+
+	if attr == data.NodeKey || attr == data.NodeKind {
+		return true
+	}
+
+	if _, ok := DerivedAttributes[kind][attr]; ok {
+		return true
+	}
+
+	return stringutil.IndexOf(attr, api.GM.NodeAttrs(kind)) != -1
+}
+
+/*
+removeDerivedAttributes removes all derived attributes from a given node.
+Derived attributes are read-only and should never be stored.
+*/
+func removeDerivedAttributes(node data.Node) {
+	for attr := range DerivedAttributes[node.Kind()] {
+		node.SetAttr(attr, nil)
+	}
+}
+
+/*
+traversalWindow collects a page of traversal results in the order of their
+node keys. Only the entries which sort before the end of the page are kept
+so large traversals do not need to be held in memory.
+*/
+type traversalWindow struct {
+	offset  int               // Number of entries to skip
+	size    int               // Number of entries to keep (-1 for all)
+	seq     int               // Number of added entries
+	entries []*traversalEntry // Max-heap of kept entries
+}
+
+/*
+traversalEntry is a single traversal result.
+*/
+type traversalEntry struct {
+	key  string    // Key of the node
+	seq  int       // Position in the traversal result
+	node data.Node // Traversed node
+	edge data.Edge // Traversed edge
+}
+
+/*
+newTraversalWindow creates a new traversalWindow for a given offset and limit
+(-1 if not set).
+*/
+func newTraversalWindow(offset int, limit int) *traversalWindow {
+	if offset < 0 {
+		offset = 0
+	}
+
+	size := -1
+
+	if limit != -1 {
+		size = offset + limit
+	}
+
+	return &traversalWindow{offset, size, 0, nil}
+}
+
+/*
+add adds a traversal result to the window.
+*/
+func (tw *traversalWindow) add(node data.Node, edge data.Edge) {
+	entry := &traversalEntry{node.Key(), tw.seq, node, edge}
+	tw.seq++
+
+	if tw.size == -1 || len(tw.entries) < tw.size {
+		heap.Push(tw, entry)
+
+	} else if tw.size > 0 && entryBefore(entry, tw.entries[0]) {
+
+		// Replace the last entry of the page
+
+		tw.entries[0] = entry
+		heap.Fix(tw, 0)
+	}
+}
+
+/*
+page returns the sorted entries of the requested page.
+*/
+func (tw *traversalWindow) page() []*traversalEntry {
+	sort.Slice(tw.entries, func(i, j int) bool {
+		return entryBefore(tw.entries[i], tw.entries[j])
+	})
+
+	if tw.offset >= len(tw.entries) {
+		return nil
+	}
+
+	return tw.entries[tw.offset:]
+}
+
+/*
+entryBefore checks if a traversal result sorts before another. Results with
+the same node key keep their traversal order.
+*/
+func entryBefore(e1 *traversalEntry, e2 *traversalEntry) bool {
+	if e1.key != e2.key {
+		return e1.key < e2.key
+	}
+	return e1.seq < e2.seq
+}
+
+func (tw *traversalWindow) Len() int { return len(tw.entries) }
+
+func (tw *traversalWindow) Less(i, j int) bool { return entryBefore(tw.entries[j], tw.entries[i]) }
+
+func (tw *traversalWindow) Swap(i, j int) {
+	tw.entries[i], tw.entries[j] = tw.entries[j], tw.entries[i]
+}
+
+func (tw *traversalWindow) Push(x interface{}) { tw.entries = append(tw.entries, x.(*traversalEntry)) }
+
+func (tw *traversalWindow) Pop() interface{} {
+	old := tw.entries
+	entry := old[len(old)-1]
+	tw.entries = old[:len(old)-1]
+	return entry
+}
+
+/*
+nodeListComparator sorts a list of nodes by a given attribute. Numbers are
+compared numerically all other values as strings. Nodes with equal values
+are ordered by their key.
+*/
+type nodeListComparator struct {
+	Attr      string        // Attribute to sort by
+	Ascending bool          // Sort should be ascending
+	Data      []interface{} // Data to sort
+}
+
+func (c nodeListComparator) Len() int {
+	return len(c.Data)
+}
+
+func (c nodeListComparator) Less(i, j int) bool {
+	n1 := c.Data[i].(map[string]interface{})
+	n2 := c.Data[j].(map[string]interface{})
+
+	v1 := fmt.Sprint(n1[c.Attr])
+	v2 := fmt.Sprint(n2[c.Attr])
+
+	if v1 == v2 {
+		return fmt.Sprint(n1[data.NodeKey]) < fmt.Sprint(n2[data.NodeKey])
+	}
+
+	num1, err := strconv.ParseFloat(v1, 64)
+	if err == nil {
+		num2, err := strconv.ParseFloat(v2, 64)
+		if err == nil {
+			if c.Ascending {
+				return num1 < num2
+			}
+			return num1 > num2
+		}
+	}
+
+	if c.Ascending {
+		return v1 < v2
+	}
+
+	return v1 > v2
+}
+
+func (c nodeListComparator) Swap(i, j int) {
+	c.Data[i], c.Data[j] = c.Data[j], c.Data[i]
+}
+
+/*
+nodeFilter filters a list of nodes by comparing an attribute with a value.
+Numbers are compared numerically all other values as strings.
+*/
+type nodeFilter struct {
+	Attr  string // Attribute to compare
+	Op    string // Comparison operator
+	Value string // Value to compare with
+}
+
+/*
+nodeFilterOps are the known comparison operators of node filters.
+*/
+var nodeFilterOps = map[string]func(c int) bool{
+	"eq":  func(c int) bool { return c == 0 },
+	"neq": func(c int) bool { return c != 0 },
+	"lt":  func(c int) bool { return c < 0 },
+	"lte": func(c int) bool { return c <= 0 },
+	"gt":  func(c int) bool { return c > 0 },
+	"gte": func(c int) bool { return c >= 0 },
+}
+
+/*
+parseNodeFilters parses a list of filter specifications of the form
+<attr>:<op>:<value>.
+*/
+func parseNodeFilters(specs []string) ([]*nodeFilter, error) {
+	var filters []*nodeFilter
+
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 3)
+
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid filter %v - filter should be of the form <attr>:<op>:<value>", spec)
+		}
+
+		if _, ok := nodeFilterOps[parts[1]]; !ok && parts[1] != "contains" {
+			return nil, fmt.Errorf("Invalid filter %v - unknown operator %v", spec, parts[1])
+		}
+
+		filters = append(filters, &nodeFilter{storedAttrName(parts[0]), parts[1], parts[2]})
+	}
+
+	return filters, nil
+}
+
+/*
+matchNodeFilters checks if the data of a node matches all given filters.
+*/
+func matchNodeFilters(filters []*nodeFilter, nodeData map[string]interface{}) bool {
+	for _, f := range filters {
+		if !f.Match(nodeData) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+Match checks if the data of a node matches this filter. Nodes which do not
+have the filter attribute only match the neq operator.
+*/
+func (f *nodeFilter) Match(nodeData map[string]interface{}) bool {
+	val, ok := nodeData[f.Attr]
+
+	if !ok || val == nil {
+		return f.Op == "neq"
+	}
+
+	v := fmt.Sprint(val)
+
+	if f.Op == "contains" {
+		return strings.Contains(v, f.Value)
+	}
+
+	c := strings.Compare(v, f.Value)
+
+	if num1, err := strconv.ParseFloat(v, 64); err == nil {
+		if num2, err := strconv.ParseFloat(f.Value, 64); err == nil {
+			if num1 < num2 {
+				c = -1
+			} else if num1 > num2 {
+				c = 1
+			} else {
+				c = 0
+			}
+		}
+	}
+
+	return nodeFilterOps[f.Op](c)
 }
@@ -11,12 +11,19 @@
 package v1
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
 	"testing"
 
 	"devt.de/krotik/common/datautil"
 	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/eql"
 	"devt.de/krotik/eliasdb/graph"
 	"devt.de/krotik/eliasdb/graph/data"
 	"devt.de/krotik/eliasdb/hash"
@@ -103,6 +110,165 @@ func TestNestedStorage(t *testing.T) {
 		t.Error("Unexpected response:", st, res)
 		return
 	}
+
+	// Retrieve the value with a reduced output precision
+
+	OutputFloatFormat = 'f'
+	OutputFloatPrecision = 2
+
+	defer func() {
+		OutputFloatFormat = 'g'
+		OutputFloatPrecision = -1
+	}()
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Test/nestedtest", "GET", nil)
+
+	if st != "200 OK" || res != `
+{
+  "float": 3.14,
+  "int": 42,
+  "key": "nestedtest",
+  "kind": "Test",
+  "nested": {
+    "more nesting": {
+      "atom": "value42"
+    },
+    "nested_float": 1.23,
+    "nested_int": 12,
+    "nested_str": "time flies like an arrow"
+  },
+  "str": "foo bar"
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Stored values must not be affected
+
+	if f := n.Attr("float"); fmt.Sprint(f) != "3.1415926" {
+		t.Error("Unexpected stored value:", f)
+		return
+	}
+
+	OutputFloatFormat = 'g'
+	OutputFloatPrecision = 3
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Test/nestedtest", "GET", nil)
+
+	if st != "200 OK" || res != `
+{
+  "float": 3.14,
+  "int": 42,
+  "key": "nestedtest",
+  "kind": "Test",
+  "nested": {
+    "more nesting": {
+      "atom": "value42"
+    },
+    "nested_float": 1.23,
+    "nested_int": 12,
+    "nested_str": "time flies like an arrow"
+  },
+  "str": "foo bar"
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Only formats which produce valid JSON numbers can be used
+
+	for format, ok := range map[string]bool{"e": true, "f": true, "g": true, "x": false, "b": false, "": false, "fg": false} {
+		if res := ValidOutputFloatFormat(format); res != ok {
+			t.Error("Unexpected result:", format, res)
+			return
+		}
+	}
+}
+
+func TestNumberStorage(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	// Store integers which cannot be represented exactly as float64
+
+	st, _, res := sendTestRequest(queryURL+"numbers/n", "POST", []byte(`
+[{
+	"key":"numtest",
+	"kind":"Test",
+	"max":9223372036854775807,
+	"min":-9223372036854775808,
+	"unsafe":9007199254740993,
+	"whole":5.0,
+	"float":0.1,
+	"huge":1e30,
+	"nested":{
+		"list":[1, 9007199254740995, 2.5]
+	}
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	n, err := api.GM.FetchNode("numbers", "numtest", "Test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for attr, expected := range map[string]string{
+		"max":    "int64 9223372036854775807",
+		"min":    "int64 -9223372036854775808",
+		"unsafe": "int64 9007199254740993",
+		"whole":  "float64 5",
+		"float":  "float64 0.1",
+		"huge":   "float64 1e+30",
+		"nested": "map[string]interface {} map[list:[1 9007199254740995 2.5]]",
+	} {
+		if res := fmt.Sprintf("%T %v", n.Attr(attr), n.Attr(attr)); res != expected {
+			t.Error("Unexpected result for", attr, ":", res)
+			return
+		}
+	}
+
+	// Numbers are returned unchanged - whole numbers have no fraction
+
+	st, _, res = sendTestRequest(queryURL+"numbers/n/Test/numtest", "GET", nil)
+
+	if st != "200 OK" || res != `
+{
+  "float": 0.1,
+  "huge": 1e+30,
+  "key": "numtest",
+  "kind": "Test",
+  "max": 9223372036854775807,
+  "min": -9223372036854775808,
+  "nested": {
+    "list": [
+      1,
+      9007199254740995,
+      2.5
+    ]
+  },
+  "unsafe": 9007199254740993,
+  "whole": 5
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Updates preserve integers as well
+
+	st, _, res = sendTestRequest(queryURL+"numbers/n", "PUT", []byte(`
+[{ "key":"numtest", "kind":"Test", "unsafe":9007199254740997 }]
+`[1:]))
+
+	if n, _ := api.GM.FetchNode("numbers", "numtest", "Test"); st != "200 OK" ||
+		fmt.Sprintf("%T %v", n.Attr("unsafe"), n.Attr("unsafe")) != "int64 9007199254740997" {
+		t.Error("Unexpected response:", st, res, n)
+		return
+	}
 }
 
 func TestGraphQuery(t *testing.T) {
@@ -273,6 +439,188 @@ func TestGraphQuery(t *testing.T) {
 		return
 	}
 
+	// Test sorting - sorting is done before applying offset and limit
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=key&limit=2", "GET", nil)
+	if st != "200 OK" || res != `
+[
+  {
+    "key": "Aria1",
+    "kind": "Song",
+    "name": "Aria1",
+    "ranking": 8
+  },
+  {
+    "key": "Aria2",
+    "kind": "Song",
+    "name": "Aria2",
+    "ranking": 2
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=ranking&dir=desc&offset=1&limit=2", "GET", nil)
+	if st != "200 OK" || res != `
+[
+  {
+    "key": "Aria4",
+    "kind": "Song",
+    "name": "Aria4",
+    "ranking": 18
+  },
+  {
+    "key": "Aria1",
+    "kind": "Song",
+    "name": "Aria1",
+    "ranking": 8
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=ranking&dir=asc&offset=8", "GET", nil)
+	if st != "200 OK" || res != `
+[
+  {
+    "key": "MyOnlySong3",
+    "kind": "Song",
+    "name": "MyOnlySong3",
+    "ranking": 19
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=ranking&dir=up", "GET", nil)
+	if st != "400 Bad Request" || res != "Invalid parameter value: dir should be asc or desc" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=ranking&offset=700", "GET", nil)
+	if st != "500 Internal Server Error" || res != "Offset exceeds available nodes" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Nodes with equal values are ordered by key
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/Song?sortby=kind&sortdir=desc&offset=2&limit=2", "GET", nil)
+	if st != "200 OK" || h.Get(HTTPHeaderTotalCount) != "9" || res != `
+[
+  {
+    "key": "Aria3",
+    "kind": "Song",
+    "name": "Aria3",
+    "ranking": 4
+  },
+  {
+    "key": "Aria4",
+    "kind": "Song",
+    "name": "Aria4",
+    "ranking": 18
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, h.Get(HTTPHeaderTotalCount), res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sortby=foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown sort attribute foo" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sortdir=up&sortby=ranking", "GET", nil)
+	if st != "400 Bad Request" || res != "Invalid parameter value: sortdir should be asc or desc" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Both spellings of a sort parameter cannot be given together
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=ranking&sortby=key", "GET", nil)
+	if st != "400 Bad Request" || res != "Parameters sort and sortby cannot be used together" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?sort=ranking&dir=asc&sortdir=desc", "GET", nil)
+	if st != "400 Bad Request" || res != "Parameters dir and sortdir cannot be used together" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Foo?sort=ranking", "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown partition or node kind" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test filtering - filtering is done before sorting and applying offset and limit
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/Song?filter=ranking:gt:5&sort=ranking&offset=1&limit=2", "GET", nil)
+	if st != "200 OK" || h.Get(HTTPHeaderTotalCount) != "4" || res != `
+[
+  {
+    "key": "Aria1",
+    "kind": "Song",
+    "name": "Aria1",
+    "ranking": 8
+  },
+  {
+    "key": "Aria4",
+    "kind": "Song",
+    "name": "Aria4",
+    "ranking": 18
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, h.Get(HTTPHeaderTotalCount), res)
+		return
+	}
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/Song?filter=name:contains:Song&filter=ranking:lte:3", "GET", nil)
+	if st != "200 OK" || h.Get(HTTPHeaderTotalCount) != "2" {
+		t.Error("Unexpected response:", st, h.Get(HTTPHeaderTotalCount), res)
+		return
+	}
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/Song?filter=key:eq:Aria3", "GET", nil)
+	if st != "200 OK" || h.Get(HTTPHeaderTotalCount) != "1" || res != `
+[
+  {
+    "key": "Aria3",
+    "kind": "Song",
+    "name": "Aria3",
+    "ranking": 4
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, h.Get(HTTPHeaderTotalCount), res)
+		return
+	}
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/Song?filter=foo:eq:1&offset=0", "GET", nil)
+	if st != "200 OK" || h.Get(HTTPHeaderTotalCount) != "0" || res != "[]" {
+		t.Error("Unexpected response:", st, h.Get(HTTPHeaderTotalCount), res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?filter=ranking:gt", "GET", nil)
+	if st != "400 Bad Request" || res != "Invalid filter ranking:gt - filter should be of the form <attr>:<op>:<value>" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song?filter=ranking:foo:5", "GET", nil)
+	if st != "400 Bad Request" || res != "Invalid filter ranking:foo:5 - unknown operator foo" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
 	// Test error cases
 
 	msm := gmMSM.StorageManager("main"+"Song"+graph.StorageSuffixNodes,
@@ -532,27 +880,278 @@ func TestGraphQueryTraversal(t *testing.T) {
 		return
 	}
 
-	// Test error cases
-
-	st, _, res = sendTestRequest(queryURL+"/main/n/Spam/x0005/:::", "GET", nil)
-
-	if st != "400 Bad Request" ||
-		res != "Unknown partition or node kind" {
-		t.Error("Unexpected response:", st, res)
-		return
-	}
-
-	msm := gmMSM.StorageManager("main"+"Song"+graph.StorageSuffixNodes,
-		true).(*storage.MemoryStorageManager)
-
-	msm.AccessMap[2] = storage.AccessCacheAndFetchError
+	// Test limit and offset
 
-	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::", "GET", nil)
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?offset=1&limit=2", "GET", nil)
 
-	if st != "500 Internal Server Error" ||
-		res != "GraphError: Failed to access graph storage component (Slot not found (mystorage/mainSong.nodes - Location:2))" {
-		t.Error("Unexpected response:", res)
-		return
+	if st != "200 OK" || res != `
+[
+  [
+    {
+      "key": "FightSong4",
+      "kind": "Song",
+      "name": "FightSong4",
+      "ranking": 3
+    },
+    {
+      "key": "LoveSong3",
+      "kind": "Song",
+      "name": "LoveSong3",
+      "ranking": 1
+    }
+  ],
+  [
+    {
+      "end1cascading": true,
+      "end1key": "123",
+      "end1kind": "Author",
+      "end1role": "Author",
+      "end2cascading": false,
+      "end2key": "FightSong4",
+      "end2kind": "Song",
+      "end2role": "Song",
+      "key": "FightSong4",
+      "kind": "Wrote",
+      "number": 4
+    },
+    {
+      "end1cascading": true,
+      "end1key": "123",
+      "end1kind": "Author",
+      "end1role": "Author",
+      "end2cascading": false,
+      "end2key": "LoveSong3",
+      "end2kind": "Song",
+      "end2role": "Song",
+      "key": "LoveSong3",
+      "kind": "Wrote",
+      "number": 3
+    }
+  ]
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Each page is a window of the full sorted result
+
+	var fullRes [][]map[string]interface{}
+
+	_, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::", "GET", nil)
+	json.Unmarshal([]byte(res), &fullRes)
+
+	if len(fullRes[0]) < 3 {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	for i := range fullRes[0] {
+		var pageRes [][]map[string]interface{}
+
+		_, _, res = sendTestRequest(fmt.Sprintf("%v/main/n/Author/123/:::?offset=%v&limit=1", queryURL, i), "GET", nil)
+		json.Unmarshal([]byte(res), &pageRes)
+
+		if len(pageRes[0]) != 1 || pageRes[0][0]["key"] != fullRes[0][i]["key"] ||
+			pageRes[1][0]["key"] != fullRes[1][i]["key"] {
+			t.Error("Unexpected page:", i, res)
+			return
+		}
+	}
+
+	// Pages are cut after sorting even if the traversal order is different
+
+	pager := data.NewGraphNode()
+	pager.SetAttr("key", "p")
+	pager.SetAttr("kind", "Pager")
+	api.GM.StoreNode("main", pager)
+
+	for i, key := range []string{"e", "b", "d", "a", "c"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "Paged")
+		api.GM.StoreNode("main", node)
+
+		edge := data.NewGraphEdge()
+		edge.SetAttr("key", fmt.Sprint(i))
+		edge.SetAttr("kind", "Pages")
+		edge.SetAttr(data.EdgeEnd1Key, "p")
+		edge.SetAttr(data.EdgeEnd1Kind, "Pager")
+		edge.SetAttr(data.EdgeEnd1Role, "pager")
+		edge.SetAttr(data.EdgeEnd1Cascading, true)
+		edge.SetAttr(data.EdgeEnd2Key, key)
+		edge.SetAttr(data.EdgeEnd2Kind, "Paged")
+		edge.SetAttr(data.EdgeEnd2Role, "page")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+		api.GM.StoreEdge("main", edge)
+	}
+
+	pageKeys := func(offset int, limit int) string {
+		var pageRes [][]map[string]interface{}
+
+		_, _, res := sendTestRequest(fmt.Sprintf("%v/main/n/Pager/p/:::?offset=%v&limit=%v", queryURL, offset, limit), "GET", nil)
+		if err := json.Unmarshal([]byte(res), &pageRes); err != nil {
+			return res
+		}
+
+		var keys []string
+		for i, n := range pageRes[0] {
+			keys = append(keys, fmt.Sprint(n["key"], "/", pageRes[1][i]["end2key"]))
+		}
+
+		return fmt.Sprint(keys)
+	}
+
+	if res := pageKeys(0, 2); res != "[a/a b/b]" {
+		t.Error("Unexpected page:", res)
+		return
+	}
+
+	if res := pageKeys(2, 2); res != "[c/c d/d]" {
+		t.Error("Unexpected page:", res)
+		return
+	}
+
+	if res := pageKeys(4, 2); res != "[e/e]" {
+		t.Error("Unexpected page:", res)
+		return
+	}
+
+	api.GM.RemoveNode("main", "p", "Pager")
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		api.GM.RemoveNode("main", key, "Paged")
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?offset=10", "GET", nil)
+
+	if st != "200 OK" || res != `
+[
+  [],
+  []
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?limit=x", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Invalid parameter value: limit should be a positive integer number" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test direction parameter - the author is the first end of all Wrote edges
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?direction=out", "GET", nil)
+
+	var dirRes [][]interface{}
+	if err := json.Unmarshal([]byte(res), &dirRes); st != "200 OK" || err != nil ||
+		len(dirRes[0]) != 4 || len(dirRes[1]) != 4 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?direction=in", "GET", nil)
+
+	dirRes = nil
+	if err := json.Unmarshal([]byte(res), &dirRes); st != "200 OK" || err != nil ||
+		len(dirRes[0]) != 0 || len(dirRes[1]) != 0 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test round trip traversals - the other songs of the author
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song/LoveSong3/:Wrote::Author?roundtrip=true", "GET", nil)
+
+	dirRes = nil
+	if err := json.Unmarshal([]byte(res), &dirRes); st != "200 OK" || err != nil ||
+		fmt.Sprint(dirRes[0]) != "[map[key:DeadSong2 kind:Song name:DeadSong2 ranking:6] "+
+			"map[key:FightSong4 kind:Song name:FightSong4 ranking:3] "+
+			"map[key:StrangeSong1 kind:Song name:StrangeSong1 ranking:5]]" || len(dirRes[1]) != 3 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song/LoveSong3/:Wrote::Author?roundtrip=true&limit=1&offset=1", "GET", nil)
+
+	dirRes = nil
+	if err := json.Unmarshal([]byte(res), &dirRes); st != "200 OK" || err != nil ||
+		len(dirRes[0]) != 1 || len(dirRes[1]) != 1 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song/LoveSong3/:Wrote::Author?roundtrip=true&direction=in", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Direction cannot be used with round trip traversals" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?direction=up", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Invalid direction: up (must be out or in)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test edge filters - only Wrote edges with a number greater than 2 are followed
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?edgefilter=number:gt:2", "GET", nil)
+
+	dirRes = nil
+	if err := json.Unmarshal([]byte(res), &dirRes); st != "200 OK" || err != nil ||
+		fmt.Sprint(dirRes[0]) != "[map[key:FightSong4 kind:Song name:FightSong4 ranking:3] "+
+			"map[key:LoveSong3 kind:Song name:LoveSong3 ranking:1]]" || len(dirRes[1]) != 2 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?edgefilter=number:gt:2&edgefilter=kind:eq:Wrote&limit=1", "GET", nil)
+
+	dirRes = nil
+	if err := json.Unmarshal([]byte(res), &dirRes); st != "200 OK" || err != nil ||
+		fmt.Sprint(dirRes[0]) != "[map[key:FightSong4 kind:Song name:FightSong4 ranking:3]]" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::?edgefilter=number:gt", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Invalid filter number:gt - filter should be of the form <attr>:<op>:<value>" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Song/LoveSong3/:Wrote::Author?roundtrip=true&edgefilter=number:gt:2", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Edge filters cannot be used with round trip traversals" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test error cases
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Spam/x0005/:::", "GET", nil)
+
+	if st != "400 Bad Request" ||
+		res != "Unknown partition or node kind" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	msm := gmMSM.StorageManager("main"+"Song"+graph.StorageSuffixNodes,
+		true).(*storage.MemoryStorageManager)
+
+	msm.AccessMap[2] = storage.AccessCacheAndFetchError
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123/:::", "GET", nil)
+
+	if st != "500 Internal Server Error" ||
+		res != "GraphError: Failed to access graph storage component (Slot not found (mystorage/mainSong.nodes - Location:2))" {
+		t.Error("Unexpected response:", res)
+		return
 	}
 
 	delete(msm.AccessMap, 2)
@@ -857,3 +1456,1497 @@ func TestGraphOperation(t *testing.T) {
 		return
 	}
 }
+
+func TestGraphAttributeValueSize(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	MaxAttributeValueSize = 10
+	defer func() {
+		MaxAttributeValueSize = 10485760
+	}()
+
+	st, _, res := sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{
+	"key":"sizetest",
+	"kind":"SizeTest",
+	"name":"1234567890",
+	"desc":"12345678901"
+}]
+`[1:]))
+
+	if st != "400 Bad Request" ||
+		res != "Value of attribute desc exceeds maximum size of 10 bytes" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "PUT", []byte(`
+[{
+	"key":"sizetest",
+	"kind":"SizeTest",
+	"list":[1,2,3,4,5,6]
+}]
+`[1:]))
+
+	if st != "400 Bad Request" ||
+		res != "Value of attribute list exceeds maximum size of 10 bytes" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "sizetest", "SizeTest"); n != nil || err != nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{
+	"key":"sizetest",
+	"kind":"SizeTest",
+	"name":"1234567890"
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Check can be disabled
+
+	MaxAttributeValueSize = 0
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{
+	"key":"sizetest",
+	"kind":"SizeTest",
+	"desc":"12345678901"
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Deletion is not affected by the check
+
+	MaxAttributeValueSize = 1
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "DELETE", []byte(`
+[{
+	"key":"sizetest",
+	"kind":"SizeTest"
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphDerivedAttributes(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	DerivedAttributes = map[string]map[string]string{
+		"DerivedTest": {
+			"score":   "ranking * 10",
+			"invalid": "name * 10",
+		},
+	}
+	defer func() {
+		DerivedAttributes = make(map[string]map[string]string)
+	}()
+
+	// Derived attributes are not stored
+
+	st, _, res := sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{
+	"key":"derivedtest",
+	"kind":"DerivedTest",
+	"name":"foo",
+	"ranking":5,
+	"score":1
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "derivedtest", "DerivedTest"); err != nil || n.Attr("score") != nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	// Derived attributes are computed when reading - attributes whose
+	// expression cannot be evaluated are omitted
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/DerivedTest/derivedtest", "GET", nil)
+
+	if st != "200 OK" || res != `
+{
+  "key": "derivedtest",
+  "kind": "DerivedTest",
+  "name": "foo",
+  "ranking": 5,
+  "score": 50
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/DerivedTest?sort=score&dir=desc", "GET", nil)
+
+	if st != "200 OK" || res != `
+[
+  {
+    "key": "derivedtest",
+    "kind": "DerivedTest",
+    "name": "foo",
+    "ranking": 5,
+    "score": 50
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Invalid expressions are reported
+
+	DerivedAttributes["DerivedTest"]["broken"] = "ranking +"
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/DerivedTest/derivedtest", "GET", nil)
+
+	if st != "500 Internal Server Error" || res != "Parse error in derived attribute broken: Unexpected end" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	delete(DerivedAttributes["DerivedTest"], "broken")
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "DELETE", []byte(`
+[{
+	"key":"derivedtest",
+	"kind":"DerivedTest"
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphAttributeNames(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	AttributeNameMapping = map[string]string{"first_name": "givenName"}
+	AttributeNameCasing = "camel"
+	defer func() {
+		AttributeNameMapping = make(map[string]string)
+		AttributeNameCasing = ""
+	}()
+
+	// Attribute names in requests are converted back to the stored names
+
+	st, _, res := sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{
+	"key":"nametest",
+	"kind":"NameTest",
+	"givenName":"Anna",
+	"releaseDate":"2001",
+	"ranking":5
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "nametest", "NameTest"); err != nil ||
+		n.Attr("first_name") != "Anna" || n.Attr("release_date") != "2001" || n.Attr("releaseDate") != nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	// Responses use the converted names
+
+	expected := `
+{
+  "givenName": "Anna",
+  "key": "nametest",
+  "kind": "NameTest",
+  "ranking": 5,
+  "releaseDate": "2001"
+}`[1:]
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/NameTest/nametest", "GET", nil)
+
+	if st != "200 OK" || res != expected {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/NameTest", "GET", nil)
+
+	if st != "200 OK" || res != "[\n  "+strings.Replace(expected, "\n", "\n  ", -1)+"\n]" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/NameTest?filter=releaseDate:eq:2001&sort=givenName", "GET", nil)
+
+	if st != "200 OK" || res != "[\n  "+strings.Replace(expected, "\n", "\n  ", -1)+"\n]" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// A round trip does not change the stored names
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "PUT", []byte("["+expected+"]"))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if attrs := api.GM.NodeAttrs("NameTest"); fmt.Sprint(attrs) != "[first_name key kind ranking release_date]" {
+		t.Error("Unexpected result:", attrs)
+		return
+	}
+
+	// Without a policy the stored names are returned
+
+	AttributeNameMapping = make(map[string]string)
+	AttributeNameCasing = ""
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/NameTest/nametest", "GET", nil)
+
+	if st != "200 OK" || !strings.Contains(res, `"first_name": "Anna"`) || !strings.Contains(res, `"release_date": "2001"`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "DELETE", []byte(`
+[{
+	"key":"nametest",
+	"kind":"NameTest"
+}]
+`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphKeyParameter(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	MaxPathKeyLength = 2
+	defer func() {
+		MaxPathKeyLength = 1024
+	}()
+
+	st, _, res := sendTestRequest(queryURL+"/main/n/Author/123", "GET", nil)
+
+	if st != "400 Bad Request" ||
+		res != "Key in request path exceeds maximum length of 2 characters - use the key query parameter instead" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author?key=123", "GET", nil)
+
+	if st != "200 OK" || res != `
+{
+  "key": "123",
+  "kind": "Author",
+  "name": "Mike"
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/e/Wrote?key=LoveSong3", "GET", nil)
+
+	if st != "200 OK" || !strings.Contains(res, `"key": "LoveSong3"`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/:Wrote::?key=123", "GET", nil)
+
+	if st != "200 OK" || !strings.Contains(res, `"key": "LoveSong3"`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/12/:Wrote::?key=123", "GET", nil)
+
+	if st != "400 Bad Request" ||
+		res != "Key must not be given as path element and query parameter" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Check can be disabled
+
+	MaxPathKeyLength = 0
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author/123", "GET", nil)
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphEmptyListNoContent(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	// Create a node kind without any nodes
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "tmp")
+	node.SetAttr("kind", "EmptyKind")
+
+	if err := api.GM.StoreNode("main", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := api.GM.RemoveNode("main", "tmp", "EmptyKind"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	st, h, res := sendTestRequest(queryURL+"/main/n/EmptyKind", "GET", nil)
+
+	if st != "200 OK" || res != "[]" || h.Get(HTTPHeaderTotalCount) != "0" {
+		t.Error("Unexpected response:", st, res, h)
+		return
+	}
+
+	EmptyListNoContent = true
+	defer func() {
+		EmptyListNoContent = false
+	}()
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/EmptyKind", "GET", nil)
+
+	if st != "204 No Content" || res != "" || h.Get(HTTPHeaderTotalCount) != "0" {
+		t.Error("Unexpected response:", st, res, h)
+		return
+	}
+
+	st, h, res = sendTestRequest(queryURL+"/main/n/EmptyKind?sort=key", "GET", nil)
+
+	if st != "204 No Content" || res != "" || h.Get(HTTPHeaderTotalCount) != "0" {
+		t.Error("Unexpected response:", st, res, h)
+		return
+	}
+
+	// Non-empty results are not affected
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/Author", "GET", nil)
+
+	if st != "200 OK" || !strings.Contains(res, `"key": "123"`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphConditionalGet(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	for i := 0; i < 3; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprint("e", i))
+		node.SetAttr("kind", "ETagKind")
+		node.SetAttr("name", fmt.Sprint("foo", i))
+		api.GM.StoreNode("main", node)
+	}
+
+	defer func() {
+		for i := 0; i < 3; i++ {
+			api.GM.RemoveNode("main", fmt.Sprint("e", i), "ETagKind")
+		}
+	}()
+
+	// Single nodes use their version as entity tag
+
+	st, etag, res := sendConditionalGetRequest(queryURL+"/main/n/ETagKind/e1", "")
+
+	if st != "200 OK" || etag != `"1"` || res == "" {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	st, etag, res = sendConditionalGetRequest(queryURL+"/main/n/ETagKind/e1", `"1"`)
+
+	if st != "304 Not Modified" || etag != `"1"` || res != "" {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	// Node lists use a hash of the response
+
+	st, listETag, res := sendConditionalGetRequest(queryURL+"/main/n/ETagKind", "")
+
+	if st != "200 OK" || listETag == "" || res == "" {
+		t.Error("Unexpected response:", st, listETag, res)
+		return
+	}
+
+	st, etag, res = sendConditionalGetRequest(queryURL+"/main/n/ETagKind", `"abc", W/`+listETag)
+
+	if st != "304 Not Modified" || etag != listETag || res != "" {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	// Changing a node of the list changes the entity tag
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "e2")
+	node.SetAttr("kind", "ETagKind")
+	node.SetAttr("name", "bar")
+	api.GM.StoreNode("main", node)
+
+	st, etag, res = sendConditionalGetRequest(queryURL+"/main/n/ETagKind", listETag)
+
+	if st != "200 OK" || etag == listETag || !strings.Contains(res, `"bar"`) {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	st, etag, res = sendConditionalGetRequest(queryURL+"/main/n/ETagKind/e2", `"1"`)
+
+	if st != "200 OK" || etag != `"2"` || !strings.Contains(res, `"bar"`) {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	// Errors are not affected
+
+	st, etag, res = sendConditionalGetRequest(queryURL+"/main/n/ETagKind/e3", "*")
+
+	if st != "400 Bad Request" || etag != "" || res != "Unknown partition or node kind" {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+}
+
+func TestGraphCursorPaging(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	for i := 0; i < 10; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprint("c", i))
+		node.SetAttr("kind", "CursorKind")
+
+		if err := api.GM.StoreNode("main", node); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	defer func() {
+		for i := 0; i < 10; i++ {
+			api.GM.RemoveNode("main", fmt.Sprint("c", i), "CursorKind")
+		}
+	}()
+
+	getPage := func(cursor string) ([]string, string, string) {
+		st, h, res := sendTestRequest(queryURL+"/main/n/CursorKind?limit=4&after="+cursor, "GET", nil)
+
+		var nodes []map[string]interface{}
+		json.Unmarshal([]byte(res), &nodes)
+
+		var keys []string
+		for _, n := range nodes {
+			keys = append(keys, fmt.Sprint(n["key"]))
+		}
+
+		if h.Get(HTTPHeaderTotalCount) == "" {
+			st = "Missing total count"
+		}
+
+		return keys, h.Get(HTTPHeaderNextCursor), st
+	}
+
+	// Page through all nodes
+
+	var all []string
+	var cursors []string
+
+	cursor := ""
+
+	for i := 0; i < 3; i++ {
+		keys, next, st := getPage(cursor)
+
+		if st != "200 OK" || (i < 2 && (len(keys) != 4 || next == "")) || (i == 2 && (len(keys) != 2 || next != "")) {
+			t.Error("Unexpected response:", i, st, keys, next)
+			return
+		}
+
+		all = append(all, keys...)
+		cursors = append(cursors, next)
+		cursor = next
+	}
+
+	sort.Strings(all)
+
+	if res := fmt.Sprint(all); res != "[c0 c1 c2 c3 c4 c5 c6 c7 c8 c9]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Removing the nodes of the first page does not change the second page
+
+	page2, next2, _ := getPage(cursors[0])
+	page1, _, _ := getPage("")
+
+	for _, key := range page1 {
+		if _, err := api.GM.RemoveNode("main", key, "CursorKind"); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if keys, next, st := getPage(cursors[0]); st != "200 OK" || fmt.Sprint(keys) != fmt.Sprint(page2) || next != next2 {
+		t.Error("Unexpected response:", st, keys, next)
+		return
+	}
+
+	// Without a limit all following nodes are returned
+
+	st, h, res := sendTestRequest(queryURL+"/main/n/CursorKind?after="+cursors[1], "GET", nil)
+
+	if st != "200 OK" || strings.Count(res, `"key"`) != 2 || h.Get(HTTPHeaderNextCursor) != "" {
+		t.Error("Unexpected response:", st, res, h)
+		return
+	}
+
+	// Test error cases
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/CursorKind?after=!!!", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Invalid cursor" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/CursorKind?after=&offset=1", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Parameter after cannot be used together with offset, sort or filter" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"/main/n/UnknownCursorKind?after=", "GET", nil)
+
+	if st != "400 Bad Request" || res != "Unknown partition or node kind" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphCSVOutput(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	sendCSVRequest := func(url string, accept string) (string, string, string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Accept", accept)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		return resp.Status, resp.Header.Get("Content-Type"), string(body)
+	}
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "csv1")
+	node.SetAttr("kind", "CsvTest")
+	node.SetAttr("name", "foo, \"bar\"")
+	node.SetAttr("nested", map[string]interface{}{"a": []interface{}{1, "b"}})
+	api.GM.StoreNode("main", node)
+
+	node = data.NewGraphNode()
+	node.SetAttr("key", "csv2")
+	node.SetAttr("kind", "CsvTest")
+	node.SetAttr("ranking", 1.5)
+	api.GM.StoreNode("main", node)
+
+	st, ct, res := sendCSVRequest(queryURL+"/main/n/CsvTest?sort=key", "text/html, text/csv;q=0.9")
+
+	if st != "200 OK" || ct != "text/csv; charset=utf-8" || res != `
+key,kind,name,nested,ranking
+csv1,CsvTest,"foo, ""bar""","{""a"":[1,""b""]}",
+csv2,CsvTest,,,1.5
+`[1:] {
+		t.Error("Unexpected response:", st, ct, res)
+		return
+	}
+
+	st, ct, res = sendCSVRequest(queryURL+"/main/n/Author/123", "text/csv")
+
+	if st != "200 OK" || ct != "text/csv; charset=utf-8" || res != `
+key,kind,name
+123,Author,Mike
+`[1:] {
+		t.Error("Unexpected response:", st, ct, res)
+		return
+	}
+
+	// JSON is the default
+
+	st, ct, res = sendCSVRequest(queryURL+"/main/n/Author/123", "application/json")
+
+	if st != "200 OK" || ct != "application/json; charset=utf-8" || !strings.HasPrefix(res, "{") {
+		t.Error("Unexpected response:", st, ct, res)
+		return
+	}
+
+	st, ct, res = sendCSVRequest(queryURL+"/main/n/CsvTest", "")
+
+	if st != "200 OK" || ct != "application/json; charset=utf-8" || !strings.HasPrefix(res, "[") {
+		t.Error("Unexpected response:", st, ct, res)
+		return
+	}
+}
+
+func TestGraphPatch(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "patch1")
+	node1.SetAttr("kind", "PatchTest")
+	node1.SetAttr("name", "foo")
+	node1.SetAttr("ranking", 1)
+	api.GM.StoreNode("main", node1)
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "patch2")
+	node2.SetAttr("kind", "PatchTest")
+	api.GM.StoreNode("main", node2)
+
+	edge := data.NewGraphEdge()
+	edge.SetAttr("key", "patchedge")
+	edge.SetAttr("kind", "PatchEdge")
+	edge.SetAttr("name", "bar")
+	edge.SetAttr(data.EdgeEnd1Key, node1.Key())
+	edge.SetAttr(data.EdgeEnd1Kind, node1.Kind())
+	edge.SetAttr(data.EdgeEnd1Role, "node1")
+	edge.SetAttr(data.EdgeEnd1Cascading, false)
+	edge.SetAttr(data.EdgeEnd2Key, node2.Key())
+	edge.SetAttr(data.EdgeEnd2Kind, node2.Kind())
+	edge.SetAttr(data.EdgeEnd2Role, "node2")
+	edge.SetAttr(data.EdgeEnd2Cascading, false)
+	api.GM.StoreEdge("main", edge)
+
+	// Only the given attributes are updated
+
+	st, _, res := sendTestRequest(queryURL+"main", "PATCH", []byte(`
+{
+	"nodes" : [{ "key" : "patch1", "kind" : "PatchTest", "ranking" : 5 }],
+	"edges" : [{ "key" : "patchedge", "kind" : "PatchEdge", "number" : 2 }]
+}`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "patch1", "PatchTest"); n == nil ||
+		fmt.Sprint(n.Attr("name")) != "foo" || fmt.Sprint(n.Attr("ranking")) != "5" {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	if e, _ := api.GM.FetchEdge("main", "patchedge", "PatchEdge"); e == nil ||
+		fmt.Sprint(e.Attr("name")) != "bar" || fmt.Sprint(e.Attr("number")) != "2" ||
+		e.End1Key() != "patch1" || e.End2Key() != "patch2" {
+		t.Error("Unexpected edge:", e)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "PATCH", []byte(`
+[{ "key" : "patch2", "kind" : "PatchTest", "name" : "baz" }]`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "patch2", "PatchTest"); n == nil || fmt.Sprint(n.Attr("name")) != "baz" {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	// Elements which do not exist are not created
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "PATCH", []byte(`
+[{ "key" : "patch3", "kind" : "PatchTest", "name" : "baz" }]`[1:]))
+
+	if st != "400 Bad Request" || res != "Unknown node patch3 of kind PatchTest in partition main" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "patch3", "PatchTest"); n != nil {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/e", "PATCH", []byte(`
+[{ "key" : "patchedge2", "kind" : "PatchEdge", "name" : "baz" }]`[1:]))
+
+	if st != "400 Bad Request" || res != "Unknown edge patchedge2 of kind PatchEdge in partition main" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Multistatus responses are supported
+
+	st, _, res = sendTestRequest(queryURL+"main/n?multistatus=1", "PATCH", []byte(`
+[{ "key" : "patch1", "kind" : "PatchTest", "name" : "foo2" },
+ { "key" : "patch3", "kind" : "PatchTest", "name" : "baz" }]`[1:]))
+
+	if st != "207 Multi-Status" || !strings.Contains(res, `"status": 200`) ||
+		!strings.Contains(res, "Unknown node patch3 of kind PatchTest in partition main") {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphNodeSchema(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	if err := api.GM.SetNodeSchema("main", "SchemaTest", `{
+	"required" : ["name"],
+	"properties" : {
+		"name" : { "type" : "string" },
+		"ranking" : { "type" : "integer", "minimum" : 0 }
+	}
+}`); err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer api.GM.DropNodeSchema("main", "SchemaTest")
+
+	st, _, res := sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{ "key" : "schema1", "kind" : "SchemaTest", "ranking" : -1 }]`[1:]))
+
+	if st != "400 Bad Request" || res != "GraphError: Schema violation (Node schema1 of kind SchemaTest does not "+
+		"match the schema in partition main: name: required attribute is missing; ranking: must be >= 0)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{ "key" : "schema1", "kind" : "SchemaTest", "name" : "foo", "ranking" : 1 }]`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Partial updates are validated after they are merged with the stored node
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "PATCH", []byte(`
+[{ "key" : "schema1", "kind" : "SchemaTest", "ranking" : 2 }]`[1:]))
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "PATCH", []byte(`
+[{ "key" : "schema1", "kind" : "SchemaTest", "ranking" : 2.5 }]`[1:]))
+
+	if st != "400 Bad Request" || res != "GraphError: Schema violation (Node schema1 of kind SchemaTest does not "+
+		"match the schema in partition main: ranking: must be of type integer)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "schema1", "SchemaTest"); n.Attr("ranking") != int64(2) {
+		t.Error("Unexpected result:", n)
+		return
+	}
+
+	// Stores replace the stored node
+
+	st, _, res = sendTestRequest(queryURL+"main/n", "POST", []byte(`
+[{ "key" : "schema1", "kind" : "SchemaTest", "ranking" : 3 }]`[1:]))
+
+	if st != "400 Bad Request" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphShortestPath(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph + "main/path/"
+
+	pathKeys := func(res string) string {
+		var data [][]map[string]interface{}
+
+		if err := json.Unmarshal([]byte(res), &data); err != nil {
+			return err.Error()
+		}
+
+		var keys []string
+		for i, n := range data[0] {
+			if i > 0 {
+				e := data[1][i-1]
+				keys = append(keys, fmt.Sprintf("-%v-", e["kind"]))
+			}
+			keys = append(keys, fmt.Sprint(n["key"]))
+		}
+
+		return fmt.Sprint(len(data[0]), len(data[1]), keys)
+	}
+
+	st, _, res := sendTestRequest(queryURL+"n/Author/000/n/Song/LoveSong3?maxdepth=6", "GET", nil)
+	if st != "200 OK" || pathKeys(res) != "4 3 [000 -Wrote- Aria3 -Contains- Best -Contains- LoveSong3]" {
+		t.Error("Unexpected response:", st, pathKeys(res), res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/123/n/Song/LoveSong3", "GET", nil)
+	if st != "200 OK" || pathKeys(res) != "2 1 [123 -Wrote- LoveSong3]" {
+		t.Error("Unexpected response:", st, pathKeys(res), res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/123/n/Author/123", "GET", nil)
+	if st != "200 OK" || pathKeys(res) != "1 0 [123]" {
+		t.Error("Unexpected response:", st, pathKeys(res), res)
+		return
+	}
+
+	// The target cannot be reached within the given depth or with the given spec
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000/n/Song/LoveSong3?maxdepth=2", "GET", nil)
+	if st != "200 OK" || res != "[\n  [],\n  []\n]" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000/n/Song/LoveSong3?spec=:Wrote::", "GET", nil)
+	if st != "200 OK" || res != "[\n  [],\n  []\n]" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Weighted paths are followed by their total weight
+
+	weightedPath := func(res string) string {
+		var data []json.RawMessage
+
+		if err := json.Unmarshal([]byte(res), &data); err != nil || len(data) != 3 {
+			return fmt.Sprint(err, len(data))
+		}
+
+		return fmt.Sprint(pathKeys(fmt.Sprintf("[%s,%s]", data[0], data[1])), " ", string(data[2]))
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Song/LoveSong3/n/Song/DeadSong2?spec=:Wrote::&weight=number", "GET", nil)
+	if st != "200 OK" || weightedPath(res) != "3 2 [LoveSong3 -Wrote- 123 -Wrote- DeadSong2] 5" {
+		t.Error("Unexpected response:", st, weightedPath(res), res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000/n/Song/LoveSong3?spec=:Wrote::&weight=number", "GET", nil)
+	if st != "200 OK" || res != "[\n  [],\n  [],\n  0\n]" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/123/n/Song/LoveSong3?weight=number", "GET", nil)
+	if st != "400 Bad Request" || res != "GraphError: Invalid data (Edge StrangeSong1 (Contains) has no weight attribute number)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/123/n/Song/LoveSong3?weight=number&maxdepth=2", "GET", nil)
+	if st != "400 Bad Request" || res != "Maximum depth cannot be used for weighted paths" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test error cases
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000/n/Song/LoveSong3?maxdepth=11", "GET", nil)
+	if st != "400 Bad Request" || res != "Maximum depth exceeds limit of 10" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000/n/Song/LoveSong3?spec=foo", "GET", nil)
+	if st != "400 Bad Request" || res != "GraphError: Invalid data (Invalid spec: foo)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/999/n/Song/LoveSong3", "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown start node" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000/n/Song/foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown target node" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"e/Author/000/n/Song/foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Entity type must be n (nodes) for path queries" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/000", "GET", nil)
+	if st != "400 Bad Request" || res != "Need a partition, start node (n, kind and key) and target node (n, kind and key)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphCascadePreview(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph + "main/"
+
+	previewKeys := func(res string) string {
+		var data [][]map[string]interface{}
+
+		if err := json.Unmarshal([]byte(res), &data); err != nil {
+			return err.Error()
+		}
+
+		var keys []string
+		for _, n := range data[0] {
+			keys = append(keys, fmt.Sprintf("%v:%v", n["kind"], n["key"]))
+		}
+		keys = append(keys, "-")
+		for _, e := range data[1] {
+			keys = append(keys, fmt.Sprintf("%v:%v", e["kind"], e["key"]))
+		}
+
+		return fmt.Sprint(keys)
+	}
+
+	st, _, res := sendTestRequest(queryURL+"n/Author/123/"+GraphCascadePreviewResource, "GET", nil)
+	if st != "200 OK" || previewKeys(res) != "[Author:123 Song:DeadSong2 Song:FightSong4 Song:LoveSong3 Song:StrangeSong1 - "+
+		"Contains:LoveSong3 Contains:StrangeSong1 Wrote:DeadSong2 Wrote:FightSong4 Wrote:LoveSong3 Wrote:StrangeSong1]" {
+		t.Error("Unexpected response:", st, previewKeys(res), res)
+		return
+	}
+
+	// Nothing was removed
+
+	st, _, res = sendTestRequest(queryURL+"n/Song/LoveSong3", "GET", nil)
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Song/LoveSong3/"+GraphCascadePreviewResource, "GET", nil)
+	if st != "200 OK" || previewKeys(res) != "[Song:LoveSong3 - Contains:LoveSong3 Wrote:LoveSong3]" {
+		t.Error("Unexpected response:", st, previewKeys(res), res)
+		return
+	}
+
+	// Test error cases
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/999/"+GraphCascadePreviewResource, "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown partition or node kind" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"e/Wrote/LoveSong3/"+GraphCascadePreviewResource, "GET", nil)
+	if st != "400 Bad Request" || res != "Entity type must be n (nodes) when requesting a cascade preview" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphDeleteByQuery(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph + "main/" + GraphDeleteResource
+
+	for i := 0; i < 4; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprint("d", i))
+		node.SetAttr("kind", "DelKind")
+		node.SetAttr("ranking", i)
+		api.GM.StoreNode("main", node)
+	}
+
+	// The node d0 owns a child node via a cascading edge
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "c0")
+	node.SetAttr("kind", "DelChild")
+	api.GM.StoreNode("main", node)
+
+	edge := data.NewGraphEdge()
+	edge.SetAttr("key", "dc0")
+	edge.SetAttr("kind", "DelEdge")
+	edge.SetAttr(data.EdgeEnd1Key, "d0")
+	edge.SetAttr(data.EdgeEnd1Kind, "DelKind")
+	edge.SetAttr(data.EdgeEnd1Role, "owner")
+	edge.SetAttr(data.EdgeEnd1Cascading, true)
+	edge.SetAttr(data.EdgeEnd2Key, "c0")
+	edge.SetAttr(data.EdgeEnd2Kind, "DelChild")
+	edge.SetAttr(data.EdgeEnd2Role, "child")
+	edge.SetAttr(data.EdgeEnd2Cascading, false)
+	api.GM.StoreEdge("main", edge)
+
+	defer func() {
+		for i := 0; i < 4; i++ {
+			api.GM.RemoveNode("main", fmt.Sprint("d", i), "DelKind")
+		}
+	}()
+
+	// Test error cases
+
+	st, _, res := sendTestRequest(queryURL, "POST", []byte(`{"query":"get DelKind where ranking < 2"}`))
+	if st != "400 Bad Request" || res != "Deleting nodes by query must be confirmed (confirm flag)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{"confirm":true}`))
+	if st != "400 Bad Request" || res != "Missing query" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`[]`))
+	if st != "400 Bad Request" || !strings.HasPrefix(res, "Could not decode request body: ") {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{"query":"foo","confirm":true}`))
+	if st != "400 Bad Request" || !strings.Contains(res, "Invalid construct") {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{"query":"get DelKind where ranking >= 0","confirm":true}`))
+	if st != "400 Bad Request" || res != "Query selects all nodes of kind DelKind - use the force flag to delete them" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "d0", "DelKind"); n == nil {
+		t.Error("Node should not have been deleted")
+		return
+	}
+
+	// Delete nodes - connected nodes are removed via cascading edges. The
+	// spill file of the query result is removed once the nodes are deleted.
+
+	spillDir, err := ioutil.TempDir("", "deletespilltest")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(spillDir)
+
+	eql.ResultSpillRows = 1
+	eql.ResultSpillDir = spillDir
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{"query":"get DelKind where ranking < 2","confirm":true}`))
+
+	eql.ResultSpillRows = 0
+	eql.ResultSpillDir = ""
+
+	if st != "200 OK" || res != `
+{
+  "deleted": 2
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if files, err := ioutil.ReadDir(spillDir); err != nil || len(files) != 0 {
+		t.Error("Unexpected spill files:", files, err)
+		return
+	}
+
+	for _, key := range []string{"d0", "d1"} {
+		if n, _ := api.GM.FetchNode("main", key, "DelKind"); n != nil {
+			t.Error("Node should have been deleted:", key)
+			return
+		}
+	}
+
+	if n, _ := api.GM.FetchNode("main", "c0", "DelChild"); n != nil {
+		t.Error("Child node should have been deleted")
+		return
+	}
+
+	// Queries which select nothing delete nothing
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{"query":"get DelKind where ranking < 0","confirm":true}`))
+	if st != "200 OK" || res != `
+{
+  "deleted": 0
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// All nodes of a kind can be deleted with the force flag
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{"query":"get DelKind","confirm":true,"force":true}`))
+	if st != "200 OK" || res != `
+{
+  "deleted": 2
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "d3", "DelKind"); n != nil {
+		t.Error("Node should have been deleted")
+		return
+	}
+}
+
+func TestGraphDegree(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph + "main/"
+
+	st, _, res := sendTestRequest(queryURL+"n/Author/123/"+GraphDegreeResource, "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "in": 0,
+  "kinds": {
+    "Wrote": {
+      "in": 0,
+      "out": 4,
+      "total": 4
+    }
+  },
+  "out": 4,
+  "total": 4
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Song/LoveSong3/"+GraphDegreeResource, "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "in": 2,
+  "kinds": {
+    "Contains": {
+      "in": 1,
+      "out": 0,
+      "total": 1
+    },
+    "Wrote": {
+      "in": 1,
+      "out": 0,
+      "total": 1
+    }
+  },
+  "out": 0,
+  "total": 2
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"n/Spam/0001/"+GraphDegreeResource, "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "in": 0,
+  "kinds": {},
+  "out": 0,
+  "total": 0
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test error cases
+
+	st, _, res = sendTestRequest(queryURL+"n/Author/999/"+GraphDegreeResource, "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown partition or node kind" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"e/Wrote/LoveSong3/"+GraphDegreeResource, "GET", nil)
+	if st != "400 Bad Request" || res != "Entity type must be n (nodes) when requesting a degree" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphMultiStatus(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	MaxAttributeValueSize = 10
+	defer func() {
+		MaxAttributeValueSize = 10485760
+	}()
+
+	graphData := []byte(`
+{
+	"nodes" : [{
+		"key":"ms1",
+		"kind":"MSNode",
+		"name":"ok"
+	}, {
+		"key":"ms2",
+		"kind":"MSNode",
+		"name":"123456789012"
+	}],
+	"edges" : [{
+		"key":"mse1",
+		"kind":"MSEdge",
+		"end1cascading":false,
+		"end1key":"ms1",
+		"end1kind":"MSNode",
+		"end1role":"a",
+		"end2cascading":false,
+		"end2key":"ms2",
+		"end2kind":"MSNode",
+		"end2role":"b"
+	}]
+}
+`[1:])
+
+	// Default is all or nothing
+
+	st, _, res := sendTestRequest(queryURL+"main", "POST", graphData)
+
+	if st != "400 Bad Request" ||
+		res != "Value of attribute name exceeds maximum size of 10 bytes" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "ms1", "MSNode"); n != nil || err != nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	// Items are stored individually in multistatus mode
+
+	st, _, res = sendTestRequest(queryURL+"main?multistatus=true", "POST", graphData)
+
+	if st != "207 Multi-Status" || res != `
+[
+  {
+    "entity_type": "n",
+    "key": "ms1",
+    "kind": "MSNode",
+    "message": "",
+    "status": 200
+  },
+  {
+    "entity_type": "n",
+    "key": "ms2",
+    "kind": "MSNode",
+    "message": "Value of attribute name exceeds maximum size of 10 bytes",
+    "status": 400
+  },
+  {
+    "entity_type": "e",
+    "key": "mse1",
+    "kind": "MSEdge",
+    "message": "GraphError: Invalid data (Can't find edge endpoint: ms2 (MSNode))",
+    "status": 500
+  }
+]`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "ms1", "MSNode"); n == nil || err != nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n?multistatus=1", "PUT", []byte(`
+[{
+	"key":"ms2",
+	"kind":"MSNode",
+	"name":"ok"
+}]
+`[1:]))
+
+	if st != "207 Multi-Status" || !strings.Contains(res, `"status": 200`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Multistatus is not supported for deletions
+
+	st, _, res = sendTestRequest(queryURL+"main/n?multistatus=true", "DELETE", []byte(`
+[{
+	"key":"ms2",
+	"kind":"MSNode"
+}]
+`[1:]))
+
+	if st != "200 OK" || res != "" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphReadOnly(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "ro1")
+	node.SetAttr("kind", "ROTest")
+	api.GM.StoreNode("main", node)
+
+	api.GM.SetReadOnly("main", true)
+	defer api.GM.SetReadOnly("main", false)
+
+	nodeData := []byte(`[{ "key" : "ro1", "kind" : "ROTest", "name" : "foo" }]`)
+
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+		st, _, res := sendTestRequest(queryURL+"main/n", method, nodeData)
+
+		if st != "405 Method Not Allowed" || res != "Partition main is read-only" {
+			t.Error("Unexpected response:", method, st, res)
+			return
+		}
+	}
+
+	st, _, res := sendTestRequest("http://localhost"+TESTPORT+EndpointGraphBatch, "POST", []byte(`
+[{ "op" : "delete", "type" : "n", "partition" : "main", "data" : { "key" : "ro1", "kind" : "ROTest" } }]
+`[1:]))
+
+	if st != "405 Method Not Allowed" || res != "Partition main is read-only" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest("http://localhost"+TESTPORT+EndpointImport+"main/n/ROTest", "POST",
+		[]byte("key,name\nro2,bar\n"))
+
+	if st != "405 Method Not Allowed" || res != "Partition main is read-only" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// The node was not changed and can still be read
+
+	if n, _ := api.GM.FetchNode("main", "ro1", "ROTest"); n == nil || n.Attr("name") != nil {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/n/ROTest/ro1", "GET", nil)
+
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestGraphConditionalUpdate(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointGraph
+
+	sendConditionalRequest := func(url string, ifMatch string, content string) (string, string, string) {
+		req, _ := http.NewRequest("PUT", url, bytes.NewBufferString(content))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		defer resp.Body.Close()
+
+		res, _ := ioutil.ReadAll(resp.Body)
+
+		return resp.Status, resp.Header.Get("ETag"), strings.Trim(string(res), " \n")
+	}
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "cond1")
+	node.SetAttr("kind", "CondTest")
+	node.SetAttr("name", "foo")
+	api.GM.StoreNode("main", node)
+	defer api.GM.RemoveNode("main", "cond1", "CondTest")
+
+	st, header, res := sendTestRequest(queryURL+"main/n/CondTest/cond1", "GET", nil)
+
+	if st != "200 OK" || header.Get("ETag") != `"1"` {
+		t.Error("Unexpected response:", st, header, res)
+		return
+	}
+
+	// Two writers which read the same version - only the first one succeeds
+
+	st, etag, res := sendConditionalRequest(queryURL+"main/n", `"1"`,
+		`[{ "key" : "cond1", "kind" : "CondTest", "name" : "bar" }]`)
+
+	if st != "200 OK" || etag != `"2"` {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	st, _, res = sendConditionalRequest(queryURL+"main/n", `"1"`,
+		`[{ "key" : "cond1", "kind" : "CondTest", "name" : "baz" }]`)
+
+	if st != "412 Precondition Failed" || res != "GraphError: Version conflict "+
+		"(Expected version 1 of cond1 (CondTest) but found version 2)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, _ := api.GM.FetchNode("main", "cond1", "CondTest"); n.Attr("name") != "bar" {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	// Error cases
+
+	st, _, res = sendConditionalRequest(queryURL+"main/n", "abc", `[{ "key" : "cond1", "kind" : "CondTest" }]`)
+
+	if st != "400 Bad Request" || res != "If-Match header must contain a version: abc" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendConditionalRequest(queryURL+"main/n", `"2"`, `[{ "key" : "cond1", "kind" : "CondTest" },`+
+		`{ "key" : "cond2", "kind" : "CondTest" }]`)
+
+	if st != "400 Bad Request" || res != "Conditional updates need exactly one node or edge" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendConditionalRequest(queryURL+"main", `"2"`, `[{ "key" : "cond1", "kind" : "CondTest" }]`)
+
+	if st != "400 Bad Request" || res != "Need a partition and an entity type (n or e) for conditional updates" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
@@ -220,7 +220,7 @@ func TestGraphQLSubscription(t *testing.T) {
 
 	sm := gmMSM.StorageManager("mainAuthor.nodes", false)
 	msm := sm.(*storage.MemoryStorageManager)
-	msm.AccessMap[8] = storage.AccessCacheAndFetchSeriousError
+	msm.AccessMap[9] = storage.AccessCacheAndFetchSeriousError
 
 	err = api.GM.StoreNode("main", data.NewGraphNodeFromMap(map[string]interface{}{
 		"key":  "Hans2",
@@ -260,7 +260,7 @@ func TestGraphQLSubscription(t *testing.T) {
 		return
 	}
 
-	delete(msm.AccessMap, 8)
+	delete(msm.AccessMap, 9)
 
 	// Create a callback error
 
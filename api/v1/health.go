@@ -0,0 +1,160 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"devt.de/krotik/eliasdb/api"
+)
+
+/*
+EndpointHealth is the health endpoint URL (rooted). Handles everything under health/...
+*/
+const EndpointHealth = api.APIRoot + APIv1 + "/health/"
+
+/*
+HealthEndpointInst creates a new endpoint handler.
+*/
+func HealthEndpointInst() api.RestEndpointHandler {
+	return &healthEndpoint{}
+}
+
+/*
+Handler object for health checks.
+*/
+type healthEndpoint struct {
+	*api.DefaultEndpointHandler
+}
+
+/*
+HandleGET handles a health check REST call.
+*/
+func (he *healthEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if !checkResources(w, resources, 0, 1, "Need 'live' or 'ready' as health check") {
+		return
+	}
+
+	data := map[string]interface{}{
+		"status": "ok",
+	}
+
+	status := http.StatusOK
+
+	if len(resources) == 0 || resources[0] == "ready" {
+
+		// Readiness check - the datastore must be able to serve requests
+
+		if component, err := he.checkReady(); err != nil {
+			data["status"] = "failed"
+			data["component"] = component
+			data["error"] = err.Error()
+			status = http.StatusServiceUnavailable
+		}
+
+	} else if resources[0] != "live" {
+
+		http.Error(w, fmt.Sprintf("Unknown health check %v - should be live or ready",
+			resources[0]), http.StatusBadRequest)
+		return
+	}
+
+	// Liveness check - the server is answering requests
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	ret := json.NewEncoder(w)
+	ret.Encode(data)
+}
+
+/*
+checkReady checks that the storage of all partitions can be read. Returns the
+failing component and its error.
+*/
+func (he *healthEndpoint) checkReady() (string, error) {
+
+	if api.GM == nil {
+		return "graph", fmt.Errorf("Graph manager is not available")
+	}
+
+	for _, part := range api.GM.Partitions() {
+		if err := api.GM.CheckStorage(part); err != nil {
+			return "partition " + part, err
+		}
+	}
+
+	return "", nil
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (he *healthEndpoint) SwaggerDefs(s map[string]interface{}) {
+
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "The check was successful.",
+		},
+		"503": map[string]interface{}{
+			"description": "The check failed. The result contains the failing component and its error.",
+		},
+		"default": map[string]interface{}{
+			"description": "Error response",
+			"schema": map[string]interface{}{
+				"$ref": "#/definitions/Error",
+			},
+		},
+	}
+
+	s["paths"].(map[string]interface{})["/v1/health"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "Check if the datastore is ready to serve requests.",
+			"description": "The health endpoint checks that the storage of all partitions can be read. This check takes no write locks.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"responses": responses,
+		},
+	}
+
+	s["paths"].(map[string]interface{})["/v1/health/{check}"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "Run a liveness or readiness check.",
+			"description": "The live check only verifies that the server answers requests. The ready check verifies that the storage of all partitions can be read.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "check",
+					"in":          "path",
+					"description": "Check to run - live or ready.",
+					"required":    true,
+					"type":        "string",
+				},
+			},
+			"responses": responses,
+		},
+	}
+
+	// Add generic error object to definition
+
+	s["definitions"].(map[string]interface{})["Error"] = map[string]interface{}{
+		"description": "A human readable error mesage.",
+		"type":        "string",
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/storage"
+)
+
+func TestHealth(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointHealth
+
+	for _, check := range []string{"", "ready", "live"} {
+		st, _, res := sendTestRequest(queryURL+check, "GET", nil)
+		if st != "200 OK" || res != `
+{
+  "status": "ok"
+}`[1:] {
+			t.Error("Unexpected response:", check, st, res)
+			return
+		}
+	}
+
+	st, _, res := sendTestRequest(queryURL+"foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Unknown health check foo - should be live or ready" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"live/foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Invalid resource specification: foo" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Storage which cannot be read fails the readiness check but not the
+	// liveness check
+
+	msm := gmMSM.StorageManager("main"+"Song"+graph.StorageSuffixNodes,
+		false).(*storage.MemoryStorageManager)
+
+	loc := msm.Root(graph.RootIDNodeHTree)
+	msm.AccessMap[loc] = storage.AccessCacheAndFetchError
+	defer delete(msm.AccessMap, loc)
+
+	st, _, res = sendTestRequest(queryURL+"ready", "GET", nil)
+	if st != "503 Service Unavailable" || res != `
+{
+  "component": "partition main",
+  "error": "GraphError: Failed to access graph storage component (Slot not found (mystorage/mainSong.nodes - Location:1))",
+  "status": "failed"
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"live", "GET", nil)
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
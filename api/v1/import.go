@@ -0,0 +1,262 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"devt.de/krotik/common/stringutil"
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+)
+
+/*
+EndpointImport is the import endpoint URL (rooted). Handles everything under import/...
+*/
+const EndpointImport = api.APIRoot + APIv1 + "/import/"
+
+/*
+ImportBatchSize is the number of nodes which are stored in a single
+transaction during a CSV import.
+*/
+var ImportBatchSize = 1000
+
+/*
+ImportEndpointInst creates a new endpoint handler.
+*/
+func ImportEndpointInst() api.RestEndpointHandler {
+	return &importEndpoint{}
+}
+
+/*
+Handler object for bulk imports.
+*/
+type importEndpoint struct {
+	*api.DefaultEndpointHandler
+}
+
+/*
+importRowError describes a CSV row which could not be imported.
+*/
+type importRowError struct {
+	Row   int    `json:"row"`   // Row number in the CSV file (the header is row 1)
+	Error string `json:"error"` // Reason why the row was not imported
+}
+
+/*
+HandlePOST handles a CSV import REST call. The request body is read row by
+row and nodes are stored in batches of ImportBatchSize.
+*/
+func (ie *importEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if !checkResources(w, resources, 3, 3, "Need a partition, entity type (n) and a kind") {
+		return
+	}
+
+	part, kind := resources[0], resources[2]
+
+	if !checkWritable(w, part) {
+		return
+	}
+
+	if resources[1] != "n" {
+		http.Error(w, "Entity type must be n (nodes) for CSV imports", http.StatusBadRequest)
+		return
+	}
+
+	if ct := r.Header.Get("content-type"); !strings.HasPrefix(ct, "text/csv") {
+		http.Error(w, "Content type must be text/csv", http.StatusBadRequest)
+		return
+	}
+
+	reader := csv.NewReader(r.Body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		http.Error(w, "Could not read CSV header: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	header = append([]string(nil), header...)
+
+	if stringutil.IndexOf(data.NodeKey, header) == -1 {
+		http.Error(w, "CSV header must contain a key column", http.StatusBadRequest)
+		return
+	}
+
+	created := 0
+	failed := make([]*importRowError, 0)
+
+	// Values of attributes with a unique constraint which were used by
+	// imported rows (kind and attribute -> value -> node key)
+
+	uniqueVals := make(map[string]map[string]string)
+
+	// A rolling transaction commits itself after every ImportBatchSize nodes
+
+	trans := graph.NewRollingTrans(graph.NewGraphTrans(api.GM), ImportBatchSize,
+		api.GM, graph.NewGraphTrans)
+
+	for row := 2; ; row++ {
+
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		} else if pe, ok := err.(*csv.ParseError); ok && pe.Err == csv.ErrFieldCount {
+			failed = append(failed, &importRowError{row, fmt.Sprintf(
+				"Row has %v columns - expected %v", len(record), len(header))})
+			continue
+		} else if err != nil {
+			trans.Commit()
+			http.Error(w, fmt.Sprintf("Could not read CSV row %v: %v", row, err), http.StatusBadRequest)
+			return
+		}
+
+		node := data.NewGraphNode()
+		node.SetAttr(data.NodeKind, kind)
+
+		for i, val := range record {
+			if val != "" {
+				node.SetAttr(header[i], val)
+			}
+		}
+
+		removeDerivedAttributes(node)
+
+		if err := checkAttributeValueSize(node); err != nil {
+			failed = append(failed, &importRowError{row, err.Error()})
+			continue
+		} else if err := api.GM.ValidateNode(part, node); err != nil {
+			failed = append(failed, &importRowError{row, err.Error()})
+			continue
+		} else if err := checkImportUniqueValues(part, node, uniqueVals); err != nil {
+			failed = append(failed, &importRowError{row, err.Error()})
+			continue
+		} else if err := trans.StoreNode(part, node); err != nil {
+			failed = append(failed, &importRowError{row, err.Error()})
+			continue
+		}
+
+		created++
+	}
+
+	if err := trans.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	ret := json.NewEncoder(w)
+	ret.Encode(map[string]interface{}{
+		"created": created,
+		"failed":  failed,
+	})
+}
+
+/*
+checkImportUniqueValues checks that a node does not use a value of an attribute
+with a unique constraint which was already used by another node of the same
+import. The values of the node are added to the given map if they are not used.
+*/
+func checkImportUniqueValues(part string, node data.Node, uniqueVals map[string]map[string]string) error {
+	attrs := api.GM.UniqueConstraints(part, node.Kind())
+
+	for _, attr := range attrs {
+		if val := node.Attr(attr); val != nil {
+			if key, ok := uniqueVals[node.Kind()+"."+attr][fmt.Sprint(val)]; ok && key != node.Key() {
+				return &util.GraphError{
+					Type: util.ErrUniqueConstraint,
+					Detail: fmt.Sprintf("%v.%v value %#v of node %v is already used by node %v in partition %v",
+						node.Kind(), attr, fmt.Sprint(val), node.Key(), key, part),
+				}
+			}
+		}
+	}
+
+	for _, attr := range attrs {
+		if val := node.Attr(attr); val != nil {
+			vals, ok := uniqueVals[node.Kind()+"."+attr]
+			if !ok {
+				vals = make(map[string]string)
+				uniqueVals[node.Kind()+"."+attr] = vals
+			}
+			vals[fmt.Sprint(val)] = node.Key()
+		}
+	}
+
+	return nil
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (ie *importEndpoint) SwaggerDefs(s map[string]interface{}) {
+
+	s["paths"].(map[string]interface{})["/v1/import/{partition}/n/{kind}"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary":     "Import nodes from a CSV file.",
+			"description": "The import endpoint creates or replaces nodes from the rows of a CSV file. The header row maps columns to node attributes and must contain a key column. The kind column is optional and defaults to the kind in the path. Nodes are stored in batches - rows before an unreadable row are kept.",
+			"consumes": []string{
+				"text/csv",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "path",
+					"description": "Partition to store the nodes in.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "kind",
+					"in":          "path",
+					"description": "Default node kind of all rows.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "rows",
+					"in":          "body",
+					"description": "CSV file with a header row.",
+					"required":    true,
+					"schema": map[string]interface{}{
+						"type": "string",
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Object with the number of created nodes and a list of rows which failed validation.",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+func TestImportCSV(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointImport
+
+	sendImportRequest := func(url string, body string) (string, string) {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/csv")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		defer resp.Body.Close()
+
+		res, _ := ioutil.ReadAll(resp.Body)
+
+		return resp.Status, strings.Trim(string(res), " \n")
+	}
+
+	st, _, res := sendTestRequest(queryURL+"main/n/ImportTest", "POST", []byte("key\n1"))
+	if st != "400 Bad Request" || res != "Content type must be text/csv" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if st, res := sendImportRequest(queryURL+"main/n", ""); st != "400 Bad Request" ||
+		res != "Need a partition, entity type (n) and a kind" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if st, res := sendImportRequest(queryURL+"main/e/ImportTest", ""); st != "400 Bad Request" ||
+		res != "Entity type must be n (nodes) for CSV imports" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if st, res := sendImportRequest(queryURL+"main/n/ImportTest", ""); st != "400 Bad Request" ||
+		res != "Could not read CSV header: EOF" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if st, res := sendImportRequest(queryURL+"main/n/ImportTest", "name\nfoo"); st != "400 Bad Request" ||
+		res != "CSV header must contain a key column" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Import rows in several batches
+
+	oldBatchSize := ImportBatchSize
+	ImportBatchSize = 2
+	defer func() {
+		ImportBatchSize = oldBatchSize
+	}()
+
+	st, res = sendImportRequest(queryURL+"main/n/ImportTest", `
+key,kind,name
+1,,"foo, bar"
+2,,
+,,nokey
+3,ImportOther,other
+4,Import-Test,badkind
+5,,x,y
+6,,last`[1:])
+
+	if st != "200 OK" || res != `{"created":4,"failed":[{"row":4,"error":"GraphError: Invalid data (Node is missing a key value)"},{"row":6,"error":"GraphError: Invalid data (Node kind Import-Test is not alphanumeric - can only contain [a-zA-Z0-9_])"},{"row":7,"error":"Row has 4 columns - expected 3"}]}` {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "1", "ImportTest"); err != nil || n.Attr("name") != "foo, bar" {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "2", "ImportTest"); err != nil || n.Attr("name") != nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "3", "ImportOther"); err != nil || n.Attr("name") != "other" {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "6", "ImportTest"); err != nil || n.Attr("name") != "last" {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	// Rows before an unreadable row are stored
+
+	st, res = sendImportRequest(queryURL+"main/n/ImportTest", "key,name\n7,ok\n8,\"broken")
+
+	if st != "400 Bad Request" || !strings.HasPrefix(res, "Could not read CSV row 3: ") {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if n, err := api.GM.FetchNode("main", "7", "ImportTest"); err != nil || n == nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	for _, key := range []string{"1", "2", "6", "7"} {
+		api.GM.RemoveNode("main", key, "ImportTest")
+	}
+	api.GM.RemoveNode("main", "3", "ImportOther")
+}
+
+func TestImportCSVValidation(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointImport
+
+	sendImportRequest := func(url string, body string) (string, string) {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/csv")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		defer resp.Body.Close()
+
+		res, _ := ioutil.ReadAll(resp.Body)
+
+		return resp.Status, strings.Trim(string(res), " \n")
+	}
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "0")
+	node.SetAttr("kind", "ImportValid")
+	node.SetAttr("name", "stored")
+	node.SetAttr("email", "a@b")
+	api.GM.StoreNode("main", node)
+
+	if err := api.GM.SetNodeSchema("main", "ImportValid",
+		`{"type" : "object", "required" : ["name"], "properties" : { "name" : { "type" : "string", "minLength" : 1 }}}`); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := api.GM.CreateUniqueConstraint("main", "ImportValid", "email"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer func() {
+		api.GM.DropNodeSchema("main", "ImportValid")
+		api.GM.DropUniqueConstraint("main", "ImportValid", "email")
+		for _, key := range []string{"0", "1", "2", "5", "6"} {
+			api.GM.RemoveNode("main", key, "ImportValid")
+		}
+	}()
+
+	oldBatchSize := ImportBatchSize
+	ImportBatchSize = 2
+	defer func() {
+		ImportBatchSize = oldBatchSize
+	}()
+
+	// Invalid rows are reported per row - the other rows of their batch are stored
+
+	st, res := sendImportRequest(queryURL+"main/n/ImportValid", `
+key,name,email
+1,foo,x@y
+2,bar,
+3,,c@d
+4,baz,a@b
+5,qux,e@f
+6,quux,
+7,dup,e@f`[1:])
+
+	if st != "200 OK" || res != `{"created":4,"failed":[`+
+		`{"row":4,"error":"GraphError: Schema violation (Node 3 of kind ImportValid does not match the schema in partition main: name: required attribute is missing)"},`+
+		`{"row":5,"error":"GraphError: Unique constraint violation (ImportValid.email value \"a@b\" of node 4 is already used by node 0 in partition main)"},`+
+		`{"row":8,"error":"GraphError: Unique constraint violation (ImportValid.email value \"e@f\" of node 7 is already used by node 5 in partition main)"}]}` {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	for _, key := range []string{"1", "2", "5", "6"} {
+		if n, err := api.GM.FetchNode("main", key, "ImportValid"); err != nil || n == nil {
+			t.Error("Unexpected result:", key, n, err)
+			return
+		}
+	}
+
+	for _, key := range []string{"3", "4", "7"} {
+		if n, err := api.GM.FetchNode("main", key, "ImportValid"); err != nil || n != nil {
+			t.Error("Unexpected result:", key, n, err)
+			return
+		}
+	}
+}
@@ -13,9 +13,15 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"reflect"
+	"strings"
 
+	"devt.de/krotik/common/stringutil"
 	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/storage"
 )
 
 /*
@@ -23,6 +29,18 @@ EndpointInfoQuery is the info endpoint URL (rooted). Handles everything under in
 */
 const EndpointInfoQuery = api.APIRoot + APIv1 + "/info/"
 
+/*
+InfoKindSampleSize is the maximum number of nodes which are sampled to
+determine the value types of node attributes.
+*/
+var InfoKindSampleSize = 100
+
+/*
+InfoSchemaSampleSize is the maximum number of nodes or edges of each kind
+which are sampled to infer the schema of the datastore.
+*/
+var InfoSchemaSampleSize = 100
+
 /*
 InfoEndpointInst creates a new endpoint handler.
 */
@@ -38,15 +56,129 @@ type infoEndpoint struct {
 }
 
 /*
-HandleGET handles a info query REST call.
+HandleGET handles a info query REST call. Responses carry an entity tag and
+are only sent if the entity tag does not match the If-None-Match header of
+the request.
 */
 func (ie *infoEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+	handleConditionalGET(w, r, resources, ie.handleGET)
+}
+
+/*
+handleGET handles a info query REST call.
+*/
+func (ie *infoEndpoint) handleGET(w http.ResponseWriter, r *http.Request, resources []string) {
 
 	data := make(map[string]interface{})
 
 	if len(resources) > 0 {
 
-		if resources[0] == "kind" {
+		if resources[0] == "storage" {
+
+			// Storage statistics are requested
+
+			part := r.URL.Query().Get("partition")
+
+			if part != "" && stringutil.IndexOf(part, api.GM.Partitions()) == -1 {
+				http.Error(w, fmt.Sprintf("Partition %s does not exist", part), http.StatusBadRequest)
+				return
+			}
+
+			if len(resources) > 1 && resources[1] == "cache" {
+
+				// Cache statistics are requested
+
+				stats := make(map[string]map[string]interface{})
+
+				for p, sms := range ie.cacheStatsManagers(part) {
+					var hits, misses, evictions uint64
+
+					for _, sm := range sms {
+						cs := sm.CacheStats()
+						hits += cs.Hits()
+						misses += cs.Misses()
+						evictions += cs.Evictions()
+					}
+
+					var ratio float64
+
+					if hits+misses > 0 {
+						ratio = float64(hits) / float64(hits+misses)
+					}
+
+					stats[p] = map[string]interface{}{
+						"hits":      hits,
+						"misses":    misses,
+						"evictions": evictions,
+						"hit_ratio": ratio,
+					}
+				}
+
+				data["partition_cache"] = stats
+
+				w.Header().Set("content-type", "application/json; charset=utf-8")
+				json.NewEncoder(w).Encode(data)
+
+				return
+			}
+
+			stats, err := ie.storageStats(part)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			data["partition_storage"] = stats
+
+		} else if resources[0] == "schema" {
+
+			// Inferred schema of all node and edge kinds is requested
+
+			part := r.URL.Query().Get("partition")
+
+			if part != "" && stringutil.IndexOf(part, api.GM.Partitions()) == -1 {
+				http.Error(w, fmt.Sprintf("Partition %s does not exist", part), http.StatusBadRequest)
+				return
+			}
+
+			// Get samples parameter; the sample size can only be lowered
+
+			samples, ok := queryParamPosNum(w, r, "samples")
+			if !ok {
+				return
+			} else if samples == -1 || samples > InfoSchemaSampleSize {
+				samples = InfoSchemaSampleSize
+			}
+
+			nks := make(map[string]interface{})
+
+			for _, kind := range api.GM.NodeKinds() {
+				schema, err := ie.inferKindSchema(part, kind, false, samples)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				nks[kind] = schema
+			}
+
+			eks := make(map[string]interface{})
+
+			for _, kind := range api.GM.EdgeKinds() {
+				schema, err := ie.inferKindSchema(part, kind, true, samples)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				eks[kind] = schema
+			}
+
+			data["node_kinds"] = nks
+			data["edge_kinds"] = eks
+			data["sample_size"] = samples
+
+		} else if resources[0] == "kind" {
 
 			// Kind info is requested
 
@@ -59,19 +191,43 @@ func (ie *infoEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resour
 			ea := api.GM.EdgeAttrs(resources[1])
 
 			if len(na) == 0 && len(ea) == 0 {
-				http.Error(w, fmt.Sprint("Unknown node kind ", resources[1]), http.StatusBadRequest)
+				http.Error(w, fmt.Sprint("Unknown node kind ", resources[1]), http.StatusNotFound)
+				return
+			}
+
+			part := r.URL.Query().Get("partition")
+
+			if part != "" && stringutil.IndexOf(part, api.GM.Partitions()) == -1 {
+				http.Error(w, fmt.Sprintf("Partition %s does not exist", part), http.StatusBadRequest)
 				return
 			}
 
 			data["node_attrs"] = na
 			data["node_edges"] = api.GM.NodeEdges(resources[1])
 			data["edge_attrs"] = ea
+
+			if len(na) > 0 {
+				types, err := ie.sampleAttrTypes(part, resources[1], na)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				data["node_attr_types"] = types
+			}
 		}
 
 	} else {
 
 		// Get general information
 
+		part := r.URL.Query().Get("partition")
+
+		if part != "" && stringutil.IndexOf(part, api.GM.Partitions()) == -1 {
+			http.Error(w, fmt.Sprintf("Partition %s does not exist", part), http.StatusBadRequest)
+			return
+		}
+
 		data["partitions"] = api.GM.Partitions()
 
 		nks := api.GM.NodeKinds()
@@ -93,6 +249,15 @@ func (ie *infoEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resour
 		}
 
 		data["edge_counts"] = ecs
+
+		pncs, pecs, err := ie.partitionCounts(part, nks, eks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data["partition_node_counts"] = pncs
+		data["partition_edge_counts"] = pecs
 	}
 
 	// Write data
@@ -103,6 +268,442 @@ func (ie *infoEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resour
 	ret.Encode(data)
 }
 
+/*
+HandleDELETE handles a REST call to reset the cache statistics of the datastore.
+*/
+func (ie *infoEndpoint) HandleDELETE(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if len(resources) == 2 && resources[0] == "storage" && resources[1] == "cache" {
+
+		part := r.URL.Query().Get("partition")
+
+		if part != "" && stringutil.IndexOf(part, api.GM.Partitions()) == -1 {
+			http.Error(w, fmt.Sprintf("Partition %s does not exist", part), http.StatusBadRequest)
+			return
+		}
+
+		for _, sms := range ie.cacheStatsManagers(part) {
+			for _, sm := range sms {
+				sm.CacheStats().Reset()
+			}
+		}
+
+		return
+	}
+
+	http.Error(w, "Request had no effect", http.StatusBadRequest)
+}
+
+/*
+cacheStatsManagers returns for each partition all node and edge storages
+which can report cache statistics. Only the given partition is examined if it
+is not empty.
+*/
+func (ie *infoEndpoint) cacheStatsManagers(part string) map[string][]storage.CacheStatsManager {
+
+	ret := make(map[string][]storage.CacheStatsManager)
+
+	for _, p := range api.GM.Partitions() {
+
+		if part != "" && part != p {
+			continue
+		}
+
+		var sms []storage.CacheStatsManager
+
+		addManagers := func(kinds []string, suffixes ...string) {
+			for _, kind := range kinds {
+				for _, suffix := range suffixes {
+
+					// Do not create storages which do not exist yet
+
+					if sm, ok := api.GS.StorageManager(p+kind+suffix, false).(storage.CacheStatsManager); ok {
+						sms = append(sms, sm)
+					}
+				}
+			}
+		}
+
+		addManagers(api.GM.NodeKinds(), graph.StorageSuffixNodes, graph.StorageSuffixNodesIndex)
+		addManagers(api.GM.EdgeKinds(), graph.StorageSuffixEdges, graph.StorageSuffixEdgesIndex)
+
+		ret[p] = sms
+	}
+
+	return ret
+}
+
+/*
+partitionCounts counts the nodes and edges of each kind in each partition.
+Only the given partition is counted if it is not empty. Kinds which do not
+exist in a partition are omitted.
+*/
+func (ie *infoEndpoint) partitionCounts(part string, nks []string,
+	eks []string) (map[string]map[string]uint64, map[string]map[string]uint64, error) {
+
+	pncs := make(map[string]map[string]uint64)
+	pecs := make(map[string]map[string]uint64)
+
+	for _, p := range api.GM.Partitions() {
+
+		if part != "" && part != p {
+			continue
+		}
+
+		ncs := make(map[string]uint64)
+
+		for _, nk := range nks {
+
+			// NodeKeyIterator returns nil if the node kind does not exist
+			// in a partition
+
+			it, err := api.GM.NodeKeyIterator(p, nk)
+			if err != nil {
+				return nil, nil, err
+			} else if it == nil {
+				continue
+			}
+
+			var count uint64
+
+			for it.HasNext() {
+				if it.Next(); it.LastError != nil {
+					return nil, nil, it.LastError
+				}
+				count++
+			}
+
+			ncs[nk] = count
+		}
+
+		ecs := make(map[string]uint64)
+
+		for _, ek := range eks {
+
+			it, err := api.GM.EdgeKeyIterator(p, ek)
+			if err != nil {
+				return nil, nil, err
+			} else if it == nil {
+				continue
+			}
+
+			var count uint64
+
+			for it.HasNext() {
+				if it.Next(); it.LastError != nil {
+					return nil, nil, it.LastError
+				}
+				count++
+			}
+
+			ecs[ek] = count
+		}
+
+		pncs[p] = ncs
+		pecs[p] = ecs
+	}
+
+	return pncs, pecs, nil
+}
+
+/*
+storageStats collects free space statistics for all node and edge storages of
+each partition. Only the given partition is examined if it is not empty.
+Storages which cannot report statistics are omitted.
+*/
+func (ie *infoEndpoint) storageStats(part string) (map[string]map[string]interface{}, error) {
+
+	ret := make(map[string]map[string]interface{})
+
+	for _, p := range api.GM.Partitions() {
+
+		if part != "" && part != p {
+			continue
+		}
+
+		pstats := make(map[string]interface{})
+
+		addStats := func(kinds []string, suffixes ...string) error {
+			for _, kind := range kinds {
+				for _, suffix := range suffixes {
+
+					// Do not create storages which do not exist yet
+
+					sm, ok := api.GS.StorageManager(p+kind+suffix, false).(storage.FreeSlotStatsManager)
+					if !ok {
+						continue
+					}
+
+					stats, err := sm.FreeSlotStats()
+					if err != nil {
+						return err
+					}
+
+					pstats[kind+suffix] = map[string]interface{}{
+						"free_slots":        stats.Slots,
+						"free_bytes":        stats.Bytes,
+						"largest_free_slot": stats.LargestSlot,
+					}
+				}
+			}
+
+			return nil
+		}
+
+		if err := addStats(api.GM.NodeKinds(), graph.StorageSuffixNodes,
+			graph.StorageSuffixNodesIndex); err != nil {
+			return nil, err
+		}
+
+		if err := addStats(api.GM.EdgeKinds(), graph.StorageSuffixEdges,
+			graph.StorageSuffixEdgesIndex); err != nil {
+			return nil, err
+		}
+
+		ret[p] = pstats
+	}
+
+	return ret, nil
+}
+
+/*
+sampleAttrTypes determines an example value type for each given attribute
+of a node kind by sampling stored nodes. Only the given partition is sampled
+if it is not empty - otherwise all partitions which do not start with an _
+character are sampled.
+*/
+func (ie *infoEndpoint) sampleAttrTypes(part string, kind string, attrs []string) (map[string]string, error) {
+	types := make(map[string]string)
+	sampled := 0
+
+	for _, p := range api.GM.Partitions() {
+
+		if strings.HasPrefix(p, "_") && part != p || part != "" && part != p {
+			continue
+		}
+
+		// NodeKeyIterator returns nil if the node kind does not exist
+		// in a partition
+
+		it, err := api.GM.NodeKeyIterator(p, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		for it != nil && it.HasNext() && sampled < InfoKindSampleSize && len(types) < len(attrs) {
+
+			key := it.Next()
+
+			if it.LastError != nil {
+				return nil, it.LastError
+			}
+
+			node, err := api.GM.FetchNode(p, key, kind)
+			if err != nil {
+				return nil, err
+			} else if node == nil {
+				continue
+			}
+
+			for attr, val := range node.Data() {
+				if _, ok := types[attr]; !ok && val != nil {
+					types[attr] = attrTypeName(val)
+				}
+			}
+
+			sampled++
+		}
+	}
+
+	// Attributes which were not found in any sample have an unknown type
+
+	for _, attr := range attrs {
+		if _, ok := types[attr]; !ok {
+			types[attr] = "unknown"
+		}
+	}
+
+	return types, nil
+}
+
+/*
+inferKindSchema infers the type of each attribute of a node or edge kind by
+sampling up to a given number of stored nodes or edges. Only the given
+partition is sampled if it is not empty - otherwise all partitions which do
+not start with an _ character are sampled. Returns the attribute types and
+the number of sampled nodes or edges.
+*/
+func (ie *infoEndpoint) inferKindSchema(part string, kind string, isEdge bool,
+	samples int) (map[string]interface{}, error) {
+
+	types := make(map[string]string)
+	sampled := 0
+
+	attrs := api.GM.NodeAttrs(kind)
+	if isEdge {
+		attrs = api.GM.EdgeAttrs(kind)
+	}
+
+	for _, p := range api.GM.Partitions() {
+
+		if strings.HasPrefix(p, "_") && part != p || part != "" && part != p {
+			continue
+		}
+
+		// Key iterators are nil if the kind does not exist in a partition
+
+		var next func() (map[string]interface{}, error)
+		var hasNext func() bool
+
+		if isEdge {
+			it, err := api.GM.EdgeKeyIterator(p, kind)
+			if err != nil {
+				return nil, err
+			} else if it == nil {
+				continue
+			}
+
+			hasNext = it.HasNext
+			next = func() (map[string]interface{}, error) {
+				key := it.Next()
+				if it.LastError != nil {
+					return nil, it.LastError
+				}
+
+				edge, err := api.GM.FetchEdge(p, key, kind)
+				if err != nil || edge == nil {
+					return nil, err
+				}
+
+				return edge.Data(), nil
+			}
+
+		} else {
+			it, err := api.GM.NodeKeyIterator(p, kind)
+			if err != nil {
+				return nil, err
+			} else if it == nil {
+				continue
+			}
+
+			hasNext = it.HasNext
+			next = func() (map[string]interface{}, error) {
+				key := it.Next()
+				if it.LastError != nil {
+					return nil, it.LastError
+				}
+
+				node, err := api.GM.FetchNode(p, key, kind)
+				if err != nil || node == nil {
+					return nil, err
+				}
+
+				return node.Data(), nil
+			}
+		}
+
+		for sampled < samples && hasNext() {
+
+			itemData, err := next()
+			if err != nil {
+				return nil, err
+			} else if itemData == nil {
+				continue
+			}
+
+			for attr, val := range itemData {
+				if val != nil {
+					types[attr] = mergeAttrTypes(types[attr], inferAttrType(val))
+				}
+			}
+
+			sampled++
+		}
+	}
+
+	// Known attributes which were not found in any sample have an unknown type
+
+	for _, attr := range attrs {
+		if _, ok := types[attr]; !ok {
+			types[attr] = "unknown"
+		}
+	}
+
+	return map[string]interface{}{
+		"attrs":   types,
+		"sampled": sampled,
+	}, nil
+}
+
+/*
+inferAttrType returns the inferred type of a given attribute value. Numbers
+without a fractional part are inferred as int.
+*/
+func inferAttrType(val interface{}) string {
+
+	v := reflect.ValueOf(val)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); f == math.Trunc(f) {
+			return "int"
+		}
+		return "float"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", val)
+}
+
+/*
+mergeAttrTypes merges the inferred types of two values of the same attribute.
+Attributes with int and float values are float - all other combinations of
+different types are mixed.
+*/
+func mergeAttrTypes(t1 string, t2 string) string {
+
+	if t1 == "" || t1 == t2 {
+		return t2
+	} else if (t1 == "int" || t1 == "float") && (t2 == "int" || t2 == "float") {
+		return "float"
+	}
+
+	return "mixed"
+}
+
+/*
+attrTypeName returns the JSON type name of a given attribute value.
+*/
+func attrTypeName(val interface{}) string {
+
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", val)
+}
+
 /*
 SwaggerDefs is used to describe the endpoint in swagger.
 */
@@ -111,15 +712,122 @@ func (ie *infoEndpoint) SwaggerDefs(s map[string]interface{}) {
 	s["paths"].(map[string]interface{})["/v1/info"] = map[string]interface{}{
 		"get": map[string]interface{}{
 			"summary":     "Return general datastore information.",
-			"description": "The info endpoint returns general database information such as known node kinds, known attributes, etc.",
+			"description": "The info endpoint returns general database information such as known node kinds, known attributes, etc. Node and edge counts are also broken down by partition.",
 			"produces": []string{
 				"text/plain",
 				"application/json",
 			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "query",
+					"description": "Only count nodes and edges per kind in a partition (without the option all partitions are counted).",
+					"required":    false,
+					"type":        "string",
+				},
+			},
 			"responses": map[string]interface{}{
 				"200": map[string]interface{}{
 					"description": "A key-value map.",
 				},
+				"304": map[string]interface{}{
+					"description": "The information did not change since it was last requested.",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+
+	s["paths"].(map[string]interface{})["/v1/info/storage"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "Return free space statistics of the datastore.",
+			"description": "The info storage endpoint returns for each partition and node or edge storage the number of free slots, the number of free bytes and the size of the largest free slot. These can be used to decide when to compact the datastore.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "query",
+					"description": "Only examine the storages of a partition (without the option all partitions are examined).",
+					"required":    false,
+					"type":        "string",
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "A key-value map.",
+				},
+				"304": map[string]interface{}{
+					"description": "The information did not change since it was last requested.",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+
+	s["paths"].(map[string]interface{})["/v1/info/storage/cache"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "Return cache statistics of the datastore.",
+			"description": "The info storage cache endpoint returns for each partition the number of cache hits, cache misses and cache evictions of all node and edge storages. These can be used to size the storage cache.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "query",
+					"description": "Only examine the storages of a partition (without the option all partitions are examined).",
+					"required":    false,
+					"type":        "string",
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "A key-value map.",
+				},
+				"304": map[string]interface{}{
+					"description": "The information did not change since it was last requested.",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+		"delete": map[string]interface{}{
+			"summary":     "Reset cache statistics of the datastore.",
+			"description": "All cache counters are set to 0.",
+			"produces": []string{
+				"text/plain",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "query",
+					"description": "Only reset the counters of a partition (without the option all partitions are reset).",
+					"required":    false,
+					"type":        "string",
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Cache statistics were reset.",
+				},
 				"default": map[string]interface{}{
 					"description": "Error response",
 					"schema": map[string]interface{}{
@@ -133,7 +841,7 @@ func (ie *infoEndpoint) SwaggerDefs(s map[string]interface{}) {
 	s["paths"].(map[string]interface{})["/v1/info/kind/{kind}"] = map[string]interface{}{
 		"get": map[string]interface{}{
 			"summary":     "Return information on a given node or edge kind.",
-			"description": "The info kind endpoint returns information on a given node kind such as known attributes and edges.",
+			"description": "The info kind endpoint returns information on a given node kind such as known attributes, example value types of attributes and edges. Value types are determined by sampling stored nodes.",
 			"produces": []string{
 				"text/plain",
 				"application/json",
@@ -146,11 +854,65 @@ func (ie *infoEndpoint) SwaggerDefs(s map[string]interface{}) {
 					"required":    true,
 					"type":        "string",
 				},
+				{
+					"name":        "partition",
+					"in":          "query",
+					"description": "Only sample nodes from a partition (without the option all partitions are sampled).",
+					"required":    false,
+					"type":        "string",
+				},
 			},
 			"responses": map[string]interface{}{
 				"200": map[string]interface{}{
 					"description": "A key-value map.",
 				},
+				"304": map[string]interface{}{
+					"description": "The information did not change since it was last requested.",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+
+	s["paths"].(map[string]interface{})["/v1/info/schema"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":     "Return the inferred schema of all node and edge kinds.",
+			"description": "The info schema endpoint returns for every node and edge kind the known attributes and their types. Types are inferred by sampling a bounded number of stored nodes and edges of each kind.",
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "query",
+					"description": "Only sample nodes and edges from a partition (without the option all partitions are sampled).",
+					"required":    false,
+					"type":        "string",
+				},
+				{
+					"name":        "samples",
+					"in":          "query",
+					"description": "Maximum number of sampled nodes or edges of each kind (cannot exceed the configured sample size).",
+					"required":    false,
+					"type":        "integer",
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "The inferred schema.",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Schema",
+					},
+				},
+				"304": map[string]interface{}{
+					"description": "The information did not change since it was last requested.",
+				},
 				"default": map[string]interface{}{
 					"description": "Error response",
 					"schema": map[string]interface{}{
@@ -161,6 +923,47 @@ func (ie *infoEndpoint) SwaggerDefs(s map[string]interface{}) {
 		},
 	}
 
+	// Add inferred schema object to definition
+
+	kindSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"attrs": map[string]interface{}{
+				"description": "Attribute names and their inferred types: int, float, bool, string, list, " +
+					"object, mixed (values of different types) or unknown (no sampled value).",
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"sampled": map[string]interface{}{
+				"description": "Number of sampled nodes or edges.",
+				"type":        "integer",
+			},
+		},
+	}
+
+	s["definitions"].(map[string]interface{})["Schema"] = map[string]interface{}{
+		"description": "Inferred schema of all node and edge kinds.",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"node_kinds": map[string]interface{}{
+				"description":          "Inferred schema of each node kind.",
+				"type":                 "object",
+				"additionalProperties": kindSchema,
+			},
+			"edge_kinds": map[string]interface{}{
+				"description":          "Inferred schema of each edge kind.",
+				"type":                 "object",
+				"additionalProperties": kindSchema,
+			},
+			"sample_size": map[string]interface{}{
+				"description": "Maximum number of sampled nodes or edges of each kind.",
+				"type":        "integer",
+			},
+		},
+	}
+
 	// Add generic error object to definition
 
 	s["definitions"].(map[string]interface{})["Error"] = map[string]interface{}{
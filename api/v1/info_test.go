@@ -10,7 +10,20 @@
 
 package v1
 
-import "testing"
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+	"devt.de/krotik/eliasdb/storage"
+)
 
 func TestInfoQuery(t *testing.T) {
 	queryURL := "http://localhost" + TESTPORT + EndpointInfoQuery
@@ -24,6 +37,48 @@ func TestInfoQuery(t *testing.T) {
 		return
 	}
 
+	// Node and edge counts per partition can be restricted to a single partition
+
+	st, _, res = sendTestRequest(queryURL+"?partition=test", "GET", nil)
+	if st != "200 OK" || !strings.Contains(res, `
+  "partition_edge_counts": {
+    "test": {}
+  },
+  "partition_node_counts": {
+    "test": {
+      "Author": 1
+    }
+  },`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Unchanged information is not sent again
+
+	st, etag, res := sendConditionalGetRequest(queryURL, "")
+	if st != "200 OK" || etag == "" {
+		t.Error("Unexpected response:", st, etag, res)
+		return
+	}
+
+	st, _, res = sendConditionalGetRequest(queryURL, etag)
+	if st != "304 Not Modified" || res != "" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendConditionalGetRequest(queryURL+"?partition=test", etag)
+	if st != "200 OK" || res == "" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
 	queryURL = "http://localhost" + TESTPORT + EndpointInfoQuery + "kind"
 
 	_, _, res = sendTestRequest(queryURL, "GET", nil)
@@ -34,9 +89,9 @@ func TestInfoQuery(t *testing.T) {
 
 	queryURL = "http://localhost" + TESTPORT + EndpointInfoQuery + "kind/foobar"
 
-	_, _, res = sendTestRequest(queryURL, "GET", nil)
-	if res != "Unknown node kind foobar" {
-		t.Error("Unexpected response:", res)
+	st, _, res = sendTestRequest(queryURL, "GET", nil)
+	if st != "404 Not Found" || res != "Unknown node kind foobar" {
+		t.Error("Unexpected response:", st, res)
 		return
 	}
 
@@ -47,6 +102,12 @@ func TestInfoQuery(t *testing.T) {
 	if res != `
 {
   "edge_attrs": null,
+  "node_attr_types": {
+    "key": "string",
+    "kind": "string",
+    "name": "string",
+    "ranking": "number"
+  },
   "node_attrs": [
     "key",
     "kind",
@@ -87,4 +148,336 @@ func TestInfoQuery(t *testing.T) {
 		t.Error("Unexpected response:", res)
 		return
 	}
+
+	// Only the given partition is sampled
+
+	queryURL = "http://localhost" + TESTPORT + EndpointInfoQuery + "kind/Song?partition=test"
+
+	_, _, res = sendTestRequest(queryURL, "GET", nil)
+
+	if res != `
+{
+  "edge_attrs": null,
+  "node_attr_types": {
+    "key": "unknown",
+    "kind": "unknown",
+    "name": "unknown",
+    "ranking": "unknown"
+  },
+  "node_attrs": [
+    "key",
+    "kind",
+    "name",
+    "ranking"
+  ],
+  "node_edges": [
+    "Song:Contains:group:group",
+    "Song:Wrote:Author:Author"
+  ]
+}`[1:] {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	queryURL = "http://localhost" + TESTPORT + EndpointInfoQuery + "kind/Song?partition=foo"
+
+	st, _, res = sendTestRequest(queryURL, "GET", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if res := attrTypeName(true); res != "boolean" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := attrTypeName([]string{"a"}); res != "list" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := attrTypeName(map[string]interface{}{"a": 1}); res != "object" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestInfoSchemaQuery(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointInfoQuery + "schema"
+
+	var schema struct {
+		NodeKinds  map[string]map[string]interface{} `json:"node_kinds"`
+		EdgeKinds  map[string]map[string]interface{} `json:"edge_kinds"`
+		SampleSize int                               `json:"sample_size"`
+	}
+
+	st, _, res := sendTestRequest(queryURL, "GET", nil)
+	if err := json.Unmarshal([]byte(res), &schema); st != "200 OK" || err != nil {
+		t.Error("Unexpected response:", st, res, err)
+		return
+	}
+
+	if res := fmt.Sprint(schema.NodeKinds["Song"], " ", schema.SampleSize); res !=
+		"map[attrs:map[key:string kind:string name:string ranking:int] sampled:9] 100" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(schema.EdgeKinds["Wrote"]["attrs"]); res != "map[end1cascading:bool end1key:string "+
+		"end1kind:string end1role:string end2cascading:bool end2key:string end2kind:string end2role:string "+
+		"key:string kind:string number:int]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// The sample size can be lowered and sampling can be restricted to a partition
+
+	st, _, res = sendTestRequest(queryURL+"?samples=2", "GET", nil)
+	if err := json.Unmarshal([]byte(res), &schema); st != "200 OK" || err != nil ||
+		schema.NodeKinds["Song"]["sampled"] != float64(2) || schema.SampleSize != 2 {
+		t.Error("Unexpected response:", st, res, err)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?samples=1000", "GET", nil)
+	if err := json.Unmarshal([]byte(res), &schema); st != "200 OK" || err != nil || schema.SampleSize != 100 {
+		t.Error("Unexpected response:", st, res, err)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=test", "GET", nil)
+	if err := json.Unmarshal([]byte(res), &schema); st != "200 OK" || err != nil ||
+		fmt.Sprint(schema.NodeKinds["Song"]) != "map[attrs:map[key:unknown kind:unknown name:unknown "+
+			"ranking:unknown] sampled:0]" {
+		t.Error("Unexpected response:", st, res, err)
+		return
+	}
+
+	// Test error cases
+
+	st, _, res = sendTestRequest(queryURL+"?partition=foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?samples=x", "GET", nil)
+	if st != "400 Bad Request" || res != "Invalid parameter value: samples should be a positive integer number" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Test type inference
+
+	for _, test := range []struct {
+		vals     []interface{}
+		expected string
+	}{
+		{[]interface{}{1, 2.0}, "int"},
+		{[]interface{}{1, 2.5}, "float"},
+		{[]interface{}{2.5, uint8(1)}, "float"},
+		{[]interface{}{true}, "bool"},
+		{[]interface{}{"a", 1}, "mixed"},
+		{[]interface{}{[]string{"a"}}, "list"},
+		{[]interface{}{map[string]interface{}{"a": 1}}, "object"},
+	} {
+		res := ""
+		for _, val := range test.vals {
+			res = mergeAttrTypes(res, inferAttrType(val))
+		}
+
+		if res != test.expected {
+			t.Error("Unexpected result:", test.vals, res)
+			return
+		}
+	}
+}
+
+func TestInfoStorageQuery(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointInfoQuery + "storage"
+
+	// Memory storages cannot report statistics
+
+	st, _, res := sendTestRequest(queryURL+"?partition=test", "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "partition_storage": {
+    "test": {}
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Use a disk storage for the remaining tests
+
+	dir, err := ioutil.TempDir("", "infostoragetest")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dgs, err := graphstorage.NewDiskGraphStorage(dir, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer dgs.Close()
+
+	oldGM, oldGS := api.GM, api.GS
+	defer func() {
+		api.GM, api.GS = oldGM, oldGS
+	}()
+
+	api.GM = graph.NewGraphManager(dgs)
+	api.GS = dgs
+
+	for _, key := range []string{"1", "2", "3"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "Author")
+		node.SetAttr("name", strings.Repeat("x", 100))
+		api.GM.StoreNode("main", node)
+	}
+
+	api.GM.RemoveNode("main", "2", "Author")
+
+	if err := dgs.FlushAll(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL, "GET", nil)
+	if st != "200 OK" || !strings.Contains(res, `
+    "main": {
+      "Author.nodeidx": {
+        "free_bytes": `) || !strings.Contains(res, `
+      "Author.nodes": {
+        "free_bytes": `) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	sm := dgs.StorageManager("mainAuthor"+graph.StorageSuffixNodes, false)
+	stats, _ := sm.(*storage.CachedDiskStorageManager).FreeSlotStats()
+
+	if stats.Slots == 0 || !strings.Contains(res, fmt.Sprintf(`
+      "Author.nodes": {
+        "free_bytes": %v,
+        "free_slots": %v,
+        "largest_free_slot": %v
+      }`, stats.Bytes, stats.Slots, stats.LargestSlot)) {
+		t.Error("Unexpected response:", stats, res)
+		return
+	}
+}
+
+func TestInfoStorageCacheQuery(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointInfoQuery + "storage/cache"
+
+	mgs := graphstorage.NewMemoryGraphStorage("cachetest")
+
+	oldGM, oldGS := api.GM, api.GS
+	defer func() {
+		api.GM, api.GS = oldGM, oldGS
+	}()
+
+	api.GM = graph.NewGraphManager(mgs)
+	api.GS = mgs
+
+	for _, key := range []string{"1", "2", "3"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "Author")
+		api.GM.StoreNode("main", node)
+	}
+
+	api.GM.StoreNode("test", data.NewGraphNodeFromMap(map[string]interface{}{
+		"key":  "1",
+		"kind": "Author",
+	}))
+
+	st, _, res := sendTestRequest(queryURL, "DELETE", nil)
+	if st != "200 OK" || res != "" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=main", "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "partition_cache": {
+    "main": {
+      "evictions": 0,
+      "hit_ratio": 0,
+      "hits": 0,
+      "misses": 0
+    }
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Reading nodes is answered from the cache of the memory storage
+
+	for _, key := range []string{"1", "2", "3"} {
+		if n, err := api.GM.FetchNode("main", key, "Author"); n == nil || err != nil {
+			t.Error("Unexpected result:", n, err)
+			return
+		}
+	}
+
+	nsm := mgs.StorageManager("mainAuthor"+graph.StorageSuffixNodes, false).(storage.CacheStatsManager)
+	hits := nsm.CacheStats().Hits()
+
+	st, _, res = sendTestRequest(queryURL, "GET", nil)
+	if st != "200 OK" || hits == 0 || !strings.Contains(res, fmt.Sprintf(`
+    "main": {
+      "evictions": 0,
+      "hit_ratio": 1,
+      "hits": %v,
+      "misses": 0
+    },
+    "test": {
+      "evictions": 0,
+      "hit_ratio": 0,
+      "hits": 0,
+      "misses": 0
+    }`, hits)) {
+		t.Error("Unexpected response:", st, hits, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=foo", "GET", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=foo", "DELETE", nil)
+	if st != "400 Bad Request" || res != "Partition foo does not exist" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"?partition=main", "DELETE", nil)
+	if st != "200 OK" || res != "" || nsm.CacheStats().Hits() != 0 {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest("http://localhost"+TESTPORT+EndpointInfoQuery+"storage", "DELETE", nil)
+	if st != "400 Bad Request" || res != "Request had no effect" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
 }
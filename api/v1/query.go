@@ -15,12 +15,15 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"devt.de/krotik/common/datautil"
 	"devt.de/krotik/common/stringutil"
 	"devt.de/krotik/eliasdb/api"
 	"devt.de/krotik/eliasdb/eql"
+	"devt.de/krotik/eliasdb/eql/interpreter"
+	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph/data"
 )
 
@@ -39,6 +42,17 @@ ResultCache is a cache for result sets (by default no expiry and no limit)
 */
 var ResultCache *datautil.MapCache
 
+/*
+cachedResults holds all results which were put into the result cache - results
+which are no longer in the cache are closed
+*/
+var cachedResults = make(map[string]*APISearchResult)
+
+/*
+cachedResultsLock is the lock for the cachedResults map
+*/
+var cachedResultsLock = &sync.Mutex{}
+
 /*
 idCount is an ID counter for results
 */
@@ -110,13 +124,13 @@ func (eq *queryEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 	resID := r.URL.Query().Get("rid")
 	if resID != "" {
 
-		res, ok := ResultCache.Get(resID)
+		res, ok := getCachedResult(resID)
 		if !ok {
 			http.Error(w, "Unknown result ID (rid parameter)", http.StatusBadRequest)
 			return
 		}
 
-		err = eq.writeResultData(w, res.(*APISearchResult), part, resID, offset, limit, showGroups)
+		err = eq.writeResultData(w, res, part, resID, offset, limit, showGroups)
 
 	} else {
 		var res eql.SearchResult
@@ -130,16 +144,29 @@ func (eq *queryEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 			return
 		}
 
-		res, err = eql.RunQuery(stringutil.CreateDisplayString(part)+" query",
+		// Count queries return only the number of rows
+
+		if strings.ToLower(parser.FirstWord(query)) == "count" {
+			eq.handleCountQuery(w, r, part, query)
+			return
+		}
+
+		res, err = eql.RunQueryContext(r.Context(), stringutil.CreateDisplayString(part)+" query",
 			part, query, api.GM)
 
 		if err == nil {
 			sres := &APISearchResult{res, nil}
 
+			// Add the query to the request log
+
+			api.SetRequestLogField(r, "query", queryLogName(query, res))
+			api.SetRequestLogField(r, "rows", res.RowCount())
+
 			// Make sure the result has a primary node column
 
 			_, err = sres.GetPrimaryNodeColumn()
 			if err != nil {
+				res.Close()
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
@@ -148,9 +175,14 @@ func (eq *queryEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 
 			resID = genID()
 
-			ResultCache.Put(resID, sres)
+			putCachedResult(resID, sres)
 
 			err = eq.writeResultData(w, sres, part, resID, offset, limit, showGroups)
+
+		} else {
+
+			writeQueryError(w, err)
+			return
 		}
 	}
 
@@ -159,6 +191,76 @@ func (eq *queryEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resou
 	}
 }
 
+/*
+handleCountQuery runs a count query and writes the number of rows which the
+equivalent get query would produce as an object with a single count value.
+*/
+func (eq *queryEndpoint) handleCountQuery(w http.ResponseWriter, r *http.Request, part string, query string) {
+
+	count, err := eql.RunCountQueryContext(r.Context(), stringutil.CreateDisplayString(part)+" query",
+		part, query, api.GM)
+
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	// Add the query to the request log
+
+	api.SetRequestLogField(r, "query", strings.ToLower(strings.Join(parser.FirstWords(query, 2), " ")))
+	api.SetRequestLogField(r, "rows", count)
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count": count,
+	})
+}
+
+/*
+queryLogName returns the name of a query for the request log. The name is the
+type of the query followed by the node kind of the result (e.g. get Song).
+*/
+func queryLogName(query string, res eql.SearchResult) string {
+	name := strings.ToLower(parser.FirstWord(query))
+
+	return strings.TrimSpace(name + " " + res.Header().PrimaryKind())
+}
+
+/*
+writeQueryError writes an error of a query as a structured error object. The
+message of the error object is the error message. The line and column of the
+query clause which caused the error are included if they are known.
+*/
+func writeQueryError(w http.ResponseWriter, err error) {
+	var line, col int
+
+	switch qerr := err.(type) {
+	case *parser.Error:
+		line, col = qerr.Line, qerr.Pos
+	case *interpreter.RuntimeError:
+		line, col = qerr.Line, qerr.Pos
+	case *interpreter.SourceError:
+		line, col = qerr.Line, qerr.Pos
+	}
+
+	errObj := map[string]interface{}{
+		"message": err.Error(),
+	}
+
+	if line != 0 {
+		errObj["line"] = line
+		errObj["col"] = col
+	}
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": errObj,
+	})
+}
+
 /*
 writeResultData writes result data for the client.
 */
@@ -186,38 +288,36 @@ func (eq *queryEndpoint) writeResultData(w http.ResponseWriter, res *APISearchRe
 
 	resdata["total_selections"] = totalSels
 
-	rows := res.Rows()
-	srcs := res.RowSources()
+	start, end := 0, res.RowCount()
 
-	if limit == -1 && offset == -1 {
-		resdata["rows"] = rows
-		resdata["sources"] = srcs
-		resdata["selections"] = sels
+	if offset > 0 {
 
-	} else {
+		if offset >= end {
+			return fmt.Errorf("Offset exceeds available rows")
+		}
 
-		if offset > 0 {
+		start = offset
+	}
 
-			if offset >= len(rows) {
-				return fmt.Errorf("Offset exceeds available rows")
-			}
+	if limit != -1 && start+limit < end {
+		end = start + limit
+	}
 
-			rows = rows[offset:]
-			srcs = srcs[offset:]
-			sels = sels[offset:]
-		}
+	// Only read the requested rows since large results might not be held
+	// in memory
 
-		if limit != -1 && limit < len(rows) {
-			rows = rows[:limit]
-			srcs = srcs[:limit]
-			sels = sels[:limit]
-		}
+	rows := make([][]interface{}, 0, end-start)
+	srcs := make([][]string, 0, end-start)
 
-		resdata["rows"] = rows
-		resdata["sources"] = srcs
-		resdata["selections"] = sels
+	for i := start; i < end; i++ {
+		rows = append(rows, res.Row(i))
+		srcs = append(srcs, res.RowSource(i))
 	}
 
+	resdata["rows"] = formatOutputFloats(rows)
+	resdata["sources"] = srcs
+	resdata["selections"] = sels[start:end]
+
 	// Write out result header
 
 	resdataHeader := make(map[string]interface{})
@@ -232,6 +332,26 @@ func (eq *queryEndpoint) writeResultData(w http.ResponseWriter, res *APISearchRe
 
 	resdataHeader["primary_kind"] = pk
 
+	// Write out the distinct values and their counts of all columns which
+	// are filtered with unique or uniquecount
+
+	uniqueList := make([]map[string]interface{}, 0)
+
+	for i, label := range header.Labels() {
+		if vals, counts := res.UniqueCounts(i); vals != nil {
+			uniqueList = append(uniqueList, map[string]interface{}{
+				"label":  label,
+				"data":   header.Data()[i],
+				"values": formatOutputFloats(vals),
+				"counts": counts,
+			})
+		}
+	}
+
+	if len(uniqueList) > 0 {
+		resdata["unique"] = uniqueList
+	}
+
 	if showGroups {
 		groupList := make([][]string, 0, len(srcs))
 
@@ -298,7 +418,8 @@ func (eq *queryEndpoint) SwaggerDefs(s map[string]interface{}) {
 				"queries against partitions. The return value is always a list " +
 				"(even if there is only a single entry). A query result gets an " +
 				"ID and is stored in a cache. The ID is returned in the X-Cache-Id " +
-				"header. Subsequent requests for the same result can use the ID instead of a query.",
+				"header. Subsequent requests for the same result can use the ID instead of a query. " +
+				"A count query returns an object with the number of rows as count value.",
 			"produces": []string{
 				"text/plain",
 				"application/json",
@@ -323,32 +444,28 @@ func (eq *queryEndpoint) SwaggerDefs(s map[string]interface{}) {
 					"in":          "query",
 					"description": "Result ID to retrieve from the result cache.",
 					"required":    false,
-					"type":        "number",
-					"format":      "integer",
+					"type":        "string",
 				},
 				{
 					"name":        "limit",
 					"in":          "query",
 					"description": "How many list items to return.",
 					"required":    false,
-					"type":        "number",
-					"format":      "integer",
+					"type":        "integer",
 				},
 				{
 					"name":        "offset",
 					"in":          "query",
 					"description": "Offset in the dataset.",
 					"required":    false,
-					"type":        "number",
-					"format":      "integer",
+					"type":        "integer",
 				},
 				{
 					"name":        "groups",
 					"in":          "query",
 					"description": "Include group information in the result if set to any value.",
 					"required":    false,
-					"type":        "number",
-					"format":      "integer",
+					"type":        "string",
 				},
 			},
 			"responses": map[string]interface{}{
@@ -358,6 +475,18 @@ func (eq *queryEndpoint) SwaggerDefs(s map[string]interface{}) {
 						"$ref": "#/definitions/QueryResult",
 					},
 				},
+				"500": map[string]interface{}{
+					"description": "The query could not be run.",
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"error": map[string]interface{}{
+								"description": "Query error with a message and the line and column of the query clause which caused the error (if known).",
+								"type":        "object",
+							},
+						},
+					},
+				},
 				"default": map[string]interface{}{
 					"description": "Error response",
 					"schema": map[string]interface{}{
@@ -439,6 +568,39 @@ func (eq *queryEndpoint) SwaggerDefs(s map[string]interface{}) {
 					},
 				},
 			},
+			"unique": map[string]interface{}{
+				"description": "Distinct values and their number of occurrences for each column " +
+					"which is filtered with unique or uniquecount (only present if such a filter is used).",
+				"type": "array",
+				"items": map[string]interface{}{
+					"description": "Distinct values of a column.",
+					"type":        "object",
+					"properties": map[string]interface{}{
+						"label": map[string]interface{}{
+							"description": "Column label.",
+							"type":        "string",
+						},
+						"data": map[string]interface{}{
+							"description": "Data source for the column (e.g. 1:n:ranking).",
+							"type":        "string",
+						},
+						"values": map[string]interface{}{
+							"description": "Distinct values ordered by their number of occurrences (most frequent first).",
+							"type":        "array",
+							"items": map[string]interface{}{
+								"type": "object",
+							},
+						},
+						"counts": map[string]interface{}{
+							"description": "Number of occurrences of each distinct value.",
+							"type":        "array",
+							"items": map[string]interface{}{
+								"type": "integer",
+							},
+						},
+					},
+				},
+			},
 			"selections": map[string]interface{}{
 				"description": "List of row selections.",
 				"type":        "array",
@@ -449,8 +611,7 @@ func (eq *queryEndpoint) SwaggerDefs(s map[string]interface{}) {
 			},
 			"total_selections": map[string]interface{}{
 				"description": "Number of total selections.",
-				"type":        "number",
-				"format":      "integer",
+				"type":        "integer",
 			},
 		},
 	}
@@ -463,6 +624,52 @@ func (eq *queryEndpoint) SwaggerDefs(s map[string]interface{}) {
 	}
 }
 
+/*
+putCachedResult stores a result in the result cache.
+*/
+func putCachedResult(resID string, res *APISearchResult) {
+	cachedResultsLock.Lock()
+	defer cachedResultsLock.Unlock()
+
+	ResultCache.Put(resID, res)
+	cachedResults[resID] = res
+
+	closeEvictedResults()
+}
+
+/*
+getCachedResult retrieves a result from the result cache.
+*/
+func getCachedResult(resID string) (*APISearchResult, bool) {
+	cachedResultsLock.Lock()
+	defer cachedResultsLock.Unlock()
+
+	res, ok := ResultCache.Get(resID)
+
+	closeEvictedResults()
+
+	if !ok {
+		return nil, false
+	}
+
+	return res.(*APISearchResult), true
+}
+
+/*
+closeEvictedResults closes all results which were removed from the result
+cache. The cachedResultsLock must be held when calling this function.
+*/
+func closeEvictedResults() {
+	current := ResultCache.GetAll()
+
+	for resID, res := range cachedResults {
+		if cres, ok := current[resID]; !ok || cres != res {
+			res.Close()
+			delete(cachedResults, resID)
+		}
+	}
+}
+
 /*
 genID generates a unique ID.
 */
@@ -487,10 +694,8 @@ func (r *APISearchResult) GetPrimaryNodeColumn() (int, error) {
 
 	pk := r.Header().PrimaryKind()
 	col := -1
-	rs := r.RowSources()
-
-	if len(rs) > 0 {
-		for i, scol := range rs[0] {
+	if r.RowCount() > 0 {
+		for i, scol := range r.RowSource(0) {
 			scolParts := strings.Split(scol, ":")
 			if len(scolParts) > 1 && pk == scolParts[1] {
 				col = i
@@ -11,9 +11,81 @@
 package v1
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"devt.de/krotik/common/datautil"
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/eql"
 )
 
+func TestQuerySpill(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointQuery
+
+	query := queryURL + "//main?q=get+Song+with+ordering(ascending+key)"
+
+	_, _, expected := sendTestRequest(query, "GET", nil)
+	_, _, expectedPage := sendTestRequest(query+"&offset=2&limit=3", "GET", nil)
+
+	// Results which are larger than the spill limit are read from a spill file
+
+	eql.ResultSpillRows = 2
+	defer func() {
+		eql.ResultSpillRows = 0
+	}()
+
+	if st, _, res := sendTestRequest(query, "GET", nil); st != "200 OK" || res != expected {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if st, _, res := sendTestRequest(query+"&offset=2&limit=3", "GET", nil); st != "200 OK" || res != expectedPage {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	if st, _, res := sendTestRequest(query+"&offset=20", "GET", nil); st != "500 Internal Server Error" ||
+		res != "Offset exceeds available rows" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Spill files of results which are evicted from the result cache are removed
+
+	spillDir, err := ioutil.TempDir("", "queryspilltest")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(spillDir)
+
+	oldResultCache := ResultCache
+	eql.ResultSpillDir = spillDir
+	ResultCache = datautil.NewMapCache(1, 0)
+	defer func() {
+		eql.ResultSpillDir = ""
+		ResultCache = oldResultCache
+	}()
+
+	for i := 0; i < 3; i++ {
+		if st, _, res := sendTestRequest(query, "GET", nil); st != "200 OK" || res != expected {
+			t.Error("Unexpected response:", st, res)
+			return
+		}
+	}
+
+	if files, err := ioutil.ReadDir(spillDir); err != nil || len(files) != 1 {
+		t.Error("Unexpected spill files:", files, err)
+		return
+	}
+}
+
 func TestQueryPagination(t *testing.T) {
 	queryURL := "http://localhost" + TESTPORT + EndpointQuery
 
@@ -367,10 +439,31 @@ func TestQuery(t *testing.T) {
 		return
 	}
 
-	_, _, res = sendTestRequest(queryURL+"main/?q=get+BLA", "GET", nil)
+	st, _, res := sendTestRequest(queryURL+"main/?q=get+BLA", "GET", nil)
 
-	if res != "EQL error in Main query: Unknown node kind (BLA) (Line:1 Pos:5)" {
-		t.Error("Unexpected response:", res)
+	if st != "500 Internal Server Error" || res != `
+{
+  "error": {
+    "col": 5,
+    "line": 1,
+    "message": "EQL error in Main query: Unknown node kind (BLA) (Line:1 Pos:5)"
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main/?q=get+Song+where+foo+%3D+%3D+bar", "GET", nil)
+
+	if st != "500 Internal Server Error" || res != `
+{
+  "error": {
+    "col": 22,
+    "line": 1,
+    "message": "Parse error in Main query: Term cannot start an expression (=) (Line:1 Pos:22)"
+  }
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
 		return
 	}
 
@@ -511,6 +604,166 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+func TestQueryUniqueCounts(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointQuery
+
+	getUnique := func(query string) (string, bool) {
+		st, _, res := sendTestRequest(queryURL+"main?q="+url.QueryEscape(query), "GET", nil)
+
+		var resdata map[string]interface{}
+
+		if err := json.Unmarshal([]byte(res), &resdata); st != "200 OK" || err != nil {
+			return fmt.Sprint(st, res, err), false
+		}
+
+		unique, ok := resdata["unique"]
+		out, _ := json.MarshalIndent(unique, "", "  ")
+
+		return string(out), ok
+	}
+
+	if res, ok := getUnique("get Author traverse :::Song end show Author:name, Author:key " +
+		"with filtering(uniquecount Author:name)"); !ok || res != `
+[
+  {
+    "counts": [
+      4,
+      4,
+      1
+    ],
+    "data": "1:n:name",
+    "label": "Author Name",
+    "values": [
+      "John",
+      "Mike",
+      "Hans"
+    ]
+  }
+]`[1:] {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	if res, ok := getUnique("get Song show name, ranking with filtering(unique ranking)"); !ok || res != `
+[
+  {
+    "counts": [
+      1,
+      1,
+      1,
+      1,
+      1,
+      1,
+      1,
+      1,
+      1
+    ],
+    "data": "1:n:ranking",
+    "label": "Ranking",
+    "values": [
+      1,
+      2,
+      3,
+      4,
+      5,
+      6,
+      8,
+      18,
+      19
+    ]
+  }
+]`[1:] {
+		t.Error("Unexpected response:", res)
+		return
+	}
+
+	// Results without unique filters have no unique counts
+
+	if res, ok := getUnique("get Song"); ok {
+		t.Error("Unexpected response:", res)
+		return
+	}
+}
+
+func TestQueryCount(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointQuery
+
+	st, _, res := sendTestRequest(queryURL+"main?q="+url.QueryEscape("count Song where ranking > 5"), "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "count": 4
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main?q="+url.QueryEscape("COUNT Song"), "GET", nil)
+	if st != "200 OK" || res != `
+{
+  "count": 9
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Clauses which shape result rows cannot be used in count queries
+
+	st, _, res = sendTestRequest(queryURL+"main?q="+url.QueryEscape("count Song show name"), "GET", nil)
+	if st != "500 Internal Server Error" || !strings.Contains(res, `"message":`) {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
+
+func TestQueryRequestLog(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointQuery
+
+	entries := make(chan *api.RequestLogEntry, 1)
+
+	api.RequestLogger = func(entry *api.RequestLogEntry) {
+		entries <- entry
+	}
+	defer func() {
+		api.RequestLogger = nil
+	}()
+
+	st, _, res := sendTestRequest(queryURL+"main?q="+url.QueryEscape("get Author where name = 'John'"), "GET", nil)
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	select {
+	case e := <-entries:
+		if e.Method != "GET" || e.Path != api.EndpointPath(EndpointQuery)+"main" || e.Status != 200 ||
+			e.Bytes == 0 || e.Fields["query"] != "get Author" || e.Fields["rows"] != 1 {
+			t.Error("Unexpected log entry:", e)
+			return
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("No log entry was written")
+		return
+	}
+
+	// Comments in front of the query are not part of the logged name
+
+	st, _, res = sendTestRequest(queryURL+"main?q="+url.QueryEscape("# All Johns\nget Author where name = 'John'"), "GET", nil)
+	if st != "200 OK" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	select {
+	case e := <-entries:
+		if e.Fields["query"] != "get Author" {
+			t.Error("Unexpected log entry:", e)
+			return
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("No log entry was written")
+	}
+}
+
 func TestGroupingInfo(t *testing.T) {
 	queryURL := "http://localhost" + TESTPORT + EndpointQuery
 
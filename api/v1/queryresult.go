@@ -91,14 +91,12 @@ func (qre *queryResultEndpoint) handleRequest(requestType string, w http.Respons
 	resID := resources[0]
 	op := resources[1]
 
-	res, ok := ResultCache.Get(resID)
+	sres, ok := getCachedResult(resID)
 	if !ok {
 		http.Error(w, "Unknown query result", http.StatusBadRequest)
 		return
 	}
 
-	sres := res.(*APISearchResult)
-
 	if op == "csv" {
 
 		if requestType != "get" {
@@ -185,6 +183,10 @@ func (qre *queryResultEndpoint) groupSelected(requestType string, w http.Respons
 	part := sres.Header().Partition()
 	selections := sres.Selections()
 
+	if requestType != "get" && !checkWritable(w, part) {
+		return
+	}
+
 	if col, err = sres.GetPrimaryNodeColumn(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -200,7 +202,8 @@ func (qre *queryResultEndpoint) groupSelected(requestType string, w http.Respons
 
 		groupName := resources[2]
 
-		for i, srcs := range sres.RowSources() {
+		for i := 0; i < sres.RowCount(); i++ {
+			srcs := sres.RowSource(i)
 			src := strings.Split(srcs[col], ":")
 			kind := src[1]
 			key := src[2]
@@ -263,7 +266,8 @@ func (qre *queryResultEndpoint) groupSelected(requestType string, w http.Respons
 
 			trans2 := graph.NewGraphTrans(api.GM)
 
-			for i, srcs := range sres.RowSources() {
+			for i := 0; i < sres.RowCount(); i++ {
+				srcs := sres.RowSource(i)
 				src := strings.Split(srcs[col], ":")
 				kind := src[1]
 				key := src[2]
@@ -332,7 +336,8 @@ func (qre *queryResultEndpoint) groupSelectionState(sres *APISearchResult, part
 	memberKeys := make(map[string][]string)
 	memberKinds := make(map[string][]string)
 
-	for i, srcs := range sres.RowSources() {
+	for i := 0; i < sres.RowCount(); i++ {
+		srcs := sres.RowSource(i)
 		src := strings.Split(srcs[primaryNodeCol], ":")
 		kind := src[1]
 		key := src[2]
@@ -412,7 +417,8 @@ func (qre *queryResultEndpoint) selectRows(requestType string, w http.ResponseWr
 
 		sels := sres.Selections()
 
-		for i, srcs := range sres.RowSources() {
+		for i := 0; i < sres.RowCount(); i++ {
+			srcs := sres.RowSource(i)
 			if sels[i] {
 				src := strings.Split(srcs[col], ":")
 				keys = append(keys, src[2])
@@ -495,7 +501,8 @@ func (qre *queryResultEndpoint) quickFilter(requestType string, w http.ResponseW
 
 	counts := make(map[string]uint64)
 
-	for _, row := range sres.Rows() {
+	for i := 0; i < sres.RowCount(); i++ {
+		row := sres.Row(i)
 		val := fmt.Sprint(row[index])
 		counts[val]++
 	}
@@ -653,7 +660,7 @@ func (qre *queryResultEndpoint) SwaggerDefs(s map[string]interface{}) {
 			"produces": []string{
 				"text/plain",
 			},
-			"parameters": append(required),
+			"parameters": required,
 			"responses": map[string]interface{}{
 				"200": map[string]interface{}{
 					"description": "A CSV string.",
@@ -797,7 +804,7 @@ func (qre *queryResultEndpoint) SwaggerDefs(s map[string]interface{}) {
 				"text/plain",
 				"application/json",
 			},
-			"parameters": append(required),
+			"parameters": required,
 			"responses": map[string]interface{}{
 				"200": map[string]interface{}{
 					"description": "Current group selection state.",
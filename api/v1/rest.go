@@ -11,11 +11,16 @@
 package v1
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
 )
 
 /*
@@ -28,11 +33,38 @@ HTTPHeaderTotalCount is a special header value containing the total count of obj
 */
 const HTTPHeaderTotalCount = "X-Total-Count"
 
+/*
+HTTPHeaderNextCursor is a special header value containing the cursor for the next page of a list.
+*/
+const HTTPHeaderNextCursor = "X-Next-Cursor"
+
 /*
 HTTPHeaderCacheID is a special header value containing a cache ID for a quick follow up query.
 */
 const HTTPHeaderCacheID = "X-Cache-Id"
 
+/*
+OutputFloatFormat is the format which is used to serialize floating point
+numbers in responses (see strconv.FormatFloat - e.g. 'f' for a fixed number
+of decimals or 'g' for a number of significant figures).
+*/
+var OutputFloatFormat byte = 'g'
+
+/*
+ValidOutputFloatFormat checks if a given format can be used as
+OutputFloatFormat. Only formats which produce valid JSON numbers are allowed.
+*/
+func ValidOutputFloatFormat(format string) bool {
+	return format == "e" || format == "f" || format == "g"
+}
+
+/*
+OutputFloatPrecision is the precision which is used to serialize floating
+point numbers in responses. A value of -1 serializes floating point numbers
+with full precision. This only affects the output, stored values are not changed.
+*/
+var OutputFloatPrecision = -1
+
 /*
 V1EndpointMap is a map of urls to endpoints for version 1 of the API
 */
@@ -40,15 +72,21 @@ var V1EndpointMap = map[string]api.RestEndpointInst{
 	EndpointBlob:                 BlobEndpointInst,
 	EndpointClusterQuery:         ClusterEndpointInst,
 	EndpointEql:                  EqlEndpointInst,
+	EndpointExport:               ExportEndpointInst,
 	EndpointGraph:                GraphEndpointInst,
+	EndpointGraphBatch:           GraphBatchEndpointInst,
 	EndpointGraphQL:              GraphQLEndpointInst,
 	EndpointGraphQLQuery:         GraphQLQueryEndpointInst,
 	EndpointGraphQLSubscriptions: GraphQLSubscriptionsEndpointInst,
+	EndpointHealth:               HealthEndpointInst,
+	EndpointImport:               ImportEndpointInst,
 	EndpointIndexQuery:           IndexEndpointInst,
 	EndpointFindQuery:            FindEndpointInst,
 	EndpointInfoQuery:            InfoEndpointInst,
 	EndpointQuery:                QueryEndpointInst,
 	EndpointQueryResult:          QueryResultEndpointInst,
+	EndpointSubscribe:            SubscribeEndpointInst,
+	EndpointTraverse:             TraverseEndpointInst,
 }
 
 // Helper functions
@@ -68,6 +106,32 @@ func checkResources(w http.ResponseWriter, resources []string, requiredMin int,
 	return true
 }
 
+/*
+checkWritable checks that a given partition is not read-only. Requests which
+would modify a read-only partition are rejected with 405.
+*/
+func checkWritable(w http.ResponseWriter, part string) bool {
+	if api.GM.IsReadOnly(part) {
+		http.Error(w, fmt.Sprintf("Partition %v is read-only", part), http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+/*
+checkDirection checks a given traversal direction.
+*/
+func checkDirection(w http.ResponseWriter, direction string) bool {
+	if direction != graph.DirectionAny && direction != graph.DirectionOutgoing &&
+		direction != graph.DirectionIncoming {
+
+		http.Error(w, "Invalid direction: "+direction+" (must be "+graph.DirectionOutgoing+
+			" or "+graph.DirectionIncoming+")", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 /*
 Extract a positive number from a query parameter. Returns -1 and true
 if the parameter was not given.
@@ -89,3 +153,163 @@ func queryParamPosNum(w http.ResponseWriter, r *http.Request, param string) (int
 
 	return num, true
 }
+
+/*
+Extract a query parameter which can also be given under an alternative name.
+Returns the value and the name of the parameter which was used. Writes an error
+and returns false if both names are given.
+*/
+func queryParamAlias(w http.ResponseWriter, r *http.Request, param string, alias string) (string, string, bool) {
+
+	val, aliasVal := r.URL.Query().Get(param), r.URL.Query().Get(alias)
+
+	if val != "" && aliasVal != "" {
+		http.Error(w, "Parameters "+param+" and "+alias+" cannot be used together", http.StatusBadRequest)
+		return "", "", false
+	} else if aliasVal != "" {
+		return aliasVal, alias, true
+	}
+
+	return val, param, true
+}
+
+/*
+formatOutputFloats returns a copy of a given data structure with all floating
+point numbers formatted according to OutputFloatFormat and OutputFloatPrecision.
+The given data structure is returned unchanged if full precision is requested.
+*/
+func formatOutputFloats(v interface{}) interface{} {
+
+	if OutputFloatPrecision < 0 {
+		return v
+	}
+
+	switch val := v.(type) {
+
+	case float64:
+		return json.Number(strconv.FormatFloat(val, OutputFloatFormat, OutputFloatPrecision, 64))
+
+	case float32:
+		return json.Number(strconv.FormatFloat(float64(val), OutputFloatFormat, OutputFloatPrecision, 32))
+
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, mv := range val {
+			ret[k] = formatOutputFloats(mv)
+		}
+		return ret
+
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, lv := range val {
+			ret[i] = formatOutputFloats(lv)
+		}
+		return ret
+
+	case []map[string]interface{}:
+		ret := make([]map[string]interface{}, len(val))
+		for i, lv := range val {
+			ret[i] = formatOutputFloats(lv).(map[string]interface{})
+		}
+		return ret
+
+	case [][]interface{}:
+		ret := make([][]interface{}, len(val))
+		for i, lv := range val {
+			ret[i] = formatOutputFloats(lv).([]interface{})
+		}
+		return ret
+
+	case [][]map[string]interface{}:
+		ret := make([][]map[string]interface{}, len(val))
+		for i, lv := range val {
+			ret[i] = formatOutputFloats(lv).([]map[string]interface{})
+		}
+		return ret
+	}
+
+	return v
+}
+
+/*
+handleConditionalGET calls a given GET handler and holds back its response.
+Successful responses get an entity tag which is derived from the response body
+unless the handler already set one. A 304 Not Modified response without a body
+is sent if the entity tag matches the If-None-Match header of the request.
+*/
+func handleConditionalGET(w http.ResponseWriter, r *http.Request, resources []string,
+	handler func(w http.ResponseWriter, r *http.Request, resources []string)) {
+
+	cw := &conditionalResponseWriter{ResponseWriter: w}
+
+	handler(cw, r, resources)
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if cw.status == http.StatusOK {
+
+		etag := w.Header().Get("ETag")
+
+		if etag == "" {
+			h := fnv.New64a()
+			h.Write(cw.buf.Bytes())
+
+			etag = fmt.Sprintf(`"%x"`, h.Sum64())
+			w.Header().Set("ETag", etag)
+		}
+
+		if matchesETag(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("content-type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.WriteHeader(cw.status)
+	w.Write(cw.buf.Bytes())
+}
+
+/*
+matchesETag checks if an If-None-Match header value contains a given entity
+tag. Weak entity tags match their strong counterparts.
+*/
+func matchesETag(ifNoneMatch string, etag string) bool {
+
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+		if tag == "*" || tag == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+conditionalResponseWriter holds back the status code and the body of a
+response until it is known if the response is sent at all.
+*/
+type conditionalResponseWriter struct {
+	http.ResponseWriter
+	status int          // Status code which was set by the handler
+	buf    bytes.Buffer // Body which was written by the handler
+}
+
+/*
+WriteHeader records the status code of the response.
+*/
+func (w *conditionalResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+/*
+Write writes (part of) the response body.
+*/
+func (w *conditionalResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
@@ -14,9 +14,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -77,6 +81,296 @@ func TestSwaggerDefs(t *testing.T) {
 	for _, inst := range V1EndpointMap {
 		inst().SwaggerDefs(data)
 	}
+
+	// Test the aggregated document is a valid swagger (OpenAPI 2.0) document
+
+	w := httptest.NewRecorder()
+	api.SwaggerEndpointInst().HandleGET(w, nil, nil)
+
+	var spec map[string]interface{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, path := range []string{"/v1/graph/{partition}/{entity_type}", "/v1/query/{partition}",
+		"/v1/eql", "/v1/info/schema"} {
+
+		if _, ok := spec["paths"].(map[string]interface{})[path]; !ok {
+			t.Error("Missing path:", path)
+			return
+		}
+	}
+
+	if errs := validateSwagger(spec); len(errs) > 0 {
+		t.Error("Invalid swagger document:\n" + strings.Join(errs, "\n"))
+		return
+	}
+
+	// Test the validator finds errors
+
+	spec = map[string]interface{}{
+		"swagger": "2.0",
+		"info":    map[string]interface{}{"title": "test"},
+		"paths": map[string]interface{}{
+			"/foo/{id}": map[string]interface{}{
+				"fetch": map[string]interface{}{},
+				"post": map[string]interface{}{
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "a", "in": "query", "type": "foo"},
+						map[string]interface{}{"name": "b", "in": "body"},
+						map[string]interface{}{"name": "c", "in": "path", "type": "string"},
+						map[string]interface{}{"name": "d", "in": "query", "type": "array"},
+						map[string]interface{}{"name": "e", "in": "query", "type": "number", "format": "integer"},
+					},
+					"responses": map[string]interface{}{
+						"200":     map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/definitions/X"}},
+						"success": map[string]interface{}{"description": "ok"},
+					},
+				},
+			},
+		},
+		"definitions": map[string]interface{}{},
+	}
+
+	if errs := validateSwagger(spec); strings.Join(errs, "\n") != `
+info: missing version
+paths./foo/{id}.post.parameters.a: invalid type foo
+paths./foo/{id}.post.parameters.b: body parameter needs a schema
+paths./foo/{id}.post.parameters.c: path parameter is not part of the path
+paths./foo/{id}.post.parameters.c: path parameter must be required
+paths./foo/{id}.post.parameters.d: array parameter needs items
+paths./foo/{id}.post.parameters.e: invalid format integer for type number
+paths./foo/{id}.post.responses.200.schema: unknown reference #/definitions/X
+paths./foo/{id}.post.responses.200: missing description
+paths./foo/{id}.post.responses.success: invalid response code
+paths./foo/{id}.post: path parameter id is not defined
+paths./foo/{id}: unknown operation fetch`[1:] {
+		t.Error("Unexpected result:", strings.Join(errs, "\n"))
+		return
+	}
+}
+
+/*
+validateSwagger checks a swagger document against the rules of the OpenAPI 2.0
+specification. Returns a sorted list of all found errors.
+*/
+func validateSwagger(spec map[string]interface{}) []string {
+	var errs []string
+
+	fail := func(path string, msg string, args ...interface{}) {
+		errs = append(errs, path+": "+fmt.Sprintf(msg, args...))
+	}
+
+	if spec["swagger"] != "2.0" {
+		fail("swagger", "version must be 2.0")
+	}
+
+	info, _ := spec["info"].(map[string]interface{})
+	for _, field := range []string{"title", "version"} {
+		if _, ok := info[field].(string); !ok {
+			fail("info", "missing %v", field)
+		}
+	}
+
+	definitions, _ := spec["definitions"].(map[string]interface{})
+
+	schemaTypes := map[string]bool{"string": true, "number": true, "integer": true,
+		"boolean": true, "array": true, "object": true, "null": true, "file": true}
+
+	// Integer and number formats are fixed - string formats are open
+
+	numberFormats := map[string]map[string]bool{
+		"integer": {"int32": true, "int64": true},
+		"number":  {"float": true, "double": true},
+	}
+
+	checkFormat := func(path string, t interface{}, format interface{}) {
+		if formats, ok := numberFormats[fmt.Sprint(t)]; ok && format != nil && !formats[fmt.Sprint(format)] {
+			fail(path, "invalid format %v for type %v", format, t)
+		}
+	}
+
+	var checkSchema func(path string, schema interface{})
+
+	checkSchema = func(path string, schema interface{}) {
+		s, ok := schema.(map[string]interface{})
+		if !ok {
+			fail(path, "schema must be an object")
+			return
+		}
+
+		if ref, ok := s["$ref"].(string); ok {
+			if _, ok := definitions[strings.TrimPrefix(ref, "#/definitions/")]; !ok ||
+				!strings.HasPrefix(ref, "#/definitions/") {
+				fail(path, "unknown reference %v", ref)
+			}
+			return
+		}
+
+		if t, ok := s["type"]; ok && !schemaTypes[fmt.Sprint(t)] {
+			fail(path, "invalid type %v", t)
+		}
+
+		checkFormat(path, s["type"], s["format"])
+
+		if s["type"] == "array" {
+			if items, ok := s["items"]; ok {
+				checkSchema(path+".items", items)
+			} else {
+				fail(path, "array schema needs items")
+			}
+		}
+
+		if props, ok := s["properties"].(map[string]interface{}); ok {
+			for name, prop := range props {
+				checkSchema(path+".properties."+name, prop)
+			}
+		}
+
+		if ap, ok := s["additionalProperties"].(map[string]interface{}); ok {
+			checkSchema(path+".additionalProperties", ap)
+		}
+	}
+
+	for name, def := range definitions {
+		checkSchema("definitions."+name, def)
+	}
+
+	paramTypes := map[string]bool{"string": true, "number": true, "integer": true,
+		"boolean": true, "array": true, "file": true}
+	paramLocations := map[string]bool{"query": true, "header": true, "path": true,
+		"formData": true, "body": true}
+	operations := map[string]bool{"get": true, "put": true, "post": true, "delete": true,
+		"options": true, "head": true, "patch": true}
+	pathParamRegex := regexp.MustCompile(`\{([^}]+)\}`)
+
+	paths, ok := spec["paths"].(map[string]interface{})
+	if !ok {
+		fail("paths", "missing paths")
+	}
+
+	for path, item := range paths {
+		ppath := "paths." + path
+
+		if !strings.HasPrefix(path, "/") {
+			fail(ppath, "path must start with /")
+		}
+
+		templateParams := make(map[string]bool)
+		for _, m := range pathParamRegex.FindAllStringSubmatch(path, -1) {
+			templateParams[m[1]] = true
+		}
+
+		for opName, op := range item.(map[string]interface{}) {
+			opath := ppath + "." + opName
+
+			if !operations[opName] {
+				fail(ppath, "unknown operation %v", opName)
+				continue
+			}
+
+			operation := op.(map[string]interface{})
+
+			params, _ := operation["parameters"].([]interface{})
+			definedParams := make(map[string]bool)
+			bodyParams := 0
+			formParams := 0
+
+			for i, p := range params {
+				param, _ := p.(map[string]interface{})
+				name, _ := param["name"].(string)
+				in, _ := param["in"].(string)
+				ppath := opath + ".parameters." + name
+
+				if name == "" {
+					fail(opath, "parameter %v has no name", i)
+					continue
+				} else if !paramLocations[in] {
+					fail(ppath, "invalid location %v", in)
+					continue
+				} else if definedParams[in+":"+name] {
+					fail(ppath, "duplicate parameter")
+				}
+
+				definedParams[in+":"+name] = true
+
+				if in == "body" {
+					bodyParams++
+
+					if schema, ok := param["schema"]; ok {
+						checkSchema(ppath+".schema", schema)
+					} else {
+						fail(ppath, "body parameter needs a schema")
+					}
+
+					continue
+				}
+
+				if in == "formData" {
+					formParams++
+				}
+
+				if t, _ := param["type"].(string); !paramTypes[t] {
+					fail(ppath, "invalid type %v", param["type"])
+				} else if t == "array" && param["items"] == nil {
+					fail(ppath, "array parameter needs items")
+				}
+
+				checkFormat(ppath, param["type"], param["format"])
+
+				if in == "path" {
+					if param["required"] != true {
+						fail(ppath, "path parameter must be required")
+					}
+					if !templateParams[name] {
+						fail(ppath, "path parameter is not part of the path")
+					}
+				}
+			}
+
+			for name := range templateParams {
+				if !definedParams["path:"+name] {
+					fail(opath, "path parameter %v is not defined", name)
+				}
+			}
+
+			if bodyParams > 1 {
+				fail(opath, "only one body parameter is allowed")
+			} else if bodyParams > 0 && formParams > 0 {
+				fail(opath, "body and formData parameters cannot be mixed")
+			}
+
+			responses, ok := operation["responses"].(map[string]interface{})
+			if !ok || len(responses) == 0 {
+				fail(opath, "missing responses")
+			}
+
+			for code, r := range responses {
+				rpath := opath + ".responses." + code
+
+				if _, err := strconv.Atoi(code); code != "default" && (err != nil || len(code) != 3) {
+					fail(rpath, "invalid response code")
+					continue
+				}
+
+				response, _ := r.(map[string]interface{})
+
+				if _, ok := response["description"].(string); !ok {
+					fail(rpath, "missing description")
+				}
+
+				if schema, ok := response["schema"]; ok {
+					checkSchema(rpath+".schema", schema)
+				}
+			}
+		}
+	}
+
+	sort.Strings(errs)
+
+	return errs
 }
 
 /*
@@ -121,6 +415,28 @@ func sendTestRequest(url string, method string, content []byte) (string, http.He
 	return resp.Status, resp.Header, bodyStr
 }
 
+/*
+Send a GET request with an If-None-Match header to a HTTP test server
+*/
+func sendConditionalGetRequest(url string, ifNoneMatch string) (string, string, string) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Set("If-None-Match", ifNoneMatch)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	return resp.Status, resp.Header.Get("ETag"), strings.Trim(string(body), " \n")
+}
+
 /*
 formatJSONString formats a given JSON string.
 */
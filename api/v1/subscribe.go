@@ -0,0 +1,347 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"devt.de/krotik/common/cryptutil"
+	"devt.de/krotik/common/stringutil"
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+/*
+EndpointSubscribe is the subscription endpoint URL (rooted). Handles websockets under subscribe/
+*/
+const EndpointSubscribe = api.APIRoot + APIv1 + "/subscribe/"
+
+/*
+SystemRuleSubscriptionsName is the name of the graph manager rule which
+forwards graph changes to subscribers.
+*/
+const SystemRuleSubscriptionsName = "system.subscriptions"
+
+/*
+SubscriptionBufferSize is the number of events which are buffered for a
+subscriber. Subscribers which fall further behind are dropped.
+*/
+var SubscriptionBufferSize = 100
+
+/*
+SubscriptionWriteTimeout is the time after which writing an event to a
+subscriber is given up.
+*/
+var SubscriptionWriteTimeout = 10 * time.Second
+
+/*
+subscribeUpgrader can upgrade normal requests to websocket communications
+*/
+var subscribeUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+/*
+subscriptionRule is the rule which receives all graph events.
+*/
+var subscriptionRule = &SystemRuleSubscriptions{make(map[string]*subscriber), &sync.RWMutex{}}
+
+/*
+subscriptionRuleLock protects the registration of the subscription rule.
+*/
+var subscriptionRuleLock = &sync.Mutex{}
+
+/*
+SubscribeEndpointInst creates a new endpoint handler.
+*/
+func SubscribeEndpointInst() api.RestEndpointHandler {
+	return &subscribeEndpoint{}
+}
+
+/*
+Handler object for subscriptions.
+*/
+type subscribeEndpoint struct {
+	*api.DefaultEndpointHandler
+}
+
+/*
+HandleGET handles a subscription request. The connection is upgraded to a
+websocket which receives an event for every change of a node or edge of the
+subscribed partition and kind.
+*/
+func (e *subscribeEndpoint) HandleGET(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	// Update the incomming connection to a websocket
+	// If the upgrade fails then the client gets an HTTP error response.
+
+	conn, err := subscribeUpgrader.Upgrade(w, r, nil)
+
+	if err != nil {
+
+		// We give details here on what went wrong
+
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	// Ensure we have a partition and a kind to monitor
+
+	part := r.URL.Query().Get("partition")
+	if part == "" && len(resources) > 0 {
+		part = resources[0]
+	}
+
+	kind := r.URL.Query().Get("kind")
+	if kind == "" && len(resources) > 1 {
+		kind = resources[1]
+	}
+
+	if part == "" || kind == "" {
+		e.WriteClose(conn, websocket.CloseUnsupportedData,
+			"Need a 'partition' and a 'kind' in path or as url parameter")
+		return
+	}
+
+	ensureSubscriptionRule()
+
+	sub := &subscriber{
+		fmt.Sprintf("%x", cryptutil.GenerateUUID()),
+		part,
+		kind,
+		make(chan []byte, SubscriptionBufferSize),
+		false,
+	}
+
+	subscriptionRule.AddSubscriber(sub)
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"subscription_success","payload":{}}`))
+
+	// Events are written in a separate go routine - the channel is closed
+	// once the subscriber was removed
+
+	go func() {
+
+		for event := range sub.events {
+
+			conn.SetWriteDeadline(time.Now().Add(SubscriptionWriteTimeout))
+
+			if err := conn.WriteMessage(websocket.TextMessage, event); err != nil {
+				subscriptionRule.RemoveSubscriber(sub)
+			}
+		}
+
+		if sub.dropped {
+			e.WriteClose(conn, websocket.ClosePolicyViolation,
+				"Subscriber could not keep up with events")
+			return
+		}
+
+		conn.Close()
+	}()
+
+	// Read messages until the client disconnects - messages from the
+	// client are ignored
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			subscriptionRule.RemoveSubscriber(sub)
+			return
+		}
+	}
+}
+
+/*
+WriteClose writes a closing control message with a given reason and closes
+the websocket.
+*/
+func (e *subscribeEndpoint) WriteClose(conn *websocket.Conn, code int, msg string) {
+
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, msg), time.Now().Add(10*time.Second))
+
+	conn.Close()
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (e *subscribeEndpoint) SwaggerDefs(s map[string]interface{}) {
+	// No swagger definitions for this endpoint as it only handles websocket requests
+}
+
+/*
+ensureSubscriptionRule ensures that the current graph manager forwards graph
+events to the subscription rule.
+*/
+func ensureSubscriptionRule() {
+	subscriptionRuleLock.Lock()
+	defer subscriptionRuleLock.Unlock()
+
+	if stringutil.IndexOf(SystemRuleSubscriptionsName, api.GM.GraphRules()) == -1 {
+		api.GM.SetGraphRule(subscriptionRule)
+	}
+}
+
+// Subscription Rule
+// =================
+
+/*
+subscriber is a client which receives graph events of a partition and kind.
+*/
+type subscriber struct {
+	id      string      // Unique ID which identifies the subscriber
+	part    string      // Partition which is monitored
+	kind    string      // Node or edge kind which is monitored
+	events  chan []byte // Buffered channel of encoded events
+	dropped bool        // Flag if the subscriber was dropped for being too slow
+}
+
+/*
+SystemRuleSubscriptions is a system rule to forward node and edge changes to
+all relevant subscribers. Events are never blocking the write path - subscribers
+which cannot keep up are dropped once their buffer is full.
+*/
+type SystemRuleSubscriptions struct {
+	subscribers     map[string]*subscriber
+	subscribersLock *sync.RWMutex
+}
+
+/*
+Name returns the name of the rule.
+*/
+func (r *SystemRuleSubscriptions) Name() string {
+	return SystemRuleSubscriptionsName
+}
+
+/*
+Handles returns a list of events which are handled by this rule.
+*/
+func (r *SystemRuleSubscriptions) Handles() []int {
+	return []int{
+		graph.EventNodeCreated,
+		graph.EventNodeUpdated,
+		graph.EventNodeDeleted,
+		graph.EventEdgeCreated,
+		graph.EventEdgeUpdated,
+		graph.EventEdgeDeleted,
+	}
+}
+
+/*
+Handle handles an event.
+*/
+func (r *SystemRuleSubscriptions) Handle(gm *graph.Manager, trans graph.Trans, event int, ed ...interface{}) error {
+	var msg []byte
+
+	part := ed[0].(string)
+	node := ed[1].(data.Node)
+
+	entityType := "n"
+	if event == graph.EventEdgeCreated || event == graph.EventEdgeUpdated ||
+		event == graph.EventEdgeDeleted {
+		entityType = "e"
+	}
+
+	var dropped []*subscriber
+
+	r.subscribersLock.RLock()
+
+	for _, sub := range r.subscribers {
+
+		if sub.part != part || sub.kind != node.Kind() {
+			continue
+		}
+
+		if msg == nil {
+			msg, _ = json.Marshal(map[string]interface{}{
+				"type":        subscriptionEventType(event),
+				"entity_type": entityType,
+				"partition":   part,
+				"payload":     formatOutputFloats(node.Data()),
+			})
+		}
+
+		// Never block the writer - drop the subscriber if its buffer is full
+
+		select {
+		case sub.events <- msg:
+		default:
+			dropped = append(dropped, sub)
+		}
+	}
+
+	r.subscribersLock.RUnlock()
+
+	for _, sub := range dropped {
+		r.dropSubscriber(sub)
+	}
+
+	return nil
+}
+
+/*
+subscriptionEventType returns the event type name of a given graph event.
+*/
+func subscriptionEventType(event int) string {
+	switch event {
+	case graph.EventNodeCreated, graph.EventEdgeCreated:
+		return "created"
+	case graph.EventNodeUpdated, graph.EventEdgeUpdated:
+		return "updated"
+	}
+	return "deleted"
+}
+
+/*
+AddSubscriber adds a new subscriber for rule events.
+*/
+func (r *SystemRuleSubscriptions) AddSubscriber(sub *subscriber) {
+	r.subscribersLock.Lock()
+	defer r.subscribersLock.Unlock()
+	r.subscribers[sub.id] = sub
+}
+
+/*
+RemoveSubscriber removes a subscriber from receiving further rule events. The
+event channel of the subscriber is closed.
+*/
+func (r *SystemRuleSubscriptions) RemoveSubscriber(sub *subscriber) {
+	r.subscribersLock.Lock()
+	defer r.subscribersLock.Unlock()
+
+	if _, ok := r.subscribers[sub.id]; ok {
+		delete(r.subscribers, sub.id)
+		close(sub.events)
+	}
+}
+
+/*
+dropSubscriber removes a subscriber which could not keep up with events.
+*/
+func (r *SystemRuleSubscriptions) dropSubscriber(sub *subscriber) {
+	r.subscribersLock.Lock()
+	defer r.subscribersLock.Unlock()
+
+	if _, ok := r.subscribers[sub.id]; ok {
+		delete(r.subscribers, sub.id)
+		sub.dropped = true
+		close(sub.events)
+	}
+}
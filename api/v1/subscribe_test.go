@@ -0,0 +1,189 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeMissingKind(t *testing.T) {
+	queryURL := "ws://localhost" + TESTPORT + EndpointSubscribe + "main"
+
+	c, _, err := websocket.DefaultDialer.Dial(queryURL, nil)
+	if err != nil {
+		t.Error("Could not open websocket:", err)
+		return
+	}
+	defer c.Close()
+
+	_, _, err = c.ReadMessage()
+	if err == nil || err.Error() != "websocket: close 1003 (unsupported data): Need a 'partition' and a 'kind' in path or as url parameter" {
+		t.Error("Unexpected response:", err)
+		return
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	queryURL := "ws://localhost" + TESTPORT + EndpointSubscribe + "main/SubAuthor"
+
+	c, _, err := websocket.DefaultDialer.Dial(queryURL, nil)
+	if err != nil {
+		t.Error("Could not open websocket:", err)
+		return
+	}
+
+	_, message, err := c.ReadMessage()
+	if msg := formatJSONString(string(message)); err != nil || msg != `{
+  "type": "subscription_success",
+  "payload": {}
+}` {
+		t.Error("Unexpected response:", msg, err)
+		return
+	}
+
+	// Changes of other kinds or in other partitions are not received
+
+	other := data.NewGraphNode()
+	other.SetAttr("key", "1")
+	other.SetAttr("kind", "SubOther")
+	api.GM.StoreNode("main", other)
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "1")
+	node.SetAttr("kind", "SubAuthor")
+	node.SetAttr("name", "Hans")
+	api.GM.StoreNode("test", node)
+	api.GM.StoreNode("main", node)
+
+	_, message, err = c.ReadMessage()
+	if msg := formatJSONString(string(message)); err != nil || msg != `{
+  "entity_type": "n",
+  "partition": "main",
+  "payload": {
+    "key": "1",
+    "kind": "SubAuthor",
+    "name": "Hans"
+  },
+  "type": "created"
+}` {
+		t.Error("Unexpected response:", msg, err)
+		return
+	}
+
+	node.SetAttr("name", "Otto")
+	api.GM.StoreNode("main", node)
+
+	_, message, err = c.ReadMessage()
+	if msg := formatJSONString(string(message)); err != nil || msg != `{
+  "entity_type": "n",
+  "partition": "main",
+  "payload": {
+    "key": "1",
+    "kind": "SubAuthor",
+    "name": "Otto"
+  },
+  "type": "updated"
+}` {
+		t.Error("Unexpected response:", msg, err)
+		return
+	}
+
+	api.GM.RemoveNode("test", "1", "SubAuthor")
+	api.GM.RemoveNode("main", "1", "SubAuthor")
+	api.GM.RemoveNode("main", "1", "SubOther")
+
+	_, message, err = c.ReadMessage()
+	if msg := formatJSONString(string(message)); err != nil || msg != `{
+  "entity_type": "n",
+  "partition": "main",
+  "payload": {
+    "key": "1",
+    "kind": "SubAuthor",
+    "name": "Otto"
+  },
+  "type": "deleted"
+}` {
+		t.Error("Unexpected response:", msg, err)
+		return
+	}
+
+	// Disconnecting clients are removed
+
+	c.Close()
+
+	for i := 0; i < 100 && subscriptionCount() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if res := subscriptionCount(); res != 0 {
+		t.Error("Unexpected number of subscribers:", res)
+		return
+	}
+
+	api.GM.StoreNode("main", node)
+	api.GM.RemoveNode("main", "1", "SubAuthor")
+}
+
+func TestSubscribeSlowConsumer(t *testing.T) {
+
+	sub := &subscriber{"123", "main", "SubAuthor", make(chan []byte, 1), false}
+
+	subscriptionRule.AddSubscriber(sub)
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "1")
+	node.SetAttr("kind", "SubAuthor")
+
+	// The first event is buffered - the second drops the subscriber
+
+	subscriptionRule.Handle(nil, nil, graph.EventNodeCreated, "main", node)
+
+	if sub.dropped || subscriptionCount() != 1 {
+		t.Error("Subscriber should not have been dropped")
+		return
+	}
+
+	subscriptionRule.Handle(nil, nil, graph.EventNodeUpdated, "main", node, node)
+
+	if !sub.dropped || subscriptionCount() != 0 {
+		t.Error("Subscriber should have been dropped")
+		return
+	}
+
+	// Buffered events can still be read before the channel is closed
+
+	if msg, ok := <-sub.events; !ok || string(msg) !=
+		`{"entity_type":"n","partition":"main","payload":{"key":"1","kind":"SubAuthor"},"type":"created"}` {
+		t.Error("Unexpected result:", string(msg), ok)
+		return
+	}
+
+	if _, ok := <-sub.events; ok {
+		t.Error("Channel should be closed")
+		return
+	}
+
+	// Removing a dropped subscriber is a NOP
+
+	subscriptionRule.RemoveSubscriber(sub)
+}
+
+func subscriptionCount() int {
+	subscriptionRule.subscribersLock.RLock()
+	defer subscriptionRule.subscribersLock.RUnlock()
+	return len(subscriptionRule.subscribers)
+}
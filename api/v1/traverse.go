@@ -0,0 +1,295 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"devt.de/krotik/eliasdb/api"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+/*
+EndpointTraverse is the bulk traversal endpoint URL (rooted). Handles everything under traverse/...
+*/
+const EndpointTraverse = api.APIRoot + APIv1 + "/traverse/"
+
+/*
+MaxBulkTraversalDepth is the maximum number of traversal steps which can be
+requested in a single bulk traversal.
+*/
+var MaxBulkTraversalDepth = 10
+
+/*
+MaxBulkTraversalNodes is the maximum number of nodes which can be collected
+in a single bulk traversal (including the start nodes).
+*/
+var MaxBulkTraversalNodes = 10000
+
+/*
+TraverseEndpointInst creates a new endpoint handler.
+*/
+func TraverseEndpointInst() api.RestEndpointHandler {
+	return &traverseEndpoint{}
+}
+
+/*
+Handler object for bulk traversals.
+*/
+type traverseEndpoint struct {
+	*api.DefaultEndpointHandler
+}
+
+/*
+bulkTraversalRequest is the expected request body of a bulk traversal.
+*/
+type bulkTraversalRequest struct {
+	Start     []map[string]string `json:"start"`     // Start nodes as kind / key pairs
+	Spec      string              `json:"spec"`      // Traversal spec
+	Direction string              `json:"direction"` // Direction of followed edges (in, out or all edges if empty)
+	Depth     int                 `json:"depth"`     // Number of traversal steps (default 1)
+	Limit     int                 `json:"limit"`     // Maximum number of returned nodes
+	Depths    bool                `json:"depths"`    // Flag if the hop distance of each node should be returned
+}
+
+/*
+HandlePOST handles a bulk traversal REST call.
+*/
+func (te *traverseEndpoint) HandlePOST(w http.ResponseWriter, r *http.Request, resources []string) {
+
+	if !checkResources(w, resources, 1, 1, "Need a partition") {
+		return
+	}
+
+	part := resources[0]
+
+	req := &bulkTraversalRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "Could not decode request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Start) == 0 {
+		http.Error(w, "Need at least one start node", http.StatusBadRequest)
+		return
+	}
+
+	if req.Spec == "" {
+		http.Error(w, "Need a traversal spec", http.StatusBadRequest)
+		return
+	}
+
+	if !checkDirection(w, req.Direction) {
+		return
+	}
+
+	depth := req.Depth
+	if depth <= 0 {
+		depth = 1
+	} else if depth > MaxBulkTraversalDepth {
+		http.Error(w, fmt.Sprintf("Depth exceeds maximum of %v", MaxBulkTraversalDepth), http.StatusBadRequest)
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > MaxBulkTraversalNodes {
+		limit = MaxBulkTraversalNodes
+	}
+
+	nodes := make(map[string]map[string]interface{})
+	edges := make(map[string]map[string]interface{})
+	depths := make(map[string]int)
+
+	var current []data.Node
+
+	// Lookup the start nodes
+
+	for _, s := range req.Start {
+		kind, key := s[data.NodeKind], s[data.NodeKey]
+
+		if kind == "" || key == "" {
+			http.Error(w, "Start nodes need a kind and a key", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := nodes[kind+"#"+key]; ok || len(nodes) >= limit {
+			continue
+		}
+
+		node, err := api.GM.FetchNode(part, key, kind)
+
+		if err == nil && node != nil {
+			err = addDerivedAttributes(part, node)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		} else if node == nil {
+			http.Error(w, fmt.Sprintf("Unknown start node %v (%v)", key, kind), http.StatusBadRequest)
+			return
+		}
+
+		nodes[kind+"#"+key] = node.Data()
+		depths[kind+"#"+key] = 0
+		current = append(current, node)
+	}
+
+	// Traverse level by level - each node is only traversed once and keeps
+	// the depth of the level on which it was first reached (minimum depth)
+
+	for i := 0; i < depth && len(current) > 0 && len(nodes) < limit; i++ {
+		var next []data.Node
+
+		for _, node := range current {
+
+			tnodes, tedges, err := api.GM.TraverseMultiDirection(part, node.Key(), node.Kind(),
+				req.Spec, req.Direction, true)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			for j, tnode := range tnodes {
+				nid := tnode.Kind() + "#" + tnode.Key()
+
+				if _, ok := nodes[nid]; !ok {
+
+					if len(nodes) >= limit {
+						break
+					}
+
+					if err := addDerivedAttributes(part, tnode); err != nil {
+						http.Error(w, err.Error(), http.StatusInternalServerError)
+						return
+					}
+
+					nodes[nid] = tnode.Data()
+					depths[nid] = i + 1
+					next = append(next, tnode)
+				}
+
+				edges[tedges[j].Kind()+"#"+tedges[j].Key()] = tedges[j].Data()
+			}
+		}
+
+		current = next
+	}
+
+	// Write data
+
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+
+	res := map[string]interface{}{
+		"nodes": sortedTraversalData(nodes),
+		"edges": sortedTraversalData(edges),
+	}
+
+	if req.Depths {
+
+		// Depths are given in the same order as the returned nodes
+
+		nodeDepths := make([]interface{}, 0, len(nodes))
+		for _, id := range sortedTraversalIDs(nodes) {
+			nodeDepths = append(nodeDepths, depths[id])
+		}
+
+		res["depths"] = nodeDepths
+	}
+
+	ret := json.NewEncoder(w)
+	ret.Encode(formatOutputFloats(res))
+}
+
+/*
+sortedTraversalData returns the values of a given map of graph elements sorted
+by their ids.
+*/
+func sortedTraversalData(m map[string]map[string]interface{}) []interface{} {
+	ids := sortedTraversalIDs(m)
+
+	ret := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, m[id])
+	}
+
+	return ret
+}
+
+/*
+sortedTraversalIDs returns the sorted ids of a given map of graph elements.
+*/
+func sortedTraversalIDs(m map[string]map[string]interface{}) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+/*
+SwaggerDefs is used to describe the endpoint in swagger.
+*/
+func (te *traverseEndpoint) SwaggerDefs(s map[string]interface{}) {
+
+	s["paths"].(map[string]interface{})["/v1/traverse/{partition}"] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary":     "Traverse the graph from multiple start nodes.",
+			"description": "The traverse endpoint returns the merged and de-duplicated set of nodes and edges which can be reached from a list of start nodes via a traversal spec.",
+			"consumes": []string{
+				"application/json",
+			},
+			"produces": []string{
+				"text/plain",
+				"application/json",
+			},
+			"parameters": []map[string]interface{}{
+				{
+					"name":        "partition",
+					"in":          "path",
+					"description": "Partition to traverse.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name": "request",
+					"in":   "body",
+					"description": "Start nodes (list of objects with kind and key), traversal spec, optional direction (out or in), optional depth (default 1), optional node limit and " +
+						"an optional depths flag.",
+					"required": true,
+					"schema": map[string]interface{}{
+						"type": "object",
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Object with a list of nodes and a list of edges. If the depths flag was set " +
+						"the object also contains a list with the minimum number of traversal steps from the " +
+						"start nodes for each returned node (in the same order as the nodes).",
+				},
+				"default": map[string]interface{}{
+					"description": "Error response",
+					"schema": map[string]interface{}{
+						"$ref": "#/definitions/Error",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestBulkTraversal(t *testing.T) {
+	queryURL := "http://localhost" + TESTPORT + EndpointTraverse
+
+	countResult := func(res string) (int, int) {
+		var ret map[string][]interface{}
+		if err := json.Unmarshal([]byte(res), &ret); err != nil {
+			t.Error("Could not decode result:", res, err)
+			return -1, -1
+		}
+		return len(ret["nodes"]), len(ret["edges"])
+	}
+
+	st, _, res := sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start" : [{"kind" : "Author", "key" : "456"}],
+  "spec"  : ":Wrote::"
+}`))
+
+	if st != "200 OK" || res != `
+{
+  "edges": [
+    {
+      "end1cascading": true,
+      "end1key": "456",
+      "end1kind": "Author",
+      "end1role": "Author",
+      "end2cascading": false,
+      "end2key": "MyOnlySong3",
+      "end2kind": "Song",
+      "end2role": "Song",
+      "key": "MyOnlySong3",
+      "kind": "Wrote",
+      "number": 3
+    }
+  ],
+  "nodes": [
+    {
+      "key": "456",
+      "kind": "Author",
+      "name": "Hans"
+    },
+    {
+      "key": "MyOnlySong3",
+      "kind": "Song",
+      "name": "MyOnlySong3",
+      "ranking": 19
+    }
+  ]
+}`[1:] {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Overlapping start nodes are merged
+
+	_, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start" : [{"kind" : "Author", "key" : "000"}, {"kind" : "Author", "key" : "456"},
+             {"kind" : "Song", "key" : "Aria1"}, {"kind" : "Author", "key" : "000"}],
+  "spec"  : ":Wrote::"
+}`))
+
+	if n, e := countResult(res); n != 7 || e != 5 {
+		t.Error("Unexpected result:", n, e, res)
+		return
+	}
+
+	// Deeper traversals
+
+	_, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start" : [{"kind" : "Song", "key" : "LoveSong3"}],
+  "spec"  : ":Wrote::",
+  "depth" : 2
+}`))
+
+	if n, e := countResult(res); n != 5 || e != 4 {
+		t.Error("Unexpected result:", n, e, res)
+		return
+	}
+
+	// Depths of nodes can be returned - nodes are sorted by kind and key
+
+	_, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start"  : [{"kind" : "Song", "key" : "LoveSong3"}],
+  "spec"   : ":Wrote::",
+  "depth"  : 2,
+  "depths" : true
+}`))
+
+	var depthRes map[string][]interface{}
+	if err := json.Unmarshal([]byte(res), &depthRes); err != nil ||
+		fmt.Sprint(depthRes["depths"]) != "[1 2 2 0 2]" {
+		t.Error("Unexpected result:", depthRes["depths"], res)
+		return
+	}
+
+	// Limit applies to the whole operation
+
+	_, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start" : [{"kind" : "Song", "key" : "LoveSong3"}],
+  "spec"  : ":Wrote::",
+  "depth" : 2,
+  "limit" : 3
+}`))
+
+	if n, e := countResult(res); n != 3 || e != 2 {
+		t.Error("Unexpected result:", n, e, res)
+		return
+	}
+
+	// Error cases
+
+	st, _, res = sendTestRequest(queryURL, "POST", []byte(`{}`))
+	if st != "400 Bad Request" || res != "Need a partition" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{`))
+	if st != "400 Bad Request" || res != "Could not decode request body: unexpected EOF" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	// Only follow edges in a given direction - songs are the second end of Wrote edges
+
+	_, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start"     : [{"kind" : "Song", "key" : "MyOnlySong3"}],
+  "spec"      : ":Wrote::",
+  "direction" : "in"
+}`))
+
+	if n, e := countResult(res); n != 2 || e != 1 {
+		t.Error("Unexpected result:", n, e, res)
+		return
+	}
+
+	_, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{
+  "start"     : [{"kind" : "Song", "key" : "MyOnlySong3"}],
+  "spec"      : ":Wrote::",
+  "direction" : "out"
+}`))
+
+	if n, e := countResult(res); n != 1 || e != 0 {
+		t.Error("Unexpected result:", n, e, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"start" : [{"kind" : "Author", "key" : "456"}], "spec" : ":::", "direction" : "both"}`))
+	if st != "400 Bad Request" || res != "Invalid direction: both (must be out or in)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"spec" : ":::"}`))
+	if st != "400 Bad Request" || res != "Need at least one start node" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"start" : [{"kind" : "Author", "key" : "456"}]}`))
+	if st != "400 Bad Request" || res != "Need a traversal spec" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"start" : [{"kind" : "Author"}], "spec" : ":::"}`))
+	if st != "400 Bad Request" || res != "Start nodes need a kind and a key" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"start" : [{"kind" : "Author", "key" : "999"}], "spec" : ":::"}`))
+	if st != "400 Bad Request" || res != "Unknown start node 999 (Author)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"start" : [{"kind" : "Author", "key" : "456"}], "spec" : ":::", "depth" : 11}`))
+	if st != "400 Bad Request" || res != "Depth exceeds maximum of 10" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+
+	st, _, res = sendTestRequest(queryURL+"main", "POST", []byte(`{"start" : [{"kind" : "Author", "key" : "456"}], "spec" : "::"}`))
+	if st != "400 Bad Request" || res != "GraphError: Invalid data (Invalid spec: ::)" {
+		t.Error("Unexpected response:", st, res)
+		return
+	}
+}
@@ -51,6 +51,7 @@ import (
 	"devt.de/krotik/common/errorutil"
 	"devt.de/krotik/common/fileutil"
 	"devt.de/krotik/common/termutil"
+	"devt.de/krotik/eliasdb/api"
 	"devt.de/krotik/eliasdb/config"
 	"devt.de/krotik/eliasdb/console"
 	"devt.de/krotik/eliasdb/graph"
@@ -112,9 +113,11 @@ RunCliConsole runs the server console on the commandline.
 func RunCliConsole() {
 	var err error
 
-	// Try to get the server host and port from the config file
+	// Try to get the server host, port and API root path from the config file
 
-	chost, cport := getHostPortFromConfig()
+	chost, cport, croot := getConnectionFromConfig()
+
+	api.APIRootPath = croot
 
 	host := flag.String("host", chost, "Host of the EliasDB server")
 	port := flag.String("port", cport, "Port of the EliasDB server")
@@ -265,12 +268,13 @@ func RunCliConsole() {
 }
 
 /*
-getHostPortFromConfig gets the host and port from the config file or the
-default config.
+getConnectionFromConfig gets the host, port and API root path from the config
+file or the default config.
 */
-func getHostPortFromConfig() (string, string) {
+func getConnectionFromConfig() (string, string, string) {
 	host := fileutil.ConfStr(config.DefaultConfig, config.HTTPSHost)
 	port := fileutil.ConfStr(config.DefaultConfig, config.HTTPSPort)
+	root := fileutil.ConfStr(config.DefaultConfig, config.APIRootPath)
 
 	if ok, _ := fileutil.PathExists(config.DefaultConfigFile); ok {
 		cfg, _ := fileutil.LoadConfig(config.DefaultConfigFile, config.DefaultConfig)
@@ -278,10 +282,11 @@ func getHostPortFromConfig() (string, string) {
 
 			host = fileutil.ConfStr(cfg, config.HTTPSHost)
 			port = fileutil.ConfStr(cfg, config.HTTPSPort)
+			root = fileutil.ConfStr(cfg, config.APIRootPath)
 		}
 	}
 
-	return host, port
+	return host, port, strings.TrimSuffix("/"+strings.Trim(root, "/"), "/")
 }
 
 /*
@@ -56,9 +56,41 @@ const (
 	EnableClusterTerminal    = "EnableClusterTerminal"
 	ResultCacheMaxSize       = "ResultCacheMaxSize"
 	ResultCacheMaxAgeSeconds = "ResultCacheMaxAgeSeconds"
+	ResultSpillRows          = "ResultSpillRows"
+	LocationResultSpill      = "LocationResultSpill"
 	ClusterStateInfoFile     = "ClusterStateInfoFile"
 	ClusterConfigFile        = "ClusterConfigFile"
 	ClusterLogHistory        = "ClusterLogHistory"
+	OutputFloatFormat        = "OutputFloatFormat"
+	OutputFloatPrecision     = "OutputFloatPrecision"
+	QueryScanWorkers         = "QueryScanWorkers"
+	MaxAttributeValueSize    = "MaxAttributeValueSize"
+	DerivedAttributes        = "DerivedAttributes"
+	MaxPathKeyLength         = "MaxPathKeyLength"
+	APIRootPath              = "APIRootPath"
+	EnableEmptyListNoContent = "EnableEmptyListNoContent"
+	MaxShortestPathDepth     = "MaxShortestPathDepth"
+	CompressionMinSize       = "CompressionMinSize"
+	CORSAllowedOrigins       = "CORSAllowedOrigins"
+	CORSAllowedMethods       = "CORSAllowedMethods"
+	CORSAllowedHeaders       = "CORSAllowedHeaders"
+	CORSAllowCredentials     = "CORSAllowCredentials"
+	AuthTokens               = "AuthTokens"
+	AuthTokenOpenRead        = "AuthTokenOpenRead"
+	StorageCacheSize         = "StorageCacheSize"
+	StorageCachePartitions   = "StorageCachePartitions"
+	StorageChecksums         = "StorageChecksums"
+	AttributeNameMapping     = "AttributeNameMapping"
+	AttributeNameCasing      = "AttributeNameCasing"
+	RateLimitRead            = "RateLimitRead"
+	RateLimitWrite           = "RateLimitWrite"
+	RateLimitBurst           = "RateLimitBurst"
+	RateLimitClientHeader    = "RateLimitClientHeader"
+	RateLimitMaxClients      = "RateLimitMaxClients"
+	EnableRequestLog         = "EnableRequestLog"
+	RequestLogFormat         = "RequestLogFormat"
+	RequestLogBodies         = "RequestLogBodies"
+	InfoSchemaSampleSize     = "InfoSchemaSampleSize"
 )
 
 /*
@@ -85,9 +117,41 @@ var DefaultConfig = map[string]interface{}{
 	LockFile:                 "eliasdb.lck",
 	ResultCacheMaxSize:       0,
 	ResultCacheMaxAgeSeconds: 0,
+	ResultSpillRows:          0,
+	LocationResultSpill:      "",
 	ClusterStateInfoFile:     "cluster.stateinfo",
 	ClusterConfigFile:        "cluster.config.json",
 	ClusterLogHistory:        100.0,
+	OutputFloatFormat:        "g",
+	OutputFloatPrecision:     -1,
+	QueryScanWorkers:         0,
+	MaxAttributeValueSize:    10485760,
+	DerivedAttributes:        map[string]interface{}{},
+	MaxPathKeyLength:         1024,
+	APIRootPath:              "/db",
+	EnableEmptyListNoContent: false,
+	MaxShortestPathDepth:     10,
+	CompressionMinSize:       1024,
+	CORSAllowedOrigins:       []interface{}{},
+	CORSAllowedMethods:       []interface{}{"GET", "POST", "PUT", "PATCH", "DELETE"},
+	CORSAllowedHeaders:       []interface{}{"Content-Type", "Authorization"},
+	CORSAllowCredentials:     false,
+	AuthTokens:               []interface{}{},
+	AuthTokenOpenRead:        true,
+	StorageCacheSize:         100000,
+	StorageCachePartitions:   map[string]interface{}{},
+	StorageChecksums:         false,
+	AttributeNameMapping:     map[string]interface{}{},
+	AttributeNameCasing:      "",
+	RateLimitRead:            0,
+	RateLimitWrite:           0,
+	RateLimitBurst:           10,
+	RateLimitClientHeader:    "",
+	RateLimitMaxClients:      10000,
+	EnableRequestLog:         false,
+	RequestLogFormat:         "text",
+	RequestLogBodies:         false,
+	InfoSchemaSampleSize:     100,
 }
 
 /*
@@ -153,6 +217,28 @@ func Bool(key string) bool {
 	return ret
 }
 
+/*
+StrList reads a config value as a list of string values. A single value
+which is not a list is returned as a list with one element.
+*/
+func StrList(key string) []string {
+	var ret []string
+
+	switch val := Config[key].(type) {
+	case nil:
+	case []interface{}:
+		for _, v := range val {
+			ret = append(ret, fmt.Sprint(v))
+		}
+	case []string:
+		ret = append(ret, val...)
+	default:
+		ret = append(ret, fmt.Sprint(val))
+	}
+
+	return ret
+}
+
 /*
 WebPath returns a path relative to the web directory.
 */
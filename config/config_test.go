@@ -59,6 +59,30 @@ func TestConfig(t *testing.T) {
 		return
 	}
 
+	if res := StrList(CORSAllowedMethods); fmt.Sprint(res) != "[GET POST PUT PATCH DELETE]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	Config[CORSAllowedOrigins] = "*"
+
+	if res := StrList(CORSAllowedOrigins); fmt.Sprint(res) != "[*]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	Config[CORSAllowedOrigins] = []string{"a", "b"}
+
+	if res := StrList(CORSAllowedOrigins); fmt.Sprint(res) != "[a b]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := StrList("foo"); res != nil {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
 	if res := WebPath("123", "456"); res != "web/123/456" {
 		t.Error("Unexpected result:", res)
 		return
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"devt.de/krotik/common/errorutil"
+	"devt.de/krotik/eliasdb/api"
 	"devt.de/krotik/eliasdb/api/ac"
 	"devt.de/krotik/eliasdb/config"
 )
@@ -462,9 +463,9 @@ func (c *EliasDBConsole) SendRequest(endpoint string, contentType string, method
 	var err error
 
 	if content != nil {
-		req, err = http.NewRequest(method, c.url+endpoint, bytes.NewBuffer(content))
+		req, err = http.NewRequest(method, c.url+api.EndpointPath(endpoint), bytes.NewBuffer(content))
 	} else {
-		req, err = http.NewRequest(method, c.url+endpoint, nil)
+		req, err = http.NewRequest(method, c.url+api.EndpointPath(endpoint), nil)
 	}
 
 	if err == nil {
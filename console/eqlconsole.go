@@ -31,7 +31,7 @@ type EQLConsole struct {
 /*
 eqlConsoleKeywords are all keywords which this console can process.
 */
-var eqlConsoleKeywords = []string{"part", "get", "lookup"}
+var eqlConsoleKeywords = []string{"part", "get", "lookup", "path", "describe", "explain"}
 
 /*
 Run executes one or more commands. It returns an error if the command
@@ -54,6 +54,12 @@ func (c *EQLConsole) Run(cmd string) (bool, error) {
 		res := resObj.(map[string]interface{})
 		var out []string
 
+		// Query errors are returned as structured error objects
+
+		if errObj, ok := res["error"].(map[string]interface{}); ok {
+			return true, fmt.Errorf("%v", errObj["message"])
+		}
+
 		header := res["header"].(map[string]interface{})
 
 		labels := header["labels"].([]interface{})
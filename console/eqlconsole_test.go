@@ -95,4 +95,10 @@ Login as user elias
 	}
 
 	out.Reset()
+
+	if ok, err := c.Run("get Bla"); !ok || err == nil ||
+		err.Error() != "EQL error in Main query: Unknown node kind (Bla) (Line:1 Pos:5)" {
+		t.Error(ok, err)
+		return
+	}
 }
@@ -0,0 +1,275 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"devt.de/krotik/eliasdb/eql/parser"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+// Runtime provider for DESCRIBE queries
+// =====================================
+
+/*
+Instance function for DESCRIBE query components
+*/
+type describeInst func(*DescribeRuntimeProvider, *parser.ASTNode) parser.Runtime
+
+/*
+Runtime map for DESCRIBE query specific components
+*/
+var describeProviderMap = map[string]describeInst{
+	parser.NodeDESCRIBE: describeRuntimeInst,
+}
+
+/*
+DescribeRuntimeProvider data structure
+*/
+type DescribeRuntimeProvider struct {
+	*eqlRuntimeProvider
+}
+
+/*
+NewDescribeRuntimeProvider creates a new DescribeRuntimeProvider object. This
+provider can interpret DESCRIBE queries.
+*/
+func NewDescribeRuntimeProvider(name string, part string, gm *graph.Manager, ni NodeInfo) *DescribeRuntimeProvider {
+	return &DescribeRuntimeProvider{&eqlRuntimeProvider{name, part, gm, ni, "", false, false, -1, -1, nil, "",
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, nil, 0, "", 0, nil, nil, nil, nil, &sync.Mutex{}, &sync.Mutex{}}}
+}
+
+/*
+Runtime returns a runtime component for a given ASTNode.
+*/
+func (rtp *DescribeRuntimeProvider) Runtime(node *parser.ASTNode) parser.Runtime {
+	if pinst, ok := generalProviderMap[node.Name]; ok {
+		return pinst(rtp.eqlRuntimeProvider, node)
+	} else if pinst, ok := describeProviderMap[node.Name]; ok {
+		return pinst(rtp, node)
+	}
+	return invalidRuntimeInst(rtp.eqlRuntimeProvider, node)
+}
+
+// DESCRIBE Runtime
+// ================
+
+type describeRuntime struct {
+	rtp  *DescribeRuntimeProvider
+	node *parser.ASTNode
+
+	kind   string // Kind which should be described
+	isEdge bool   // Flag if the kind is an edge kind
+}
+
+func describeRuntimeInst(rtp *DescribeRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &describeRuntime{rtp, node, "", false}
+}
+
+/*
+ Validate and reset this runtime component and all its child components.
+*/
+func (rt *describeRuntime) Validate() error {
+
+	// First child is always the kind which should be described
+
+	rt.kind = rt.node.Children[0].Token.Val
+	rt.isEdge = false
+
+	if !rt.hasKind(rt.rtp.gm.NodeKinds()) {
+
+		if !rt.hasKind(rt.rtp.gm.EdgeKinds()) {
+			return rt.rtp.newRuntimeError(ErrUnknownKind, rt.kind, rt.node.Children[0])
+		}
+
+		rt.isEdge = true
+	}
+
+	// Initialise the result columns - the describe result always has a fixed
+	// layout with one row per attribute
+
+	rt.rtp.withFlags = &withFlags{make([]byte, 0), make([]int, 0), make([]int, 0),
+		make([]int, 0), make([]bool, 0), make([]int, 0), make([]string, 0)}
+
+	rt.rtp.primaryKind = rt.kind
+
+	rt.rtp.colLabels = []string{"Attribute", "Types", "Count"}
+	rt.rtp.colFormat = []string{"auto", "auto", "auto"}
+	rt.rtp.colData = []string{"1:n:attribute", "1:n:types", "1:n:count"}
+	rt.rtp.colFunc = []FuncShow{nil, nil, nil}
+
+	return nil
+}
+
+/*
+hasKind checks if the described kind is in a given list of kinds.
+*/
+func (rt *describeRuntime) hasKind(kinds []string) bool {
+	for _, k := range kinds {
+		if k == rt.kind {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *describeRuntime) Eval() (interface{}, error) {
+
+	if err := rt.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, err := parser.PrettyPrint(rt.node)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newSearchResult(rt.rtp.eqlRuntimeProvider, query)
+
+	attrs := rt.rtp.gm.NodeAttrs(rt.kind)
+	if rt.isEdge {
+		attrs = rt.rtp.gm.EdgeAttrs(rt.kind)
+	}
+
+	types, counts, err := rt.observeAttributes()
+
+	if err == nil {
+
+		sort.Strings(attrs)
+
+		for _, attr := range attrs {
+			var attrTypes []string
+
+			for t := range types[attr] {
+				attrTypes = append(attrTypes, t)
+			}
+
+			sort.Strings(attrTypes)
+
+			row := data.NewGraphNode()
+			row.SetAttr("attribute", attr)
+			row.SetAttr("types", strings.Join(attrTypes, ", "))
+			row.SetAttr("count", counts[attr])
+
+			if err = res.addRow([]data.Node{row}, []data.Edge{nil}); err != nil {
+				break
+			}
+
+			// Rows are not backed by a stored node or edge
+
+			res.Source[len(res.Source)-1] = []string{"", "", ""}
+		}
+
+		if finishErr := res.finish(); err == nil {
+			err = finishErr
+		}
+	}
+
+	return res, err
+}
+
+/*
+observeAttributes goes through all stored nodes or edges of the described kind
+and collects for each attribute the types of its values and the number of
+nodes or edges which have a value.
+*/
+func (rt *describeRuntime) observeAttributes() (map[string]map[string]bool, map[string]int, error) {
+	var keys []string
+
+	types := make(map[string]map[string]bool)
+	counts := make(map[string]int)
+
+	if rt.isEdge {
+		it, err := rt.rtp.gm.EdgeKeyIterator(rt.rtp.part, rt.kind)
+
+		for err == nil && it != nil && it.HasNext() {
+			keys = append(keys, it.Next())
+			err = it.LastError
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+	} else {
+		it, err := rt.rtp.gm.NodeKeyIterator(rt.rtp.part, rt.kind)
+
+		for err == nil && it != nil && it.HasNext() {
+			keys = append(keys, it.Next())
+			err = it.LastError
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, key := range keys {
+		var item data.Node
+		var err error
+
+		if err = rt.rtp.checkCancelled(); err != nil {
+			return nil, nil, err
+		}
+
+		if rt.isEdge {
+			item, err = rt.rtp.gm.FetchEdge(rt.rtp.part, key, rt.kind)
+		} else {
+			item, err = rt.rtp.gm.FetchNode(rt.rtp.part, key, rt.kind)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for attr, val := range item.Data() {
+			if val == nil {
+				continue
+			}
+
+			if _, ok := types[attr]; !ok {
+				types[attr] = make(map[string]bool)
+			}
+
+			types[attr][valueTypeName(val)] = true
+			counts[attr]++
+		}
+	}
+
+	return types, counts, nil
+}
+
+/*
+valueTypeName returns a type name for a given attribute value.
+*/
+func valueTypeName(val interface{}) string {
+	switch val.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "number"
+	case []interface{}, []string:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", val)
+}
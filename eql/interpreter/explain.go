@@ -0,0 +1,272 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"devt.de/krotik/eliasdb/eql/parser"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+// EXPLAIN Runtime
+// ===============
+
+/*
+explainRuntime is the runtime for explain queries. It describes how an
+explained query would be executed without executing it.
+*/
+type explainRuntime struct {
+	rtp  *eqlRuntimeProvider
+	node *parser.ASTNode
+}
+
+/*
+planStep is a single step in the execution plan of a query.
+*/
+type planStep struct {
+	operation string // Operation of the step
+	detail    string // Details of the operation
+	cost      uint64 // Estimated number of nodes or edges which are read
+}
+
+/*
+explainRuntimeInst returns a new runtime component instance.
+*/
+func explainRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &explainRuntime{rtp, node}
+}
+
+/*
+ Validate and reset this runtime component and all its child components.
+*/
+func (rt *explainRuntime) Validate() error {
+
+	// Validating the explained query initialises the runtime provider with
+	// the start kinds, the where clause and all traversals
+
+	if err := rt.node.Children[0].Runtime.Validate(); err != nil {
+		return err
+	}
+
+	// Initialise the result columns - the explain result always has a fixed
+	// layout with one row per step of the execution plan
+
+	rt.rtp.withFlags = &withFlags{make([]byte, 0), make([]int, 0), make([]int, 0),
+		make([]int, 0), make([]bool, 0), make([]int, 0), make([]string, 0)}
+
+	rt.rtp.distinct = false
+	rt.rtp.limit = -1
+	rt.rtp.offset = -1
+	rt.rtp.groupCol = make([]int, 0)
+
+	rt.rtp.colLabels = []string{"Step", "Operation", "Detail", "Cost"}
+	rt.rtp.colFormat = []string{"auto", "auto", "auto", "auto"}
+	rt.rtp.colData = []string{"1:n:step", "1:n:operation", "1:n:detail", "1:n:cost"}
+	rt.rtp.colFunc = []FuncShow{nil, nil, nil, nil}
+
+	return nil
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *explainRuntime) Eval() (interface{}, error) {
+
+	if err := rt.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, err := parser.PrettyPrint(rt.node)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newSearchResult(rt.rtp, query)
+
+	steps, err := rt.plan()
+
+	if err == nil {
+
+		for i, step := range steps {
+
+			row := data.NewGraphNode()
+			row.SetAttr("step", i+1)
+			row.SetAttr("operation", step.operation)
+			row.SetAttr("detail", step.detail)
+			row.SetAttr("cost", step.cost)
+
+			if err = res.addRow([]data.Node{row}, []data.Edge{nil}); err != nil {
+				break
+			}
+
+			// Rows are not backed by a stored node or edge
+
+			res.Source[len(res.Source)-1] = []string{"", "", "", ""}
+		}
+
+		if finishErr := res.finish(); err == nil {
+			err = finishErr
+		}
+	}
+
+	return res, err
+}
+
+/*
+plan produces the execution plan of the explained query. The start nodes are
+provided first, then the where clause is applied and finally all traversals
+are followed in the order of the query.
+*/
+func (rt *explainRuntime) plan() ([]*planStep, error) {
+	var steps []*planStep
+	var err error
+
+	child := rt.node.Children[0]
+
+	switch crt := child.Runtime.(type) {
+	case *lookupRuntime:
+		steps = rt.lookupSteps()
+	case *countRuntime:
+		steps, err = rt.startSteps(crt.getRuntime)
+	case *getRuntime:
+		steps, err = rt.startSteps(crt)
+	default:
+		err = rt.rtp.newRuntimeError(ErrInvalidConstruct,
+			"Only get, count and lookup queries can be explained", child)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// All start nodes are checked against the where clause
+
+	if rt.rtp.where != nil {
+		var cost uint64
+
+		for _, step := range steps {
+			cost += step.cost
+		}
+
+		cond, _ := parser.PrettyPrint(rt.rtp.where.Children[0])
+		steps = append(steps, &planStep{"filter", cond, cost})
+	}
+
+	// Traversals are followed depth first from each start node
+
+	from := len(steps)
+
+	for _, traversal := range rt.rtp.traversals {
+		steps = rt.traversalSteps(steps, traversal, from)
+	}
+
+	return steps, nil
+}
+
+/*
+startSteps returns the steps which provide the start nodes of a get or count
+query. Keys of start nodes are either looked up in an index, read from a group
+or all nodes of a kind are scanned.
+*/
+func (rt *explainRuntime) startSteps(grt *getRuntime) ([]*planStep, error) {
+	var steps []*planStep
+
+	for _, kind := range rt.rtp.startKinds {
+
+		if rt.rtp.groupScope != "" {
+
+			steps = append(steps, &planStep{"group traversal",
+				fmt.Sprintf("Nodes of kind %v in group %v", kind, rt.rtp.groupScope),
+				rt.rtp.gm.NodeCount(kind)})
+
+		} else if lookup, desc := grt.indexLookup(kind); lookup != nil {
+
+			// Index lookups only read the index - the number of found
+			// keys is the number of nodes which need to be read
+
+			keys, err := lookup()
+			if err != nil {
+				return nil, rt.rtp.newSourceError(err, rt.node)
+			}
+
+			steps = append(steps, &planStep{"index lookup",
+				fmt.Sprintf("Nodes of kind %v with %v", kind, desc), uint64(len(keys))})
+
+		} else {
+
+			steps = append(steps, &planStep{"full scan",
+				fmt.Sprintf("All nodes of kind %v", kind), rt.rtp.gm.NodeCount(kind)})
+		}
+	}
+
+	return steps, nil
+}
+
+/*
+lookupSteps returns the step which provides the start nodes of a lookup query.
+*/
+func (rt *explainRuntime) lookupSteps() []*planStep {
+	kind := rt.rtp.startKinds[0]
+
+	if rt.rtp.groupScope != "" {
+		return []*planStep{{"group traversal",
+			fmt.Sprintf("Nodes of kind %v in group %v", kind, rt.rtp.groupScope),
+			rt.rtp.gm.NodeCount(kind)}}
+	}
+
+	keys := rt.rtp.lookup.keys
+
+	return []*planStep{{"key lookup",
+		fmt.Sprintf("Nodes of kind %v by key", kind), uint64(len(keys))}}
+}
+
+/*
+traversalSteps adds the steps of a traversal and all its deeper traversals to
+a given list of steps. The cost of a traversal is estimated with the number of
+stored edges of the traversed edge kind.
+*/
+func (rt *explainRuntime) traversalSteps(steps []*planStep, traversal *parser.ASTNode,
+	from int) []*planStep {
+
+	trt := traversal.Runtime.(*traversalRuntime)
+
+	var cost uint64
+
+	if edgeKind := strings.Split(trt.spec, ":")[1]; edgeKind != "" {
+		cost = rt.rtp.gm.EdgeCount(edgeKind)
+	} else {
+		for _, kind := range rt.rtp.gm.EdgeKinds() {
+			cost += rt.rtp.gm.EdgeCount(kind)
+		}
+	}
+
+	steps = append(steps, &planStep{"traversal",
+		fmt.Sprintf("%v from step %v", trt.spec, from), cost})
+
+	if trt.where != nil {
+		cond, _ := parser.PrettyPrint(trt.where.Children[0])
+		steps = append(steps, &planStep{"filter", cond, cost})
+	}
+
+	// Deeper traversals start from the nodes of this traversal
+
+	from = len(steps)
+
+	for _, child := range traversal.Children[1:] {
+		if child.Name == parser.NodeTRAVERSE {
+			steps = rt.traversalSteps(steps, child, from)
+		}
+	}
+
+	return steps
+}
@@ -0,0 +1,63 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package interpreter
+
+import (
+	"devt.de/krotik/eliasdb/eql/parser"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+/*
+Expression is a single EQL expression (e.g. a where condition or an arithmetic
+expression) which can be evaluated on nodes.
+*/
+type Expression struct {
+	rtp  *eqlRuntimeProvider
+	node *parser.ASTNode
+}
+
+/*
+NewExpression parses a given EQL expression. The expression can then be
+evaluated on nodes of the given partition.
+*/
+func NewExpression(name string, part string, expr string, gm *graph.Manager, ni NodeInfo) (*Expression, error) {
+	rtp := NewGetRuntimeProvider(name, part, gm, ni)
+
+	ast, err := parser.ParseWithRuntime(name, "get _ where "+expr, rtp)
+	if err != nil {
+		return nil, err
+	}
+
+	// The expression must be the only thing in the where clause
+
+	if len(ast.Children) != 2 || ast.Children[1].Name != parser.NodeWHERE {
+		return nil, rtp.newRuntimeError(ErrInvalidConstruct, "Invalid expression: "+expr, ast)
+	}
+
+	rtp.attrsNodes = []map[string]string{make(map[string]string)}
+	rtp.attrsEdges = []map[string]string{make(map[string]string)}
+
+	where := ast.Children[1]
+
+	if err := where.Runtime.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Expression{rtp.eqlRuntimeProvider, where.Children[0]}, nil
+}
+
+/*
+Eval evaluates the expression on a given node.
+*/
+func (e *Expression) Eval(node data.Node) (interface{}, error) {
+	return e.node.Runtime.(CondRuntime).CondEval(node, nil)
+}
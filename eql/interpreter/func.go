@@ -14,14 +14,19 @@ Package interpreter contains the EQL interpreter.
 package interpreter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"devt.de/krotik/common/datautil"
 	"devt.de/krotik/common/errorutil"
+	"devt.de/krotik/common/stringutil"
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph/data"
 )
@@ -40,6 +45,7 @@ Runtime map for where related functions
 */
 var whereFunc = map[string]FuncWhere{
 	"count":     whereCount,
+	"date":      whereDate,
 	"parseDate": whereParseDate,
 }
 
@@ -71,8 +77,14 @@ func whereCount(astNode *parser.ASTNode, rtp *eqlRuntimeProvider,
 
 		conditionString := astNode.Children[2].Token.Val
 
+		// Parsing registers the attributes of the condition with the runtime
+		// provider - conditions may be evaluated by parallel scan workers
+
+		rtp.condMutex.Lock()
+
 		ast, err := parser.ParseWithRuntime("count condition", "get _ where "+conditionString, &GetRuntimeProvider{rtp})
 		if err != nil {
+			rtp.condMutex.Unlock()
 			return nil, rtp.newRuntimeError(ErrInvalidConstruct,
 				fmt.Sprintf("Invalid condition clause in count function: %s", err), astNode)
 		}
@@ -81,6 +93,8 @@ func whereCount(astNode *parser.ASTNode, rtp *eqlRuntimeProvider,
 
 		errorutil.AssertOk(cond.Runtime.Validate()) // Validation should alwasys succeed
 
+		rtp.condMutex.Unlock()
+
 		for _, n := range nodes {
 			res, err := cond.Children[0].Runtime.(CondRuntime).CondEval(n, nil)
 
@@ -149,6 +163,42 @@ func whereParseDate(astNode *parser.ASTNode, rtp *eqlRuntimeProvider,
 	return ret, err
 }
 
+/*
+whereDate converts a value into a time value which can be compared
+chronologically. The value is parsed with the date formats of the runtime
+provider.
+*/
+func whereDate(astNode *parser.ASTNode, rtp *eqlRuntimeProvider,
+	node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Check parameters
+
+	if len(astNode.Children) != 2 {
+		return nil, rtp.newRuntimeError(ErrInvalidConstruct,
+			"date function requires 1 parameter: date value", astNode)
+	}
+
+	val, err := astNode.Children[1].Runtime.(CondRuntime).CondEval(node, edge)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := rtp.parseDate(val)
+
+	if !ok {
+
+		// A value which is not a date does not match any date comparison
+
+		if rtp.strictDates {
+			return nil, rtp.newRuntimeError(ErrNotADate, fmt.Sprint(val), astNode.Children[1])
+		}
+
+		return nil, nil
+	}
+
+	return t, nil
+}
+
 // Show related functions
 // ======================
 
@@ -156,8 +206,17 @@ func whereParseDate(astNode *parser.ASTNode, rtp *eqlRuntimeProvider,
 Runtime map for show related functions
 */
 var showFunc = map[string]FuncShowInst{
-	"count":  showCountInst,
-	"objget": showObjgetInst,
+	"count":     showCountInst,
+	"objget":    showObjgetInst,
+	"argmax":    showArgmaxInst,
+	"argmin":    showArgminInst,
+	"countbool": showCountBoolInst,
+	"hash":      showHashInst,
+	"exists":    showExistsInst,
+	"sum":       showSumInst,
+	"avg":       showAvgInst,
+	"min":       showMinInst,
+	"max":       showMaxInst,
 }
 
 /*
@@ -181,6 +240,20 @@ type FuncShow interface {
 	eval(node data.Node, edge data.Edge) (interface{}, string, error)
 }
 
+/*
+FuncShowAggregate is the interface definition for show related functions which
+aggregate the values of all result rows into a single value. The eval function
+of an aggregate function returns the value of a single row.
+*/
+type FuncShowAggregate interface {
+	FuncShow
+
+	/*
+		aggregate combines the values of all rows into a single value.
+	*/
+	aggregate(values []interface{}) interface{}
+}
+
 /*
 FuncShowInst creates a function object. Returns which column data should be queried and
 how the colummn should be named.
@@ -200,8 +273,15 @@ func showCountInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow,
 
 	np := len(astNode.Children)
 
+	if np == 2 && isColDataSpec(astNode.Children[1].Token.Val) {
+
+		// A single column data parameter counts the values of all result rows
+
+		return showAggregateInst(astNode, rtp, "count")
+	}
+
 	if np != 3 && np != 4 {
-		return nil, "", "", errors.New("Count function requires 2 or 3 parameters: traversal step, traversal spec, condition clause")
+		return nil, "", "", errors.New("Count function requires 1 parameter: column data or 2 or 3 parameters: traversal step, traversal spec, condition clause")
 	}
 
 	pos := astNode.Children[1].Token.Val
@@ -335,9 +415,697 @@ func (so *showObjget) eval(node data.Node, edge data.Edge) (interface{}, string,
 
 	val := node.Attr(so.attr)
 
-	if valMap, ok := val.(map[string]interface{}); ok {
-		val, _ = datautil.GetNestedValue(valMap, so.path)
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		val = nestedValue(val, so.path)
 	}
 
 	return val, "n:" + node.Kind() + ":" + node.Key(), nil
 }
+
+// Show Argmax / Argmin
+// --------------------
+
+/*
+showArgmaxInst creates a new showArgExtreme object which looks for the maximum.
+*/
+func showArgmaxInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	return showArgExtremeInst(astNode, rtp, true)
+}
+
+/*
+showArgminInst creates a new showArgExtreme object which looks for the minimum.
+*/
+func showArgminInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	return showArgExtremeInst(astNode, rtp, false)
+}
+
+/*
+showArgExtremeInst creates a new showArgExtreme object.
+*/
+func showArgExtremeInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider, max bool) (FuncShow, string, string, error) {
+	name := "argmin"
+	if max {
+		name = "argmax"
+	}
+
+	// Check parameters
+
+	if len(astNode.Children) != 5 {
+		return nil, "", "", fmt.Errorf("%v function requires 4 parameters: traversal step, traversal spec, attribute name, result attribute name",
+			strings.ToUpper(name[:1])+name[1:])
+	}
+
+	pos := astNode.Children[1].Token.Val
+	spec := astNode.Children[2].Token.Val
+	attr := astNode.Children[3].Token.Val
+	resAttr := astNode.Children[4].Token.Val
+
+	return &showArgExtreme{rtp, name, max, spec, attr, resAttr}, pos + ":n:key",
+		rtp.ni.AttributeDisplayString("", resAttr), nil
+}
+
+/*
+showArgExtreme picks an attribute from the reachable node which has the
+maximum or minimum value of another attribute.
+*/
+type showArgExtreme struct {
+	rtp     *eqlRuntimeProvider
+	fname   string
+	max     bool
+	spec    string
+	attr    string
+	resAttr string
+}
+
+/*
+name returns the name of the function.
+*/
+func (sa *showArgExtreme) name() string {
+	return sa.fname
+}
+
+/*
+eval looks for the reachable node with the maximum or minimum attribute value.
+Numbers are compared numerically all other values as strings. If several nodes
+have the extreme value then the node with the lowest key is picked.
+*/
+func (sa *showArgExtreme) eval(node data.Node, edge data.Edge) (interface{}, string, error) {
+	var res data.Node
+	var resVal interface{}
+
+	nodes, _, err := sa.rtp.gm.TraverseMulti(sa.rtp.part, node.Key(), node.Kind(), sa.spec, true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, n := range nodes {
+		val := n.Attr(sa.attr)
+
+		if val == nil {
+			continue
+		}
+
+		if res == nil {
+			res, resVal = n, val
+			continue
+		}
+
+		c := compareValues(val, resVal)
+
+		if !sa.max {
+			c = -c
+		}
+
+		if c > 0 || (c == 0 && n.Key() < res.Key()) {
+			res, resVal = n, val
+		}
+	}
+
+	if res == nil {
+		return nil, "", nil
+	}
+
+	return res.Attr(sa.resAttr), "n:" + res.Kind() + ":" + res.Key(), nil
+}
+
+// Show CountBool
+// --------------
+
+/*
+showCountBoolInst creates a new showCountBool object.
+*/
+func showCountBoolInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+
+	// Check parameters
+
+	if len(astNode.Children) != 5 {
+		return nil, "", "", errors.New("CountBool function requires 4 parameters: traversal step, traversal spec, condition clause, bucket (true or false)")
+	}
+
+	pos := astNode.Children[1].Token.Val
+	spec := astNode.Children[2].Token.Val
+	condString := astNode.Children[3].Token.Val
+	bucketString := astNode.Children[4].Token.Val
+
+	bucket, err := strconv.ParseBool(bucketString)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("Invalid bucket in countbool function: %s (must be true or false)", bucketString)
+	}
+
+	ast, err := parser.ParseWithRuntime("countbool condition", "get _ where "+condString, &GetRuntimeProvider{rtp})
+	if err != nil {
+		return nil, "", "", fmt.Errorf("Invalid condition clause in countbool function: %s", err)
+	}
+
+	cond := ast.Children[1] // This should always pick out just the condition clause
+
+	errorutil.AssertOk(cond.Runtime.Validate()) // Validation should alwasys succeed
+
+	return &showCountBool{rtp, astNode, spec, cond, bucket}, pos + ":n:key", "Count " + bucketString, nil
+}
+
+/*
+showCountBool is the number of reachable nodes via a given traversal spec for
+which a condition evaluates to a given boolean value.
+*/
+type showCountBool struct {
+	rtp       *eqlRuntimeProvider
+	astNode   *parser.ASTNode
+	spec      string
+	condition *parser.ASTNode
+	bucket    bool
+}
+
+/*
+name returns the name of the function.
+*/
+func (sc *showCountBool) name() string {
+	return "countbool"
+}
+
+/*
+eval counts reachable nodes via a given traversal which fall into the bucket
+of this function. An empty bucket has a count of 0.
+*/
+func (sc *showCountBool) eval(node data.Node, edge data.Edge) (interface{}, string, error) {
+	var count int
+
+	nodes, _, err := sc.rtp.gm.TraverseMulti(sc.rtp.part, node.Key(), node.Kind(), sc.spec, true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, n := range nodes {
+		res, err := sc.condition.Children[0].Runtime.(CondRuntime).CondEval(n, nil)
+
+		if err != nil {
+			return nil, "", err
+		} else if b, ok := res.(bool); ok {
+			if b == sc.bucket {
+				count++
+			}
+		} else {
+
+			return nil, "", sc.rtp.newRuntimeError(ErrInvalidConstruct,
+				"Could not evaluate condition clause in countbool function", sc.astNode)
+		}
+	}
+
+	condString, _ := parser.PrettyPrint(sc.condition.Children[0])
+
+	if !sc.bucket {
+		condString = "not (" + condString + ")"
+	}
+
+	srcQuery := fmt.Sprintf("q:lookup %s %s traverse %s where %s end show 2:n:%s, 2:n:%s, 2:n:%s",
+		node.Kind(), strconv.Quote(node.Key()), sc.spec, condString, data.NodeKey, data.NodeKind, data.NodeName)
+
+	return count, srcQuery, nil
+}
+
+// Show Hash
+// ---------
+
+/*
+showHashInst creates a new showHash object.
+*/
+func showHashInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	pos := "1"
+	excluded := make(map[string]bool)
+
+	// Check parameters - all parameters are optional
+
+	if len(astNode.Children) > 1 {
+		pos = astNode.Children[1].Token.Val
+
+		if _, err := strconv.Atoi(pos); err != nil {
+			return nil, "", "", fmt.Errorf("Invalid traversal step in hash function: %s", pos)
+		}
+
+		for _, c := range astNode.Children[2:] {
+			excluded[c.Token.Val] = true
+		}
+	}
+
+	return &showHash{rtp, excluded}, pos + ":n:key", "Hash", nil
+}
+
+/*
+showHash is a fingerprint over the attributes of a node.
+*/
+type showHash struct {
+	rtp      *eqlRuntimeProvider
+	excluded map[string]bool
+}
+
+/*
+name returns the name of the function.
+*/
+func (sh *showHash) name() string {
+	return "hash"
+}
+
+/*
+eval computes a SHA256 hash over all attributes of a node which were not
+excluded. Attributes are hashed in sorted order and values are JSON encoded so
+the hash of a node is the same on all machines.
+*/
+func (sh *showHash) eval(node data.Node, edge data.Edge) (interface{}, string, error) {
+
+	// The given node might only contain the attributes which are shown so
+	// fetch the whole node
+
+	n, err := sh.rtp.gm.FetchNode(sh.rtp.part, node.Key(), node.Kind())
+	if err != nil || n == nil {
+		return nil, "", err
+	}
+
+	nodeData := n.Data()
+	attrs := make([]string, 0, len(nodeData))
+
+	for attr := range nodeData {
+		if !sh.excluded[attr] {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	sort.Strings(attrs)
+
+	h := sha256.New()
+
+	for _, attr := range attrs {
+		val, err := json.Marshal(nodeData[attr])
+		if err != nil {
+			val = []byte(fmt.Sprint(nodeData[attr]))
+		}
+
+		name, _ := json.Marshal(attr)
+
+		h.Write(name)
+		h.Write([]byte(":"))
+		h.Write(val)
+		h.Write([]byte("\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), "n:" + n.Kind() + ":" + n.Key(), nil
+}
+
+// Show Exists
+// -----------
+
+/*
+showExistsInst creates a new showExists object.
+*/
+func showExistsInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+
+	// Check parameters
+
+	if len(astNode.Children) != 2 {
+		return nil, "", "", fmt.Errorf("Exists function requires 1 parameter: column data (e.g. 1:n:name)")
+	}
+
+	colData := astNode.Children[1].Token.Val
+
+	if !isColDataSpec(colData) {
+		return nil, "", "", fmt.Errorf("Invalid column data in exists function: %v (must be <step>:<n or e>:<attribute>)",
+			colData)
+	}
+
+	colDataSplit := strings.SplitN(colData, ":", 3)
+	attr := colDataSplit[2]
+
+	return &showExists{colDataSplit[1] == "e", attr}, colData,
+		"Exists " + rtp.ni.AttributeDisplayString("", attr), nil
+}
+
+/*
+showExists checks if a node or an edge has a certain attribute.
+*/
+type showExists struct {
+	isEdge bool
+	attr   string
+}
+
+/*
+name returns the name of the function.
+*/
+func (se *showExists) name() string {
+	return "exists"
+}
+
+/*
+eval checks if the node or the edge of a row has the attribute. An attribute
+with a false, zero or empty value exists - nodes and edges never store null
+values.
+*/
+func (se *showExists) eval(node data.Node, edge data.Edge) (interface{}, string, error) {
+	if se.isEdge {
+		if edge == nil {
+			return false, "", nil
+		}
+
+		_, ok := edge.Data()[se.attr]
+
+		return ok, "e:" + edge.Kind() + ":" + edge.Key(), nil
+	}
+
+	if node == nil {
+		return false, "", nil
+	}
+
+	_, ok := node.Data()[se.attr]
+
+	return ok, "n:" + node.Kind() + ":" + node.Key(), nil
+}
+
+// Show Aggregates
+// ---------------
+
+/*
+showSumInst creates a new showAggregate object which sums up all values.
+*/
+func showSumInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	return showAggregateInst(astNode, rtp, "sum")
+}
+
+/*
+showAvgInst creates a new showAggregate object which averages all values.
+*/
+func showAvgInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	return showAggregateInst(astNode, rtp, "avg")
+}
+
+/*
+showMinInst creates a new showAggregate object which finds the minimum value.
+*/
+func showMinInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	return showAggregateInst(astNode, rtp, "min")
+}
+
+/*
+showMaxInst creates a new showAggregate object which finds the maximum value.
+*/
+func showMaxInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	return showAggregateInst(astNode, rtp, "max")
+}
+
+/*
+showAggregateInst creates a new showAggregate object.
+*/
+func showAggregateInst(astNode *parser.ASTNode, rtp *eqlRuntimeProvider, name string) (FuncShow, string, string, error) {
+	title := strings.ToUpper(name[:1]) + name[1:]
+
+	// Check parameters
+
+	if len(astNode.Children) != 2 {
+		return nil, "", "", fmt.Errorf("%v function requires 1 parameter: column data (e.g. 1:n:name)", title)
+	}
+
+	colData := astNode.Children[1].Token.Val
+
+	if !isColDataSpec(colData) {
+		return nil, "", "", fmt.Errorf("Invalid column data in %v function: %v (must be <step>:<n or e>:<attribute>)",
+			name, colData)
+	}
+
+	colDataSplit := strings.SplitN(colData, ":", 3)
+	attr := colDataSplit[2]
+
+	return &showAggregate{rtp, name, colDataSplit[1] == "e", attr}, colData,
+		title + " " + rtp.ni.AttributeDisplayString("", attr), nil
+}
+
+/*
+isColDataSpec checks if a given string is a column data spec
+(e.g. 1:n:name or 2:e:number).
+*/
+func isColDataSpec(colData string) bool {
+	colDataSplit := strings.SplitN(colData, ":", 3)
+
+	if len(colDataSplit) != 3 || (colDataSplit[1] != "n" && colDataSplit[1] != "e") {
+		return false
+	}
+
+	_, err := strconv.Atoi(colDataSplit[0])
+
+	return err == nil
+}
+
+/*
+showAggregate aggregates the values of an attribute over all result rows.
+*/
+type showAggregate struct {
+	rtp    *eqlRuntimeProvider
+	fname  string
+	isEdge bool
+	attr   string
+}
+
+/*
+name returns the name of the function.
+*/
+func (sa *showAggregate) name() string {
+	return sa.fname
+}
+
+/*
+eval returns the attribute value of a single row.
+*/
+func (sa *showAggregate) eval(node data.Node, edge data.Edge) (interface{}, string, error) {
+	if sa.isEdge {
+		if edge == nil {
+			return nil, "", nil
+		}
+		return edge.Attr(sa.attr), "e:" + edge.Kind() + ":" + edge.Key(), nil
+	}
+
+	if node == nil {
+		return nil, "", nil
+	}
+
+	return node.Attr(sa.attr), "n:" + node.Kind() + ":" + node.Key(), nil
+}
+
+/*
+aggregate combines the values of all rows. Null values are ignored. The sum and
+the average are calculated over all numeric values. Minimum and maximum compare
+numbers numerically and all other values as strings.
+*/
+func (sa *showAggregate) aggregate(values []interface{}) interface{} {
+	var res interface{}
+	var sum float64
+	var count int
+
+	for _, val := range values {
+
+		if val == nil {
+			continue
+		}
+
+		switch sa.fname {
+		case "count":
+			count++
+
+		case "sum", "avg":
+			if num, err := strconv.ParseFloat(fmt.Sprint(val), 64); err == nil {
+				sum += num
+				count++
+			}
+
+		case "min", "max":
+			if res == nil {
+				res = val
+				continue
+			}
+
+			c := compareValues(val, res)
+
+			if (sa.fname == "max" && c > 0) || (sa.fname == "min" && c < 0) {
+				res = val
+			}
+		}
+	}
+
+	switch sa.fname {
+	case "count":
+		res = count
+	case "sum":
+		res = sum
+	case "avg":
+		if count > 0 {
+			res = sum / float64(count)
+		}
+	}
+
+	return res
+}
+
+/*
+compareValues returns -1, 0 or 1 if the first value is smaller, equal or
+greater than the second value. Numbers are compared numerically all other
+values as strings.
+*/
+func compareValues(v1, v2 interface{}) int {
+	s1, s2 := fmt.Sprint(v1), fmt.Sprint(v2)
+
+	if num1, err := strconv.ParseFloat(s1, 64); err == nil {
+		if num2, err := strconv.ParseFloat(s2, 64); err == nil {
+			if num1 < num2 {
+				return -1
+			} else if num1 > num2 {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(s1, s2)
+}
+
+// Custom functions
+// ================
+
+/*
+QueryFunc is a custom function which can be used in show and where clauses.
+It is called with the node and edge of the current row and the values of the
+function arguments. Arguments which are node attribute names are replaced by
+the attribute values of the node.
+*/
+type QueryFunc func(node data.Node, edge data.Edge, args []interface{}) (interface{}, error)
+
+/*
+Registry of custom functions
+*/
+var customFunc = make(map[string]QueryFunc)
+var customFuncLock = &sync.RWMutex{}
+
+/*
+RegisterFunction registers a custom function under a given name. The function
+can then be used as @<name>(...) in where clauses and as
+@<name>(<traversal step>, ...) in show clauses. Registering a function under
+an existing custom function name replaces the function. Built-in functions
+cannot be replaced. Functions which are used in where clauses must be safe for
+concurrent use if start nodes are scanned in parallel (see SetScanWorkers).
+*/
+func RegisterFunction(name string, fn QueryFunc) error {
+
+	if !stringutil.IsAlphaNumeric(name) || name == "" {
+		return fmt.Errorf("Invalid function name: %#v - can only contain [a-zA-Z0-9_]", name)
+	}
+
+	_, isWhereFunc := whereFunc[name]
+	_, isShowFunc := showFunc[name]
+
+	if isWhereFunc || isShowFunc {
+		return fmt.Errorf("Cannot replace built-in function: %v", name)
+	}
+
+	customFuncLock.Lock()
+	defer customFuncLock.Unlock()
+
+	if fn == nil {
+		delete(customFunc, name)
+	} else {
+		customFunc[name] = fn
+	}
+
+	return nil
+}
+
+/*
+lookupCustomFunction returns a registered custom function or nil.
+*/
+func lookupCustomFunction(name string) QueryFunc {
+	customFuncLock.RLock()
+	defer customFuncLock.RUnlock()
+
+	return customFunc[name]
+}
+
+/*
+whereCustom runs a custom function in a where clause.
+*/
+func whereCustom(fn QueryFunc, astNode *parser.ASTNode, node data.Node, edge data.Edge) (interface{}, error) {
+	var args []interface{}
+
+	for _, child := range astNode.Children[1:] {
+
+		arg, err := child.Runtime.(CondRuntime).CondEval(node, edge)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+	}
+
+	return fn(node, edge, args)
+}
+
+/*
+showCustomInst creates a new showCustom object.
+*/
+func showCustomInst(fn QueryFunc, astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+	funcName := astNode.Children[0].Token.Val
+
+	// Check parameters
+
+	if len(astNode.Children) < 2 {
+		return nil, "", "", fmt.Errorf("Function %v requires at least 1 parameter in a show clause: traversal step", funcName)
+	}
+
+	pos := astNode.Children[1].Token.Val
+
+	step, err := strconv.Atoi(pos)
+	if err != nil || step < 1 || step > len(rtp.attrsNodes) {
+		return nil, "", "", fmt.Errorf("Invalid traversal step in %v function: %s", funcName, pos)
+	}
+
+	args := &parser.ASTNode{Name: parser.NodeLIST, Token: astNode.Token, Children: astNode.Children[2:]}
+
+	// Arguments are interpreted like values in a where clause of the
+	// traversal step - node attributes are fetched with the node
+
+	if err := (&whereRuntime{rtp, args, step - 1}).Validate(); err != nil {
+		return nil, "", "", err
+	}
+
+	return &showCustom{funcName, fn, args}, pos + ":n:key", stringutil.CreateDisplayString(funcName), nil
+}
+
+/*
+showCustom runs a custom function in a show clause.
+*/
+type showCustom struct {
+	fname string
+	fn    QueryFunc
+	args  *parser.ASTNode
+}
+
+/*
+name returns the name of the function.
+*/
+func (sc *showCustom) name() string {
+	return sc.fname
+}
+
+/*
+eval runs the custom function with the node of the traversal step.
+*/
+func (sc *showCustom) eval(node data.Node, edge data.Edge) (interface{}, string, error) {
+	var args []interface{}
+
+	for _, child := range sc.args.Children {
+
+		arg, err := child.Runtime.(CondRuntime).CondEval(node, edge)
+		if err != nil {
+			return nil, "", err
+		}
+
+		args = append(args, arg)
+	}
+
+	res, err := sc.fn(node, edge, args)
+
+	return res, "n:" + node.Kind() + ":" + node.Key(), err
+}
@@ -10,7 +10,16 @@
 
 package interpreter
 
-import "testing"
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"devt.de/krotik/eliasdb/eql/parser"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+)
 
 func TestDateFunctions(t *testing.T) {
 	gm, _ := dateGraph()
@@ -54,6 +63,98 @@ Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
 	}
 }
 
+func TestDateComparison(t *testing.T) {
+	gm, _ := dateGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Date values are compared chronologically and not lexically
+
+	if _, err := getResult("get datetest where RFC3339_value >= @date('2012-10-12 17:00:55')", `
+Labels: Datetest Key, Rfc3339 Value, Naive Value, Datetest Name, Unix
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
+001, 2012-10-12T19:00:55+02:00, 2012-10-12, date2, 1350061255
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get datetest where RFC3339_value > @date('2012-10-12 18:00:00')", `
+Labels: Datetest Key, Rfc3339 Value, Naive Value, Datetest Name, Unix
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get datetest where @date(naive_value) < RFC3339_value and @date(naive_value) != RFC3339_value and naive_value <= @date('2012-10-09')", `
+Labels: Datetest Key, Rfc3339 Value, Naive Value, Datetest Name, Unix
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
+000, 2012-10-09T19:00:55Z, 2012-10-09, date1, 1349809255
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get datetest where @date('2012-10-09T21:00:55+02:00') = RFC3339_value", `
+Labels: Datetest Key, Rfc3339 Value, Naive Value, Datetest Name, Unix
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
+000, 2012-10-09T19:00:55Z, 2012-10-09, date1, 1349809255
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Values which are not dates never match
+
+	if _, err := getResult("get datetest where name > @date('2012-01-01') or name != @date('2012-01-01') or @date(name) < @date('2012-01-01')", `
+Labels: Datetest Key, Rfc3339 Value, Naive Value, Datetest Name, Unix
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Date formats can be set on the runtime provider
+
+	rt.SetDateFormats([]string{"02.01.2006"}, false)
+
+	if _, err := getResult("get datetest where @date('11.10.2012') > @date('10.10.2012') and RFC3339_value < @date('11.10.2012')", `
+Labels: Datetest Key, Rfc3339 Value, Naive Value, Datetest Name, Unix
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:RFC3339_value, 1:n:naive_value, 1:n:name, 1:n:unix
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// In strict mode values which are not dates cause an error
+
+	rt.SetDateFormats(nil, true)
+
+	if _, err := getResult("get datetest where RFC3339_value > @date('foo')", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Value of operand is not a date (foo) (Line:1 Pos:42)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get datetest where name = date1 and unix > @date('2012-01-01')", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Value of operand is not a date (1349809255) (Line:1 Pos:37)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get datetest where @date() > 1", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (date function requires 1 parameter: date value) (Line:1 Pos:20)" {
+		t.Error(err)
+		return
+	}
+}
+
 func TestCountFunctions(t *testing.T) {
 	gm, _ := songGraphGroups()
 	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
@@ -160,6 +261,91 @@ Data: 1:n:key, 1:n:name, 1:func:count()
 	}
 }
 
+func TestArgExtremeFunctions(t *testing.T) {
+	gm, _ := songGraphGroups()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if res, err := getResult("get Author show name, @argmax(1, :::Song, ranking, name) as top, @argmin(1, :::Song, ranking, name) as bottom", `
+Labels: Author Name, top, bottom
+Format: auto, auto, auto
+Data: 1:n:name, 1:func:argmax(), 1:func:argmin()
+Hans, MyOnlySong3, MyOnlySong3
+John, Aria4, Aria2
+Mike, DeadSong2, LoveSong3
+`[1:], rt, true); err != nil || res.RowSource(1)[1] != "n:Song:Aria4" {
+		t.Error(res.RowSource(1)[1], err)
+		return
+	}
+
+	// Overall extreme values can be found via a group
+
+	if _, err := getResult("get group show key, @argmax(1, :::Song, ranking, key), @argmin(1, :::Song, ranking, ranking)", `
+Labels: Group Key, Key, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:func:argmax(), 1:func:argmin()
+Best, MyOnlySong3, 1
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Ties are resolved by picking the lowest key - nodes without the
+	// attribute are ignored
+
+	if _, err := getResult("get Author show name, @argmax(1, :::Song, kind, key), @argmin(1, :::Song, foo, key)", `
+Labels: Author Name, Key, Key
+Format: auto, auto, auto
+Data: 1:n:name, 1:func:argmax(), 1:func:argmin()
+Hans, MyOnlySong3, <not set>
+John, Aria1, <not set>
+Mike, DeadSong2, <not set>
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show @argmax(1, :::Song, ranking)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Argmax function requires 4 parameters: traversal step, traversal spec, attribute name, result attribute name) (Line:1 Pos:17)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestCountBoolFunction(t *testing.T) {
+	gm, _ := songGraphGroups()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if res, err := getResult("get Author show name, @countbool(1, :::Song, 'ranking > 5', 'true'), @countbool(1, :::Song, 'ranking > 5', 'false')", `
+Labels: Author Name, Count true, Count false
+Format: auto, auto, auto
+Data: 1:n:name, 1:func:countbool(), 1:func:countbool()
+Hans, 1, 0
+John, 2, 2
+Mike, 1, 3
+`[1:], rt, true); err != nil || res.RowSource(1)[2] != `q:lookup Author "000" traverse :::Song where not (ranking > 5) end show 2:n:key, 2:n:kind, 2:n:name` {
+		t.Error(res.RowSource(1)[2], err)
+		return
+	}
+
+	if _, err := getResult("get Author show @countbool(1, :::Song, 'ranking > 5')", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (CountBool function requires 4 parameters: traversal step, traversal spec, condition clause, bucket (true or false)) (Line:1 Pos:17)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show @countbool(1, :::Song, 'ranking > 5', yes)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Invalid bucket in countbool function: yes (must be true or false)) (Line:1 Pos:17)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show @countbool(1, :::Song, 'ranking', 'true')", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Could not evaluate condition clause in countbool function) (Line:1 Pos:17)" {
+		t.Error(err)
+		return
+	}
+}
+
 func TestFunctionErrors(t *testing.T) {
 	gm, _ := songGraphGroups()
 	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
@@ -173,7 +359,7 @@ func TestFunctionErrors(t *testing.T) {
 	}
 
 	if _, err := getResult("get group show key, @count(:::Author)", "", rt, true); err.Error() !=
-		"EQL error in test: Invalid construct (Count function requires 2 or 3 parameters: traversal step, traversal spec, condition clause) (Line:1 Pos:21)" {
+		"EQL error in test: Invalid construct (Count function requires 1 parameter: column data or 2 or 3 parameters: traversal step, traversal spec, condition clause) (Line:1 Pos:21)" {
 		t.Error(err)
 		return
 	}
@@ -269,3 +455,236 @@ Mike, 4
 		return
 	}
 }
+
+func TestHashFunction(t *testing.T) {
+	gm, _ := dateGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Hashes are stable and only depend on the attributes which were not excluded
+
+	if res, err := getResult("get datetest show key, @hash(), @hash(1, key, name, unix, RFC3339_value, naive_value)", `
+Labels: Datetest Key, Hash, Hash
+Format: auto, auto, auto
+Data: 1:n:key, 1:func:hash(), 1:func:hash()
+000, 32c2ed04f9b73578c9c6b310c60d21262f412136aa387b8135d8abf310af3b4e, 7bb3110a5da2d67757dbc929e4de9712739ced2e925635e7789bfd68e099b0b4
+001, 5ce69bd582d3832664cbf120704dfca434b5e81a9645466bf373e7d57a82140c, 7bb3110a5da2d67757dbc929e4de9712739ced2e925635e7789bfd68e099b0b4
+`[1:], rt, true); err != nil || res.RowSource(0)[1] != "n:datetest:000" {
+		t.Error(res, err)
+		return
+	}
+
+	if _, err := getResult("get datetest show @hash(key)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Invalid traversal step in hash function: key) (Line:1 Pos:19)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestExistsFunction(t *testing.T) {
+	gm := graph.NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+
+	for key, val := range map[string]interface{}{"a": 5, "b": 0, "c": false, "d": nil} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "item")
+		node.SetAttr("ranking", val)
+		gm.StoreNode("main", node)
+	}
+
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Attributes with a false or zero value exist
+
+	if res, err := getResult("get item show key, @exists(1:n:ranking) as has_ranking, ranking", `
+Labels: Item Key, has_ranking, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:func:exists(), 1:n:ranking
+a, true, 5
+b, true, 0
+c, true, false
+d, false, <not set>
+`[1:], rt, true); err != nil || res.RowSource(0)[1] != "n:item:a" {
+		t.Error(res, err)
+		return
+	}
+
+	if _, err := getResult("get item show @exists(ranking)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Invalid column data in exists function: ranking (must be <step>:<n or e>:<attribute>)) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestAggregateFunctions(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if res, err := getResult("get Song show @count(1:n:key), @sum(1:n:ranking), @avg(1:n:ranking), @min(1:n:ranking), @max(1:n:name)", `
+Labels: Count Key, Sum Ranking, Avg Ranking, Min Ranking, Max Name
+Format: auto, auto, auto, auto, auto
+Data: 1:func:count(), 1:func:sum(), 1:func:avg(), 1:func:min(), 1:func:max()
+9, 66, 7.333333333333333, 1, StrangeSong1
+`[1:], rt, true); err != nil || res.RowCount() != 1 {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author where name = John traverse :Wrote::Song end show @count(2:n:key), @sum(2:e:number), @max(2:n:ranking)", `
+Labels: Count Key, Sum Number, Max Ranking
+Format: auto, auto, auto
+Data: 2:func:count(), 2:func:sum(), 2:func:max()
+4, 10, 18
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// An empty result is aggregated into a single row
+
+	if _, err := getResult("get Song where ranking > 100 show @count(1:n:key), @sum(1:n:ranking), @avg(1:n:ranking)", `
+Labels: Count Key, Sum Ranking, Avg Ranking
+Format: auto, auto, auto
+Data: 1:func:count(), 1:func:sum(), 1:func:avg()
+0, 0, <not set>
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song show name, @sum(1:n:ranking)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Column 1:n:name is not aggregated and cannot be shown together with aggregate functions) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song show @sum(ranking)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Invalid column data in sum function: ranking (must be <step>:<n or e>:<attribute>)) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song show @avg(1:n:ranking, 1:n:name)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Avg function requires 1 parameter: column data (e.g. 1:n:name)) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestCustomFunctions(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Example function which puts a ranking into a tier
+
+	tier := func(node data.Node, edge data.Edge, args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("Tier function requires 1 parameter: ranking")
+		}
+
+		ranking, err := strconv.ParseFloat(fmt.Sprint(args[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ranking: %v", args[0])
+		}
+
+		if ranking >= 10 {
+			return "gold", nil
+		} else if ranking >= 5 {
+			return "silver", nil
+		}
+
+		return "bronze", nil
+	}
+
+	if err := RegisterFunction("tier", tier); err != nil {
+		t.Error(err)
+		return
+	}
+	defer RegisterFunction("tier", nil)
+
+	if res, err := getResult("get Song where @tier(ranking) = gold show name, @tier(1, ranking)", `
+Labels: Song Name, Tier
+Format: auto, auto
+Data: 1:n:name, 1:func:tier()
+Aria4, gold
+MyOnlySong3, gold
+`[1:], rt, true); err != nil || res.RowSource(0)[1] != "n:Song:Aria4" {
+		t.Error(res, err)
+		return
+	}
+
+	if _, err := getResult("get Author traverse :::Song where @tier(ranking) = silver end show name, 2:n:name, @tier(2, ranking)", `
+Labels: Author Name, Name, Tier
+Format: auto, auto, auto
+Data: 1:n:name, 2:n:name, 2:func:tier()
+John, Aria1, silver
+Mike, DeadSong2, silver
+Mike, StrangeSong1, silver
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Errors of the function are returned
+
+	if _, err := getResult("get Song where @tier(ranking, name) = gold", "", rt, true); err == nil || err.Error() !=
+		"Tier function requires 1 parameter: ranking" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song where name = Aria1 show @tier(1, name)", "", rt, true); err == nil || err.Error() !=
+		"Invalid ranking: Aria1" {
+		t.Error(err)
+		return
+	}
+
+	// Test show parameter errors
+
+	if _, err := getResult("get Song show @tier()", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Function tier requires at least 1 parameter in a show clause: traversal step) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song show @tier(ranking)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Invalid traversal step in tier function: ranking) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song show @tier(2, ranking)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Invalid traversal step in tier function: 2) (Line:1 Pos:15)" {
+		t.Error(err)
+		return
+	}
+
+	// Test registration errors
+
+	if err := RegisterFunction("count", tier); err == nil || err.Error() !=
+		"Cannot replace built-in function: count" {
+		t.Error(err)
+		return
+	}
+
+	if err := RegisterFunction("my-tier", tier); err == nil || err.Error() !=
+		`Invalid function name: "my-tier" - can only contain [a-zA-Z0-9_]` {
+		t.Error(err)
+		return
+	}
+
+	// Removed functions are unknown when the query is prepared
+
+	RegisterFunction("tier", nil)
+
+	ast, err := parser.ParseWithRuntime("test", "get Song where @tier(ranking) = gold", rt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := ast.Runtime.Validate(); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Unknown function: tier) (Line:1 Pos:16)" {
+		t.Error(err)
+		return
+	}
+}
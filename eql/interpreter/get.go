@@ -11,8 +11,12 @@
 package interpreter
 
 import (
+	"fmt"
+	"sync"
+
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/util"
 )
 
 // Runtime provider for GET queries
@@ -27,7 +31,8 @@ type getInst func(*GetRuntimeProvider, *parser.ASTNode) parser.Runtime
 Runtime map for GET query specific components
 */
 var getProviderMap = map[string]getInst{
-	parser.NodeGET: getRuntimeInst,
+	parser.NodeGET:   getRuntimeInst,
+	parser.NodeCOUNT: countRuntimeInst,
 }
 
 /*
@@ -39,11 +44,11 @@ type GetRuntimeProvider struct {
 
 /*
 NewGetRuntimeProvider creates a new GetRuntimeProvider object. This provider
-can interpret GET queries.
+can interpret GET and COUNT queries.
 */
 func NewGetRuntimeProvider(name string, part string, gm *graph.Manager, ni NodeInfo) *GetRuntimeProvider {
-	return &GetRuntimeProvider{&eqlRuntimeProvider{name, part, gm, ni, "", false, nil, "",
-		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}}
+	return &GetRuntimeProvider{&eqlRuntimeProvider{name, part, gm, ni, "", false, false, -1, -1, nil, "",
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, nil, 0, "", 0, nil, nil, nil, nil, &sync.Mutex{}, &sync.Mutex{}}}
 }
 
 /*
@@ -75,61 +80,225 @@ func getRuntimeInst(rtp *GetRuntimeProvider, node *parser.ASTNode) parser.Runtim
 */
 func (rt *getRuntime) Validate() error {
 
-	// First child is always the first node kind to query
-	// (validation of this value was done during lexing)
+	// First children are always the node kinds to query
+	// (validation of these values was done during lexing)
+
+	var startKinds []string
+
+	i := 0
+	for ; i < len(rt.node.Children) && rt.node.Children[i].Token.ID == parser.TokenNODEKIND; i++ {
+		startKinds = append(startKinds, rt.node.Children[i].Token.Val)
+	}
+
+	initErr := rt.rtp.init(startKinds, rt.node.Children[i:])
+
+	// Collect the start keys of all kinds
+
+	var nextKeys []func() (string, error)
+
+	for i, startKind := range startKinds {
+
+		nextKey, err := rt.startKeys(startKind, rt.node.Children[i], initErr == nil)
+		if err != nil {
+			return err
+		}
+
+		nextKeys = append(nextKeys, nextKey)
+	}
+
+	// Start keys are provided kind by kind in the order of the query
+
+	kindPtr := 0
 
-	startKind := rt.node.Children[0].Token.Val
+	rt.rtp.nextStartKey = func() (string, string, error) {
 
-	initErr := rt.rtp.init(startKind, rt.node.Children[1:])
+		for kindPtr < len(nextKeys) {
 
-	if rt.rtp.groupScope == "" {
+			key, err := nextKeys[kindPtr]()
+			if err != nil || key != "" {
+				return key, startKinds[kindPtr], err
+			}
+
+			kindPtr++
+		}
+
+		return "", "", nil
+	}
+
+	return initErr
+}
+
+/*
+startKeys returns a function which provides the keys of all start nodes of a
+given kind. An empty key is returned once all keys have been provided.
+*/
+func (rt *getRuntime) startKeys(startKind string, kindNode *parser.ASTNode,
+	useIndex bool) (func() (string, error), error) {
+
+	if lookup, _ := rt.indexLookup(startKind); useIndex && rt.rtp.groupScope == "" && lookup != nil {
+
+		// Start keys can be looked up in an attribute or word index
+
+		keys, err := lookup()
+		if err != nil {
+			return nil, rt.rtp.newSourceError(err, kindNode)
+		}
+
+		keyPtr := -1
+
+		return func() (string, error) {
+			keyPtr++
+
+			if keyPtr < len(keys) {
+				return keys[keyPtr], nil
+			}
+
+			return "", nil
+		}, nil
+
+	} else if rt.rtp.groupScope == "" {
 
 		// Start keys can be provided by a simple node key iterator
 
 		startKeyIterator, err := rt.rtp.gm.NodeKeyIterator(rt.rtp.part, startKind)
 
 		if err != nil {
-			return err
+			return nil, rt.rtp.newSourceError(err, kindNode)
 		} else if startKeyIterator == nil {
-			return rt.rtp.newRuntimeError(ErrUnknownNodeKind, startKind, rt.node.Children[0])
+			return nil, rt.rtp.newRuntimeError(ErrUnknownNodeKind, startKind, kindNode)
 		}
 
-		rt.rtp.nextStartKey = func() (string, error) {
+		return func() (string, error) {
 			nextKey := startKeyIterator.Next()
 			if startKeyIterator.LastError != nil {
-				return "", startKeyIterator.LastError
+				return "", rt.rtp.newSourceError(startKeyIterator.LastError, kindNode)
 			}
 			return nextKey, nil
+		}, nil
+	}
+
+	// Try to lookup group node
+
+	nodes, _, err := rt.rtp.gm.TraverseMulti(rt.rtp.part, rt.rtp.groupScope,
+		GroupNodeKind, ":::"+startKind, false)
+
+	if err != nil {
+		return nil, rt.rtp.newSourceError(err, kindNode)
+	}
+
+	nodePtr := len(nodes)
+
+	// Iterate over all traversed nodes
+
+	return func() (string, error) {
+		nodePtr--
+
+		if nodePtr >= 0 {
+			return nodes[nodePtr].Key(), nil
+
 		}
 
-	} else {
+		return "", nil
+	}, nil
+}
 
-		// Try to lookup group node
+/*
+indexLookup looks for an equality condition on an indexed attribute or a
+containsword condition on a word indexed attribute of the start kind in the
+where clause. Only conditions which must be true for every result row are
+considered. Returns a function which looks up the start keys or nil if no
+index can be used and a description of the used condition.
+*/
+func (rt *getRuntime) indexLookup(startKind string) (func() ([]string, error), string) {
+	var visit func(astNode *parser.ASTNode) (func() ([]string, error), string)
 
-		nodes, _, err := rt.rtp.gm.TraverseMulti(rt.rtp.part, rt.rtp.groupScope,
-			GroupNodeKind, ":::"+startKind, false)
+	if rt.rtp.where == nil {
+		return nil, ""
+	}
 
-		if err != nil {
-			return err
+	part, gm := rt.rtp.part, rt.rtp.gm
+
+	// Check if a value is an attribute of the start node
+
+	nodeAttr := func(astNode *parser.ASTNode) string {
+		if valRT, ok := astNode.Runtime.(*valueRuntime); ok && astNode.Name == parser.NodeVALUE &&
+			valRT.isNodeAttrValue && valRT.nestedValuePath == nil {
+			return valRT.condVal
+		}
+		return ""
+	}
+
+	// Check if a value is a constant
+
+	constant := func(astNode *parser.ASTNode) (interface{}, bool) {
+
+		if valRT, ok := astNode.Runtime.(*valueRuntime); ok && astNode.Name == parser.NodeVALUE &&
+			!valRT.isNodeAttrValue && !valRT.isEdgeAttrValue {
+
+			switch astNode.Token.ID {
+			case parser.TokenAT, parser.TokenTRUE, parser.TokenFALSE, parser.TokenNULL:
+				return nil, false
+			}
+
+			return valRT.condVal, true
+
+		} else if paramRT, ok := astNode.Runtime.(*paramRuntime); ok {
+
+			switch paramRT.val.(type) {
+			case string, int, int64, float64, bool:
+				return paramRT.val, true
+			}
 		}
 
-		nodePtr := len(nodes)
+		return nil, false
+	}
 
-		// Iterate over all traversed nodes
+	visit = func(astNode *parser.ASTNode) (func() ([]string, error), string) {
 
-		rt.rtp.nextStartKey = func() (string, error) {
-			nodePtr--
+		switch astNode.Name {
 
-			if nodePtr >= 0 {
-				return nodes[nodePtr].Key(), nil
+		case parser.NodeWHERE:
+			return visit(astNode.Children[0])
 
+		case parser.NodeAND:
+			for _, child := range astNode.Children {
+				if lookup, desc := visit(child); lookup != nil {
+					return lookup, desc
+				}
 			}
 
-			return "", nil
+		case parser.NodeEQ:
+			for i := 0; i < 2; i++ {
+				attr := nodeAttr(astNode.Children[i])
+
+				if val, ok := constant(astNode.Children[1-i]); ok && attr != "" &&
+					gm.HasIndex(part, startKind, attr) {
+
+					return func() ([]string, error) {
+						return gm.LookupIndex(part, startKind, attr, val)
+					}, fmt.Sprintf("%v = %v (attribute index)", attr, val)
+				}
+			}
+
+		case parser.NodeCONTAINSWORD:
+
+			// A text without words is contained in every value
+
+			attr := nodeAttr(astNode.Children[0])
+
+			if val, ok := constant(astNode.Children[1]); ok && attr != "" &&
+				len(util.FoldedWords(fmt.Sprint(val))) > 0 && gm.HasWordIndex(part, startKind, attr) {
+
+				return func() ([]string, error) {
+					return gm.LookupWordIndex(part, startKind, attr, fmt.Sprint(val))
+				}, fmt.Sprintf("%v containsword %v (word index)", attr, val)
+			}
 		}
+
+		return nil, ""
 	}
 
-	return initErr
+	return visit(rt.rtp.where)
 }
 
 /*
@@ -158,6 +327,10 @@ func (rt *getRuntime) gaterResult(topNode *parser.ASTNode) (interface{}, error)
 
 	res := newSearchResult(rt.rtp.eqlRuntimeProvider, query)
 
+	// Make sure a parallel scan of start nodes does not outlive the evaluation
+
+	defer rt.rtp.stopScan()
+
 	if err == nil {
 		var more bool
 
@@ -172,6 +345,12 @@ func (rt *getRuntime) gaterResult(topNode *parser.ASTNode) (interface{}, error)
 				return nil, err
 			}
 
+			// Stop early if no further rows are needed
+
+			if res.isComplete() {
+				break
+			}
+
 			// More on to the next row
 
 			more, err = rt.rtp.next()
@@ -179,8 +358,81 @@ func (rt *getRuntime) gaterResult(topNode *parser.ASTNode) (interface{}, error)
 
 		// Finish the result
 
-		res.finish()
+		if finishErr := res.finish(); err == nil {
+			err = finishErr
+		}
 	}
 
-	return res, err
+	// Errors without a more specific position are reported at the query
+
+	return res, rt.rtp.newSourceError(err, topNode)
+}
+
+// COUNT Runtime
+// =============
+
+type countRuntime struct {
+	*getRuntime
+}
+
+func countRuntimeInst(rtp *GetRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &countRuntime{&getRuntime{rtp, node}}
+}
+
+/*
+ Validate and reset this runtime component and all its child components.
+*/
+func (rt *countRuntime) Validate() error {
+
+	// A count query only returns the number of rows - clauses which
+	// shape the result rows are not allowed
+
+	for _, child := range rt.node.Children {
+		switch child.Name {
+
+		case parser.NodeSHOW, parser.NodeGROUPBY, parser.NodePRIMARY,
+			parser.NodeLIMIT, parser.NodeOFFSET:
+
+			return rt.rtp.newRuntimeError(ErrInvalidConstruct,
+				child.Name+" cannot be used in a count query", child)
+
+		case parser.NodeWITH:
+
+			for _, flag := range child.Children {
+				if flag.Name != parser.NodeNULLTRAVERSAL {
+					return rt.rtp.newRuntimeError(ErrInvalidConstruct,
+						flag.Name+" cannot be used in a count query", flag)
+				}
+			}
+		}
+	}
+
+	return rt.getRuntime.Validate()
+}
+
+/*
+Eval evaluate this runtime component. Returns the number of rows which the
+query would produce. Result rows are not assembled.
+*/
+func (rt *countRuntime) Eval() (interface{}, error) {
+
+	if rt.rtp.specs == nil || !allowMultiEval {
+		if err := rt.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	count := 0
+
+	defer rt.rtp.stopScan()
+
+	more, err := rt.rtp.next()
+	for more && err == nil {
+		count++
+		more, err = rt.rtp.next()
+	}
+
+	// Errors without a more specific position are reported at the query
+
+	return count, rt.rtp.newSourceError(err, rt.node)
 }
@@ -11,7 +11,9 @@
 package interpreter
 
 import (
-	"devt.de/krotik/common/datautil"
+	"reflect"
+	"strconv"
+
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph/data"
 )
@@ -106,6 +108,13 @@ func (rt *valueRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, e
 
 		funcInst, ok := whereFunc[funcName]
 		if !ok {
+
+			// Try to lookup a custom function
+
+			if fn := lookupCustomFunction(funcName); fn != nil {
+				return whereCustom(fn, rt.node, node, edge)
+			}
+
 			return nil, rt.rtp.newRuntimeError(ErrInvalidConstruct,
 				"Unknown function: "+funcName, rt.node)
 		}
@@ -146,9 +155,7 @@ func (rt *valueRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, e
 		// Check for nested values
 
 		if rt.nestedValuePath != nil {
-			if valMap, ok := node.Attr(rt.nestedValuePath[0]).(map[string]interface{}); ok {
-				valRet, _ = datautil.GetNestedValue(valMap, rt.nestedValuePath[1:])
-			}
+			valRet = nestedValue(node.Attr(rt.nestedValuePath[0]), rt.nestedValuePath[1:])
 		} else {
 			valRet = node.Attr(rt.condVal)
 		}
@@ -168,3 +175,91 @@ func (rt *valueRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, e
 
 	return rt.condVal, nil
 }
+
+/*
+nestedValue walks a nested object structure along a given path. Maps are
+accessed by key and lists by a numeric index. Returns nil if a path segment
+cannot be resolved.
+*/
+func nestedValue(val interface{}, path []string) interface{} {
+
+	for _, seg := range path {
+
+		switch v := val.(type) {
+
+		case map[string]interface{}:
+			val = v[seg]
+
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			val = v[i]
+
+		default:
+			return nil
+		}
+	}
+
+	return val
+}
+
+// Bind parameter runtime
+// ======================
+
+/*
+Runtime for bind parameters
+*/
+type paramRuntime struct {
+	rtp  *eqlRuntimeProvider
+	node *parser.ASTNode
+	val  interface{}
+}
+
+/*
+paramRuntimeInst returns a new runtime component instance.
+*/
+func paramRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &paramRuntime{rtp, node, nil}
+}
+
+/*
+SetValue sets the value of the parameter. Slices are converted into
+lists so they can be used with list operators.
+*/
+func (rt *paramRuntime) SetValue(val interface{}) {
+
+	if v := reflect.ValueOf(val); v.Kind() == reflect.Slice {
+		if _, ok := val.([]interface{}); !ok {
+			list := make([]interface{}, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				list[i] = v.Index(i).Interface()
+			}
+			val = list
+		}
+	}
+
+	rt.val = val
+}
+
+/*
+Validate this node and all its child nodes.
+*/
+func (rt *paramRuntime) Validate() error {
+	return nil
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *paramRuntime) Eval() (interface{}, error) {
+	return rt.val, nil
+}
+
+/*
+Evaluate the parameter as a condition component.
+*/
+func (rt *paramRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	return rt.val, nil
+}
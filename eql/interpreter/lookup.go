@@ -11,6 +11,8 @@
 package interpreter
 
 import (
+	"sync"
+
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph"
 )
@@ -35,6 +37,7 @@ LookupRuntimeProvider data structure
 */
 type LookupRuntimeProvider struct {
 	*eqlRuntimeProvider
+	strictKeys bool // Flag if keys of nodes which do not exist are an error
 }
 
 /*
@@ -42,8 +45,17 @@ NewLookupRuntimeProvider creates a new LookupRuntimeProvider object. This provid
 can interpret LOOKUP queries.
 */
 func NewLookupRuntimeProvider(name string, part string, gm *graph.Manager, ni NodeInfo) *LookupRuntimeProvider {
-	return &LookupRuntimeProvider{&eqlRuntimeProvider{name, part, gm, ni, "", false, nil, "",
-		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}}
+	return &LookupRuntimeProvider{&eqlRuntimeProvider{name, part, gm, ni, "", false, false, -1, -1, nil, "",
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, nil, 0, "", 0, nil, nil, nil, nil, &sync.Mutex{}, &sync.Mutex{}}, false}
+}
+
+/*
+SetStrictKeys sets if a lookup of a node which does not exist should fail with
+an ErrUnknownNodeKey error. By default keys of nodes which do not exist are
+skipped.
+*/
+func (rtp *LookupRuntimeProvider) SetStrictKeys(strict bool) {
+	rtp.strictKeys = strict
 }
 
 /*
@@ -90,7 +102,15 @@ func (rt *lookupRuntime) Validate() error {
 	initIndex := len(rt.node.Children) - 1
 
 	for i, child := range rt.node.Children[1:] {
-		if child.Token.ID != parser.TokenVALUE {
+		if i == 0 && child.Name == parser.NodeLIST {
+
+			// Keys were given as a list
+
+			for _, key := range child.Children {
+				keys = append(keys, key.Token.Val)
+			}
+
+		} else if child.Token.ID != parser.TokenVALUE {
 
 			// We have a first non-id child
 
@@ -107,27 +127,19 @@ func (rt *lookupRuntime) Validate() error {
 
 	// Initialise the runtime provider
 
-	initErr := rt.rtp.init(startKind, rt.node.Children[initIndex+1:])
+	initErr := rt.rtp.init([]string{startKind}, rt.node.Children[initIndex+1:])
 
 	if rt.rtp.groupScope == "" {
 
-		nodePtr := len(keys)
-
-		if nodePtr > 0 {
-
-			// Iterate over all traversed nodes
-
-			rt.rtp.nextStartKey = func() (string, error) {
-				nodePtr--
-				if nodePtr >= 0 {
-					return keys[nodePtr], nil
+		// Fetch the nodes of all given keys in batches (in reverse order)
 
-				}
-
-				return "", nil
-			}
+		reversed := make([]string, len(keys))
+		for i, key := range keys {
+			reversed[len(keys)-1-i] = key
 		}
 
+		rt.rtp.lookup = &lookupScan{rt.node, startKind, reversed, nil, rt.rtp.strictKeys}
+
 	} else {
 
 		// Build a map of keys
@@ -143,27 +155,27 @@ func (rt *lookupRuntime) Validate() error {
 			GroupNodeKind, ":::"+startKind, false)
 
 		if err != nil {
-			return err
+			return rt.rtp.newSourceError(err, rt.node.Children[0])
 		}
 
 		nodePtr := len(nodes)
 
 		// Iterate over all traversed nodes
 
-		rt.rtp.nextStartKey = func() (string, error) {
+		rt.rtp.nextStartKey = func() (string, string, error) {
 			nodePtr--
 
 			if nodePtr >= 0 {
 				nodeKey := nodes[nodePtr].Key()
 
 				if _, ok := keyMap[nodeKey]; ok {
-					return nodeKey, nil
+					return nodeKey, startKind, nil
 				}
 
 				return rt.rtp.nextStartKey()
 			}
 
-			return "", nil
+			return "", "", nil
 		}
 	}
 
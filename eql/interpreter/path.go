@@ -0,0 +1,239 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package interpreter
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"devt.de/krotik/eliasdb/eql/parser"
+	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+// Runtime provider for PATH queries
+// =================================
+
+/*
+Instance function for PATH query components
+*/
+type pathInst func(*PathRuntimeProvider, *parser.ASTNode) parser.Runtime
+
+/*
+Runtime map for PATH query specific components
+*/
+var pathProviderMap = map[string]pathInst{
+	parser.NodePATH: pathRuntimeInst,
+}
+
+/*
+PathRuntimeProvider data structure
+*/
+type PathRuntimeProvider struct {
+	*eqlRuntimeProvider
+}
+
+/*
+NewPathRuntimeProvider creates a new PathRuntimeProvider object. This provider
+can interpret PATH queries.
+*/
+func NewPathRuntimeProvider(name string, part string, gm *graph.Manager, ni NodeInfo) *PathRuntimeProvider {
+	return &PathRuntimeProvider{&eqlRuntimeProvider{name, part, gm, ni, "", false, false, -1, -1, nil, "",
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, nil, 0, "", 0, nil, nil, nil, nil, &sync.Mutex{}, &sync.Mutex{}}}
+}
+
+/*
+Runtime returns a runtime component for a given ASTNode.
+*/
+func (rtp *PathRuntimeProvider) Runtime(node *parser.ASTNode) parser.Runtime {
+	if pinst, ok := generalProviderMap[node.Name]; ok {
+		return pinst(rtp.eqlRuntimeProvider, node)
+	} else if pinst, ok := pathProviderMap[node.Name]; ok {
+		return pinst(rtp, node)
+	}
+	return invalidRuntimeInst(rtp.eqlRuntimeProvider, node)
+}
+
+// PATH Runtime
+// ============
+
+type pathRuntime struct {
+	rtp  *PathRuntimeProvider
+	node *parser.ASTNode
+
+	startKind string // Kind of the start node
+	startKey  string // Key of the start node
+	endKind   string // Kind of the end node
+	endKey    string // Key of the end node
+	spec      string // Traversal spec which is used for each hop
+	maxHops   int    // Maximum number of hops (-1 for no limit)
+}
+
+func pathRuntimeInst(rtp *PathRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &pathRuntime{rtp, node, "", "", "", "", "", -1}
+}
+
+/*
+ Validate and reset this runtime component and all its child components.
+*/
+func (rt *pathRuntime) Validate() error {
+	var err error
+
+	splitNodeID := func(child *parser.ASTNode) (string, string, bool) {
+		id := strings.SplitN(child.Token.Val, ":", 2)
+		if len(id) != 2 || id[0] == "" || id[1] == "" {
+			return "", "", false
+		}
+		return id[0], id[1], true
+	}
+
+	// First two children are the start and the end node as <kind>:<key>
+
+	for i, child := range rt.node.Children[:2] {
+		kind, key, ok := splitNodeID(child)
+		if !ok {
+			return rt.rtp.newRuntimeError(ErrInvalidConstruct,
+				"Node must be given as <kind>:<key>: "+child.Token.Val, child)
+		}
+
+		if i == 0 {
+			rt.startKind, rt.startKey = kind, key
+		} else {
+			rt.endKind, rt.endKey = kind, key
+		}
+	}
+
+	// Third child is the traversal spec
+
+	rt.spec = rt.node.Children[2].Token.Val
+
+	if len(strings.Split(rt.spec, ":")) != 4 {
+		return rt.rtp.newRuntimeError(ErrInvalidSpec, rt.spec, rt.node.Children[2])
+	}
+
+	// Optional fourth child is the maximum number of hops
+
+	rt.maxHops = -1
+
+	if len(rt.node.Children) > 3 {
+		maxHops := rt.node.Children[3].Children[0]
+
+		if rt.maxHops, err = strconv.Atoi(maxHops.Token.Val); err != nil || rt.maxHops < 1 {
+			return rt.rtp.newRuntimeError(ErrInvalidConstruct,
+				"Maximum number of hops must be a positive number: "+maxHops.Token.Val, maxHops)
+		}
+	}
+
+	// Initialise the result columns - the path result always has a fixed
+	// layout with one row per node on the path
+
+	rt.rtp.withFlags = &withFlags{make([]byte, 0), make([]int, 0), make([]int, 0),
+		make([]int, 0), make([]bool, 0), make([]int, 0), make([]string, 0)}
+
+	rt.rtp.primaryKind = rt.startKind
+
+	rt.rtp.colLabels = make([]string, 0)
+	rt.rtp.colFormat = make([]string, 0)
+	rt.rtp.colData = make([]string, 0)
+	rt.rtp.colFunc = make([]FuncShow, 0)
+
+	addCol := func(label string, colData string) {
+		rt.rtp.colLabels = append(rt.rtp.colLabels, label)
+		rt.rtp.colFormat = append(rt.rtp.colFormat, "auto")
+		rt.rtp.colData = append(rt.rtp.colData, colData)
+		rt.rtp.colFunc = append(rt.rtp.colFunc, nil)
+	}
+
+	for _, attr := range rt.rtp.ni.SummaryAttributes("") {
+		addCol(rt.rtp.ni.AttributeDisplayString("", attr), "1:n:"+attr)
+	}
+
+	addCol("Edge Key", "1:e:"+data.NodeKey)
+	addCol("Edge Kind", "1:e:"+data.NodeKind)
+
+	return nil
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (rt *pathRuntime) Eval() (interface{}, error) {
+
+	if err := rt.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, err := parser.PrettyPrint(rt.node)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newSearchResult(rt.rtp.eqlRuntimeProvider, query)
+
+	nodes, edges, err := rt.shortestPath()
+
+	if err == nil {
+
+		// Fetch the attributes which should be displayed for the path nodes
+
+		attrs := rt.rtp.ni.SummaryAttributes("")
+
+		for i, node := range nodes {
+			var n data.Node
+
+			if n, err = rt.rtp.gm.FetchNodePart(rt.rtp.part, node.Key(), node.Kind(), attrs); err != nil {
+				break
+			} else if n != nil {
+				node = n
+			}
+
+			if err = res.addRow([]data.Node{node}, []data.Edge{edges[i]}); err != nil {
+				break
+			}
+		}
+
+		if finishErr := res.finish(); err == nil {
+			err = finishErr
+		}
+	}
+
+	return res, err
+}
+
+/*
+shortestPath finds the shortest path from the start node to the end node.
+Returns the nodes of the path and for each node the edge which was used to
+reach it (the first edge is always nil). Returns empty lists if the end node
+is not reachable.
+*/
+func (rt *pathRuntime) shortestPath() ([]data.Node, []data.Edge, error) {
+
+	ctx := rt.rtp.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	nodes, edges, err := rt.rtp.gm.ShortestPath(ctx, rt.rtp.part, rt.startKey, rt.startKind,
+		rt.endKey, rt.endKind, rt.spec, rt.maxHops)
+
+	if err != nil {
+		if cerr := rt.rtp.checkCancelled(); cerr != nil {
+			err = cerr
+		}
+		return nil, nil, err
+	} else if nodes == nil {
+		return nil, nil, nil
+	}
+
+	return nodes, append([]data.Edge{nil}, edges...), nil
+}
@@ -11,13 +11,17 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph"
 	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
 )
 
 /*
@@ -29,11 +33,13 @@ var allowMultiEval = false
 // Special flags which can be set by with statements
 
 type withFlags struct {
-	ordering     []byte // Result ordering
-	orderingCol  []int  // Columns which should be ordered
-	notnullCol   []int  // Columns which must not be null
-	uniqueCol    []int  // Columns which will only contain unique values
-	uniqueColCnt []bool // Flag if unique values should be counted
+	ordering     []byte   // Result ordering
+	orderingCol  []int    // Columns which should be ordered
+	notnullCol   []int    // Columns which must not be null
+	uniqueCol    []int    // Columns which will only contain unique values
+	uniqueColCnt []bool   // Flag if unique values should be counted
+	typeCol      []int    // Columns which should be converted to a type
+	typeColType  []string // Types of the converted columns
 }
 
 const (
@@ -46,6 +52,13 @@ GroupNodeKind is a special group node kind
 */
 const GroupNodeKind = "group"
 
+/*
+DefaultDateFormats are the layouts which are used to parse date values if
+no other layouts were set on the runtime provider.
+*/
+var DefaultDateFormats = []string{time.RFC3339, "2006-01-02T15:04:05",
+	"2006-01-02 15:04:05", "2006-01-02"}
+
 // General runtime provider
 // ========================
 
@@ -61,13 +74,19 @@ type eqlRuntimeProvider struct {
 	groupScope string         // Group scope for query
 
 	allowNilTraversal bool       // Flag if empty traversals should be included in the result
+	distinct          bool       // Flag if the result should only contain distinct rows
+	limit             int        // Maximum number of result rows (-1 for no limit)
+	offset            int        // Number of result rows to skip (-1 for no offset)
 	withFlags         *withFlags // Special flags which can be set by with statements
 
-	primaryKind  string                 // Primary node kind
-	nextStartKey func() (string, error) // Function to get the next start key
+	primaryKind  string                         // Primary node kind
+	startKinds   []string                       // Node kinds of the start nodes
+	nextStartKey func() (string, string, error) // Function to get the next start key and its node kind
+	kindAttrs    map[string]map[string]bool     // Known attributes of several start kinds
 
 	traversals []*parser.ASTNode // Array of all top level query traversals
 	where      *parser.ASTNode   // First where clause
+	groupBy    *parser.ASTNode   // Group by clause node
 	show       *parser.ASTNode   // Show clause node
 
 	specs      []string            // Flat list of traversals of this query
@@ -80,31 +99,162 @@ type eqlRuntimeProvider struct {
 	colFormat []string   // Format for columns
 	colData   []string   // Data for columns
 	colFunc   []FuncShow // Function to transform column value
+	groupCol  []int      // Columns which are used to group rows
+
+	dateFormats []string // Layouts which are used to parse date values
+	strictDates bool     // Flag if values which are not dates should cause an error
+
+	ctx context.Context // Context which can cancel the evaluation
+
+	spillMaxRows int    // Number of result rows which are kept in memory (0 for no limit)
+	spillDir     string // Directory for spill files of large results
+
+	scanWorkers int            // Number of goroutines which fetch and filter start nodes (below 2 for a sequential scan)
+	scan        *startNodeScan // Running parallel scan of start nodes
+	lookup      *lookupScan    // Scan of a known list of start node keys (lookup queries)
 
 	_attrsNodesFetch [][]string // Internal copy of attrsNodes better suited for fetchPart calls
 	_attrsEdgesFetch [][]string // Internal copy of attrsEdges better suited for fetchPart calls
+
+	mutex     *sync.Mutex // Mutex to serialize evaluations of prepared queries
+	condMutex *sync.Mutex // Mutex to serialize parsing of count conditions by parallel scan workers
+}
+
+/*
+SetDateFormats sets the layouts which are used to parse values in date
+comparisons (nil restores the default layouts). Values which cannot be parsed
+do not match a date comparison unless strict is set - in this case the query
+fails with an error.
+*/
+func (p *eqlRuntimeProvider) SetDateFormats(formats []string, strict bool) {
+	p.dateFormats = formats
+	p.strictDates = strict
+}
+
+/*
+SetContext sets a context which is checked during the evaluation of a query.
+The evaluation stops with an ErrCancelled error once the context is done.
+*/
+func (p *eqlRuntimeProvider) SetContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+/*
+SetResultSpill sets the number of result rows which are kept in memory while
+a result is collected. Once a result grows larger its rows are written to a
+temporary file in the given directory (an empty string uses the default
+directory for temporary files). A value below 1 keeps all rows in memory.
+*/
+func (p *eqlRuntimeProvider) SetResultSpill(maxRows int, dir string) {
+	p.spillMaxRows = maxRows
+	p.spillDir = dir
+}
+
+/*
+SetScanWorkers sets the number of goroutines which fetch the start nodes of a
+query and evaluate the where clause on them. The result rows are produced in
+the same order as with a sequential scan. A value below 2 disables parallel
+scans.
+*/
+func (p *eqlRuntimeProvider) SetScanWorkers(workers int) {
+	p.scanWorkers = workers
+}
+
+/*
+Version returns the schema version of the graph manager of this provider.
+Prepared queries (see parser.ParseCached) are parsed again once the schema
+version changed.
+*/
+func (p *eqlRuntimeProvider) Version() uint64 {
+	return p.gm.SchemaVersion()
+}
+
+/*
+Lock locks this provider. Prepared queries (see parser.ParseCached) hold the
+lock while they are evaluated since runtime components keep their state in
+the provider.
+*/
+func (p *eqlRuntimeProvider) Lock() {
+	p.mutex.Lock()
+}
+
+/*
+Unlock unlocks this provider.
+*/
+func (p *eqlRuntimeProvider) Unlock() {
+	p.mutex.Unlock()
+}
+
+/*
+checkCancelled returns an error if the context of this provider is done.
+*/
+func (p *eqlRuntimeProvider) checkCancelled() error {
+	if p.ctx != nil {
+		if err := p.ctx.Err(); err != nil {
+			return &RuntimeError{p.name, ErrCancelled, err.Error(), nil, 0, 0}
+		}
+	}
+	return nil
+}
+
+/*
+parseDate converts a given value into a time value using the date formats of
+this provider. Returns false if the value cannot be converted.
+*/
+func (p *eqlRuntimeProvider) parseDate(val interface{}) (time.Time, bool) {
+
+	if t, ok := val.(time.Time); ok {
+		return t, true
+	} else if val == nil {
+		return time.Time{}, false
+	}
+
+	formats := p.dateFormats
+	if formats == nil {
+		formats = DefaultDateFormats
+	}
+
+	valStr := fmt.Sprint(val)
+
+	for _, layout := range formats {
+		if t, err := time.Parse(layout, valStr); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
 }
 
 /*
 Initialise and validate data structures.
 */
-func (p *eqlRuntimeProvider) init(startKind string,
+func (p *eqlRuntimeProvider) init(startKinds []string,
 	rootChildren []*parser.ASTNode) error {
 
 	// By default we don't include empty traversals in the result
 
 	p.allowNilTraversal = false
 
+	// By default all rows are included in the result
+
+	p.distinct = false
+
+	// By default the result is not paged
+
+	p.limit = -1
+	p.offset = -1
+
 	// Clear any with flags
 
 	p.withFlags = &withFlags{make([]byte, 0), make([]int, 0), make([]int, 0),
-		make([]int, 0), make([]bool, 0)}
+		make([]int, 0), make([]bool, 0), make([]int, 0), make([]string, 0)}
 
 	// Reinitialise datastructures
 
 	p.groupScope = ""
 	p.traversals = make([]*parser.ASTNode, 0)
 	p.where = nil
+	p.groupBy = nil
 	p.show = nil
 
 	p.specs = make([]string, 0)
@@ -115,14 +265,43 @@ func (p *eqlRuntimeProvider) init(startKind string,
 	p._attrsNodesFetch = nil
 	p._attrsEdgesFetch = nil
 
+	p.stopScan()
+	p.lookup = nil
+
 	p.colLabels = make([]string, 0)
 	p.colFormat = make([]string, 0)
 	p.colData = make([]string, 0)
 	p.colFunc = make([]FuncShow, 0)
+	p.groupCol = make([]int, 0)
 
 	p.primaryKind = ""
+	p.startKinds = startKinds
+	p.kindAttrs = nil
+
+	// Rows of several start kinds are described by a spec without a kind
+
+	startSpec := ""
+
+	if len(startKinds) == 1 {
+		startSpec = startKinds[0]
+
+	} else {
+
+		// Remember the attributes of each start kind - conditions on
+		// attributes which a kind does not have are false
+
+		p.kindAttrs = make(map[string]map[string]bool)
+
+		for _, kind := range startKinds {
+			p.kindAttrs[kind] = make(map[string]bool)
 
-	p.specs = append(p.specs, startKind)
+			for _, attr := range p.gm.NodeAttrs(kind) {
+				p.kindAttrs[kind][attr] = true
+			}
+		}
+	}
+
+	p.specs = append(p.specs, startSpec)
 	p.attrsNodes = append(p.attrsNodes, make(map[string]string))
 	p.attrsEdges = append(p.attrsEdges, make(map[string]string))
 
@@ -134,7 +313,11 @@ func (p *eqlRuntimeProvider) init(startKind string,
 
 	for _, child := range rootChildren {
 
-		if child.Name == parser.NodeWHERE {
+		if child.Name == parser.NodeDISTINCT {
+
+			p.distinct = true
+
+		} else if child.Name == parser.NodeWHERE {
 
 			// Check if the show clause or some traversals are already populated
 
@@ -158,6 +341,9 @@ func (p *eqlRuntimeProvider) init(startKind string,
 			if p.show != nil {
 				return p.newRuntimeError(ErrInvalidConstruct,
 					"traversals must be before show clause", child)
+			} else if p.groupBy != nil {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"traversals must be before group by clause", child)
 			}
 
 			// Reset state of traversal and add it to the traversal list
@@ -168,6 +354,17 @@ func (p *eqlRuntimeProvider) init(startKind string,
 
 			p.traversals = append(p.traversals, child)
 
+		} else if child.Name == parser.NodeGROUPBY {
+
+			// Check if the show clause is already populated
+
+			if p.show != nil {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"group by clause must be before show clause", child)
+			}
+
+			p.groupBy = child
+
 		} else if child.Name == parser.NodeSHOW {
 
 			p.show = child
@@ -196,6 +393,18 @@ func (p *eqlRuntimeProvider) init(startKind string,
 
 			withChild = child
 
+		} else if child.Name == parser.NodeLIMIT {
+
+			// Value was validated by the parser
+
+			p.limit, _ = strconv.Atoi(child.Children[0].Token.Val)
+
+		} else if child.Name == parser.NodeOFFSET {
+
+			// Value was validated by the parser
+
+			p.offset, _ = strconv.Atoi(child.Children[0].Token.Val)
+
 		} else {
 
 			return p.newRuntimeError(ErrInvalidConstruct, child.Name, child)
@@ -209,6 +418,14 @@ func (p *eqlRuntimeProvider) init(startKind string,
 		return err
 	}
 
+	// Group by clause refers to the populated columns
+
+	if p.groupBy != nil {
+		if err := p.initGroupCols(nodeKindPos, edgeKindPos); err != nil {
+			return err
+		}
+	}
+
 	// Interpret with clause straight after populating the columns
 
 	if withChild != nil {
@@ -218,7 +435,7 @@ func (p *eqlRuntimeProvider) init(startKind string,
 	}
 
 	if p.primaryKind == "" {
-		p.primaryKind = startKind
+		p.primaryKind = startKinds[0]
 	}
 
 	return nil
@@ -247,6 +464,7 @@ func (p *eqlRuntimeProvider) initWithFlags(withNode *parser.ASTNode,
 				cds := strings.SplitN(cd, ":", 3)
 				if cds[2] == colDataSplit[0] {
 					col = i
+					break
 				}
 			}
 
@@ -263,6 +481,7 @@ func (p *eqlRuntimeProvider) initWithFlags(withNode *parser.ASTNode,
 				for i, c := range p.colData {
 					if c == cstr {
 						col = i
+						break
 					}
 				}
 			}
@@ -354,6 +573,50 @@ func (p *eqlRuntimeProvider) initWithFlags(withNode *parser.ASTNode,
 				}
 			}
 
+		} else if child.Name == parser.NodeTYPE {
+
+			if len(child.Children) != 2 {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"Type directive requires 2 parameters: column, type", child)
+			}
+
+			c, err := findColumn(child.Children[0].Token.Val, child)
+			if err != nil {
+				return err
+			}
+
+			colType := child.Children[1].Token.Val
+
+			if _, ok := columnTypes[colType]; !ok {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"Unknown column type: "+colType+" (must be int, float, bool or string)", child.Children[1])
+			}
+
+			p.withFlags.typeCol = append(p.withFlags.typeCol, c)
+			p.withFlags.typeColType = append(p.withFlags.typeColType, colType)
+
+		} else if child.Name == parser.NodeFORMAT {
+
+			if len(child.Children) != 1 {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"Format directive requires 1 parameter: casing", child)
+			}
+
+			casing := child.Children[0].Token.Val
+
+			convert, ok := util.CaseConverters[casing]
+			if !ok {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"Unknown casing: "+casing+" (must be camel or snake)", child.Children[0])
+			}
+
+			// Only the column labels are converted - attributes are still
+			// referenced by their stored names
+
+			for i, label := range p.colLabels {
+				p.colLabels[i] = convert(label)
+			}
+
 		} else {
 			return p.newRuntimeError(ErrInvalidConstruct, child.Token.Val, child)
 		}
@@ -391,7 +654,9 @@ func (p *eqlRuntimeProvider) initCols() (map[string][]int, map[string][]int, err
 	for i, spec := range p.specs {
 
 		if i == 0 {
-			addPos(nodeKindPos, spec, i)
+			for _, kind := range p.startKinds {
+				addPos(nodeKindPos, kind, i)
+			}
 		} else {
 			sspec := strings.Split(spec, ":")
 
@@ -461,8 +726,18 @@ func (p *eqlRuntimeProvider) initCols() (map[string][]int, map[string][]int, err
 
 				funcInst, ok := showFunc[funcName]
 				if !ok {
-					return nil, nil, p.newRuntimeError(ErrInvalidConstruct,
-						"Unknown function: "+funcName, col)
+
+					// Try to lookup a custom function
+
+					fn := lookupCustomFunction(funcName)
+					if fn == nil {
+						return nil, nil, p.newRuntimeError(ErrInvalidConstruct,
+							"Unknown function: "+funcName, col)
+					}
+
+					funcInst = func(astNode *parser.ASTNode, rtp *eqlRuntimeProvider) (FuncShow, string, string, error) {
+						return showCustomInst(fn, astNode, rtp)
+					}
 				}
 
 				colFunc, colData, label, err = funcInst(col.Children[0], p)
@@ -581,17 +856,112 @@ func (p *eqlRuntimeProvider) initCols() (map[string][]int, map[string][]int, err
 				p.attrsEdges[pos][attr] = ""
 			}
 		}
+
+		// Aggregate functions collapse all rows into a single row - they
+		// cannot be mixed with columns which show a value per row unless
+		// a group by clause is given
+
+		if p.groupBy == nil {
+			hasAggregate := false
+			for _, cf := range p.colFunc {
+				if _, ok := cf.(FuncShowAggregate); ok {
+					hasAggregate = true
+				}
+			}
+
+			if hasAggregate {
+				for i, cf := range p.colFunc {
+					if _, ok := cf.(FuncShowAggregate); !ok {
+						return nil, nil, p.newRuntimeError(ErrInvalidConstruct,
+							"Column "+p.colData[i]+" is not aggregated and cannot be shown together with aggregate functions",
+							p.show.Children[i])
+					}
+				}
+			}
+		}
 	}
 
 	return nodeKindPos, edgeKindPos, nil
 }
 
+/*
+initGroupCols populates the list of grouping columns. It is assumed that the
+columns have been populated before calling this function. Each grouping
+attribute must be shown in a column and all other columns must be aggregated.
+*/
+func (p *eqlRuntimeProvider) initGroupCols(nodeKindPos map[string][]int,
+	edgeKindPos map[string][]int) error {
+
+	isGroupCol := make(map[int]bool)
+
+	for _, child := range p.groupBy.Children {
+		colData := child.Token.Val
+		colDataSplit := strings.SplitN(colData, ":", 3)
+
+		// Normalise the grouping attribute in the same way as a show term
+
+		switch len(colDataSplit) {
+		case 1:
+			colData = "1:n:" + colDataSplit[0]
+
+		case 2:
+			kind := colDataSplit[0]
+
+			if poslist, ok := nodeKindPos[kind]; ok {
+				colData = fmt.Sprint(poslist[0]+1, ":n:", colDataSplit[1])
+			} else if poslist, ok := edgeKindPos[kind]; ok {
+				colData = fmt.Sprint(poslist[0]+1, ":e:", colDataSplit[1])
+			} else {
+				return p.newRuntimeError(ErrInvalidConstruct,
+					"Cannot determine data position for kind: "+kind, child)
+			}
+		}
+
+		// Find the first column which shows the grouping attribute
+
+		col := -1
+		for i, cd := range p.colData {
+			if cd == colData && p.colFunc[i] == nil {
+				col = i
+				break
+			}
+		}
+
+		if col == -1 {
+			return p.newRuntimeError(ErrInvalidConstruct,
+				"Group by attribute "+child.Token.Val+" must be shown in a column", child)
+		}
+
+		if !isGroupCol[col] {
+			isGroupCol[col] = true
+			p.groupCol = append(p.groupCol, col)
+		}
+	}
+
+	// All other columns must be aggregated
+
+	for i, cf := range p.colFunc {
+		if _, ok := cf.(FuncShowAggregate); !ok && !isGroupCol[i] {
+			return p.newRuntimeError(ErrInvalidConstruct,
+				"Column "+p.colData[i]+" is neither grouped nor aggregated", p.groupBy)
+		}
+	}
+
+	return nil
+}
+
 /*
 next advances to the next query row. Returns false if no more rows are available.
 It is assumed that all traversal specs and query attrs have been filled.
 */
 func (p *eqlRuntimeProvider) next() (bool, error) {
 
+	// Stop if the query was cancelled
+
+	if err := p.checkCancelled(); err != nil {
+		return false, err
+	}
+
 	// Create fetch lists if it is the first next() call
 
 	if p._attrsNodesFetch == nil {
@@ -642,89 +1012,61 @@ func (p *eqlRuntimeProvider) next() (bool, error) {
 	for _, child := range p.traversals {
 		childRuntime := child.Runtime.(*traversalRuntime)
 		if childRuntime.hasMoreNodes() {
-			_, err := childRuntime.Eval()
-			return err == nil, err
-		}
-	}
 
-	// Get next root node
+			// Continue with the next start node if the remaining nodes
+			// of the traversal produce no row
 
-	startKey, err := p.nextStartKey()
-	if err != nil || startKey == "" {
-		return false, err
+			if _, err := childRuntime.Eval(); err != ErrEmptyTraversal {
+				return err == nil, err
+			}
+		}
 	}
 
-	// Fetch node - always require the key attribute
-	// to make sure we get a node back if it exists
-
-	node, err := p.gm.FetchNodePart(p.part, startKey, p.specs[0],
-		append(p._attrsNodesFetch[0], "key"))
+	// Get next root node which matches the where clause
 
+	node, err := p.nextStartNode()
 	if err != nil || node == nil {
 		return false, err
 	}
 
-	// Decide if this node should be added
+	// Add node and the first traversal
 
-	addNode := true
+	if len(p.rowNode) == 0 {
+		p.rowNode = append(p.rowNode, node)
+		p.rowEdge = append(p.rowEdge, nil)
+	} else {
 
-	if p.where != nil {
-		res, err := p.where.Runtime.(CondRuntime).CondEval(node, nil)
-		if err != nil {
-			return false, err
-		}
+		// Clear out the row
 
-		addNode = res.(bool)
-	}
+		for i := range p.rowNode {
+			p.rowNode[i] = nil
+			p.rowEdge[i] = nil
+		}
 
-	if addNode {
+		// Fill in the first node
 
-		// Add node and the first traversal
+		p.rowNode[0] = node
+		p.rowEdge[0] = nil
+	}
 
-		if len(p.rowNode) == 0 {
-			p.rowNode = append(p.rowNode, node)
-			p.rowEdge = append(p.rowEdge, nil)
-		} else {
+	// Give the new source to the children and let them evaluate
 
-			// Clear out the row
+	for _, child := range p.traversals {
+		childRuntime := child.Runtime.(*traversalRuntime)
 
-			for i := range p.rowNode {
-				p.rowNode[i] = nil
-				p.rowEdge[i] = nil
-			}
+		if err := childRuntime.newSource(node); err == ErrEmptyTraversal {
 
-			// Fill in the first node
+			// If an empty traversal error comes back advance until
+			// there is an element or the end
 
-			p.rowNode[0] = node
+			p.rowNode[0] = nil
 			p.rowEdge[0] = nil
-		}
-
-		// Give the new source to the children and let them evaluate
-
-		for _, child := range p.traversals {
-			childRuntime := child.Runtime.(*traversalRuntime)
 
-			if err := childRuntime.newSource(node); err == ErrEmptyTraversal {
+			return p.next()
 
-				// If an empty traversal error comes back advance until
-				// there is an element or the end
-
-				p.rowNode[0] = nil
-				p.rowEdge[0] = nil
-
-				return p.next()
-
-			} else if err != nil {
-				return false, err
-			}
+		} else if err != nil {
+			return false, err
 		}
-
-	} else {
-
-		// Recursively call next until there is a condition-matching node or
-		// there are no more start keys available
-
-		return p.next()
 	}
 
 	return true, nil
@@ -741,6 +1083,7 @@ Runtime map for general components
 var generalProviderMap = map[string]generalInst{
 	parser.NodeEOF:      invalidRuntimeInst,
 	parser.NodeVALUE:    valueRuntimeInst,
+	parser.NodePARAM:    paramRuntimeInst,
 	parser.NodeTRUE:     valueRuntimeInst,
 	parser.NodeFALSE:    valueRuntimeInst,
 	parser.NodeNULL:     valueRuntimeInst,
@@ -748,6 +1091,7 @@ var generalProviderMap = map[string]generalInst{
 	parser.NodeFUNC:     valueRuntimeInst,
 	parser.NodeTRAVERSE: traversalRuntimeInst,
 	parser.NodeWHERE:    whereRuntimeInst,
+	parser.NodeEXPLAIN:  explainRuntimeInst,
 
 	// Condition components
 	// ====================
@@ -763,6 +1107,9 @@ var generalProviderMap = map[string]generalInst{
 	parser.NodeAND: andRuntimeInst,
 	parser.NodeOR:  orRuntimeInst,
 
+	parser.NodeISNULL:    isNullRuntimeInst,
+	parser.NodeISNOTNULL: isNotNullRuntimeInst,
+
 	// Simple arithmetic expressions
 
 	parser.NodePLUS:   plusRuntimeInst,
@@ -771,17 +1118,27 @@ var generalProviderMap = map[string]generalInst{
 	parser.NodeDIV:    divRuntimeInst,
 	parser.NodeMODINT: modIntRuntimeInst,
 	parser.NodeDIVINT: divIntRuntimeInst,
+	parser.NodePOW:    powRuntimeInst,
 
 	// List operations
 
-	parser.NodeIN:    inRuntimeInst,
-	parser.NodeNOTIN: notInRuntimeInst,
+	parser.NodeIN:          inRuntimeInst,
+	parser.NodeNOTIN:       notInRuntimeInst,
+	parser.NodeCONTAINSALL: containsAllRuntimeInst,
+	parser.NodeBETWEEN:     betweenRuntimeInst,
 
 	// String operations
 
 	parser.NodeLIKE:        likeRuntimeInst,
+	parser.NodeMATCHES:     matchesRuntimeInst,
 	parser.NodeCONTAINS:    containsRuntimeInst,
 	parser.NodeCONTAINSNOT: containsNotRuntimeInst,
 	parser.NodeBEGINSWITH:  beginsWithRuntimeInst,
 	parser.NodeENDSWITH:    endsWithRuntimeInst,
+
+	parser.NodeCONTAINSWORD: containsWordRuntimeInst,
+
+	parser.NodeNOTLIKE:       notLikeRuntimeInst,
+	parser.NodeNOTBEGINSWITH: notBeginsWithRuntimeInst,
+	parser.NodeNOTENDSWITH:   notEndsWithRuntimeInst,
 }
@@ -11,9 +11,11 @@
 package interpreter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	"devt.de/krotik/eliasdb/eql/parser"
@@ -33,6 +35,18 @@ func (ni *testNodeInfo) SummaryAttributes(kind string) []string {
 	return []string{"key"}
 }
 
+/*
+Helper function to run a count query.
+*/
+func getCount(query string, rt parser.RuntimeProvider) (interface{}, error) {
+	ast, err := parser.ParseWithRuntime("test", query, rt)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Runtime.Eval()
+}
+
 /*
 Helper function to run a search and check against a result.
 */
@@ -80,6 +94,782 @@ Data: 1:n:key, 1:n:Name
 		t.Error(err)
 		return
 	}
+
+	oldScanBatchSize := scanBatchSize
+	scanBatchSize = 1
+	defer func() {
+		scanBatchSize = oldScanBatchSize
+	}()
+
+	// Keys can be given as a list - keys of nodes which do not exist are skipped
+
+	if err := runSearch("lookup mynode ['000', 'xxx', '123', 'yyy'] where Name != Node0", `
+Labels: Mynode Key, Name
+Format: auto, auto
+Data: 1:n:key, 1:n:Name
+123, Node1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	rt.SetStrictKeys(true)
+
+	if err := runSearch("lookup mynode ['000', 'xxx', '123']", "", rt); err == nil ||
+		err.Error() != "EQL error in test: Unknown node key (Node xxx of kind mynode does not exist) (Line:1 Pos:1)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestPath(t *testing.T) {
+	gm, _ := songGraphGroups()
+	rt := NewPathRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if _, err := getResult("path from Author:000 to Author:456 via :::", `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+000, Author, John, <not set>, <not set>
+Aria3, Song, Aria3, Aria3, Wrote
+Best, group, <not set>, Aria3, Contains
+MyOnlySong3, Song, MyOnlySong3, MyOnlySong3, Contains
+456, Author, Hans, MyOnlySong3, Wrote
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("path from Author:000 to Author:456 via ::: maxhops 4", `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+000, Author, John, <not set>, <not set>
+Aria3, Song, Aria3, Aria3, Wrote
+Best, group, <not set>, Aria3, Contains
+MyOnlySong3, Song, MyOnlySong3, MyOnlySong3, Contains
+456, Author, Hans, MyOnlySong3, Wrote
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Path is longer than the maximum number of hops
+
+	if _, err := getResult("path from Author:000 to Author:456 via ::: maxhops 3", `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// End node is not reachable via the given spec
+
+	if _, err := getResult("path from Author:000 to Author:456 via :Wrote::", `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Start node does not exist
+
+	if _, err := getResult("path from Author:999 to Author:456 via :::", `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Start node is the end node
+
+	if _, err := getResult("path from Song:Aria1 to Song:Aria1 via ::: maxhops 1", `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+Aria1, Song, Aria1, <not set>, <not set>
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Error cases
+
+	if _, err := getResult("path from Author to Author:456 via :::", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Node must be given as <kind>:<key>: Author) (Line:1 Pos:11)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := getResult("path from Author:000 to Author:456 via ::", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid traversal spec (::) (Line:1 Pos:40)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := getResult("path from Author:000 to Author:456 via ::: maxhops 0", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Maximum number of hops must be a positive number: 0) (Line:1 Pos:52)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	gm, _ := songGraphGroups()
+	rt := NewDescribeRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "MixedSong")
+	node.SetAttr("kind", "Song")
+	node.SetAttr("ranking", "high")
+	node.SetAttr("tags", []interface{}{"a", "b"})
+	gm.StoreNode("main", node)
+
+	if res, err := getResult("describe Song", `
+Labels: Attribute, Types, Count
+Format: auto, auto, auto
+Data: 1:n:attribute, 1:n:types, 1:n:count
+key, string, 10
+kind, string, 10
+name, string, 9
+ranking, number, string, 10
+tags, list, 1
+`[1:], rt, false); err != nil || res.RowSource(0)[0] != "" {
+		t.Error(res, err)
+		return
+	}
+}
+
+func TestCount(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if res, err := getCount("count Song", rt); err != nil || res != 9 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount("count Song where ranking > 3", rt); err != nil || res != 6 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount("count Author traverse :::Song where ranking > 5 end", rt); err != nil || res != 4 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount("count Author traverse :::Song where ranking > 18 end", rt); err != nil || res != 1 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount("count Author traverse :::Song where ranking > 18 end with nulltraversal(true)", rt); err != nil || res != 3 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The count function can still be used in get queries
+
+	if err := runSearch("get Author traverse :::Song end show name, @count(1, :::Song)", `
+Labels: Author Name, Count
+Format: auto, auto
+Data: 1:n:name, 1:func:count()
+Hans, 1
+John, 4
+John, 4
+John, 4
+John, 4
+Mike, 4
+Mike, 4
+Mike, 4
+Mike, 4
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Count queries can use an index
+
+	if err := gm.CreateIndex("main", "Song", "ranking"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := getCount("count Song where ranking = 4", rt); err != nil || res != 1 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount("count Song where ranking = 7", rt); err != nil || res != 0 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Test error cases
+
+	if _, err := getCount("count Song show name", rt); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (show cannot be used in a count query) (Line:1 Pos:12)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getCount("count Song limit 2", rt); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (limit cannot be used in a count query) (Line:1 Pos:12)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getCount("count Song with ordering(ascending name)", rt); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (ordering cannot be used in a count query) (Line:1 Pos:17)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getCount("count Spam", rt); err == nil || err.Error() !=
+		"EQL error in test: Unknown node kind (Spam) (Line:1 Pos:7)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestCancel(t *testing.T) {
+	gm, _ := songGraphGroups()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancel the query while the where clause is evaluated
+
+	RegisterFunction("cancelquery", func(node data.Node, edge data.Edge, args []interface{}) (interface{}, error) {
+		cancel()
+		return true, nil
+	})
+	defer RegisterFunction("cancelquery", nil)
+
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+	rt.SetContext(ctx)
+
+	if _, err := getResult("get Author traverse :::Song where @cancelquery() end", "", rt, false); err == nil ||
+		err.(*RuntimeError).Type != ErrCancelled || err.Error() !=
+		"EQL error in test: Query was cancelled (context canceled)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getCount("count Song", rt); err == nil || err.(*RuntimeError).Type != ErrCancelled {
+		t.Error(err)
+		return
+	}
+
+	prt := NewPathRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+	prt.SetContext(ctx)
+
+	if _, err := getResult("path from Author:000 to Author:456 via :::", "", prt, false); err == nil ||
+		err.(*RuntimeError).Type != ErrCancelled {
+		t.Error(err)
+		return
+	}
+
+	drt := NewDescribeRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+	drt.SetContext(ctx)
+
+	if _, err := getResult("describe Song", "", drt, false); err == nil ||
+		err.(*RuntimeError).Type != ErrCancelled {
+		t.Error(err)
+		return
+	}
+
+	// A context which is not done does not change the result
+
+	rt = NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+	rt.SetContext(context.Background())
+
+	if res, err := getCount("count Song", rt); err != nil || res != 9 {
+		t.Error(res, err)
+		return
+	}
+}
+
+/*
+scanGraph creates a graph with a given number of numbered nodes which are
+connected to a few category nodes.
+*/
+func scanGraph(count int) *graph.Manager {
+	gm := graph.NewGraphManager(graphstorage.NewMemoryGraphStorage("scan"))
+
+	for i := 0; i < 5; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprint("c", i))
+		node.SetAttr("kind", "category")
+		gm.StoreNode("main", node)
+	}
+
+	for i := 0; i < count; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprintf("%05d", i))
+		node.SetAttr("kind", "item")
+		node.SetAttr("name", fmt.Sprint("item", i))
+		node.SetAttr("num", i)
+		gm.StoreNode("main", node)
+
+		edge := data.NewGraphEdge()
+		edge.SetAttr("key", fmt.Sprintf("%05d", i))
+		edge.SetAttr("kind", "in")
+		edge.SetAttr(data.EdgeEnd1Key, node.Key())
+		edge.SetAttr(data.EdgeEnd1Kind, node.Kind())
+		edge.SetAttr(data.EdgeEnd1Role, "item")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+		edge.SetAttr(data.EdgeEnd2Key, fmt.Sprint("c", i%5))
+		edge.SetAttr(data.EdgeEnd2Kind, "category")
+		edge.SetAttr(data.EdgeEnd2Role, "category")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+		gm.StoreEdge("main", edge)
+	}
+
+	return gm
+}
+
+func TestParallelScan(t *testing.T) {
+	gm := scanGraph(500)
+
+	oldScanBatchSize := scanBatchSize
+	scanBatchSize = 7
+	defer func() {
+		scanBatchSize = oldScanBatchSize
+	}()
+
+	run := func(query string, workers int) (string, error) {
+		var rtp parser.RuntimeProvider
+
+		if strings.HasPrefix(query, "lookup") {
+			lrt := NewLookupRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+			lrt.SetScanWorkers(workers)
+			rtp = lrt
+		} else {
+			grt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+			grt.SetScanWorkers(workers)
+			rtp = grt
+		}
+
+		res, err := getCount(query, rtp)
+
+		return fmt.Sprint(res), err
+	}
+
+	// Parallel scans produce the same rows in the same order as a sequential scan
+
+	for _, query := range []string{
+		"get item",
+		"get item where num % 3 = 0",
+		"get item where name like '^item1' and @count(:::category, \"key = c1\") = 1",
+		"get item where name matches 'item4.*' or num < 10",
+		"get item where name not like '^item[1-3]' and num < 200",
+		"get item where num > 100 traverse :::category end show key, 2:n:key",
+		"get item where num > 100 with ordering(descending num) limit 10",
+		"get item where num >= 0 limit 3",
+		"get item where num < 0",
+		"lookup item '00001', '00200', '00300' where num > 100",
+		"count item where num % 7 = 1",
+	} {
+		expected, err := run(query, 1)
+		if err != nil {
+			t.Error(query, err)
+			return
+		}
+
+		for _, workers := range []int{2, 4, 16} {
+			if res, err := run(query, workers); err != nil || res != expected {
+				t.Error("Unexpected result for", query, "with", workers, "workers:", res, err)
+				return
+			}
+		}
+	}
+
+	// Errors in the where clause are reported
+
+	RegisterFunction("scanerror", func(node data.Node, edge data.Edge, args []interface{}) (interface{}, error) {
+		if fmt.Sprint(args[0]) == "250" {
+			return nil, errors.New("Test error")
+		}
+		return true, nil
+	})
+	defer RegisterFunction("scanerror", nil)
+
+	if _, err := run("get item where @scanerror(num)", 4); err == nil || err.Error() != "Test error" {
+		t.Error(err)
+		return
+	}
+
+	// Invalid constant patterns are reported before any row is evaluated
+
+	if _, err := run("get item where num < 0 and name like '['", 4); err == nil ||
+		err.(*RuntimeError).Type != ErrNotARegex {
+		t.Error(err)
+		return
+	}
+
+	// Parallel scans can be cancelled
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+	rt.SetScanWorkers(4)
+	rt.SetContext(ctx)
+
+	if _, err := getCount("get item where num > 10", rt); err == nil ||
+		err.(*RuntimeError).Type != ErrCancelled {
+		t.Error(err)
+		return
+	}
+}
+
+func TestParseCached(t *testing.T) {
+	gm := scanGraph(50)
+
+	parser.ClearPlanCache()
+
+	rt1 := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+	rt2 := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	query := "get item where num % 10 = 0 with ordering(ascending num)"
+
+	q1, err := parser.ParseCached("test", query, rt1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// The same query with the same provider returns the cached query
+
+	if q, err := parser.ParseCached("test", query, rt1); err != nil || q != q1 {
+		t.Error("Unexpected result:", q, err)
+		return
+	}
+
+	// Another provider gets its own query
+
+	if q, err := parser.ParseCached("test", query, rt2); err != nil || q == q1 {
+		t.Error("Unexpected result:", q, err)
+		return
+	}
+
+	// Queries with errors are not cached
+
+	if _, err := parser.ParseCached("test", "get item where", rt1); err == nil {
+		t.Error("Parse error expected")
+		return
+	}
+
+	// Prepared queries can be evaluated concurrently
+
+	var wg sync.WaitGroup
+
+	results := make([]string, 5)
+	errs := make([]error, 5)
+
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			res, err := q1.Eval()
+			results[i], errs[i] = fmt.Sprint(res), err
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := range results {
+		if errs[i] != nil || results[i] != results[0] || !strings.Contains(results[0], "00040, item40, 40") {
+			t.Error("Unexpected result:", results[i], errs[i])
+			return
+		}
+	}
+
+	// Different prepared queries which share a provider can be evaluated
+	// concurrently
+
+	q3, err := parser.ParseCached("test", "get item where num < 7", rt1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	counts := make([]int, 10)
+
+	for i := range counts {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			q := q1
+			if i%2 == 1 {
+				q = q3
+			}
+
+			if res, err := q.Eval(); err == nil {
+				counts[i] = res.(*SearchResult).RowCount()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, c := range counts {
+		if (i%2 == 0 && c != 5) || (i%2 == 1 && c != 7) {
+			t.Error("Unexpected result:", counts)
+			return
+		}
+	}
+
+	// A change of the schema invalidates the cached query
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "foo")
+	node.SetAttr("kind", "item")
+	node.SetAttr("newattr", "bar")
+
+	if err := gm.StoreNode("main", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	q2, err := parser.ParseCached("test", query, rt1)
+	if err != nil || q2 == q1 {
+		t.Error("Unexpected result:", q2, err)
+		return
+	}
+
+	if q, err := parser.ParseCached("test", query, rt1); err != nil || q != q2 {
+		t.Error("Unexpected result:", q, err)
+		return
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	gm := scanGraph(5000)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprint(workers, " workers"), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rt := NewGetRuntimeProvider("bench", "main", gm, NewDefaultNodeInfo(gm))
+				rt.SetScanWorkers(workers)
+
+				res, err := getCount("get item where name matches 'item4[0-9]*1$' and "+
+					"@count(:::category, \"key = c1\") = 1", rt)
+
+				if err != nil || res.(*SearchResult).RowCount() != 111 {
+					b.Fatal(res, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiKindGet(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Rows of all kinds are returned kind by kind in the order of the query
+
+	if _, err := getResult("get Song, Author where name beginswith M", `
+Labels: Key, Kind, Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name
+MyOnlySong3, Song, MyOnlySong3
+123, Author, Mike
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author, Song where name beginswith M", `
+Labels: Key, Kind, Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name
+123, Author, Mike
+MyOnlySong3, Song, MyOnlySong3
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Conditions on attributes which a kind does not have are false
+
+	if err := runSearch("get Author, Song where ranking > 5 or name = Hans show kind, name, Song:ranking", `
+Labels: Kind, Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:kind, 1:n:name, 1:n:ranking
+Author, Hans, <not set>
+Song, Aria1, 8
+Song, Aria4, 18
+Song, DeadSong2, 6
+Song, MyOnlySong3, 19
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get Author, Song where ranking * 2 > 30", `
+Labels: Key, Kind, Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name
+Aria4, Song, Aria4
+MyOnlySong3, Song, MyOnlySong3
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get Author, Song where not ranking > 5 and name beginswith A", `
+Labels: Key, Kind, Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name
+Aria2, Song, Aria2
+Aria3, Song, Aria3
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get Author, Song where name = Hans traverse :::Song end show name, 2:n:name", `
+Labels: Name, Name
+Format: auto, auto
+Data: 1:n:name, 2:n:name
+Hans, MyOnlySong3
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Count queries can also use several kinds
+
+	if res, err := getCount("count Author, Song where ranking > 5", rt); err != nil || res != 4 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount("count Author, Song", rt); err != nil || res != 12 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Start keys can be looked up in an index of one of the kinds
+
+	if err := gm.CreateIndex("main", "Song", "ranking"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get Author, Song where ranking = 8", `
+Labels: Key, Kind, Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name
+Aria1, Song, Aria1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get Song, Spam", "", rt); err == nil || err.Error() !=
+		"EQL error in test: Unknown node kind (Spam) (Line:1 Pos:11)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestExplain(t *testing.T) {
+	gm, _ := songGraphGroups()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if res, err := getResult("explain get Song", `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, full scan, All nodes of kind Song, 9
+`[1:], rt, false); err != nil || res.RowSource(0)[0] != "" {
+		t.Error(res, err)
+		return
+	}
+
+	// Traversals are listed in the order in which they are followed
+
+	if res, err := getResult(`explain get Song where name = "Aria1" and ranking > 1
+  traverse :Wrote:: where number > 1
+    traverse ::: end
+  end
+  traverse :::Author end
+show name`, `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, full scan, All nodes of kind Song, 9
+2, filter, name = Aria1 and ranking > 1, 9
+3, traversal, :Wrote:: from step 2, 9
+4, filter, number > 1, 9
+5, traversal, ::: from step 4, 13
+6, traversal, :::Author from step 2, 13
+`[1:], rt, false); err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	// Start nodes are looked up in an index if possible
+
+	if err := gm.CreateIndex("main", "Song", "name"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := getResult(`explain get Song where name = "Aria1" and ranking > 1 limit 1`, `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, index lookup, Nodes of kind Song with name = Aria1 (attribute index), 1
+2, filter, name = Aria1 and ranking > 1, 1
+`[1:], rt, false); err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	if res, err := getResult(`explain count Author, Song from group Best`, `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, group traversal, Nodes of kind Author in group Best, 3
+2, group traversal, Nodes of kind Song in group Best, 9
+`[1:], rt, false); err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	lrt := NewLookupRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if res, err := getResult(`explain lookup Song "Aria1", "Aria2" where ranking > 1`, `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, key lookup, Nodes of kind Song by key, 2
+2, filter, ranking > 1, 2
+`[1:], lrt, false); err != nil {
+		t.Error(res, err)
+		return
+	}
+
+	if _, err := getResult(`explain get Spam`, "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Unknown node kind (Spam) (Line:1 Pos:13)" {
+		t.Error(err)
+		return
+	}
 }
 
 func TestMultiKindTraversal(t *testing.T) {
@@ -112,6 +902,27 @@ Data: 1:n:key, 2:n:key, 2:e:key, 2:e:name
 		return
 	}
 
+	// Test reverse traversal - roles of the spec are swapped
+
+	if err := runSearch("get mynode3 traverse reverse src:myedge:dest: end show mynode3:key, 2:n:key, 2:e:key", `
+Labels: Mynode3 Key, Key, Key
+Format: auto, auto, auto
+Data: 1:n:key, 2:n:key, 2:e:key
+789, 456, abc3
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode3 traverse src:myedge:dest: end", `
+Labels: Mynode3 Key, Key, Kind, Name
+Format: auto, auto, auto, auto
+Data: 1:n:key, 2:n:key, 2:n:kind, 2:n:name
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
 	// Test primary kind
 
 	ast, err := parser.ParseWithRuntime("test", "get mynode0 traverse ::: end primary mynode2", rt)
@@ -235,7 +1046,7 @@ func TestErrors(t *testing.T) {
 
 	msm.AccessMap[1] = storage.AccessCacheAndFetchSeriousError
 
-	if _, err := rt.nextStartKey(); err.Error() != "GraphError: Could not read graph information (Record is already in-use (? - ))" {
+	if _, _, err := rt.nextStartKey(); err.Error() != "GraphError: Could not read graph information (Record is already in-use (? - ))" {
 		t.Error(err)
 		return
 	}
@@ -258,25 +1069,33 @@ func TestErrors(t *testing.T) {
 
 	msm = mgs.StorageManager("main"+"mynewnode"+graph.StorageSuffixNodes, false).(*storage.MemoryStorageManager)
 
-	msm.AccessMap[5] = storage.AccessCacheAndFetchError // Node 3 attribute lookup
+	msm.AccessMap[6] = storage.AccessCacheAndFetchError // Node 3 attribute lookup
 
 	if err := runSearch("get mynode traverse :::mynewnode traverse :::mynewnode end end", "", rt); err.Error() !=
-		"GraphError: Could not read graph information (Slot not found (mystorage/mainmynewnode.nodes - Location:5))" {
+		"GraphError: Could not read graph information (Slot not found (mystorage/mainmynewnode.nodes - Location:6))" {
 		t.Error(err)
 		return
 	}
 
-	delete(msm.AccessMap, 5)
+	delete(msm.AccessMap, 6)
 
-	msm.AccessMap[11] = storage.AccessCacheAndFetchError // Traversal spec error
+	msm.AccessMap[15] = storage.AccessCacheAndFetchError // Traversal spec error
 
 	if err := runSearch("get mynode traverse :::mynewnode traverse :::mynewnode end end", "", rt); err.Error() !=
-		"GraphError: Could not read graph information (Slot not found (mystorage/mainmynewnode.nodes - Location:11))" {
+		"GraphError: Could not read graph information (Slot not found (mystorage/mainmynewnode.nodes - Location:15))" {
 		t.Error(err)
 		return
 	}
 
-	delete(msm.AccessMap, 11)
+	// Storage errors keep the position of the clause which caused them
+
+	if err := runSearch("get mynode traverse :::mynewnode traverse :::mynewnode end end", "", rt); err.(*SourceError).Line != 1 ||
+		err.(*SourceError).Pos != 34 || err.(*SourceError).Node.Name != parser.NodeTRAVERSE {
+		t.Error(err)
+		return
+	}
+
+	delete(msm.AccessMap, 15)
 
 	msm = mgs.StorageManager("main"+"myedge"+graph.StorageSuffixEdges, false).(*storage.MemoryStorageManager)
 
@@ -393,12 +1212,12 @@ func TestErrors(t *testing.T) {
 
 	i := 0
 	oldNextStartKey := ast.Runtime.(*getRuntime).rtp.nextStartKey
-	ast.Runtime.(*getRuntime).rtp.nextStartKey = func() (string, error) {
+	ast.Runtime.(*getRuntime).rtp.nextStartKey = func() (string, string, error) {
 		i++
 		if i == 3 {
-			return "", errors.New("testerror")
+			return "", "", errors.New("testerror")
 		}
-		return "000", nil
+		return "000", "mynode", nil
 	}
 
 	allowMultiEval = true
@@ -427,6 +1246,72 @@ Data: 1:n:key, 1:n:Name
 	}
 }
 
+func TestNestedTraversal(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Each nesting level adds a traversal step to the result rows
+
+	if res, err := getResult(`
+get Author where key = '456'
+	traverse :Wrote::Song
+		traverse :Wrote::Author
+			traverse :Wrote::Song
+			end
+		end
+	end`, `
+Labels: Author Key, Author Name, Song Key, Song Name, Ranking, Author Key, Author Name, Song Key, Song Name, Ranking
+Format: auto, auto, auto, auto, auto, auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:name, 2:n:key, 2:n:name, 2:n:ranking, 3:n:key, 3:n:name, 4:n:key, 4:n:name, 4:n:ranking
+456, Hans, MyOnlySong3, MyOnlySong3, 19, 456, Hans, MyOnlySong3, MyOnlySong3, 19
+`[1:], rt, true); err != nil || res == nil {
+		t.Error(res, err)
+		return
+	}
+
+	// Conditions on each level restrict the rows
+
+	if res, err := getResult(`
+get Author where key = '123'
+	traverse :Wrote::Song where ranking < 4
+		traverse :Wrote::Author
+			traverse :Wrote::Song where ranking > 4
+			end
+		end
+	end
+show 1:n:name, 2:n:key, 3:n:name, 4:n:key, 4:n:ranking`, `
+Labels: Name, Key, Name, Key, Ranking
+Format: auto, auto, auto, auto, auto
+Data: 1:n:name, 2:n:key, 3:n:name, 4:n:key, 4:n:ranking
+Mike, FightSong4, Mike, DeadSong2, 6
+Mike, FightSong4, Mike, StrangeSong1, 5
+Mike, LoveSong3, Mike, DeadSong2, 6
+Mike, LoveSong3, Mike, StrangeSong1, 5
+`[1:], rt, true); err != nil || res == nil {
+		t.Error(res, err)
+		return
+	}
+
+	// Rows are dropped if the deepest traversal finds nothing
+
+	if res, err := getResult(`
+get Author where key = '456'
+	traverse :Wrote::Song
+		traverse :Wrote::Author
+			traverse :Wrote::Song where ranking < 10
+			end
+		end
+	end
+show 1:n:key, 4:n:key`, `
+Labels: Key, Key
+Format: auto, auto
+Data: 1:n:key, 4:n:key
+`[1:], rt, true); err != nil || res == nil {
+		t.Error(res, err)
+		return
+	}
+}
+
 func TestBasicTraversalAndShow(t *testing.T) {
 	gm, _ := simpleGraph()
 	rt := NewGetRuntimeProvider("test", "main", gm, &testNodeInfo{&defaultNodeInfo{gm}})
@@ -576,6 +1461,157 @@ Data: 3:n:key, 2:n:key, 1:n:key
 		t.Error("Unexpected search result:", res, err)
 		return
 	}
+
+	// Test nulltraversal directive on individual traversals
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode nulltraversal(true)
+	end`, `
+Labels: Mynode Key, Mynewnode Key
+Format: auto, auto
+Data: 1:n:key, 2:n:key
+000, <not set>
+123, 456
+123, 456
+123, xxx ⌘
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Mixed traversal steps - node 000 is dropped by the first traversal
+	// while xxx is kept by the second
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode
+		traverse :::mynewnode where key != "789" nulltraversal(true)
+		end
+	end`, `
+Labels: Mynode Key, Mynewnode Key, Mynewnode Key
+Format: auto, auto, auto
+Data: 1:n:key, 2:n:key, 3:n:key
+123, 456, 789-2
+123, 456, 789-2
+123, xxx ⌘, <not set>
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Without nulltraversal a row is dropped if a later traversal step finds
+	// no nodes - other rows of the same start node are kept (inner join).
+	// With nulltraversal the row is kept and the missing step has no values
+	// (outer join).
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode
+		traverse :::mynewnode where key = "789-2"
+		end
+	end`, `
+Labels: Mynode Key, Mynewnode Key, Mynewnode Key
+Format: auto, auto, auto
+Data: 1:n:key, 2:n:key, 3:n:key
+123, 456, 789-2
+123, 456, 789-2
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode
+		traverse :::mynewnode where key = "789-2"
+		end
+	end
+with nulltraversal(true)`, `
+Labels: Mynode Key, Mynewnode Key, Mynewnode Key
+Format: auto, auto, auto
+Data: 1:n:key, 2:n:key, 3:n:key
+000, <not set>, <not set>
+123, 456, 789-2
+123, 456, 789-2
+123, xxx ⌘, <not set>
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Start nodes without any complete row are dropped
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode
+		traverse :::mynewnode where key = "foo"
+		end
+	end`, `
+Labels: Mynode Key, Mynewnode Key, Mynewnode Key
+Format: auto, auto, auto
+Data: 1:n:key, 2:n:key, 3:n:key
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// A step which requires nodes drops rows of a previous step which
+	// found no nodes
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode nulltraversal(true)
+		traverse :::mynewnode where key = "789-2" nulltraversal(false)
+		end
+	end`, `
+Labels: Mynode Key, Mynewnode Key, Mynewnode Key
+Format: auto, auto, auto
+Data: 1:n:key, 2:n:key, 3:n:key
+123, 456, 789-2
+123, 456, 789-2
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Count queries follow the same rules
+
+	if res, err := getCount(`count mynode traverse :::mynewnode traverse :::mynewnode where key = "789-2" end end`,
+		rt); err != nil || res != 2 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := getCount(`count mynode traverse :::mynewnode traverse :::mynewnode where key = "789-2" end end `+
+		`with nulltraversal(true)`, rt); err != nil || res != 4 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The directive on a traversal overrides the query wide setting
+
+	if err := runSearch(`
+get mynode
+	traverse :::mynewnode nulltraversal(false)
+	end
+with nulltraversal(true)`, `
+Labels: Mynode Key, Mynewnode Key
+Format: auto, auto
+Data: 1:n:key, 2:n:key
+123, 456
+123, 456
+123, xxx ⌘
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode traverse :::mynewnode nulltraversal(1) end", "", rt); err == nil ||
+		err.Error() != "EQL error in test: Invalid construct (nulltraversal directive must be true or false) (Line:1 Pos:34)" {
+		t.Error(err)
+		return
+	}
 }
 
 func simpleGraph() (*graph.Manager, *graphstorage.MemoryGraphStorage) {
@@ -24,6 +24,45 @@ func (rt *eqlRuntimeProvider) newRuntimeError(t error, d string, node *parser.AS
 	return &RuntimeError{rt.name, t, d, node, node.Token.Lline, node.Token.Lpos}
 }
 
+/*
+newSourceError wraps an error which occurred while a given AST node was
+evaluated (e.g. an error of the graph storage) into a SourceError. Errors which
+already carry a position and other EQL errors are returned unchanged.
+*/
+func (rt *eqlRuntimeProvider) newSourceError(err error, node *parser.ASTNode) error {
+
+	switch err.(type) {
+	case nil, *RuntimeError, *ResultError, *SourceError:
+		return err
+	}
+
+	if err == ErrEmptyTraversal || node == nil || node.Token == nil {
+		return err
+	}
+
+	return &SourceError{rt.name, err, node, node.Token.Lline, node.Token.Lpos}
+}
+
+/*
+SourceError is an error which occurred while a part of a query was evaluated
+(e.g. an error of the graph storage). The error keeps the position of the
+clause which caused it. Its message is the message of the original error.
+*/
+type SourceError struct {
+	Source string          // Name of the source which was given to the parser
+	Err    error           // Original error
+	Node   *parser.ASTNode // AST Node where the error occurred
+	Line   int             // Line of the error
+	Pos    int             // Position of the error
+}
+
+/*
+Error returns the message of the original error.
+*/
+func (se *SourceError) Error() string {
+	return se.Err.Error()
+}
+
 /*
 RuntimeError is a runtime related error
 */
@@ -55,13 +94,21 @@ Runtime related error types
 var (
 	ErrNotARegex        = errors.New("Value of operand is not a valid regex")
 	ErrNotANumber       = errors.New("Value of operand is not a number")
+	ErrNotADate         = errors.New("Value of operand is not a date")
+	ErrDivisionByZero   = errors.New("Division by zero")
+	ErrInvalidExponent  = errors.New("Invalid exponent")
 	ErrNotAList         = errors.New("Value of operand is not a list")
 	ErrInvalidConstruct = errors.New("Invalid construct")
 	ErrUnknownNodeKind  = errors.New("Unknown node kind")
+	ErrUnknownNodeKey   = errors.New("Unknown node key")
+	ErrUnknownKind      = errors.New("Unknown node or edge kind")
 	ErrInvalidSpec      = errors.New("Invalid traversal spec")
 	ErrInvalidWhere     = errors.New("Invalid where clause")
 	ErrInvalidColData   = errors.New("Invalid column data spec")
+	ErrInvalidColValue  = errors.New("Invalid column value")
 	ErrEmptyTraversal   = errors.New("Empty traversal")
+	ErrCancelled        = errors.New("Query was cancelled")
+	ErrSpillFailed      = errors.New("Could not use spill file")
 )
 
 /*
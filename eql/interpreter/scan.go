@@ -0,0 +1,362 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"sync"
+
+	"devt.de/krotik/eliasdb/eql/parser"
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+/*
+scanBatchSize is the number of start keys which are handed to a scan worker
+at once.
+*/
+var scanBatchSize = 64
+
+// Start node scan
+// ===============
+
+/*
+nextStartNode returns the next start node which matches the where clause of
+the query. Returns nil if there are no more start nodes.
+*/
+func (p *eqlRuntimeProvider) nextStartNode() (data.Node, error) {
+
+	if p.lookup != nil {
+
+		// Start nodes of a known list of keys are fetched in batches
+
+		return p.nextLookupNode()
+
+	} else if p.scanWorkers > 1 {
+
+		// Start nodes are fetched and filtered by several goroutines
+
+		if p.scan == nil {
+			p.scan = p.startScan()
+		}
+
+		return p.scan.next()
+	}
+
+	attrs := append(p._attrsNodesFetch[0], "key")
+
+	for {
+		startKey, startKind, err := p.nextStartKey()
+		if err != nil || startKey == "" {
+			return nil, err
+		}
+
+		node, match, err := p.fetchStartNode(startKey, startKind, attrs)
+		if err != nil || node == nil || match {
+			return node, err
+		}
+
+		// Stop if the query was cancelled while looking for a matching node
+
+		if err := p.checkCancelled(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+fetchStartNode fetches a start node with a given list of attributes and
+evaluates the where clause on it. The list of attributes must contain the key
+attribute to make sure a node is returned if it exists.
+*/
+func (p *eqlRuntimeProvider) fetchStartNode(key string, kind string,
+	attrs []string) (data.Node, bool, error) {
+
+	node, err := p.gm.FetchNodePart(p.part, key, kind, attrs)
+	if err != nil || node == nil {
+		return nil, false, err
+	}
+
+	match, err := p.matchStartNode(node)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return node, match, nil
+}
+
+/*
+matchStartNode evaluates the where clause on a given start node.
+*/
+func (p *eqlRuntimeProvider) matchStartNode(node data.Node) (bool, error) {
+
+	// Decide if this node should be added
+
+	if p.where != nil {
+		res, err := p.where.Runtime.(CondRuntime).CondEval(node, nil)
+		if err != nil {
+			return false, err
+		}
+
+		return res.(bool), nil
+	}
+
+	return true, nil
+}
+
+/*
+lookupScan is a scan of start nodes whose keys are known in advance. The nodes
+are fetched in batches of scanBatchSize nodes.
+*/
+type lookupScan struct {
+	node   *parser.ASTNode // Lookup node of the query (used for errors)
+	kind   string          // Node kind of all start nodes
+	keys   []string        // Keys of the start nodes which have not been fetched
+	nodes  []data.Node     // Fetched start nodes which match the where clause
+	strict bool            // Flag if a key of a node which does not exist is an error
+}
+
+/*
+nextLookupNode returns the next start node of a lookup scan which matches the
+where clause of the query. Returns nil if there are no more start nodes.
+*/
+func (p *eqlRuntimeProvider) nextLookupNode() (data.Node, error) {
+	s := p.lookup
+
+	for len(s.nodes) == 0 {
+
+		if len(s.keys) == 0 {
+			return nil, nil
+		}
+
+		// Stop if the query was cancelled
+
+		if err := p.checkCancelled(); err != nil {
+			return nil, err
+		}
+
+		batch := s.keys
+		if len(batch) > scanBatchSize {
+			batch = batch[:scanBatchSize]
+		}
+
+		s.keys = s.keys[len(batch):]
+
+		nodes, err := p.gm.FetchNodesPart(p.part, batch, s.kind,
+			append(p._attrsNodesFetch[0], "key"))
+		if err != nil {
+			return nil, err
+		}
+
+		for i, node := range nodes {
+
+			if node == nil {
+
+				// Keys of nodes which do not exist are skipped
+
+				if s.strict {
+					return nil, p.newRuntimeError(ErrUnknownNodeKey,
+						fmt.Sprintf("Node %v of kind %v does not exist", batch[i], s.kind), s.node)
+				}
+
+				continue
+			}
+
+			match, err := p.matchStartNode(node)
+			if err != nil {
+				return nil, err
+			} else if match {
+				s.nodes = append(s.nodes, node)
+			}
+		}
+	}
+
+	node := s.nodes[0]
+	s.nodes = s.nodes[1:]
+
+	return node, nil
+}
+
+/*
+startNodeScan is a parallel scan of start nodes. A producer reads batches of
+start keys which are fetched and filtered by several workers. The batches are
+put back into their original order so the scan produces the same start nodes
+in the same order as a sequential scan.
+*/
+type startNodeScan struct {
+	results chan *scanBatch    // Batches which have been processed by a worker
+	quit    chan bool          // Channel which is closed to stop the scan
+	pending map[int]*scanBatch // Processed batches which arrived out of order
+	nextSeq int                // Sequence number of the next batch
+	current *scanBatch         // Batch which is currently read
+	pos     int                // Position in the current batch
+}
+
+/*
+scanBatch is a batch of start keys.
+*/
+type scanBatch struct {
+	seq   int         // Sequence number of the batch
+	keys  []string    // Start keys
+	kinds []string    // Node kinds of the start keys
+	nodes []data.Node // Start nodes which match the where clause
+	end   bool        // Flag if a start node did not exist (ends the scan)
+	err   error       // Error which occurred while reading or processing the batch
+}
+
+/*
+startScan starts a parallel scan of all start nodes.
+*/
+func (p *eqlRuntimeProvider) startScan() *startNodeScan {
+	var wg sync.WaitGroup
+
+	s := &startNodeScan{make(chan *scanBatch, p.scanWorkers), make(chan bool),
+		make(map[int]*scanBatch), 0, nil, 0}
+
+	jobs := make(chan *scanBatch, p.scanWorkers)
+	attrs := p._attrsNodesFetch[0]
+
+	// Read start keys in batches - the node key iterators cannot be shared
+
+	go func() {
+		defer close(jobs)
+
+		for seq := 0; ; seq++ {
+			b := &scanBatch{seq: seq}
+
+			for len(b.keys) < scanBatchSize {
+				key, kind, err := p.nextStartKey()
+				if err != nil {
+					b.err = err
+					break
+				} else if key == "" {
+					break
+				}
+
+				b.keys = append(b.keys, key)
+				b.kinds = append(b.kinds, kind)
+			}
+
+			if len(b.keys) == 0 && b.err == nil {
+				return
+			}
+
+			// The batch belongs to the workers once it was sent
+
+			last := b.err != nil || len(b.keys) < scanBatchSize
+
+			select {
+			case jobs <- b:
+			case <-s.quit:
+				return
+			}
+
+			if last {
+				return
+			}
+		}
+	}()
+
+	// Fetch and filter the start nodes
+
+	for i := 0; i < p.scanWorkers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Every worker gets its own fetch list
+
+			workerAttrs := append(append([]string{}, attrs...), "key")
+
+			for b := range jobs {
+
+				for i := 0; i < len(b.keys) && b.err == nil; i++ {
+					var node data.Node
+					var match bool
+
+					if b.err = p.checkCancelled(); b.err == nil {
+						node, match, b.err = p.fetchStartNode(b.keys[i], b.kinds[i], workerAttrs)
+					}
+
+					if b.err == nil && node == nil {
+						b.end = true
+						break
+					} else if match {
+						b.nodes = append(b.nodes, node)
+					}
+				}
+
+				select {
+				case s.results <- b:
+				case <-s.quit:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(s.results)
+	}()
+
+	return s
+}
+
+/*
+next returns the next start node of this scan. Returns nil if there are no more
+start nodes.
+*/
+func (s *startNodeScan) next() (data.Node, error) {
+
+	for {
+
+		if b := s.current; b != nil {
+
+			if s.pos < len(b.nodes) {
+				s.pos++
+				return b.nodes[s.pos-1], nil
+			} else if b.err != nil || b.end {
+				return nil, b.err
+			}
+		}
+
+		// Wait for the next batch in order
+
+		b, ok := s.pending[s.nextSeq]
+
+		for !ok {
+			res, more := <-s.results
+			if !more {
+				return nil, nil
+			}
+
+			s.pending[res.seq] = res
+			b, ok = s.pending[s.nextSeq]
+		}
+
+		delete(s.pending, s.nextSeq)
+
+		s.nextSeq++
+		s.current = b
+		s.pos = 0
+	}
+}
+
+/*
+stopScan stops a running parallel scan of start nodes.
+*/
+func (p *eqlRuntimeProvider) stopScan() {
+	if p.scan != nil {
+		close(p.scan.quit)
+		p.scan = nil
+	}
+}
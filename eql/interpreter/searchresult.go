@@ -14,6 +14,7 @@ import (
 	"bytes"
 	"encoding/csv"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -76,12 +77,24 @@ type SearchResult struct {
 	name      string     // Name to identify the result
 	query     string     // Query which produced the search result
 	withFlags *withFlags // With flags which should be applied to the result
+	distinct  bool       // Flag if duplicate rows should be removed
+	limit     int        // Maximum number of rows (-1 for no limit)
+	offset    int        // Number of rows to skip (-1 for no offset)
 
 	SearchHeader            // Embedded search header
 	colFunc      []FuncShow // Function which transforms the data
+	groupCol     []int      // Columns which are used to group rows
+
+	spillMaxRows int          // Number of rows which are kept in memory (0 for no limit)
+	spillDir     string       // Directory for the spill file
+	spill        *resultSpill // Spill file which holds rows which do not fit into memory
+
+	uniqueCounters []*uniqueCounter // Counters for values of columns with unique filters
 
 	Source [][]string      // Special string holding the data source (node / edge) for each column
 	Data   [][]interface{} // Data which is held by this search result
+
+	LastError error // Last error which occurred when reading rows from a spill file
 }
 
 /*
@@ -99,8 +112,9 @@ func newSearchResult(rtp *eqlRuntimeProvider, query string) *SearchResult {
 		}
 	}
 
-	return &SearchResult{rtp.name, query, rtp.withFlags, SearchHeader{rtp.primaryKind, rtp.part, rtp.colLabels, rtp.colFormat,
-		cdl}, rtp.colFunc, make([][]string, 0), make([][]interface{}, 0)}
+	return &SearchResult{rtp.name, query, rtp.withFlags, rtp.distinct, rtp.limit, rtp.offset, SearchHeader{rtp.primaryKind, rtp.part, rtp.colLabels, rtp.colFormat,
+		cdl}, rtp.colFunc, rtp.groupCol, rtp.spillMaxRows, rtp.spillDir, nil, nil, make([][]string, 0),
+		make([][]interface{}, 0), nil}
 }
 
 /*
@@ -111,6 +125,15 @@ func (sr *SearchResult) addRow(rowNodes []data.Node, rowEdges []data.Edge) error
 	var isNode bool
 	var err error
 
+	// Write the rows which were collected so far to the spill file if the
+	// result got too large
+
+	if sr.spillMaxRows > 0 && len(sr.Data) >= sr.spillMaxRows {
+		if err = sr.spillData(); err != nil {
+			return err
+		}
+	}
+
 	src := make([]string, 0, len(sr.ColData))
 	row := make([]interface{}, 0, len(sr.ColData))
 
@@ -205,19 +228,42 @@ func (sr *SearchResult) addRow(rowNodes []data.Node, rowEdges []data.Edge) error
 	return nil
 }
 
+/*
+spillData writes all rows which are held in memory to the spill file.
+*/
+func (sr *SearchResult) spillData() error {
+	var err error
+
+	if sr.spill == nil {
+		if sr.spill, err = newResultSpill(sr.spillDir); err != nil {
+			return &ResultError{sr.name, ErrSpillFailed, err.Error()}
+		}
+	}
+
+	if err = sr.spill.writeRun(sr.Data, sr.Source); err != nil {
+		return &ResultError{sr.name, ErrSpillFailed, err.Error()}
+	}
+
+	sr.Data = make([][]interface{}, 0)
+	sr.Source = make([][]string, 0)
+
+	return nil
+}
+
 /*
 finish is called once all rows have been added.
 */
-func (sr *SearchResult) finish() {
+func (sr *SearchResult) finish() error {
+
+	if sr.spill != nil {
+		return sr.finishSpilled()
+	}
 
 	// Apply filtering
 
 	if len(sr.withFlags.notnullCol) > 0 || len(sr.withFlags.uniqueCol) > 0 {
 
-		uniqueMaps := make([]map[string]int, len(sr.withFlags.uniqueCol))
-		for i := range uniqueMaps {
-			uniqueMaps[i] = make(map[string]int)
-		}
+		sr.uniqueCounters = newUniqueCounters(len(sr.withFlags.uniqueCol))
 
 		// Using downward loop so we can remove the current element if necessary
 
@@ -230,6 +276,7 @@ func (sr *SearchResult) finish() {
 			for _, nn := range sr.withFlags.notnullCol {
 				if row[nn] == nil {
 					sr.Data = append(sr.Data[:i], sr.Data[i+1:]...)
+					sr.Source = append(sr.Source[:i], sr.Source[i+1:]...)
 					cont = true
 					break
 				}
@@ -242,12 +289,10 @@ func (sr *SearchResult) finish() {
 			// Apply unique
 
 			for j, u := range sr.withFlags.uniqueCol {
-				if _, ok := uniqueMaps[j][fmt.Sprint(row[u])]; ok {
-					uniqueMaps[j][fmt.Sprint(row[u])]++
+				if !sr.uniqueCounters[j].add(row[u]) {
 					sr.Data = append(sr.Data[:i], sr.Data[i+1:]...)
+					sr.Source = append(sr.Source[:i], sr.Source[i+1:]...)
 					break
-				} else {
-					uniqueMaps[j][fmt.Sprint(row[u])] = 1
 				}
 			}
 		}
@@ -258,19 +303,285 @@ func (sr *SearchResult) finish() {
 			u := sr.withFlags.uniqueCol[j]
 			if uc {
 				for _, row := range sr.Data {
-					row[u] = fmt.Sprintf("%v (%d)", row[u], uniqueMaps[j][fmt.Sprint(row[u])])
+					row[u] = fmt.Sprintf("%v (%d)", row[u], sr.uniqueCounters[j].count(row[u]))
 				}
 			}
 		}
 	}
 
+	// Apply aggregation
+
+	if sr.isAggregated() {
+		sr.Data, sr.Source, _ = sr.aggregate(sr.eachRow)
+	}
+
+	return sr.finishRows()
+}
+
+/*
+finishRows removes duplicate rows, orders the rows, applies offset and limit
+and converts columns to their declared types.
+*/
+func (sr *SearchResult) finishRows() error {
+
+	// Remove duplicate rows
+
+	if sr.distinct {
+		sr.removeDuplicates()
+	}
+
 	// Apply ordering
 
+	if len(sr.withFlags.ordering) > 0 {
+		sort.Sort(sr.rowComparator(sr.Data, sr.Source))
+	}
+
+	// Apply offset and limit
+
+	if sr.offset > 0 {
+		if sr.offset >= len(sr.Data) {
+			sr.Data = sr.Data[:0]
+			sr.Source = sr.Source[:0]
+		} else {
+			sr.Data = sr.Data[sr.offset:]
+			sr.Source = sr.Source[sr.offset:]
+		}
+	}
+
+	if sr.limit >= 0 && sr.limit < len(sr.Data) {
+		sr.Data = sr.Data[:sr.limit]
+		sr.Source = sr.Source[:sr.limit]
+	}
+
+	// Convert columns to their declared types
+
+	for j, row := range sr.Data {
+		if err := sr.convertRow(row, j); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+rowComparator returns a comparator which orders given rows according to the
+ordering of this result.
+*/
+func (sr *SearchResult) rowComparator(data [][]interface{}, source [][]string) *SearchResultRowComparator {
+	ascending := make([]bool, len(sr.withFlags.ordering))
+
 	for i, ordering := range sr.withFlags.ordering {
-		sort.Stable(&SearchResultRowComparator{ordering == withOrderingAscending,
-			sr.withFlags.orderingCol[i], sr.Data, sr.Source})
+		ascending[i] = ordering == withOrderingAscending
 	}
 
+	return &SearchResultRowComparator{ascending, sr.withFlags.orderingCol, data, source}
+}
+
+/*
+convertRow converts the columns of a given row to their declared types.
+*/
+func (sr *SearchResult) convertRow(row []interface{}, line int) error {
+
+	for i, c := range sr.withFlags.typeCol {
+
+		// Missing values stay missing
+
+		if row[c] == nil {
+			continue
+		}
+
+		val, err := columnTypes[sr.withFlags.typeColType[i]](row[c])
+		if err != nil {
+			return &ResultError{sr.name, ErrInvalidColValue, fmt.Sprintf(
+				"Cannot convert %#v in row %v column %v (%v) to %v",
+				fmt.Sprint(row[c]), line+1, c+1, sr.ColData[c], sr.withFlags.typeColType[i])}
+		}
+
+		row[c] = val
+	}
+
+	return nil
+}
+
+/*
+isComplete checks if enough rows have been added to fill the limit of the
+result. Rows can only be skipped if all rows which were added so far are
+guaranteed to be part of the final result.
+*/
+func (sr *SearchResult) isComplete() bool {
+
+	if sr.limit < 0 || sr.distinct || len(sr.groupCol) > 0 ||
+		len(sr.withFlags.ordering) > 0 || len(sr.withFlags.notnullCol) > 0 ||
+		len(sr.withFlags.uniqueCol) > 0 {
+		return false
+	}
+
+	for _, cf := range sr.colFunc {
+		if _, ok := cf.(FuncShowAggregate); ok {
+			return false
+		}
+	}
+
+	offset := sr.offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	rowCount := len(sr.Data)
+	if sr.spill != nil {
+		rowCount += sr.spill.rowCount()
+	}
+
+	return rowCount >= offset+sr.limit
+}
+
+/*
+isAggregated checks if the rows of this result should be aggregated.
+*/
+func (sr *SearchResult) isAggregated() bool {
+
+	if len(sr.groupCol) > 0 {
+		return true
+	} else if len(sr.colFunc) > 0 {
+		_, ok := sr.colFunc[0].(FuncShowAggregate)
+		return ok
+	}
+
+	return false
+}
+
+/*
+eachRow calls a given function for each row which is held in memory.
+*/
+func (sr *SearchResult) eachRow(f func(row []interface{}, src []string) (bool, error)) error {
+
+	for i, row := range sr.Data {
+		if ok, err := f(row, sr.Source[i]); !ok || err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+aggregate collapses all rows into a single row for each group. Rows with the
+same values in all grouping columns form a group. Without grouping columns all
+rows form a single group. It is assumed that all other columns are aggregate
+functions. The rows are read through a given iteration function.
+*/
+func (sr *SearchResult) aggregate(forEach func(func([]interface{}, []string) (bool, error)) error) ([][]interface{}, [][]string, error) {
+	var groupKeys []string
+
+	// Only the first row and the values of each column are kept for each group
+
+	type group struct {
+		first  []interface{}
+		values [][]interface{}
+	}
+
+	groups := make(map[string]*group)
+
+	if len(sr.groupCol) == 0 {
+
+		// All rows (even no rows) form a single group
+
+		groupKeys = []string{""}
+		groups[""] = &group{nil, make([][]interface{}, len(sr.colFunc))}
+	}
+
+	// Bucket rows by their grouping values keeping the order in which
+	// the groups were encountered
+
+	err := forEach(func(r []interface{}, _ []string) (bool, error) {
+		key := ""
+
+		if len(sr.groupCol) > 0 {
+			groupVals := make([]interface{}, len(sr.groupCol))
+
+			for i, c := range sr.groupCol {
+				groupVals[i] = r[c]
+			}
+
+			key = fmt.Sprintf("%#v", groupVals)
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{nil, make([][]interface{}, len(sr.colFunc))}
+			groups[key] = g
+			groupKeys = append(groupKeys, key)
+		}
+
+		if g.first == nil {
+			g.first = r
+		}
+
+		for i, cf := range sr.colFunc {
+			if _, ok := cf.(FuncShowAggregate); ok {
+				g.values[i] = append(g.values[i], r[i])
+			}
+		}
+
+		return true, nil
+	})
+
+	data := make([][]interface{}, 0, len(groupKeys))
+	source := make([][]string, 0, len(groupKeys))
+
+	for _, key := range groupKeys {
+		g := groups[key]
+		row := make([]interface{}, len(sr.colFunc))
+
+		for i, cf := range sr.colFunc {
+
+			if af, ok := cf.(FuncShowAggregate); ok {
+
+				values := g.values[i]
+				if values == nil {
+					values = make([]interface{}, 0)
+				}
+
+				row[i] = af.aggregate(values)
+
+			} else {
+
+				// Grouping columns have the same value in all rows of a group
+
+				row[i] = g.first[i]
+			}
+		}
+
+		data = append(data, row)
+		source = append(source, make([]string, len(sr.colFunc)))
+	}
+
+	return data, source, err
+}
+
+/*
+removeDuplicates removes all rows which are equal to a previous row. The
+first occurrence of a row is kept together with its source.
+*/
+func (sr *SearchResult) removeDuplicates() {
+	seen := make(map[string]bool)
+
+	data := make([][]interface{}, 0, len(sr.Data))
+	source := make([][]string, 0, len(sr.Source))
+
+	for i, row := range sr.Data {
+		key := fmt.Sprintf("%#v", row)
+
+		if !seen[key] {
+			seen[key] = true
+			data = append(data, row)
+			source = append(source, sr.Source[i])
+		}
+	}
+
+	sr.Data = data
+	sr.Source = source
 }
 
 /*
@@ -291,6 +602,9 @@ func (sr *SearchResult) Query() string {
 RowCount returns the number of rows of the result.
 */
 func (sr *SearchResult) RowCount() int {
+	if sr.spill != nil {
+		return sr.spill.rowCount()
+	}
 	return len(sr.Data)
 }
 
@@ -298,13 +612,22 @@ func (sr *SearchResult) RowCount() int {
 Row returns a row of the result.
 */
 func (sr *SearchResult) Row(line int) []interface{} {
+	if sr.spill != nil {
+		row, _ := sr.spillRow(line)
+		return row
+	}
 	return sr.Data[line]
 }
 
 /*
-Rows returns all rows.
+Rows returns all rows. The rows of a result which was written to a spill file
+are all read into memory.
 */
 func (sr *SearchResult) Rows() [][]interface{} {
+	if sr.spill != nil {
+		data, _ := sr.spillRows()
+		return data
+	}
 	return sr.Data
 }
 
@@ -313,16 +636,58 @@ RowSource returns the sources of a result row.
 Format is either: <n/e>:<kind>:<key> or q:<query>
 */
 func (sr *SearchResult) RowSource(line int) []string {
+	if sr.spill != nil {
+		_, src := sr.spillRow(line)
+		return src
+	}
 	return sr.Source[line]
 }
 
 /*
-RowSources returns the sources of a result.
+RowSources returns the sources of a result. The sources of a result which was
+written to a spill file are all read into memory.
 */
 func (sr *SearchResult) RowSources() [][]string {
+	if sr.spill != nil {
+		_, source := sr.spillRows()
+		return source
+	}
 	return sr.Source
 }
 
+/*
+UniqueCounts returns the distinct values of a column which is filtered with
+unique or uniquecount and the number of occurrences of each value. The values
+are ordered by their number of occurrences (most frequent first) and then by
+value. Returns nil lists if the column is not filtered with unique or
+uniquecount.
+*/
+func (sr *SearchResult) UniqueCounts(col int) ([]interface{}, []int) {
+
+	for j, u := range sr.withFlags.uniqueCol {
+		if u == col && j < len(sr.uniqueCounters) {
+			return sr.uniqueCounters[j].result()
+		}
+	}
+
+	return nil, nil
+}
+
+/*
+Close removes the spill file of this result. The result has no rows
+afterwards if it was written to a spill file.
+*/
+func (sr *SearchResult) Close() error {
+	var err error
+
+	if sr.spill != nil {
+		err = sr.spill.close()
+		sr.spill = nil
+	}
+
+	return err
+}
+
 /*
 String returns a string representation of this search result.
 */
@@ -343,7 +708,9 @@ func (sr *SearchResult) String() string {
 
 	// Render the table
 
-	for _, row := range sr.Data {
+	for j := 0; j < sr.RowCount(); j++ {
+		row := sr.Row(j)
+
 		for i, col := range row {
 
 			if col != nil {
@@ -368,7 +735,7 @@ func (sr *SearchResult) CSV() string {
 	var buf bytes.Buffer
 
 	labels := sr.Header().ColLabels
-	strData := make([][]string, len(sr.Data)+1)
+	strData := make([][]string, sr.RowCount()+1)
 
 	// Prepare string data
 
@@ -376,7 +743,8 @@ func (sr *SearchResult) CSV() string {
 	for i, s := range labels {
 		strData[0][i] = s
 	}
-	for i, row := range sr.Data {
+	for i := 0; i < sr.RowCount(); i++ {
+		row := sr.Row(i)
 		strData[i+1] = make([]string, len(row))
 		for j, s := range row {
 			strData[i+1][j] = fmt.Sprint(s)
@@ -396,13 +764,48 @@ func (sr *SearchResult) CSV() string {
 // ==============
 
 /*
-SearchResultRowComparator is a comparator object used for sorting the result
+columnTypes maps the types which can be declared for a column to their
+conversion functions
+*/
+var columnTypes = map[string]func(interface{}) (interface{}, error){
+	"int": func(val interface{}) (interface{}, error) {
+		valStr := fmt.Sprint(val)
+
+		if i, err := strconv.Atoi(valStr); err == nil {
+			return i, nil
+		}
+
+		// Allow whole numbers which are written as floating point values
+
+		f, err := strconv.ParseFloat(valStr, 64)
+		if err != nil || f != math.Trunc(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("Not an int")
+		}
+
+		return int(f), nil
+	},
+	"float": func(val interface{}) (interface{}, error) {
+		return strconv.ParseFloat(fmt.Sprint(val), 64)
+	},
+	"bool": func(val interface{}) (interface{}, error) {
+		return strconv.ParseBool(fmt.Sprint(val))
+	},
+	"string": func(val interface{}) (interface{}, error) {
+		return fmt.Sprint(val), nil
+	},
+}
+
+/*
+SearchResultRowComparator is a comparator object used for sorting the result.
+Rows are compared column by column from left to right. Rows which have equal
+values in all columns are ordered by their source (e.g. node keys) so the
+resulting order is deterministic.
 */
 type SearchResultRowComparator struct {
-	Ascening bool            // Sort should be ascending
-	Column   int             // Column to sort
-	Data     [][]interface{} // Data to sort
-	Source   [][]string      // Source entries which follow the data
+	Ascending []bool          // Sort direction for each column
+	Columns   []int           // Columns to sort (first column has highest priority)
+	Data      [][]interface{} // Data to sort
+	Source    [][]string      // Source entries which follow the data
 }
 
 func (c SearchResultRowComparator) Len() int {
@@ -410,30 +813,130 @@ func (c SearchResultRowComparator) Len() int {
 }
 
 func (c SearchResultRowComparator) Less(i, j int) bool {
-	c1 := c.Data[i][c.Column]
-	c2 := c.Data[j][c.Column]
+	return c.lessRow(c.Data[i], c.Source[i], c.Data[j], c.Source[j])
+}
+
+/*
+lessRow checks if a given row should be ordered before another given row.
+*/
+func (c SearchResultRowComparator) lessRow(row1 []interface{}, src1 []string,
+	row2 []interface{}, src2 []string) bool {
+
+	for k, col := range c.Columns {
+		if res := compareResultValues(row1[col], row2[col]); res != 0 {
+			if c.Ascending[k] {
+				return res < 0
+			}
+			return res > 0
+		}
+	}
+
+	// Break ties by the source of the rows and finally by all row values
+
+	srcStr1, srcStr2 := strings.Join(src1, ","), strings.Join(src2, ",")
+	if srcStr1 != srcStr2 {
+		return srcStr1 < srcStr2
+	}
+
+	return fmt.Sprint(row1) < fmt.Sprint(row2)
+}
+
+func (c SearchResultRowComparator) Swap(i, j int) {
+	c.Data[i], c.Data[j] = c.Data[j], c.Data[i]
+	c.Source[i], c.Source[j] = c.Source[j], c.Source[i]
+}
+
+/*
+compareResultValues compares two result values. Values are compared as numbers
+if possible otherwise as strings. Returns a negative number if the first value
+is smaller, a positive number if it is greater and 0 if both are equal.
+*/
+func compareResultValues(c1 interface{}, c2 interface{}) int {
 
 	num1, err := strconv.ParseFloat(fmt.Sprint(c1), 64)
 	if err == nil {
 		num2, err := strconv.ParseFloat(fmt.Sprint(c2), 64)
 		if err == nil {
-			if c.Ascening {
-				return num1 < num2
+			if num1 < num2 {
+				return -1
+			} else if num1 > num2 {
+				return 1
 			}
-			return num1 > num2
+			return 0
 		}
 	}
 
-	if c.Ascening {
-		return fmt.Sprintf("%v", c1) < fmt.Sprintf("%v", c2)
+	return strings.Compare(fmt.Sprintf("%v", c1), fmt.Sprintf("%v", c2))
+}
+
+// Unique counter
+// ==============
+
+/*
+uniqueCounter counts the occurrences of distinct values in a column.
+*/
+type uniqueCounter struct {
+	values []interface{}  // Distinct values in the order they were counted
+	counts map[string]int // Number of occurrences of each value
+}
+
+/*
+newUniqueCounters creates a given number of unique counters.
+*/
+func newUniqueCounters(n int) []*uniqueCounter {
+	ucs := make([]*uniqueCounter, n)
+	for i := range ucs {
+		ucs[i] = &uniqueCounter{nil, make(map[string]int)}
 	}
+	return ucs
+}
+
+/*
+add counts a value. Returns true if the value was encountered for the first time.
+*/
+func (uc *uniqueCounter) add(val interface{}) bool {
+	key := fmt.Sprint(val)
+
+	cnt, ok := uc.counts[key]
+	if !ok {
+		uc.values = append(uc.values, val)
+	}
+
+	uc.counts[key] = cnt + 1
 
-	return fmt.Sprintf("%v", c1) > fmt.Sprintf("%v", c2)
+	return !ok
 }
 
-func (c SearchResultRowComparator) Swap(i, j int) {
-	c.Data[i], c.Data[j] = c.Data[j], c.Data[i]
-	c.Source[i], c.Source[j] = c.Source[j], c.Source[i]
+/*
+count returns the number of occurrences of a value.
+*/
+func (uc *uniqueCounter) count(val interface{}) int {
+	return uc.counts[fmt.Sprint(val)]
+}
+
+/*
+result returns all distinct values and their number of occurrences. The
+values are ordered by their number of occurrences (most frequent first) and
+then by value.
+*/
+func (uc *uniqueCounter) result() ([]interface{}, []int) {
+	values := make([]interface{}, len(uc.values))
+	copy(values, uc.values)
+
+	sort.SliceStable(values, func(i, j int) bool {
+		ci, cj := uc.count(values[i]), uc.count(values[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return compareResultValues(values[i], values[j]) < 0
+	})
+
+	counts := make([]int, len(values))
+	for i, val := range values {
+		counts[i] = uc.count(val)
+	}
+
+	return values, counts
 }
 
 // Testing functions
@@ -465,5 +968,9 @@ func (s rowSort) Less(i, j int) bool {
 StableSort sorts the rows of the result in a stable 100% reproducible way.
 */
 func (sr *SearchResult) StableSort() {
+	if sr.spill != nil {
+		sr.Data, sr.Source = sr.spillRows()
+		sr.Close()
+	}
 	sort.Stable(rowSort(*sr))
 }
@@ -13,6 +13,9 @@ package interpreter
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 
 	"devt.de/krotik/eliasdb/eql/parser"
@@ -345,7 +348,7 @@ Data: 1:n:key, 1:n:name, 2:n:key, 2:n:name, 2:n:ranking
 		return
 	}
 
-	if _, err := getResult("get Author traverse :Wrote::Song end show 1:n:name, 2:n:name, 2:e:number with ordering(descending Song:name, ascending Wrote:number)", `
+	if _, err := getResult("get Author traverse :Wrote::Song end show 1:n:name, 2:n:name, 2:e:number with ordering(ascending Wrote:number, descending Song:name)", `
 Labels: Name, Name, Number
 Format: auto, auto, auto
 Data: 1:n:name, 2:n:name, 2:e:number
@@ -363,6 +366,47 @@ John, Aria4, 4
 		return
 	}
 
+	// Show and order attributes of the start node, the traversed node and the
+	// connecting edge in one row
+
+	if _, err := getResult("get Author traverse :Wrote::Song end show Author:name, Song:name, Wrote:number with ordering(ascending Author:name, descending Wrote:number)", `
+Labels: Author Name, Song Name, Number
+Format: auto, auto, auto
+Data: 1:n:name, 2:n:name, 2:e:number
+Hans, MyOnlySong3, 3
+John, Aria4, 4
+John, Aria3, 3
+John, Aria2, 2
+John, Aria1, 1
+Mike, FightSong4, 4
+Mike, LoveSong3, 3
+Mike, DeadSong2, 2
+Mike, StrangeSong1, 1
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// An attribute without kind is resolved to the first column which shows it
+
+	if _, err := getResult("get Author traverse :Wrote::Song end show Author:name, Song:name with ordering(ascending name, ascending Song:name)", `
+Labels: Author Name, Song Name
+Format: auto, auto
+Data: 1:n:name, 2:n:name
+Hans, MyOnlySong3
+John, Aria1
+John, Aria2
+John, Aria3
+John, Aria4
+Mike, DeadSong2
+Mike, FightSong4
+Mike, LoveSong3
+Mike, StrangeSong1
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
 	// Test empty traversal flag
 
 	if _, err := getResult("get Author traverse :::Song where name = '123' end with nulltraversal(true)", `
@@ -423,6 +467,106 @@ John (4)
 	}
 }
 
+func TestUniqueCounts(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Every song has a different ranking
+
+	res, err := getResult("get Song show name, ranking with filtering(unique ranking), ordering(ascending ranking)", `
+Labels: Song Name, Ranking
+Format: auto, auto
+Data: 1:n:name, 1:n:ranking
+LoveSong3, 1
+Aria2, 2
+FightSong4, 3
+Aria3, 4
+StrangeSong1, 5
+DeadSong2, 6
+Aria1, 8
+Aria4, 18
+MyOnlySong3, 19
+`[1:], rt, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if vals, counts := res.UniqueCounts(1); fmt.Sprint(vals, counts) != "[1 2 3 4 5 6 8 18 19] [1 1 1 1 1 1 1 1 1]" {
+		t.Error("Unexpected result:", vals, counts)
+		return
+	}
+
+	// Columns without unique filter have no counts
+
+	if vals, counts := res.UniqueCounts(0); vals != nil || counts != nil {
+		t.Error("Unexpected result:", vals, counts)
+		return
+	}
+
+	// Authors are counted once for each of their songs
+
+	res, err = getResult("get Author traverse :::Song end show Author:name with filtering(uniquecount Author:name), ordering(ascending Author:name)", `
+Labels: Author Name
+Format: auto
+Data: 1:n:name
+Hans (1)
+John (4)
+Mike (4)
+`[1:], rt, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if vals, counts := res.UniqueCounts(0); fmt.Sprint(vals, counts) != "[John Mike Hans] [4 4 1]" {
+		t.Error("Unexpected result:", vals, counts)
+		return
+	}
+
+	// Unique counts are only taken from rows which pass the not null filter
+
+	ast, _ := parser.ParseWithRuntime("test", "get Author traverse :::Song where ranking > 4 end "+
+		"show Author:name, 2:n:ranking with nulltraversal(true), filtering(isnotnull 2:n:ranking, unique Author:name)", rt)
+
+	sres, err := ast.Runtime.Eval()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if vals, counts := sres.(*SearchResult).UniqueCounts(0); fmt.Sprint(vals, counts) != "[John Mike Hans] [2 2 1]" {
+		t.Error("Unexpected result:", vals, counts)
+		return
+	}
+
+	// Test label casing
+
+	if _, err := getResult("get Author show key, name with format(camel), ordering(ascending name)", `
+Labels: authorKey, authorName
+Format: auto, auto
+Data: 1:n:key, 1:n:name
+456, Hans
+000, John
+123, Mike
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show key as AuthorKey, name with format(snake), ordering(ascending name)", `
+Labels: author_key, author_name
+Format: auto, auto
+Data: 1:n:key, 1:n:name
+456, Hans
+000, John
+123, Mike
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
 func TestWithFlagsErrors(t *testing.T) {
 	gm, _ := songGraph()
 	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
@@ -456,11 +600,576 @@ func TestWithFlagsErrors(t *testing.T) {
 		t.Error(err)
 		return
 	}
+
+	if _, err := getResult("get Author with format(upper)", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Unknown casing: upper (must be camel or snake)) (Line:1 Pos:24)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	res, err := getResult("get Author show key, name with type(1:n:key, int), ordering(ascending name)", `
+Labels: Author Key, Author Name
+Format: auto, auto
+Data: 1:n:key, 1:n:name
+456, Hans
+0, John
+123, Mike
+`[1:], rt, false)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r := fmt.Sprintf("%#v", res.Rows()); r != `[][]interface {}{[]interface {}{456, "Hans"}, []interface {}{0, "John"}, []interface {}{123, "Mike"}}` {
+		t.Error("Unexpected result:", r)
+		return
+	}
+
+	res, err = getResult("get Song where name = Aria1 show name, ranking with type(ranking, float), type(name, string)", `
+Labels: Song Name, Ranking
+Format: auto, auto
+Data: 1:n:name, 1:n:ranking
+Aria1, 8
+`[1:], rt, false)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r, ok := res.Row(0)[1].(float64); !ok || r != 8 {
+		t.Errorf("Unexpected result: %#v", res.Row(0))
+		return
+	}
+
+	res, err = getResult("get Song where name = LoveSong3 show name, ranking with type(ranking, bool)", `
+Labels: Song Name, Ranking
+Format: auto, auto
+Data: 1:n:name, 1:n:ranking
+LoveSong3, true
+`[1:], rt, false)
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if r := fmt.Sprintf("%#v", res.Rows()); r != `[][]interface {}{[]interface {}{"LoveSong3", true}}` {
+		t.Error("Unexpected result:", r)
+		return
+	}
+
+	// Conversion errors name the row and the column
+
+	if _, err := getResult("get Author show name with type(name, int), ordering(ascending name)", "", rt, false); err == nil || err.Error() !=
+		`EQL result error in test: Invalid column value (Cannot convert "Hans" in row 1 column 1 (1:n:name) to int)` {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show name with type(name, integer)", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Unknown column type: integer (must be int, float, bool or string)) (Line:1 Pos:38)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show name with type(name)", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Type directive requires 2 parameters: column, type) (Line:1 Pos:27)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Author show name with type(p:bla, int)", "", rt, false); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Cannot determine column for with term: p:bla) (Line:1 Pos:27)" {
+		t.Error(err)
+		return
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Without distinct each author appears once for every song
+
+	if _, err := getResult("get Author traverse :Wrote::Song end show Author:name", `
+Labels: Author Name
+Format: auto
+Data: 1:n:name
+Hans
+John
+John
+John
+John
+Mike
+Mike
+Mike
+Mike
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Duplicate rows are removed before the result is ordered
+
+	if res, err := getResult("get distinct Author traverse :Wrote::Song end primary Song show Author:name with ordering(descending Author:name)", `
+Labels: Author Name
+Format: auto
+Data: 1:n:name
+Mike
+John
+Hans
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	} else if res.PrimaryKind() != "Song" || res.RowCount() != 3 {
+		t.Error("Unexpected result:", res.PrimaryKind(), res.RowCount())
+		return
+	}
+
+	// The first occurrence of a row provides the source
+
+	if res, err := getResult("get distinct Author traverse :Wrote::Song end show Author:name, Song:name", `
+Labels: Author Name, Song Name
+Format: auto, auto
+Data: 1:n:name, 2:n:name
+Hans, MyOnlySong3
+John, Aria1
+John, Aria2
+John, Aria3
+John, Aria4
+Mike, DeadSong2
+Mike, FightSong4
+Mike, LoveSong3
+Mike, StrangeSong1
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	} else if len(res.Source) != len(res.Data) {
+		t.Error("Unexpected sources:", res.Source)
+		return
+	}
+
+	// Rows with different values are all kept
+
+	if _, err := getResult("get distinct Song show name", `
+Labels: Song Name
+Format: auto
+Data: 1:n:name
+Aria1
+Aria2
+Aria3
+Aria4
+DeadSong2
+FightSong4
+LoveSong3
+MyOnlySong3
+StrangeSong1
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestLimitOffset(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Offset and limit are applied after ordering
+
+	if _, err := getResult("get Song show name with ordering(ascending name) limit 3 offset 2", `
+Labels: Song Name
+Format: auto
+Data: 1:n:name
+Aria3
+Aria4
+DeadSong2
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get distinct Author traverse :Wrote::Song end show Author:name with ordering(descending Author:name) offset 1", `
+Labels: Author Name
+Format: auto
+Data: 1:n:name
+John
+Hans
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := getResult("get Song show name offset 9", `
+Labels: Song Name
+Format: auto
+Data: 1:n:name
+`[1:], rt, false); err != nil || len(res.Source) != 0 {
+		t.Error(err)
+		return
+	}
+
+	if res, err := getResult("get Song show name limit 0", `
+Labels: Song Name
+Format: auto
+Data: 1:n:name
+`[1:], rt, false); err != nil || len(res.Source) != 0 {
+		t.Error(err)
+		return
+	}
+
+	// Without operations which need all rows the query stops early
+
+	ast, err := parser.ParseWithRuntime("test", "get Song show name limit 2 offset 1", rt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := ast.Runtime.Eval(); err != nil || res.(*SearchResult).RowCount() != 2 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if more, err := rt.next(); !more || err != nil {
+		t.Error("Query should have stopped early:", more, err)
+		return
+	}
+
+	if _, err := getResult("get Song show name with ordering(ascending name) limit 2", `
+Labels: Song Name
+Format: auto
+Data: 1:n:name
+Aria1
+Aria2
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if more, err := rt.next(); more || err != nil {
+		t.Error("Query should have read all rows:", more, err)
+		return
+	}
+}
+
+func TestOrderingTies(t *testing.T) {
+	gm, _ := filterGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Rows with equal values are ordered by their node keys
+
+	if _, err := getResult("get filtertest show val2, key with ordering(descending val2)", `
+Labels: Val2, Filtertest Key
+Format: auto, auto
+Data: 1:n:val2, 1:n:key
+X5, 19
+X4, 18
+X3, 17
+X2, 16
+X1, 15
+Steve, 10
+Steve, 9
+Peter, 4
+Peter, 5
+Peter, 6
+Kevin, 12
+Kevin, 13
+Kevin, 14
+Hans, 1
+Hans, 2
+Hans, 3
+Franz, 11
+Anna, 7
+Anna, 8
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Pages over an ordering with equal values neither skip nor repeat rows
+
+	if _, err := getResult("get filtertest show val2, key with ordering(descending val2) limit 3 offset 5", `
+Labels: Val2, Filtertest Key
+Format: auto, auto
+Data: 1:n:val2, 1:n:key
+Steve, 10
+Steve, 9
+Peter, 4
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get filtertest show val2, key with ordering(descending val2) limit 3 offset 8", `
+Labels: Val2, Filtertest Key
+Format: auto, auto
+Data: 1:n:val2, 1:n:key
+Peter, 5
+Peter, 6
+Kevin, 12
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Several ordering expressions are applied from left to right
+
+	if _, err := getResult("get filtertest where val2 < Kevin show val2, key with ordering(ascending val2, descending key)", `
+Labels: Val2, Filtertest Key
+Format: auto, auto
+Data: 1:n:val2, 1:n:key
+Anna, 8
+Anna, 7
+Franz, 11
+Hans, 3
+Hans, 2
+Hans, 1
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if _, err := getResult("get Author traverse :Wrote::Song end group by Author:name show Author:name, @count(2:n:key), @sum(2:n:ranking), @max(2:e:number)", `
+Labels: Author Name, Count Key, Sum Ranking, Max Number
+Format: auto, auto, auto, auto
+Data: 1:n:name, 2:func:count(), 2:func:sum(), 2:func:max()
+Hans, 1, 19, 3
+John, 4, 32, 4
+Mike, 4, 15, 4
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Groups are ordered after grouping
+
+	if _, err := getResult("get Author traverse :Wrote::Song end group by Wrote:number show Wrote:number, @count(2:n:key) with ordering(descending Wrote:number)", `
+Labels: Number, Count Key
+Format: auto, auto
+Data: 2:e:number, 2:func:count()
+4, 2
+3, 3
+2, 2
+1, 2
+`[1:], rt, false); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Multiple grouping attributes
+
+	if _, err := getResult("get Author traverse :Wrote::Song end group by Author:name, 2:e:number show Author:name, Wrote:number, @count(2:n:key), @min(2:n:name)", `
+Labels: Author Name, Number, Count Key, Min Name
+Format: auto, auto, auto, auto
+Data: 1:n:name, 2:e:number, 2:func:count(), 2:func:min()
+Hans, 3, 1, MyOnlySong3
+John, 1, 1, Aria1
+John, 2, 1, Aria2
+John, 3, 1, Aria3
+John, 4, 1, Aria4
+Mike, 1, 1, StrangeSong1
+Mike, 2, 1, DeadSong2
+Mike, 3, 1, LoveSong3
+Mike, 4, 1, FightSong4
+`[1:], rt, true); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// An empty result has no groups
+
+	if res, err := getResult("get Song where ranking > 100 group by name show name, @count(1:n:key)", `
+Labels: Song Name, Count Key
+Format: auto, auto
+Data: 1:n:name, 1:func:count()
+`[1:], rt, true); err != nil || res.RowCount() != 0 {
+		t.Error(err)
+		return
+	}
+
+	// Test error cases
+
+	if _, err := getResult("get Song group by ranking show name, @count(1:n:key)", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Group by attribute ranking must be shown in a column) (Line:1 Pos:19)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song group by name show name, ranking", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Column 1:n:ranking is neither grouped nor aggregated) (Line:1 Pos:10)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song group by Foo:name show name", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Cannot determine data position for kind: Foo) (Line:1 Pos:19)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song show name group by name", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (group by clause must be before show clause) (Line:1 Pos:20)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := getResult("get Song group by name traverse :Wrote::Author end show name", "", rt, true); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (traversals must be before group by clause) (Line:1 Pos:24)" {
+		t.Error(err)
+		return
+	}
 }
 
 /*
 Helper function to run a search and check against a result.
 */
+func TestResultSpill(t *testing.T) {
+	gm, _ := songGraph()
+
+	dir, err := ioutil.TempDir("", "spilltest")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	oldSpillPageRows := spillPageRows
+	spillPageRows = 2
+	defer func() {
+		spillPageRows = oldSpillPageRows
+	}()
+
+	spillDir := dir
+
+	run := func(query string, spillRows int) (*SearchResult, error) {
+		rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+		rt.SetResultSpill(spillRows, spillDir)
+
+		ast, err := parser.ParseWithRuntime("test", query, rt)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := ast.Runtime.Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		return res.(*SearchResult), nil
+	}
+
+	// Spilled results must be the same as results which are held in memory
+
+	for _, query := range []string{
+		"get Song with ordering(ascending key)",
+		"get Song show name, ranking with ordering(descending ranking)",
+		"get Song show name, ranking with ordering(ascending ranking, descending name)",
+		"get Song show name with ordering(ascending name) limit 3 offset 2",
+		"get Song show name with ordering(descending name) limit 2 offset 1",
+		"get Song show name with ordering(ascending name) offset 9",
+		"get Author traverse :::Song end show Author:name with filtering(unique Author:name), ordering(ascending Author:name)",
+		"get Author traverse :::Song end show Author:name with filtering(uniquecount Author:name), ordering(ascending Author:name)",
+		"get Author traverse :::Song where name = 'DeadSong2' end with nulltraversal(true), filtering(isnotnull Song:name)",
+		"get Song show ranking, name with filtering(unique ranking), ordering(ascending name)",
+		"get distinct Author traverse :Wrote::Song end show Author:name with ordering(descending Author:name) offset 1",
+		"get Author traverse :Wrote::Song end group by Author:name show Author:name, @count(2:n:key), @sum(2:n:ranking), @max(2:e:number) with ordering(ascending Author:name)",
+		"get Song show name, ranking with type(ranking, float), ordering(ascending ranking)",
+	} {
+		expected, err := run(query, 0)
+		if err != nil {
+			t.Error(query, err)
+			return
+		}
+
+		res, err := run(query, 3)
+		if err != nil {
+			t.Error(query, err)
+			return
+		}
+
+		uniqueCounts := func(sr *SearchResult) string {
+			vals, counts := sr.UniqueCounts(0)
+			return fmt.Sprint(vals, counts)
+		}
+
+		if res.String() != expected.String() || res.CSV() != expected.CSV() ||
+			!reflect.DeepEqual(res.Rows(), expected.Rows()) ||
+			!reflect.DeepEqual(res.RowSources(), expected.RowSources()) ||
+			uniqueCounts(res) != uniqueCounts(expected) {
+			t.Error("Unexpected result for:", query, "\n", res, "\nexpected:\n", expected)
+			return
+		}
+
+		res.Close()
+	}
+
+	// Large results stay in the spill file
+
+	res, err := run("get Song show name, ranking with ordering(ascending name)", 3)
+	if err != nil || res.spill == nil || len(res.Data) != 0 || res.RowCount() != 9 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if row, src := res.Row(8), res.RowSource(8); fmt.Sprint(row, src) !=
+		"[StrangeSong1 5] [n:Song:StrangeSong1 n:Song:StrangeSong1]" || res.LastError != nil {
+		t.Error("Unexpected row:", row, src, res.LastError)
+		return
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Error("Unexpected spill files:", files)
+		return
+	}
+
+	if err := res.Close(); err != nil || res.RowCount() != 0 {
+		t.Error("Unexpected result:", res.RowCount(), err)
+		return
+	}
+
+	// Small results are moved back into memory
+
+	res, err = run("get Song show name with ordering(ascending name) limit 2", 3)
+	if err != nil || res.spill != nil || len(res.Data) != 2 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Error("Unexpected spill files:", files)
+		return
+	}
+
+	// Errors during type conversion are reported like for results in memory
+
+	if _, err := run("get Song show name, key with type(name, int), ordering(ascending name)", 3); err == nil || err.Error() !=
+		"EQL result error in test: Invalid column value (Cannot convert \"Aria1\" in row 1 column 1 (1:n:name) to int)" {
+		t.Error(err)
+		return
+	}
+
+	// Spill files cannot be created in a directory which does not exist
+
+	spillDir = dir + "/missing"
+
+	if _, err := run("get Song", 3); err == nil || err.(*ResultError).Type != ErrSpillFailed {
+		t.Error(err)
+		return
+	}
+}
+
 func getResult(query string, expectedResult string, rt parser.RuntimeProvider, sort bool) (*SearchResult, error) {
 	ast, err := parser.ParseWithRuntime("test", query, rt)
 	if err != nil {
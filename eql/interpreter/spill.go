@@ -0,0 +1,681 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package interpreter
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"devt.de/krotik/eliasdb/storage"
+)
+
+func init() {
+
+	// List values in result rows need to be known to the spill file
+
+	gob.Register(make([]interface{}, 0))
+}
+
+/*
+spillPageRows is the number of rows which are stored in a single page of a
+spill file.
+*/
+var spillPageRows = 100
+
+// Spill file
+// ==========
+
+/*
+spillPage is a page of rows in a spill file.
+*/
+type spillPage struct {
+	Data   [][]interface{} // Row data
+	Source [][]string      // Row sources
+}
+
+/*
+spillRun is a sequence of pages in a spill file. All pages of a run are full
+except the last one.
+*/
+type spillRun struct {
+	pages []uint64 // Storage locations of the pages
+	rows  int      // Number of rows in this run
+}
+
+/*
+resultSpill stores the rows of a search result in a temporary paged file. The
+rows are stored in one or more runs.
+*/
+type resultSpill struct {
+	dir  string                      // Temporary directory of the spill file
+	sm   *storage.DiskStorageManager // Storage manager of the spill file
+	runs []*spillRun                 // Runs which hold the rows
+
+	pageLock *sync.Mutex // Lock for the page cache
+	pageLoc  uint64      // Location of the cached page
+	page     *spillPage  // Last page which was read
+}
+
+/*
+newResultSpill creates a new spill file in a given directory. An empty
+directory uses the default directory for temporary files. The spill file is
+removed once it is closed or no longer referenced.
+*/
+func newResultSpill(dir string) (*resultSpill, error) {
+
+	tempDir, err := ioutil.TempDir(dir, "eqlspill")
+	if err != nil {
+		return nil, err
+	}
+
+	sm := storage.NewDiskStorageManager(filepath.Join(tempDir, "rows"), false,
+		false, true, true)
+
+	s := &resultSpill{tempDir, sm, nil, &sync.Mutex{}, 0, nil}
+
+	runtime.SetFinalizer(s, func(s *resultSpill) {
+		s.close()
+	})
+
+	return s, nil
+}
+
+/*
+rowCount returns the number of rows in the spill file.
+*/
+func (s *resultSpill) rowCount() int {
+	var count int
+
+	for _, run := range s.runs {
+		count += run.rows
+	}
+
+	return count
+}
+
+/*
+writeRun writes given rows as a new run.
+*/
+func (s *resultSpill) writeRun(data [][]interface{}, source [][]string) error {
+	w := s.newWriter()
+
+	for i, row := range data {
+		if err := w.add(row, source[i]); err != nil {
+			return err
+		}
+	}
+
+	run, err := w.finish()
+	if err == nil {
+		s.runs = append(s.runs, run)
+	}
+
+	return err
+}
+
+/*
+replaceRuns replaces all runs of this spill file and frees the pages of the
+old runs.
+*/
+func (s *resultSpill) replaceRuns(runs []*spillRun) error {
+
+	for _, run := range s.runs {
+		for _, loc := range run.pages {
+			if err := s.sm.Free(loc); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.runs = runs
+
+	s.pageLock.Lock()
+	s.page = nil
+	s.pageLock.Unlock()
+
+	return nil
+}
+
+/*
+fetchPage reads a page from the spill file.
+*/
+func (s *resultSpill) fetchPage(loc uint64) (*spillPage, error) {
+	page := &spillPage{}
+	err := s.sm.Fetch(loc, page)
+	return page, err
+}
+
+/*
+row returns a single row of the spill file.
+*/
+func (s *resultSpill) row(line int) ([]interface{}, []string, error) {
+
+	for _, run := range s.runs {
+
+		if line >= run.rows {
+			line -= run.rows
+			continue
+		}
+
+		loc := run.pages[line/spillPageRows]
+
+		s.pageLock.Lock()
+		defer s.pageLock.Unlock()
+
+		if s.page == nil || s.pageLoc != loc {
+			page, err := s.fetchPage(loc)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			s.page, s.pageLoc = page, loc
+		}
+
+		i := line % spillPageRows
+
+		return s.page.Data[i], s.page.Source[i], nil
+	}
+
+	return nil, nil, fmt.Errorf("Row %v does not exist", line)
+}
+
+/*
+forEach calls a given function for each row of the spill file. The rows are
+visited in reverse order if the reverse flag is set. The iteration stops if
+the function returns false.
+*/
+func (s *resultSpill) forEach(reverse bool, f func(row []interface{}, src []string) (bool, error)) error {
+
+	for i := range s.runs {
+		run := s.runs[i]
+
+		if reverse {
+			run = s.runs[len(s.runs)-1-i]
+		}
+
+		for j := range run.pages {
+			loc := run.pages[j]
+
+			if reverse {
+				loc = run.pages[len(run.pages)-1-j]
+			}
+
+			page, err := s.fetchPage(loc)
+			if err != nil {
+				return err
+			}
+
+			for k := range page.Data {
+				l := k
+
+				if reverse {
+					l = len(page.Data) - 1 - k
+				}
+
+				if ok, err := f(page.Data[l], page.Source[l]); !ok || err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+sort orders all rows of the spill file. Runs of at most maxRows rows are sorted
+in memory and then merged into a single run.
+*/
+func (s *resultSpill) sort(maxRows int, comp *SearchResultRowComparator) error {
+	var runs []*spillRun
+
+	// Sort chunks of rows in memory
+
+	chunk := &SearchResultRowComparator{comp.Ascending, comp.Columns, nil, nil}
+
+	writeChunk := func() error {
+		sort.Sort(chunk)
+
+		w := s.newWriter()
+
+		for i, row := range chunk.Data {
+			if err := w.add(row, chunk.Source[i]); err != nil {
+				return err
+			}
+		}
+
+		run, err := w.finish()
+		runs = append(runs, run)
+
+		chunk.Data, chunk.Source = nil, nil
+
+		return err
+	}
+
+	err := s.forEach(false, func(row []interface{}, src []string) (bool, error) {
+		chunk.Data = append(chunk.Data, row)
+		chunk.Source = append(chunk.Source, src)
+
+		if len(chunk.Data) >= maxRows {
+			return true, writeChunk()
+		}
+
+		return true, nil
+	})
+
+	if err == nil && len(chunk.Data) > 0 {
+		err = writeChunk()
+	}
+
+	if err == nil {
+		err = s.replaceRuns(runs)
+	}
+
+	if err != nil || len(s.runs) < 2 {
+		return err
+	}
+
+	// Merge the sorted runs reading only a single page of each run at a time
+
+	cursors := make([]*spillCursor, 0, len(s.runs))
+
+	for _, run := range s.runs {
+		c := &spillCursor{s, run, nil, 0, 0}
+
+		if err = c.next(); err != nil {
+			return err
+		} else if c.page != nil {
+			cursors = append(cursors, c)
+		}
+	}
+
+	w := s.newWriter()
+
+	for len(cursors) > 0 {
+		sel := 0
+
+		for i, c := range cursors[1:] {
+			if comp.lessRow(c.row(), c.src(), cursors[sel].row(), cursors[sel].src()) {
+				sel = i + 1
+			}
+		}
+
+		c := cursors[sel]
+
+		if err = w.add(c.row(), c.src()); err == nil {
+			err = c.next()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if c.page == nil {
+			cursors = append(cursors[:sel], cursors[sel+1:]...)
+		}
+	}
+
+	run, err := w.finish()
+	if err == nil {
+		err = s.replaceRuns([]*spillRun{run})
+	}
+
+	return err
+}
+
+/*
+close closes and removes the spill file.
+*/
+func (s *resultSpill) close() error {
+	runtime.SetFinalizer(s, nil)
+
+	err := s.sm.Close()
+
+	if rerr := os.RemoveAll(s.dir); err == nil {
+		err = rerr
+	}
+
+	return err
+}
+
+/*
+spillWriter writes rows as a new run into a spill file.
+*/
+type spillWriter struct {
+	s    *resultSpill // Spill file to write to
+	run  *spillRun    // Run which is written
+	page *spillPage   // Page which is currently filled
+}
+
+/*
+newWriter creates a new writer for a run.
+*/
+func (s *resultSpill) newWriter() *spillWriter {
+	return &spillWriter{s, &spillRun{}, &spillPage{}}
+}
+
+/*
+add adds a row to the run.
+*/
+func (w *spillWriter) add(row []interface{}, src []string) error {
+
+	w.page.Data = append(w.page.Data, row)
+	w.page.Source = append(w.page.Source, src)
+	w.run.rows++
+
+	if len(w.page.Data) >= spillPageRows {
+		return w.writePage()
+	}
+
+	return nil
+}
+
+/*
+writePage writes the current page to the spill file.
+*/
+func (w *spillWriter) writePage() error {
+
+	loc, err := w.s.sm.Insert(w.page)
+	if err == nil {
+		w.run.pages = append(w.run.pages, loc)
+		w.page = &spillPage{}
+	}
+
+	return err
+}
+
+/*
+finish writes all remaining rows and returns the written run.
+*/
+func (w *spillWriter) finish() (*spillRun, error) {
+	var err error
+
+	if len(w.page.Data) > 0 {
+		err = w.writePage()
+	}
+
+	return w.run, err
+}
+
+/*
+spillCursor reads the rows of a run one page at a time.
+*/
+type spillCursor struct {
+	s       *resultSpill // Spill file to read from
+	run     *spillRun    // Run which is read
+	page    *spillPage   // Current page (nil if there are no more rows)
+	pagePos int          // Position of the next page in the run
+	rowPos  int          // Position of the current row in the page
+}
+
+/*
+next advances the cursor to the next row.
+*/
+func (c *spillCursor) next() error {
+	var err error
+
+	c.rowPos++
+
+	if c.page == nil || c.rowPos >= len(c.page.Data) {
+		c.page, c.rowPos = nil, 0
+
+		if c.pagePos < len(c.run.pages) {
+			c.page, err = c.s.fetchPage(c.run.pages[c.pagePos])
+			c.pagePos++
+		}
+	}
+
+	return err
+}
+
+/*
+row returns the data of the current row.
+*/
+func (c *spillCursor) row() []interface{} {
+	return c.page.Data[c.rowPos]
+}
+
+/*
+src returns the sources of the current row.
+*/
+func (c *spillCursor) src() []string {
+	return c.page.Source[c.rowPos]
+}
+
+// Search result functions for spilled rows
+// ========================================
+
+/*
+finishSpilled is called once all rows have been added to a result which was
+written to a spill file. It applies the same steps as finish but only keeps a
+limited number of rows in memory.
+*/
+func (sr *SearchResult) finishSpilled() error {
+
+	err := sr.spillData()
+
+	// Apply filtering
+
+	if err == nil && (len(sr.withFlags.notnullCol) > 0 || len(sr.withFlags.uniqueCol) > 0) {
+		err = sr.filterSpilled()
+	}
+
+	// Apply aggregation - the aggregated rows are held in memory
+
+	if err == nil && sr.isAggregated() {
+
+		sr.Data, sr.Source, err = sr.aggregate(func(f func([]interface{}, []string) (bool, error)) error {
+			return sr.spill.forEach(false, f)
+		})
+
+		if cerr := sr.Close(); err == nil {
+			err = cerr
+		}
+
+		if err != nil {
+			return &ResultError{sr.name, ErrSpillFailed, err.Error()}
+		}
+
+		return sr.finishRows()
+	}
+
+	// Remove duplicate rows
+
+	if err == nil && sr.distinct {
+		seen := make(map[[sha256.Size]byte]bool)
+
+		err = sr.rewriteSpilled(func(w *spillWriter, row []interface{}, src []string) (bool, error) {
+			key := sha256.Sum256([]byte(fmt.Sprintf("%#v", row)))
+
+			if !seen[key] {
+				seen[key] = true
+				return true, w.add(row, src)
+			}
+
+			return true, nil
+		})
+	}
+
+	// Apply ordering
+
+	if err == nil && len(sr.withFlags.ordering) > 0 {
+		maxRows := sr.spillMaxRows
+		if maxRows < 1 {
+			maxRows = spillPageRows
+		}
+		err = sr.spill.sort(maxRows, sr.rowComparator(nil, nil))
+	}
+
+	if err != nil {
+		return &ResultError{sr.name, ErrSpillFailed, err.Error()}
+	}
+
+	// Apply offset and limit and convert columns to their declared types
+
+	var line, pos int
+
+	err = sr.rewriteSpilled(func(w *spillWriter, row []interface{}, src []string) (bool, error) {
+
+		if pos++; pos <= sr.offset {
+			return true, nil
+		} else if sr.limit >= 0 && line >= sr.limit {
+			return false, nil
+		}
+
+		if err := sr.convertRow(row, line); err != nil {
+			return false, err
+		}
+
+		line++
+
+		return true, w.add(row, src)
+	})
+
+	if _, ok := err.(*ResultError); !ok && err != nil {
+		return &ResultError{sr.name, ErrSpillFailed, err.Error()}
+	}
+
+	// Move the result back into memory if it is small enough
+
+	if err == nil && sr.RowCount() <= sr.spillMaxRows {
+		if sr.Data, sr.Source = sr.spillRows(); sr.LastError == nil {
+			err = sr.Close()
+		} else {
+			err = &ResultError{sr.name, ErrSpillFailed, sr.LastError.Error()}
+		}
+	}
+
+	return err
+}
+
+/*
+filterSpilled applies not null and unique filters to the rows of a spill file.
+Like in finish the rows are visited from the last to the first row to decide
+which rows should be removed.
+*/
+func (sr *SearchResult) filterSpilled() error {
+
+	sr.uniqueCounters = newUniqueCounters(len(sr.withFlags.uniqueCol))
+
+	removed := make([]bool, sr.spill.rowCount())
+	line := len(removed)
+
+	err := sr.spill.forEach(true, func(row []interface{}, _ []string) (bool, error) {
+		line--
+
+		// Apply not null
+
+		for _, nn := range sr.withFlags.notnullCol {
+			if row[nn] == nil {
+				removed[line] = true
+				return true, nil
+			}
+		}
+
+		// Apply unique
+
+		for j, u := range sr.withFlags.uniqueCol {
+			if !sr.uniqueCounters[j].add(row[u]) {
+				removed[line] = true
+				break
+			}
+		}
+
+		return true, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// Write all remaining rows and add unique counts if necessary
+
+	return sr.rewriteSpilled(func(w *spillWriter, row []interface{}, src []string) (bool, error) {
+
+		if removed[line] {
+			line++
+			return true, nil
+		}
+
+		line++
+
+		for j, uc := range sr.withFlags.uniqueColCnt {
+			u := sr.withFlags.uniqueCol[j]
+			if uc {
+				row[u] = fmt.Sprintf("%v (%d)", row[u], sr.uniqueCounters[j].count(row[u]))
+			}
+		}
+
+		return true, w.add(row, src)
+	})
+}
+
+/*
+rewriteSpilled replaces all rows of the spill file with the rows which are
+written by a given function. The function is called for each existing row.
+*/
+func (sr *SearchResult) rewriteSpilled(f func(w *spillWriter, row []interface{}, src []string) (bool, error)) error {
+	w := sr.spill.newWriter()
+
+	err := sr.spill.forEach(false, func(row []interface{}, src []string) (bool, error) {
+		return f(w, row, src)
+	})
+
+	if err == nil {
+		var run *spillRun
+
+		if run, err = w.finish(); err == nil {
+			err = sr.spill.replaceRuns([]*spillRun{run})
+		}
+	}
+
+	return err
+}
+
+/*
+spillRow reads a single row from the spill file.
+*/
+func (sr *SearchResult) spillRow(line int) ([]interface{}, []string) {
+
+	row, src, err := sr.spill.row(line)
+	if err != nil {
+		sr.LastError = err
+	}
+
+	return row, src
+}
+
+/*
+spillRows reads all rows from the spill file.
+*/
+func (sr *SearchResult) spillRows() ([][]interface{}, [][]string) {
+	data := make([][]interface{}, 0, sr.spill.rowCount())
+	source := make([][]string, 0, sr.spill.rowCount())
+
+	err := sr.spill.forEach(false, func(row []interface{}, src []string) (bool, error) {
+		data = append(data, row)
+		source = append(source, src)
+		return true, nil
+	})
+
+	if err != nil {
+		sr.LastError = err
+	}
+
+	return data, source
+}
@@ -24,7 +24,8 @@ type traversalRuntime struct {
 	rtp  *eqlRuntimeProvider
 	node *parser.ASTNode
 
-	where *parser.ASTNode // Traversal where clause
+	where         *parser.ASTNode // Traversal where clause
+	nullTraversal *parser.ASTNode // Traversal nulltraversal directive
 
 	sourceNode data.Node   // Source node for traversal - should be injected by the parent
 	spec       string      // Spec for this traversal
@@ -38,7 +39,7 @@ type traversalRuntime struct {
 traversalRuntimeInst returns a new runtime component instance.
 */
 func traversalRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
-	return &traversalRuntime{rtp, node, nil, nil, "", -1, nil, nil, 0}
+	return &traversalRuntime{rtp, node, nil, nil, nil, "", -1, nil, nil, 0}
 }
 
 /*
@@ -56,9 +57,18 @@ func (rt *traversalRuntime) Validate() error {
 		return rt.rtp.newRuntimeError(ErrInvalidSpec, spec, rt.node)
 	}
 
+	// A reverse traversal swaps the source and destination role of the spec.
+	// The relationship kind and the destination kind stay as given.
+
+	if len(rt.node.Children) > 1 && rt.node.Children[1].Name == parser.NodeREVERSE {
+		sspec[0], sspec[2] = sspec[2], sspec[0]
+		spec = strings.Join(sspec, ":")
+	}
+
 	rt.spec = spec
 	rt.specIndex = len(rt.rtp.specs)
 	rt.where = nil
+	rt.nullTraversal = nil
 	rt.rtp.specs = append(rt.rtp.specs, spec)
 	rt.rtp.attrsNodes = append(rt.rtp.attrsNodes, make(map[string]string))
 	rt.rtp.attrsEdges = append(rt.rtp.attrsEdges, make(map[string]string))
@@ -87,7 +97,21 @@ func (rt *traversalRuntime) Validate() error {
 
 			rt.where = child
 
-		} else {
+		} else if child.Name == parser.NodeNULLTRAVERSAL {
+
+			// A nulltraversal directive overrides the query wide setting for
+			// this traversal step
+
+			if len(child.Children) != 1 || (child.Children[0].Name != parser.NodeTRUE &&
+				child.Children[0].Name != parser.NodeFALSE) {
+
+				return rt.rtp.newRuntimeError(ErrInvalidConstruct,
+					"nulltraversal directive must be true or false", child)
+			}
+
+			rt.nullTraversal = child
+
+		} else if child.Name != parser.NodeREVERSE {
 			return rt.rtp.newRuntimeError(ErrInvalidConstruct, child.Name, child)
 		}
 	}
@@ -126,13 +150,19 @@ func (rt *traversalRuntime) newSource(node data.Node) error {
 	if node != nil {
 		var err error
 
+		// Stop if the query was cancelled
+
+		if err = rt.rtp.checkCancelled(); err != nil {
+			return err
+		}
+
 		// Do a simple traversal without getting any node data first
 
 		nodes, edges, err = rt.rtp.gm.TraverseMulti(rt.rtp.part, rt.sourceNode.Key(),
 			rt.sourceNode.Kind(), rt.spec, false)
 
 		if err != nil {
-			return err
+			return rt.rtp.newSourceError(err, rt.node)
 		}
 
 		// Now get the attributes which are required
@@ -144,7 +174,7 @@ func (rt *traversalRuntime) newSource(node data.Node) error {
 				n, err := rt.rtp.gm.FetchNodePart(rt.rtp.part, node.Key(), node.Kind(), attrs)
 
 				if err != nil {
-					return err
+					return rt.rtp.newSourceError(err, rt.node)
 				} else if n != nil {
 					for _, attr := range attrs {
 						node.SetAttr(attr, n.Attr(attr))
@@ -159,7 +189,7 @@ func (rt *traversalRuntime) newSource(node data.Node) error {
 				e, err := rt.rtp.gm.FetchEdgePart(rt.rtp.part, edge.Key(), edge.Kind(), attrs)
 
 				if err != nil {
-					return err
+					return rt.rtp.newSourceError(err, rt.node)
 				} else if e != nil {
 					for _, attr := range attrs {
 						edge.SetAttr(attr, e.Attr(attr))
@@ -201,19 +231,23 @@ func (rt *traversalRuntime) newSource(node data.Node) error {
 	// Check if there are no nodes to display and return an error if
 	// empty traversals are not allowed
 
-	if len(rt.nodes) == 0 && !rt.rtp.allowNilTraversal {
+	allowNilTraversal := rt.rtp.allowNilTraversal
+	if rt.nullTraversal != nil {
+		allowNilTraversal = rt.nullTraversal.Children[0].Name == parser.NodeTRUE
+	}
+
+	if len(rt.nodes) == 0 && !allowNilTraversal {
 		return ErrEmptyTraversal
 	}
 
 	// Evaluate the new source
 
-	_, err := rt.Eval()
-
-	return err
+	return rt.nextRow()
 }
 
 /*
-Eval evaluate this runtime component.
+Eval evaluate this runtime component. Returns ErrEmptyTraversal if none of the
+remaining nodes of this traversal produces a row.
 */
 func (rt *traversalRuntime) Eval() (interface{}, error) {
 
@@ -223,45 +257,69 @@ func (rt *traversalRuntime) Eval() (interface{}, error) {
 		if child.Name == parser.NodeTRAVERSE {
 			childRuntime := child.Runtime.(*traversalRuntime)
 			if childRuntime.hasMoreNodes() {
-				return childRuntime.Eval()
+
+				// Continue with the next node of this traversal if the
+				// remaining nodes of the child produce no row
+
+				if _, err := childRuntime.Eval(); err != ErrEmptyTraversal {
+					return nil, err
+				}
 			}
 		}
 	}
 
-	// Get the next node and fill the row entry in the provider
+	if rt.curptr >= len(rt.nodes) {
+		return nil, ErrEmptyTraversal
+	}
 
-	var rowNode data.Node
-	var rowEdge data.Edge
+	return nil, rt.nextRow()
+}
 
-	if rt.curptr < len(rt.nodes) {
+/*
+nextRow fills the row entry in the provider with the next node of this
+traversal. Nodes for which a nested traversal returns ErrEmptyTraversal are
+skipped (the row is dropped). Returns ErrEmptyTraversal if no node is left.
+*/
+func (rt *traversalRuntime) nextRow() error {
 
-		// Get a new node from our node list if possible
+	for {
+		var rowNode data.Node
+		var rowEdge data.Edge
 
-		rowNode = rt.nodes[rt.curptr]
-		rowEdge = rt.edges[rt.curptr]
-		rt.curptr++
+		if rt.curptr < len(rt.nodes) {
 
-	}
+			// Get a new node from our node list if possible - the row
+			// contains no node for this step if the traversal was empty
 
-	if len(rt.rtp.rowNode) == rt.specIndex {
-		rt.rtp.rowNode = append(rt.rtp.rowNode, rowNode)
-		rt.rtp.rowEdge = append(rt.rtp.rowEdge, rowEdge)
-	} else {
-		rt.rtp.rowNode[rt.specIndex] = rowNode
-		rt.rtp.rowEdge[rt.specIndex] = rowEdge
-	}
+			rowNode = rt.nodes[rt.curptr]
+			rowEdge = rt.edges[rt.curptr]
+			rt.curptr++
+		}
 
-	// Give the new source to the children and let them evaluate
+		if len(rt.rtp.rowNode) == rt.specIndex {
+			rt.rtp.rowNode = append(rt.rtp.rowNode, rowNode)
+			rt.rtp.rowEdge = append(rt.rtp.rowEdge, rowEdge)
+		} else {
+			rt.rtp.rowNode[rt.specIndex] = rowNode
+			rt.rtp.rowEdge[rt.specIndex] = rowEdge
+		}
 
-	for _, child := range rt.node.Children[1:] {
-		if child.Name == parser.NodeTRAVERSE {
-			childRuntime := child.Runtime.(*traversalRuntime)
+		// Give the new source to the children and let them evaluate
+
+		var err error
+
+		for _, child := range rt.node.Children[1:] {
+			if child.Name == parser.NodeTRAVERSE {
+				childRuntime := child.Runtime.(*traversalRuntime)
 
-			if err := childRuntime.newSource(rt.rtp.rowNode[rt.specIndex]); err != nil {
-				return nil, err
+				if err = childRuntime.newSource(rowNode); err != nil {
+					break
+				}
 			}
 		}
-	}
 
-	return nil, nil
+		if err != ErrEmptyTraversal || rt.curptr >= len(rt.nodes) {
+			return err
+		}
+	}
 }
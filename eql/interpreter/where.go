@@ -12,12 +12,16 @@ package interpreter
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
 )
 
 /*
@@ -72,6 +76,47 @@ func (rt *whereItemRuntime) valOp(node data.Node, edge data.Edge, op func(interf
 	return op(res1, res2), nil
 }
 
+/*
+isDateOp checks if one of the operands of this operation is a date function.
+*/
+func (rt *whereItemRuntime) isDateOp() bool {
+	for _, child := range rt.astNode.Children[:2] {
+		if child.Name == parser.NodeFUNC && child.Children[0].Token.Val == "date" {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+dateOp executes an operation on two date values. Operands which are not
+dates are parsed with the date formats of the runtime provider. A value
+which cannot be parsed does not match unless strict date parsing is required.
+*/
+func (rt *whereItemRuntime) dateOp(node data.Node, edge data.Edge, op func(time.Time, time.Time) bool) (interface{}, error) {
+	var dates [2]time.Time
+
+	for i, child := range rt.astNode.Children[:2] {
+
+		res, err := child.Runtime.(CondRuntime).CondEval(node, edge)
+		if err != nil {
+			return nil, err
+		}
+
+		t, ok := rt.rtp.parseDate(res)
+		if !ok {
+			if rt.rtp.strictDates {
+				return nil, rt.rtp.newRuntimeError(ErrNotADate, fmt.Sprint(res), child)
+			}
+			return false, nil
+		}
+
+		dates[i] = t
+	}
+
+	return op(dates[0], dates[1]), nil
+}
+
 /*
 stringOp executes an operation on two strings.
 */
@@ -122,6 +167,36 @@ func (rt *whereItemRuntime) regexOp(node data.Node, edge data.Edge, op func(stri
 numOp executes an operation on two number values.
 */
 func (rt *whereItemRuntime) numOp(node data.Node, edge data.Edge, op func(float64, float64) interface{}) (interface{}, error) {
+	return rt.numOpErr(node, edge, func(res1 float64, res2 float64) (interface{}, error) { return op(res1, res2), nil })
+}
+
+/*
+unaryNumOp executes an operation on a single number value (e.g. -x).
+*/
+func (rt *whereItemRuntime) unaryNumOp(node data.Node, edge data.Edge, op func(float64) interface{}) (interface{}, error) {
+
+	res, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	if err != nil {
+		return nil, err
+	}
+
+	resStr := fmt.Sprint(res)
+	resNum, err := strconv.ParseFloat(resStr, 64)
+	if err != nil {
+		detail := resStr
+		if tokenVal := rt.astNode.Children[0].Token.Val; tokenVal != resStr {
+			detail = tokenVal + "=" + resStr
+		}
+		return nil, rt.rtp.newRuntimeError(ErrNotANumber, detail, rt.astNode.Children[0])
+	}
+
+	return op(resNum), nil
+}
+
+/*
+numOpErr executes an operation on two number values. The operation can fail.
+*/
+func (rt *whereItemRuntime) numOpErr(node data.Node, edge data.Edge, op func(float64, float64) (interface{}, error)) (interface{}, error) {
 
 	res1, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
 	if err != nil {
@@ -155,7 +230,21 @@ func (rt *whereItemRuntime) numOp(node data.Node, edge data.Edge, op func(float6
 		return nil, rt.rtp.newRuntimeError(ErrNotANumber, errDetail(rt.astNode.Children[1].Token.Val, res2Str), rt.astNode.Children[1])
 	}
 
-	return op(res1Num, res2Num), nil
+	return op(res1Num, res2Num)
+}
+
+/*
+divisorOp executes a division operation on two number values. A zero divisor
+results in an error.
+*/
+func (rt *whereItemRuntime) divisorOp(node data.Node, edge data.Edge, op func(float64, float64) interface{}) (interface{}, error) {
+	return rt.numOpErr(node, edge, func(res1 float64, res2 float64) (interface{}, error) {
+		if res2 == 0 || (rt.astNode.Name != parser.NodeDIV && int(res2) == 0) {
+			return nil, rt.rtp.newRuntimeError(ErrDivisionByZero,
+				fmt.Sprintf("%v %v %v", res1, rt.astNode.Token.Val, res2), rt.astNode.Children[1])
+		}
+		return op(res1, res2), nil
+	})
 }
 
 /*
@@ -198,7 +287,7 @@ short circuit operation.
 func (rt *whereItemRuntime) boolOp(node data.Node, edge data.Edge, op func(bool, bool) interface{},
 	scop func(bool) interface{}) (interface{}, error) {
 
-	res1, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	res1, err := rt.rtp.predicateCondEval(rt.astNode.Children[0], node, edge)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +309,7 @@ func (rt *whereItemRuntime) boolOp(node data.Node, edge data.Edge, op func(bool,
 		}
 	}
 
-	res2, err := rt.astNode.Children[1].Runtime.(CondRuntime).CondEval(node, edge)
+	res2, err := rt.rtp.predicateCondEval(rt.astNode.Children[1], node, edge)
 	if err != nil {
 		return nil, err
 	}
@@ -244,6 +333,9 @@ func toBool(res interface{}) bool {
 	case float64:
 		return res > 0
 
+	case int64:
+		return res > 0
+
 	case string:
 
 		// Try to convert the string into a number
@@ -257,8 +349,16 @@ func toBool(res interface{}) bool {
 	}
 }
 
+/*
+equals compares two values. Null values (e.g. absent attributes) are only
+equal to other null values.
+*/
 func equals(res1 interface{}, res2 interface{}) bool {
 
+	if res1 == nil || res2 == nil {
+		return res1 == nil && res2 == nil
+	}
+
 	// Try to convert the string into a number
 
 	num1, err := strconv.ParseFloat(fmt.Sprint(res1), 64)
@@ -272,6 +372,58 @@ func equals(res1 interface{}, res2 interface{}) bool {
 	return fmt.Sprintf("%v", res1) == fmt.Sprintf("%v", res2)
 }
 
+/*
+predicateCondEval evaluates a condition component. If a query has several
+start kinds then a predicate which refers to an attribute that the kind of
+the node does not have is false.
+*/
+func (p *eqlRuntimeProvider) predicateCondEval(astNode *parser.ASTNode, node data.Node,
+	edge data.Edge) (interface{}, error) {
+
+	if attrs, ok := p.kindAttrs[nodeKind(node)]; ok {
+
+		switch astNode.Name {
+		case parser.NodeAND, parser.NodeOR, parser.NodeNOT, parser.NodeISNULL:
+
+		default:
+			if refersToAbsentAttr(astNode, attrs) {
+				return false, nil
+			}
+		}
+	}
+
+	return astNode.Runtime.(CondRuntime).CondEval(node, edge)
+}
+
+/*
+refersToAbsentAttr checks if a condition component refers to a node attribute
+which is not in a given set of attributes.
+*/
+func refersToAbsentAttr(astNode *parser.ASTNode, attrs map[string]bool) bool {
+
+	if valRT, ok := astNode.Runtime.(*valueRuntime); ok && valRT.isNodeAttrValue && !attrs[valRT.condVal] {
+		return true
+	}
+
+	for _, child := range astNode.Children {
+		if refersToAbsentAttr(child, attrs) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+nodeKind returns the kind of a given node or an empty string if there is no node.
+*/
+func nodeKind(node data.Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.Kind()
+}
+
 // Where runtime
 // =============
 
@@ -300,6 +452,17 @@ func (rt *whereRuntime) Validate() error {
 
 	visitChildren = func(astNode *parser.ASTNode) error {
 
+		// Functions must be known when the query is prepared
+
+		if astNode.Name == parser.NodeFUNC {
+			funcName := astNode.Children[0].Token.Val
+
+			if _, ok := whereFunc[funcName]; !ok && lookupCustomFunction(funcName) == nil {
+				return rt.rtp.newRuntimeError(ErrInvalidConstruct,
+					"Unknown function: "+funcName, astNode)
+			}
+		}
+
 		// Determine which values should be interpreted as node attributes
 
 		if astNode.Name == parser.NodeVALUE {
@@ -360,6 +523,17 @@ func (rt *whereRuntime) Validate() error {
 			}
 		}
 
+		// Compile constant regexes once the operands are known
+
+		switch crt := astNode.Runtime.(type) {
+		case *matchesRuntime:
+			return crt.compile()
+		case *likeRuntime:
+			return crt.compile()
+		case *notLikeRuntime:
+			return crt.compile()
+		}
+
 		return nil
 	}
 
@@ -377,7 +551,7 @@ func (rt *whereRuntime) Eval() (interface{}, error) {
 CondEval evaluates this condition runtime element.
 */
 func (rt *whereRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
-	res, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	res, err := rt.rtp.predicateCondEval(rt.astNode.Children[0], node, edge)
 	return toBool(res), err
 }
 
@@ -402,6 +576,13 @@ func equalRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runt
 Evaluate this condition runtime element.
 */
 func (rt *equalRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Compare chronologically if a date function is involved
+
+	if rt.isDateOp() {
+		return rt.dateOp(node, edge, func(t1 time.Time, t2 time.Time) bool { return t1.Equal(t2) })
+	}
+
 	return rt.valOp(node, edge, func(res1 interface{}, res2 interface{}) interface{} { return equals(res1, res2) })
 }
 
@@ -423,6 +604,13 @@ func notEqualRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.R
 CondEval evaluates this condition runtime element.
 */
 func (rt *notEqualRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Compare chronologically if a date function is involved
+
+	if rt.isDateOp() {
+		return rt.dateOp(node, edge, func(t1 time.Time, t2 time.Time) bool { return !t1.Equal(t2) })
+	}
+
 	return rt.valOp(node, edge, func(res1 interface{}, res2 interface{}) interface{} { return !equals(res1, res2) })
 }
 
@@ -444,6 +632,13 @@ func lessThanRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.R
 CondEval evaluates this condition runtime element.
 */
 func (rt *lessThanRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Compare chronologically if a date function is involved
+
+	if rt.isDateOp() {
+		return rt.dateOp(node, edge, func(t1 time.Time, t2 time.Time) bool { return t1.Before(t2) })
+	}
+
 	ret, err := rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 < res2 })
 
 	if err != nil {
@@ -474,6 +669,13 @@ func lessThanEqualsRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) pa
 CondEval evaluates this condition runtime element.
 */
 func (rt *lessThanEqualsRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Compare chronologically if a date function is involved
+
+	if rt.isDateOp() {
+		return rt.dateOp(node, edge, func(t1 time.Time, t2 time.Time) bool { return !t1.After(t2) })
+	}
+
 	ret, err := rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 <= res2 })
 
 	if err != nil {
@@ -504,6 +706,13 @@ func greaterThanRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parse
 CondEval evaluates this condition runtime element.
 */
 func (rt *greaterThanRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Compare chronologically if a date function is involved
+
+	if rt.isDateOp() {
+		return rt.dateOp(node, edge, func(t1 time.Time, t2 time.Time) bool { return t1.After(t2) })
+	}
+
 	ret, err := rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 > res2 })
 
 	if err != nil {
@@ -534,6 +743,13 @@ func greaterThanEqualsRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode)
 CondEval evaluates this condition runtime element.
 */
 func (rt *greaterThanEqualsRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	// Compare chronologically if a date function is involved
+
+	if rt.isDateOp() {
+		return rt.dateOp(node, edge, func(t1 time.Time, t2 time.Time) bool { return !t1.Before(t2) })
+	}
+
 	ret, err := rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 >= res2 })
 
 	if err != nil {
@@ -621,6 +837,50 @@ func (rt *notRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, err
 	return rt.boolOp(node, edge, func(res1 bool, res2 bool) interface{} { return !res1 }, nil)
 }
 
+/*
+Is null runtime
+*/
+type isNullRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+isNullRuntimeInst returns a new runtime component instance.
+*/
+func isNullRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &isNullRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *isNullRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	res, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	return res == nil, err
+}
+
+/*
+Is not null runtime
+*/
+type isNotNullRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+isNotNullRuntimeInst returns a new runtime component instance.
+*/
+func isNotNullRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &isNotNullRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *isNotNullRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	res, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	return res != nil, err
+}
+
 /*
 Plus runtime
 */
@@ -639,6 +899,9 @@ func plusRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runti
 CondEval evaluates this condition runtime element.
 */
 func (rt *plusRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	if len(rt.astNode.Children) == 1 {
+		return rt.unaryNumOp(node, edge, func(res float64) interface{} { return res })
+	}
 	return rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 + res2 })
 }
 
@@ -660,6 +923,9 @@ func minusRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runt
 CondEval evaluates this condition runtime element.
 */
 func (rt *minusRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	if len(rt.astNode.Children) == 1 {
+		return rt.unaryNumOp(node, edge, func(res float64) interface{} { return -res })
+	}
 	return rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 - res2 })
 }
 
@@ -702,7 +968,7 @@ func divRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtim
 CondEval evaluates this condition runtime element.
 */
 func (rt *divRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
-	return rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 / res2 })
+	return rt.divisorOp(node, edge, func(res1 float64, res2 float64) interface{} { return res1 / res2 })
 }
 
 /*
@@ -723,7 +989,7 @@ func modIntRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Run
 CondEval evaluates this condition runtime element.
 */
 func (rt *modIntRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
-	return rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return int(int(res1) % int(res2)) })
+	return rt.divisorOp(node, edge, func(res1 float64, res2 float64) interface{} { return int(int(res1) % int(res2)) })
 }
 
 /*
@@ -744,7 +1010,38 @@ func divIntRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Run
 CondEval evaluates this condition runtime element.
 */
 func (rt *divIntRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
-	return rt.numOp(node, edge, func(res1 float64, res2 float64) interface{} { return int(int(res1) / int(res2)) })
+	return rt.divisorOp(node, edge, func(res1 float64, res2 float64) interface{} { return int(int(res1) / int(res2)) })
+}
+
+/*
+Pow runtime
+*/
+type powRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+powRuntimeInst returns a new runtime component instance.
+*/
+func powRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &powRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *powRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	return rt.numOpErr(node, edge, func(res1 float64, res2 float64) (interface{}, error) {
+
+		// An integer base cannot have a negative exponent
+
+		if res2 < 0 && res1 == math.Trunc(res1) {
+			return nil, rt.rtp.newRuntimeError(ErrInvalidExponent,
+				fmt.Sprintf("%v ^ %v (negative exponent for integer base)", res1, res2), rt.astNode.Children[1])
+		}
+
+		return math.Pow(res1, res2), nil
+	})
 }
 
 /*
@@ -807,11 +1104,103 @@ func (rt *notInRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, e
 	})
 }
 
+/*
+Contains all runtime
+*/
+type containsAllRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+containsAllRuntimeInst returns a new runtime component instance.
+*/
+func containsAllRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &containsAllRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element. The condition is true if
+all items of the right list can be found in the left list. An empty right
+list is always contained.
+*/
+func (rt *containsAllRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	return rt.listOp(node, edge, func(res1 interface{}, res2 []interface{}) interface{} {
+		var res1List []interface{}
+
+		switch res1 := res1.(type) {
+		case []interface{}:
+			res1List = res1
+		case []string:
+			for _, item := range res1 {
+				res1List = append(res1List, item)
+			}
+		}
+
+		for _, item := range res2 {
+			found := false
+
+			for _, litem := range res1List {
+				if equals(litem, item) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+/*
+Between runtime
+*/
+type betweenRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+betweenRuntimeInst returns a new runtime component instance.
+*/
+func betweenRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &betweenRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element. The condition is true if
+the left value is in the inclusive range given by the right list. Values are
+compared as numbers if possible otherwise as strings.
+*/
+func (rt *betweenRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	return rt.listOp(node, edge, func(res1 interface{}, res2 []interface{}) interface{} {
+
+		if len(res2) != 2 || res1 == nil {
+			return false
+		}
+
+		val, lower, upper := fmt.Sprint(res1), fmt.Sprint(res2[0]), fmt.Sprint(res2[1])
+
+		if valNum, err := strconv.ParseFloat(val, 64); err == nil {
+			if lowerNum, err := strconv.ParseFloat(lower, 64); err == nil {
+				if upperNum, err := strconv.ParseFloat(upper, 64); err == nil {
+					return lowerNum <= valNum && valNum <= upperNum
+				}
+			}
+		}
+
+		return lower <= val && val <= upper
+	})
+}
+
 /*
 Like runtime
 */
 type likeRuntime struct {
-	compiledRegex *regexp.Regexp // Quick lookup of the compiled regex if it is a constant
+	compiledRegex *regexp.Regexp // Compiled regex if the pattern is a constant
+	compiled      bool           // Flag if the pattern was checked for a constant
 	*whereItemRuntime
 }
 
@@ -819,30 +1208,45 @@ type likeRuntime struct {
 likeRuntimeInst returns a new runtime component instance.
 */
 func likeRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
-	return &likeRuntime{nil, &whereItemRuntime{rtp, node}}
+	return &likeRuntime{nil, false, &whereItemRuntime{rtp, node}}
 }
 
 /*
-CondEval evaluates this condition runtime element.
+compile compiles the pattern if it is a constant. Invalid constant patterns
+are reported before any row is evaluated.
 */
-func (rt *likeRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+func (rt *likeRuntime) compile() error {
+	var err error
 
-	// Check for constant regexp
+	rt.compiledRegex = nil
+	rt.compiled = true
 
 	if valRT, ok := rt.astNode.Children[1].Runtime.(*valueRuntime); ok {
 		if !valRT.isNodeAttrValue && !valRT.isEdgeAttrValue {
 
 			// Given regex is a constant and only needs to be compiled once
 
-			val, _ := valRT.CondEval(node, edge)
+			val, _ := valRT.CondEval(nil, nil)
 			valStr := fmt.Sprint(val)
-			regexp, err := regexp.Compile(valStr)
-			if err != nil {
-				return nil, rt.rtp.newRuntimeError(ErrNotARegex,
+
+			if rt.compiledRegex, err = regexp.Compile(valStr); err != nil {
+				err = rt.rtp.newRuntimeError(ErrNotARegex,
 					fmt.Sprintf("%#v - %s", valStr, err.Error()), rt.astNode.Children[1])
 			}
+		}
+	}
+
+	return err
+}
 
-			rt.compiledRegex = regexp
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *likeRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	if !rt.compiled {
+		if err := rt.compile(); err != nil {
+			return nil, err
 		}
 	}
 
@@ -853,6 +1257,124 @@ func (rt *likeRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, er
 	return rt.stringOp(node, edge, func(res1 string, res2 string) interface{} { return rt.compiledRegex.MatchString(res1) })
 }
 
+/*
+Matches runtime
+*/
+type matchesRuntime struct {
+	compiledRegex *regexp.Regexp            // Compiled regex if the pattern is a constant
+	regexCache    map[string]*regexp.Regexp // Compiled regexes of patterns which are not constant
+	regexLock     *sync.Mutex               // Lock for the regex cache (conditions may be evaluated in parallel)
+	*whereItemRuntime
+}
+
+/*
+matchesRuntimeInst returns a new runtime component instance.
+*/
+func matchesRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &matchesRuntime{nil, nil, &sync.Mutex{}, &whereItemRuntime{rtp, node}}
+}
+
+/*
+compile resets the regex cache and compiles the pattern if it is a constant.
+Invalid constant patterns are reported before any row is evaluated.
+*/
+func (rt *matchesRuntime) compile() error {
+	var err error
+
+	rt.compiledRegex = nil
+	rt.regexCache = make(map[string]*regexp.Regexp)
+
+	if valRT, ok := rt.astNode.Children[1].Runtime.(*valueRuntime); ok && rt.astNode.Children[1].Name == parser.NodeVALUE {
+		if !valRT.isNodeAttrValue && !valRT.isEdgeAttrValue {
+			val, _ := valRT.CondEval(nil, nil)
+			rt.compiledRegex, err = rt.regex(fmt.Sprint(val))
+		}
+	}
+
+	return err
+}
+
+/*
+regex returns the compiled regex of a given pattern.
+*/
+func (rt *matchesRuntime) regex(pattern string) (*regexp.Regexp, error) {
+	rt.regexLock.Lock()
+	defer rt.regexLock.Unlock()
+
+	if re, ok := rt.regexCache[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, rt.rtp.newRuntimeError(ErrNotARegex,
+			fmt.Sprintf("%#v for %v - %s", pattern, rt.astNode.Children[0].Token.Val, err.Error()),
+			rt.astNode.Children[1])
+	}
+
+	rt.regexCache[pattern] = re
+
+	return re, nil
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *matchesRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	if rt.regexCache == nil {
+		if err := rt.compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	res1, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	if err != nil {
+		return nil, err
+	}
+
+	re := rt.compiledRegex
+
+	if re == nil {
+		res2, err := rt.astNode.Children[1].Runtime.(CondRuntime).CondEval(node, edge)
+		if err != nil {
+			return nil, err
+		}
+
+		if re, err = rt.regex(fmt.Sprint(res2)); err != nil {
+			return nil, err
+		}
+	}
+
+	return re.MatchString(fmt.Sprint(res1)), nil
+}
+
+/*
+Not like runtime
+*/
+type notLikeRuntime struct {
+	*likeRuntime
+}
+
+/*
+notLikeRuntimeInst returns a new runtime component instance.
+*/
+func notLikeRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &notLikeRuntime{&likeRuntime{nil, false, &whereItemRuntime{rtp, node}}}
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *notLikeRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	res, err := rt.likeRuntime.CondEval(node, edge)
+	if err != nil {
+		return nil, err
+	}
+
+	return !toBool(res), nil
+}
+
 /*
 Contains runtime
 */
@@ -895,6 +1417,55 @@ func (rt *containsNotRuntime) CondEval(node data.Node, edge data.Edge) (interfac
 	return rt.stringOp(node, edge, func(res1 string, res2 string) interface{} { return !strings.Contains(res1, res2) })
 }
 
+/*
+Contains word runtime
+*/
+type containsWordRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+containsWordRuntimeInst returns a new runtime component instance.
+*/
+func containsWordRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &containsWordRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element. The condition is true if
+the first value contains all words of the second value. Words are compared in
+their folded form (see util.FoldedWords). A missing first value contains no
+words.
+*/
+func (rt *containsWordRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+
+	res1, err := rt.astNode.Children[0].Runtime.(CondRuntime).CondEval(node, edge)
+	if err != nil {
+		return nil, err
+	}
+
+	res2, err := rt.astNode.Children[1].Runtime.(CondRuntime).CondEval(node, edge)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make(map[string]bool)
+
+	if res1 != nil {
+		for _, word := range util.FoldedWords(fmt.Sprint(res1)) {
+			words[word] = true
+		}
+	}
+
+	for _, word := range util.FoldedWords(fmt.Sprint(res2)) {
+		if !words[word] {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 /*
 Begins with runtime
 */
@@ -936,3 +1507,45 @@ CondEval evaluates this condition runtime element.
 func (rt *endsWithRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
 	return rt.stringOp(node, edge, func(res1 string, res2 string) interface{} { return strings.HasSuffix(res1, res2) })
 }
+
+/*
+Not begins with runtime
+*/
+type notBeginsWithRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+notBeginsWithRuntimeInst returns a new runtime component instance.
+*/
+func notBeginsWithRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &notBeginsWithRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *notBeginsWithRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	return rt.stringOp(node, edge, func(res1 string, res2 string) interface{} { return !strings.HasPrefix(res1, res2) })
+}
+
+/*
+Not ends with runtime
+*/
+type notEndsWithRuntime struct {
+	*whereItemRuntime
+}
+
+/*
+notEndsWithRuntimeInst returns a new runtime component instance.
+*/
+func notEndsWithRuntimeInst(rtp *eqlRuntimeProvider, node *parser.ASTNode) parser.Runtime {
+	return &notEndsWithRuntime{&whereItemRuntime{rtp, node}}
+}
+
+/*
+CondEval evaluates this condition runtime element.
+*/
+func (rt *notEndsWithRuntime) CondEval(node data.Node, edge data.Edge) (interface{}, error) {
+	return rt.stringOp(node, edge, func(res1 string, res2 string) interface{} { return !strings.HasSuffix(res1, res2) })
+}
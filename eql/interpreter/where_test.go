@@ -13,6 +13,7 @@ package interpreter
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"devt.de/krotik/eliasdb/eql/parser"
@@ -122,6 +123,75 @@ Data: 1:n:key, 1:func:objget()
 
 }
 
+func TestWhereNestedPath(t *testing.T) {
+	gm := graph.NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	for key, meta := range map[string]interface{}{
+		"1": map[string]interface{}{
+			"genre": "rock",
+			"tags":  []interface{}{"studio", "live"},
+		},
+		"2": map[string]interface{}{
+			"genre": "jazz",
+			"tags":  []interface{}{"live"},
+		},
+		"3": "rock",
+	} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "song")
+		node.SetAttr("meta", meta)
+		gm.StoreNode("main", node)
+	}
+
+	for query, res := range map[string]string{
+
+		// Access by key
+
+		"get song where meta.genre = rock show key": "1",
+
+		// Access list items by index
+
+		"get song where meta.tags.1 = live show key": "1",
+		"get song where meta.tags.0 = live show key": "2",
+
+		// Out of range or non-numeric indices do not match
+
+		"get song where meta.tags.5 = live show key": "",
+		"get song where meta.tags.x = live show key": "",
+
+		// Missing intermediate keys evaluate to null
+
+		"get song where isnull meta.foo.bar show key":   "1\n2\n3",
+		"get song where meta.genre.foo = null show key": "1\n2\n3",
+	} {
+		expected := `
+Labels: Song Key
+Format: auto
+Data: 1:n:key
+`[1:]
+		if res != "" {
+			expected += res + "\n"
+		}
+
+		if err := runSearch(query, expected, rt); err != nil {
+			t.Error(query, err)
+			return
+		}
+	}
+
+	if err := runSearch("get song where meta.genre = rock show @objget(1, meta, tags.1)", `
+Labels: Meta.tags.1
+Format: auto
+Data: 1:func:objget()
+live
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
 func TestWhere(t *testing.T) {
 	gm, _ := simpleGraph()
 	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
@@ -449,6 +519,86 @@ Data: 1:n:key, 1:n:name, 1:n:ranking
 		return
 	}
 
+	if err := runSearch("get mynode where ranking * 2 ^ 3 ^ 2 = 512", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where (2 ^ 3) ^ 2 = ranking * 64", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking ^ 0.5 > 1.8", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := testSimpleOperationErrors("get mynode where 2 ^ 3", rt); err != nil {
+		t.Error(err)
+	}
+
+	if err := runSearch("get mynode where 2 ^ (1 - 2) = ranking", "", rt); err == nil || err.Error() !=
+		"EQL error in test: Invalid exponent (2 ^ -1 (negative exponent for integer base)) (Line:1 Pos:25)" {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where 2 ^ -1 = 0", "", rt); err == nil || err.Error() !=
+		"EQL error in test: Invalid exponent (2 ^ -1 (negative exponent for integer base)) (Line:1 Pos:22)" {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where -ranking = -1 and +ranking = 1", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where -name = 1", "", rt); err == nil || !strings.HasPrefix(err.Error(),
+		"EQL error in test: Value of operand is not a number (name=Node") {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking / (ranking - 1) = 1", "", rt); err == nil || err.Error() !=
+		"EQL error in test: Division by zero (1 / 0) (Line:1 Pos:37)" {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking = 1 and ranking // 0.5 = 1", "", rt); err == nil || err.Error() !=
+		"EQL error in test: Division by zero (1 // 0.5) (Line:1 Pos:45)" {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking = 1 and ranking % 0 = 1", "", rt); err == nil || err.Error() !=
+		"EQL error in test: Division by zero (1 % 0) (Line:1 Pos:44)" {
+		t.Error(err)
+		return
+	}
+
 	if err := runSearch("get mynode where ranking = (3 * 4 - 10 + 0.1) / 2.1", `
 Labels: Mynode Key, Mynode Name, Ranking
 Format: auto, auto, auto
@@ -528,6 +678,186 @@ Data: 1:n:key, 1:n:name, 1:n:ranking
 		return
 	}
 
+	if err := runSearch("get mynode where [1,2,3] containsall [3,1]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+123, Node1, 2.1
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where [1,2,3] containsall [1,4]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// An empty list is always contained - also in non-list values
+
+	if err := runSearch("get mynode where ranking containsall []", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+123, Node1, 2.1
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking containsall [1]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where [1,2] containsall 1", "", rt); err.Error() !=
+		"EQL error in test: Value of operand is not a list (1) (Line:1 Pos:36)" {
+		t.Error(err)
+		return
+	}
+
+	if err := testSimpleOperationErrors("get mynode where [1] containsall [1]", rt); err != nil {
+		t.Error(err)
+	}
+
+	// Test containsall on stored list attributes
+
+	tagsGm := graph.NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+
+	tagNode := data.NewGraphNode()
+	tagNode.SetAttr("key", "000")
+	tagNode.SetAttr("kind", "tagnode")
+	tagNode.SetAttr("tags", []string{"a", "b", "c"})
+	tagsGm.StoreNode("main", tagNode)
+
+	tagNode = data.NewGraphNode()
+	tagNode.SetAttr("key", "123")
+	tagNode.SetAttr("kind", "tagnode")
+	tagNode.SetAttr("tags", []interface{}{"b", "c"})
+	tagsGm.StoreNode("main", tagNode)
+
+	tagsRt := NewGetRuntimeProvider("test", "main", tagsGm, NewDefaultNodeInfo(tagsGm))
+
+	if err := runSearch("get tagnode where tags containsall [a, c] show key", `
+Labels: Tagnode Key
+Format: auto
+Data: 1:n:key
+000
+`[1:], tagsRt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get tagnode where tags containsall [c, b] show key", `
+Labels: Tagnode Key
+Format: auto
+Data: 1:n:key
+000
+123
+`[1:], tagsRt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Test range checks
+
+	if err := runSearch("get mynode where ranking between [1, 2.1]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+123, Node1, 2.1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where not ranking between [1, 2.1]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where key between [001, 200]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+123, Node1, 2.1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where name between [Node0, Node0]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	lookupRt := NewLookupRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if err := runSearch("lookup mynode '000', '456' where ranking between [2, 4]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+456, Node1, 3.5
+`[1:], lookupRt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking between 1", "", rt); err.Error() !=
+		"Parse error in test: Invalid range (between requires a list with a lower and an upper bound) (Line:1 Pos:34)" {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where ranking between [1, 2, 3]", "", rt); err.Error() !=
+		"Parse error in test: Invalid range (between requires a list with a lower and an upper bound) (Line:1 Pos:34)" {
+		t.Error(err)
+		return
+	}
+
+	if err := testSimpleOperationErrors("get mynode where 1 between [0, 2]", rt); err != nil {
+		t.Error(err)
+	}
+
+	// Test range checks on edges
+
+	songGm, _ := songGraph()
+	songRt := NewGetRuntimeProvider("test", "main", songGm, NewDefaultNodeInfo(songGm))
+
+	if err := runSearch("get Author where name = John traverse :Wrote::Song where eattr:number between [2, 3] end show Song:name, Wrote:number", `
+Labels: Song Name, Number
+Format: auto, auto
+Data: 2:n:name, 2:e:number
+Aria2, 2
+Aria3, 3
+`[1:], songRt); err != nil {
+		t.Error(err)
+		return
+	}
+
 	if err := runSearch("get mynode where name contains 1", `
 Labels: Mynode Key, Mynode Name, Ranking
 Format: auto, auto, auto
@@ -605,6 +935,59 @@ Data: 1:n:key, 1:n:name, 1:n:ranking
 		t.Error(err)
 	}
 
+	// Test negated string operators
+
+	if err := runSearch("get mynode where name not like 'Node0' and name not beginswith x", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+123, Node1, 2.1
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where name not endswith de1 or not name not beginswith Node", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+123, Node1, 2.1
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where name not endswith de0 and ranking not in [1, 2.1]", `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := testSimpleOperationErrors("get mynode where 0 not like 2", rt); err != nil {
+		t.Error(err)
+	}
+
+	if err := testSimpleOperationErrors("get mynode where 0 not beginswith 2", rt); err != nil {
+		t.Error(err)
+	}
+
+	if err := testSimpleOperationErrors("get mynode where 0 not endswith 2", rt); err != nil {
+		t.Error(err)
+	}
+
+	if err := runSearch("get mynode where name not like '[1'", "", rt); err.Error() !=
+		"EQL error in test: Value of operand is not a valid regex (\"[1\" - error parsing regexp: missing closing ]: `[1`) (Line:1 Pos:32)" {
+		t.Error(err)
+		return
+	}
+
 	if err := runSearch("get mynode where name like '[1'", "", rt); err.Error() !=
 		"EQL error in test: Value of operand is not a valid regex (\"[1\" - error parsing regexp: missing closing ]: `[1`) (Line:1 Pos:28)" {
 		t.Error(err)
@@ -633,6 +1016,65 @@ Data: 1:n:key, 1:n:name, 1:n:ranking, 1:n:regex
 	if err := testSimpleOperationErrors("get mynode where name like regex", rt); err != nil {
 		t.Error(err)
 	}
+
+	// Test matches operator
+
+	if err := runSearch("get mynode where name matches regex", `
+Labels: Mynode Key, Mynode Name, Ranking, Regex
+Format: auto, auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking, 1:n:regex
+000, node0, 1, ^[a-z]+[0-9]$
+`[1:], rt); err == nil || err.Error() !=
+		"EQL error in test: Value of operand is not a valid regex (\"[1\" for name - error parsing regexp: missing closing ]: `[1`) (Line:1 Pos:31)" {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get mynode where name = node0 and name matches regex", `
+Labels: Mynode Key, Mynode Name, Ranking, Regex
+Format: auto, auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking, 1:n:regex
+000, node0, 1, ^[a-z]+[0-9]$
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := testSimpleOperationErrors("get mynode where name matches regex", rt); err != nil {
+		t.Error(err)
+	}
+
+	gm, _ = simpleList()
+	rt = NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	if err := runSearch(`get mynode where name matches "^Node[0-9]+$" and not name matches "0$"`, `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+123, Node1, 2.1
+456, Node1, 3.5
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := testSimpleOperationErrors("get mynode where 0 matches 2", rt); err != nil {
+		t.Error(err)
+	}
+
+	// Invalid constant patterns are found before any row is evaluated
+
+	ast, err := parser.ParseWithRuntime("test", "get mynode where ranking matches '[1'", rt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := ast.Runtime.Validate(); err == nil || err.Error() !=
+		"EQL error in test: Value of operand is not a valid regex (\"[1\" for ranking - error parsing regexp: missing closing ]: `[1`) (Line:1 Pos:34)" {
+		t.Error(err)
+		return
+	}
 }
 
 func TestWhereErrors(t *testing.T) {
@@ -719,6 +1161,335 @@ func TestWhereErrors(t *testing.T) {
 	}
 }
 
+func TestWhereIndex(t *testing.T) {
+	gm, _ := songGraph()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	queries := map[string]string{
+		`get Song where name = "Aria1"`: `
+Labels: Song Key, Song Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+Aria1, Aria1, 8
+`[1:],
+		`get Song where ranking = 4 and name beginswith "Aria"`: `
+Labels: Song Key, Song Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+Aria3, Aria3, 4
+`[1:],
+		`get Song where "DeadSong2" = name or ranking = 4`: `
+Labels: Song Key, Song Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+Aria3, Aria3, 4
+DeadSong2, DeadSong2, 6
+`[1:],
+		`get Song where name = "Aria1" and ranking = 4`: `
+Labels: Song Key, Song Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+`[1:],
+	}
+
+	// Queries must return the same results with and without an index
+
+	for i := 0; i < 2; i++ {
+		for query, expected := range queries {
+			if err := runSearch(query, expected, rt); err != nil {
+				t.Error(query, err)
+				return
+			}
+		}
+
+		if i == 0 {
+			if err := gm.CreateIndex("main", "Song", "name"); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := gm.CreateIndex("main", "Song", "ranking"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+}
+
+func TestWhereContainsWord(t *testing.T) {
+	gm := graph.NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	for key, lyrics := range map[string]interface{}{
+		"1": "All you need is love",
+		"2": "Love, love me do!",
+		"3": "Café au lait",
+		"4": nil,
+	} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "song")
+		node.SetAttr("name", "Song"+key)
+		if lyrics != nil {
+			node.SetAttr("lyrics", lyrics)
+		}
+		gm.StoreNode("main", node)
+	}
+
+	queries := map[string]string{
+		`get song where lyrics containsword "LOVE"`: `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+1, All you need is love, Song1
+2, Love, love me do!, Song2
+`[1:],
+		`get song where lyrics containsword "me love" and name != "Song1"`: `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+2, Love, love me do!, Song2
+`[1:],
+		`get song where lyrics containsword "CAFE"`: `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+3, Café au lait, Song3
+`[1:],
+		`get song where lyrics containsword "lov"`: `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+`[1:],
+		`get song where lyrics containsword "nil"`: `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+`[1:],
+		`get song where lyrics containsword "!?"`: `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+1, All you need is love, Song1
+2, Love, love me do!, Song2
+3, Café au lait, Song3
+4, <not set>, Song4
+`[1:],
+	}
+
+	// Queries must return the same results with and without a word index
+
+	for i := 0; i < 2; i++ {
+		for query, expected := range queries {
+			if err := runSearch(query, expected, rt); err != nil {
+				t.Error(query, err)
+				return
+			}
+		}
+
+		if i == 0 {
+			if err := gm.CreateWordIndex("main", "song", "lyrics"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+
+	// Removed words are no longer found
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "2")
+	node.SetAttr("kind", "song")
+	node.SetAttr("lyrics", "Help!")
+	gm.UpdateNode("main", node)
+
+	if err := runSearch(`get song where lyrics containsword "love"`, `
+Labels: Song Key, Lyrics, Song Name
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:lyrics, 1:n:name
+1, All you need is love, Song1
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestWhereNull(t *testing.T) {
+	gm := graph.NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	// Node 1 has empty values, node 2 has no values and nodes 3 and 4 have
+	// values which look like null
+
+	for key, note := range map[string]interface{}{
+		"1": "",
+		"2": nil,
+		"3": "null",
+		"4": "<nil>",
+	} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "nulltest")
+		if note != nil {
+			node.SetAttr("note", note)
+		}
+		if key == "1" {
+			node.SetAttr("other", "")
+		}
+		gm.StoreNode("main", node)
+	}
+
+	if err := runSearch("get nulltest where isnull note show key", `
+Labels: Nulltest Key
+Format: auto
+Data: 1:n:key
+2
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get nulltest where isnotnull note show key", `
+Labels: Nulltest Key
+Format: auto
+Data: 1:n:key
+1
+3
+4
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get nulltest where note = null show key", `
+Labels: Nulltest Key
+Format: auto
+Data: 1:n:key
+2
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := runSearch("get nulltest where note != null and note != 'null' show key", `
+Labels: Nulltest Key
+Format: auto
+Data: 1:n:key
+1
+4
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Two absent attributes are equal - an absent attribute is not equal
+	// to any value
+
+	if err := runSearch("get nulltest where note = other show key", `
+Labels: Nulltest Key
+Format: auto
+Data: 1:n:key
+1
+2
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// A prefix only applies to the next value
+
+	if err := runSearch("get nulltest where isnull other and not isnull note or key = 2 show key", `
+Labels: Nulltest Key
+Format: auto
+Data: 1:n:key
+2
+3
+4
+`[1:], rt); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	gm, _ := simpleList()
+	rt := NewGetRuntimeProvider("test", "main", gm, NewDefaultNodeInfo(gm))
+
+	runParamSearch := func(query string, params map[string]interface{}) (string, error) {
+		ast, err := parser.ParseWithParams("test", query, params, rt)
+		if err != nil {
+			return "", err
+		}
+
+		res, err := ast.Runtime.Eval()
+		if err != nil {
+			return "", err
+		}
+
+		res.(*SearchResult).StableSort()
+
+		return fmt.Sprint(res), nil
+	}
+
+	res, err := runParamSearch("get mynode where name = :name and ranking > :min",
+		map[string]interface{}{"name": "Node1", "min": 3})
+	if err != nil || res != `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+456, Node1, 3.5
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Parameter values are never interpreted as part of the query
+
+	res, err = runParamSearch("get mynode where name = :name",
+		map[string]interface{}{"name": "Node0 or true"})
+	if err != nil || res != `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Slices can be used as lists
+
+	res, err = runParamSearch("get mynode where key in :keys",
+		map[string]interface{}{"keys": []string{"000", "456"}})
+	if err != nil || res != `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+456, Node1, 3.5
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = runParamSearch("get mynode where ranking in [:a, 2.1]",
+		map[string]interface{}{"a": 1})
+	if err != nil || res != `
+Labels: Mynode Key, Mynode Name, Ranking
+Format: auto, auto, auto
+Data: 1:n:key, 1:n:name, 1:n:ranking
+000, Node0, 1
+123, Node1, 2.1
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if _, err = runParamSearch("get mynode where name = :name", nil); err == nil || err.Error() !=
+		"Parse error in test: Unbound parameter (name) (Line:1 Pos:25)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func testSimpleOperationErrors(query string, rt *GetRuntimeProvider) error {
 	ast, err := parser.ParseWithRuntime("test", query, rt)
 	if err != nil {
@@ -0,0 +1,157 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package parser
+
+import (
+	"sync"
+
+	"devt.de/krotik/common/datautil"
+)
+
+/*
+PlanCacheMaxSize is the maximum number of prepared queries which are kept by
+ParseCached. The oldest entries are removed once the cache is full.
+*/
+const PlanCacheMaxSize = 1000
+
+/*
+PlanCacheMaxProviders is the maximum number of prepared queries of different
+runtime providers which are kept by ParseCached for the same input.
+*/
+const PlanCacheMaxProviders = 10
+
+/*
+planCache holds all prepared queries of ParseCached.
+*/
+var planCache = datautil.NewMapCache(PlanCacheMaxSize, 0)
+
+/*
+VersionedRuntimeProvider is a runtime provider which has a version stamp. The
+version must change whenever a change of the underlying data (e.g. new node
+kinds or attributes) could change the runtime components of a parse tree.
+Prepared queries of an older version are discarded by ParseCached.
+*/
+type VersionedRuntimeProvider interface {
+	RuntimeProvider
+
+	/*
+		Version returns the current version stamp of the runtime provider.
+	*/
+	Version() uint64
+}
+
+/*
+providerMutex serializes evaluations of prepared queries whose runtime
+provider cannot be locked.
+*/
+var providerMutex = &sync.Mutex{}
+
+/*
+PreparedQuery is a parsed query whose AST is decorated with runtime
+components. A prepared query can be evaluated several times.
+*/
+type PreparedQuery struct {
+	AST     *ASTNode        // AST of the query with runtime components
+	rp      RuntimeProvider // Runtime provider of the runtime components
+	version uint64          // Version of the runtime provider when the query was parsed
+}
+
+/*
+Eval evaluates the prepared query. The runtime components of a query keep
+their state in the runtime provider - evaluations of all prepared queries
+which share a runtime provider are therefore run one after another. The
+provider is locked during the evaluation if it implements sync.Locker
+otherwise all evaluations of such providers are serialized.
+*/
+func (q *PreparedQuery) Eval() (interface{}, error) {
+	lock := providerLock(q.rp)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	return q.AST.Runtime.Eval()
+}
+
+/*
+providerLock returns the lock which must be held while a given runtime
+provider is used.
+*/
+func providerLock(rp RuntimeProvider) sync.Locker {
+	if l, ok := rp.(sync.Locker); ok {
+		return l
+	}
+	return providerMutex
+}
+
+/*
+ParseCached parses a given input string like ParseWithRuntime and returns a
+prepared query. Prepared queries are cached by the input string and the
+runtime provider - parsing the same input with the same runtime provider
+again returns the cached query. Up to PlanCacheMaxProviders prepared queries
+of different runtime providers are kept for each input. If the runtime
+provider is a VersionedRuntimeProvider then a cached query is only returned
+if the version of the runtime provider did not change since the query was
+parsed. Inputs which cannot be parsed are not cached.
+*/
+func ParseCached(name string, input string, rp RuntimeProvider) (*PreparedQuery, error) {
+	var version uint64
+	var queries []*PreparedQuery
+
+	if vrp, ok := rp.(VersionedRuntimeProvider); ok {
+		version = vrp.Version()
+	}
+
+	if cached, ok := planCache.Get(input); ok {
+		queries = cached.([]*PreparedQuery)
+
+		for _, q := range queries {
+			if q.rp == rp && q.version == version {
+				return q, nil
+			}
+		}
+	}
+
+	// Runtime components are created while parsing - the provider must
+	// not be evaluating at the same time
+
+	lock := providerLock(rp)
+
+	lock.Lock()
+	ast, err := ParseWithRuntime(name, input, rp)
+	lock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	q := &PreparedQuery{ast, rp, version}
+
+	// Replace an outdated query of the same provider or the oldest query
+
+	newQueries := []*PreparedQuery{q}
+
+	for _, cq := range queries {
+		if cq.rp != rp && len(newQueries) < PlanCacheMaxProviders {
+			newQueries = append(newQueries, cq)
+		}
+	}
+
+	planCache.Put(input, newQueries)
+
+	return q, nil
+}
+
+/*
+ClearPlanCache removes all prepared queries from the cache of ParseCached.
+*/
+func ClearPlanCache() {
+	planCache.Clear()
+}
@@ -51,6 +51,7 @@ const (
 
 	TokenVALUE    // Simple value
 	TokenNODEKIND // Node kind value
+	TokenPARAM    // Bind parameter
 
 	TokenGeneral // General token used for plain ASTs
 
@@ -74,22 +75,37 @@ const (
 	TokenDIV
 	TokenDIVINT
 	TokenMODINT
+	TokenPOW
 
 	// The colon ':' has a context specific meaning and is not processed by the parser
 
 	TOKENodeKEYWORDS // Used to separate keywords from other tokens in this list
 
 	TokenGET
+	TokenDISTINCT
 	TokenLOOKUP
+	TokenPATH
+	TokenDESCRIBE
+	TokenCOUNT
+	TokenEXPLAIN
 	TokenFROM
+	TokenTO
+	TokenVIA
+	TokenMAXHOPS
 	TokenGROUP
+	TokenBY
+	TokenGROUPBY
 	TokenWITH
+	TokenLIMIT
+	TokenOFFSET
 	TokenLIST
 	TokenNULLTRAVERSAL
 	TokenFILTERING
 	TokenORDERING
+	TokenTYPE
 	TokenWHERE
 	TokenTRAVERSE
+	TokenREVERSE
 	TokenEND
 	TokenPRIMARY
 	TokenSHOW
@@ -98,13 +114,20 @@ const (
 	TokenAND
 	TokenOR
 	TokenLIKE
+	TokenMATCHES
 	TokenIN
 	TokenCONTAINS
 	TokenBEGINSWITH
 	TokenENDSWITH
 	TokenCONTAINSNOT
+	TokenCONTAINSWORD
 	TokenNOT
 	TokenNOTIN
+	TokenNOTLIKE
+	TokenNOTBEGINSWITH
+	TokenNOTENDSWITH
+	TokenCONTAINSALL
+	TokenBETWEEN
 	TokenFALSE
 	TokenTRUE
 	TokenUNIQUE
@@ -123,40 +146,56 @@ const (
 	NodeEOF = "EOF"
 
 	NodeVALUE         = "value"
+	NodePARAM         = "param"
 	NodeTRUE          = "true"
 	NodeFALSE         = "false"
 	NodeNULL          = "null"
 	NodeFUNC          = "func"
 	NodeORDERING      = "ordering"
 	NodeFILTERING     = "filtering"
+	NodeTYPE          = "type"
 	NodeNULLTRAVERSAL = "nulltraversal"
 
 	// Special tokens - always handled in a denotation function
 
 	NodeCOMMA  = "comma"
 	NodeGROUP  = "group"
+	NodeBY     = "by"
 	NodeEND    = "end"
 	NodeAS     = "as"
 	NodeFORMAT = "format"
+	NodeTO     = "to"
+	NodeVIA    = "via"
 
 	// Keywords
 
-	NodeGET    = "get"
-	NodeLOOKUP = "lookup"
-	NodeFROM   = "from"
-	NodeWHERE  = "where"
+	NodeGET      = "get"
+	NodeDISTINCT = "distinct"
+	NodeLOOKUP   = "lookup"
+	NodePATH     = "path"
+	NodeDESCRIBE = "describe"
+	NodeCOUNT    = "count"
+	NodeEXPLAIN  = "explain"
+	NodeFROM     = "from"
+	NodeWHERE    = "where"
+	NodeMAXHOPS  = "maxhops"
 
 	NodeUNIQUE      = "unique"
 	NodeUNIQUECOUNT = "uniquecount"
+	NodeISNULL      = "isnull"
 	NodeISNOTNULL   = "isnotnull"
 	NodeASCENDING   = "asc"
 	NodeDESCENDING  = "desc"
 
 	NodeTRAVERSE = "traverse"
+	NodeREVERSE  = "reverse"
 	NodePRIMARY  = "primary"
+	NodeGROUPBY  = "groupby"
 	NodeSHOW     = "show"
 	NodeSHOWTERM = "showterm"
 	NodeWITH     = "with"
+	NodeLIMIT    = "limit"
+	NodeOFFSET   = "offset"
 	NodeLIST     = "list"
 
 	// Boolean operations
@@ -174,17 +213,26 @@ const (
 
 	// List operations
 
-	NodeIN    = "in"
-	NodeNOTIN = "notin"
+	NodeIN          = "in"
+	NodeNOTIN       = "notin"
+	NodeCONTAINSALL = "containsall"
+	NodeBETWEEN     = "between"
 
 	// String operations
 
 	NodeLIKE        = "like"
+	NodeMATCHES     = "matches"
 	NodeCONTAINS    = "contains"
 	NodeBEGINSWITH  = "beginswith"
 	NodeENDSWITH    = "endswith"
 	NodeCONTAINSNOT = "containsnot"
 
+	NodeCONTAINSWORD = "containsword"
+
+	NodeNOTLIKE       = "notlike"
+	NodeNOTBEGINSWITH = "notbeginswith"
+	NodeNOTENDSWITH   = "notendswith"
+
 	// Simple arithmetic expressions
 
 	NodePLUS   = "plus"
@@ -193,6 +241,7 @@ const (
 	NodeDIV    = "div"
 	NodeMODINT = "modint"
 	NodeDIVINT = "divint"
+	NodePOW    = "pow"
 
 	// Brackets
 
@@ -51,6 +51,9 @@ func (t LexToken) String() string {
 	case t.ID == TokenError:
 		return fmt.Sprintf("Error: %s (%s)", t.Val, t.PosString())
 
+	case t.ID == TokenPARAM:
+		return ":" + t.Val
+
 	case t.ID > TOKENodeSYMBOLS && t.ID < TOKENodeKEYWORDS:
 		return fmt.Sprintf("%s", strings.ToUpper(t.Val))
 
@@ -73,14 +76,26 @@ Map of keywords - these require spaces between them
 var keywordMap = map[string]LexTokenID{
 	"get":           TokenGET,
 	"lookup":        TokenLOOKUP,
+	"path":          TokenPATH,
+	"describe":      TokenDESCRIBE,
+	"count":         TokenCOUNT,
+	"explain":       TokenEXPLAIN,
 	"from":          TokenFROM,
+	"to":            TokenTO,
+	"via":           TokenVIA,
+	"maxhops":       TokenMAXHOPS,
 	"group":         TokenGROUP,
+	"by":            TokenBY,
 	"with":          TokenWITH,
+	"limit":         TokenLIMIT,
+	"offset":        TokenOFFSET,
 	"filtering":     TokenFILTERING,
 	"ordering":      TokenORDERING,
 	"nulltraversal": TokenNULLTRAVERSAL,
+	"type":          TokenTYPE,
 	"where":         TokenWHERE,
 	"traverse":      TokenTRAVERSE,
+	"reverse":       TokenREVERSE,
 	"end":           TokenEND,
 	"primary":       TokenPRIMARY,
 	"show":          TokenSHOW,
@@ -89,18 +104,23 @@ var keywordMap = map[string]LexTokenID{
 	"and":           TokenAND,
 	"or":            TokenOR,
 	"like":          TokenLIKE,
+	"matches":       TokenMATCHES,
 	"in":            TokenIN,
 	"contains":      TokenCONTAINS,
 	"beginswith":    TokenBEGINSWITH,
 	"endswith":      TokenENDSWITH,
 	"containsnot":   TokenCONTAINSNOT,
+	"containsword":  TokenCONTAINSWORD,
 	"not":           TokenNOT,
 	"notin":         TokenNOTIN,
+	"containsall":   TokenCONTAINSALL,
+	"between":       TokenBETWEEN,
 	"false":         TokenFALSE,
 	"true":          TokenTRUE,
 	"unique":        TokenUNIQUE,
 	"uniquecount":   TokenUNIQUECOUNT,
 	"null":          TokenNULL,
+	"isnull":        TokenISNULL,
 	"isnotnull":     TokenISNOTNULL,
 	"ascending":     TokenASCENDING,
 	"descending":    TokenDESCENDING,
@@ -128,6 +148,7 @@ var symbolMap = map[string]LexTokenID{
 	"/":  TokenDIV,
 	"//": TokenDIVINT,
 	"%":  TokenMODINT,
+	"^":  TokenPOW,
 }
 
 // Lexer
@@ -155,6 +176,8 @@ type lexer struct {
 	width  int           // Width of last rune
 	start  int           // Start position of the current read token
 	scope  LexTokenID    // Current scope
+	last   LexTokenID    // Last emitted token
+	prev   LexTokenID    // Token emitted before the last token
 	tokens chan LexToken // Channel for lexer output
 }
 
@@ -163,22 +186,45 @@ FirstWord returns the first word of a given input.
 */
 func FirstWord(input string) string {
 	var word string
-	l := &lexer{"", input, 0, 0, 0, 0, 0, -1, nil}
 
-	if skipWhiteSpace(l) {
+	if words := FirstWords(input, 1); len(words) > 0 {
+		word = words[0]
+	}
+
+	return word
+}
+
+/*
+FirstWords returns up to n words from the start of a given input.
+*/
+func FirstWords(input string, n int) []string {
+	var words []string
+	l := &lexer{"", input, 0, 0, 0, 0, 0, -1, -1, -1, nil}
+
+	for len(words) < n && skipWhiteSpace(l) {
+
+		// Comments in front of or between the words are ignored
+
+		if isCommentStart(l) {
+			if !skipComment(l) {
+				break
+			}
+			continue
+		}
+
 		l.startNew()
 		lexTextBlock(l, false)
-		word = input[l.start:l.pos]
+		words = append(words, input[l.start:l.pos])
 	}
 
-	return word
+	return words
 }
 
 /*
 Lex lexes a given input. Returns a channel which contains tokens.
 */
 func Lex(name string, input string) chan LexToken {
-	l := &lexer{name, input, 0, 0, 0, 0, 0, -1, make(chan LexToken)}
+	l := &lexer{name, input, 0, 0, 0, 0, 0, -1, -1, -1, make(chan LexToken)}
 	go l.run()
 	return l.tokens
 }
@@ -271,6 +317,8 @@ func (l *lexer) emitToken(t LexTokenID) {
 		l.tokens <- LexToken{t, l.start, l.input[l.start:l.pos],
 			l.line + 1, l.start - l.lastnl + 1}
 	}
+
+	l.prev, l.last = l.last, t
 }
 
 /*
@@ -280,6 +328,8 @@ func (l *lexer) emitTokenAndValue(t LexTokenID, val string) {
 	if l.tokens != nil {
 		l.tokens <- LexToken{t, l.start, val, l.line + 1, l.start - l.lastnl + 1}
 	}
+
+	l.prev, l.last = l.last, t
 }
 
 /*
@@ -299,16 +349,21 @@ lexToken is the main entry function for the lexer.
 */
 func lexToken(l *lexer) lexFunc {
 
-	// Check if we got a quoted value or a comment
+	// Check if we got a comment
+
+	if isCommentStart(l) {
+		if !skipComment(l) {
+			return nil
+		}
+		return lexToken
+	}
+
+	// Check if we got a quoted value
 
 	n1 := l.next(false)
 	n2 := l.next(true)
 	l.backup()
 
-	if n1 == '#' {
-		return skipRestOfLine
-	}
-
 	if (n1 == '"' || n1 == '\'') || (n1 == 'r' && (n2 == '"' || n2 == '\'')) {
 		return lexValue
 	}
@@ -324,6 +379,69 @@ func lexToken(l *lexer) lexFunc {
 
 	token, ok := keywordMap[keywordCandidate]
 
+	// Count is only a keyword at the start of a query - elsewhere it can
+	// be the name of a function or an unquoted value
+
+	if ok && token == TokenCOUNT && l.scope != -1 {
+		ok = false
+	}
+
+	// Explain is only a keyword at the start of a query - elsewhere it can
+	// be an attribute name or an unquoted value
+
+	if ok && token == TokenEXPLAIN && l.scope != -1 {
+		ok = false
+	}
+
+	// Describe is only a keyword at the start of a query - elsewhere it can
+	// be an attribute name or an unquoted value
+
+	if ok && token == TokenDESCRIBE && l.scope != -1 {
+		ok = false
+	}
+
+	// Path is only a keyword at the start of a query and to, via and maxhops
+	// are only keywords inside a path query - elsewhere they can be
+	// attribute names or unquoted values
+
+	if ok && token == TokenPATH && l.scope != -1 {
+		ok = false
+	}
+
+	if ok && (token == TokenTO || token == TokenVIA || token == TokenMAXHOPS) &&
+		l.scope != TokenPATH {
+		ok = false
+	}
+
+	// Type is only a keyword for a directive of a with clause - elsewhere it
+	// can be an attribute name or an unquoted value
+
+	if ok && token == TokenTYPE && (l.scope != TokenWITH ||
+		!strings.HasPrefix(strings.TrimLeftFunc(l.input[l.pos:], unicode.IsSpace), "(")) {
+		ok = false
+	}
+
+	// By is only a keyword directly after the group of a group by clause -
+	// elsewhere it can be an attribute name, a group name or an unquoted value
+
+	if ok && token == TokenBY && (l.last != TokenGROUP || l.prev == TokenFROM) {
+		ok = false
+	}
+
+	// Reverse is only a keyword directly after traverse - elsewhere it can be
+	// an attribute name or an unquoted value
+
+	if ok && token == TokenREVERSE && l.last != TokenTRAVERSE {
+		ok = false
+	}
+
+	// Limit and offset are only keywords if they start a new clause after a
+	// complete term - elsewhere they can be attribute names or unquoted values
+
+	if ok && (token == TokenLIMIT || token == TokenOFFSET) && !l.afterTerm() {
+		ok = false
+	}
+
 	if !ok {
 		token, ok = symbolMap[keywordCandidate]
 	}
@@ -341,8 +459,25 @@ func lexToken(l *lexer) lexFunc {
 		case TokenLOOKUP:
 			l.scope = token
 			return lexNodeKind
+		case TokenPATH:
+			l.scope = token
+		case TokenDESCRIBE:
+			l.scope = token
+			return lexNodeKind
+		case TokenCOUNT:
+			l.scope = token
+			return lexNodeKind
+		case TokenWITH:
+			l.scope = token
 		}
 
+	} else if block := l.input[l.start:l.pos]; len(block) > 1 && block[0] == ':' &&
+		stringutil.IsAlphaNumeric(block[1:]) {
+
+		// A name prefixed with a colon is a bind parameter
+
+		l.emitTokenAndValue(TokenPARAM, block[1:])
+
 	} else {
 
 		// An unknown token was found - it must be an unquoted value
@@ -355,30 +490,37 @@ func lexToken(l *lexer) lexFunc {
 }
 
 /*
-skipRestOfLine skips all characters until the next newline character.
+lexNodeKind lexes a node kind string.
 */
-func skipRestOfLine(l *lexer) lexFunc {
-	r := l.next(false)
+func lexNodeKind(l *lexer) lexFunc {
 
-	for r != '\n' && r != RuneEOF {
-		r = l.next(false)
+	if isCommentStart(l) {
+		if !skipComment(l) {
+			return nil
+		}
+		return lexNodeKind
 	}
 
-	if r == RuneEOF {
-		return nil
-	}
+	// Node kinds of get and count queries can be followed by a comma
 
-	return lexToken
-}
+	multiKind := l.scope == TokenGET || l.scope == TokenCOUNT
 
-/*
-lexNodeKind lexes a node kind string.
-*/
-func lexNodeKind(l *lexer) lexFunc {
 	l.startNew()
 	lexTextBlock(l, false)
 
+	if i := strings.IndexRune(l.input[l.start:l.pos], ','); multiKind && i > 0 {
+		l.pos = l.start + i
+	}
+
 	nodeKindCandidate := strings.ToLower(l.input[l.start:l.pos])
+
+	// A get query can have a distinct modifier before the node kind
+
+	if l.scope == TokenGET && nodeKindCandidate == "distinct" {
+		l.emitToken(TokenDISTINCT)
+		return lexNodeKind
+	}
+
 	if !stringutil.IsAlphaNumeric(nodeKindCandidate) {
 		l.emitError("Invalid node kind " + fmt.Sprintf("'%v'", nodeKindCandidate) +
 			" - can only contain [a-zA-Z0-9_]")
@@ -387,13 +529,51 @@ func lexNodeKind(l *lexer) lexFunc {
 
 	l.emitToken(TokenNODEKIND)
 
-	if l.scope == TokenGET {
+	if multiKind {
+		return lexNodeKindList
+	} else if l.scope == TokenDESCRIBE {
 		return lexToken
 	}
 
-	// In a lookup scope more values are following
+	// In a lookup scope more values or a list of values are following
+
+	return lexLookupKeys
+}
+
+/*
+lexLookupKeys lexes the node keys of a lookup query. The keys are either
+values separated by commas or a list of values.
+*/
+func lexLookupKeys(l *lexer) lexFunc {
+
+	if isCommentStart(l) {
+		if !skipComment(l) {
+			return nil
+		}
+		return lexLookupKeys
+	}
+
+	if l.next(true) == '[' {
+		return lexToken(l)
+	}
+
+	return lexValue(l)
+}
+
+/*
+lexNodeKindList lexes the comma which separates several node kinds.
+*/
+func lexNodeKindList(l *lexer) lexFunc {
+
+	if l.next(true) != ',' {
+		return lexToken(l)
+	}
+
+	l.startNew()
+	l.next(false)
+	l.emitToken(TokenCOMMA)
 
-	return lexValue
+	return lexNodeKind
 }
 
 /*
@@ -410,6 +590,13 @@ Characters are parsed plain between quote
 func lexValue(l *lexer) lexFunc {
 	var endToken rune
 
+	if isCommentStart(l) {
+		if !skipComment(l) {
+			return nil
+		}
+		return lexValue
+	}
+
 	l.startNew()
 	allowEscapes := false
 
@@ -485,6 +672,19 @@ func lexValue(l *lexer) lexFunc {
 // Helper functions
 // ================
 
+/*
+afterTerm checks if the last emitted token completed a term.
+*/
+func (l *lexer) afterTerm() bool {
+	switch l.last {
+	case TokenVALUE, TokenNODEKIND, TokenPARAM, TokenRPAREN, TokenRBRACK,
+		TokenTRUE, TokenFALSE, TokenNULL, TokenEND:
+		return true
+	}
+
+	return false
+}
+
 /*
 skipWhiteSpace skips any number of whitespace characters. Returns false if the parser
 reaches EOF while skipping whitespaces.
@@ -508,6 +708,66 @@ func skipWhiteSpace(l *lexer) bool {
 	return true
 }
 
+/*
+isCommentStart checks if a comment starts at the current position. Comments
+are either line comments starting with # or block comments which start with
+a slash and an asterisk and end with an asterisk and a slash.
+*/
+func isCommentStart(l *lexer) bool {
+	n1 := l.next(false)
+	n2 := l.next(true)
+	l.backup()
+
+	return n1 == '#' || (n1 == '/' && n2 == '*')
+}
+
+/*
+skipComment skips a comment which starts at the current position. Line
+comments end with the next newline character. Returns false if a block
+comment is not terminated.
+*/
+func skipComment(l *lexer) bool {
+	l.startNew()
+	lLine := l.line
+	lLastnl := l.lastnl
+
+	r := l.next(false)
+	blockComment := r == '/'
+
+	if blockComment {
+		l.next(false)
+	}
+
+	for r = l.next(false); r != RuneEOF; r = l.next(false) {
+
+		if r == '\n' {
+			l.line++
+			l.lastnl = l.pos
+
+			if !blockComment {
+				return true
+			}
+
+		} else if blockComment && r == '*' && l.next(true) == '/' {
+			l.next(false)
+			return true
+		}
+	}
+
+	if blockComment {
+
+		// Report the error at the start of the comment
+
+		l.line = lLine
+		l.lastnl = lLastnl
+
+		l.emitError("Unexpected end while reading comment")
+		return false
+	}
+
+	return true
+}
+
 /*
 lexTextBlock lexes a block of text without whitespaces. Interprets
 optionally all one or two letter tokens.
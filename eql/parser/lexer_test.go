@@ -95,6 +95,97 @@ func TestSimpleLexing(t *testing.T) {
 		return
 	}
 
+	// Test distinct modifier
+
+	input = "GET Distinct mynode WHERE distinct = 1"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> <DISTINCT> "mynode" <WHERE> "distinct" = "1" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test several node kinds
+
+	input = "GET Distinct Song,Album , Author WHERE count = 1"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> <DISTINCT> "Song" , "Album" , "Author" <WHERE> "count" = "1" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test type directive which is only a keyword in a with clause
+
+	input = "GET mynode WHERE type = type show type with type (type, 'int'), ordering(ascending type)"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> "mynode" <WHERE> "type" = "type" <SHOW> "type" <WITH> <TYPE> ( "type" , "int" ) , <ORDERING> ( <ASCENDING> "type" ) EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test path keywords which are only keywords inside a path query
+
+	input = "PATH from a:1 TO b:2 via ::: maxhops 3"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<PATH> <FROM> "a:1" <TO> "b:2" <VIA> ":::" <MAXHOPS> "3" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = "GET mynode WHERE path = path and to = 1 and via = maxhops show to"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> "mynode" <WHERE> "path" = "path" <AND> "to" = "1" <AND> "via" = "maxhops" <SHOW> "to" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test limit and offset which are only keywords after a complete term
+
+	input = "GET mynode WHERE limit > 1 and offset = limit show offset limit 2 OFFSET 1"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> "mynode" <WHERE> "limit" > "1" <AND> "offset" = "limit" <SHOW> "offset" <LIMIT> "2" <OFFSET> "1" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test by which is only a keyword after the group of a group by clause
+
+	input = "GET mynode FROM group by WHERE by = 'x' GROUP BY by"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> "mynode" <FROM> <GROUP> "by" <WHERE> "by" = "x" <GROUP> <BY> "by" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test reverse which is only a keyword directly after traverse
+
+	input = "GET mynode WHERE reverse = 'x' TRAVERSE REVERSE ::: where reverse = reverse end"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> "mynode" <WHERE> "reverse" = "x" <TRAVERSE> <REVERSE> ":::" <WHERE> "reverse" = "reverse" <END> EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test describe which is only a keyword at the start of a query
+
+	input = "LOOKUP mynode 'a' WHERE describe = 1"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<LOOKUP> "mynode" "a" <WHERE> "describe" = "1" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = "COUNT Song, Album"
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<COUNT> "Song" , "Album" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = "GET Song, my@node where x"
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		"[<GET> \"Song\" , Error: Invalid node kind 'my@node' - can only contain [a-zA-Z0-9_] (Line 1, Pos 11)]" {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Test bind parameters
+
+	input = `GET mynode WHERE name = :name and key = ":key" traverse :::`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<GET> "mynode" <WHERE> "name" = :name <AND> "key" = ":key" <TRAVERSE> ":::" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
 	// Test unquoted value parsing
 
 	input = `GET mynode WHERE name = "myname:x"`
@@ -130,6 +221,42 @@ WHERE a = b
 		return
 	}
 
+	input = `GET /* kind */ mynode WHERE a/*x*/= "#b" # c
+/* multi
+   line */ AND b = 'c /* d */'`
+	res := LexToList("mytest", input)
+	if fmt.Sprint(res) != `[<GET> "mynode" <WHERE> "a" = "#b" <AND> "b" = "c /* d */" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	// Positions after comments are preserved
+
+	if res[6].PosString() != "Line 3, Pos 12" {
+		t.Error("Unexpected position:", res[6].PosString())
+		return
+	}
+
+	input = `GET mynode /* unterminated
+WHERE a = b`
+	if res := LexToList("mytest", input); fmt.Sprint(res) !=
+		`[<GET> "mynode" Error: Unexpected end while reading comment (Line 1, Pos 12) EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = `LOOKUP mynode /* key */ "a" # end`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<LOOKUP> "mynode" "a" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
+	input = `LOOKUP mynode /* keys */ ["a", 'b',c] where x = 1`
+	if res := LexToList("mytest", input); fmt.Sprint(res) != `[<LOOKUP> "mynode" [ "a" , "b" , "c" ] <WHERE> "x" = "1" EOF]` {
+		t.Error("Unexpected lexer result:", res)
+		return
+	}
+
 	// Test traversal
 
 	input = `GET mynode WHERE Author = rabatt TRAVERSE Song:PerformedSong:Author:Author WHERE Author = 6 # This is a comment
@@ -170,6 +297,26 @@ func TestValueParsing(t *testing.T) {
 		return
 	}
 
+	if res := FirstWord("# comment\n /* more */ get Song"); res != "get" {
+		t.Error("Unexpected first word:", res)
+		return
+	}
+
+	if res := FirstWord("/* get Song"); res != "" {
+		t.Error("Unexpected first word:", res)
+		return
+	}
+
+	if res := FirstWords("explain /* plan */ GET Song", 2); fmt.Sprint(res) != "[explain GET]" {
+		t.Error("Unexpected first words:", res)
+		return
+	}
+
+	if res := FirstWords("explain", 2); fmt.Sprint(res) != "[explain]" {
+		t.Error("Unexpected first words:", res)
+		return
+	}
+
 	// Test normal quoted case
 
 	input := `WHERE "name"`
@@ -263,7 +410,7 @@ func TestValueParsing(t *testing.T) {
 
 func TestLexerInputControl(t *testing.T) {
 
-	test := &lexer{"test", "test x\xe2\x8c\x98c", 0, 0, 0, 0, 0, -1, nil}
+	test := &lexer{"test", "test x\xe2\x8c\x98c", 0, 0, 0, 0, 0, -1, -1, -1, nil}
 
 	if r := test.next(false); r != 't' {
 		t.Error("Unexpected first rune:", r)
@@ -13,6 +13,10 @@ package parser
 import (
 	"bytes"
 	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
 
 	"devt.de/krotik/common/stringutil"
 )
@@ -146,7 +150,7 @@ func (n *ASTNode) levelString(indent int, buf *bytes.Buffer) {
 
 	buf.WriteString(stringutil.GenerateRollingString(" ", indent*2))
 
-	if n.Name == NodeVALUE || (n.Name == NodeSHOWTERM && n.Token.Val != "@") {
+	if n.Name == NodeVALUE || n.Name == NodePARAM || (n.Name == NodeSHOWTERM && n.Token.Val != "@") {
 		buf.WriteString(fmt.Sprintf(n.Name+": %v", n.Token))
 	} else {
 		buf.WriteString(n.Name)
@@ -177,6 +181,7 @@ func init() {
 		TokenEOF:           {NodeEOF, nil, nil, nil, 0, ndTerm, nil},
 		TokenVALUE:         {NodeVALUE, nil, nil, nil, 0, ndTerm, nil},
 		TokenNODEKIND:      {NodeVALUE, nil, nil, nil, 0, ndTerm, nil},
+		TokenPARAM:         {NodePARAM, nil, nil, nil, 0, ndParam, nil},
 		TokenTRUE:          {NodeTRUE, nil, nil, nil, 0, ndTerm, nil},
 		TokenFALSE:         {NodeFALSE, nil, nil, nil, 0, ndTerm, nil},
 		TokenNULL:          {NodeNULL, nil, nil, nil, 0, ndTerm, nil},
@@ -184,33 +189,49 @@ func init() {
 		TokenORDERING:      {NodeORDERING, nil, nil, nil, 0, ndWithFunc, nil},
 		TokenFILTERING:     {NodeFILTERING, nil, nil, nil, 0, ndWithFunc, nil},
 		TokenNULLTRAVERSAL: {NodeNULLTRAVERSAL, nil, nil, nil, 0, ndWithFunc, nil},
+		TokenTYPE:          {NodeTYPE, nil, nil, nil, 0, ndWithFunc, nil},
+		TokenFORMAT:        {NodeFORMAT, nil, nil, nil, 0, ndWithFunc, nil},
 
 		// Special tokens - always handled in a denotation function
 
-		TokenCOMMA:  {NodeCOMMA, nil, nil, nil, 0, nil, nil},
-		TokenGROUP:  {NodeGROUP, nil, nil, nil, 0, nil, nil},
-		TokenEND:    {NodeEND, nil, nil, nil, 0, nil, nil},
-		TokenAS:     {NodeAS, nil, nil, nil, 0, nil, nil},
-		TokenFORMAT: {NodeFORMAT, nil, nil, nil, 0, nil, nil},
+		TokenCOMMA:   {NodeCOMMA, nil, nil, nil, 0, nil, nil},
+		TokenGROUP:   {NodeGROUP, nil, nil, nil, 0, ndGroupBy, nil},
+		TokenBY:      {NodeBY, nil, nil, nil, 0, nil, nil},
+		TokenEND:     {NodeEND, nil, nil, nil, 0, nil, nil},
+		TokenREVERSE: {NodeREVERSE, nil, nil, nil, 0, nil, nil},
+		TokenAS:      {NodeAS, nil, nil, nil, 0, nil, nil},
+		TokenTO:      {NodeTO, nil, nil, nil, 0, nil, nil},
+		TokenVIA:     {NodeVIA, nil, nil, nil, 0, nil, nil},
+
+		TokenDISTINCT: {NodeDISTINCT, nil, nil, nil, 0, nil, nil},
 
 		// Keywords
 
-		TokenGET:    {NodeGET, nil, nil, nil, 0, ndGet, nil},
-		TokenLOOKUP: {NodeLOOKUP, nil, nil, nil, 0, ndLookup, nil},
-		TokenFROM:   {NodeFROM, nil, nil, nil, 0, ndFrom, nil},
-		TokenWHERE:  {NodeWHERE, nil, nil, nil, 0, ndPrefix, nil},
+		TokenGET:      {NodeGET, nil, nil, nil, 0, ndGet, nil},
+		TokenLOOKUP:   {NodeLOOKUP, nil, nil, nil, 0, ndLookup, nil},
+		TokenPATH:     {NodePATH, nil, nil, nil, 0, ndPath, nil},
+		TokenDESCRIBE: {NodeDESCRIBE, nil, nil, nil, 0, ndDescribe, nil},
+		TokenCOUNT:    {NodeCOUNT, nil, nil, nil, 0, ndCount, nil},
+		TokenEXPLAIN:  {NodeEXPLAIN, nil, nil, nil, 0, ndExplain, nil},
+		TokenFROM:     {NodeFROM, nil, nil, nil, 0, ndFrom, nil},
+		TokenWHERE:    {NodeWHERE, nil, nil, nil, 0, ndPrefix, nil},
+		TokenMAXHOPS:  {NodeMAXHOPS, nil, nil, nil, 0, nil, nil},
 
 		TokenUNIQUE:      {NodeUNIQUE, nil, nil, nil, 0, ndPrefix, nil},
 		TokenUNIQUECOUNT: {NodeUNIQUECOUNT, nil, nil, nil, 0, ndPrefix, nil},
-		TokenISNOTNULL:   {NodeISNOTNULL, nil, nil, nil, 0, ndPrefix, nil},
+		TokenISNULL:      {NodeISNULL, nil, nil, nil, 60, ndPrefix, nil},
+		TokenISNOTNULL:   {NodeISNOTNULL, nil, nil, nil, 60, ndPrefix, nil},
 		TokenASCENDING:   {NodeASCENDING, nil, nil, nil, 0, ndPrefix, nil},
 		TokenDESCENDING:  {NodeDESCENDING, nil, nil, nil, 0, ndPrefix, nil},
 
 		TokenTRAVERSE: {NodeTRAVERSE, nil, nil, nil, 0, ndTraverse, nil},
 		TokenPRIMARY:  {NodePRIMARY, nil, nil, nil, 0, ndPrefix, nil},
+		TokenGROUPBY:  {NodeGROUPBY, nil, nil, nil, 0, nil, nil},
 		TokenSHOW:     {NodeSHOW, nil, nil, nil, 0, ndShow, nil},
 		TokenSHOWTERM: {NodeSHOWTERM, nil, nil, nil, 0, ndShow, nil},
 		TokenWITH:     {NodeWITH, nil, nil, nil, 0, ndWith, nil},
+		TokenLIMIT:    {NodeLIMIT, nil, nil, nil, 0, ndLimit, nil},
+		TokenOFFSET:   {NodeOFFSET, nil, nil, nil, 0, ndLimit, nil},
 		TokenLIST:     {NodeLIST, nil, nil, nil, 0, nil, nil},
 
 		// Boolean operations
@@ -227,12 +248,21 @@ func init() {
 		TokenLT:  {NodeLT, nil, nil, nil, 60, nil, ldInfix},
 
 		TokenLIKE:        {NodeLIKE, nil, nil, nil, 60, nil, ldInfix},
+		TokenMATCHES:     {NodeMATCHES, nil, nil, nil, 60, nil, ldInfix},
 		TokenIN:          {NodeIN, nil, nil, nil, 60, nil, ldInfix},
 		TokenCONTAINS:    {NodeCONTAINS, nil, nil, nil, 60, nil, ldInfix},
 		TokenBEGINSWITH:  {NodeBEGINSWITH, nil, nil, nil, 60, nil, ldInfix},
 		TokenENDSWITH:    {NodeENDSWITH, nil, nil, nil, 60, nil, ldInfix},
 		TokenCONTAINSNOT: {NodeCONTAINSNOT, nil, nil, nil, 60, nil, ldInfix},
 		TokenNOTIN:       {NodeNOTIN, nil, nil, nil, 60, nil, ldInfix},
+		TokenCONTAINSALL: {NodeCONTAINSALL, nil, nil, nil, 60, nil, ldInfix},
+		TokenBETWEEN:     {NodeBETWEEN, nil, nil, nil, 60, nil, ldBetween},
+
+		TokenCONTAINSWORD: {NodeCONTAINSWORD, nil, nil, nil, 60, nil, ldInfix},
+
+		TokenNOTLIKE:       {NodeNOTLIKE, nil, nil, nil, 60, nil, ldInfix},
+		TokenNOTBEGINSWITH: {NodeNOTBEGINSWITH, nil, nil, nil, 60, nil, ldInfix},
+		TokenNOTENDSWITH:   {NodeNOTENDSWITH, nil, nil, nil, 60, nil, ldInfix},
 
 		// Simple arithmetic expressions
 
@@ -242,6 +272,7 @@ func init() {
 		TokenDIV:    {NodeDIV, nil, nil, nil, 120, nil, ldInfix},
 		TokenMODINT: {NodeMODINT, nil, nil, nil, 120, nil, ldInfix},
 		TokenDIVINT: {NodeDIVINT, nil, nil, nil, 120, nil, ldInfix},
+		TokenPOW:    {NodePOW, nil, nil, nil, 130, nil, ldInfixRight},
 
 		// Brackets
 
@@ -255,14 +286,31 @@ func init() {
 // Parser
 // ======
 
+/*
+LogWarning is used to log warnings of the parser
+*/
+var LogWarning = log.Print
+
 /*
 Parser data structure
 */
 type parser struct {
-	name   string          // Name to identify the input
-	node   *ASTNode        // Current ast node
-	tokens chan LexToken   // Channel which contains lex tokens
-	rp     RuntimeProvider // Runtime provider which creates runtime components
+	name      string                 // Name to identify the input
+	node      *ASTNode               // Current ast node
+	tokens    chan LexToken          // Channel which contains lex tokens
+	rp        RuntimeProvider        // Runtime provider which creates runtime components
+	lookahead *LexToken              // Token which was read ahead
+	params    map[string]interface{} // Values of bind parameters
+}
+
+/*
+Operators which can be combined with a preceding not into a single operator
+*/
+var notOperatorMap = map[LexTokenID]LexTokenID{
+	TokenIN:         TokenNOTIN,
+	TokenLIKE:       TokenNOTLIKE,
+	TokenBEGINSWITH: TokenNOTBEGINSWITH,
+	TokenENDSWITH:   TokenNOTENDSWITH,
 }
 
 /*
@@ -277,7 +325,20 @@ ParseWithRuntime parses a given input string and returns an AST decorated with
 runtime components.
 */
 func ParseWithRuntime(name string, input string, rp RuntimeProvider) (*ASTNode, error) {
-	p := &parser{name, nil, Lex(name, input), rp}
+	return ParseWithParams(name, input, nil, rp)
+}
+
+/*
+ParseWithParams parses a given input string with bind parameters (e.g. :name)
+and returns an AST decorated with runtime components. The runtime component of
+each parameter is given the value of the parameter. All parameters of the
+input must have a value. Parameters which are not used in the input are
+reported with LogWarning.
+*/
+func ParseWithParams(name string, input string, params map[string]interface{},
+	rp RuntimeProvider) (*ASTNode, error) {
+
+	p := &parser{name, nil, Lex(name, input), rp, nil, params}
 
 	node, err := p.next()
 
@@ -287,7 +348,39 @@ func ParseWithRuntime(name string, input string, rp RuntimeProvider) (*ASTNode,
 
 	p.node = node
 
-	return p.run(0)
+	ast, err := p.run(0)
+
+	if err == nil && len(params) > 0 {
+		used := make(map[string]bool)
+		collectParams(ast, used)
+
+		unused := make([]string, 0)
+		for param := range params {
+			if !used[param] {
+				unused = append(unused, param)
+			}
+		}
+
+		if len(unused) > 0 {
+			sort.Strings(unused)
+			LogWarning(fmt.Sprintf("Unused parameters in %v: %v", name, strings.Join(unused, ", ")))
+		}
+	}
+
+	return ast, err
+}
+
+/*
+collectParams collects the names of all bind parameters in a given AST.
+*/
+func collectParams(ast *ASTNode, params map[string]bool) {
+	if ast.Name == NodePARAM {
+		params[ast.Token.Val] = true
+	}
+
+	for _, child := range ast.Children {
+		collectParams(child, params)
+	}
 }
 
 /*
@@ -352,8 +445,29 @@ func (p *parser) run(rightBinding int) (*ASTNode, error) {
 next retrieves the next lexer token.
 */
 func (p *parser) next() (*ASTNode, error) {
+	var token LexToken
+	var more bool
+
+	if p.lookahead != nil {
+		token, more = *p.lookahead, true
+		p.lookahead = nil
+	} else {
+		token, more = <-p.tokens
+	}
 
-	token, more := <-p.tokens
+	if more && token.ID == TokenNOT {
+
+		// Check if the not is part of an operator (e.g. not like) - otherwise
+		// the not is a prefix and the next token is kept for the next call
+
+		if ahead, ok := <-p.tokens; ok {
+			if id, ok := notOperatorMap[ahead.ID]; ok {
+				token = LexToken{id, token.Pos, token.Val + " " + ahead.Val, token.Lline, token.Lpos}
+			} else {
+				p.lookahead = &ahead
+			}
+		}
+	}
 
 	if !more {
 
@@ -403,6 +517,26 @@ func ndInner(p *parser, self *ASTNode) (*ASTNode, error) {
 	return exp, skipToken(p, TokenRPAREN)
 }
 
+/*
+ndParam is used for bind parameters. If the AST is decorated with runtime
+components then the parameter must have a value.
+*/
+func ndParam(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	if p.rp != nil {
+		val, ok := p.params[self.Token.Val]
+		if !ok {
+			return nil, p.newParserError(ErrUnboundParam, self.Token.Val, *self.Token)
+		}
+
+		if paramRuntime, ok := self.Runtime.(ParamRuntime); ok {
+			paramRuntime.SetValue(val)
+		}
+	}
+
+	return self, nil
+}
+
 /*
 ndPrefix is used for prefix operators.
 */
@@ -427,13 +561,35 @@ func ndPrefix(p *parser, self *ASTNode) (*ASTNode, error) {
 ndGet is used to parse lookup expressions.
 */
 func ndGet(p *parser, self *ASTNode) (*ASTNode, error) {
+	var distinct *ASTNode
 
-	// Must specify a node kind
+	// Check if the result should only contain distinct rows
+
+	if p.node.Token.ID == TokenDISTINCT {
+		distinct = p.node
+		skipToken(p, TokenDISTINCT)
+	}
+
+	// Must specify at least one node kind
 
 	if err := acceptChild(p, self, TokenNODEKIND); err != nil {
 		return nil, err
 	}
 
+	// Read all commas and accept further node kinds
+
+	for skipToken(p, TokenCOMMA) == nil {
+		if err := acceptChild(p, self, TokenNODEKIND); err != nil {
+			return nil, err
+		}
+	}
+
+	// The distinct flag is always the first child after the node kinds
+
+	if distinct != nil {
+		self.Children = append(self.Children, distinct)
+	}
+
 	// Parse the rest and add it as children
 
 	for p.node.Token.ID != TokenEOF {
@@ -449,28 +605,113 @@ func ndGet(p *parser, self *ASTNode) (*ASTNode, error) {
 }
 
 /*
-ndLookup is used to parse lookup expressions.
+ndCount is used to parse count expressions.
 */
-func ndLookup(p *parser, self *ASTNode) (*ASTNode, error) {
+func ndCount(p *parser, self *ASTNode) (*ASTNode, error) {
 
-	// Must specify a node kind
+	// Must specify at least one node kind
 
 	if err := acceptChild(p, self, TokenNODEKIND); err != nil {
 		return nil, err
 	}
 
-	// Must have at least on node key
+	// Read all commas and accept further node kinds
 
-	if err := acceptChild(p, self, TokenVALUE); err != nil {
+	for skipToken(p, TokenCOMMA) == nil {
+		if err := acceptChild(p, self, TokenNODEKIND); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse the rest and add it as children
+
+	for p.node.Token.ID != TokenEOF {
+		exp, err := p.run(0)
+		if err != nil {
+			return nil, err
+		}
+
+		self.Children = append(self.Children, exp)
+	}
+
+	return self, nil
+}
+
+/*
+ndExplain is used to parse explain expressions.
+*/
+func ndExplain(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	// Only get, count and lookup queries can be explained
+
+	switch p.node.Token.ID {
+	case TokenGET, TokenCOUNT, TokenLOOKUP:
+	default:
+		return nil, p.newParserError(ErrUnexpectedToken, p.node.Token.Val, *p.node.Token)
+	}
+
+	// The explained query is the only child (it is parsed to the end)
+
+	exp, err := p.run(0)
+	if err != nil {
 		return nil, err
 	}
 
-	// Read all commas and accept further values as additional node keys
+	self.Children = append(self.Children, exp)
+
+	return self, nil
+}
+
+/*
+ndLookup is used to parse lookup expressions. The node keys are either
+values separated by commas or a list of values.
+*/
+func ndLookup(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	// Must specify a node kind
+
+	if err := acceptChild(p, self, TokenNODEKIND); err != nil {
+		return nil, err
+	}
+
+	if p.node.Token.ID == TokenLBRACK {
+
+		// Node keys can be given as a list of values
+
+		bracket := p.node
+
+		if err := skipToken(p, TokenLBRACK); err != nil {
+			return nil, err
+		}
+
+		list, err := ndList(p, bracket)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range list.Children {
+			if child.Token.ID != TokenVALUE {
+				return nil, p.newParserError(ErrUnexpectedToken, child.Token.Val, *child.Token)
+			}
+		}
+
+		self.Children = append(self.Children, list)
+
+	} else {
+
+		// Must have at least on node key
 
-	for skipToken(p, TokenCOMMA) == nil {
 		if err := acceptChild(p, self, TokenVALUE); err != nil {
 			return nil, err
 		}
+
+		// Read all commas and accept further values as additional node keys
+
+		for skipToken(p, TokenCOMMA) == nil {
+			if err := acceptChild(p, self, TokenVALUE); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Parse the rest and add it as children
@@ -487,6 +728,82 @@ func ndLookup(p *parser, self *ASTNode) (*ASTNode, error) {
 	return self, nil
 }
 
+/*
+ndPath is used to parse path expressions.
+*/
+func ndPath(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	// Must specify a start node
+
+	if err := skipToken(p, TokenFROM); err != nil {
+		return nil, err
+	}
+
+	if err := acceptChild(p, self, TokenVALUE); err != nil {
+		return nil, err
+	}
+
+	// Must specify an end node
+
+	if err := skipToken(p, TokenTO); err != nil {
+		return nil, err
+	}
+
+	if err := acceptChild(p, self, TokenVALUE); err != nil {
+		return nil, err
+	}
+
+	// Must specify a traversal spec
+
+	if err := skipToken(p, TokenVIA); err != nil {
+		return nil, err
+	}
+
+	if err := acceptChild(p, self, TokenVALUE); err != nil {
+		return nil, err
+	}
+
+	// Parse an optional maximum number of hops
+
+	if p.node.Token.ID == TokenMAXHOPS {
+
+		current := p.node
+		acceptChild(p, self, TokenMAXHOPS)
+
+		if err := acceptChild(p, current, TokenVALUE); err != nil {
+			return nil, err
+		}
+	}
+
+	// Nothing else may follow
+
+	if p.node.Token.ID != TokenEOF {
+		return nil, p.newParserError(ErrUnexpectedToken, p.node.Token.Val, *p.node.Token)
+	}
+
+	return self, nil
+}
+
+/*
+ndDescribe is used to parse describe expressions.
+*/
+func ndDescribe(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	// Must specify a node or edge kind
+
+	if err := acceptChild(p, self, TokenNODEKIND); err != nil {
+		return nil, err
+	}
+
+	// Nothing else may follow
+
+	if p.node.Token.ID != TokenEOF {
+		return nil, p.newParserError(ErrUnexpectedToken, p.node.Token.Val, *p.node.Token)
+	}
+
+	return self, nil
+}
+
 /*
 ndFrom is used to parse from group ... expressions.
 */
@@ -507,6 +824,14 @@ func ndFrom(p *parser, self *ASTNode) (*ASTNode, error) {
 ndTraverse is used to parse traverse expressions.
 */
 func ndTraverse(p *parser, self *ASTNode) (*ASTNode, error) {
+	var reverse *ASTNode
+
+	// Check if the traversal should be done in reverse
+
+	if p.node.Token.ID == TokenREVERSE {
+		reverse = p.node
+		skipToken(p, TokenREVERSE)
+	}
 
 	// Must be followed by traversal spec
 
@@ -514,6 +839,12 @@ func ndTraverse(p *parser, self *ASTNode) (*ASTNode, error) {
 		return nil, err
 	}
 
+	// The reverse flag is always the second child after the spec
+
+	if reverse != nil {
+		self.Children = append(self.Children, reverse)
+	}
+
 	// Parse the rest and add it as children - must end with "end" if
 	// further clauses are given
 
@@ -572,6 +903,38 @@ func ndFunc(p *parser, self *ASTNode) (*ASTNode, error) {
 	return self, skipToken(p, TokenRPAREN)
 }
 
+/*
+ndGroupBy is used to parse group by clauses.
+*/
+func ndGroupBy(p *parser, self *ASTNode) (*ASTNode, error) {
+
+	// Create a group by token
+
+	st := astNodeMap[TokenGROUPBY].instance(p, self.Token)
+
+	// Must be followed by a by keyword
+
+	if err := skipToken(p, TokenBY); err != nil {
+		return nil, err
+	}
+
+	// Must have at least one grouping attribute
+
+	if err := acceptChild(p, st, TokenVALUE); err != nil {
+		return nil, err
+	}
+
+	// Read all commas and accept further grouping attributes
+
+	for skipToken(p, TokenCOMMA) == nil {
+		if err := acceptChild(p, st, TokenVALUE); err != nil {
+			return nil, err
+		}
+	}
+
+	return st, nil
+}
+
 /*
 ndShow is used to parse a show clauses.
 */
@@ -651,9 +1014,11 @@ ndWith is used to parse a with clauses.
 */
 func ndWith(p *parser, self *ASTNode) (*ASTNode, error) {
 
-	// Parse the rest and add it as children
+	// Parse the rest and add it as children - only limit and offset
+	// clauses can follow
 
-	for p.node.Token.ID != TokenEOF {
+	for p.node.Token.ID != TokenEOF && p.node.Token.ID != TokenLIMIT &&
+		p.node.Token.ID != TokenOFFSET {
 		exp, err := p.run(0)
 		if err != nil {
 			return nil, err
@@ -669,6 +1034,29 @@ func ndWith(p *parser, self *ASTNode) (*ASTNode, error) {
 	return self, nil
 }
 
+/*
+ndLimit is used to parse limit and offset clauses.
+*/
+func ndLimit(p *parser, self *ASTNode) (*ASTNode, error) {
+	current := p.node
+
+	// Must have a number of rows
+
+	if err := acceptChild(p, self, TokenVALUE); err != nil {
+		return nil, err
+	}
+
+	// The number of rows must be a non-negative integer
+
+	if n, err := strconv.Atoi(current.Token.Val); err != nil || n < 0 {
+		return nil, p.newParserError(ErrInvalidNumber,
+			fmt.Sprintf("%v must be a non-negative integer: %v", self.Name, current.Token.Val),
+			*current.Token)
+	}
+
+	return self, nil
+}
+
 /*
 ndWithFunc is used to parse directives in with clauses.
 */
@@ -752,6 +1140,49 @@ func ldInfix(p *parser, self *ASTNode, left *ASTNode) (*ASTNode, error) {
 	return self, nil
 }
 
+/*
+ldInfixRight is used for right-associative infix operators.
+*/
+func ldInfixRight(p *parser, self *ASTNode, left *ASTNode) (*ASTNode, error) {
+
+	// Parse the right side with a lower binding so an operator of the
+	// same kind binds to the right
+
+	right, err := p.run(self.binding - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	self.Children = append(self.Children, left)
+	self.Children = append(self.Children, right)
+
+	return self, nil
+}
+
+/*
+ldBetween is the left denotation for a range check. The right side must be a
+list with exactly two elements (lower and upper bound).
+*/
+func ldBetween(p *parser, self *ASTNode, left *ASTNode) (*ASTNode, error) {
+
+	token := *p.node.Token
+
+	right, err := p.run(self.binding)
+	if err != nil {
+		return nil, err
+	}
+
+	if right.Name != NodeLIST || len(right.Children) != 2 {
+		return nil, p.newParserError(ErrInvalidRange,
+			"between requires a list with a lower and an upper bound", token)
+	}
+
+	self.Children = append(self.Children, left)
+	self.Children = append(self.Children, right)
+
+	return self, nil
+}
+
 // Helper functions
 // ================
 
@@ -104,6 +104,24 @@ plus
 		return
 	}
 
+	// Test power operator - binds stronger than times and is right-associative
+
+	input = "a * 2 ^ 3 ^ 2"
+	expectedOutput = `
+times
+  value: "a"
+  pow
+    value: "2"
+    pow
+      value: "3"
+      value: "2"
+`[1:]
+
+	if res, err := ParseWithRuntime("mytest", input, &TestRuntimeProvider{}); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
 	// Test brackets
 
 	input = "a + 1 * (5 + 6)"
@@ -228,6 +246,224 @@ lookup
 		return
 	}
 
+	input = `
+lookup Song ["a", 'b', c] /* keys */ where name = "x"`
+	expectedOutput = `
+lookup
+  value: "Song"
+  list
+    value: "a"
+    value: "b"
+    value: "c"
+  where
+    =
+      value: "name"
+      value: "x"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	if res, err := Parse("mytest", `lookup Song ["a", 1 + 2]`); err == nil ||
+		err.Error() != "Parse error in mytest: Unexpected term (+) (Line:1 Pos:21)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Test path expressions
+
+	input = `
+PATH from Author:123 TO "Song:Love Song" via ::: maxhops 5`
+	expectedOutput = `
+path
+  value: "Author:123"
+  value: "Song:Love "...
+  value: ":::"
+  maxhops
+    value: "5"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Path keywords can still be used as attribute names and values
+
+	input = `
+get Song where path = 'a' or to = 1 or name = path traverse ::: where via = maxhops end`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    or
+      or
+        =
+          value: "path"
+          value: "a"
+        =
+          value: "to"
+          value: "1"
+      =
+        value: "name"
+        value: "path"
+  traverse
+    value: ":::"
+    where
+      =
+        value: "via"
+        value: "maxhops"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Limit and offset can still be used as attribute names
+
+	input = `
+get Song where limit > 1 and offset > 1 show limit limit 5 offset 2`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    and
+      >
+        value: "limit"
+        value: "1"
+      >
+        value: "offset"
+        value: "1"
+  show
+    showterm: "limit"
+  limit
+    value: "5"
+  offset
+    value: "2"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// By can still be used as an attribute name
+
+	input = `
+get Song where by = 'x' group by by`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    =
+      value: "by"
+      value: "x"
+  groupby
+    value: "by"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Reverse can still be used as an attribute name
+
+	input = `
+get Song where reverse = 'x' traverse reverse :::`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    =
+      value: "reverse"
+      value: "x"
+  traverse
+    value: ":::"
+    reverse
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Test describe expressions
+
+	input = `
+DESCRIBE Song`
+	expectedOutput = `
+describe
+  value: "Song"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Describe can still be used as an attribute name
+
+	input = `
+lookup Song 'a' where describe = 1`
+	expectedOutput = `
+lookup
+  value: "Song"
+  value: "a"
+  where
+    =
+      value: "describe"
+      value: "1"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Test explain expressions
+
+	input = `
+EXPLAIN get Song where name = "Aria1"`
+	expectedOutput = `
+explain
+  get
+    value: "Song"
+    where
+      =
+        value: "name"
+        value: "Aria1"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	// Test count expressions
+
+	input = `
+COUNT Song, Album where ranking > 3 traverse :::Author end`
+	expectedOutput = `
+count
+  value: "Song"
+  value: "Album"
+  where
+    >
+      value: "ranking"
+      value: "3"
+  traverse
+    value: ":::Author"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
 	// Test where clause
 
 	input = `
@@ -309,6 +545,25 @@ get
 		return
 	}
 
+	// Test reverse traverse clause
+
+	input = `
+GeT Song TraverSE reverse Author:Wrote:Song: where true END`
+	expectedOutput = `
+get
+  value: "Song"
+  traverse
+    value: "Author:Wro"...
+    reverse
+    where
+      true
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
 	// Test functions
 
 	input = `
@@ -476,6 +731,72 @@ get
 		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
 		return
 	}
+
+	input = `get song where isnull name and not ISNOTNULL 1:n:ranking or isnull a + b`
+	expectedOutput = `
+get
+  value: "song"
+  where
+    or
+      and
+        isnull
+          value: "name"
+        not
+          isnotnull
+            value: "1:n:rankin"...
+      isnull
+        plus
+          value: "a"
+          value: "b"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = `get song show ranking with type(1:n:ranking, "int"), TYPE (2:n:name, string)`
+	expectedOutput = `
+get
+  value: "song"
+  show
+    showterm: "ranking"
+  with
+    type
+      value: "1:n:rankin"...
+      value: "int"
+    type
+      value: "2:n:name"
+      value: "string"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+
+	input = `get Author traverse :Wrote::Song end group by Author:name, 2:n:year show Author:name, 2:n:year, @count(2:n:key)`
+	expectedOutput = `
+get
+  value: "Author"
+  traverse
+    value: ":Wrote::So"...
+  groupby
+    value: "Author:nam"...
+    value: "2:n:year"
+  show
+    showterm: "Author:nam"...
+    showterm: "2:n:year"
+    showterm
+      func
+        value: "count"
+        value: "2:n:key"
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
 }
 
 func TestParserErrorCases(t *testing.T) {
@@ -510,6 +831,103 @@ func TestParserErrorCases(t *testing.T) {
 		return
 	}
 
+	if res, err := ParseWithRuntime("mytest", "get a where not like b", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Term cannot start an expression (<NOT LIKE>) (Line:1 Pos:13)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get a where b not", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Term can only start an expression (<NOT>) (Line:1 Pos:15)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "describe Song where true", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected term (where) (Line:1 Pos:15)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "explain describe Song", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected term (describe) (Line:1 Pos:9)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "path from a:1 to b:2", &TestRuntimeProvider{}); err.Error() !=
+		"Parse error in mytest: Unexpected end (Line:1 Pos:18)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "path from a:1 to b:2 via ::: where true", &TestRuntimeProvider{}); err.Error() !=
+		"Parse error in mytest: Unexpected term (where) (Line:1 Pos:30)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song group name show name", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected term (name) (Line:1 Pos:16)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song group by show name", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected term (show) (Line:1 Pos:19)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song group by name,", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected end" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get distinct", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected end" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get distinct distinct Song", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected term (distinct) (Line:1 Pos:14)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song limit 1.5", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Invalid number (limit must be a non-negative integer: 1.5) (Line:1 Pos:16)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song /* comment\n */ where\n# comment\n )", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Term cannot start an expression ()) (Line:4 Pos:2)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithParams("mytest", "get Song where name = :song", map[string]interface{}{"name": "x"},
+		&TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unbound parameter (song) (Line:1 Pos:23)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song with ordering(ascending name) offset x", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Invalid number (offset must be a non-negative integer: x) (Line:1 Pos:47)" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if res, err := ParseWithRuntime("mytest", "get Song limit", &TestRuntimeProvider{}); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected end" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
 	// Test "Get" parsing with invalid lexer output
 
 	res, err := testParserRun([]LexToken{
@@ -651,6 +1069,45 @@ func TestParserErrorCases(t *testing.T) {
 	}
 }
 
+func TestParamParsing(t *testing.T) {
+	var warnings []string
+
+	oldLogWarning := LogWarning
+	LogWarning = func(v ...interface{}) {
+		warnings = append(warnings, fmt.Sprint(v...))
+	}
+	defer func() {
+		LogWarning = oldLogWarning
+	}()
+
+	// Parameters do not need values if no runtime is used
+
+	if res, err := Parse("mytest", "get Song where name = :song"); err != nil || fmt.Sprint(res) != `
+get
+  value: "Song"
+  where
+    =
+      value: "name"
+      param: :song
+`[1:] {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	res, err := ParseWithParams("mytest", "get Song where name = :song or name in [:a, 'b']",
+		map[string]interface{}{"song": "Aria1", "a": "x", "year": 1, "album": "y"}, &TestRuntimeProvider{})
+
+	if err != nil || res.Children[1].Children[0].Children[0].Children[1].Token.Val != "song" {
+		t.Error("Unexpected result", res, err)
+		return
+	}
+
+	if fmt.Sprint(warnings) != "[Unused parameters in mytest: album, year]" {
+		t.Error("Unexpected warnings:", warnings)
+		return
+	}
+}
+
 func TestAstPlainRepresentation(t *testing.T) {
 
 	input := `
@@ -904,6 +1361,66 @@ get
 		t.Error("Unexpected error:", err)
 		return
 	}
+
+	// Test round-trip of range checks
+
+	res, err = Parse("mytest", "get Song where ranking between [1, 10]")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    between
+      value: "ranking"
+      list
+        value: "1"
+        value: "10"
+`[1:]
+
+	if fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput)
+		return
+	}
+
+	plainres = res.Plain()
+
+	jsonplainres, err = json.Marshal(plainres)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data = make(map[string]interface{})
+	json.NewDecoder(bytes.NewBuffer(jsonplainres)).Decode(&data)
+
+	astfromplain, err = ASTFromPlain(data)
+	if err != nil || fmt.Sprint(astfromplain) != expectedOutput {
+		t.Error("Unexpected output:", astfromplain, err)
+		return
+	}
+
+	if ppquery, err := PrettyPrint(astfromplain); err != nil || ppquery != "get Song where ranking between [1, 10]" {
+		t.Error("Unexpected output:", ppquery, err)
+		return
+	}
+
+	// Test range check errors
+
+	if _, err := Parse("mytest", "get Song where ranking between 1"); err == nil || err.Error() !=
+		"Parse error in mytest: Invalid range (between requires a list with a lower and an upper bound) (Line:1 Pos:32)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := Parse("mytest", "get Song where ranking between [1]"); err == nil || err.Error() !=
+		"Parse error in mytest: Invalid range (between requires a list with a lower and an upper bound) (Line:1 Pos:32)" {
+		t.Error(err)
+		return
+	}
 }
 
 /*
@@ -923,7 +1440,7 @@ func testParserRun(tokens []LexToken) (*ASTNode, error) {
 
 	// Create parser which processes the given tokens
 
-	p := &parser{"special test", nil, tokenChan, nil}
+	p := &parser{"special test", nil, tokenChan, nil, nil, nil}
 
 	node, err := p.next()
 
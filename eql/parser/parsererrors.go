@@ -19,7 +19,7 @@ import (
 newParserError creates a new ParserError object.
 */
 func (p *parser) newParserError(t error, d string, token LexToken) error {
-	return &Error{p.name, t, d, token.Lline, token.Lpos}
+	return &Error{p.name, t, d, token.Lline, token.Lpos, token.Val}
 }
 
 /*
@@ -31,6 +31,7 @@ type Error struct {
 	Detail string // Details of this error
 	Line   int    // Line of the error
 	Pos    int    // Position of the error
+	Token  string // Value of the token where the error occurred
 }
 
 /*
@@ -62,4 +63,7 @@ var (
 	ErrImpossibleNullDenotation = errors.New("Term cannot start an expression")
 	ErrImpossibleLeftDenotation = errors.New("Term can only start an expression")
 	ErrUnexpectedToken          = errors.New("Unexpected term")
+	ErrInvalidRange             = errors.New("Invalid range")
+	ErrInvalidNumber            = errors.New("Invalid number")
+	ErrUnboundParam             = errors.New("Unbound parameter")
 )
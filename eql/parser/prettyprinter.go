@@ -24,30 +24,41 @@ import (
 /*
 Map of pretty printer templates for AST nodes
 
-There is special treatment for NodeVALUE, NodeGET, NodeLOOKUP, NodeTRAVERSE,
-NodeFUNC, NodeSHOW, NodeSHOWTERM, NodeORDERING, NodeFILTERING, NodeWITH,
-NodeLPAREN, NodeRPAREN, NodeLBRACK and NodeRBRACK.
+There is special treatment for NodeVALUE, NodeGET, NodeCOUNT, NodeLOOKUP,
+NodeTRAVERSE, NodeFUNC, NodeGROUPBY, NodeSHOW, NodeSHOWTERM, NodeORDERING,
+NodeFILTERING, NodeWITH, NodeLPAREN, NodeRPAREN, NodeLBRACK and NodeRBRACK.
 */
 var prettyPrinterMap = map[string]*template.Template{
 	NodeTRUE:                 template.Must(template.New(NodeTRUE).Parse("true")),
 	NodeFALSE:                template.Must(template.New(NodeFALSE).Parse("false")),
 	NodeNULL:                 template.Must(template.New(NodeNULL).Parse("null")),
 	NodeNULLTRAVERSAL + "_1": template.Must(template.New(NodeNULLTRAVERSAL).Parse("nulltraversal({{.c1}})")),
+	NodeTYPE + "_2":          template.Must(template.New(NodeTYPE).Parse("type({{.c1}}, {{.c2}})")),
 
 	// Special tokens - always handled in a denotation function
 
 	NodeGROUP + "_1":  template.Must(template.New(NodeGROUP).Parse("group {{.c1}}")),
 	NodeEND:           template.Must(template.New(NodeEND).Parse("end")),
+	NodeREVERSE:       template.Must(template.New(NodeREVERSE).Parse("reverse")),
+	NodeDISTINCT:      template.Must(template.New(NodeDISTINCT).Parse("distinct")),
 	NodeAS + "_1":     template.Must(template.New(NodeAS).Parse("as {{.c1}}")),
 	NodeFORMAT + "_1": template.Must(template.New(NodeFORMAT).Parse("format {{.c1}}")),
 
 	// Keywords
 
-	NodeFROM + "_1":  template.Must(template.New(NodeFROM).Parse("from {{.c1}}")),
-	NodeWHERE + "_1": template.Must(template.New(NodeWHERE).Parse("where {{.c1}}")),
+	NodePATH + "_3":     template.Must(template.New(NodePATH).Parse("path from {{.c1}} to {{.c2}} via {{.c3}}")),
+	NodePATH + "_4":     template.Must(template.New(NodePATH).Parse("path from {{.c1}} to {{.c2}} via {{.c3}} {{.c4}}")),
+	NodeMAXHOPS + "_1":  template.Must(template.New(NodeMAXHOPS).Parse("maxhops {{.c1}}")),
+	NodeDESCRIBE + "_1": template.Must(template.New(NodeDESCRIBE).Parse("describe {{.c1}}")),
+	NodeEXPLAIN + "_1":  template.Must(template.New(NodeEXPLAIN).Parse("explain {{.c1}}")),
+	NodeFROM + "_1":     template.Must(template.New(NodeFROM).Parse("from {{.c1}}")),
+	NodeLIMIT + "_1":    template.Must(template.New(NodeLIMIT).Parse("limit {{.c1}}")),
+	NodeOFFSET + "_1":   template.Must(template.New(NodeOFFSET).Parse("offset {{.c1}}")),
+	NodeWHERE + "_1":    template.Must(template.New(NodeWHERE).Parse("where {{.c1}}")),
 
 	NodeUNIQUE + "_1":      template.Must(template.New(NodeUNIQUE).Parse("unique {{.c1}}")),
 	NodeUNIQUECOUNT + "_1": template.Must(template.New(NodeUNIQUECOUNT).Parse("uniquecount {{.c1}}")),
+	NodeISNULL + "_1":      template.Must(template.New(NodeISNULL).Parse("isnull {{.c1}}")),
 	NodeISNOTNULL + "_1":   template.Must(template.New(NodeISNOTNULL).Parse("isnotnull {{.c1}}")),
 	NodeASCENDING + "_1":   template.Must(template.New(NodeASCENDING).Parse("ascending {{.c1}}")),
 	NodeDESCENDING + "_1":  template.Must(template.New(NodeDESCENDING).Parse("descending {{.c1}}")),
@@ -68,19 +79,29 @@ var prettyPrinterMap = map[string]*template.Template{
 
 	// List operations
 
-	NodeIN + "_2":    template.Must(template.New(NodeIN).Parse("{{.c1}} in {{.c2}}")),
-	NodeNOTIN + "_2": template.Must(template.New(NodeNOTIN).Parse("{{.c1}} notin {{.c2}}")),
+	NodeIN + "_2":          template.Must(template.New(NodeIN).Parse("{{.c1}} in {{.c2}}")),
+	NodeNOTIN + "_2":       template.Must(template.New(NodeNOTIN).Parse("{{.c1}} notin {{.c2}}")),
+	NodeCONTAINSALL + "_2": template.Must(template.New(NodeCONTAINSALL).Parse("{{.c1}} containsall {{.c2}}")),
+	NodeBETWEEN + "_2":     template.Must(template.New(NodeBETWEEN).Parse("{{.c1}} between {{.c2}}")),
 
 	// String operations
 
 	NodeLIKE + "_2":        template.Must(template.New(NodeLIKE).Parse("{{.c1}} like {{.c2}}")),
+	NodeMATCHES + "_2":     template.Must(template.New(NodeMATCHES).Parse("{{.c1}} matches {{.c2}}")),
 	NodeCONTAINS + "_2":    template.Must(template.New(NodeCONTAINS).Parse("{{.c1}} contains {{.c2}}")),
 	NodeBEGINSWITH + "_2":  template.Must(template.New(NodeBEGINSWITH).Parse("{{.c1}} beginswith {{.c2}}")),
 	NodeENDSWITH + "_2":    template.Must(template.New(NodeENDSWITH).Parse("{{.c1}} endswith {{.c2}}")),
 	NodeCONTAINSNOT + "_2": template.Must(template.New(NodeCONTAINSNOT).Parse("{{.c1}} containsnot {{.c2}}")),
 
+	NodeCONTAINSWORD + "_2": template.Must(template.New(NodeCONTAINSWORD).Parse("{{.c1}} containsword {{.c2}}")),
+
+	NodeNOTLIKE + "_2":       template.Must(template.New(NodeNOTLIKE).Parse("{{.c1}} not like {{.c2}}")),
+	NodeNOTBEGINSWITH + "_2": template.Must(template.New(NodeNOTBEGINSWITH).Parse("{{.c1}} not beginswith {{.c2}}")),
+	NodeNOTENDSWITH + "_2":   template.Must(template.New(NodeNOTENDSWITH).Parse("{{.c1}} not endswith {{.c2}}")),
+
 	// Simple arithmetic expressions
 
+	NodePLUS + "_1":   template.Must(template.New(NodePLUS).Parse("+{{.c1}}")),
 	NodePLUS + "_2":   template.Must(template.New(NodePLUS).Parse("{{.c1}} + {{.c2}}")),
 	NodeMINUS + "_1":  template.Must(template.New(NodeMINUS).Parse("-{{.c1}}")),
 	NodeMINUS + "_2":  template.Must(template.New(NodeMINUS).Parse("{{.c1}} - {{.c2}}")),
@@ -88,16 +109,22 @@ var prettyPrinterMap = map[string]*template.Template{
 	NodeDIV + "_2":    template.Must(template.New(NodeDIV).Parse("{{.c1}} / {{.c2}}")),
 	NodeMODINT + "_2": template.Must(template.New(NodeMODINT).Parse("{{.c1}} % {{.c2}}")),
 	NodeDIVINT + "_2": template.Must(template.New(NodeDIVINT).Parse("{{.c1}} // {{.c2}}")),
+	NodePOW + "_2":    template.Must(template.New(NodePOW).Parse("{{.c1}} ^ {{.c2}}")),
 }
 
 /*
 Map of nodes where the precedence might have changed because of parentheses
 */
 var bracketPrecedenceMap = map[string]bool{
-	NodePLUS:  true,
-	NodeMINUS: true,
-	NodeAND:   true,
-	NodeOR:    true,
+	NodePLUS:   true,
+	NodeMINUS:  true,
+	NodeTIMES:  true,
+	NodeDIV:    true,
+	NodeMODINT: true,
+	NodeDIVINT: true,
+	NodePOW:    true,
+	NodeAND:    true,
+	NodeOR:     true,
 }
 
 /*
@@ -133,6 +160,8 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 		if ast.Name == NodeVALUE || (ast.Name == NodeSHOWTERM && len(ast.Children) == 0) {
 			return quoteValue(ast.Token.Val, true), nil
+		} else if ast.Name == NodePARAM {
+			return ":" + ast.Token.Val, nil
 		}
 
 		var children map[string]string
@@ -151,6 +180,13 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 				if _, ok := bracketPrecedenceMap[child.Name]; ok && ast.binding > child.binding {
 					res = fmt.Sprintf("(%v)", res)
+
+				} else if ast.Name == NodePOW && child.Name == NodePOW && i == 0 {
+
+					// Power is right-associative - a power on the left side
+					// needs brackets
+
+					res = fmt.Sprintf("(%v)", res)
 				}
 
 				children[fmt.Sprint("c", i+1)] = res
@@ -184,6 +220,21 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 			}
 
 			i := 1
+
+			// Node keys are either given as a list or as values
+
+			if i < len(children) && ast.Children[i].Name == NodeLIST {
+				buf.WriteString("[")
+				for j, key := range ast.Children[i].Children {
+					if j > 0 {
+						buf.WriteString(", ")
+					}
+					buf.WriteString(quoteValue(key.Token.Val, false))
+				}
+				buf.WriteString("]")
+				i++
+			}
+
 			for ; i < len(children) && ast.Children[i].Name == NodeVALUE; i++ {
 				buf.WriteString(quoteValue(ast.Children[i].Token.Val, false))
 
@@ -205,15 +256,37 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 			return buf.String(), nil
 
-		} else if ast.Name == NodeGET {
+		} else if ast.Name == NodeGET || ast.Name == NodeCOUNT {
 
-			buf.WriteString("get ")
-			buf.WriteString(children["c1"])
-			if 1 < len(children) {
+			buf.WriteString(ast.Name)
+			buf.WriteString(" ")
+
+			// The node kinds are followed by an optional distinct flag (node
+			// kinds are value nodes - token IDs are not kept in plain ASTs)
+
+			kinds := 1
+			for kinds < len(ast.Children) && ast.Children[kinds].Name == NodeVALUE {
+				kinds++
+			}
+
+			i := kinds
+			if len(ast.Children) > i && ast.Children[i].Name == NodeDISTINCT {
+				buf.WriteString("distinct ")
+				i++
+			}
+
+			for j := 1; j <= kinds; j++ {
+				buf.WriteString(children[fmt.Sprint("c", j)])
+				if j < kinds {
+					buf.WriteString(", ")
+				}
+			}
+
+			if i < len(children) {
 				buf.WriteString(" ")
 			}
 
-			for i := 1; i < len(children); i++ {
+			for ; i < len(children); i++ {
 				buf.WriteString(children[fmt.Sprint("c", i+1)])
 				if i < len(children)-1 && ast.Children[i+1].Name != NodeSHOW {
 					buf.WriteString(" ")
@@ -228,13 +301,22 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 			buf.WriteString(stringutil.GenerateRollingString(" ", level*2))
 			buf.WriteString("traverse ")
 
+			// The reverse flag is written in front of the traversal spec
+
+			var parts []string
+
+			if len(ast.Children) > 1 && ast.Children[1].Name == NodeREVERSE {
+				parts = append(parts, "reverse")
+			}
+
 			for i := 0; i < len(children); i++ {
-				buf.WriteString(children[fmt.Sprint("c", i+1)])
-				if i < len(children)-1 {
-					buf.WriteString(" ")
+				if ast.Children[i].Name != NodeREVERSE {
+					parts = append(parts, children[fmt.Sprint("c", i+1)])
 				}
 			}
 
+			buf.WriteString(strings.Join(parts, " "))
+
 			buf.WriteString("\n")
 			buf.WriteString(stringutil.GenerateRollingString(" ", level*2))
 			buf.WriteString("end")
@@ -258,6 +340,19 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 			return buf.String(), nil
 
+		} else if ast.Name == NodeGROUPBY {
+
+			buf.WriteString("group by ")
+
+			for i := 0; i < len(children); i++ {
+				buf.WriteString(children[fmt.Sprint("c", i+1)])
+				if i < len(children)-1 {
+					buf.WriteString(", ")
+				}
+			}
+
+			return buf.String(), nil
+
 		} else if ast.Name == NodeSHOW {
 
 			buf.WriteString("\nshow\n  ")
@@ -325,7 +420,20 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 			for i := 0; i < len(children); i++ {
 				buf.WriteString("  ")
-				buf.WriteString(children[fmt.Sprint("c", i+1)])
+
+				// The format directive uses the same node as format
+				// definitions in show clauses
+
+				if child := ast.Children[i]; child.Name == NodeFORMAT && len(child.Children) == 1 {
+					casing, err := visit(child.Children[0], level+2)
+					if err != nil {
+						return "", err
+					}
+					buf.WriteString(fmt.Sprintf("format(%v)", casing))
+				} else {
+					buf.WriteString(children[fmt.Sprint("c", i+1)])
+				}
+
 				if i < len(children)-1 {
 					buf.WriteString(",\n")
 				}
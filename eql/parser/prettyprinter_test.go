@@ -11,6 +11,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 )
@@ -55,6 +56,29 @@ div
 		return
 	}
 
+	input = "(a * 2) ^ 3 ^ 2 + (2 ^ 3) ^ 2"
+	expectedOutput = `
+plus
+  pow
+    times
+      value: "a"
+      value: "2"
+    pow
+      value: "3"
+      value: "2"
+  pow
+    pow
+      value: "2"
+      value: "3"
+    value: "2"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"(a * 2) ^ 3 ^ 2 + (2 ^ 3) ^ 2"); err != nil {
+		t.Error(err)
+		return
+	}
+
 	input = "a + (1 * 5) / 6 - 2"
 	expectedOutput = `
 minus
@@ -167,6 +191,116 @@ get
 		return
 	}
 
+	input = `
+GeT DISTINCT Song primary 1:Song show name`
+	expectedOutput = `
+get
+  value: "Song"
+  distinct
+  primary
+    value: "1:Song"
+  show
+    showterm: "name"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get distinct Song primary 1:Song\nshow\n  name"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+get distinct Song`
+	expectedOutput = `
+get
+  value: "Song"
+  distinct
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get distinct Song"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+path from Author:123 to "Song:Love Song" via :Wrote:: maxhops 3`
+	expectedOutput = `
+path
+  value: "Author:123"
+  value: "Song:Love "...
+  value: ":Wrote::"
+  maxhops
+    value: "3"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		`path from Author:123 to "Song:Love Song" via :Wrote:: maxhops 3`); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+describe Wrote`
+	expectedOutput = `
+describe
+  value: "Wrote"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `describe Wrote`); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+explain lookup Song "a", "b"`
+	expectedOutput = `
+explain
+  lookup
+    value: "Song"
+    value: "a"
+    value: "b"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `explain lookup Song "a", "b"`); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+count Song where ranking > 3`
+	expectedOutput = `
+count
+  value: "Song"
+  where
+    >
+      value: "ranking"
+      value: "3"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `count Song where ranking > 3`); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+get distinct Song,Album where name = "x"`
+	expectedOutput = `
+get
+  value: "Song"
+  value: "Album"
+  distinct
+  where
+    =
+      value: "name"
+      value: "x"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `get distinct Song, Album where name = x`); err != nil {
+		t.Error(err)
+		return
+	}
+
 	input = `
 GeT Song where foo in bar and bar notin foo or xx = ""`
 	expectedOutput = `
@@ -192,6 +326,157 @@ get
 		return
 	}
 
+	input = `
+GeT Song where a = 1 and name NOT like "^a.*" or name not beginswith b and not name not endswith c or name not in [d]`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    or
+      or
+        and
+          =
+            value: "a"
+            value: "1"
+          notlike
+            value: "name"
+            value: "^a.*"
+        and
+          notbeginswith
+            value: "name"
+            value: "b"
+          not
+            notendswith
+              value: "name"
+              value: "c"
+      notin
+        value: "name"
+        list
+          value: "d"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get Song where a = 1 and name not like \"^a.*\" or name not beginswith b and not name not endswith c or name notin [d]"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Not is still a prefix if it is not followed by a string operator
+
+	input = `
+GeT Song where not (name like "a") and not a = 1`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    and
+      not
+        like
+          value: "name"
+          value: "a"
+      not
+        =
+          value: "a"
+          value: "1"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get Song where not name like a and not a = 1"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Bind parameters have no values without runtime
+
+	astres, _ := Parse("mytest", `get Song where name = :song and key in [:key, "x"]`)
+	if res, err := PrettyPrint(astres); err != nil || res !=
+		"get Song where name = :song and key in [:key, x]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	input = `
+GeT Song where name MATCHES "^Aria[0-9]+$" and a = 1`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    and
+      matches
+        value: "name"
+        value: "^Aria[0-9]"...
+      =
+        value: "a"
+        value: "1"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get Song where name matches \"^Aria[0-9]+$\" and a = 1"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+GeT Song where tags CONTAINSALL [a, b]`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    containsall
+      value: "tags"
+      list
+        value: "a"
+        value: "b"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get Song where tags containsall [a, b]"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+GeT Song where lyrics CONTAINSWORD "love me"`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    containsword
+      value: "lyrics"
+      value: "love me"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get Song where lyrics containsword \"love me\""); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+GeT Song where ranking BETWEEN [1, 10] and name between ["a", "m"]`
+	expectedOutput = `
+get
+  value: "Song"
+  where
+    and
+      between
+        value: "ranking"
+        list
+          value: "1"
+          value: "10"
+      between
+        value: "name"
+        list
+          value: "a"
+          value: "m"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get Song where ranking between [1, 10] and name between [a, m]"); err != nil {
+		t.Error(err)
+		return
+	}
+
 	input = `
 lOOkup Song "a","b","c"`
 	expectedOutput = `
@@ -208,6 +493,27 @@ lookup
 		return
 	}
 
+	input = `
+lookup Song ["a", b,'c'] where x = 1`
+	expectedOutput = `
+lookup
+  value: "Song"
+  list
+    value: "a"
+    value: "b"
+    value: "c"
+  where
+    =
+      value: "x"
+      value: "1"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		`lookup Song ["a", "b", "c"] where x = 1`); err != nil {
+		t.Error(err)
+		return
+	}
+
 	input = `
 lOOkup Song "a","b","c", "blaД" primary Song
 FROM group test
@@ -306,6 +612,65 @@ get bla
 		return
 	}
 
+	input = `
+GeT Song TraverSE REVERSE Author:Wrote:Song: where true END`
+	expectedOutput = `
+get
+  value: "Song"
+  traverse
+    value: "Author:Wro"...
+    reverse
+    where
+      true
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get Song 
+  traverse reverse Author:Wrote:Song: where true
+  end`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+GeT Song TraverSE REVERSE :Wrote::Song END`
+	expectedOutput = `
+get
+  value: "Song"
+  traverse
+    value: ":Wrote::So"...
+    reverse
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get Song 
+  traverse reverse :Wrote::Song
+  end`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `
+GeT Song TraverSE Author:Wrote:Song: nulltraversal(true) where true END`
+	expectedOutput = `
+get
+  value: "Song"
+  traverse
+    value: "Author:Wro"...
+    nulltraversal
+      true
+    where
+      true
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get Song 
+  traverse Author:Wrote:Song: nulltraversal(true) where true
+  end`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
+
 	input = `
 GeT Song where @a() or @count("File:File:StoredData:Data") > 1 and @boolfunc1(123,"test", aaa)`
 	expectedOutput = `
@@ -495,6 +860,135 @@ with
 		t.Error(err)
 		return
 	}
+
+	input = `get song where isnull name and not ISNOTNULL 1:n:ranking or isnull a + b`
+	expectedOutput = `
+get
+  value: "song"
+  where
+    or
+      and
+        isnull
+          value: "name"
+        not
+          isnotnull
+            value: "1:n:rankin"...
+      isnull
+        plus
+          value: "a"
+          value: "b"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		"get song where isnull name and not isnotnull 1:n:ranking or isnull a + b"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `get song show ranking with type(1:n:ranking, "int"), TYPE (2:n:name, string)`
+	expectedOutput = `
+get
+  value: "song"
+  show
+    showterm: "ranking"
+  with
+    type
+      value: "1:n:rankin"...
+      value: "int"
+    type
+      value: "2:n:name"
+      value: "string"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get song 
+show
+  ranking 
+with
+  type(1:n:ranking, int),
+  type(2:n:name, string)`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `get song show name format text with FORMAT(camel)`
+	expectedOutput = `
+get
+  value: "song"
+  show
+    showterm: "name"
+      format
+        value: "text"
+  with
+    format
+      value: "camel"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get song 
+show
+  name format text 
+with
+  format(camel)`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `get Author traverse :Wrote::Song end group by Author:name, 2:n:year show Author:name, 2:n:year, @count(2:n:key)`
+	expectedOutput = `
+get
+  value: "Author"
+  traverse
+    value: ":Wrote::So"...
+  groupby
+    value: "Author:nam"...
+    value: "2:n:year"
+  show
+    showterm: "Author:nam"...
+    showterm: "2:n:year"
+    showterm
+      func
+        value: "count"
+        value: "2:n:key"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get Author 
+  traverse :Wrote::Song
+  end group by Author:name, 2:n:year
+show
+  Author:name,
+  2:n:year,
+  @count(2:n:key)`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
+
+	input = `get Song show name with ordering(ascending name) LIMIT 10 offset 5`
+	expectedOutput = `
+get
+  value: "Song"
+  show
+    showterm: "name"
+  with
+    ordering
+      asc
+        value: "name"
+  limit
+    value: "10"
+  offset
+    value: "5"
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput, `
+get Song 
+show
+  name 
+with
+  ordering(ascending name) limit 10 offset 5`[1:]); err != nil {
+		t.Error(err)
+		return
+	}
 }
 
 func TestSpecialCases(t *testing.T) {
@@ -555,6 +1049,75 @@ func TestSpecialCases(t *testing.T) {
 	}
 }
 
+func TestPlainRoundTrip(t *testing.T) {
+
+	// Parse -> Plain -> ASTFromPlain -> PrettyPrint -> Parse should produce
+	// an equivalent AST
+
+	queries := []string{
+		"get Song",
+		"get distinct Song, Author where name = 'Aria1' or ranking >= 5",
+		"GET Song where not (ranking between [1, 10]) and name like 'Aria.*' limit 3 offset 1",
+		"get Author primary Author traverse :Wrote::Song where ranking > 2 traverse ::: end end show name, 2:n:name as 'Song name' format text",
+		"get Author traverse :::Song nulltraversal(true) where true end group by Author:name show Author:name, @count(2:n:key)",
+		"get Song where @count(1, :::Author, 'name = Hans') = 1 and tags containsall [rock, live]",
+		"get Song where isnull name or lyrics containsword 'love me' show name with ordering(ascending name, descending ranking), filtering(unique name), type(1:n:ranking, int)",
+		"get Song show name format text, ranking as Rank with format(camel), ordering(ascending name)",
+		"get Song where a + b * 5 / 2 - 1 ^ 2 // 3 % 4 > -1 and name notin [a, b]",
+		"count Song, Author where name beginswith 'A'",
+		"lookup Song 'Aria1', 'Aria2' where name != null",
+		"path from Author:000 to Song:Aria1 via ::: maxhops 3",
+		"describe Song",
+		"get Song where name = :name show name",
+	}
+
+	for _, query := range queries {
+
+		ast, err := Parse("mytest", query)
+		if err != nil {
+			t.Error("Could not parse query:", query, err)
+			return
+		}
+
+		// Send the plain AST through JSON as a client would
+
+		var plain map[string]interface{}
+
+		plainJSON, err := json.Marshal(ast.Plain())
+		if err == nil {
+			err = json.Unmarshal(plainJSON, &plain)
+		}
+
+		if err != nil {
+			t.Error("Could not encode plain AST:", query, err)
+			return
+		}
+
+		astFromPlain, err := ASTFromPlain(plain)
+		if err != nil {
+			t.Error("Could not create AST from plain AST:", query, err)
+			return
+		}
+
+		ppres, err := PrettyPrint(astFromPlain)
+		if err != nil {
+			t.Error("Could not pretty print AST:", query, err)
+			return
+		}
+
+		ast2, err := Parse("mytest", ppres)
+		if err != nil {
+			t.Error("Could not parse pretty printed query:", ppres, err)
+			return
+		}
+
+		if fmt.Sprint(ast2) != fmt.Sprint(ast) {
+			t.Errorf("Round trip of %v produced a different AST:\n%v\nexpected was:\n%v", query, ast2, ast)
+			return
+		}
+	}
+}
+
 func testPrettyPrinting(input, astOutput, ppOutput string) error {
 
 	astres, err := ParseWithRuntime("mytest", input, &TestRuntimeProvider{})
@@ -36,3 +36,16 @@ type Runtime interface {
 	*/
 	Eval() (interface{}, error)
 }
+
+/*
+ParamRuntime is a runtime component of a bind parameter. The parser sets the
+value of the parameter.
+*/
+type ParamRuntime interface {
+	Runtime
+
+	/*
+		SetValue sets the value of the parameter.
+	*/
+	SetValue(val interface{})
+}
@@ -18,11 +18,13 @@ GET Person where name = "Marvin"
 package eql
 
 import (
+	"context"
 	"strings"
 
 	"devt.de/krotik/eliasdb/eql/interpreter"
 	"devt.de/krotik/eliasdb/eql/parser"
 	"devt.de/krotik/eliasdb/graph"
+	"devt.de/krotik/eliasdb/graph/data"
 )
 
 /*
@@ -30,6 +32,69 @@ GroupNodeKind is a special node kind representing groups
 */
 const GroupNodeKind = interpreter.GroupNodeKind
 
+/*
+QueryFunc is a custom function which can be used in show and where clauses.
+It is called with the node and edge of the current row and the values of the
+function arguments.
+*/
+type QueryFunc = interpreter.QueryFunc
+
+/*
+RegisterFunction registers a custom function which can be used as
+@<name>(...) in where clauses and as @<name>(<traversal step>, ...) in show
+clauses. A nil function removes a registered function.
+*/
+func RegisterFunction(name string, fn QueryFunc) error {
+	return interpreter.RegisterFunction(name, fn)
+}
+
+/*
+ErrCancelled is the error type of a RuntimeError which is returned if the
+context of a query is done before the query has finished.
+*/
+var ErrCancelled = interpreter.ErrCancelled
+
+/*
+ResultSpillRows is the number of result rows which are kept in memory while
+the result of a query is collected. The rows of larger results are written to
+a temporary file in ResultSpillDir. A value below 1 keeps all rows in memory.
+*/
+var ResultSpillRows = 0
+
+/*
+ResultSpillDir is the directory for temporary files of large results (an
+empty string uses the default directory for temporary files).
+*/
+var ResultSpillDir = ""
+
+/*
+ScanWorkers is the number of goroutines which fetch the start nodes of get and
+lookup queries and evaluate their where clause. The result rows are the same
+as with a sequential scan. A value below 2 scans start nodes sequentially.
+Custom functions which are used in where clauses must be safe for concurrent
+use if parallel scans are enabled.
+*/
+var ScanWorkers = 0
+
+/*
+StrictLookupKeys is a flag if lookup queries should fail with an error if a
+given node key does not exist. By default keys of nodes which do not exist are
+skipped.
+*/
+var StrictLookupKeys = false
+
+/*
+queryRuntimeProvider is a runtime provider which can be cancelled through
+a context, which can write large results to a temporary file and which can
+scan start nodes in parallel.
+*/
+type queryRuntimeProvider interface {
+	parser.RuntimeProvider
+	SetContext(ctx context.Context)
+	SetResultSpill(maxRows int, dir string)
+	SetScanWorkers(workers int)
+}
+
 /*
 RunQuery runs a search query against a given graph database.
 */
@@ -37,19 +102,64 @@ func RunQuery(name string, part string, query string, gm *graph.Manager) (Search
 	return RunQueryWithNodeInfo(name, part, query, gm, interpreter.NewDefaultNodeInfo(gm))
 }
 
+/*
+RunQueryContext runs a search query against a given graph database. The query
+is cancelled with an ErrCancelled error once the given context is done.
+*/
+func RunQueryContext(ctx context.Context, name string, part string, query string, gm *graph.Manager) (SearchResult, error) {
+	return evalQuery(ctx, name, part, query, gm, interpreter.NewDefaultNodeInfo(gm))
+}
+
 /*
 RunQueryWithNodeInfo runs a search query against a given graph database. Using
 a given NodeInfo object to retrieve rendering information.
 */
 func RunQueryWithNodeInfo(name string, part string, query string, gm *graph.Manager, ni interpreter.NodeInfo) (SearchResult, error) {
-	var rtp parser.RuntimeProvider
+	return evalQuery(context.Background(), name, part, query, gm, ni)
+}
+
+/*
+evalQuery runs a search query against a given graph database. The query is
+cancelled once the given context is done.
+*/
+func evalQuery(ctx context.Context, name string, part string, query string, gm *graph.Manager, ni interpreter.NodeInfo) (SearchResult, error) {
+	var rtp queryRuntimeProvider
 
 	word := strings.ToLower(parser.FirstWord(query))
 
+	if word == "explain" {
+
+		// The runtime provider of an explain query is determined by the
+		// explained query
+
+		if words := parser.FirstWords(query, 2); len(words) > 1 {
+			word = strings.ToLower(words[1])
+
+			if word == "count" {
+				word = "get"
+			}
+		}
+	}
+
 	if word == "get" {
 		rtp = interpreter.NewGetRuntimeProvider(name, part, gm, ni)
 	} else if word == "lookup" {
-		rtp = interpreter.NewLookupRuntimeProvider(name, part, gm, ni)
+		lrtp := interpreter.NewLookupRuntimeProvider(name, part, gm, ni)
+		lrtp.SetStrictKeys(StrictLookupKeys)
+		rtp = lrtp
+	} else if word == "path" {
+		rtp = interpreter.NewPathRuntimeProvider(name, part, gm, ni)
+	} else if word == "describe" {
+		rtp = interpreter.NewDescribeRuntimeProvider(name, part, gm, ni)
+	} else if word == "count" {
+		return nil, &interpreter.RuntimeError{
+			Source: name,
+			Type:   interpreter.ErrInvalidConstruct,
+			Detail: "Count queries must be run with RunCountQuery",
+			Node:   nil,
+			Line:   1,
+			Pos:    1,
+		}
 	} else {
 		return nil, &interpreter.RuntimeError{
 			Source: name,
@@ -61,6 +171,10 @@ func RunQueryWithNodeInfo(name string, part string, query string, gm *graph.Mana
 		}
 	}
 
+	rtp.SetContext(ctx)
+	rtp.SetResultSpill(ResultSpillRows, ResultSpillDir)
+	rtp.SetScanWorkers(ScanWorkers)
+
 	ast, err := parser.ParseWithRuntime(name, query, rtp)
 	if err != nil {
 		return nil, err
@@ -74,6 +188,61 @@ func RunQueryWithNodeInfo(name string, part string, query string, gm *graph.Mana
 	return &queryResult{res.(*interpreter.SearchResult)}, nil
 }
 
+/*
+RunCountQuery runs a count query against a given graph database and returns
+the number of rows which the equivalent get query would produce.
+
+Example EQL count query:
+
+COUNT Song where ranking > 3
+*/
+func RunCountQuery(name string, part string, query string, gm *graph.Manager) (int, error) {
+	return evalCountQuery(context.Background(), name, part, query, gm)
+}
+
+/*
+RunCountQueryContext runs a count query against a given graph database. The
+query is cancelled with an ErrCancelled error once the given context is done.
+*/
+func RunCountQueryContext(ctx context.Context, name string, part string, query string, gm *graph.Manager) (int, error) {
+	return evalCountQuery(ctx, name, part, query, gm)
+}
+
+/*
+evalCountQuery runs a count query against a given graph database. The query is
+cancelled once the given context is done.
+*/
+func evalCountQuery(ctx context.Context, name string, part string, query string, gm *graph.Manager) (int, error) {
+
+	if word := strings.ToLower(parser.FirstWord(query)); word != "count" {
+		return 0, &interpreter.RuntimeError{
+			Source: name,
+			Type:   interpreter.ErrInvalidConstruct,
+			Detail: "Not a count query: " + word,
+			Node:   nil,
+			Line:   1,
+			Pos:    1,
+		}
+	}
+
+	rtp := interpreter.NewGetRuntimeProvider(name, part, gm, interpreter.NewDefaultNodeInfo(gm))
+
+	rtp.SetContext(ctx)
+	rtp.SetScanWorkers(ScanWorkers)
+
+	ast, err := parser.ParseWithRuntime(name, query, rtp)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := ast.Runtime.Eval()
+	if err != nil {
+		return 0, err
+	}
+
+	return res.(int), nil
+}
+
 /*
 ParseQuery parses a search query and return its Abstract Syntax Tree.
 */
@@ -86,6 +255,30 @@ func ParseQuery(name string, query string) (*parser.ASTNode, error) {
 	return ast, nil
 }
 
+/*
+Expression is an EQL expression which can be evaluated on nodes.
+*/
+type Expression interface {
+
+	/*
+		Eval evaluates the expression on a given node.
+	*/
+	Eval(node data.Node) (interface{}, error)
+}
+
+/*
+ParseExpression parses a single EQL expression (e.g. "ranking * 2") which can
+be evaluated on nodes of a given partition.
+*/
+func ParseExpression(name string, part string, expr string, gm *graph.Manager) (Expression, error) {
+	e, err := interpreter.NewExpression(name, part, expr, gm, interpreter.NewDefaultNodeInfo(gm))
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
 /*
 queryResult datastructure to hide implementation details.
 */
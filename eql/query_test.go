@@ -11,6 +11,9 @@
 package eql
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	"devt.de/krotik/eliasdb/eql/interpreter"
@@ -82,9 +85,108 @@ Data: 1:n:key, 1:n:name
 		return
 	}
 
+	res, _ = RunQuery("test", "main", "path from Author:123 to Song:LoveSong3 via :::", gm)
+	if res.String() != `
+Labels: Key, Kind, Name, Edge Key, Edge Kind
+Format: auto, auto, auto, auto, auto
+Data: 1:n:key, 1:n:kind, 1:n:name, 1:e:key, 1:e:kind
+123, Author, Mike, <not set>, <not set>
+LoveSong3, Song, LoveSong3, LoveSong3, Wrote
+`[1:] {
+		t.Error("Unexpected result: ", res)
+		return
+	}
+
+	res, err := RunQuery("test", "main", "describe Song", gm)
+	if err != nil || res.String() != `
+Labels: Attribute, Types, Count
+Format: auto, auto, auto
+Data: 1:n:attribute, 1:n:types, 1:n:count
+key, string, 9
+kind, string, 9
+name, string, 9
+ranking, number, 9
+`[1:] {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = RunQuery("test", "main", "describe Wrote", gm)
+	if err != nil || res.String() != `
+Labels: Attribute, Types, Count
+Format: auto, auto, auto
+Data: 1:n:attribute, 1:n:types, 1:n:count
+end1cascading, boolean, 9
+end1key, string, 9
+end1kind, string, 9
+end1role, string, 9
+end2cascading, boolean, 9
+end2key, string, 9
+end2kind, string, 9
+end2role, string, 9
+key, string, 9
+kind, string, 9
+number, number, 9
+`[1:] {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if _, err = RunQuery("test", "main", "describe Spam", gm); err == nil || err.Error() !=
+		"EQL error in test: Unknown node or edge kind (Spam) (Line:1 Pos:10)" {
+		t.Error(err)
+		return
+	}
+
+	res, err = RunQuery("test", "main", "explain count Song where ranking > 3", gm)
+	if err != nil || res.String() != `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, full scan, All nodes of kind Song, 9
+2, filter, ranking > 3, 9
+`[1:] {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	res, err = RunQuery("test", "main", `explain lookup Song "LoveSong3"`, gm)
+	if err != nil || res.String() != `
+Labels: Step, Operation, Detail, Cost
+Format: auto, auto, auto, auto
+Data: 1:n:step, 1:n:operation, 1:n:detail, 1:n:cost
+1, key lookup, Nodes of kind Song by key, 1
+`[1:] {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if count, err := RunCountQuery("test", "main", "count Song where ranking > 3", gm); err != nil || count != 6 {
+		t.Error("Unexpected result: ", count, err)
+		return
+	}
+
+	if _, err = RunCountQuery("test", "main", "get Song", gm); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Not a count query: get) (Line:1 Pos:1)" {
+		t.Error(err)
+		return
+	}
+
+	if _, err = RunCountQuery("test", "main", "count Song where", gm); err == nil || err.Error() !=
+		"Parse error in test: Unexpected end" {
+		t.Error(err)
+		return
+	}
+
+	if _, err = RunQuery("test", "main", "count Song", gm); err == nil || err.Error() !=
+		"EQL error in test: Invalid construct (Count queries must be run with RunCountQuery) (Line:1 Pos:1)" {
+		t.Error(err)
+		return
+	}
+
 	// Test error cases
 
-	_, err := RunQuery("test", "main", "boo Author", gm)
+	_, err = RunQuery("test", "main", "boo Author", gm)
 	if err.Error() != "EQL error in test: Invalid construct (Unknown query type: boo) (Line:1 Pos:1)" {
 		t.Error(err)
 		return
@@ -142,6 +244,40 @@ Data: 1:n:key, 1:n:name
 	}
 }
 
+func TestRegisterFunction(t *testing.T) {
+	gm, _ := songGraph()
+
+	double := func(node data.Node, edge data.Edge, args []interface{}) (interface{}, error) {
+		var res []string
+		for _, arg := range args {
+			res = append(res, fmt.Sprint(arg, arg))
+		}
+		return strings.Join(res, " "), nil
+	}
+
+	if err := RegisterFunction("double", double); err != nil {
+		t.Error(err)
+		return
+	}
+	defer RegisterFunction("double", nil)
+
+	res, err := RunQuery("test", "main", "get Author where @double(name) = JohnJohn show name, @double(1, key, name)", gm)
+	if err != nil || res.String() != `
+Labels: Author Name, Double
+Format: auto, auto
+Data: 1:n:name, 1:func:double()
+John, 000000 JohnJohn
+`[1:] {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	if err := RegisterFunction("count", double); err == nil || err.Error() != "Cannot replace built-in function: count" {
+		t.Error(err)
+		return
+	}
+}
+
 func TestParseQuery(t *testing.T) {
 	res, _ := ParseQuery("test", "get Author with ordering(ascending key)")
 	if res.String() != `
@@ -166,6 +302,32 @@ get
 
 }
 
+func TestQueryContext(t *testing.T) {
+	gm, _ := songGraph()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if res, err := RunCountQueryContext(ctx, "test", "main", "count Song", gm); err != nil || res != 9 {
+		t.Error("Unexpected result: ", res, err)
+		return
+	}
+
+	cancel()
+
+	if _, err := RunQueryContext(ctx, "test", "main", "get Author traverse :::Song end", gm); err == nil ||
+		err.(*interpreter.RuntimeError).Type != ErrCancelled {
+		t.Error(err)
+		return
+	}
+
+	if _, err := RunCountQueryContext(ctx, "test", "main", "count Song", gm); err == nil ||
+		err.Error() != "EQL error in test: Query was cancelled (context canceled)" {
+		t.Error(err)
+		return
+	}
+}
+
 func songGraph() (*graph.Manager, *graphstorage.MemoryGraphStorage) {
 
 	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
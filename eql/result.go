@@ -84,6 +84,13 @@ type SearchResult interface {
 	*/
 	RowSources() [][]string
 
+	/*
+	   UniqueCounts returns the distinct values of a column which is filtered
+	   with unique or uniquecount and the number of occurrences of each value.
+	   The lists are nil if the column is not filtered with unique or uniquecount.
+	*/
+	UniqueCounts(col int) ([]interface{}, []int)
+
 	/*
 		String returns a string representation of this search result.
 	*/
@@ -93,4 +100,10 @@ type SearchResult interface {
 	   CSV returns this search result as comma-separated strings.
 	*/
 	CSV() string
+
+	/*
+	   Close releases all resources of this search result. A result which was
+	   written to a spill file has no rows afterwards.
+	*/
+	Close() error
 }
@@ -0,0 +1,404 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/hash"
+)
+
+/*
+CreateIndex creates an index on an attribute of a node kind in a partition.
+The index maps attribute values to node keys and is updated when nodes are
+stored or removed. All existing nodes are added to the new index.
+*/
+func (gm *Manager) CreateIndex(part string, kind string, attr string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	if attr == "" || attr == data.NodeKey || attr == data.NodeKind {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Cannot index attribute %#v", attr),
+		}
+	}
+
+	attTree, valTree, err := gm.getNodeStorageHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	if err := gm.createAttrIndex(part, kind, attr, attTree, valTree, aiht); err != nil {
+		return err
+	}
+
+	gm.flushMain()
+
+	return gm.flushNodeIndex(part, kind)
+}
+
+/*
+createAttrIndex creates an index on an attribute and adds all existing nodes
+to it. It is assumed that the caller holds the writer lock.
+*/
+func (gm *Manager) createAttrIndex(part string, kind string, attr string, attTree *hash.HTree,
+	valTree *hash.HTree, aiht *hash.HTree) error {
+
+	indexes := gm.attrIndexes(part, kind)
+
+	if _, ok := indexes[attr]; ok {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Index on %v.%v already exists in partition %v", kind, attr, part),
+		}
+	}
+
+	// Add all existing nodes to the index
+
+	it := hash.NewHTreeIterator(attTree)
+
+	for it.HasNext() {
+		k, _ := it.Next()
+
+		if it.LastError != nil {
+			return &util.GraphError{Type: util.ErrReading, Detail: it.LastError.Error()}
+		}
+
+		if !strings.HasPrefix(string(k), PrefixNSAttrs) {
+			continue
+		}
+
+		key := string(k[len(PrefixNSAttrs):])
+
+		node, err := gm.readNode(key, kind, []string{attr}, attTree, valTree)
+		if err != nil {
+			return err
+		}
+
+		if node != nil {
+			if val := node.Attr(attr); val != nil {
+				if err := gm.addAttrIndexEntry(aiht, attrIndexKey(attr, val), key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	indexes[attr] = ""
+	gm.storeMainDBMap(MainDBNodeIndexes+part+"#"+kind, indexes)
+
+	return nil
+}
+
+/*
+DropIndex removes an index on an attribute of a node kind in a partition.
+*/
+func (gm *Manager) DropIndex(part string, kind string, attr string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, false)
+	if err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	if _, ok := gm.uniqueConstraints(part, kind)[attr]; ok {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Index on %v.%v is used by a unique constraint in partition %v", kind, attr, part),
+		}
+	}
+
+	if err := gm.dropAttrIndex(part, kind, attr, aiht); err != nil {
+		return err
+	}
+
+	gm.flushMain()
+
+	return gm.flushNodeIndex(part, kind)
+}
+
+/*
+dropAttrIndex removes an index on an attribute and all its entries. It is
+assumed that the caller holds the writer lock.
+*/
+func (gm *Manager) dropAttrIndex(part string, kind string, attr string, aiht *hash.HTree) error {
+
+	indexes := gm.attrIndexes(part, kind)
+
+	if _, ok := indexes[attr]; !ok || aiht == nil {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Index on %v.%v does not exist in partition %v", kind, attr, part),
+		}
+	}
+
+	// Collect and remove all index entries of the attribute
+
+	var entries [][]byte
+
+	prefix := attr + "\x00"
+	it := hash.NewHTreeIterator(aiht)
+
+	for it.HasNext() {
+		k, _ := it.Next()
+
+		if it.LastError != nil {
+			return &util.GraphError{Type: util.ErrReading, Detail: it.LastError.Error()}
+		}
+
+		if strings.HasPrefix(string(k), prefix) {
+			entries = append(entries, k)
+		}
+	}
+
+	for _, k := range entries {
+		if _, err := aiht.Remove(k); err != nil {
+			return &util.GraphError{Type: util.ErrWriting, Detail: err.Error()}
+		}
+	}
+
+	delete(indexes, attr)
+	gm.storeMainDBMap(MainDBNodeIndexes+part+"#"+kind, indexes)
+
+	return nil
+}
+
+/*
+Indexes returns all indexed attributes of a node kind in a partition.
+*/
+func (gm *Manager) Indexes(part string, kind string) []string {
+	var ret []string
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	for attr := range gm.attrIndexes(part, kind) {
+		ret = append(ret, attr)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+/*
+HasIndex checks if an attribute of a node kind in a partition is indexed.
+*/
+func (gm *Manager) HasIndex(part string, kind string, attr string) bool {
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	_, ok := gm.attrIndexes(part, kind)[attr]
+
+	return ok
+}
+
+/*
+LookupIndex returns the keys of all nodes of a kind in a partition where an
+indexed attribute has a given value. Values which are numbers are compared
+by their numeric value.
+*/
+func (gm *Manager) LookupIndex(part string, kind string, attr string, value interface{}) ([]string, error) {
+
+	if !gm.HasIndex(part, kind, attr) {
+		return nil, &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Index on %v.%v does not exist in partition %v", kind, attr, part),
+		}
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, false)
+	if err != nil || aiht == nil {
+		return nil, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	obj, err := aiht.Get(attrIndexKey(attr, value))
+	if err != nil {
+		return nil, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+	}
+
+	var ret []string
+
+	if obj != nil {
+		for key := range obj.(map[string]string) {
+			ret = append(ret, key)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+/*
+updateAttrIndexes updates all attribute and word indexes of a node kind. The new node
+is nil if a node was removed and the old node is nil if a node was inserted.
+Attributes which are missing from the new node are not changed if only an
+update was done. It is assumed that the caller holds the writer lock.
+*/
+func (gm *Manager) updateAttrIndexes(part string, kind string, key string, node data.Node,
+	oldnode data.Node, onlyUpdate bool) error {
+
+	indexes := gm.attrIndexes(part, kind)
+	wordIndexes := gm.wordIndexes(part, kind)
+
+	if len(indexes) == 0 && len(wordIndexes) == 0 {
+		return nil
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	for attr := range indexes {
+		var val, oldval interface{}
+
+		if node != nil {
+			if _, ok := node.Data()[attr]; !ok && onlyUpdate {
+				continue
+			}
+			val = node.Attr(attr)
+		}
+
+		if oldnode != nil {
+			oldval = oldnode.Attr(attr)
+		}
+
+		if oldval != nil {
+			if err := gm.removeAttrIndexEntry(aiht, attrIndexKey(attr, oldval), key); err != nil {
+				return err
+			}
+		}
+
+		if val != nil {
+			if err := gm.addAttrIndexEntry(aiht, attrIndexKey(attr, val), key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return gm.updateWordIndexes(aiht, wordIndexes, key, node, oldnode, onlyUpdate)
+}
+
+/*
+attrIndexes returns the indexed attributes of a node kind in a partition.
+*/
+func (gm *Manager) attrIndexes(part string, kind string) map[string]string {
+	indexes := gm.getMainDBMap(MainDBNodeIndexes + part + "#" + kind)
+
+	if indexes == nil {
+		indexes = make(map[string]string)
+	}
+
+	return indexes
+}
+
+/*
+addAttrIndexEntry adds a node key to an index entry.
+*/
+func (gm *Manager) addAttrIndexEntry(aiht *hash.HTree, indexKey []byte, key string) error {
+
+	obj, err := aiht.Get(indexKey)
+	if err != nil {
+		return &util.GraphError{Type: util.ErrIndexError, Detail: err.Error()}
+	}
+
+	keys, ok := obj.(map[string]string)
+	if !ok {
+		keys = make(map[string]string)
+	}
+
+	keys[key] = ""
+
+	if _, err := aiht.Put(indexKey, keys); err != nil {
+		return &util.GraphError{Type: util.ErrIndexError, Detail: err.Error()}
+	}
+
+	return nil
+}
+
+/*
+removeAttrIndexEntry removes a node key from an index entry.
+*/
+func (gm *Manager) removeAttrIndexEntry(aiht *hash.HTree, indexKey []byte, key string) error {
+
+	obj, err := aiht.Get(indexKey)
+	if err != nil {
+		return &util.GraphError{Type: util.ErrIndexError, Detail: err.Error()}
+	}
+
+	keys, ok := obj.(map[string]string)
+	if !ok {
+		return nil
+	}
+
+	delete(keys, key)
+
+	if len(keys) == 0 {
+		_, err = aiht.Remove(indexKey)
+	} else {
+		_, err = aiht.Put(indexKey, keys)
+	}
+
+	if err != nil {
+		return &util.GraphError{Type: util.ErrIndexError, Detail: err.Error()}
+	}
+
+	return nil
+}
+
+/*
+attrIndexKey returns the index key for an attribute value. Numbers are stored
+in a canonical form so different notations of the same number share the same
+index entry.
+*/
+func attrIndexKey(attr string, value interface{}) []byte {
+	val := fmt.Sprint(value)
+
+	if num, err := strconv.ParseFloat(val, 64); err == nil {
+		return []byte(attr + "\x00\x01" + strconv.FormatFloat(num, 'g', -1, 64))
+	}
+
+	return []byte(attr + "\x00\x00" + val)
+}
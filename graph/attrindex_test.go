@@ -0,0 +1,201 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+)
+
+func TestAttrIndex(t *testing.T) {
+
+	gs := graphstorage.NewMemoryGraphStorage("test")
+	gm := NewGraphManager(gs)
+
+	storeNode := func(key string, name string, val interface{}) {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "mynode")
+		node.SetAttr("name", name)
+		node.SetAttr("val", val)
+
+		if err := gm.StoreNode("main", node); err != nil {
+			t.Error(err)
+		}
+	}
+
+	lookup := func(attr string, val interface{}) string {
+		res, err := gm.LookupIndex("main", "mynode", attr, val)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprint(res)
+	}
+
+	storeNode("1", "foo", 1)
+	storeNode("2", "bar", "1")
+	storeNode("3", "foo", 2)
+
+	// Existing nodes are added to a new index
+
+	if err := gm.CreateIndex("main", "mynode", "name"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("name", "foo"); res != "[1 3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if err := gm.CreateIndex("main", "mynode", "val"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Different notations of the same number share an index entry
+
+	if res := lookup("val", 1.0); res != "[1 2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(gm.Indexes("main", "mynode")); res != "[name val]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if !gm.HasIndex("main", "mynode", "name") || gm.HasIndex("main", "mynode", "foo") ||
+		gm.HasIndex("test", "mynode", "name") {
+		t.Error("Unexpected index state")
+		return
+	}
+
+	// Indexes are updated when nodes are stored, updated or removed
+
+	storeNode("3", "bar", 2)
+	storeNode("4", "foo", 5)
+
+	if res := lookup("name", "foo"); res != "[1 4]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "1")
+	node.SetAttr("kind", "mynode")
+	node.SetAttr("val", 5)
+
+	if err := gm.UpdateNode("main", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("name", "foo"); res != "[1 4]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lookup("val", "5"); res != "[1 4]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if _, err := gm.RemoveNode("main", "4", "mynode"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("name", "foo"); res != "[1]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Indexes are updated by transactions
+
+	trans := NewGraphTrans(gm)
+
+	node = data.NewGraphNode()
+	node.SetAttr("key", "5")
+	node.SetAttr("kind", "mynode")
+	node.SetAttr("name", "foo")
+
+	trans.StoreNode("main", node)
+	trans.RemoveNode("main", "1", "mynode")
+
+	if err := trans.Commit(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("name", "foo"); res != "[5]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lookup("name", "xxx"); res != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Test error cases
+
+	if err := gm.CreateIndex("main", "mynode", "name"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Index on mynode.name already exists in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.CreateIndex("main", "mynode", "key"); err == nil || err.Error() !=
+		`GraphError: Invalid data (Cannot index attribute "key")` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.DropIndex("main", "mynode", "foo"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Index on mynode.foo does not exist in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Drop an index
+
+	if err := gm.DropIndex("main", "mynode", "name"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("name", "foo"); res !=
+		"GraphError: Invalid data (Index on mynode.name does not exist in partition main)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(gm.Indexes("main", "mynode")); res != "[val]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// A recreated index contains the current nodes
+
+	if err := gm.CreateIndex("main", "mynode", "name"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("name", "bar"); res != "[2 3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -0,0 +1,355 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+
+	"devt.de/krotik/eliasdb/graph/data"
+)
+
+/*
+ExportPartitionGraphML writes the contents of a partition to an io.Writer as
+GraphML document. Nodes and edges are streamed one at a time.
+*/
+func ExportPartitionGraphML(out io.Writer, part string, gm *Manager) error {
+	return exportPartitionXML(part, gm, &graphMLWriter{&xmlDocWriter{out: out}})
+}
+
+/*
+ExportPartitionGEXF writes the contents of a partition to an io.Writer as
+GEXF document. Nodes and edges are streamed one at a time.
+*/
+func ExportPartitionGEXF(out io.Writer, part string, gm *Manager) error {
+	return exportPartitionXML(part, gm, &gexfWriter{&xmlDocWriter{out: out}})
+}
+
+/*
+xmlGraphWriter writes nodes and edges of a graph in a particular XML format.
+All nodes are written before the first edge.
+*/
+type xmlGraphWriter interface {
+
+	/*
+		writeHeader writes the document header declaring all node and edge attributes.
+	*/
+	writeHeader(nodeAttrs []string, edgeAttrs []string)
+
+	/*
+		writeNode writes a single node.
+	*/
+	writeNode(node data.Node)
+
+	/*
+		writeEdgesStart is called once all nodes have been written.
+	*/
+	writeEdgesStart()
+
+	/*
+		writeEdge writes a single edge.
+	*/
+	writeEdge(edge data.Edge)
+
+	/*
+		writeFooter writes the end of the document.
+	*/
+	writeFooter()
+}
+
+/*
+exportPartitionXML iterates over all nodes and edges of a partition and
+writes them with a given xmlGraphWriter.
+*/
+func exportPartitionXML(part string, gm *Manager, w xmlGraphWriter) error {
+
+	// Loop over all available kinds and build iterators if nodes
+	// or edges exist in the given partition
+
+	var nodeIters []*NodeKeyIterator
+	var nodeKinds []string
+	var edgeIters []*EdgeKeyIterator
+	var edgeKinds []string
+
+	for _, k := range gm.NodeKinds() {
+
+		it, err := gm.NodeKeyIterator(part, k)
+		if err != nil {
+			return err
+		}
+		if it != nil {
+			nodeIters = append(nodeIters, it)
+			nodeKinds = append(nodeKinds, k)
+		}
+	}
+
+	for _, k := range gm.EdgeKinds() {
+
+		it, err := gm.EdgeKeyIterator(part, k)
+		if err != nil {
+			return err
+		}
+		if it != nil {
+			edgeIters = append(edgeIters, it)
+			edgeKinds = append(edgeKinds, k)
+		}
+	}
+
+	// Attributes need to be declared in the header
+
+	collectAttrs := func(kinds []string, attrs func(string) []string) []string {
+		attrMap := make(map[string]bool)
+		for _, k := range kinds {
+			for _, attr := range attrs(k) {
+				attrMap[attr] = true
+			}
+		}
+
+		ret := make([]string, 0, len(attrMap))
+		for attr := range attrMap {
+			ret = append(ret, attr)
+		}
+		sort.Strings(ret)
+
+		return ret
+	}
+
+	w.writeHeader(collectAttrs(nodeKinds, gm.NodeAttrs), collectAttrs(edgeKinds, gm.EdgeAttrs))
+
+	for i, it := range nodeIters {
+
+		for it.HasNext() {
+			key := it.Next()
+
+			if it.LastError != nil {
+				return it.LastError
+			}
+
+			node, err := gm.FetchNode(part, key, nodeKinds[i])
+			if err != nil {
+				return err
+			} else if node != nil {
+				w.writeNode(node)
+			}
+		}
+	}
+
+	w.writeEdgesStart()
+
+	for i, it := range edgeIters {
+
+		for it.HasNext() {
+			key := it.Next()
+
+			if it.LastError != nil {
+				return it.LastError
+			}
+
+			edge, err := gm.FetchEdge(part, key, edgeKinds[i])
+			if err != nil {
+				return err
+			} else if edge != nil {
+				w.writeEdge(edge)
+			}
+		}
+	}
+
+	w.writeFooter()
+
+	return nil
+}
+
+/*
+xmlEntityID returns the document ID of a node or edge. Keys are only unique
+within a kind.
+*/
+func xmlEntityID(key string, kind string) string {
+	return kind + ":" + key
+}
+
+/*
+xmlEscape escapes a given value for use in XML attributes and text. Values
+which are not strings are JSON encoded.
+*/
+func xmlEscape(v interface{}) string {
+	var buf bytes.Buffer
+
+	s, ok := v.(string)
+	if !ok {
+		jv, err := json.Marshal(v)
+		if err != nil {
+			jv = []byte("null")
+		}
+		s = string(jv)
+	}
+
+	xml.EscapeText(&buf, []byte(s))
+
+	return buf.String()
+}
+
+/*
+xmlDocWriter holds the state which is shared by all XML graph writers.
+*/
+type xmlDocWriter struct {
+	out         io.Writer         // Writer which receives the document
+	nodeAttrs   []string          // Declared node attributes
+	edgeAttrs   []string          // Declared edge attributes
+	nodeAttrIDs map[string]string // Map of node attributes to document IDs
+	edgeAttrIDs map[string]string // Map of edge attributes to document IDs
+}
+
+/*
+declareAttrs assigns document IDs to all node and edge attributes.
+*/
+func (w *xmlDocWriter) declareAttrs(nodeAttrs []string, edgeAttrs []string,
+	nodePrefix string, edgePrefix string) {
+
+	attrIDs := func(prefix string, attrs []string) map[string]string {
+		ret := make(map[string]string)
+		for i, attr := range attrs {
+			ret[attr] = fmt.Sprintf("%s%v", prefix, i)
+		}
+		return ret
+	}
+
+	w.nodeAttrs = nodeAttrs
+	w.edgeAttrs = edgeAttrs
+	w.nodeAttrIDs = attrIDs(nodePrefix, nodeAttrs)
+	w.edgeAttrIDs = attrIDs(edgePrefix, edgeAttrs)
+}
+
+/*
+writeValues writes all attribute values of a node or edge using a given
+line format which receives the attribute ID and the escaped value.
+*/
+func (w *xmlDocWriter) writeValues(node data.Node, attrs []string, ids map[string]string, format string) {
+	for _, attr := range attrs {
+		if v := node.Attr(attr); v != nil {
+			fmt.Fprintf(w.out, format, ids[attr], xmlEscape(v))
+		}
+	}
+}
+
+// GraphML writer
+// ==============
+
+/*
+graphMLWriter writes a GraphML document.
+*/
+type graphMLWriter struct {
+	*xmlDocWriter
+}
+
+func (w *graphMLWriter) writeHeader(nodeAttrs []string, edgeAttrs []string) {
+	w.declareAttrs(nodeAttrs, edgeAttrs, "n", "e")
+
+	fmt.Fprint(w.out, `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+`)
+
+	for _, attr := range nodeAttrs {
+		fmt.Fprintf(w.out, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"string\"/>\n",
+			w.nodeAttrIDs[attr], xmlEscape(attr))
+	}
+
+	for _, attr := range edgeAttrs {
+		fmt.Fprintf(w.out, "  <key id=\"%s\" for=\"edge\" attr.name=\"%s\" attr.type=\"string\"/>\n",
+			w.edgeAttrIDs[attr], xmlEscape(attr))
+	}
+
+	fmt.Fprint(w.out, "  <graph edgedefault=\"undirected\">\n")
+}
+
+func (w *graphMLWriter) writeNode(node data.Node) {
+	fmt.Fprintf(w.out, "    <node id=\"%s\">\n", xmlEscape(xmlEntityID(node.Key(), node.Kind())))
+	w.writeValues(node, w.nodeAttrs, w.nodeAttrIDs, "      <data key=\"%s\">%s</data>\n")
+	fmt.Fprint(w.out, "    </node>\n")
+}
+
+func (w *graphMLWriter) writeEdgesStart() {
+}
+
+func (w *graphMLWriter) writeEdge(edge data.Edge) {
+	fmt.Fprintf(w.out, "    <edge id=\"%s\" source=\"%s\" target=\"%s\">\n",
+		xmlEscape(xmlEntityID(edge.Key(), edge.Kind())),
+		xmlEscape(xmlEntityID(edge.End1Key(), edge.End1Kind())),
+		xmlEscape(xmlEntityID(edge.End2Key(), edge.End2Kind())))
+	w.writeValues(edge, w.edgeAttrs, w.edgeAttrIDs, "      <data key=\"%s\">%s</data>\n")
+	fmt.Fprint(w.out, "    </edge>\n")
+}
+
+func (w *graphMLWriter) writeFooter() {
+	fmt.Fprint(w.out, "  </graph>\n</graphml>\n")
+}
+
+// GEXF writer
+// ===========
+
+/*
+gexfWriter writes a GEXF document.
+*/
+type gexfWriter struct {
+	*xmlDocWriter
+}
+
+func (w *gexfWriter) writeHeader(nodeAttrs []string, edgeAttrs []string) {
+	w.declareAttrs(nodeAttrs, edgeAttrs, "n", "e")
+
+	fmt.Fprint(w.out, `<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://www.gexf.net/1.2draft" version="1.2">
+  <graph mode="static" defaultedgetype="undirected">
+    <attributes class="node">
+`)
+
+	for _, attr := range nodeAttrs {
+		fmt.Fprintf(w.out, "      <attribute id=\"%s\" title=\"%s\" type=\"string\"/>\n",
+			w.nodeAttrIDs[attr], xmlEscape(attr))
+	}
+
+	fmt.Fprint(w.out, "    </attributes>\n    <attributes class=\"edge\">\n")
+
+	for _, attr := range edgeAttrs {
+		fmt.Fprintf(w.out, "      <attribute id=\"%s\" title=\"%s\" type=\"string\"/>\n",
+			w.edgeAttrIDs[attr], xmlEscape(attr))
+	}
+
+	fmt.Fprint(w.out, "    </attributes>\n    <nodes>\n")
+}
+
+func (w *gexfWriter) writeNode(node data.Node) {
+	fmt.Fprintf(w.out, "      <node id=\"%s\" label=\"%s\">\n        <attvalues>\n",
+		xmlEscape(xmlEntityID(node.Key(), node.Kind())), xmlEscape(node.Key()))
+	w.writeValues(node, w.nodeAttrs, w.nodeAttrIDs, "          <attvalue for=\"%s\" value=\"%s\"/>\n")
+	fmt.Fprint(w.out, "        </attvalues>\n      </node>\n")
+}
+
+func (w *gexfWriter) writeEdgesStart() {
+	fmt.Fprint(w.out, "    </nodes>\n    <edges>\n")
+}
+
+func (w *gexfWriter) writeEdge(edge data.Edge) {
+	fmt.Fprintf(w.out, "      <edge id=\"%s\" source=\"%s\" target=\"%s\" label=\"%s\">\n        <attvalues>\n",
+		xmlEscape(xmlEntityID(edge.Key(), edge.Kind())),
+		xmlEscape(xmlEntityID(edge.End1Key(), edge.End1Kind())),
+		xmlEscape(xmlEntityID(edge.End2Key(), edge.End2Kind())),
+		xmlEscape(edge.Kind()))
+	w.writeValues(edge, w.edgeAttrs, w.edgeAttrIDs, "          <attvalue for=\"%s\" value=\"%s\"/>\n")
+	fmt.Fprint(w.out, "        </attvalues>\n      </edge>\n")
+}
+
+func (w *gexfWriter) writeFooter() {
+	fmt.Fprint(w.out, "    </edges>\n  </graph>\n</gexf>\n")
+}
@@ -0,0 +1,144 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+)
+
+func TestExportXML(t *testing.T) {
+	var res bytes.Buffer
+
+	gs := graphstorage.NewMemoryGraphStorage("test")
+	gm := NewGraphManager(gs)
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "1")
+	node1.SetAttr("kind", "Author")
+	node1.SetAttr("name", "John <Doe>")
+	gm.StoreNode("main", node1)
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "2")
+	node2.SetAttr("kind", "Song")
+	node2.SetAttr("ranking", 5)
+	gm.StoreNode("main", node2)
+
+	edge := data.NewGraphEdge()
+	edge.SetAttr("key", "3")
+	edge.SetAttr("kind", "Wrote")
+	edge.SetAttr(data.EdgeEnd1Key, node1.Key())
+	edge.SetAttr(data.EdgeEnd1Kind, node1.Kind())
+	edge.SetAttr(data.EdgeEnd1Role, "Author")
+	edge.SetAttr(data.EdgeEnd1Cascading, true)
+	edge.SetAttr(data.EdgeEnd2Key, node2.Key())
+	edge.SetAttr(data.EdgeEnd2Kind, node2.Kind())
+	edge.SetAttr(data.EdgeEnd2Role, "Song")
+	edge.SetAttr(data.EdgeEnd2Cascading, false)
+	gm.StoreEdge("main", edge)
+
+	// Nodes in other partitions are not exported
+
+	gm.StoreNode("other", node1)
+
+	if err := ExportPartitionGraphML(&res, "main", gm); err != nil || res.String() != `
+<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="n0" for="node" attr.name="key" attr.type="string"/>
+  <key id="n1" for="node" attr.name="kind" attr.type="string"/>
+  <key id="n2" for="node" attr.name="name" attr.type="string"/>
+  <key id="n3" for="node" attr.name="ranking" attr.type="string"/>
+  <key id="e0" for="edge" attr.name="end1cascading" attr.type="string"/>
+  <key id="e1" for="edge" attr.name="end1key" attr.type="string"/>
+  <key id="e2" for="edge" attr.name="end1kind" attr.type="string"/>
+  <key id="e3" for="edge" attr.name="end1role" attr.type="string"/>
+  <key id="e4" for="edge" attr.name="end2cascading" attr.type="string"/>
+  <key id="e5" for="edge" attr.name="end2key" attr.type="string"/>
+  <key id="e6" for="edge" attr.name="end2kind" attr.type="string"/>
+  <key id="e7" for="edge" attr.name="end2role" attr.type="string"/>
+  <key id="e8" for="edge" attr.name="key" attr.type="string"/>
+  <key id="e9" for="edge" attr.name="kind" attr.type="string"/>
+  <graph edgedefault="undirected">
+    <node id="Author:1">
+      <data key="n0">1</data>
+      <data key="n1">Author</data>
+      <data key="n2">John &lt;Doe&gt;</data>
+    </node>
+    <node id="Song:2">
+      <data key="n0">2</data>
+      <data key="n1">Song</data>
+      <data key="n3">5</data>
+    </node>
+    <edge id="Wrote:3" source="Author:1" target="Song:2">
+      <data key="e0">true</data>
+      <data key="e1">1</data>
+      <data key="e2">Author</data>
+      <data key="e3">Author</data>
+      <data key="e4">false</data>
+      <data key="e5">2</data>
+      <data key="e6">Song</data>
+      <data key="e7">Song</data>
+      <data key="e8">3</data>
+      <data key="e9">Wrote</data>
+    </edge>
+  </graph>
+</graphml>
+`[1:] {
+		t.Error("Unexpected result:", res.String(), err)
+		return
+	}
+
+	res.Reset()
+
+	if err := ExportPartitionGEXF(&res, "other", gm); err != nil || res.String() != `
+<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://www.gexf.net/1.2draft" version="1.2">
+  <graph mode="static" defaultedgetype="undirected">
+    <attributes class="node">
+      <attribute id="n0" title="key" type="string"/>
+      <attribute id="n1" title="kind" type="string"/>
+      <attribute id="n2" title="name" type="string"/>
+    </attributes>
+    <attributes class="edge">
+    </attributes>
+    <nodes>
+      <node id="Author:1" label="1">
+        <attvalues>
+          <attvalue for="n0" value="1"/>
+          <attvalue for="n1" value="Author"/>
+          <attvalue for="n2" value="John &lt;Doe&gt;"/>
+        </attvalues>
+      </node>
+    </nodes>
+    <edges>
+    </edges>
+  </graph>
+</gexf>
+`[1:] {
+		t.Error("Unexpected result:", res.String(), err)
+		return
+	}
+
+	res.Reset()
+
+	if err := ExportPartitionGEXF(&res, "main", gm); err != nil || !bytes.Contains(res.Bytes(), []byte(`
+    <edges>
+      <edge id="Wrote:3" source="Author:1" target="Song:2" label="Wrote">
+        <attvalues>
+          <attvalue for="e0" value="true"/>`)) {
+		t.Error("Unexpected result:", res.String(), err)
+		return
+	}
+}
@@ -83,6 +83,9 @@ Each node kind database stores:
 	PrefixNSEdge + node key + spec -> map[edge key]edgeinfo{other node key, other node kind}]
 	(connection from one node to another via a spec)
 
+	PrefixNSVersion + node key -> version
+	(version of a certain node which is increased on every write)
+
 Edges database
 
 Each edge kind database stores:
@@ -93,10 +96,23 @@ Each edge kind database stores:
 	PrefixNSAttr + edge key + attr num -> value
 	(attribute value of a certain edge)
 
+	PrefixNSVersion + edge key -> version
+	(version of a certain edge which is increased on every write)
+
 Index database
 
 The text index managed by util/indexmanager.go. IndexQuery provides access to
 the full text search index.
+
+Attribute indexes which were created with CreateIndex are stored in a second
+HTree of the index database:
+
+	attr + 0x00 + value -> map[node key]<empty string>
+	(all nodes which have a certain attribute value)
+
+	0x01 + attr + 0x00 + word -> map[node key]<empty string>
+	(all nodes which contain a certain folded word in an attribute which has
+	a word index created with CreateWordIndex)
 */
 package graph
 
@@ -158,6 +174,26 @@ MainDBEdgeCount is the MainDB entry key for an edge count
 */
 const MainDBEdgeCount = MainDBEntryPrefix + "ecnt"
 
+/*
+MainDBNodeIndexes is the MainDB entry key for a list of indexed node attributes
+*/
+const MainDBNodeIndexes = MainDBEntryPrefix + "nidx"
+
+/*
+MainDBNodeWordIndexes is the MainDB entry key for a list of word indexed node attributes
+*/
+const MainDBNodeWordIndexes = MainDBEntryPrefix + "nwidx"
+
+/*
+MainDBNodeUniqueConstraints is the MainDB entry key for a list of node attributes with a unique constraint
+*/
+const MainDBNodeUniqueConstraints = MainDBEntryPrefix + "nuniq"
+
+/*
+MainDBNodeSchema is the MainDB entry key for the JSON Schema of a node kind
+*/
+const MainDBNodeSchema = MainDBEntryPrefix + "nschema"
+
 // Root IDs for StorageManagers
 // ============================
 
@@ -221,6 +257,11 @@ PrefixNSEdge is the prefix for storing a link from a node (and a spec) to an edg
 */
 const PrefixNSEdge = "\x04"
 
+/*
+PrefixNSVersion is the prefix for storing the version of a node or edge
+*/
+const PrefixNSVersion = "\x05"
+
 // Graph events
 //=============
 
@@ -15,10 +15,12 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"devt.de/krotik/eliasdb/graph/data"
 	"devt.de/krotik/eliasdb/graph/graphstorage"
 	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/hash"
 )
 
 /*
@@ -31,6 +33,12 @@ type Manager struct {
 	mapCache     map[string]map[string]string // Cache which caches maps stored in the main database
 	mutex        *sync.RWMutex                // Mutex to protect atomic graph operations
 	storageMutex *sync.Mutex                  // Special mutex for storage object access
+	mainDBMutex  *sync.RWMutex                // Mutex to protect the main database
+
+	schemaVersion *uint64 // Number of changes to the stored kinds, attributes, edge specs and indices
+
+	readOnly      map[string]bool // Read-only partitions (an empty name marks all partitions)
+	readOnlyMutex *sync.RWMutex   // Mutex to protect the read-only flags
 }
 
 /*
@@ -75,15 +83,55 @@ func createGraphManager(gs graphstorage.Storage) *Manager {
 		}
 	}
 
+	mainDBMutex := &sync.RWMutex{}
+
 	gm := &Manager{gs, &graphRulesManager{nil, make(map[string]Rule),
-		make(map[int]map[string]Rule)}, util.NewNamesManager(mdb),
-		make(map[string]map[string]string), &sync.RWMutex{}, &sync.Mutex{}}
+		make(map[int]map[string]Rule)}, util.NewNamesManagerWithMutex(mdb, mainDBMutex),
+		make(map[string]map[string]string), &sync.RWMutex{}, &sync.Mutex{}, mainDBMutex,
+		new(uint64), make(map[string]bool), &sync.RWMutex{}}
 
 	gm.gr.gm = gm
 
 	return gm
 }
 
+/*
+SchemaVersion returns a version stamp of the stored schema. The version changes
+whenever a partition, node or edge kind, attribute, edge spec, index or unique
+constraint is added or removed. The version is not persisted and can be used
+to invalidate data which depends on the schema (e.g. cached query plans).
+*/
+func (gm *Manager) SchemaVersion() uint64 {
+	return atomic.LoadUint64(gm.schemaVersion)
+}
+
+/*
+SetReadOnly sets if a partition is read-only. An empty partition name applies
+to all partitions. All operations which change a read-only partition fail
+with an ErrReadOnly error.
+*/
+func (gm *Manager) SetReadOnly(part string, readonly bool) {
+	gm.readOnlyMutex.Lock()
+	defer gm.readOnlyMutex.Unlock()
+
+	if readonly {
+		gm.readOnly[part] = true
+	} else {
+		delete(gm.readOnly, part)
+	}
+}
+
+/*
+IsReadOnly returns if a partition is read-only. A partition is read-only if
+either the partition itself or all partitions were set read-only.
+*/
+func (gm *Manager) IsReadOnly(part string) bool {
+	gm.readOnlyMutex.RLock()
+	defer gm.readOnlyMutex.RUnlock()
+
+	return gm.readOnly[""] || gm.readOnly[part]
+}
+
 /*
 Name returns the name of this graph manager.
 */
@@ -171,6 +219,43 @@ func (gm *Manager) EdgeAttrs(kind string) []string {
 	return gm.mainStringList(MainDBEdgeAttrs + kind)
 }
 
+/*
+CheckStorage checks that the storage of all node and edge kinds of a given
+partition can be read. Only the root of each storage is read and only the
+reader lock is taken so the check is cheap.
+*/
+func (gm *Manager) CheckStorage(part string) error {
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	check := func(kinds []string, suffix string) error {
+		for _, kind := range kinds {
+
+			sm := gm.gs.StorageManager(part+kind+suffix, false)
+			if sm == nil {
+				continue
+			}
+
+			if loc := sm.Root(RootIDNodeHTree); loc != 0 {
+				if _, err := hash.LoadHTree(sm, loc); err != nil {
+					return &util.GraphError{Type: util.ErrAccessComponent, Detail: err.Error()}
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if err := check(gm.NodeKinds(), StorageSuffixNodes); err != nil {
+		return err
+	}
+
+	return check(gm.EdgeKinds(), StorageSuffixEdges)
+}
+
 /*
 mainStringList return a list in the MainDB.
 */
@@ -47,6 +47,8 @@ func init() {
 EdgeCount returns the edge count for a given edge kind.
 */
 func (gm *Manager) EdgeCount(kind string) uint64 {
+	gm.mainDBMutex.RLock()
+	defer gm.mainDBMutex.RUnlock()
 
 	if val, ok := gm.gs.MainDB()[MainDBEdgeCount+kind]; ok {
 		return binary.LittleEndian.Uint64([]byte(val))
@@ -98,6 +100,112 @@ func (gm *Manager) FetchNodeEdgeSpecs(part string, key string, kind string) ([]s
 	return specsNode, nil
 }
 
+/*
+Degree is the number of incoming and outgoing edges of a node. An edge which
+connects a node with itself is counted as incoming and outgoing edge.
+*/
+type Degree struct {
+	In  int // Number of incoming edges
+	Out int // Number of outgoing edges
+}
+
+/*
+NodeDegree counts the edges of a node by edge kind. Only the edges are read -
+connected nodes are not fetched. Returns nil if the node does not exist.
+*/
+func (gm *Manager) NodeDegree(part string, key string, kind string) (map[string]*Degree, error) {
+
+	attTree, tree, err := gm.getNodeStorageHTree(part, kind, false)
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	if ok, err := attTree.Exists([]byte(PrefixNSAttrs + key)); err != nil {
+		return nil, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+	} else if !ok {
+		return nil, nil
+	}
+
+	degrees := make(map[string]*Degree)
+
+	obj, err := tree.Get([]byte(PrefixNSSpecs + key))
+	if err != nil {
+		return nil, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+	} else if obj == nil {
+		return degrees, nil
+	}
+
+	// An edge can be listed under several specs of a node if it connects
+	// the node with itself
+
+	counted := make(map[string]bool)
+
+	for spec := range obj.(map[string]string) {
+		edgeKind := gm.nm.Decode16(spec[2:4])
+
+		obj, err := tree.Get([]byte(PrefixNSEdge + key + spec))
+		if err != nil {
+			return nil, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+		} else if obj == nil {
+			continue
+		}
+
+		edgeht, err := gm.getEdgeStorageHTree(part, edgeKind, false)
+		if err != nil || edgeht == nil {
+			return nil, err
+		}
+
+		for edgeKey := range obj.(map[string]*edgeTargetInfo) {
+
+			if counted[edgeKind+"#"+edgeKey] {
+				continue
+			}
+
+			counted[edgeKind+"#"+edgeKey] = true
+
+			edgenode, err := gm.readNode(edgeKey, edgeKind, []string{data.EdgeEnd1Key,
+				data.EdgeEnd1Kind, data.EdgeEnd2Key, data.EdgeEnd2Kind}, edgeht, edgeht)
+			if err != nil {
+				return nil, err
+			} else if edgenode == nil {
+				continue
+			}
+
+			degree, ok := degrees[edgeKind]
+			if !ok {
+				degree = &Degree{}
+				degrees[edgeKind] = degree
+			}
+
+			if edgenode.Attr(data.EdgeEnd1Key) == key && edgenode.Attr(data.EdgeEnd1Kind) == kind {
+				degree.Out++
+			}
+
+			if edgenode.Attr(data.EdgeEnd2Key) == key && edgenode.Attr(data.EdgeEnd2Kind) == kind {
+				degree.In++
+			}
+		}
+	}
+
+	return degrees, nil
+}
+
+/*
+Traversal directions. An edge is outgoing for the node which is its first end
+and incoming for the node which is its second end. An edge which connects a
+node with itself is both outgoing and incoming.
+*/
+const (
+	DirectionAny      = ""
+	DirectionOutgoing = "out"
+	DirectionIncoming = "in"
+)
+
 /*
 TraverseMulti traverses from a given node to other nodes following a given
 partial edge spec. Since the edge spec can be partial it is possible to
@@ -109,18 +217,214 @@ the minimal set of attributes will be populated.
 func (gm *Manager) TraverseMulti(part string, key string, kind string,
 	spec string, allData bool) ([]data.Node, []data.Edge, error) {
 
+	return gm.TraverseMultiDirection(part, key, kind, spec, DirectionAny, allData)
+}
+
+/*
+TraverseMultiDirection traverses from a given node to other nodes like
+TraverseMulti but only follows edges of a given direction. Edge specs cannot
+express a direction if both ends of an edge have the same kind and role - the
+direction is then only given by the end which the node occupies in the stored
+edge.
+*/
+func (gm *Manager) TraverseMultiDirection(part string, key string, kind string,
+	spec string, direction string, allData bool) ([]data.Node, []data.Edge, error) {
+
+	if err := checkDirection(direction); err != nil {
+		return nil, nil, err
+	}
+
+	specs, err := gm.matchingEdgeSpecs(part, key, kind, spec)
+	if err != nil || specs == nil {
+		return nil, nil, err
+	}
+
+	// Collect the results of all matching specs
+
+	var nodes []data.Node
+	var edges []data.Edge
+
+	for _, rspec := range specs {
+
+		sn, se, err := gm.traverse(part, key, kind, rspec, direction, allData)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		nodes = append(nodes, sn...)
+		edges = append(edges, se...)
+	}
+
+	return nodes, edges, nil
+}
+
+/*
+EdgeFilter decides if an edge should be followed during a traversal. The
+filter is called with all data of the edge.
+*/
+type EdgeFilter func(edge data.Edge) bool
+
+/*
+TraverseMultiFilter traverses from a given node to other nodes like
+TraverseMultiDirection but only follows edges which match a given filter.
+Nodes and edges always contain all their data. Every reached node is only
+returned once together with the first matching edge which leads to it - a
+node which is only reachable via non-matching edges is not returned.
+*/
+func (gm *Manager) TraverseMultiFilter(part string, key string, kind string,
+	spec string, direction string, filter EdgeFilter) ([]data.Node, []data.Edge, error) {
+
+	it, err := gm.TraverseMultiIterFilter(part, key, kind, spec, direction, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nodes []data.Node
+	var edges []data.Edge
+
+	for it.HasNext() {
+
+		node, edge, err := it.Next()
+		if err != nil {
+			return nil, nil, err
+		} else if node != nil {
+			nodes = append(nodes, node)
+			edges = append(edges, edge)
+		}
+	}
+
+	return nodes, edges, it.Error()
+}
+
+/*
+TraverseMultiIter traverses from a given node to other nodes following a given
+partial edge spec like TraverseMulti. Instead of returning all connected nodes
+and edges at once an iterator is returned which reads the connected nodes and
+edges (with all their data) one at a time.
+*/
+func (gm *Manager) TraverseMultiIter(part string, key string, kind string,
+	spec string) (*TraversalIterator, error) {
+
+	return gm.TraverseMultiIterDirection(part, key, kind, spec, DirectionAny)
+}
+
+/*
+TraverseMultiIterDirection returns an iterator like TraverseMultiIter which
+only follows edges of a given direction.
+*/
+func (gm *Manager) TraverseMultiIterDirection(part string, key string, kind string,
+	spec string, direction string) (*TraversalIterator, error) {
+
+	if err := checkDirection(direction); err != nil {
+		return nil, err
+	}
+
+	specs, err := gm.matchingEdgeSpecs(part, key, kind, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TraversalIterator{gm, part, key, kind, direction, specs, nil, nil, nil,
+		nil, nil, nil, nil, nil}, nil
+}
+
+/*
+TraverseMultiIterFilter returns an iterator like TraverseMultiIterDirection
+which only follows edges that match a given filter. Every reached node is
+only returned once together with the first matching edge which leads to it.
+*/
+func (gm *Manager) TraverseMultiIterFilter(part string, key string, kind string,
+	spec string, direction string, filter EdgeFilter) (*TraversalIterator, error) {
+
+	it, err := gm.TraverseMultiIterDirection(part, key, kind, spec, direction)
+
+	if err == nil && filter != nil {
+		it.filter = filter
+		it.seen = make(map[string]bool)
+	}
+
+	return it, err
+}
+
+/*
+TraverseRoundTrip traverses from a given node to other nodes following a given
+partial edge spec and then follows the inverse spec back to nodes of the start
+node's kind (e.g. the spec :Wrote::Song from an Author returns all authors who
+wrote a song together with the start author). The inverse spec swaps the roles
+of the given spec. Every reached node is only returned once together with the
+edge of the return hop which reached it first. The start node itself is never
+returned - even if it is connected to itself.
+*/
+func (gm *Manager) TraverseRoundTrip(part string, key string, kind string,
+	spec string, allData bool) ([]data.Node, []data.Edge, error) {
+
 	sspec := strings.Split(spec, ":")
 	if len(sspec) != 4 {
 		return nil, nil, &util.GraphError{Type: util.ErrInvalidData, Detail: "Invalid spec: " + spec}
+	}
+
+	inverseSpec := strings.Join([]string{sspec[2], sspec[1], sspec[0], kind}, ":")
+
+	hopNodes, _, err := gm.TraverseMulti(part, key, kind, spec, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nodes []data.Node
+	var edges []data.Edge
+
+	seen := map[string]bool{kind + "#" + key: true}
+
+	for _, hopNode := range hopNodes {
+
+		sn, se, err := gm.TraverseMulti(part, hopNode.Key(), hopNode.Kind(), inverseSpec, allData)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i, n := range sn {
+			if nid := n.Kind() + "#" + n.Key(); !seen[nid] {
+				seen[nid] = true
+				nodes = append(nodes, n)
+				edges = append(edges, se[i])
+			}
+		}
+	}
+
+	return nodes, edges, nil
+}
+
+/*
+checkDirection checks if a given traversal direction is valid.
+*/
+func checkDirection(direction string) error {
+
+	if direction != DirectionAny && direction != DirectionOutgoing && direction != DirectionIncoming {
+		return &util.GraphError{Type: util.ErrInvalidData, Detail: "Invalid direction: " +
+			direction + " (must be " + DirectionOutgoing + " or " + DirectionIncoming + ")"}
+	}
+
+	return nil
+}
+
+/*
+matchingEdgeSpecs returns all fully specified edge specs of a given node which
+match a given partial edge spec.
+*/
+func (gm *Manager) matchingEdgeSpecs(part string, key string, kind string, spec string) ([]string, error) {
+
+	sspec := strings.Split(spec, ":")
+	if len(sspec) != 4 {
+		return nil, &util.GraphError{Type: util.ErrInvalidData, Detail: "Invalid spec: " + spec}
 	} else if IsFullSpec(spec) {
-		return gm.Traverse(part, key, kind, spec, allData)
+		return []string{spec}, nil
 	}
 
 	// Get all specs for the given node
 
 	specs, err := gm.FetchNodeEdgeSpecs(part, key, kind)
 	if err != nil || specs == nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	matchSpec := func(spec string) bool {
@@ -139,25 +443,15 @@ func (gm *Manager) TraverseMulti(part string, key string, kind string,
 		return true
 	}
 
-	// Match specs and collect the results
-
-	var nodes []data.Node
-	var edges []data.Edge
+	matchingSpecs := make([]string, 0, len(specs))
 
 	for _, rspec := range specs {
 		if spec == ":::" || matchSpec(rspec) {
-
-			sn, se, err := gm.Traverse(part, key, kind, rspec, allData)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			nodes = append(nodes, sn...)
-			edges = append(edges, se...)
+			matchingSpecs = append(matchingSpecs, rspec)
 		}
 	}
 
-	return nodes, edges, nil
+	return matchingSpecs, nil
 }
 
 /*
@@ -169,6 +463,16 @@ attributes will be populated.
 func (gm *Manager) Traverse(part string, key string, kind string,
 	spec string, allData bool) ([]data.Node, []data.Edge, error) {
 
+	return gm.traverse(part, key, kind, spec, DirectionAny, allData)
+}
+
+/*
+traverse traverses from a given node to other nodes following a given edge
+spec and only follows edges of a given direction.
+*/
+func (gm *Manager) traverse(part string, key string, kind string,
+	spec string, direction string, allData bool) ([]data.Node, []data.Edge, error) {
+
 	_, tree, err := gm.getNodeStorageHTree(part, kind, false)
 	if err != nil || tree == nil {
 		return nil, nil, err
@@ -179,6 +483,42 @@ func (gm *Manager) Traverse(part string, key string, kind string,
 	gm.mutex.RLock()
 	defer gm.mutex.RUnlock()
 
+	sspec, targetMap, err := gm.fetchEdgeTargets(tree, key, spec)
+	if err != nil || targetMap == nil {
+		return nil, nil, err
+	}
+
+	nodes := make([]data.Node, 0, len(targetMap))
+	edges := make([]data.Edge, 0, len(targetMap))
+
+	for k, v := range targetMap {
+
+		if ok, err := gm.hasDirection(part, key, kind, sspec[1], k, direction); err != nil {
+			return nil, nil, err
+		} else if !ok {
+			continue
+		}
+
+		node, edge, err := gm.readEdgeTarget(part, key, kind, sspec, k, v, allData)
+		if err != nil || node == nil {
+			return nil, nil, err
+		}
+
+		edges = append(edges, edge)
+		nodes = append(nodes, node)
+	}
+
+	return nodes, edges, nil
+}
+
+/*
+fetchEdgeTargets looks up the targets of all edges of a node which match a
+fully specified edge spec. Returns the split spec and a map of edge keys to
+edge target information. The caller must hold the reader lock.
+*/
+func (gm *Manager) fetchEdgeTargets(tree *hash.HTree, key string,
+	spec string) ([]string, map[string]*edgeTargetInfo, error) {
+
 	sspec := strings.Split(spec, ":")
 	if len(sspec) != 4 {
 		return nil, nil, &util.GraphError{Type: util.ErrInvalidData, Detail: "Invalid spec: " + spec}
@@ -196,101 +536,152 @@ func (gm *Manager) Traverse(part string, key string, kind string,
 
 	obj, err := tree.Get([]byte(edgeInfoKey))
 	if err != nil || obj == nil {
-		return nil, nil, err
+		return sspec, nil, err
 	}
 
-	targetMap := obj.(map[string]*edgeTargetInfo)
+	return sspec, obj.(map[string]*edgeTargetInfo), nil
+}
 
-	nodes := make([]data.Node, 0, len(targetMap))
-	edges := make([]data.Edge, 0, len(targetMap))
+/*
+hasDirection checks if a given edge has a given direction from the point of
+view of a given node. The caller must hold the reader lock.
+*/
+func (gm *Manager) hasDirection(part string, key string, kind string,
+	edgeKind string, edgeKey string, direction string) (bool, error) {
 
-	if !allData {
+	if direction == DirectionAny {
+		return true, nil
+	}
 
-		// Populate nodes and edges with the minimal set of attributes
-		// no further lookups required
+	edgeht, err := gm.getEdgeStorageHTree(part, edgeKind, false)
+	if err != nil || edgeht == nil {
+		return false, err
+	}
 
-		for k, v := range targetMap {
+	edgenode, err := gm.readNode(edgeKey, edgeKind, []string{data.EdgeEnd1Key,
+		data.EdgeEnd1Kind, data.EdgeEnd2Key, data.EdgeEnd2Kind}, edgeht, edgeht)
+	if err != nil || edgenode == nil {
+		return false, err
+	}
 
-			edge := data.NewGraphEdge()
+	if direction == DirectionOutgoing {
+		return edgenode.Attr(data.EdgeEnd1Key) == key && edgenode.Attr(data.EdgeEnd1Kind) == kind, nil
+	}
 
-			edge.SetAttr(data.NodeKey, k)
-			edge.SetAttr(data.NodeKind, sspec[1])
+	return edgenode.Attr(data.EdgeEnd2Key) == key && edgenode.Attr(data.EdgeEnd2Kind) == kind, nil
+}
 
-			edge.SetAttr(data.EdgeEnd1Key, key)
-			edge.SetAttr(data.EdgeEnd1Kind, kind)
-			edge.SetAttr(data.EdgeEnd1Role, sspec[0])
-			edge.SetAttr(data.EdgeEnd1Cascading, v.CascadeToTarget)
-			edge.SetAttr(data.EdgeEnd1CascadingLast, v.CascadeLastToTarget)
+/*
+readEdgeTarget reads a single edge and its target node. If allData is false
+only the minimal set of attributes will be populated without any further
+lookups. The caller must hold the reader lock.
+*/
+func (gm *Manager) readEdgeTarget(part string, key string, kind string, sspec []string,
+	edgeKey string, v *edgeTargetInfo, allData bool) (data.Node, data.Edge, error) {
 
-			edge.SetAttr(data.EdgeEnd2Key, v.TargetNodeKey)
-			edge.SetAttr(data.EdgeEnd2Kind, v.TargetNodeKind)
-			edge.SetAttr(data.EdgeEnd2Role, sspec[2])
-			edge.SetAttr(data.EdgeEnd2Cascading, v.CascadeFromTarget)
-			edge.SetAttr(data.EdgeEnd2CascadingLast, v.CascadeLastFromTarget)
+	if !allData {
 
-			edges = append(edges, edge)
+		// Populate node and edge with the minimal set of attributes
 
-			node := data.NewGraphNode()
+		edge := data.NewGraphEdge()
 
-			node.SetAttr(data.NodeKey, v.TargetNodeKey)
-			node.SetAttr(data.NodeKind, v.TargetNodeKind)
+		edge.SetAttr(data.NodeKey, edgeKey)
+		edge.SetAttr(data.NodeKind, sspec[1])
 
-			nodes = append(nodes, node)
-		}
+		edge.SetAttr(data.EdgeEnd1Key, key)
+		edge.SetAttr(data.EdgeEnd1Kind, kind)
+		edge.SetAttr(data.EdgeEnd1Role, sspec[0])
+		edge.SetAttr(data.EdgeEnd1Cascading, v.CascadeToTarget)
+		edge.SetAttr(data.EdgeEnd1CascadingLast, v.CascadeLastToTarget)
 
-	} else {
+		edge.SetAttr(data.EdgeEnd2Key, v.TargetNodeKey)
+		edge.SetAttr(data.EdgeEnd2Kind, v.TargetNodeKind)
+		edge.SetAttr(data.EdgeEnd2Role, sspec[2])
+		edge.SetAttr(data.EdgeEnd2Cascading, v.CascadeFromTarget)
+		edge.SetAttr(data.EdgeEnd2CascadingLast, v.CascadeLastFromTarget)
 
-		// Get the HTrees which stores the edges
+		node := data.NewGraphNode()
 
-		edgeht, err := gm.getEdgeStorageHTree(part, sspec[1], false)
-		if err != nil || edgeht == nil {
-			return nil, nil, err
-		}
+		node.SetAttr(data.NodeKey, v.TargetNodeKey)
+		node.SetAttr(data.NodeKind, v.TargetNodeKind)
 
-		for k, v := range targetMap {
+		return node, edge, nil
+	}
 
-			// Read the edge from the datastore
+	// Get the HTrees which stores the edges
 
-			edgenode, err := gm.readNode(k, sspec[1], nil, edgeht, edgeht)
-			if err != nil || edgenode == nil {
-				return nil, nil, err
-			}
-			edge := data.NewGraphEdgeFromNode(edgenode)
+	edgeht, err := gm.getEdgeStorageHTree(part, sspec[1], false)
+	if err != nil || edgeht == nil {
+		return nil, nil, err
+	}
 
-			// Exchange ends if necessary
+	// Read the edge from the datastore
 
-			if edge.End2Key() == key && edge.End2Kind() == kind {
-				swap := func(attr1 string, attr2 string) {
-					tmp := edge.Attr(attr1)
-					edge.SetAttr(attr1, edge.Attr(attr2))
-					edge.SetAttr(attr2, tmp)
-				}
+	edgenode, err := gm.readNode(edgeKey, sspec[1], nil, edgeht, edgeht)
+	if err != nil || edgenode == nil {
+		return nil, nil, err
+	}
+	edge := data.NewGraphEdgeFromNode(edgenode)
 
-				swap(data.EdgeEnd1Key, data.EdgeEnd2Key)
-				swap(data.EdgeEnd1Kind, data.EdgeEnd2Kind)
-				swap(data.EdgeEnd1Role, data.EdgeEnd2Role)
-				swap(data.EdgeEnd1Cascading, data.EdgeEnd2Cascading)
-			}
+	// Exchange ends if necessary
 
-			edges = append(edges, edge)
+	if edge.End2Key() == key && edge.End2Kind() == kind {
+		swap := func(attr1 string, attr2 string) {
+			tmp := edge.Attr(attr1)
+			edge.SetAttr(attr1, edge.Attr(attr2))
+			edge.SetAttr(attr2, tmp)
+		}
 
-			// Get the HTrees which stores the node
+		swap(data.EdgeEnd1Key, data.EdgeEnd2Key)
+		swap(data.EdgeEnd1Kind, data.EdgeEnd2Kind)
+		swap(data.EdgeEnd1Role, data.EdgeEnd2Role)
+		swap(data.EdgeEnd1Cascading, data.EdgeEnd2Cascading)
+	}
 
-			attht, valht, err := gm.getNodeStorageHTree(part, v.TargetNodeKind, false)
-			if err != nil || attht == nil || valht == nil {
-				return nil, nil, err
-			}
+	// Get the HTrees which stores the node
 
-			node, err := gm.readNode(v.TargetNodeKey, v.TargetNodeKind, nil, attht, valht)
-			if err != nil {
-				return nil, nil, err
-			}
+	attht, valht, err := gm.getNodeStorageHTree(part, v.TargetNodeKind, false)
+	if err != nil || attht == nil || valht == nil {
+		return nil, nil, err
+	}
 
-			nodes = append(nodes, node)
+	node, err := gm.readNode(v.TargetNodeKey, v.TargetNodeKind, nil, attht, valht)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return node, edge, nil
+}
+
+/*
+EdgeKeyIterator iterates edge keys of a certain kind.
+*/
+func (gm *Manager) EdgeKeyIterator(part string, kind string) (*EdgeKeyIterator, error) {
+	// Get the HTree which stores the edge
+
+	tree, err := gm.getEdgeStorageHTree(part, kind, false)
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	it := hash.NewHTreeIterator(tree)
+	if it.LastError != nil {
+		return nil, &util.GraphError{
+			Type:   util.ErrReading,
+			Detail: it.LastError.Error(),
 		}
 	}
 
-	return nodes, edges, nil
+	eit := &EdgeKeyIterator{gm, it, "", false, nil}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	eit.fetchNext()
+
+	return eit, nil
 }
 
 /*
@@ -330,6 +721,18 @@ StoreEdge stores a single edge in a partition of the graph. This function will
 overwrites any existing edge.
 */
 func (gm *Manager) StoreEdge(part string, edge data.Edge) error {
+	return gm.storeEdge(part, edge, nil)
+}
+
+/*
+storeEdge stores a single edge in a partition of the graph. If a version is
+given the stored edge must have this version.
+*/
+func (gm *Manager) storeEdge(part string, edge data.Edge, version *uint64) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
 
 	// Check if the edge can be stored
 
@@ -389,6 +792,10 @@ func (gm *Manager) StoreEdge(part string, edge data.Edge) error {
 	gm.mutex.Lock()
 	defer gm.mutex.Unlock()
 
+	if err := gm.checkVersion(edgeht, edge.Key(), edge.Kind(), version); err != nil {
+		return err
+	}
+
 	// Write edge to the datastore
 
 	oldedge, err := gm.writeEdge(edge, edgeht, end1ht, end2ht)
@@ -455,7 +862,7 @@ func (gm *Manager) StoreEdge(part string, edge data.Edge) error {
 
 	// Flush changes - errors only reported on the actual node storage flush
 
-	gm.gs.FlushMain()
+	gm.flushMain()
 
 	gm.flushEdgeIndex(part, edge.Kind())
 
@@ -605,6 +1012,10 @@ RemoveEdge removes a single edge from a partition of the graph.
 */
 func (gm *Manager) RemoveEdge(part string, key string, kind string) (data.Edge, error) {
 
+	if err := gm.checkWritable(part); err != nil {
+		return nil, err
+	}
+
 	// Get the HTrees which stores the edges and the edge index
 
 	iht, err := gm.getEdgeIndexHTree(part, kind, true)
@@ -677,7 +1088,7 @@ func (gm *Manager) RemoveEdge(part string, key string, kind string) (data.Edge,
 
 		// Flush changes - errors only reported on the actual node storage flush
 
-		gm.gs.FlushMain()
+		gm.flushMain()
 
 		gm.flushEdgeIndex(part, edge.Kind())
 
@@ -13,6 +13,7 @@ package graph
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"testing"
 
@@ -1309,3 +1310,359 @@ func TestEdgeOperations(t *testing.T) {
 		return
 	}
 }
+
+func TestTraverseDirection(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("direction test")
+	gm := newGraphManagerNoRules(mgs)
+
+	for _, key := range []string{"a", "b", "c"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "person")
+		gm.StoreNode("main", node)
+	}
+
+	// Both ends of the edges have the same kind and role - specs cannot
+	// distinguish the direction
+
+	for _, e := range [][]string{{"e1", "a", "b"}, {"e2", "c", "a"}, {"e3", "a", "a"}} {
+		edge := data.NewGraphEdge()
+
+		edge.SetAttr("key", e[0])
+		edge.SetAttr("kind", "knows")
+
+		edge.SetAttr(data.EdgeEnd1Key, e[1])
+		edge.SetAttr(data.EdgeEnd1Kind, "person")
+		edge.SetAttr(data.EdgeEnd1Role, "person")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+
+		edge.SetAttr(data.EdgeEnd2Key, e[2])
+		edge.SetAttr(data.EdgeEnd2Kind, "person")
+		edge.SetAttr(data.EdgeEnd2Role, "person")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	edgeKeys := func(edges []data.Edge) string {
+		var keys []string
+		for _, e := range edges {
+			keys = append(keys, e.Key())
+		}
+		sort.Strings(keys)
+		return fmt.Sprint(keys)
+	}
+
+	for _, allData := range []bool{false, true} {
+		for dir, expected := range map[string]string{
+			DirectionAny:      "[e1 e2 e3]",
+			DirectionOutgoing: "[e1 e3]",
+			DirectionIncoming: "[e2 e3]",
+		} {
+			_, edges, err := gm.TraverseMultiDirection("main", "a", "person", ":::", dir, allData)
+			if res := edgeKeys(edges); err != nil || res != expected {
+				t.Error("Unexpected result for direction", dir, ":", res, err)
+				return
+			}
+		}
+	}
+
+	_, edges, err := gm.TraverseMultiDirection("main", "b", "person", "person:knows:person:person",
+		DirectionOutgoing, false)
+	if len(edges) != 0 || err != nil {
+		t.Error("Unexpected result:", edges, err)
+		return
+	}
+
+	_, edges, err = gm.TraverseMultiDirection("main", "b", "person", "person:knows:person:person",
+		DirectionIncoming, true)
+	if res := edgeKeys(edges); res != "[e1]" || err != nil || edges[0].End1Key() != "b" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ti, err := gm.TraverseMultiIterDirection("main", "a", "person", ":::", DirectionIncoming)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var iterEdges []data.Edge
+
+	for ti.HasNext() {
+		_, edge, err := ti.Next()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		iterEdges = append(iterEdges, edge)
+	}
+
+	if res := edgeKeys(iterEdges); res != "[e2 e3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if _, _, err := gm.TraverseMultiDirection("main", "a", "person", ":::", "up", false); err == nil ||
+		err.Error() != "GraphError: Invalid data (Invalid direction: up (must be out or in))" {
+		t.Error(err)
+		return
+	}
+
+	if _, err := gm.TraverseMultiIterDirection("main", "a", "person", ":::", "up"); err == nil {
+		t.Error("Expected an error")
+		return
+	}
+}
+
+func TestTraverseFilter(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("filter test")
+	gm := newGraphManagerNoRules(mgs)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "person")
+		gm.StoreNode("main", node)
+	}
+
+	// Node b is reachable via a matching and a non-matching edge, node d only
+	// via a non-matching edge
+
+	for _, e := range []struct {
+		key    string
+		end2   string
+		number int
+	}{{"e1", "b", 1}, {"e2", "b", 3}, {"e3", "c", 5}, {"e4", "c", 4}, {"e5", "d", 2}} {
+		edge := data.NewGraphEdge()
+
+		edge.SetAttr("key", e.key)
+		edge.SetAttr("kind", "knows")
+		edge.SetAttr("number", e.number)
+
+		edge.SetAttr(data.EdgeEnd1Key, "a")
+		edge.SetAttr(data.EdgeEnd1Kind, "person")
+		edge.SetAttr(data.EdgeEnd1Role, "from")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+
+		edge.SetAttr(data.EdgeEnd2Key, e.end2)
+		edge.SetAttr(data.EdgeEnd2Kind, "person")
+		edge.SetAttr(data.EdgeEnd2Role, "to")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	filter := func(edge data.Edge) bool {
+		return edge.Attr("number").(int) > 2
+	}
+
+	nodes, edges, err := gm.TraverseMultiFilter("main", "a", "person", ":::", DirectionAny, filter)
+	if err != nil || len(nodes) != 2 || len(edges) != 2 {
+		t.Error("Unexpected result:", nodes, edges, err)
+		return
+	}
+
+	// Each node is returned once with the first matching edge
+
+	if res := fmt.Sprintf("%v:%v %v:%v", nodes[0].Key(), edges[0].Key(),
+		nodes[1].Key(), edges[1].Key()); res != "b:e2 c:e3" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Filters are combined with directions
+
+	nodes, _, err = gm.TraverseMultiFilter("main", "a", "person", ":::", DirectionIncoming, filter)
+	if err != nil || len(nodes) != 0 {
+		t.Error("Unexpected result:", nodes, err)
+		return
+	}
+
+	// Without a filter all edges are followed
+
+	nodes, _, err = gm.TraverseMultiFilter("main", "a", "person", ":::", DirectionAny, nil)
+	if err != nil || len(nodes) != 5 {
+		t.Error("Unexpected result:", nodes, err)
+		return
+	}
+
+	if _, _, err := gm.TraverseMultiFilter("main", "a", "person", ":::", "up", filter); err == nil {
+		t.Error("Expected an error")
+		return
+	}
+}
+
+func TestNodeDegree(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("degree test")
+	gm := newGraphManagerNoRules(mgs)
+
+	for _, key := range []string{"a", "b", "c"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "person")
+		gm.StoreNode("main", node)
+	}
+
+	// The edge e3 connects a with itself using different roles
+
+	for _, e := range [][]string{{"e1", "knows", "a", "b", "friend"}, {"e2", "knows", "c", "a", "friend"},
+		{"e3", "knows", "a", "a", "self"}, {"e4", "likes", "b", "a", "fan"}} {
+
+		edge := data.NewGraphEdge()
+
+		edge.SetAttr("key", e[0])
+		edge.SetAttr("kind", e[1])
+
+		edge.SetAttr(data.EdgeEnd1Key, e[2])
+		edge.SetAttr(data.EdgeEnd1Kind, "person")
+		edge.SetAttr(data.EdgeEnd1Role, "person")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+
+		edge.SetAttr(data.EdgeEnd2Key, e[3])
+		edge.SetAttr(data.EdgeEnd2Kind, "person")
+		edge.SetAttr(data.EdgeEnd2Role, e[4])
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	degreeString := func(degrees map[string]*Degree) string {
+		var res []string
+		for kind, d := range degrees {
+			res = append(res, fmt.Sprintf("%v:%v/%v", kind, d.In, d.Out))
+		}
+		sort.Strings(res)
+		return fmt.Sprint(res)
+	}
+
+	if degrees, err := gm.NodeDegree("main", "a", "person"); err != nil || degreeString(degrees) != "[knows:2/2 likes:1/0]" {
+		t.Error("Unexpected result:", degreeString(degrees), err)
+		return
+	}
+
+	if degrees, err := gm.NodeDegree("main", "b", "person"); err != nil || degreeString(degrees) != "[knows:1/0 likes:0/1]" {
+		t.Error("Unexpected result:", degreeString(degrees), err)
+		return
+	}
+
+	if _, err := gm.RemoveEdge("main", "e2", "knows"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if degrees, err := gm.NodeDegree("main", "c", "person"); err != nil || degrees == nil || len(degrees) != 0 {
+		t.Error("Unexpected result:", degreeString(degrees), err)
+		return
+	}
+
+	// Unknown nodes have no degree
+
+	if degrees, err := gm.NodeDegree("main", "d", "person"); err != nil || degrees != nil {
+		t.Error("Unexpected result:", degrees, err)
+		return
+	}
+
+	if degrees, err := gm.NodeDegree("main", "a", "animal"); err != nil || degrees != nil {
+		t.Error("Unexpected result:", degrees, err)
+		return
+	}
+}
+
+func TestTraverseRoundTrip(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("roundtrip test")
+	gm := newGraphManagerNoRules(mgs)
+
+	storeNode := func(key, kind string) {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", kind)
+		node.SetAttr("name", "Name "+key)
+		gm.StoreNode("main", node)
+	}
+
+	storeEdge := func(key, key1, kind1, key2, kind2 string) {
+		edge := data.NewGraphEdge()
+
+		edge.SetAttr("key", key)
+		edge.SetAttr("kind", "Wrote")
+
+		edge.SetAttr(data.EdgeEnd1Key, key1)
+		edge.SetAttr(data.EdgeEnd1Kind, kind1)
+		edge.SetAttr(data.EdgeEnd1Role, "Author")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+
+		edge.SetAttr(data.EdgeEnd2Key, key2)
+		edge.SetAttr(data.EdgeEnd2Kind, kind2)
+		edge.SetAttr(data.EdgeEnd2Role, "Song")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, key := range []string{"a1", "a2", "a3", "a4"} {
+		storeNode(key, "Author")
+	}
+	for _, key := range []string{"s1", "s2", "s3"} {
+		storeNode(key, "Song")
+	}
+
+	storeEdge("e1", "a1", "Author", "s1", "Song")
+	storeEdge("e2", "a1", "Author", "s2", "Song")
+	storeEdge("e3", "a2", "Author", "s1", "Song")
+	storeEdge("e4", "a3", "Author", "s2", "Song")
+	storeEdge("e5", "a3", "Author", "s1", "Song")
+	storeEdge("e6", "a4", "Author", "s3", "Song")
+
+	// Self-referential edge
+
+	storeEdge("e7", "a1", "Author", "a1", "Author")
+
+	roundTrip := func(key string, spec string, allData bool) string {
+		nodes, edges, err := gm.TraverseRoundTrip("main", key, "Author", spec, allData)
+		if err != nil {
+			return err.Error()
+		}
+
+		var res []string
+		for i, n := range nodes {
+			res = append(res, fmt.Sprintf("%v/%v/%v", n.Key(), n.Attr("name"), edges[i].End2Key()))
+		}
+		sort.Strings(res)
+
+		return fmt.Sprint(res)
+	}
+
+	// Each co-author is only returned once and the start node is never returned
+
+	if res := roundTrip("a1", ":Wrote::Song", true); res != "[a2/Name a2/a2 a3/Name a3/a3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := roundTrip("a1", ":Wrote::", false); res != "[a2/<nil>/a2 a3/<nil>/a3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := roundTrip("a4", ":Wrote::Song", true); res != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := roundTrip("a1", "::", true); res != "GraphError: Invalid data (Invalid spec: ::)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
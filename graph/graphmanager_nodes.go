@@ -13,6 +13,7 @@ package graph
 import (
 	"encoding/binary"
 	"encoding/gob"
+	"sort"
 
 	"devt.de/krotik/eliasdb/graph/data"
 	"devt.de/krotik/eliasdb/graph/util"
@@ -30,6 +31,8 @@ func init() {
 NodeCount returns the node count for a given node kind.
 */
 func (gm *Manager) NodeCount(kind string) uint64 {
+	gm.mainDBMutex.RLock()
+	defer gm.mainDBMutex.RUnlock()
 
 	if val, ok := gm.gs.MainDB()[MainDBNodeCount+kind]; ok {
 		return binary.LittleEndian.Uint64([]byte(val))
@@ -60,6 +63,47 @@ func (gm *Manager) NodeKeyIterator(part string, kind string) (*NodeKeyIterator,
 	return &NodeKeyIterator{gm, it, nil}, nil
 }
 
+/*
+NodeKeysAfter returns up to limit node keys of a given kind which follow a
+given key. The keys are returned in a stable order which is not changed by
+storing or removing other nodes. The given key does not need to exist (e.g.
+the node was removed) - an empty key returns the first keys. A limit smaller
+than 1 returns all following keys.
+*/
+func (gm *Manager) NodeKeysAfter(part string, kind string, after string, limit int) ([]string, error) {
+
+	// Get the HTrees which stores the node
+
+	tree, _, err := gm.getNodeStorageHTree(part, kind, false)
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	var start []byte
+
+	if after != "" {
+		start = []byte(PrefixNSAttrs + after)
+	}
+
+	keys, _, err := tree.ScanAfter(start, limit)
+	if err != nil {
+		return nil, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+	}
+
+	res := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		res = append(res, string(k[len(PrefixNSAttrs):]))
+	}
+
+	return res, nil
+}
+
 /*
 FetchNode fetches a single node from a partition of the graph.
 */
@@ -90,6 +134,40 @@ func (gm *Manager) FetchNodePart(part string, key string, kind string,
 	return gm.readNode(key, kind, attrs, attht, valht)
 }
 
+/*
+FetchNodesPart fetches part of several nodes of the same kind from a partition
+of the graph. The storage of the node kind is only looked up once and the
+reader lock is only taken once for all nodes. The returned list has the same
+length as the given list of keys - nodes which do not exist are nil.
+*/
+func (gm *Manager) FetchNodesPart(part string, keys []string, kind string,
+	attrs []string) ([]data.Node, error) {
+
+	nodes := make([]data.Node, len(keys))
+
+	// Get the HTrees which stores the nodes
+
+	attht, valht, err := gm.getNodeStorageHTree(part, kind, false)
+	if err != nil || attht == nil || valht == nil {
+		return nodes, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	// Read the nodes from the datastore
+
+	for i, key := range keys {
+		if nodes[i], err = gm.readNode(key, kind, attrs, attht, valht); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
 /*
 readNode reads a given node from the datastore.
 */
@@ -184,7 +262,7 @@ StoreNode stores a single node in a partition of the graph. This function will
 overwrites any existing node.
 */
 func (gm *Manager) StoreNode(part string, node data.Node) error {
-	return gm.storeOrUpdateNode(part, node, false)
+	return gm.storeOrUpdateNode(part, node, false, nil)
 }
 
 /*
@@ -192,13 +270,42 @@ UpdateNode updates a single node in a partition of the graph. This function will
 only update the given values of the node.
 */
 func (gm *Manager) UpdateNode(part string, node data.Node) error {
-	return gm.storeOrUpdateNode(part, node, true)
+	return gm.storeOrUpdateNode(part, node, true, nil)
+}
+
+/*
+ValidateNode checks if a node could be stored in a partition of the graph
+without violating a schema or a unique constraint of its kind. Nothing is
+written - this can be used to reject invalid nodes before they are added to
+a transaction.
+*/
+func (gm *Manager) ValidateNode(part string, node data.Node) error {
+
+	if err := gm.checkNode(node); err != nil {
+		return err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	if err := gm.checkUniqueConstraints(part, node, nil); err != nil {
+		return err
+	}
+
+	return gm.checkNodeSchema(part, node, nil)
 }
 
 /*
 storeOrUpdateNode stores or updates a single node in a partition of the graph.
+If a version is given the stored node must have this version.
 */
-func (gm *Manager) storeOrUpdateNode(part string, node data.Node, onlyUpdate bool) error {
+func (gm *Manager) storeOrUpdateNode(part string, node data.Node, onlyUpdate bool, version *uint64) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
 
 	// Check if the node can be stored
 
@@ -223,6 +330,24 @@ func (gm *Manager) storeOrUpdateNode(part string, node data.Node, onlyUpdate boo
 	gm.mutex.Lock()
 	defer gm.mutex.Unlock()
 
+	// Check version, unique constraints and schema before anything is written
+
+	var oldNode func() (data.Node, error)
+
+	if onlyUpdate {
+		oldNode = func() (data.Node, error) {
+			return gm.readNode(node.Key(), node.Kind(), nil, attht, valht)
+		}
+	}
+
+	if err := gm.checkVersion(valht, node.Key(), node.Kind(), version); err != nil {
+		return err
+	} else if err := gm.checkUniqueConstraints(part, node, nil); err != nil {
+		return err
+	} else if err := gm.checkNodeSchema(part, node, oldNode); err != nil {
+		return err
+	}
+
 	// Write the node to the datastore
 
 	oldnode, err := gm.writeNode(node, onlyUpdate, attht, valht, nodeAttributeFilter)
@@ -264,6 +389,10 @@ func (gm *Manager) storeOrUpdateNode(part string, node data.Node, onlyUpdate boo
 		}
 	}
 
+	if err := gm.updateAttrIndexes(part, node.Kind(), node.Key(), node, oldnode, onlyUpdate); err != nil {
+		return err
+	}
+
 	// Execute rules
 
 	trans := newInternalGraphTrans(gm)
@@ -284,7 +413,7 @@ func (gm *Manager) storeOrUpdateNode(part string, node data.Node, onlyUpdate boo
 
 	// Flush changes - errors only reported on the actual node storage flush
 
-	gm.gs.FlushMain()
+	gm.flushMain()
 
 	gm.flushNodeIndex(part, node.Kind())
 
@@ -402,6 +531,10 @@ func (gm *Manager) writeNode(node data.Node, onlyUpdate bool, attrTree *hash.HTr
 		return nil, &util.GraphError{Type: util.ErrWriting, Detail: err.Error()}
 	}
 
+	if err := gm.increaseVersion(valTree, node.Key()); err != nil {
+		return nil, err
+	}
+
 	// Remove deleted keys
 
 	if attrListOld != nil {
@@ -439,6 +572,10 @@ RemoveNode removes a single node from a partition of the graph.
 */
 func (gm *Manager) RemoveNode(part string, key string, kind string) (data.Node, error) {
 
+	if err := gm.checkWritable(part); err != nil {
+		return nil, err
+	}
+
 	// Get the HTree which stores the node index and node kind
 
 	iht, err := gm.getNodeIndexHTree(part, kind, false)
@@ -474,6 +611,10 @@ func (gm *Manager) RemoveNode(part string, key string, kind string) (data.Node,
 			}
 		}
 
+		if err := gm.updateAttrIndexes(part, kind, key, nil, node, false); err != nil {
+			return node, err
+		}
+
 		// Decrease the node count
 
 		currentCount := gm.NodeCount(kind)
@@ -494,7 +635,7 @@ func (gm *Manager) RemoveNode(part string, key string, kind string) (data.Node,
 
 		// Flush changes - errors only reported on the actual node storage flush
 
-		gm.gs.FlushMain()
+		gm.flushMain()
 
 		gm.flushNodeIndex(part, kind)
 
@@ -504,6 +645,70 @@ func (gm *Manager) RemoveNode(part string, key string, kind string) (data.Node,
 	return nil, nil
 }
 
+/*
+CascadePreview returns all nodes and edges which would be removed if a given
+node was removed. Nothing is removed. The result contains the given node and
+is empty if the node does not exist. Each node and edge is contained only
+once even if cascading edges form a cycle. A node at the end of cascading
+last edges is contained if all its edges of that kind are removed. Nodes and edges contain only a
+minimal set of attributes and are sorted by kind and key. The reader lock is
+held while the preview is computed.
+*/
+func (gm *Manager) CascadePreview(part string, key string, kind string) ([]data.Node, []data.Edge, error) {
+
+	// Take reader lock - queries are done with a clone which has its own lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	gmclone := gm.gr.cloneGraphManager()
+
+	node, err := gmclone.FetchNodePart(part, key, kind, []string{data.NodeKey, data.NodeKind})
+	if err != nil || node == nil {
+		return nil, nil, err
+	}
+
+	// Edges and connected nodes are only removed by the system rule
+
+	_, cascade := gm.gr.rules[(&SystemRuleDeleteNodeEdges{}).Name()]
+
+	nodes := []data.Node{node}
+	seen := map[string]bool{kind + "#" + key: true}
+
+	var edges []data.Edge
+	removed := make(map[string]bool)
+
+	for i := 0; i < len(nodes) && cascade; i++ {
+
+		redges, rnodes, err := cascadeRemovals(gmclone, part, nodes[i], removed)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, edge := range redges {
+			removed[cascadeEdgeID(edge)] = true
+			edges = append(edges, edge)
+		}
+
+		for _, rnode := range rnodes {
+			if id := rnode.Kind() + "#" + rnode.Key(); !seen[id] {
+				seen[id] = true
+				nodes = append(nodes, rnode)
+			}
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Kind()+"#"+nodes[i].Key() < nodes[j].Kind()+"#"+nodes[j].Key()
+	})
+
+	sort.Slice(edges, func(i, j int) bool {
+		return cascadeEdgeID(edges[i]) < cascadeEdgeID(edges[j])
+	})
+
+	return nodes, edges, nil
+}
+
 /*
 deleteNode deletes a given node from the datastore. It is assumed that the caller
 holds the writer lock before calling the functions and that, after the function
@@ -524,6 +729,10 @@ func (gm *Manager) deleteNode(key string, kind string, attrTree *hash.HTree,
 		return nil, nil
 	}
 
+	if _, err := valTree.Remove([]byte(PrefixNSVersion + key)); err != nil {
+		return nil, &util.GraphError{Type: util.ErrWriting, Detail: err.Error()}
+	}
+
 	// Create the node object which is returned
 
 	node := data.NewGraphNode()
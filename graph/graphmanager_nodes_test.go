@@ -13,6 +13,7 @@ package graph
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"testing"
@@ -539,7 +540,7 @@ func TestSimpleNodeStorageErrorCases(t *testing.T) {
 
 	delete(sm.AccessMap, 1)
 
-	msm.AccessMap[5] = storage.AccessInsertError
+	msm.AccessMap[6] = storage.AccessInsertError
 
 	if err := gm.StoreNode("testpart", node2); err.Error() !=
 		"GraphError: Could not write graph information (Record is already in-use (? - ))" {
@@ -548,9 +549,9 @@ func TestSimpleNodeStorageErrorCases(t *testing.T) {
 		return
 	}
 
-	delete(msm.AccessMap, 5)
+	delete(msm.AccessMap, 6)
 
-	msm.AccessMap[5] = storage.AccessInsertError
+	msm.AccessMap[6] = storage.AccessInsertError
 
 	if err := gm.StoreNode("testpart", node2); err.Error() !=
 		"GraphError: Could not write graph information (Record is already in-use (? - ))" {
@@ -559,7 +560,7 @@ func TestSimpleNodeStorageErrorCases(t *testing.T) {
 		return
 	}
 
-	delete(msm.AccessMap, 5)
+	delete(msm.AccessMap, 6)
 
 	node2.SetAttr("key", "123")
 	node2.SetAttr("Name", nil)
@@ -629,7 +630,7 @@ func TestSimpleNodeStorageErrorCases(t *testing.T) {
 		delete(is.AccessMap, uint64(i))
 	}
 
-	msm.AccessMap[9] = storage.AccessCacheAndFetchError
+	msm.AccessMap[12] = storage.AccessCacheAndFetchError
 
 	// This call does delete the node by blowing
 	// away the attribute list - the node is removed though its attribute
@@ -637,12 +638,12 @@ func TestSimpleNodeStorageErrorCases(t *testing.T) {
 
 	if res, err := gm.deleteNode("123", "testkind", attTree, valTree); err.Error() !=
 		"GraphError: Could not write graph information "+
-			"(Slot not found (mystorage/testparttestkind.nodes - Location:9))" {
+			"(Slot not found (mystorage/testparttestkind.nodes - Location:12))" {
 
 		t.Error("Unexpected result:", res, err)
 		return
 	}
-	delete(msm.AccessMap, 9)
+	delete(msm.AccessMap, 12)
 
 	if res, err := gm.FetchNodePart("testpart", "123", "testkind", nil); res != nil || err != nil {
 		t.Error("Unexpected result:", res, err)
@@ -774,3 +775,326 @@ func testVersionPanic(t *testing.T, gs graphstorage.Storage) {
 
 	newGraphManagerNoRules(gs)
 }
+
+func TestNodeNumberStorage(t *testing.T) {
+	if !RunDiskStorageTests {
+		return
+	}
+
+	dgs, err := graphstorage.NewDiskGraphStorage(GraphManagerTestDBDir2, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	gm := newGraphManagerNoRules(dgs)
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "123")
+	node.SetAttr("kind", "numbers")
+	node.SetAttr("max", int64(math.MaxInt64))
+	node.SetAttr("min", int64(math.MinInt64))
+	node.SetAttr("unsafe", int64(9007199254740993))
+	node.SetAttr("float", 0.1)
+
+	if err := gm.StoreNode("num", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	dgs.Close()
+
+	// Numbers must keep their type and value after reopening the storage
+
+	dgs2, err := graphstorage.NewDiskGraphStorage(GraphManagerTestDBDir2, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer dgs2.Close()
+
+	gm = newGraphManagerNoRules(dgs2)
+
+	fnode, err := gm.FetchNode("num", "123", "numbers")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for attr, expected := range map[string]string{
+		"max":    "int64 9223372036854775807",
+		"min":    "int64 -9223372036854775808",
+		"unsafe": "int64 9007199254740993",
+		"float":  "float64 0.1",
+	} {
+		if res := fmt.Sprintf("%T %v", fnode.Attr(attr), fnode.Attr(attr)); res != expected {
+			t.Error("Unexpected result for", attr, ":", res)
+			return
+		}
+	}
+}
+
+func TestCascadePreview(t *testing.T) {
+	gm := NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+
+	storeNode := func(key string) {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "mynode")
+		gm.StoreNode("main", node)
+	}
+
+	storeEdge := func(key string, key1 string, key2 string, cascading bool, cascadingLast bool) {
+		edge := data.NewGraphEdge()
+		edge.SetAttr("key", key)
+		edge.SetAttr("kind", "myedge")
+		edge.SetAttr(data.EdgeEnd1Key, key1)
+		edge.SetAttr(data.EdgeEnd1Kind, "mynode")
+		edge.SetAttr(data.EdgeEnd1Role, "parent")
+		edge.SetAttr(data.EdgeEnd1Cascading, cascading)
+		edge.SetAttr(data.EdgeEnd1CascadingLast, cascadingLast)
+		edge.SetAttr(data.EdgeEnd2Key, key2)
+		edge.SetAttr(data.EdgeEnd2Kind, "mynode")
+		edge.SetAttr(data.EdgeEnd2Role, "child")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		storeNode(key)
+	}
+
+	// Cascading cycle a -> b -> c -> a
+
+	storeEdge("ab", "a", "b", true, false)
+	storeEdge("bc", "b", "c", true, false)
+	storeEdge("ca", "c", "a", true, false)
+
+	// Non cascading edge to d
+
+	storeEdge("ad", "a", "d", false, false)
+
+	// e and f are only removed if their last parent is removed - e stays
+	// since g is still a parent
+
+	storeEdge("be", "b", "e", true, true)
+	storeEdge("ge", "g", "e", true, true)
+	storeEdge("cf", "c", "f", true, true)
+
+	preview := func(key string) string {
+		nodes, edges, err := gm.CascadePreview("main", key, "mynode")
+		if err != nil {
+			return err.Error()
+		}
+
+		var res []string
+		for _, n := range nodes {
+			res = append(res, n.Key())
+		}
+		res = append(res, "-")
+		for _, e := range edges {
+			res = append(res, e.Key())
+		}
+
+		return fmt.Sprint(res)
+	}
+
+	if res := preview("a"); res != "[a b c f - ab ad bc be ca cf]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := preview("d"); res != "[d - ad]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := preview("x"); res != "[-]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// The preview did not remove anything
+
+	if cnt := gm.NodeCount("mynode"); cnt != 7 {
+		t.Error("Unexpected node count:", cnt)
+		return
+	}
+
+	// The preview matches an actual removal
+
+	if _, err := gm.RemoveNode("main", "a", "mynode"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var remaining []string
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		if n, _ := gm.FetchNode("main", key, "mynode"); n != nil {
+			remaining = append(remaining, key)
+		}
+	}
+
+	if res := fmt.Sprint(remaining); res != "[d e g]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := preview("g"); res != "[e g - ge]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestFetchNodesPart(t *testing.T) {
+	gm := NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+
+	for _, key := range []string{"a", "b", "c"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "mynode")
+		node.SetAttr("name", "Node "+key)
+		node.SetAttr("other", "foo")
+		gm.StoreNode("main", node)
+	}
+
+	nodes, err := gm.FetchNodesPart("main", []string{"c", "x", "a"}, "mynode", []string{"key", "name"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(nodes) != 3 || nodes[1] != nil {
+		t.Error("Unexpected result:", nodes)
+		return
+	}
+
+	if res := fmt.Sprint(nodes[0].Data(), nodes[2].Data()); res != "map[key:c kind:mynode name:Node c] map[key:a kind:mynode name:Node a]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Unknown node kinds return only nil nodes
+
+	if nodes, err := gm.FetchNodesPart("main", []string{"a", "b"}, "unknown", nil); err != nil ||
+		len(nodes) != 2 || nodes[0] != nil || nodes[1] != nil {
+		t.Error("Unexpected result:", nodes, err)
+		return
+	}
+}
+
+func TestNodeKeysAfter(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	if keys, err := gm.NodeKeysAfter("main", "mynode", "", 10); keys != nil || err != nil {
+		t.Error("Unexpected result:", keys, err)
+		return
+	}
+
+	for i := 0; i < 100; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprintf("%03d", i))
+		node.SetAttr("kind", "mynode")
+		gm.StoreNode("main", node)
+	}
+
+	all, err := gm.NodeKeysAfter("main", "mynode", "", 0)
+	if len(all) != 100 || err != nil {
+		t.Error("Unexpected result:", len(all), err)
+		return
+	}
+
+	// Page through all keys
+
+	var paged []string
+	var after string
+
+	for {
+		keys, err := gm.NodeKeysAfter("main", "mynode", after, 30)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if len(keys) == 0 {
+			break
+		}
+
+		paged = append(paged, keys...)
+		after = keys[len(keys)-1]
+	}
+
+	if fmt.Sprint(paged) != fmt.Sprint(all) {
+		t.Error("Unexpected result:", paged)
+		return
+	}
+
+	// Removing nodes of a previous page does not change the following page
+
+	next, _ := gm.NodeKeysAfter("main", "mynode", all[29], 30)
+
+	for _, key := range all[:30] {
+		if _, err := gm.RemoveNode("main", key, "mynode"); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if keys, err := gm.NodeKeysAfter("main", "mynode", all[29], 30); fmt.Sprint(keys) != fmt.Sprint(next) || err != nil {
+		t.Error("Unexpected result:", keys, err)
+		return
+	}
+
+	if keys, err := gm.NodeKeysAfter("main", "mynode", "", 30); fmt.Sprint(keys) != fmt.Sprint(next) || err != nil {
+		t.Error("Unexpected result:", keys, err)
+		return
+	}
+}
+
+func benchmarkFetchGraph(b *testing.B) (*Manager, []string) {
+	gm := NewGraphManager(graphstorage.NewMemoryGraphStorage("mystorage"))
+
+	var keys []string
+
+	for i := 0; i < 2000; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprintf("%05d", i))
+		node.SetAttr("kind", "mynode")
+		node.SetAttr("name", fmt.Sprint("Node ", i))
+		gm.StoreNode("main", node)
+
+		keys = append(keys, fmt.Sprintf("%05d", i))
+	}
+
+	b.ResetTimer()
+
+	return gm, keys
+}
+
+func BenchmarkFetchNodePart(b *testing.B) {
+	gm, keys := benchmarkFetchGraph(b)
+
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			if _, err := gm.FetchNodePart("main", key, "mynode", []string{"key", "name"}); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	}
+}
+
+func BenchmarkFetchNodesPart(b *testing.B) {
+	gm, keys := benchmarkFetchGraph(b)
+
+	for i := 0; i < b.N; i++ {
+		if _, err := gm.FetchNodesPart("main", keys, "mynode", []string{"key", "name"}); err != nil {
+			b.Error(err)
+			return
+		}
+	}
+}
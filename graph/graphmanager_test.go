@@ -11,13 +11,17 @@
 package graph
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"testing"
 
 	"devt.de/krotik/common/fileutil"
+	"devt.de/krotik/eliasdb/graph/data"
 	"devt.de/krotik/eliasdb/graph/graphstorage"
+	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/storage"
 )
 
 /*
@@ -32,10 +36,12 @@ const GraphManagerTestDBDir3 = "gmtest3"
 const GraphManagerTestDBDir4 = "gmtest4"
 const GraphManagerTestDBDir5 = "gmtest5"
 const GraphManagerTestDBDir6 = "gmtest6"
+const GraphManagerTestDBDir7 = "gmtest7"
+const GraphManagerTestDBDir8 = "gmtest8"
 
 var DBDIRS = []string{GraphManagerTestDBDir1, GraphManagerTestDBDir2,
 	GraphManagerTestDBDir3, GraphManagerTestDBDir4, GraphManagerTestDBDir5,
-	GraphManagerTestDBDir6}
+	GraphManagerTestDBDir6, GraphManagerTestDBDir7, GraphManagerTestDBDir8}
 
 const InvlaidFileName = "**" + "\x00"
 
@@ -75,3 +81,275 @@ NewGraphManager returns a new GraphManager instance without loading rules.
 func newGraphManagerNoRules(gs graphstorage.Storage) *Manager {
 	return createGraphManager(gs)
 }
+
+func TestCheckStorage(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	if err := gm.CheckStorage("main"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "123")
+	node1.SetAttr("kind", "testkind")
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "456")
+	node2.SetAttr("kind", "testkind")
+
+	gm.StoreNode("main", node1)
+	gm.StoreNode("main", node2)
+
+	edge := data.NewGraphEdge()
+	edge.SetAttr("key", "abc")
+	edge.SetAttr("kind", "testedge")
+	edge.SetAttr(data.EdgeEnd1Key, node1.Key())
+	edge.SetAttr(data.EdgeEnd1Kind, node1.Kind())
+	edge.SetAttr(data.EdgeEnd1Role, "node1")
+	edge.SetAttr(data.EdgeEnd1Cascading, false)
+	edge.SetAttr(data.EdgeEnd2Key, node2.Key())
+	edge.SetAttr(data.EdgeEnd2Kind, node2.Kind())
+	edge.SetAttr(data.EdgeEnd2Role, "node2")
+	edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+	if err := gm.StoreEdge("main", edge); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.CheckStorage("main"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Partitions without storage are fine
+
+	if err := gm.CheckStorage("other"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	msm := mgs.StorageManager("main"+"testedge"+StorageSuffixEdges,
+		false).(*storage.MemoryStorageManager)
+
+	loc := msm.Root(RootIDNodeHTree)
+	msm.AccessMap[loc] = storage.AccessCacheAndFetchError
+
+	if err := gm.CheckStorage("main"); err == nil || err.Error() != fmt.Sprintf("GraphError: Failed to access graph storage component "+
+		"(Slot not found (mystorage/maintestedge.edges - Location:%v))", loc) {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	delete(msm.AccessMap, loc)
+
+	msm = mgs.StorageManager("main"+"testkind"+StorageSuffixNodes,
+		false).(*storage.MemoryStorageManager)
+
+	loc = msm.Root(RootIDNodeHTree)
+	msm.AccessMap[loc] = storage.AccessCacheAndFetchError
+
+	if err := gm.CheckStorage("main"); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "123")
+	node1.SetAttr("kind", "testkind")
+
+	if err := gm.StoreNode("main", node1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	version := gm.SchemaVersion()
+
+	// Storing a node with known attributes does not change the schema
+
+	node1.SetAttr("key", "456")
+
+	if err := gm.StoreNode("main", node1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v := gm.SchemaVersion(); v != version {
+		t.Error("Unexpected version:", v, version)
+		return
+	}
+
+	// A new attribute changes the schema
+
+	node1.SetAttr("name", "foo")
+
+	if err := gm.StoreNode("main", node1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v := gm.SchemaVersion(); v == version {
+		t.Error("Schema version should have changed")
+		return
+	}
+
+	version = gm.SchemaVersion()
+
+	// A new kind changes the schema
+
+	node1.SetAttr("kind", "testkind2")
+
+	if err := gm.StoreNode("main", node1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v := gm.SchemaVersion(); v == version {
+		t.Error("Schema version should have changed")
+		return
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "123")
+	node1.SetAttr("kind", "testkind")
+	node1.SetAttr("name", "foo")
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "456")
+	node2.SetAttr("kind", "testkind")
+
+	edge := data.NewGraphEdge()
+	edge.SetAttr("key", "abc")
+	edge.SetAttr("kind", "testedge")
+	edge.SetAttr(data.EdgeEnd1Key, "123")
+	edge.SetAttr(data.EdgeEnd1Kind, "testkind")
+	edge.SetAttr(data.EdgeEnd1Role, "role1")
+	edge.SetAttr(data.EdgeEnd1Cascading, false)
+	edge.SetAttr(data.EdgeEnd2Key, "456")
+	edge.SetAttr(data.EdgeEnd2Kind, "testkind")
+	edge.SetAttr(data.EdgeEnd2Role, "role2")
+	edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+	if err := gm.StoreNode("main", node1); err != nil {
+		t.Error(err)
+		return
+	} else if err := gm.StoreNode("main", node2); err != nil {
+		t.Error(err)
+		return
+	} else if err := gm.StoreEdge("main", edge); err != nil {
+		t.Error(err)
+		return
+	} else if err := gm.CreateIndex("main", "testkind", "name"); err != nil {
+		t.Error(err)
+		return
+	} else if err := gm.CreateWordIndex("main", "testkind", "name"); err != nil {
+		t.Error(err)
+		return
+	} else if err := gm.CreateUniqueConstraint("main", "testkind", "name"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var snapshot bytes.Buffer
+
+	if err := gm.Snapshot("main", &snapshot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Queue some changes before the partition becomes read-only
+
+	trans := NewGraphTrans(gm)
+
+	if err := trans.StoreNode("main", node1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	gm.SetReadOnly("main", true)
+
+	if !gm.IsReadOnly("main") || gm.IsReadOnly("other") {
+		t.Error("Unexpected read-only state")
+		return
+	}
+
+	checkReadOnlyError := func(op string, err error) {
+		if gerr, ok := err.(*util.GraphError); !ok || gerr.Type != util.ErrReadOnly {
+			t.Error("Unexpected result for", op, ":", err)
+		} else if gerr.Error() != "GraphError: Failed write to readonly storage (Partition main is read-only)" {
+			t.Error("Unexpected error message for", op, ":", gerr)
+		}
+	}
+
+	checkReadOnlyError("commit", trans.Commit())
+
+	checkReadOnlyError("StoreNode", gm.StoreNode("main", node1))
+	checkReadOnlyError("UpdateNode", gm.UpdateNode("main", node1))
+	_, err := gm.RemoveNode("main", "123", "testkind")
+	checkReadOnlyError("RemoveNode", err)
+	checkReadOnlyError("StoreEdge", gm.StoreEdge("main", edge))
+	_, err = gm.RemoveEdge("main", "abc", "testedge")
+	checkReadOnlyError("RemoveEdge", err)
+
+	checkReadOnlyError("CreateIndex", gm.CreateIndex("main", "testkind", "key"))
+	checkReadOnlyError("DropIndex", gm.DropIndex("main", "testkind", "name"))
+	checkReadOnlyError("CreateWordIndex", gm.CreateWordIndex("main", "testkind", "key"))
+	checkReadOnlyError("DropWordIndex", gm.DropWordIndex("main", "testkind", "name"))
+	checkReadOnlyError("CreateUniqueConstraint", gm.CreateUniqueConstraint("main", "testkind", "key"))
+	checkReadOnlyError("DropUniqueConstraint", gm.DropUniqueConstraint("main", "testkind", "name"))
+	checkReadOnlyError("Restore", gm.Restore("main", bytes.NewReader(snapshot.Bytes())))
+
+	trans = NewGraphTrans(gm)
+
+	checkReadOnlyError("trans StoreNode", trans.StoreNode("main", node1))
+	checkReadOnlyError("trans UpdateNode", trans.UpdateNode("main", node1))
+	checkReadOnlyError("trans RemoveNode", trans.RemoveNode("main", "123", "testkind"))
+	checkReadOnlyError("trans StoreEdge", trans.StoreEdge("main", edge))
+	checkReadOnlyError("trans RemoveEdge", trans.RemoveEdge("main", "abc", "testedge"))
+
+	// Reading is still possible
+
+	if n, err := gm.FetchNode("main", "123", "testkind"); err != nil || n == nil {
+		t.Error("Unexpected result:", n, err)
+		return
+	}
+
+	// Other partitions are not affected
+
+	if err := gm.StoreNode("other", node1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// All partitions can be made read-only
+
+	gm.SetReadOnly("", true)
+
+	if err := gm.StoreNode("other", node1); err == nil {
+		t.Error("Unexpected result")
+		return
+	}
+
+	gm.SetReadOnly("", false)
+	gm.SetReadOnly("main", false)
+
+	if err := gm.StoreNode("main", node2); err != nil {
+		t.Error(err)
+		return
+	} else if _, err := gm.RemoveNode("main", "456", "testkind"); err != nil {
+		t.Error(err)
+		return
+	}
+}
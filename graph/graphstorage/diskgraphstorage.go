@@ -32,6 +32,12 @@ FilenameNameDB is the filename for the name storage file
 */
 var FilenameNameDB = "names.pm"
 
+/*
+DefaultCacheSize is the default maximum number of objects which are cached
+by each storage manager.
+*/
+var DefaultCacheSize = 100000
+
 /*
 DiskGraphStorage data structure
 */
@@ -40,6 +46,9 @@ type DiskGraphStorage struct {
 	readonly        bool                          // Flag for readonly mode
 	mainDB          *datautil.PersistentStringMap // Database storing names
 	storagemanagers map[string]storage.Manager    // Map of StorageManagers
+	cacheSize       int                           // Maximum number of cached objects per StorageManager
+	partCacheSizes  map[string]int                // Cache sizes for specific partitions
+	checksums       bool                          // Flag if new storage files store page checksums
 }
 
 /*
@@ -47,7 +56,8 @@ NewDiskGraphStorage creates a new DiskGraphStorage instance.
 */
 func NewDiskGraphStorage(name string, readonly bool) (Storage, error) {
 
-	dgs := &DiskGraphStorage{name, readonly, nil, make(map[string]storage.Manager)}
+	dgs := &DiskGraphStorage{name, readonly, nil, make(map[string]storage.Manager),
+		DefaultCacheSize, make(map[string]int), false}
 
 	// Load the graph storage if the storage directory already exists if not try to create it
 
@@ -132,6 +142,53 @@ func (dgs *DiskGraphStorage) FlushMain() error {
 	return nil
 }
 
+/*
+SetCacheSize sets the maximum number of objects which are cached by each
+storage manager. The size only applies to storage managers which are opened
+after this call.
+*/
+func (dgs *DiskGraphStorage) SetCacheSize(size int) {
+	dgs.cacheSize = size
+}
+
+/*
+SetPartitionCacheSize sets the maximum number of objects which are cached by
+each storage manager of a given partition. The size only applies to storage
+managers which are opened after this call.
+*/
+func (dgs *DiskGraphStorage) SetPartitionCacheSize(part string, size int) {
+	dgs.partCacheSizes[part] = size
+}
+
+/*
+SetChecksums sets if storage managers store a CRC32 checksum with every page
+of their files. Pages with a checksum mismatch cannot be read. The flag only
+applies to files which are created after this call - existing files keep the
+format they were created with.
+*/
+func (dgs *DiskGraphStorage) SetChecksums(checksums bool) {
+	dgs.checksums = checksums
+}
+
+/*
+storageCacheSize returns the cache size for a given storage manager. Storage
+manager names start with the name of their partition - the longest matching
+partition with a specific cache size is used.
+*/
+func (dgs *DiskGraphStorage) storageCacheSize(smname string) int {
+	size := dgs.cacheSize
+	match := ""
+
+	for part, psize := range dgs.partCacheSizes {
+		if strings.HasPrefix(smname, part) && len(part) > len(match) {
+			size = psize
+			match = part
+		}
+	}
+
+	return size
+}
+
 /*
 StorageManager gets a storage manager with a certain name. A non-existing
 StorageManager is created automatically if the create flag is set to true.
@@ -146,8 +203,15 @@ func (dgs *DiskGraphStorage) StorageManager(smname string, create bool) storage.
 	// database already exists
 
 	if !ok && (create || storage.DataFileExist(filename)) {
-		dsm := storage.NewDiskStorageManager(dgs.name+"/"+smname, dgs.readonly, false, false, false)
-		sm = storage.NewCachedDiskStorageManager(dsm, 100000)
+		var dsm *storage.DiskStorageManager
+
+		if dgs.checksums {
+			dsm = storage.NewChecksumDiskStorageManager(filename, dgs.readonly, false, false, false)
+		} else {
+			dsm = storage.NewDiskStorageManager(filename, dgs.readonly, false, false, false)
+		}
+
+		sm = storage.NewCachedDiskStorageManager(dsm, dgs.storageCacheSize(smname))
 		dgs.storagemanagers[smname] = sm
 	}
 
@@ -15,17 +15,22 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"devt.de/krotik/common/datautil"
 	"devt.de/krotik/common/fileutil"
 	"devt.de/krotik/eliasdb/storage"
+	"devt.de/krotik/eliasdb/storage/file"
 )
 
 const diskGraphStorageTestDBDir = "diskgraphstoragetest1"
 const diskGraphStorageTestDBDir2 = "diskgraphstoragetest2"
+const diskGraphStorageTestDBDir3 = "diskgraphstoragetest3"
+const diskGraphStorageTestDBDir4 = "diskgraphstoragetest4"
 
-var dbdirs = []string{diskGraphStorageTestDBDir, diskGraphStorageTestDBDir2}
+var dbdirs = []string{diskGraphStorageTestDBDir, diskGraphStorageTestDBDir2,
+	diskGraphStorageTestDBDir3, diskGraphStorageTestDBDir4}
 
 const invalidFileName = "**" + "\x00"
 
@@ -187,7 +192,7 @@ func TestDiskGraphStorageErrors(t *testing.T) {
 	FilenameNameDB = old
 
 	dgs := &DiskGraphStorage{invalidFileName, false, nil,
-		make(map[string]storage.Manager)}
+		make(map[string]storage.Manager), DefaultCacheSize, make(map[string]int), false}
 	pm, _ := datautil.NewPersistentStringMap(invalidFileName)
 	dgs.mainDB = pm
 
@@ -216,3 +221,132 @@ func TestDiskGraphStorageErrors(t *testing.T) {
 		return
 	}
 }
+
+func TestDiskGraphStorageCacheSize(t *testing.T) {
+	gs, err := NewDiskGraphStorage(diskGraphStorageTestDBDir3, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer gs.Close()
+
+	dgs := gs.(*DiskGraphStorage)
+
+	dgs.SetCacheSize(5)
+	dgs.SetPartitionCacheSize("small", 2)
+	dgs.SetPartitionCacheSize("smallest", 1)
+
+	for smname, size := range map[string]int{
+		"mainAuthor.nodes":     5,
+		"smallAuthor.nodes":    2,
+		"smallestAuthor.nodes": 1,
+	} {
+		if res := dgs.StorageManager(smname, true).(*storage.CachedDiskStorageManager).MaxObjects(); res != size {
+			t.Error("Unexpected cache size:", smname, res)
+			return
+		}
+	}
+
+	// Exceed the cache size - evicted objects are read again from disk
+
+	sm := dgs.StorageManager("smallAuthor.nodes", false)
+
+	var locs []uint64
+
+	for i := 0; i < 5; i++ {
+		loc, err := sm.Insert(fmt.Sprint("test", i))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		locs = append(locs, loc)
+	}
+
+	if stats := sm.(storage.CacheStatsManager).CacheStats(); stats.Evictions() != 3 {
+		t.Error("Unexpected evictions:", stats.Evictions())
+		return
+	}
+
+	for i, loc := range locs {
+		if _, err := sm.FetchCached(loc); (i < 3) != (err == storage.ErrNotInCache) {
+			t.Error("Unexpected cache state:", i, err)
+			return
+		}
+	}
+
+	for i, loc := range locs {
+		var res string
+
+		if err := sm.Fetch(loc, &res); err != nil || res != fmt.Sprint("test", i) {
+			t.Error("Unexpected result:", res, err)
+			return
+		}
+	}
+}
+
+func TestDiskGraphStorageChecksums(t *testing.T) {
+	gs, err := NewDiskGraphStorage(diskGraphStorageTestDBDir4, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	plainLoc, err := gs.StorageManager("plain.nodes", true).Insert("plain")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	gs.(*DiskGraphStorage).SetChecksums(true)
+
+	loc, err := gs.StorageManager("main.nodes", true).Insert("test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gs.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Corrupt the data page of the new storage file on disk
+
+	f, err := os.OpenFile(diskGraphStorageTestDBDir4+"/main.nodes.db.0", os.O_RDWR, 0660)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	b := make([]byte, 1)
+	offset := int64(storage.BlockSizePhysicalSlots + file.SizeInt + 100)
+
+	f.ReadAt(b, offset)
+	b[0] ^= 0xFF
+	f.WriteAt(b, offset)
+	f.Close()
+
+	gs, err = NewDiskGraphStorage(diskGraphStorageTestDBDir4, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer gs.Close()
+
+	gs.(*DiskGraphStorage).SetChecksums(true)
+
+	var res string
+
+	if err := gs.StorageManager("main.nodes", false).Fetch(loc, &res); err == nil ||
+		!strings.Contains(err.Error(), file.ErrChecksumMismatch.Error()) {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Files which were created without checksums keep their format
+
+	if err := gs.StorageManager("plain.nodes", false).Fetch(plainLoc, &res); err != nil || res != "plain" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"encoding/gob"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"devt.de/krotik/common/stringutil"
 	"devt.de/krotik/eliasdb/graph/data"
@@ -41,6 +42,20 @@ func (gm *Manager) checkPartitionName(part string) error {
 	return nil
 }
 
+/*
+checkWritable checks if a given partition can be changed.
+*/
+func (gm *Manager) checkWritable(part string) error {
+	if gm.IsReadOnly(part) {
+		return &util.GraphError{
+			Type:   util.ErrReadOnly,
+			Detail: fmt.Sprintf("Partition %v is read-only", part),
+		}
+	}
+
+	return nil
+}
+
 /*
 checkNode checks if a given node can be written to the datastore.
 */
@@ -136,10 +151,13 @@ func (gm *Manager) writeNodeCount(kind string, count uint64, flush bool) error {
 	numstr := make([]byte, 8)
 
 	binary.LittleEndian.PutUint64(numstr, count)
+
+	gm.mainDBMutex.Lock()
 	gm.gs.MainDB()[MainDBNodeCount+kind] = string(numstr)
+	gm.mainDBMutex.Unlock()
 
 	if flush {
-		return gm.gs.FlushMain()
+		return gm.flushMain()
 	}
 
 	return nil
@@ -152,10 +170,13 @@ func (gm *Manager) writeEdgeCount(kind string, count uint64, flush bool) error {
 	numstr := make([]byte, 8)
 
 	binary.LittleEndian.PutUint64(numstr, count)
+
+	gm.mainDBMutex.Lock()
 	gm.gs.MainDB()[MainDBEdgeCount+kind] = string(numstr)
+	gm.mainDBMutex.Unlock()
 
 	if flush {
-		return gm.gs.FlushMain()
+		return gm.flushMain()
 	}
 
 	return nil
@@ -204,9 +225,11 @@ func (gm *Manager) getNodeStorageHTree(part string, kind string,
 		gm.storeMainDBMap(MainDBNodeEdges+kind, make(map[string]string))
 	}
 
+	gm.mainDBMutex.Lock()
 	if _, ok := gm.gs.MainDB()[MainDBNodeCount+kind]; !ok {
 		gm.gs.MainDB()[MainDBNodeCount+kind] = string(make([]byte, 8, 8))
 	}
+	gm.mainDBMutex.Unlock()
 
 	// Return the actual storage
 
@@ -262,9 +285,11 @@ func (gm *Manager) getEdgeStorageHTree(part string, kind string, create bool) (*
 		gm.storeMainDBMap(MainDBEdgeAttrs+kind, make(map[string]string))
 	}
 
+	gm.mainDBMutex.Lock()
 	if _, ok := gm.gs.MainDB()[MainDBEdgeCount+kind]; !ok {
 		gm.gs.MainDB()[MainDBEdgeCount+kind] = string(make([]byte, 8, 8))
 	}
+	gm.mainDBMutex.Unlock()
 
 	// Return the actual storage
 
@@ -280,20 +305,28 @@ func (gm *Manager) getEdgeStorageHTree(part string, kind string, create bool) (*
 getNodeIndexHTree gets a HTree which can be used to index nodes.
 */
 func (gm *Manager) getNodeIndexHTree(part string, kind string, create bool) (*hash.HTree, error) {
-	return gm.getIndexHTree(part, kind, create, "Node", StorageSuffixNodesIndex)
+	return gm.getIndexHTree(part, kind, create, "Node", StorageSuffixNodesIndex, RootIDNodeHTree)
+}
+
+/*
+getNodeAttrIndexHTree gets a HTree which can be used for attribute indexes of nodes.
+*/
+func (gm *Manager) getNodeAttrIndexHTree(part string, kind string, create bool) (*hash.HTree, error) {
+	return gm.getIndexHTree(part, kind, create, "Node", StorageSuffixNodesIndex, RootIDNodeHTreeSecond)
 }
 
 /*
 getEdgeIndexHTree gets a HTree which can be used to index edges.
 */
 func (gm *Manager) getEdgeIndexHTree(part string, kind string, create bool) (*hash.HTree, error) {
-	return gm.getIndexHTree(part, kind, create, "Edge", StorageSuffixEdgesIndex)
+	return gm.getIndexHTree(part, kind, create, "Edge", StorageSuffixEdgesIndex, RootIDNodeHTree)
 }
 
 /*
 getIndexHTree gets a HTree which can be used to index items.
 */
-func (gm *Manager) getIndexHTree(part string, kind string, create bool, name string, suffix string) (*hash.HTree, error) {
+func (gm *Manager) getIndexHTree(part string, kind string, create bool, name string,
+	suffix string, slot int) (*hash.HTree, error) {
 
 	gm.storageMutex.Lock()
 	defer gm.storageMutex.Unlock()
@@ -318,7 +351,7 @@ func (gm *Manager) getIndexHTree(part string, kind string, create bool, name str
 		return nil, nil
 	}
 
-	return gm.getHTree(gs, RootIDNodeHTree)
+	return gm.getHTree(gs, slot)
 }
 
 /*
@@ -453,35 +486,71 @@ func (gm *Manager) getHTree(sm storage.Manager, slot int) (*hash.HTree, error) {
 }
 
 /*
-getMainDBMap gets a map from the main database.
+getMainDBMap gets a map from the main database. The returned map is a copy
+which can be changed and stored again with storeMainDBMap.
 */
 func (gm *Manager) getMainDBMap(key string) map[string]string {
+	gm.mainDBMutex.Lock()
+	defer gm.mainDBMutex.Unlock()
 
 	// First try to cache
 
 	mapval, ok := gm.mapCache[key]
-	if ok {
-		return mapval
-	}
 
-	// Lookup map and decode it
+	if !ok {
+
+		// Lookup map and decode it
+
+		val, ok := gm.gs.MainDB()[key]
+		if !ok {
+			return nil
+		}
 
-	val, ok := gm.gs.MainDB()[key]
-	if ok {
 		mapval = stringToMap(val)
 		gm.mapCache[key] = mapval
 	}
 
-	return mapval
+	ret := make(map[string]string, len(mapval))
+	for k, v := range mapval {
+		ret[k] = v
+	}
+
+	return ret
 }
 
 /*
 storeMainDBMap stores a map in the main database. The map is stored as a gob byte slice.
-Once it has been decoded it is cached for read operations.
+Once it has been decoded it is cached for read operations. All maps in the main
+database describe the schema so every call changes the schema version.
 */
 func (gm *Manager) storeMainDBMap(key string, mapval map[string]string) {
+	gm.mainDBMutex.Lock()
+	defer gm.mainDBMutex.Unlock()
+
 	gm.mapCache[key] = mapval
 	gm.gs.MainDB()[key] = mapToString(mapval)
+
+	atomic.AddUint64(gm.schemaVersion, 1)
+}
+
+/*
+flushMain writes the main database to the graph storage.
+*/
+func (gm *Manager) flushMain() error {
+	gm.mainDBMutex.Lock()
+	defer gm.mainDBMutex.Unlock()
+
+	return gm.gs.FlushMain()
+}
+
+/*
+rollbackMain discards all changes to the main database since the last flush.
+*/
+func (gm *Manager) rollbackMain() error {
+	gm.mainDBMutex.Lock()
+	defer gm.mainDBMutex.Unlock()
+
+	return gm.gs.RollbackMain()
 }
 
 // Static helper functions
@@ -109,7 +109,7 @@ func TestImportExportError(t *testing.T) {
 	}))
 
 	msm = gs.StorageManager("main"+"bla"+StorageSuffixNodes, false).(*storage.MemoryStorageManager)
-	msm.AccessMap[6] = storage.AccessCacheAndFetchSeriousError
+	msm.AccessMap[7] = storage.AccessCacheAndFetchSeriousError
 
 	res.Reset()
 	err = ExportPartition(&res, "main", gm)
@@ -118,9 +118,9 @@ func TestImportExportError(t *testing.T) {
 		return
 	}
 
-	delete(msm.AccessMap, 6)
+	delete(msm.AccessMap, 7)
 
-	msm.AccessMap[5] = storage.AccessCacheAndFetchSeriousError
+	msm.AccessMap[6] = storage.AccessCacheAndFetchSeriousError
 
 	res.Reset()
 	err = ExportPartition(&res, "main", gm)
@@ -129,7 +129,7 @@ func TestImportExportError(t *testing.T) {
 		return
 	}
 
-	delete(msm.AccessMap, 5)
+	delete(msm.AccessMap, 6)
 
 	gm.StoreEdge("main", data.NewGraphEdgeFromNode(data.NewGraphNodeFromMap(map[string]interface{}{
 		"end1cascading": false,
@@ -146,7 +146,7 @@ func TestImportExportError(t *testing.T) {
 
 	// Traverse to relationship should fail
 
-	msm.AccessMap[7] = storage.AccessCacheAndFetchSeriousError
+	msm.AccessMap[9] = storage.AccessCacheAndFetchSeriousError
 
 	res.Reset()
 	err = ExportPartition(&res, "main", gm)
@@ -155,7 +155,7 @@ func TestImportExportError(t *testing.T) {
 		return
 	}
 
-	delete(msm.AccessMap, 7)
+	delete(msm.AccessMap, 9)
 
 	// Lookup of relationship should fail
 
@@ -11,6 +11,10 @@
 package graph
 
 import (
+	"sort"
+	"strings"
+
+	"devt.de/krotik/eliasdb/graph/data"
 	"devt.de/krotik/eliasdb/graph/util"
 	"devt.de/krotik/eliasdb/hash"
 )
@@ -59,3 +63,224 @@ Error returns the last encountered error.
 func (it *NodeKeyIterator) Error() error {
 	return it.LastError
 }
+
+/*
+EdgeKeyIterator can be used to iterate edge keys of a certain edge kind.
+*/
+type EdgeKeyIterator struct {
+	gm        *Manager            // GraphManager which created the iterator
+	it        *hash.HTreeIterator // Internal HTree iterator
+	nextKey   string              // Next edge key
+	hasNext   bool                // Flag if there is a next edge key
+	LastError error               // Last encountered error
+}
+
+/*
+Next returns the next edge key. Sets the LastError attribute if an error occurs.
+*/
+func (it *EdgeKeyIterator) Next() string {
+
+	// Take reader lock
+
+	it.gm.mutex.RLock()
+	defer it.gm.mutex.RUnlock()
+
+	key := it.nextKey
+
+	it.fetchNext()
+
+	return key
+}
+
+/*
+fetchNext looks up the next edge key. The edge storage holds the attribute
+list and the attribute values of each edge - only attribute lists are
+considered.
+*/
+func (it *EdgeKeyIterator) fetchNext() {
+
+	it.nextKey = ""
+	it.hasNext = false
+
+	for it.it.HasNext() {
+		k, _ := it.it.Next()
+
+		if it.it.LastError != nil {
+			it.LastError = &util.GraphError{Type: util.ErrReading, Detail: it.it.LastError.Error()}
+			return
+		}
+
+		if key := string(k); strings.HasPrefix(key, PrefixNSAttrs) {
+			it.nextKey = key[len(PrefixNSAttrs):]
+			it.hasNext = true
+			return
+		}
+	}
+}
+
+/*
+HasNext returns if there is a next edge key.
+*/
+func (it *EdgeKeyIterator) HasNext() bool {
+	return it.hasNext
+}
+
+/*
+Error returns the last encountered error.
+*/
+func (it *EdgeKeyIterator) Error() error {
+	return it.LastError
+}
+
+/*
+TraversalIterator can be used to iterate the nodes and edges which are
+connected to a certain node. The edge targets of a single edge spec are
+looked up at a time and the reader lock is only held while loading the next
+batch of edge targets or reading a single node and edge.
+*/
+type TraversalIterator struct {
+	gm        *Manager                   // GraphManager which created the iterator
+	part      string                     // Partition of the start node
+	key       string                     // Key of the start node
+	kind      string                     // Kind of the start node
+	direction string                     // Direction of the followed edges
+	specs     []string                   // Remaining edge specs to traverse
+	sspec     []string                   // Current split edge spec
+	edgeKeys  []string                   // Remaining edge keys of the current spec
+	targets   map[string]*edgeTargetInfo // Edge targets of the current spec
+	filter    EdgeFilter                 // Filter for followed edges (optional)
+	seen      map[string]bool            // Already returned nodes (only with filter)
+	nextNode  data.Node                  // Next matching node (only with filter)
+	nextEdge  data.Edge                  // Next matching edge (only with filter)
+	LastError error                      // Last encountered error
+}
+
+/*
+Next returns the next connected node and the edge which leads to it. All
+data of the node and the edge is populated.
+*/
+func (it *TraversalIterator) Next() (data.Node, data.Edge, error) {
+
+	if !it.HasNext() {
+		return nil, nil, it.LastError
+	}
+
+	if it.nextNode != nil {
+		node, edge := it.nextNode, it.nextEdge
+		it.nextNode, it.nextEdge = nil, nil
+
+		return node, edge, nil
+	}
+
+	return it.readNext()
+}
+
+/*
+readNext reads the node and edge of the next remaining edge key.
+*/
+func (it *TraversalIterator) readNext() (data.Node, data.Edge, error) {
+
+	edgeKey := it.edgeKeys[0]
+	it.edgeKeys = it.edgeKeys[1:]
+
+	// Take reader lock
+
+	it.gm.mutex.RLock()
+	defer it.gm.mutex.RUnlock()
+
+	node, edge, err := it.gm.readEdgeTarget(it.part, it.key, it.kind, it.sspec,
+		edgeKey, it.targets[edgeKey], true)
+
+	if err != nil {
+		it.LastError = err
+	}
+
+	return node, edge, err
+}
+
+/*
+HasNext returns if there is a next connected node. Loads the edge targets of
+the next edge spec if the current one is exhausted. If the iterator has an
+edge filter then edges are read ahead until a matching edge is found.
+*/
+func (it *TraversalIterator) HasNext() bool {
+
+	for {
+
+		for len(it.edgeKeys) == 0 && len(it.specs) > 0 && it.LastError == nil {
+			it.fetchNextSpec()
+		}
+
+		if it.filter == nil || it.nextNode != nil || len(it.edgeKeys) == 0 || it.LastError != nil {
+			return (it.nextNode != nil || len(it.edgeKeys) > 0) && it.LastError == nil
+		}
+
+		// Look ahead for the next edge which matches the filter and leads
+		// to a node which was not returned yet
+
+		node, edge, err := it.readNext()
+
+		if err == nil && node != nil && it.filter(edge) {
+
+			if nid := node.Kind() + "#" + node.Key(); !it.seen[nid] {
+				it.seen[nid] = true
+				it.nextNode, it.nextEdge = node, edge
+			}
+		}
+	}
+}
+
+/*
+fetchNextSpec loads the edge targets of the next remaining edge spec.
+*/
+func (it *TraversalIterator) fetchNextSpec() {
+
+	spec := it.specs[0]
+	it.specs = it.specs[1:]
+
+	it.sspec = nil
+	it.targets = nil
+	it.edgeKeys = nil
+
+	_, tree, err := it.gm.getNodeStorageHTree(it.part, it.kind, false)
+	if err != nil || tree == nil {
+		it.LastError = err
+		return
+	}
+
+	// Take reader lock
+
+	it.gm.mutex.RLock()
+	defer it.gm.mutex.RUnlock()
+
+	sspec, targets, err := it.gm.fetchEdgeTargets(tree, it.key, spec)
+	if err != nil || targets == nil {
+		it.LastError = err
+		return
+	}
+
+	// Iterate edges in a stable order
+
+	edgeKeys := make([]string, 0, len(targets))
+	for k := range targets {
+
+		if ok, err := it.gm.hasDirection(it.part, it.key, it.kind, sspec[1], k, it.direction); err != nil {
+			it.LastError = err
+			return
+		} else if ok {
+			edgeKeys = append(edgeKeys, k)
+		}
+	}
+	sort.Strings(edgeKeys)
+
+	it.sspec = sspec
+	it.targets = targets
+	it.edgeKeys = edgeKeys
+}
+
+/*
+Error returns the last encountered error.
+*/
+func (it *TraversalIterator) Error() error {
+	return it.LastError
+}
@@ -143,3 +143,201 @@ func TestNodeKeyIterator(t *testing.T) {
 		return
 	}
 }
+
+func TestEdgeKeyIterator(t *testing.T) {
+
+	mgs := graphstorage.NewMemoryGraphStorage("iterator test")
+
+	gm := newGraphManagerNoRules(mgs)
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "123")
+	node1.SetAttr("kind", "mykind")
+
+	gm.StoreNode("main", node1)
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "456")
+	node2.SetAttr("kind", "mykind")
+
+	gm.StoreNode("main", node2)
+
+	for _, key := range []string{"abc", "def"} {
+		edge := data.NewGraphEdge()
+
+		edge.SetAttr("key", key)
+		edge.SetAttr("kind", "myedge")
+		edge.SetAttr("name", "Edge "+key)
+
+		edge.SetAttr(data.EdgeEnd1Key, node1.Key())
+		edge.SetAttr(data.EdgeEnd1Kind, node1.Kind())
+		edge.SetAttr(data.EdgeEnd1Role, "node1")
+		edge.SetAttr(data.EdgeEnd1Cascading, true)
+
+		edge.SetAttr(data.EdgeEnd2Key, node2.Key())
+		edge.SetAttr(data.EdgeEnd2Kind, node2.Kind())
+		edge.SetAttr(data.EdgeEnd2Role, "node2")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		gm.StoreEdge("main", edge)
+	}
+
+	if ei, err := gm.EdgeKeyIterator("main", "myunknownedge"); ei != nil || err != nil {
+		t.Error("Unexpected result:", ei, err)
+		return
+	}
+
+	ei, err := gm.EdgeKeyIterator("main", "myedge")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	keys := make(map[string]bool)
+
+	for ei.HasNext() {
+		key := ei.Next()
+
+		if ei.LastError != nil {
+			t.Error(ei.LastError)
+			return
+		}
+
+		keys[key] = true
+	}
+
+	if len(keys) != 2 || !keys["abc"] || !keys["def"] {
+		t.Error("Unexpected keys:", keys)
+		return
+	}
+
+	if ei.Next() != "" || ei.Error() != nil {
+		t.Error("Expected iterator to run out of items:", ei.Error())
+		return
+	}
+}
+
+func TestTraversalIterator(t *testing.T) {
+
+	mgs := graphstorage.NewMemoryGraphStorage("iterator test")
+
+	gm := newGraphManagerNoRules(mgs)
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "123")
+	node1.SetAttr("kind", "mykind")
+
+	gm.StoreNode("main", node1)
+
+	for _, key := range []string{"456", "789"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "mykind")
+		node.SetAttr("name", "Node "+key)
+
+		gm.StoreNode("main", node)
+	}
+
+	for i, ekind := range []string{"myedge", "myotheredge"} {
+		target := []string{"456", "789"}[i]
+
+		edge := data.NewGraphEdge()
+
+		edge.SetAttr("key", "e"+target)
+		edge.SetAttr("kind", ekind)
+		edge.SetAttr("name", "Edge "+target)
+
+		edge.SetAttr(data.EdgeEnd1Key, node1.Key())
+		edge.SetAttr(data.EdgeEnd1Kind, node1.Kind())
+		edge.SetAttr(data.EdgeEnd1Role, "node1")
+		edge.SetAttr(data.EdgeEnd1Cascading, true)
+
+		edge.SetAttr(data.EdgeEnd2Key, target)
+		edge.SetAttr(data.EdgeEnd2Kind, "mykind")
+		edge.SetAttr(data.EdgeEnd2Role, "node2")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		gm.StoreEdge("main", edge)
+	}
+
+	if _, err := gm.TraverseMultiIter("main", node1.Key(), node1.Kind(), "abc"); err == nil ||
+		err.Error() != "GraphError: Invalid data (Invalid spec: abc)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ti, err := gm.TraverseMultiIter("main", node1.Key(), node1.Kind(), ":::")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	names := make(map[string]string)
+
+	for ti.HasNext() {
+		node, edge, err := ti.Next()
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		names[edge.Attr("name").(string)] = node.Attr("name").(string)
+	}
+
+	if len(names) != 2 || names["Edge 456"] != "Node 456" || names["Edge 789"] != "Node 789" {
+		t.Error("Unexpected result:", names)
+		return
+	}
+
+	if node, edge, err := ti.Next(); node != nil || edge != nil || err != nil || ti.Error() != nil {
+		t.Error("Expected iterator to run out of items:", node, edge, err)
+		return
+	}
+
+	// Partial specs only follow matching edges
+
+	ti, err = gm.TraverseMultiIter("main", node1.Key(), node1.Kind(), ":myotheredge::")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !ti.HasNext() {
+		t.Error("Expected a result")
+		return
+	}
+
+	if node, edge, err := ti.Next(); err != nil || node.Key() != "789" || edge.Key() != "e789" ||
+		edge.End1Key() != "123" {
+		t.Error("Unexpected result:", node, edge, err)
+		return
+	}
+
+	if ti.HasNext() {
+		t.Error("Unexpected further result")
+		return
+	}
+
+	// Traversal from the other end swaps the edge ends
+
+	ti, err = gm.TraverseMultiIter("main", "456", "mykind", "node2:myedge:node1:mykind")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if node, edge, err := ti.Next(); err != nil || node.Key() != "123" || edge.End1Key() != "456" ||
+		edge.End1Role() != "node2" {
+		t.Error("Unexpected result:", node, edge, err)
+		return
+	}
+
+	// Unknown nodes have no connections
+
+	ti, err = gm.TraverseMultiIter("main", "000", "mykind", ":::")
+	if err != nil || ti.HasNext() {
+		t.Error("Unexpected result:", ti, err)
+		return
+	}
+}
@@ -0,0 +1,257 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+)
+
+/*
+ShortestPath finds a path with the fewest hops between two nodes. The path is
+found with a breadth-first search which follows all edges matching a given
+traversal spec (an empty spec follows all edges). The search gives up after a
+given maximum number of hops (-1 for no limit) or when the given context is
+done. Returns the nodes and edges along the path. The lists are nil if the
+target node cannot be reached.
+*/
+func (gm *Manager) ShortestPath(ctx context.Context, part string, fromKey string, fromKind string,
+	toKey string, toKind string, spec string, maxHops int) ([]data.Node, []data.Edge, error) {
+
+	type pathStep struct {
+		prev string    // Node ID of the previous node
+		node data.Node // Node of this step
+		edge data.Edge // Edge which lead to this step
+	}
+
+	if spec == "" {
+		spec = ":::"
+	}
+
+	start, err := gm.FetchNode(part, fromKey, fromKind)
+	if err != nil || start == nil {
+		return nil, nil, err
+	}
+
+	nodeID := func(key string, kind string) string {
+		return kind + "#" + key
+	}
+
+	targetID := nodeID(toKey, toKind)
+
+	// Each visited node remembers the step which reached it first
+
+	steps := map[string]*pathStep{nodeID(fromKey, fromKind): {"", start, nil}}
+	frontier := []string{nodeID(fromKey, fromKind)}
+
+	for hops := 0; (maxHops == -1 || hops < maxHops) && len(frontier) > 0 && steps[targetID] == nil; hops++ {
+		var next []string
+
+		for _, id := range frontier {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+
+			step := steps[id]
+
+			nodes, edges, err := gm.TraverseMulti(part, step.node.Key(), step.node.Kind(), spec, true)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for i, node := range nodes {
+				if nid := nodeID(node.Key(), node.Kind()); steps[nid] == nil {
+					steps[nid] = &pathStep{id, node, edges[i]}
+					next = append(next, nid)
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	step, ok := steps[targetID]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	// Walk back from the target to the start
+
+	var nodes []data.Node
+	var edges []data.Edge
+
+	for ; step.edge != nil; step = steps[step.prev] {
+		nodes = append([]data.Node{step.node}, nodes...)
+		edges = append([]data.Edge{step.edge}, edges...)
+	}
+
+	return append([]data.Node{step.node}, nodes...), edges, nil
+}
+
+/*
+ShortestPathWeighted finds the path with the lowest total weight between two
+nodes. The weight of an edge is the value of a given numeric edge attribute.
+The path is found with Dijkstra's algorithm which follows all edges matching a
+given traversal spec (an empty spec follows all edges). Returns the nodes and
+edges along the path and the total weight of the path. The lists are nil if
+the target node cannot be reached. All edges which are followed must have a
+non-negative weight otherwise an ErrInvalidData error is returned.
+*/
+func (gm *Manager) ShortestPathWeighted(part string, fromKey string, fromKind string,
+	toKey string, toKind string, spec string, weightAttr string) ([]data.Node, []data.Edge, float64, error) {
+
+	type pathStep struct {
+		prev string    // Node ID of the previous node
+		node data.Node // Node of this step
+		edge data.Edge // Edge which lead to this step
+		cost float64   // Total weight of the path to this step
+		done bool      // Flag if the lowest total weight of this step is known
+	}
+
+	if spec == "" {
+		spec = ":::"
+	}
+
+	start, err := gm.FetchNode(part, fromKey, fromKind)
+	if err != nil || start == nil {
+		return nil, nil, 0, err
+	}
+
+	nodeID := func(key string, kind string) string {
+		return kind + "#" + key
+	}
+
+	startID := nodeID(fromKey, fromKind)
+	targetID := nodeID(toKey, toKind)
+
+	steps := map[string]*pathStep{startID: {"", start, nil, 0, false}}
+	queue := &pathQueue{{startID, 0}}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(*pathQueueItem)
+		step := steps[item.id]
+
+		// Skip nodes which have already been reached on a cheaper path
+
+		if step.done || item.cost > step.cost {
+			continue
+		}
+
+		step.done = true
+
+		if item.id == targetID {
+			break
+		}
+
+		nodes, edges, err := gm.TraverseMulti(part, step.node.Key(), step.node.Kind(), spec, true)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		for i, node := range nodes {
+
+			weight, err := edgeWeight(edges[i], weightAttr)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+
+			id := nodeID(node.Key(), node.Kind())
+			cost := step.cost + weight
+
+			if next, ok := steps[id]; !ok || (!next.done && cost < next.cost) {
+				steps[id] = &pathStep{item.id, node, edges[i], cost, false}
+				heap.Push(queue, &pathQueueItem{id, cost})
+			}
+		}
+	}
+
+	step, ok := steps[targetID]
+	if !ok || !step.done {
+		return nil, nil, 0, nil
+	}
+
+	cost := step.cost
+
+	// Walk back from the target to the start
+
+	var nodes []data.Node
+	var edges []data.Edge
+
+	for ; step.edge != nil; step = steps[step.prev] {
+		nodes = append([]data.Node{step.node}, nodes...)
+		edges = append([]data.Edge{step.edge}, edges...)
+	}
+
+	return append([]data.Node{step.node}, nodes...), edges, cost, nil
+}
+
+/*
+edgeWeight returns the weight of an edge which is stored in a given attribute.
+*/
+func edgeWeight(edge data.Edge, weightAttr string) (float64, error) {
+
+	val := edge.Attr(weightAttr)
+	if val == nil {
+		return 0, &util.GraphError{Type: util.ErrInvalidData,
+			Detail: fmt.Sprintf("Edge %v (%v) has no weight attribute %v", edge.Key(), edge.Kind(), weightAttr)}
+	}
+
+	weight, err := strconv.ParseFloat(fmt.Sprint(val), 64)
+	if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return 0, &util.GraphError{Type: util.ErrInvalidData,
+			Detail: fmt.Sprintf("Edge %v (%v) has a weight which is not a number: %v", edge.Key(), edge.Kind(), val)}
+	} else if weight < 0 {
+		return 0, &util.GraphError{Type: util.ErrInvalidData,
+			Detail: fmt.Sprintf("Edge %v (%v) has a negative weight: %v", edge.Key(), edge.Kind(), val)}
+	}
+
+	return weight, nil
+}
+
+/*
+pathQueueItem is a node in the priority queue of a weighted path search.
+*/
+type pathQueueItem struct {
+	id   string  // Node ID
+	cost float64 // Total weight of the path to the node
+}
+
+/*
+pathQueue is a priority queue which provides the node with the lowest total
+weight first. Nodes with the same weight are ordered by their ID.
+*/
+type pathQueue []*pathQueueItem
+
+func (q pathQueue) Len() int { return len(q) }
+
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].cost == q[j].cost {
+		return q[i].id < q[j].id
+	}
+	return q[i].cost < q[j].cost
+}
+
+func (q pathQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*pathQueueItem)) }
+
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
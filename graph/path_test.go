@@ -0,0 +1,254 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+)
+
+func TestShortestPath(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "city")
+		gm.StoreNode("main", node)
+	}
+
+	storeRoad := func(key string, kind string, from string, to string) {
+		edge := data.NewGraphEdge()
+		edge.SetAttr("key", key)
+		edge.SetAttr("kind", kind)
+		edge.SetAttr(data.EdgeEnd1Key, from)
+		edge.SetAttr(data.EdgeEnd1Kind, "city")
+		edge.SetAttr(data.EdgeEnd1Role, "from")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+		edge.SetAttr(data.EdgeEnd2Key, to)
+		edge.SetAttr(data.EdgeEnd2Kind, "city")
+		edge.SetAttr(data.EdgeEnd2Role, "to")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The roads between a, b and c form a cycle - d can be reached directly
+	// by rail or via the cycle by road
+
+	storeRoad("ab", "road", "a", "b")
+	storeRoad("bc", "road", "b", "c")
+	storeRoad("ca", "road", "c", "a")
+	storeRoad("cd", "road", "c", "d")
+	storeRoad("ad", "rail", "a", "d")
+
+	pathKeys := func(nodes []data.Node, edges []data.Edge, err error) string {
+		var keys []string
+
+		for i, n := range nodes {
+			if i > 0 {
+				keys = append(keys, fmt.Sprintf("-%v-", edges[i-1].Key()))
+			}
+			keys = append(keys, n.Key())
+		}
+
+		return fmt.Sprint(keys, " ", err)
+	}
+
+	ctx := context.Background()
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "a", "city", "d", "city", "", -1)); res != "[a -ad- d] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "b", "city", "d", "city", ":road::", -1)); res != "[b -bc- c -cd- d] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "a", "city", "a", "city", "", -1)); res != "[a] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// The search is limited by the maximum number of hops
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "a", "city", "d", "city", ":road::", 1)); res != "[] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "a", "city", "d", "city", ":road::", 2)); res != "[a -ca- c -cd- d] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Disconnected nodes and unknown nodes cannot be reached
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "a", "city", "e", "city", "", -1)); res != "[] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "x", "city", "a", "city", "", -1)); res != "[] <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Test error cases
+
+	if res := pathKeys(gm.ShortestPath(ctx, "main", "a", "city", "d", "city", "foo", -1)); res != "[] GraphError: Invalid data (Invalid spec: foo)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if res := pathKeys(gm.ShortestPath(cctx, "main", "a", "city", "d", "city", "", -1)); res != "[] context canceled" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestShortestPathWeighted(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "city")
+		gm.StoreNode("main", node)
+	}
+
+	storeRoad := func(key string, kind string, from string, to string, length interface{}) {
+		edge := data.NewGraphEdge()
+		edge.SetAttr("key", key)
+		edge.SetAttr("kind", kind)
+		edge.SetAttr(data.EdgeEnd1Key, from)
+		edge.SetAttr(data.EdgeEnd1Kind, "city")
+		edge.SetAttr(data.EdgeEnd1Role, "from")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+		edge.SetAttr(data.EdgeEnd2Key, to)
+		edge.SetAttr(data.EdgeEnd2Kind, "city")
+		edge.SetAttr(data.EdgeEnd2Role, "to")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+		if length != nil {
+			edge.SetAttr("length", length)
+		}
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The direct road from a to d is longer than the detour via b and c.
+	// The roads between a, b and c form a cycle.
+
+	storeRoad("ab", "road", "a", "b", 1)
+	storeRoad("bc", "road", "b", "c", 1.5)
+	storeRoad("ca", "road", "c", "a", 5)
+	storeRoad("cd", "road", "c", "d", "1")
+	storeRoad("ad", "road", "a", "d", 10)
+
+	pathKeys := func(nodes []data.Node, edges []data.Edge, cost float64, err error) string {
+		var keys []string
+
+		for i, n := range nodes {
+			if i > 0 {
+				keys = append(keys, fmt.Sprintf("-%v-", edges[i-1].Key()))
+			}
+			keys = append(keys, n.Key())
+		}
+
+		return fmt.Sprint(keys, " ", cost, " ", err)
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", "", "length")); res != "[a -ab- b -bc- c -cd- d] 3.5 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "d", "city", "b", "city", ":road::", "length")); res != "[d -cd- c -bc- b] 2.5 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "a", "city", "", "length")); res != "[a] 0 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Disconnected nodes and unknown nodes cannot be reached
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "e", "city", "", "length")); res != "[] 0 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "x", "city", "a", "city", "", "length")); res != "[] 0 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", ":rail::", "length")); res != "[] 0 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Test error cases
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", "foo", "length")); res != "[] 0 GraphError: Invalid data (Invalid spec: foo)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", "", "lanes")); !strings.HasSuffix(res, "(road) has no weight attribute lanes)") {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	storeRoad("ae", "track", "a", "e", nil)
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", "", "length")); res != "[] 0 GraphError: Invalid data (Edge ae (track) has no weight attribute length)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", ":road::", "length")); res != "[a -ab- b -bc- c -cd- d] 3.5 <nil>" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	storeRoad("cd", "road", "c", "d", -1)
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", ":road::", "length")); res != "[] 0 GraphError: Invalid data (Edge cd (road) has a negative weight: -1)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	storeRoad("cd", "road", "c", "d", "far")
+
+	if res := pathKeys(gm.ShortestPathWeighted("main", "a", "city", "d", "city", ":road::", "length")); res != "[] 0 GraphError: Invalid data (Edge cd (road) has a weight which is not a number: far)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -92,7 +92,8 @@ func (gr *graphRulesManager) graphEvent(trans Trans, event int, data ...interfac
 Clone a given graph manager and insert a new RWMutex.
 */
 func (gr *graphRulesManager) cloneGraphManager() *Manager {
-	return &Manager{gr.gm.gs, gr, gr.gm.nm, gr.gm.mapCache, &sync.RWMutex{}, &sync.Mutex{}}
+	return &Manager{gr.gm.gs, gr, gr.gm.nm, gr.gm.mapCache, &sync.RWMutex{}, &sync.Mutex{},
+		gr.gm.mainDBMutex, gr.gm.schemaVersion, gr.gm.readOnly, gr.gm.readOnlyMutex}
 }
 
 /*
@@ -159,14 +160,43 @@ func (r *SystemRuleDeleteNodeEdges) Handle(gm *Manager, trans Trans, event int,
 	part := ed[0].(string)
 	node := ed[1].(data.Node)
 
+	edges, nodes, err := cascadeRemovals(gm, part, node, nil)
+
+	// Remove the edges in any case
+
+	for _, edge := range edges {
+		trans.RemoveEdge(part, edge.Key(), edge.Kind())
+	}
+
+	// No error handling at this point since only a wrong partition
+	// name can cause an issue and this would have failed before
+
+	for _, node := range nodes {
+		trans.RemoveNode(part, node.Key(), node.Kind())
+	}
+
+	return err
+}
+
+/*
+cascadeRemovals determines all edges which are removed together with a given
+node and all nodes which are removed through cascading edges. Edges which are
+in the given removed map (see cascadeEdgeID) are regarded as already removed.
+*/
+func cascadeRemovals(gm *Manager, part string, node data.Node,
+	removed map[string]bool) ([]data.Edge, []data.Node, error) {
+
+	var removeEdges []data.Edge
+	var removeNodes []data.Node
+
 	// Get all connected nodes and relationships
 
 	nnodes, edges, err := gm.TraverseMulti(part, node.Key(), node.Kind(), ":::", false)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	edgeRemovalCount := make(map[string]int) // Count of cascading last edges which are removed
+	removing := make(map[string]bool) // Edges which are removed with this node
 
 	// Nodes which need to be checked if the last edge of a certain kind has been removed
 
@@ -175,9 +205,14 @@ func (r *SystemRuleDeleteNodeEdges) Handle(gm *Manager, trans Trans, event int,
 
 	for i, edge := range edges {
 
+		if removed[cascadeEdgeID(edge)] {
+			continue
+		}
+
 		// Remove the edge in any case
 
-		trans.RemoveEdge(part, edge.Key(), edge.Kind())
+		removing[cascadeEdgeID(edge)] = true
+		removeEdges = append(removeEdges, edge)
 
 		// Remove the node on the other side if the edge is cascading on this end
 
@@ -193,21 +228,12 @@ func (r *SystemRuleDeleteNodeEdges) Handle(gm *Manager, trans Trans, event int,
 				nodeOtherSide := nnodes[i]
 				specOtherSide := edge.Spec(nodeOtherSide.Key())
 
-				if c, ok := edgeRemovalCount[specOtherSide]; ok {
-					edgeRemovalCount[specOtherSide] = c + 1
-				} else {
-					edgeRemovalCount[specOtherSide] = 1
-				}
-
 				nodeRemovalCheckSpecs = append(nodeRemovalCheckSpecs, specOtherSide)
 				nodeRemovalCheckNodes = append(nodeRemovalCheckNodes, nodeOtherSide)
 
 			} else {
 
-				// No error handling at this point since only a wrong partition
-				// name can cause an issue and this would have failed before
-
-				trans.RemoveNode(part, nnodes[i].Key(), nnodes[i].Kind())
+				removeNodes = append(removeNodes, nnodes[i])
 			}
 		}
 	}
@@ -216,20 +242,33 @@ func (r *SystemRuleDeleteNodeEdges) Handle(gm *Manager, trans Trans, event int,
 
 	for i, node := range nodeRemovalCheckNodes {
 		specToCheck := nodeRemovalCheckSpecs[i]
-		removalCount := edgeRemovalCount[specToCheck]
 
 		if err == nil {
 
 			_, edges, err = gm.TraverseMulti(part, node.Key(), node.Kind(), specToCheck, false)
 
-			if len(edges)-removalCount == 0 {
+			remaining := 0
+
+			for _, edge := range edges {
+				if id := cascadeEdgeID(edge); !removing[id] && !removed[id] {
+					remaining++
+				}
+			}
 
-				trans.RemoveNode(part, node.Key(), node.Kind())
+			if remaining == 0 {
+				removeNodes = append(removeNodes, node)
 			}
 		}
 	}
 
-	return err
+	return removeEdges, removeNodes, err
+}
+
+/*
+cascadeEdgeID returns an identifier for an edge which is unique within a partition.
+*/
+func cascadeEdgeID(edge data.Edge) string {
+	return edge.Kind() + "#" + edge.Key()
 }
 
 // System rule SystemRuleUpdateNodeStats
@@ -0,0 +1,543 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+)
+
+/*
+SetNodeSchema registers a JSON Schema for a node kind in a partition. All
+nodes of the kind which are stored afterwards must match the schema - the
+schema is applied to an object which contains all attributes of a node except
+key and kind. Updates are validated against the merged node. The following
+keywords are supported: type, enum, const, properties, required,
+additionalProperties, items, minItems, maxItems, minimum, maximum,
+exclusiveMinimum, exclusiveMaximum, minLength, maxLength and pattern. Other
+keywords are ignored. An existing schema is replaced. Returns an error if
+stored nodes do not match the schema.
+*/
+func (gm *Manager) SetNodeSchema(part string, kind string, schema string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	parsed, err := parseNodeSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	// Check that the stored nodes match the schema
+
+	it, err := gm.NodeKeyIterator(part, kind)
+	if err != nil {
+		return err
+	}
+
+	for it != nil && it.HasNext() {
+		key := it.Next()
+
+		if it.LastError != nil {
+			return it.LastError
+		}
+
+		node, err := gm.FetchNode(part, key, kind)
+		if err != nil {
+			return err
+		} else if node == nil {
+			continue
+		}
+
+		if err := validateNodeSchema(parsed, part, node); err != nil {
+			return err
+		}
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	gm.storeMainDBMap(MainDBNodeSchema+part+"#"+kind, map[string]string{"schema": schema})
+
+	return gm.flushMain()
+}
+
+/*
+DropNodeSchema removes the JSON Schema of a node kind in a partition.
+*/
+func (gm *Manager) DropNodeSchema(part string, kind string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	if gm.nodeSchema(part, kind) == "" {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Schema for %v does not exist in partition %v", kind, part),
+		}
+	}
+
+	gm.storeMainDBMap(MainDBNodeSchema+part+"#"+kind, map[string]string{})
+
+	return gm.flushMain()
+}
+
+/*
+NodeSchema returns the JSON Schema of a node kind in a partition. Returns an
+empty string if the kind has no schema.
+*/
+func (gm *Manager) NodeSchema(part string, kind string) string {
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	return gm.nodeSchema(part, kind)
+}
+
+/*
+checkNodeSchema checks that a node which is about to be stored matches the
+schema of its kind. If the node is an update then the oldNode function is
+used to lookup the stored node which is merged with the update before the
+check. It is assumed that the caller holds the writer lock.
+*/
+func (gm *Manager) checkNodeSchema(part string, node data.Node, oldNode func() (data.Node, error)) error {
+
+	schema := gm.nodeSchema(part, node.Kind())
+	if schema == "" {
+		return nil
+	}
+
+	parsed, err := parseNodeSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	if oldNode != nil {
+		old, err := oldNode()
+		if err != nil {
+			return err
+		} else if old != nil {
+			node = data.NodeMerge(old, node)
+		}
+	}
+
+	return validateNodeSchema(parsed, part, node)
+}
+
+/*
+nodeSchema returns the JSON Schema of a node kind in a partition.
+*/
+func (gm *Manager) nodeSchema(part string, kind string) string {
+	return gm.getMainDBMap(MainDBNodeSchema + part + "#" + kind)["schema"]
+}
+
+/*
+parseNodeSchema parses a JSON Schema and checks the supported keywords.
+*/
+func parseNodeSchema(schema string) (map[string]interface{}, error) {
+	var parsed map[string]interface{}
+
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: "Invalid schema: " + err.Error(),
+		}
+	}
+
+	if err := checkSchemaDefinition(parsed, ""); err != nil {
+		return nil, &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: "Invalid schema: " + err.Error(),
+		}
+	}
+
+	return parsed, nil
+}
+
+/*
+validateNodeSchema validates all attributes of a node (except key and kind)
+against a parsed schema. The returned error lists all failing attributes.
+*/
+func validateNodeSchema(schema map[string]interface{}, part string, node data.Node) error {
+	var errs []string
+
+	attrs := make(map[string]interface{})
+
+	for attr, val := range node.Data() {
+		if !nodeAttributeFilter(attr) {
+			attrs[attr] = val
+		}
+	}
+
+	validateSchemaValue(schema, "", attrs, &errs)
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+
+		return &util.GraphError{
+			Type: util.ErrSchemaViolation,
+			Detail: fmt.Sprintf("Node %v of kind %v does not match the schema in partition %v: %v",
+				node.Key(), node.Kind(), part, strings.Join(errs, "; ")),
+		}
+	}
+
+	return nil
+}
+
+/*
+schemaTypes are the known types of JSON Schema.
+*/
+var schemaTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+	"null":    true,
+}
+
+/*
+checkSchemaDefinition checks the supported keywords of a (sub)schema.
+*/
+func checkSchemaDefinition(schema map[string]interface{}, path string) error {
+
+	at := func(msg string, args ...interface{}) error {
+		if path != "" {
+			return fmt.Errorf("%v: "+msg, append([]interface{}{path}, args...)...)
+		}
+		return fmt.Errorf(msg, args...)
+	}
+
+	if t, ok := schema["type"]; ok {
+		types, ok := schemaStringList(t)
+		if !ok {
+			return at("type must be a string or a list of strings")
+		}
+		for _, typ := range types {
+			if !schemaTypes[typ] {
+				return at("unknown type %v", typ)
+			}
+		}
+	}
+
+	if r, ok := schema["required"]; ok {
+		if _, ok := r.([]interface{}); !ok {
+			return at("required must be a list of strings")
+		} else if _, ok := schemaStringList(r); !ok {
+			return at("required must be a list of strings")
+		}
+	}
+
+	if e, ok := schema["enum"]; ok {
+		if _, ok := e.([]interface{}); !ok {
+			return at("enum must be a list")
+		}
+	}
+
+	if p, ok := schema["pattern"]; ok {
+		ps, ok := p.(string)
+		if !ok {
+			return at("pattern must be a string")
+		} else if _, err := regexp.Compile(ps); err != nil {
+			return at("invalid pattern %v", ps)
+		}
+	}
+
+	for _, kw := range []string{"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum",
+		"minLength", "maxLength", "minItems", "maxItems"} {
+
+		if v, ok := schema[kw]; ok {
+			if _, ok := v.(float64); !ok {
+				return at("%v must be a number", kw)
+			}
+		}
+	}
+
+	if p, ok := schema["properties"]; ok {
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return at("properties must be an object")
+		}
+		for name, sub := range props {
+			subSchema, ok := sub.(map[string]interface{})
+			if !ok {
+				return at("property %v must be a schema object", name)
+			} else if err := checkSchemaDefinition(subSchema, schemaPath(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if a, ok := schema["additionalProperties"]; ok {
+		if subSchema, ok := a.(map[string]interface{}); ok {
+			if err := checkSchemaDefinition(subSchema, schemaPath(path, "*")); err != nil {
+				return err
+			}
+		} else if _, ok := a.(bool); !ok {
+			return at("additionalProperties must be a boolean or a schema object")
+		}
+	}
+
+	if i, ok := schema["items"]; ok {
+		subSchema, ok := i.(map[string]interface{})
+		if !ok {
+			return at("items must be a schema object")
+		} else if err := checkSchemaDefinition(subSchema, path+"[]"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+validateSchemaValue validates a value against a (sub)schema. All failures are
+added to a given list of errors.
+*/
+func validateSchemaValue(schema map[string]interface{}, path string, val interface{}, errs *[]string) {
+
+	fail := func(msg string, args ...interface{}) {
+		p := path
+		if p == "" {
+			p = "(node)"
+		}
+		*errs = append(*errs, p+": "+fmt.Sprintf(msg, args...))
+	}
+
+	val = normalizeSchemaValue(val)
+
+	if t, ok := schema["type"]; ok {
+		types, _ := schemaStringList(t)
+		if !schemaTypeMatches(types, val) {
+			fail("must be of type %v", strings.Join(types, " or "))
+			return
+		}
+	}
+
+	if e, ok := schema["enum"]; ok {
+		found := false
+		for _, ev := range e.([]interface{}) {
+			if reflect.DeepEqual(ev, val) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %v", schemaJSON(e))
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, val) {
+		fail("must be %v", schemaJSON(c))
+	}
+
+	switch v := val.(type) {
+
+	case float64:
+		if m, ok := schema["minimum"].(float64); ok && v < m {
+			fail("must be >= %v", m)
+		}
+		if m, ok := schema["maximum"].(float64); ok && v > m {
+			fail("must be <= %v", m)
+		}
+		if m, ok := schema["exclusiveMinimum"].(float64); ok && v <= m {
+			fail("must be > %v", m)
+		}
+		if m, ok := schema["exclusiveMaximum"].(float64); ok && v >= m {
+			fail("must be < %v", m)
+		}
+
+	case string:
+		l := float64(len([]rune(v)))
+		if m, ok := schema["minLength"].(float64); ok && l < m {
+			fail("must be at least %v characters long", m)
+		}
+		if m, ok := schema["maxLength"].(float64); ok && l > m {
+			fail("must be at most %v characters long", m)
+		}
+		if p, ok := schema["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(v) {
+			fail("must match pattern %v", p)
+		}
+
+	case []interface{}:
+		l := float64(len(v))
+		if m, ok := schema["minItems"].(float64); ok && l < m {
+			fail("must have at least %v items", m)
+		}
+		if m, ok := schema["maxItems"].(float64); ok && l > m {
+			fail("must have at most %v items", m)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaValue(items, fmt.Sprintf("%v[%v]", path, i), item, errs)
+			}
+		}
+
+	case map[string]interface{}:
+		if r, ok := schema["required"]; ok {
+			required, _ := schemaStringList(r)
+			for _, name := range required {
+				if _, ok := v[name]; !ok {
+					*errs = append(*errs, schemaPath(path, name)+": required attribute is missing")
+				}
+			}
+		}
+
+		props, _ := schema["properties"].(map[string]interface{})
+
+		for name, pval := range v {
+			if sub, ok := props[name]; ok {
+				validateSchemaValue(sub.(map[string]interface{}), schemaPath(path, name), pval, errs)
+			} else if a, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				validateSchemaValue(a, schemaPath(path, name), pval, errs)
+			} else if a, ok := schema["additionalProperties"].(bool); ok && !a {
+				*errs = append(*errs, schemaPath(path, name)+": attribute is not allowed")
+			}
+		}
+	}
+}
+
+/*
+normalizeSchemaValue converts a Go value into the representation which is
+produced by decoding JSON (numbers become float64, lists and maps become
+[]interface{} and map[string]interface{}).
+*/
+func normalizeSchemaValue(val interface{}) interface{} {
+
+	switch v := val.(type) {
+	case nil, bool, string, float64, []interface{}, map[string]interface{}:
+		return val
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(val)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	}
+
+	// Round trip all other values through JSON
+
+	var ret interface{}
+
+	if b, err := json.Marshal(val); err == nil && json.Unmarshal(b, &ret) == nil {
+		return ret
+	}
+
+	return fmt.Sprint(val)
+}
+
+/*
+schemaTypeMatches checks if a normalized value has one of the given types.
+*/
+func schemaTypeMatches(types []string, val interface{}) bool {
+	for _, typ := range types {
+		switch v := val.(type) {
+		case nil:
+			if typ == "null" {
+				return true
+			}
+		case bool:
+			if typ == "boolean" {
+				return true
+			}
+		case string:
+			if typ == "string" {
+				return true
+			}
+		case float64:
+			if typ == "number" || (typ == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case []interface{}:
+			if typ == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if typ == "object" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+schemaStringList returns a string or a list of strings as a list of strings.
+*/
+func schemaStringList(val interface{}) ([]string, bool) {
+	if s, ok := val.(string); ok {
+		return []string{s}, true
+	}
+
+	l, ok := val.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	ret := make([]string, 0, len(l))
+
+	for _, v := range l {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		ret = append(ret, s)
+	}
+
+	return ret, true
+}
+
+/*
+schemaPath returns the path of an attribute in a (nested) value.
+*/
+func schemaPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+/*
+schemaJSON returns a value of a schema as JSON string.
+*/
+func schemaJSON(val interface{}) string {
+	b, _ := json.Marshal(val)
+	return string(b)
+}
@@ -0,0 +1,223 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+	"devt.de/krotik/eliasdb/graph/util"
+)
+
+const testSongSchema = `{
+	"type" : "object",
+	"required" : ["name", "ranking"],
+	"properties" : {
+		"name" : { "type" : "string", "minLength" : 1 },
+		"ranking" : { "type" : "integer", "minimum" : 0, "maximum" : 10 },
+		"genre" : { "enum" : ["rock", "pop"] },
+		"tags" : { "type" : "array", "items" : { "type" : "string", "pattern" : "^[a-z]+$" } }
+	},
+	"additionalProperties" : false
+}`
+
+func TestNodeSchema(t *testing.T) {
+
+	// Use a disk storage so failed transactions can be rolled back and the
+	// schema can be read back after a restart
+
+	dgs, err := graphstorage.NewDiskGraphStorage(GraphManagerTestDBDir8, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	gm := NewGraphManager(dgs)
+
+	newSong := func(key string, attrs map[string]interface{}) data.Node {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "Song")
+		for attr, val := range attrs {
+			node.SetAttr(attr, val)
+		}
+		return node
+	}
+
+	gm.StoreNode("main", newSong("1", map[string]interface{}{"name": "foo"}))
+
+	// Existing nodes must match the schema
+
+	if err := gm.SetNodeSchema("main", "Song", testSongSchema); err == nil || err.Error() !=
+		"GraphError: Schema violation (Node 1 of kind Song does not match the schema in partition main: "+
+			"ranking: required attribute is missing)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if res := gm.NodeSchema("main", "Song"); res != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	gm.StoreNode("main", newSong("1", map[string]interface{}{"name": "foo", "ranking": 1}))
+
+	if err := gm.SetNodeSchema("main", "Song", testSongSchema); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Stores are checked - all failing attributes are listed
+
+	err = gm.StoreNode("main", newSong("2", map[string]interface{}{
+		"name":    "",
+		"ranking": 1.5,
+		"genre":   "jazz",
+		"tags":    []string{"a", "B"},
+		"foo":     "bar",
+	}))
+
+	if err == nil || err.(*util.GraphError).Type != util.ErrSchemaViolation || err.Error() !=
+		`GraphError: Schema violation (Node 2 of kind Song does not match the schema in partition main: `+
+			`foo: attribute is not allowed; genre: must be one of ["rock","pop"]; `+
+			`name: must be at least 1 characters long; ranking: must be of type integer; `+
+			`tags[1]: must match pattern ^[a-z]+$)` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if node, _ := gm.FetchNode("main", "2", "Song"); node != nil {
+		t.Error("Unexpected result:", node)
+		return
+	}
+
+	if err := gm.StoreNode("main", newSong("2", map[string]interface{}{
+		"name": "bar", "ranking": 10, "genre": "rock", "tags": []string{"a", "b"},
+	})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Updates are checked after they are merged with the stored node
+
+	if err := gm.UpdateNode("main", newSong("2", map[string]interface{}{"ranking": 3})); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.UpdateNode("main", newSong("2", map[string]interface{}{"ranking": 11})); err == nil ||
+		err.Error() != "GraphError: Schema violation (Node 2 of kind Song does not match the schema "+
+			"in partition main: ranking: must be <= 10)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.UpdateNode("main", newSong("3", map[string]interface{}{"ranking": 3})); err == nil ||
+		err.Error() != "GraphError: Schema violation (Node 3 of kind Song does not match the schema "+
+			"in partition main: name: required attribute is missing)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Transactions are checked and rolled back
+
+	trans := NewGraphTrans(gm)
+	trans.StoreNode("main", newSong("4", map[string]interface{}{"name": "x", "ranking": 1}))
+	trans.UpdateNode("main", newSong("2", map[string]interface{}{"name": 5}))
+
+	if err := trans.Commit(); err == nil || err.Error() != "GraphError: Schema violation (Node 2 of kind "+
+		"Song does not match the schema in partition main: name: must be of type string)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if node, _ := gm.FetchNode("main", "4", "Song"); node != nil {
+		t.Error("Unexpected result:", node)
+		return
+	}
+
+	trans = NewGraphTrans(gm)
+	trans.UpdateNode("main", newSong("2", map[string]interface{}{"name": "baz"}))
+
+	if err := trans.Commit(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Other kinds and partitions are not affected
+
+	if err := gm.StoreNode("test", newSong("5", nil)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// The schema survives a restart
+
+	dgs.Close()
+
+	dgs, err = graphstorage.NewDiskGraphStorage(GraphManagerTestDBDir8, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer dgs.Close()
+
+	gm = NewGraphManager(dgs)
+
+	if res := gm.NodeSchema("main", "Song"); res != testSongSchema {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if err := gm.StoreNode("main", newSong("6", nil)); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Test error cases
+
+	if err := gm.SetNodeSchema("main", "Song", `{"type":"foo"}`); err == nil || err.Error() !=
+		"GraphError: Invalid data (Invalid schema: unknown type foo)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.SetNodeSchema("main", "Song", `{"properties":{"a":{"pattern":"("}}}`); err == nil ||
+		err.Error() != "GraphError: Invalid data (Invalid schema: a: invalid pattern ()" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.SetNodeSchema("main", "Song", `[]`); err == nil || err.Error() !=
+		"GraphError: Invalid data (Invalid schema: json: cannot unmarshal array into Go value of type map[string]interface {})" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.DropNodeSchema("main", "Author"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Schema for Author does not exist in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Drop the schema
+
+	if err := gm.DropNodeSchema("main", "Song"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.StoreNode("main", newSong("6", nil)); err != nil {
+		t.Error(err)
+		return
+	}
+}
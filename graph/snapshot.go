@@ -0,0 +1,267 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/hash"
+)
+
+func init() {
+
+	// Attribute values are encoded as interface values - all types which
+	// can be stored as attribute values need to be known to gob
+
+	gob.Register(make([]interface{}, 0))
+	gob.Register(make(map[string]interface{}))
+	gob.Register(make([]string, 0))
+	gob.Register(make(map[string]string))
+	gob.Register(make([]int, 0))
+	gob.Register(make([]int64, 0))
+	gob.Register(make([]float64, 0))
+	gob.Register(make([]bool, 0))
+}
+
+/*
+SnapshotVersion is the version of the snapshot archive format.
+*/
+const SnapshotVersion = 1
+
+/*
+Entry types in a snapshot archive
+*/
+const (
+	snapshotEntryNode = iota
+	snapshotEntryEdge
+	snapshotEntryEnd
+)
+
+/*
+snapshotHeader is the first object in a snapshot archive.
+*/
+type snapshotHeader struct {
+	Version   int    // Version of the archive format
+	Partition string // Partition which was archived
+}
+
+/*
+snapshotEntry is a single node or edge in a snapshot archive. The archive
+ends with an end entry which holds the number of archived nodes and edges.
+*/
+type snapshotEntry struct {
+	Type  int                    // Type of the entry
+	Data  map[string]interface{} // Node or edge data
+	Nodes int                    // Number of archived nodes (end entry only)
+	Edges int                    // Number of archived edges (end entry only)
+}
+
+/*
+Snapshot writes a consistent point-in-time archive of all nodes and edges of
+a partition to an io.Writer. The reader lock is held while the archive is
+written - other readers can continue but writers are blocked until the
+snapshot has finished.
+*/
+func (gm *Manager) Snapshot(part string, w io.Writer) error {
+	var nodeKinds, edgeKinds []string
+	var nodeTrees [][2]*hash.HTree
+	var edgeTrees []*hash.HTree
+
+	// Get the storage of all node and edge kinds in the partition
+
+	for _, kind := range gm.NodeKinds() {
+		attTree, valTree, err := gm.getNodeStorageHTree(part, kind, false)
+		if err != nil {
+			return err
+		} else if attTree != nil {
+			nodeKinds = append(nodeKinds, kind)
+			nodeTrees = append(nodeTrees, [2]*hash.HTree{attTree, valTree})
+		}
+	}
+
+	for _, kind := range gm.EdgeKinds() {
+		tree, err := gm.getEdgeStorageHTree(part, kind, false)
+		if err != nil {
+			return err
+		} else if tree != nil {
+			edgeKinds = append(edgeKinds, kind)
+			edgeTrees = append(edgeTrees, tree)
+		}
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	enc := gob.NewEncoder(w)
+
+	writeErr := func(err error) error {
+		return &util.GraphError{Type: util.ErrWriting, Detail: err.Error()}
+	}
+
+	if err := enc.Encode(&snapshotHeader{SnapshotVersion, part}); err != nil {
+		return writeErr(err)
+	}
+
+	end := &snapshotEntry{Type: snapshotEntryEnd}
+
+	// Write all items of a storage tree - only attribute lists identify items
+
+	writeTree := func(entryType int, kind string, attTree *hash.HTree,
+		valTree *hash.HTree) (int, error) {
+
+		count := 0
+		it := hash.NewHTreeIterator(attTree)
+
+		for it.HasNext() {
+			k, _ := it.Next()
+
+			if it.LastError != nil {
+				return 0, &util.GraphError{Type: util.ErrReading, Detail: it.LastError.Error()}
+			}
+
+			key := string(k)
+
+			if !strings.HasPrefix(key, PrefixNSAttrs) {
+				continue
+			}
+
+			node, err := gm.readNode(key[len(PrefixNSAttrs):], kind, nil, attTree, valTree)
+			if err != nil {
+				return 0, err
+			} else if node == nil {
+				continue
+			}
+
+			if err := enc.Encode(&snapshotEntry{Type: entryType, Data: node.Data()}); err != nil {
+				return 0, writeErr(err)
+			}
+
+			count++
+		}
+
+		return count, nil
+	}
+
+	for i, kind := range nodeKinds {
+		count, err := writeTree(snapshotEntryNode, kind, nodeTrees[i][0], nodeTrees[i][1])
+		if err != nil {
+			return err
+		}
+		end.Nodes += count
+	}
+
+	for i, kind := range edgeKinds {
+		count, err := writeTree(snapshotEntryEdge, kind, edgeTrees[i], edgeTrees[i])
+		if err != nil {
+			return err
+		}
+		end.Edges += count
+	}
+
+	if err := enc.Encode(end); err != nil {
+		return writeErr(err)
+	}
+
+	return nil
+}
+
+/*
+Restore recreates a partition from an archive which was written by Snapshot.
+The partition must not contain any nodes. All nodes are stored before any edge
+is stored. Returns an error if the archive is incomplete - items which were
+read up to this point have been stored.
+*/
+func (gm *Manager) Restore(part string, r io.Reader) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	// Check that the partition is empty
+
+	for _, kind := range gm.NodeKinds() {
+		it, err := gm.NodeKeyIterator(part, kind)
+		if err != nil {
+			return err
+		} else if it != nil && it.HasNext() {
+			return &util.GraphError{
+				Type:   util.ErrInvalidData,
+				Detail: fmt.Sprintf("Partition %v is not empty", part),
+			}
+		}
+	}
+
+	dec := gob.NewDecoder(r)
+
+	readErr := func(err error) error {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprint("Could not read snapshot: ", err.Error()),
+		}
+	}
+
+	var header snapshotHeader
+
+	if err := dec.Decode(&header); err != nil {
+		return readErr(err)
+	} else if header.Version != SnapshotVersion {
+		return readErr(fmt.Errorf("Unsupported version %v", header.Version))
+	}
+
+	// Edges are kept back until all nodes have been stored
+
+	var edges []data.Edge
+	var nodes int
+
+	for {
+		var entry snapshotEntry
+
+		if err := dec.Decode(&entry); err != nil {
+			return readErr(err)
+		}
+
+		switch entry.Type {
+
+		case snapshotEntryNode:
+			if err := gm.StoreNode(part, data.NewGraphNodeFromMap(entry.Data)); err != nil {
+				return err
+			}
+			nodes++
+
+		case snapshotEntryEdge:
+			edges = append(edges, data.NewGraphEdgeFromNode(data.NewGraphNodeFromMap(entry.Data)))
+
+		case snapshotEntryEnd:
+			if entry.Nodes != nodes || entry.Edges != len(edges) {
+				return readErr(fmt.Errorf("Expected %v nodes and %v edges but found %v nodes and %v edges",
+					entry.Nodes, entry.Edges, nodes, len(edges)))
+			}
+
+			for _, edge := range edges {
+				if err := gm.StoreEdge(part, edge); err != nil {
+					return err
+				}
+			}
+
+			return nil
+
+		default:
+			return readErr(fmt.Errorf("Unknown entry type %v", entry.Type))
+		}
+	}
+}
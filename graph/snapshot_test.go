@@ -0,0 +1,179 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+
+	gs := graphstorage.NewMemoryGraphStorage("test")
+	gm := NewGraphManager(gs)
+
+	for i := 0; i < 10; i++ {
+		node := data.NewGraphNode()
+		node.SetAttr("key", fmt.Sprint(i))
+		node.SetAttr("kind", "mynode")
+		node.SetAttr("name", fmt.Sprint("node", i))
+		node.SetAttr("val", i)
+
+		if err := gm.StoreNode("main", node); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for i := 1; i < 10; i++ {
+		edge := data.NewGraphEdge()
+		edge.SetAttr("key", fmt.Sprint("e", i))
+		edge.SetAttr("kind", "myedge")
+		edge.SetAttr(data.EdgeEnd1Key, "0")
+		edge.SetAttr(data.EdgeEnd1Kind, "mynode")
+		edge.SetAttr(data.EdgeEnd1Role, "src")
+		edge.SetAttr(data.EdgeEnd1Cascading, false)
+		edge.SetAttr(data.EdgeEnd2Key, fmt.Sprint(i))
+		edge.SetAttr(data.EdgeEnd2Kind, "mynode")
+		edge.SetAttr(data.EdgeEnd2Role, "dst")
+		edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+		if err := gm.StoreEdge("main", edge); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	var snapshot bytes.Buffer
+
+	if err := gm.Snapshot("main", &snapshot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	archive := append([]byte{}, snapshot.Bytes()...)
+
+	// Restore the snapshot into another partition
+
+	if err := gm.Restore("restored", bytes.NewBuffer(archive)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var expected, res bytes.Buffer
+
+	ExportPartition(&expected, "main", gm)
+	ExportPartition(&res, "restored", gm)
+
+	if SortDump(expected.String()) != SortDump(res.String()) {
+		t.Error("Unexpected result:", SortDump(res.String()))
+		return
+	}
+
+	if node, err := gm.FetchNode("restored", "5", "mynode"); err != nil || node.Attr("val") != 5 {
+		t.Error("Unexpected result:", node, err)
+		return
+	}
+
+	if nodes, _, err := gm.TraverseMulti("restored", "0", "mynode", ":::", false); err != nil || len(nodes) != 9 {
+		t.Error("Unexpected result:", nodes, err)
+		return
+	}
+
+	// Snapshot of an empty partition
+
+	snapshot.Reset()
+
+	if err := gm.Snapshot("empty", &snapshot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.Restore("empty2", &snapshot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Test error cases
+
+	if err := gm.Restore("main", bytes.NewBuffer(archive)); err == nil ||
+		err.Error() != "GraphError: Invalid data (Partition main is not empty)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.Restore("broken", bytes.NewBuffer(archive[:len(archive)-20])); err == nil ||
+		err.Error() != "GraphError: Invalid data (Could not read snapshot: unexpected EOF)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.Restore("broken2", bytes.NewBufferString("foo")); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.Snapshot("in valid", &snapshot); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestSnapshotNestedAttributes(t *testing.T) {
+
+	gs := graphstorage.NewMemoryGraphStorage("test")
+	gm := NewGraphManager(gs)
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "1")
+	node.SetAttr("kind", "mynode")
+	node.SetAttr("list", []interface{}{"a", 1.5, []interface{}{true}})
+	node.SetAttr("map", map[string]interface{}{
+		"b": []interface{}{"c"},
+		"d": map[string]interface{}{"e": 1.0},
+	})
+	node.SetAttr("strings", []string{"x", "y"})
+	node.SetAttr("ints", []int{1, 2})
+
+	if err := gm.StoreNode("main", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var snapshot bytes.Buffer
+
+	if err := gm.Snapshot("main", &snapshot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.Restore("restored", &snapshot); err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := gm.FetchNode("restored", "1", "mynode")
+	if err != nil || res == nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	for _, attr := range []string{"list", "map", "strings", "ints"} {
+		if fmt.Sprint(res.Attr(attr)) != fmt.Sprint(node.Attr(attr)) {
+			t.Error("Unexpected result:", attr, res.Attr(attr))
+			return
+		}
+	}
+}
@@ -226,12 +226,18 @@ func (gt *baseTrans) Commit() error {
 		return nil
 	}
 
+	// Fail before anything is written if a partition became read-only
+
+	if err := gt.checkWritable(); err != nil {
+		return err
+	}
+
 	doRollback := func(nodePartsAndKinds map[string]string,
 		edgePartsAndKinds map[string]string) {
 
 		// Rollback main database
 
-		gt.gm.gs.RollbackMain()
+		gt.gm.rollbackMain()
 
 		// Rollback node storages
 
@@ -291,7 +297,7 @@ func (gt *baseTrans) Commit() error {
 		}
 	}
 
-	panicIfError(gt.gm.gs.FlushMain())
+	panicIfError(gt.gm.flushMain())
 
 	for kkey := range nodePartsAndKinds {
 
@@ -312,6 +318,35 @@ func (gt *baseTrans) Commit() error {
 	return nil
 }
 
+/*
+checkWritable checks that all partitions which are changed by this transaction
+can be changed.
+*/
+func (gt *baseTrans) checkWritable() error {
+	var keys []string
+
+	for tkey := range gt.storeNodes {
+		keys = append(keys, tkey)
+	}
+	for tkey := range gt.removeNodes {
+		keys = append(keys, tkey)
+	}
+	for tkey := range gt.storeEdges {
+		keys = append(keys, tkey)
+	}
+	for tkey := range gt.removeEdges {
+		keys = append(keys, tkey)
+	}
+
+	for _, tkey := range keys {
+		if err := gt.gm.checkWritable(strings.Split(tkey, "#")[0]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 /*
 commitNodes tries to commit all transaction nodes.
 */
@@ -340,6 +375,24 @@ func (gt *baseTrans) commitNodes(nodePartsAndKinds map[string]string, edgePartsA
 			return err
 		}
 
+		// Check unique constraints - nodes which are removed by this
+		// transaction do not cause a violation
+
+		err = gt.gm.checkUniqueConstraints(part, node, func(key string) bool {
+			_, ok := gt.removeNodes[gt.createKey(part, key, node.Kind())]
+			return ok
+		})
+
+		if err != nil {
+			return err
+		}
+
+		// Check the schema - updates were already merged with the stored node
+
+		if err := gt.gm.checkNodeSchema(part, node, nil); err != nil {
+			return err
+		}
+
 		// Write the node to the datastore
 
 		oldnode, err := gt.gm.writeNode(node, false, attht, valht, nodeAttributeFilter)
@@ -380,6 +433,10 @@ func (gt *baseTrans) commitNodes(nodePartsAndKinds map[string]string, edgePartsA
 			}
 		}
 
+		if err := gt.gm.updateAttrIndexes(part, node.Kind(), node.Key(), node, oldnode, false); err != nil {
+			return err
+		}
+
 		// Execute rules
 
 		var event int
@@ -446,6 +503,10 @@ func (gt *baseTrans) commitNodes(nodePartsAndKinds map[string]string, edgePartsA
 				}
 			}
 
+			if err := gt.gm.updateAttrIndexes(part, node.Kind(), node.Key(), nil, oldnode, false); err != nil {
+				return err
+			}
+
 			// Decrease the node count
 
 			currentCount := gt.gm.NodeCount(node.Kind())
@@ -676,6 +737,8 @@ overwrites any existing node.
 func (gt *baseTrans) StoreNode(part string, node data.Node) error {
 	if err := gt.gm.checkPartitionName(part); err != nil {
 		return err
+	} else if err := gt.gm.checkWritable(part); err != nil {
+		return err
 	} else if err := gt.gm.checkNode(node); err != nil {
 		return err
 	}
@@ -698,6 +761,8 @@ only update the given values of the node.
 func (gt *baseTrans) UpdateNode(part string, node data.Node) error {
 	if err := gt.gm.checkPartitionName(part); err != nil {
 		return err
+	} else if err := gt.gm.checkWritable(part); err != nil {
+		return err
 	} else if err := gt.gm.checkNode(node); err != nil {
 		return err
 	}
@@ -731,6 +796,8 @@ RemoveNode removes a single node from a partition of the graph.
 func (gt *baseTrans) RemoveNode(part string, nkey string, nkind string) error {
 	if err := gt.gm.checkPartitionName(part); err != nil {
 		return err
+	} else if err := gt.gm.checkWritable(part); err != nil {
+		return err
 	}
 
 	key := gt.createKey(part, nkey, nkind)
@@ -755,6 +822,8 @@ overwrites any existing edge.
 func (gt *baseTrans) StoreEdge(part string, edge data.Edge) error {
 	if err := gt.gm.checkPartitionName(part); err != nil {
 		return err
+	} else if err := gt.gm.checkWritable(part); err != nil {
+		return err
 	} else if err := gt.gm.checkEdge(edge); err != nil {
 		return err
 	}
@@ -776,6 +845,8 @@ RemoveEdge removes a single edge from a partition of the graph.
 func (gt *baseTrans) RemoveEdge(part string, ekey string, ekind string) error {
 	if err := gt.gm.checkPartitionName(part); err != nil {
 		return err
+	} else if err := gt.gm.checkWritable(part); err != nil {
+		return err
 	}
 
 	key := gt.createKey(part, ekey, ekind)
@@ -1245,12 +1245,12 @@ func TestTransErrors(t *testing.T) {
 	}
 
 	sm = mgs.StorageManager("main"+deleteEdge.End2Kind()+StorageSuffixNodes, false).(*storage.MemoryStorageManager)
-	sm.AccessMap[5] = storage.AccessCacheAndFetchError
+	sm.AccessMap[6] = storage.AccessCacheAndFetchError
 	if err := trans2.Commit(); !strings.Contains(fmt.Sprint(err), "GraphError: Could not read graph information") {
 		t.Error("Unexpected error return:", err)
 		return
 	}
-	delete(sm.AccessMap, 5)
+	delete(sm.AccessMap, 6)
 
 	resetTransAndStorage()
 
@@ -1294,3 +1294,55 @@ func testTransPanic(t *testing.T) {
 
 	trans.Commit()
 }
+
+func TestTransCommitAfterFailedWrite(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := newGraphManagerNoRules(mgs)
+
+	storeNode := func(key string) error {
+		trans := NewGraphTrans(gm)
+
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "mynode")
+		node.SetAttr("Name", "Node"+key)
+
+		if err := trans.StoreNode("main", node); err != nil {
+			return err
+		}
+
+		return trans.Commit()
+	}
+
+	if err := storeNode("123"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Kill the writes of the next transaction half way through
+
+	sm := mgs.StorageManager("main"+"mynode"+StorageSuffixNodes, false).(*storage.MemoryStorageManager)
+	sm.AccessMap[sm.LocCount] = storage.AccessInsertError
+
+	if err := storeNode("456"); !strings.Contains(fmt.Sprint(err), "GraphError: Could not write graph information") {
+		t.Error("Unexpected error return:", err)
+		return
+	}
+
+	delete(sm.AccessMap, sm.LocCount)
+
+	// Following transactions are written and all committed data can be read
+
+	if err := storeNode("789"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, key := range []string{"123", "789"} {
+		if node, err := gm.FetchNode("main", key, "mynode"); err != nil || node == nil ||
+			node.Attr("Name") != "Node"+key {
+			t.Error("Unexpected result:", node, err)
+			return
+		}
+	}
+}
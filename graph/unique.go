@@ -0,0 +1,230 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/hash"
+)
+
+/*
+CreateUniqueConstraint registers a unique constraint on an attribute of a node
+kind in a partition. No two nodes of the kind may have the same value for the
+attribute - values which are numbers are compared by their numeric value.
+Nodes without the attribute are not restricted. The constraint uses an
+attribute index which is created if it does not exist. Returns an error if
+stored nodes already violate the constraint.
+*/
+func (gm *Manager) CreateUniqueConstraint(part string, kind string, attr string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	if attr == "" || attr == data.NodeKey || attr == data.NodeKind {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Cannot index attribute %#v", attr),
+		}
+	}
+
+	attTree, valTree, err := gm.getNodeStorageHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	constraints := gm.uniqueConstraints(part, kind)
+
+	if _, ok := constraints[attr]; ok {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Unique constraint on %v.%v already exists in partition %v", kind, attr, part),
+		}
+	}
+
+	createdIndex := false
+
+	if _, ok := gm.attrIndexes(part, kind)[attr]; !ok {
+		if err := gm.createAttrIndex(part, kind, attr, attTree, valTree, aiht); err != nil {
+			return err
+		}
+		createdIndex = true
+	}
+
+	// Check that the stored nodes do not violate the constraint
+
+	prefix := attr + "\x00"
+	it := hash.NewHTreeIterator(aiht)
+
+	for it.HasNext() {
+		k, v := it.Next()
+
+		if it.LastError != nil {
+			return &util.GraphError{Type: util.ErrReading, Detail: it.LastError.Error()}
+		}
+
+		if keys, ok := v.(map[string]string); ok && strings.HasPrefix(string(k), prefix) && len(keys) > 1 {
+
+			if createdIndex {
+				gm.dropAttrIndex(part, kind, attr, aiht)
+				gm.flushMain()
+				gm.flushNodeIndex(part, kind)
+			}
+
+			var nodeKeys []string
+			for key := range keys {
+				nodeKeys = append(nodeKeys, key)
+			}
+			sort.Strings(nodeKeys)
+
+			return &util.GraphError{
+				Type: util.ErrUniqueConstraint,
+				Detail: fmt.Sprintf("%v.%v has the same value for nodes %v in partition %v",
+					kind, attr, strings.Join(nodeKeys, ", "), part),
+			}
+		}
+	}
+
+	constraints[attr] = ""
+	gm.storeMainDBMap(MainDBNodeUniqueConstraints+part+"#"+kind, constraints)
+
+	gm.flushMain()
+
+	return gm.flushNodeIndex(part, kind)
+}
+
+/*
+DropUniqueConstraint removes a unique constraint on an attribute of a node kind
+in a partition. The attribute index of the constraint is kept and can be
+removed with DropIndex.
+*/
+func (gm *Manager) DropUniqueConstraint(part string, kind string, attr string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	constraints := gm.uniqueConstraints(part, kind)
+
+	if _, ok := constraints[attr]; !ok {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Unique constraint on %v.%v does not exist in partition %v", kind, attr, part),
+		}
+	}
+
+	delete(constraints, attr)
+	gm.storeMainDBMap(MainDBNodeUniqueConstraints+part+"#"+kind, constraints)
+
+	return gm.flushMain()
+}
+
+/*
+UniqueConstraints returns all attributes of a node kind in a partition which
+have a unique constraint.
+*/
+func (gm *Manager) UniqueConstraints(part string, kind string) []string {
+	var ret []string
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	for attr := range gm.uniqueConstraints(part, kind) {
+		ret = append(ret, attr)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+/*
+checkUniqueConstraints checks that a node which is about to be stored does not
+violate a unique constraint. Attributes which are missing from the node are
+not checked. Nodes for which the removed function returns true are ignored.
+It is assumed that the caller holds the writer lock - the check and the
+following write must happen under the same lock.
+*/
+func (gm *Manager) checkUniqueConstraints(part string, node data.Node,
+	removed func(key string) bool) error {
+
+	constraints := gm.uniqueConstraints(part, node.Kind())
+	if len(constraints) == 0 {
+		return nil
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, node.Kind(), false)
+	if err != nil || aiht == nil {
+		return err
+	}
+
+	for attr := range constraints {
+
+		val := node.Attr(attr)
+
+		if val == nil {
+			continue
+		}
+
+		obj, err := aiht.Get(attrIndexKey(attr, val))
+		if err != nil {
+			return &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+		}
+
+		keys, _ := obj.(map[string]string)
+
+		for key := range keys {
+			if key != node.Key() && (removed == nil || !removed(key)) {
+				return &util.GraphError{
+					Type: util.ErrUniqueConstraint,
+					Detail: fmt.Sprintf("%v.%v value %#v of node %v is already used by node %v in partition %v",
+						node.Kind(), attr, fmt.Sprint(val), node.Key(), key, part),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+uniqueConstraints returns the attributes of a node kind in a partition which
+have a unique constraint.
+*/
+func (gm *Manager) uniqueConstraints(part string, kind string) map[string]string {
+	constraints := gm.getMainDBMap(MainDBNodeUniqueConstraints + part + "#" + kind)
+
+	if constraints == nil {
+		constraints = make(map[string]string)
+	}
+
+	return constraints
+}
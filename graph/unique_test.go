@@ -0,0 +1,231 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+	"devt.de/krotik/eliasdb/graph/util"
+)
+
+func TestUniqueConstraint(t *testing.T) {
+
+	// Use a disk storage so failed transactions can be rolled back
+
+	dgs, err := graphstorage.NewDiskGraphStorage(GraphManagerTestDBDir7, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer dgs.Close()
+
+	gm := NewGraphManager(dgs)
+
+	newAuthor := func(key string, email interface{}) data.Node {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "Author")
+		if email != nil {
+			node.SetAttr("email", email)
+		}
+		return node
+	}
+
+	gm.StoreNode("main", newAuthor("1", "a@example.com"))
+	gm.StoreNode("main", newAuthor("2", "a@example.com"))
+	gm.StoreNode("main", newAuthor("3", nil))
+
+	// Existing nodes must not violate the constraint
+
+	if err := gm.CreateUniqueConstraint("main", "Author", "email"); err == nil || err.Error() !=
+		"GraphError: Unique constraint violation (Author.email has the same value for nodes 1, 2 in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if res := fmt.Sprint(gm.UniqueConstraints("main", "Author"), gm.Indexes("main", "Author")); res != "[] []" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	gm.StoreNode("main", newAuthor("2", "b@example.com"))
+
+	if err := gm.CreateUniqueConstraint("main", "Author", "email"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := fmt.Sprint(gm.UniqueConstraints("main", "Author"), gm.Indexes("main", "Author")); res != "[email] [email]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Stores and updates are checked
+
+	err = gm.StoreNode("main", newAuthor("3", "a@example.com"))
+	if err == nil || err.(*util.GraphError).Type != util.ErrUniqueConstraint || err.Error() !=
+		`GraphError: Unique constraint violation (Author.email value "a@example.com" of node 3 is already used by node 1 in partition main)` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.UpdateNode("main", newAuthor("2", "a@example.com")); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if node, _ := gm.FetchNode("main", "3", "Author"); node.Attr("email") != nil {
+		t.Error("Unexpected result:", node)
+		return
+	}
+
+	// A node can be stored again with its own value and nodes without the
+	// attribute are not restricted
+
+	if err := gm.StoreNode("main", newAuthor("1", "a@example.com")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.StoreNode("main", newAuthor("4", nil)); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Values are free again once a node is removed
+
+	gm.RemoveNode("main", "2", "Author")
+
+	if err := gm.UpdateNode("main", newAuthor("3", "b@example.com")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Transactions are checked and rolled back
+
+	trans := NewGraphTrans(gm)
+	trans.StoreNode("main", newAuthor("5", "c@example.com"))
+	trans.StoreNode("main", newAuthor("6", "c@example.com"))
+
+	if err := trans.Commit(); err == nil || err.(*util.GraphError).Type != util.ErrUniqueConstraint {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if node, _ := gm.FetchNode("main", "5", "Author"); node != nil {
+		t.Error("Unexpected result:", node)
+		return
+	}
+
+	if node, _ := gm.FetchNode("main", "6", "Author"); node != nil {
+		t.Error("Unexpected result:", node)
+		return
+	}
+
+	// A value of a node which is removed in the same transaction can be reused
+
+	trans = NewGraphTrans(gm)
+	trans.RemoveNode("main", "1", "Author")
+	trans.StoreNode("main", newAuthor("5", "a@example.com"))
+
+	if err := trans.Commit(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Test error cases
+
+	if err := gm.DropIndex("main", "Author", "email"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Index on Author.email is used by a unique constraint in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.CreateUniqueConstraint("main", "Author", "email"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Unique constraint on Author.email already exists in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.DropUniqueConstraint("main", "Author", "name"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Unique constraint on Author.name does not exist in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Drop the constraint
+
+	if err := gm.DropUniqueConstraint("main", "Author", "email"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.StoreNode("main", newAuthor("6", "a@example.com")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.DropIndex("main", "Author", "email"); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestUniqueConstraintConcurrentStores(t *testing.T) {
+
+	gs := graphstorage.NewMemoryGraphStorage("test")
+	gm := NewGraphManager(gs)
+
+	if err := gm.CreateUniqueConstraint("main", "Author", "email"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+
+	stored := 0
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			node := data.NewGraphNode()
+			node.SetAttr("key", fmt.Sprint(i))
+			node.SetAttr("kind", "Author")
+			node.SetAttr("email", "same@example.com")
+
+			if err := gm.StoreNode("main", node); err == nil {
+				mutex.Lock()
+				stored++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if stored != 1 {
+		t.Error("Unexpected number of stored nodes:", stored)
+		return
+	}
+
+	if keys, _ := gm.LookupIndex("main", "Author", "email", "same@example.com"); len(keys) != 1 {
+		t.Error("Unexpected result:", keys)
+		return
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+Known casing policies for names
+*/
+const (
+	CasingCamel = "camel" // Names like releaseDate
+	CasingSnake = "snake" // Names like release_date
+)
+
+/*
+CaseConverters maps casing policies to functions which convert a name into
+the casing of the policy.
+*/
+var CaseConverters = map[string]func(string) string{
+	CasingCamel: CamelCase,
+	CasingSnake: SnakeCase,
+}
+
+/*
+CamelCase converts a given name into camel case (e.g. "release_date" or
+"Release Date" become "releaseDate"). Underscores, hyphens and whitespace
+separate words. Names without separators only have their first letter
+converted to lower case.
+*/
+func CamelCase(s string) string {
+	var buf strings.Builder
+
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '_' || r == '-' || unicode.IsSpace(r)
+	})
+
+	for i, word := range words {
+		runes := []rune(word)
+
+		if i == 0 {
+			runes[0] = unicode.ToLower(runes[0])
+		} else {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+
+		buf.WriteString(string(runes))
+	}
+
+	return buf.String()
+}
+
+/*
+SnakeCase converts a given name into snake case (e.g. "releaseDate" or
+"Release Date" become "release_date"). Upper case letters, underscores,
+hyphens and whitespace start a new word.
+*/
+func SnakeCase(s string) string {
+	var buf strings.Builder
+
+	sep := false
+
+	for _, r := range s {
+
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			sep = buf.Len() > 0
+			continue
+		}
+
+		if unicode.IsUpper(r) {
+			sep = sep || buf.Len() > 0
+			r = unicode.ToLower(r)
+		}
+
+		if sep {
+			buf.WriteRune('_')
+			sep = false
+		}
+
+		buf.WriteRune(r)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,56 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package util
+
+import (
+	"testing"
+)
+
+func TestCasing(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"":                 "",
+		"ranking":          "ranking",
+		"release_date":     "releaseDate",
+		"Release Date":     "releaseDate",
+		"song-name_2":      "songName2",
+		"__first__second_": "firstSecond",
+		"releaseDate":      "releaseDate",
+		"end1key":          "end1key",
+	} {
+		if res := CamelCase(name); res != expected {
+			t.Error("Unexpected camel case result for", name, ":", res, "expected:", expected)
+		}
+	}
+
+	for name, expected := range map[string]string{
+		"":             "",
+		"ranking":      "ranking",
+		"releaseDate":  "release_date",
+		"ReleaseDate":  "release_date",
+		"Release Date": "release_date",
+		"song-name2":   "song_name2",
+		"release_date": "release_date",
+		"end1key":      "end1key",
+	} {
+		if res := SnakeCase(name); res != expected {
+			t.Error("Unexpected snake case result for", name, ":", res, "expected:", expected)
+		}
+	}
+
+	// Converting back and forth keeps snake case names
+
+	for _, name := range []string{"ranking", "release_date", "first_second_third"} {
+		if res := CaseConverters[CasingSnake](CaseConverters[CasingCamel](name)); res != name {
+			t.Error("Unexpected result:", res)
+		}
+	}
+}
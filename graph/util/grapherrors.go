@@ -87,4 +87,8 @@ var (
 	ErrReading     = errors.New("Could not read graph information")
 	ErrWriting     = errors.New("Could not write graph information")
 	ErrRule        = errors.New("Graph rule error")
+
+	ErrUniqueConstraint = errors.New("Unique constraint violation")
+	ErrVersionConflict  = errors.New("Version conflict")
+	ErrSchemaViolation  = errors.New("Schema violation")
 )
@@ -10,7 +10,10 @@
 
 package util
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"sync"
+)
 
 /*
 PrefixCode is the prefix for entries storing codes
@@ -42,13 +45,23 @@ NamesManager data structure
 */
 type NamesManager struct {
 	nameDB map[string]string // Database storing names
+	mutex  *sync.RWMutex     // Mutex to protect the names database
 }
 
 /*
 NewNamesManager creates a new names manager instance.
 */
 func NewNamesManager(nameDB map[string]string) *NamesManager {
-	return &NamesManager{nameDB}
+	return NewNamesManagerWithMutex(nameDB, &sync.RWMutex{})
+}
+
+/*
+NewNamesManagerWithMutex creates a new names manager instance which uses a
+given mutex to protect the names database. The mutex must be used by all other
+users of the names database.
+*/
+func NewNamesManagerWithMutex(nameDB map[string]string, mutex *sync.RWMutex) *NamesManager {
+	return &NamesManager{nameDB, mutex}
 }
 
 /*
@@ -87,11 +100,21 @@ encode encodes a name to a code.
 func (gs *NamesManager) encode(prefix string, name string, create bool) string {
 	codekey := string(PrefixCode) + prefix + name
 
+	if !create {
+		gs.mutex.RLock()
+		defer gs.mutex.RUnlock()
+
+		return gs.nameDB[codekey]
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
 	code, ok := gs.nameDB[codekey]
 
 	// If the code doesn't exist yet create it
 
-	if !ok && create {
+	if !ok {
 		if prefix == Prefix16Bit {
 			code = gs.newCode16()
 
@@ -113,6 +136,9 @@ decode decodes a name from a code.
 func (gs *NamesManager) decode(prefix string, code string) string {
 	namekey := string(PrefixName) + prefix + code
 
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
 	return gs.nameDB[namekey]
 }
 
@@ -0,0 +1,99 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+foldTable maps lower case letters with diacritics to their base letters.
+*/
+var foldTable = make(map[rune]string)
+
+func init() {
+	for base, letters := range map[string]string{
+		"a":  "àáâãäåāăą",
+		"c":  "çćĉċč",
+		"d":  "ďđð",
+		"e":  "èéêëēĕėęě",
+		"g":  "ĝğġģ",
+		"h":  "ĥħ",
+		"i":  "ìíîïĩīĭįı",
+		"j":  "ĵ",
+		"k":  "ķ",
+		"l":  "ĺļľŀł",
+		"n":  "ñńņňŉ",
+		"o":  "òóôõöøōŏő",
+		"r":  "ŕŗř",
+		"s":  "śŝşšſ",
+		"t":  "ţťŧ",
+		"u":  "ùúûüũūŭůűų",
+		"w":  "ŵ",
+		"y":  "ýÿŷ",
+		"z":  "źżž",
+		"ae": "æ",
+		"oe": "œ",
+		"ss": "ß",
+		"th": "þ",
+	} {
+		for _, r := range letters {
+			foldTable[r] = base
+		}
+	}
+}
+
+/*
+FoldedWords splits a given string into words and returns the folded form of
+each word in the order of their first occurrence. Words are sequences of
+letters and digits - punctuation, symbols and whitespace separate words while
+apostrophes within a word are removed. Folding converts all letters to lower
+case and removes diacritics from latin letters (e.g. "Café" becomes "cafe").
+*/
+func FoldedWords(s string) []string {
+	var ret []string
+	var word strings.Builder
+
+	seen := make(map[string]bool)
+
+	addWord := func() {
+		if w := word.String(); w != "" && !seen[w] {
+			seen[w] = true
+			ret = append(ret, w)
+		}
+		word.Reset()
+	}
+
+	for _, r := range s {
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			r = unicode.ToLower(r)
+
+			if f, ok := foldTable[r]; ok {
+				word.WriteString(f)
+			} else {
+				word.WriteRune(r)
+			}
+
+		} else if unicode.Is(unicode.Mn, r) || (r == '\'' || r == '’') && word.Len() > 0 {
+
+			// Combining marks and apostrophes do not separate words
+
+		} else {
+			addWord()
+		}
+	}
+
+	addWord()
+
+	return ret
+}
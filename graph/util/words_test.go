@@ -0,0 +1,36 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package util
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFoldedWords(t *testing.T) {
+
+	for text, expected := range map[string]string{
+		"":                                 "[]",
+		"  ...!? ":                         "[]",
+		"Love me, love me!":                "[love me]",
+		"Don't stop (believin')":           "[dont stop believin]",
+		"rock'n'roll - 'live' in 1969":     "[rocknroll live in 1969]",
+		"Café Crème, ÉCOLE; naïve Straße":  "[cafe creme ecole naive strasse]",
+		"Ærø Œuvre Łódź":                   "[aero oeuvre lodz]",
+		"Cafe\u0301 cafe":                  "[cafe]",
+		"Привет, МИР мир":                  "[привет мир]",
+		"line1\nline2\ttab_separated/path": "[line1 line2 tab separated path]",
+	} {
+		if res := fmt.Sprint(FoldedWords(text)); res != expected {
+			t.Error("Unexpected result for", text, ":", res, "expected:", expected)
+		}
+	}
+}
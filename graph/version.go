@@ -0,0 +1,138 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/hash"
+)
+
+/*
+NodeVersion returns the version of a node. The version of a node is increased
+every time the node is written. Returns 0 if the node does not exist (or was
+stored before versions were introduced).
+*/
+func (gm *Manager) NodeVersion(part string, key string, kind string) (uint64, error) {
+
+	_, valTree, err := gm.getNodeStorageHTree(part, kind, false)
+	if err != nil || valTree == nil {
+		return 0, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	return gm.readVersion(valTree, key)
+}
+
+/*
+EdgeVersion returns the version of an edge. The version of an edge is increased
+every time the edge is written. Returns 0 if the edge does not exist (or was
+stored before versions were introduced).
+*/
+func (gm *Manager) EdgeVersion(part string, key string, kind string) (uint64, error) {
+
+	edgeTree, err := gm.getEdgeStorageHTree(part, kind, false)
+	if err != nil || edgeTree == nil {
+		return 0, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	return gm.readVersion(edgeTree, key)
+}
+
+/*
+UpdateNodeIfVersion updates a single node in a partition of the graph if the
+stored node has a given version. Returns an ErrVersionConflict error if the
+version does not match. The version is checked and increased while the writer
+lock is held so concurrent writers cannot overwrite each other's changes.
+*/
+func (gm *Manager) UpdateNodeIfVersion(part string, node data.Node, version uint64) error {
+	return gm.storeOrUpdateNode(part, node, true, &version)
+}
+
+/*
+StoreEdgeIfVersion stores a single edge in a partition of the graph if the
+stored edge has a given version. Returns an ErrVersionConflict error if the
+version does not match. The version is checked and increased while the writer
+lock is held so concurrent writers cannot overwrite each other's changes.
+*/
+func (gm *Manager) StoreEdgeIfVersion(part string, edge data.Edge, version uint64) error {
+	return gm.storeEdge(part, edge, &version)
+}
+
+/*
+readVersion reads the version of a node or edge from a given tree. It is
+assumed that the caller holds a lock.
+*/
+func (gm *Manager) readVersion(tree *hash.HTree, key string) (uint64, error) {
+
+	obj, err := tree.Get([]byte(PrefixNSVersion + key))
+	if err != nil {
+		return 0, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+	} else if obj == nil {
+		return 0, nil
+	}
+
+	return obj.(uint64), nil
+}
+
+/*
+checkVersion checks that a node or edge in a given tree has an expected version.
+No check is done if no version is given. It is assumed that the caller holds
+the writer lock.
+*/
+func (gm *Manager) checkVersion(tree *hash.HTree, key string, kind string, version *uint64) error {
+
+	if version == nil {
+		return nil
+	}
+
+	current, err := gm.readVersion(tree, key)
+	if err != nil {
+		return err
+	} else if current != *version {
+		return &util.GraphError{
+			Type: util.ErrVersionConflict,
+			Detail: fmt.Sprintf("Expected version %v of %v (%v) but found version %v",
+				*version, key, kind, current),
+		}
+	}
+
+	return nil
+}
+
+/*
+increaseVersion increases the version of a node or edge in a given tree. It is
+assumed that the caller holds the writer lock.
+*/
+func (gm *Manager) increaseVersion(tree *hash.HTree, key string) error {
+
+	current, err := gm.readVersion(tree, key)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tree.Put([]byte(PrefixNSVersion+key), current+1); err != nil {
+		return &util.GraphError{Type: util.ErrWriting, Detail: err.Error()}
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+	"devt.de/krotik/eliasdb/graph/util"
+)
+
+func TestNodeVersion(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "123")
+	node.SetAttr("kind", "mykind")
+	node.SetAttr("name", "foo")
+
+	if v, err := gm.NodeVersion("main", "123", "mykind"); v != 0 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	// Every write increases the version
+
+	for i := 1; i <= 3; i++ {
+		if err := gm.StoreNode("main", node); err != nil {
+			t.Error(err)
+			return
+		}
+
+		if v, err := gm.NodeVersion("main", "123", "mykind"); v != uint64(i) || err != nil {
+			t.Error("Unexpected result:", v, err)
+			return
+		}
+	}
+
+	trans := NewGraphTrans(gm)
+	trans.UpdateNode("main", node)
+
+	if err := trans.Commit(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, err := gm.NodeVersion("main", "123", "mykind"); v != 4 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	// Conditional updates
+
+	node.SetAttr("name", "bar")
+
+	err := gm.UpdateNodeIfVersion("main", node, 3)
+
+	if gerr, ok := err.(*util.GraphError); !ok || gerr.Type != util.ErrVersionConflict ||
+		err.Error() != "GraphError: Version conflict (Expected version 3 of 123 (mykind) but found version 4)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if n, _ := gm.FetchNode("main", "123", "mykind"); n.Attr("name") != "foo" {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	if err := gm.UpdateNodeIfVersion("main", node, 4); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if n, _ := gm.FetchNode("main", "123", "mykind"); n.Attr("name") != "bar" {
+		t.Error("Unexpected node:", n)
+		return
+	}
+
+	if v, err := gm.NodeVersion("main", "123", "mykind"); v != 5 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	// Version 0 means the node must not exist
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "456")
+	node2.SetAttr("kind", "mykind")
+
+	if err := gm.UpdateNodeIfVersion("main", node2, 0); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.UpdateNodeIfVersion("main", node2, 0); err == nil {
+		t.Error("Unexpected result")
+		return
+	}
+
+	// Removing a node removes its version
+
+	if _, err := gm.RemoveNode("main", "123", "mykind"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, err := gm.NodeVersion("main", "123", "mykind"); v != 0 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	if v, err := gm.NodeVersion("main", "123", "unknownkind"); v != 0 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+}
+
+func TestNodeVersionConcurrentWriters(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "123")
+	node.SetAttr("kind", "mykind")
+
+	if err := gm.StoreNode("main", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// All writers try to update the same version - only one can win
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+
+	success := 0
+	conflicts := 0
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			n := data.NewGraphNode()
+			n.SetAttr("key", "123")
+			n.SetAttr("kind", "mykind")
+			n.SetAttr("writer", fmt.Sprint(i))
+
+			err := gm.UpdateNodeIfVersion("main", n, 1)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err == nil {
+				success++
+			} else if gerr, ok := err.(*util.GraphError); ok && gerr.Type == util.ErrVersionConflict {
+				conflicts++
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if success != 1 || conflicts != 9 {
+		t.Error("Unexpected result:", success, conflicts)
+		return
+	}
+
+	if v, err := gm.NodeVersion("main", "123", "mykind"); v != 2 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+}
+
+func TestEdgeVersion(t *testing.T) {
+	mgs := graphstorage.NewMemoryGraphStorage("mystorage")
+	gm := NewGraphManager(mgs)
+
+	node1 := data.NewGraphNode()
+	node1.SetAttr("key", "123")
+	node1.SetAttr("kind", "mykind")
+	gm.StoreNode("main", node1)
+
+	node2 := data.NewGraphNode()
+	node2.SetAttr("key", "456")
+	node2.SetAttr("kind", "mykind")
+	gm.StoreNode("main", node2)
+
+	edge := data.NewGraphEdge()
+	edge.SetAttr("key", "abc")
+	edge.SetAttr("kind", "myedge")
+	edge.SetAttr(data.EdgeEnd1Key, node1.Key())
+	edge.SetAttr(data.EdgeEnd1Kind, node1.Kind())
+	edge.SetAttr(data.EdgeEnd1Role, "node1")
+	edge.SetAttr(data.EdgeEnd1Cascading, false)
+	edge.SetAttr(data.EdgeEnd2Key, node2.Key())
+	edge.SetAttr(data.EdgeEnd2Kind, node2.Kind())
+	edge.SetAttr(data.EdgeEnd2Role, "node2")
+	edge.SetAttr(data.EdgeEnd2Cascading, false)
+
+	if err := gm.StoreEdge("main", edge); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, err := gm.EdgeVersion("main", "abc", "myedge"); v != 1 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	edge.SetAttr("name", "foo")
+
+	if err := gm.StoreEdgeIfVersion("main", edge, 2); err == nil ||
+		err.Error() != "GraphError: Version conflict (Expected version 2 of abc (myedge) but found version 1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.StoreEdgeIfVersion("main", edge, 1); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, err := gm.EdgeVersion("main", "abc", "myedge"); v != 2 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+
+	// Edge key iteration is not affected by the stored versions
+
+	it, _ := gm.EdgeKeyIterator("main", "myedge")
+
+	var keys []string
+	for it.HasNext() {
+		keys = append(keys, it.Next())
+	}
+
+	if fmt.Sprint(keys) != "[abc]" {
+		t.Error("Unexpected result:", keys)
+		return
+	}
+
+	if _, err := gm.RemoveEdge("main", "abc", "myedge"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, err := gm.EdgeVersion("main", "abc", "myedge"); v != 0 || err != nil {
+		t.Error("Unexpected result:", v, err)
+		return
+	}
+}
@@ -0,0 +1,345 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/util"
+	"devt.de/krotik/eliasdb/hash"
+)
+
+/*
+PrefixWordIndex is the prefix for word index entries in the attribute index
+*/
+const PrefixWordIndex = "\x01"
+
+/*
+CreateWordIndex creates a word index on an attribute of a node kind in a
+partition. The index maps the folded words of attribute values (see
+util.FoldedWords) to node keys and is updated when nodes are stored or
+removed. All existing nodes are added to the new index.
+*/
+func (gm *Manager) CreateWordIndex(part string, kind string, attr string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	if attr == "" || attr == data.NodeKey || attr == data.NodeKind {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Cannot index attribute %#v", attr),
+		}
+	}
+
+	attTree, valTree, err := gm.getNodeStorageHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, true)
+	if err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	indexes := gm.wordIndexes(part, kind)
+
+	if _, ok := indexes[attr]; ok {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Word index on %v.%v already exists in partition %v", kind, attr, part),
+		}
+	}
+
+	// Add all existing nodes to the index
+
+	it := hash.NewHTreeIterator(attTree)
+
+	for it.HasNext() {
+		k, _ := it.Next()
+
+		if it.LastError != nil {
+			return &util.GraphError{Type: util.ErrReading, Detail: it.LastError.Error()}
+		}
+
+		if !strings.HasPrefix(string(k), PrefixNSAttrs) {
+			continue
+		}
+
+		key := string(k[len(PrefixNSAttrs):])
+
+		node, err := gm.readNode(key, kind, []string{attr}, attTree, valTree)
+		if err != nil {
+			return err
+		}
+
+		if node != nil {
+			for _, word := range attrWords(node, attr) {
+				if err := gm.addAttrIndexEntry(aiht, wordIndexKey(attr, word), key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	indexes[attr] = ""
+	gm.storeMainDBMap(MainDBNodeWordIndexes+part+"#"+kind, indexes)
+
+	gm.flushMain()
+
+	return gm.flushNodeIndex(part, kind)
+}
+
+/*
+DropWordIndex removes a word index on an attribute of a node kind in a partition.
+*/
+func (gm *Manager) DropWordIndex(part string, kind string, attr string) error {
+
+	if err := gm.checkWritable(part); err != nil {
+		return err
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, false)
+	if err != nil {
+		return err
+	}
+
+	// Take writer lock
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	indexes := gm.wordIndexes(part, kind)
+
+	if _, ok := indexes[attr]; !ok || aiht == nil {
+		return &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Word index on %v.%v does not exist in partition %v", kind, attr, part),
+		}
+	}
+
+	// Collect and remove all index entries of the attribute
+
+	var entries [][]byte
+
+	prefix := string(wordIndexKey(attr, ""))
+	it := hash.NewHTreeIterator(aiht)
+
+	for it.HasNext() {
+		k, _ := it.Next()
+
+		if it.LastError != nil {
+			return &util.GraphError{Type: util.ErrReading, Detail: it.LastError.Error()}
+		}
+
+		if strings.HasPrefix(string(k), prefix) {
+			entries = append(entries, k)
+		}
+	}
+
+	for _, k := range entries {
+		if _, err := aiht.Remove(k); err != nil {
+			return &util.GraphError{Type: util.ErrWriting, Detail: err.Error()}
+		}
+	}
+
+	delete(indexes, attr)
+	gm.storeMainDBMap(MainDBNodeWordIndexes+part+"#"+kind, indexes)
+
+	gm.flushMain()
+
+	return gm.flushNodeIndex(part, kind)
+}
+
+/*
+WordIndexes returns all word indexed attributes of a node kind in a partition.
+*/
+func (gm *Manager) WordIndexes(part string, kind string) []string {
+	var ret []string
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	for attr := range gm.wordIndexes(part, kind) {
+		ret = append(ret, attr)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+/*
+HasWordIndex checks if an attribute of a node kind in a partition has a word index.
+*/
+func (gm *Manager) HasWordIndex(part string, kind string, attr string) bool {
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	_, ok := gm.wordIndexes(part, kind)[attr]
+
+	return ok
+}
+
+/*
+LookupWordIndex returns the keys of all nodes of a kind in a partition where a
+word indexed attribute contains all words of a given text. Words are compared
+in their folded form.
+*/
+func (gm *Manager) LookupWordIndex(part string, kind string, attr string, text string) ([]string, error) {
+
+	if !gm.HasWordIndex(part, kind, attr) {
+		return nil, &util.GraphError{
+			Type:   util.ErrInvalidData,
+			Detail: fmt.Sprintf("Word index on %v.%v does not exist in partition %v", kind, attr, part),
+		}
+	}
+
+	aiht, err := gm.getNodeAttrIndexHTree(part, kind, false)
+	if err != nil || aiht == nil {
+		return nil, err
+	}
+
+	// Take reader lock
+
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	var keys map[string]string
+
+	for _, word := range util.FoldedWords(text) {
+
+		obj, err := aiht.Get(wordIndexKey(attr, word))
+		if err != nil {
+			return nil, &util.GraphError{Type: util.ErrReading, Detail: err.Error()}
+		}
+
+		wordKeys, _ := obj.(map[string]string)
+
+		if keys == nil {
+
+			// Copy the first set of keys - stored objects must not be modified
+
+			keys = make(map[string]string, len(wordKeys))
+			for key := range wordKeys {
+				keys[key] = ""
+			}
+
+		} else {
+			for key := range keys {
+				if _, ok := wordKeys[key]; !ok {
+					delete(keys, key)
+				}
+			}
+		}
+
+		if len(keys) == 0 {
+			break
+		}
+	}
+
+	var ret []string
+
+	for key := range keys {
+		ret = append(ret, key)
+	}
+
+	sort.Strings(ret)
+
+	return ret, nil
+}
+
+/*
+updateWordIndexes updates the given word indexes of a node. Words which are
+no longer contained in an attribute are removed from the index. It is
+assumed that the caller holds the writer lock.
+*/
+func (gm *Manager) updateWordIndexes(aiht *hash.HTree, indexes map[string]string, key string,
+	node data.Node, oldnode data.Node, onlyUpdate bool) error {
+
+	for attr := range indexes {
+		var words, oldwords []string
+
+		if node != nil {
+			if _, ok := node.Data()[attr]; !ok && onlyUpdate {
+				continue
+			}
+			words = attrWords(node, attr)
+		}
+
+		if oldnode != nil {
+			oldwords = attrWords(oldnode, attr)
+		}
+
+		wordSet := make(map[string]bool)
+		for _, word := range words {
+			wordSet[word] = true
+		}
+
+		for _, word := range oldwords {
+			if wordSet[word] {
+				delete(wordSet, word)
+			} else if err := gm.removeAttrIndexEntry(aiht, wordIndexKey(attr, word), key); err != nil {
+				return err
+			}
+		}
+
+		for _, word := range words {
+			if wordSet[word] {
+				if err := gm.addAttrIndexEntry(aiht, wordIndexKey(attr, word), key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+wordIndexes returns the word indexed attributes of a node kind in a partition.
+*/
+func (gm *Manager) wordIndexes(part string, kind string) map[string]string {
+	indexes := gm.getMainDBMap(MainDBNodeWordIndexes + part + "#" + kind)
+
+	if indexes == nil {
+		indexes = make(map[string]string)
+	}
+
+	return indexes
+}
+
+/*
+attrWords returns the folded words of an attribute value of a node.
+*/
+func attrWords(node data.Node, attr string) []string {
+	if val := node.Attr(attr); val != nil {
+		return util.FoldedWords(fmt.Sprint(val))
+	}
+	return nil
+}
+
+/*
+wordIndexKey returns the index key for a folded word of an attribute.
+*/
+func wordIndexKey(attr string, word string) []byte {
+	return []byte(PrefixWordIndex + attr + "\x00" + word)
+}
@@ -0,0 +1,181 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"devt.de/krotik/eliasdb/graph/data"
+	"devt.de/krotik/eliasdb/graph/graphstorage"
+)
+
+func TestWordIndex(t *testing.T) {
+
+	gs := graphstorage.NewMemoryGraphStorage("test")
+	gm := NewGraphManager(gs)
+
+	storeNode := func(key string, lyrics string) {
+		node := data.NewGraphNode()
+		node.SetAttr("key", key)
+		node.SetAttr("kind", "song")
+		node.SetAttr("lyrics", lyrics)
+
+		if err := gm.StoreNode("main", node); err != nil {
+			t.Error(err)
+		}
+	}
+
+	lookup := func(text string) string {
+		res, err := gm.LookupWordIndex("main", "song", "lyrics", text)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprint(res)
+	}
+
+	storeNode("1", "All you need is love")
+	storeNode("2", "Love, love me do!")
+	storeNode("3", "Café au lait")
+
+	// Existing nodes are added to a new index
+
+	if err := gm.CreateWordIndex("main", "song", "lyrics"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("LOVE"); res != "[1 2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lookup("love me"); res != "[2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lookup("cafe"); res != "[3]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lookup("..."); res != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(gm.WordIndexes("main", "song")); res != "[lyrics]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if !gm.HasWordIndex("main", "song", "lyrics") || gm.HasWordIndex("main", "song", "name") ||
+		gm.HasIndex("main", "song", "lyrics") {
+		t.Error("Unexpected index state")
+		return
+	}
+
+	// Removed words are removed from the index
+
+	storeNode("2", "Love is all")
+
+	if res := lookup("me"); res != "[]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lookup("all love"); res != "[1 2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	node := data.NewGraphNode()
+	node.SetAttr("key", "1")
+	node.SetAttr("kind", "song")
+	node.SetAttr("name", "foo")
+
+	if err := gm.UpdateNode("main", node); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("need"); res != "[1]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if _, err := gm.RemoveNode("main", "1", "song"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("love"); res != "[2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Word and value indexes can exist on the same attribute
+
+	if err := gm.CreateIndex("main", "song", "lyrics"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := gm.DropIndex("main", "song", "lyrics"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("love"); res != "[2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Test error cases
+
+	if err := gm.CreateWordIndex("main", "song", "lyrics"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Word index on song.lyrics already exists in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.CreateWordIndex("main", "song", "kind"); err == nil || err.Error() !=
+		`GraphError: Invalid data (Cannot index attribute "kind")` {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := gm.DropWordIndex("main", "song", "name"); err == nil || err.Error() !=
+		"GraphError: Invalid data (Word index on song.name does not exist in partition main)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Drop the index
+
+	if err := gm.DropWordIndex("main", "song", "lyrics"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := lookup("love"); res !=
+		"GraphError: Invalid data (Word index on song.lyrics does not exist in partition main)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	aiht, _ := gm.getNodeAttrIndexHTree("main", "song", false)
+	if obj, _ := aiht.Get(wordIndexKey("lyrics", "love")); obj != nil {
+		t.Error("Unexpected index entry:", obj)
+		return
+	}
+}
@@ -58,6 +58,12 @@ func (rt *selectionSetRuntime) Eval() (map[string]interface{}, error) {
 		if c.Name == parser.NodeField {
 			field := c.Runtime.(*fieldRuntime)
 
+			// Check for skip and include directive
+
+			if rt.skipField([]string{field.Alias()}, c) {
+				continue
+			}
+
 			if field.Name() == "__schema" {
 
 				// We have an introspection query - handle this one in a special way
@@ -70,6 +76,14 @@ func (rt *selectionSetRuntime) Eval() (map[string]interface{}, error) {
 					field.Name(), field.Arguments(), nil)
 
 				res[field.Alias()] = nodes
+
+			} else {
+
+				// Node kinds must always have a selection set
+
+				rt.rtp.handleRuntimeError(fmt.Errorf(
+					"Selection set is missing for node kind %s", field.Name()),
+					[]string{field.Alias()}, c)
 			}
 		}
 	}
@@ -579,11 +593,26 @@ func (rt *selectionSetRuntime) GetPlainFieldsAndAliases(path []string, kind stri
 						"Traversal argument is missing"), path, c)
 				}
 
-			} else if stringutil.IndexOf(field.Name(), resList) == -1 {
+			} else {
+				args := field.Arguments()
+
+				// Attribute lookups cannot have arguments
+
+				if _, ok := args["traverse"]; ok {
+					rt.rtp.handleRuntimeError(fmt.Errorf(
+						"Traversal %s requires a selection set", field.Alias()), path, c)
+
+				} else if len(args) > 0 {
+					rt.rtp.handleRuntimeError(fmt.Errorf(
+						"Arguments are not supported on attribute %s", field.Alias()), path, c)
+				}
+
+				if stringutil.IndexOf(field.Name(), resList) == -1 {
 
-				// Handle normal attribute lookup
+					// Handle normal attribute lookup
 
-				resList = append(resList, field.Name())
+					resList = append(resList, field.Name())
+				}
 			}
 
 		} else if c.Name == parser.NodeFragmentSpread || c.Name == parser.NodeInlineFragment {
@@ -653,6 +682,11 @@ func (rt *selectionSetRuntime) skipField(path []string, node *parser.ASTNode) bo
 
 					rt.rtp.handleRuntimeError(fmt.Errorf(
 						"Directive %s is missing the 'if' argument", name), path, c)
+
+				} else {
+
+					rt.rtp.handleRuntimeError(fmt.Errorf(
+						"Unknown directive: %s", name), path, c)
 				}
 			}
 		}
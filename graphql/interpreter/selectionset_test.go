@@ -903,6 +903,94 @@ func TestTraversals(t *testing.T) {
 	}
 }
 
+func TestUnsupportedFeatures(t *testing.T) {
+	gm, _ := songGraphGroups()
+
+	query := map[string]interface{}{
+		"operationName": nil,
+		"query": `
+{
+  Song(key : "StrangeSong1") {
+    key @foo(if : true)
+    name(foo : 1)
+    ranking(traverse : ":::")
+  }
+  Author
+  Writer : Song @skip(if : true) {
+    key
+  }
+}
+`,
+		"variables": nil,
+	}
+
+	if rerr := checkResult(`
+{
+  "data": {
+    "Song": [
+      {
+        "key": "StrangeSong1",
+        "name": "StrangeSong1",
+        "ranking": 5
+      }
+    ]
+  },
+  "errors": [
+    {
+      "locations": [
+        {
+          "column": 10,
+          "line": 4
+        }
+      ],
+      "message": "Unknown directive: foo",
+      "path": [
+        "Song"
+      ]
+    },
+    {
+      "locations": [
+        {
+          "column": 6,
+          "line": 5
+        }
+      ],
+      "message": "Arguments are not supported on attribute name",
+      "path": [
+        "Song"
+      ]
+    },
+    {
+      "locations": [
+        {
+          "column": 6,
+          "line": 6
+        }
+      ],
+      "message": "Traversal ranking requires a selection set",
+      "path": [
+        "Song"
+      ]
+    },
+    {
+      "locations": [
+        {
+          "column": 4,
+          "line": 8
+        }
+      ],
+      "message": "Selection set is missing for node kind Author",
+      "path": [
+        "Author"
+      ]
+    }
+  ]
+}`[1:], query, gm); rerr != nil {
+		t.Error(rerr)
+		return
+	}
+}
+
 func TestListQueries(t *testing.T) {
 	gm, _ := songGraphGroups()
 
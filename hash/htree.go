@@ -23,12 +23,17 @@ The last link is always to a bucket. The default tree has 4 levels each with
 Hash buckets are on the lowest level of the tree and contain actual keys and
 values. The object stores multiple keys and values if there are hash collisions.
 In a sparsely populated tree buckets can also be found on the upper levels.
+The number of elements a bucket can hold before it is converted into a page
+can be chosen when the tree is created (see NewHTreeWithBucketSize). Larger
+buckets mean fewer page splits for write-heavy workloads at the cost of longer
+bucket scans. The bucket size is stored with the tree.
 
 Iterator
 
 Entries in the HTree can be iterated by using an HTreeIterator. The HTree may
 change behind the iterator's back. The iterator will try to cope with best
-effort and only report an error as a last resort.
+effort and only report an error as a last resort. An iterator which can be used
+while other goroutines access the tree can be obtained with HTree.Iterator().
 
 Hash function
 
@@ -40,7 +45,10 @@ Reference implementation: http://code.google.com/p/smhasher/wiki/MurmurHash3
 package hash
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"devt.de/krotik/eliasdb/storage"
@@ -68,6 +76,11 @@ is converted into a page except leaf buckets which grow indefinitely
 */
 const MaxBucketElements = 8
 
+/*
+ErrInvalidBucketSize is returned if a tree should be created with an invalid bucket size
+*/
+var ErrInvalidBucketSize = errors.New("Invalid bucket size")
+
 /*
 HTree data structure
 */
@@ -90,6 +103,18 @@ type htreeNode struct {
 	Keys       [][]byte      // Stored keys (only used for buckets)
 	Values     []interface{} // Stored values (only used for buckets)
 	BucketSize byte          // Bucket size (only used for buckets)
+	MaxBucket  byte          // Maximum number of elements in non-leaf buckets (0 for trees without this setting)
+}
+
+/*
+maxBucketSize returns the maximum number of elements a non-leaf bucket can
+contain. Nodes which were stored without this setting use the default.
+*/
+func (n *htreeNode) maxBucketSize() byte {
+	if n.MaxBucket == 0 {
+		return MaxBucketElements
+	}
+	return n.MaxBucket
 }
 
 /*
@@ -105,16 +130,38 @@ func (n *htreeNode) fetchNode(loc uint64) (*htreeNode, error) {
 		}
 		node = &res
 	} else {
-		node = obj.(*htreeNode)
+		node = obj.(*htreeNode).copy()
 	}
 
 	return node, nil
 }
 
+/*
+copy returns a shallow copy of a HTree node. Nodes from the cache of a storage
+manager are shared by all readers - the location and storage manager fields
+must only be set on a copy. Changed nodes are put back with an update.
+*/
+func (n *htreeNode) copy() *htreeNode {
+	c := *n
+	return &c
+}
+
 /*
 NewHTree creates a new HTree.
 */
 func NewHTree(sm storage.Manager) (*HTree, error) {
+	return NewHTreeWithBucketSize(sm, MaxBucketElements)
+}
+
+/*
+NewHTreeWithBucketSize creates a new HTree whose non-leaf buckets hold up to
+bucketSize elements before they are converted into pages.
+*/
+func NewHTreeWithBucketSize(sm storage.Manager, bucketSize int) (*HTree, error) {
+	if bucketSize < 1 || bucketSize > 255 {
+		return nil, ErrInvalidBucketSize
+	}
+
 	tree := &HTree{}
 
 	// Protect tree creation
@@ -123,7 +170,7 @@ func NewHTree(sm storage.Manager) (*HTree, error) {
 	cm.Lock()
 	defer cm.Unlock()
 
-	tree.Root = newHTreePage(tree, 0)
+	tree.Root = newHTreePage(tree, 0, byte(bucketSize))
 
 	loc, err := sm.Insert(tree.Root.htreeNode)
 	if err != nil {
@@ -157,7 +204,7 @@ func LoadHTree(sm storage.Manager, loc uint64) (*HTree, error) {
 		}
 		tree = &HTree{&htreePage{&res}, nil}
 	} else {
-		tree = &HTree{&htreePage{obj.(*htreeNode)}, nil}
+		tree = &HTree{&htreePage{obj.(*htreeNode).copy()}, nil}
 	}
 
 	tree.Root.loc = loc
@@ -175,6 +222,13 @@ func (t *HTree) Location() uint64 {
 	return t.Root.loc
 }
 
+/*
+BucketSize returns the maximum number of elements in non-leaf buckets of this tree.
+*/
+func (t *HTree) BucketSize() int {
+	return int(t.Root.maxBucketSize())
+}
+
 /*
 Get gets a value for a given key.
 */
@@ -203,6 +257,131 @@ func (t *HTree) GetValueAndLocation(key []byte) (interface{}, uint64, error) {
 	return res, 0, err
 }
 
+/*
+GetValuesAndLocations returns the values and storage locations for a list of
+keys. Keys which share a path in the tree are looked up together. The results
+are in the order of the given keys. The value of a missing key is nil and its
+location is 0.
+*/
+func (t *HTree) GetValuesAndLocations(keys [][]byte) ([]interface{}, []uint64, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	vals := make([]interface{}, len(keys))
+	locs := make([]uint64, len(keys))
+	hashes := make([]uint32, len(keys))
+	idx := make([]int, len(keys))
+
+	// Calculate the hash codes once and order the keys by them
+
+	for i, key := range keys {
+		hashes[i], _ = MurMurHashData(key, 0, len(key)-1, 42)
+		idx[i] = i
+	}
+
+	sort.Slice(idx, func(i, j int) bool {
+		return hashes[idx[i]] < hashes[idx[j]]
+	})
+
+	err := t.Root.GetMulti(keys, hashes, idx, vals, locs)
+
+	return vals, locs, err
+}
+
+/*
+ScanPrefix returns all key / value pairs whose keys start with a given prefix.
+The results are ordered by key. Since the hash codes of keys do not preserve
+their order this does a full scan of the tree - the cost depends on the size
+of the tree and not on the number of results.
+*/
+func (t *HTree) ScanPrefix(prefix []byte) ([][]byte, []interface{}, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var keys [][]byte
+	var vals []interface{}
+
+	err := t.Root.Scan(func(key []byte, value interface{}) {
+		if bytes.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			vals = append(vals, value)
+		}
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Sort(&keyValueList{keys, vals})
+
+	return keys, vals, nil
+}
+
+/*
+ScanAfter returns up to limit key / value pairs which follow a given key. The
+pairs are ordered by the hash codes of their keys and then by key. This order
+does not depend on the layout of the tree and the given key does not need to
+be stored in the tree. A nil key returns the first pairs of the tree and a
+limit smaller than 1 returns all following pairs. Successive calls which pass
+the last returned key can be used to iterate over the tree in chunks.
+*/
+func (t *HTree) ScanAfter(key []byte, limit int) ([][]byte, []interface{}, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	res := &hashKeyValueList{}
+
+	var hash uint32
+
+	if key != nil {
+		hash, _ = MurMurHashData(key, 0, len(key)-1, 42)
+	}
+
+	if err := t.Root.ScanAfter(hash, key, key != nil, limit, res); err != nil {
+		return nil, nil, err
+	}
+
+	return res.keys, res.vals, nil
+}
+
+/*
+keyValueList is a list of key / value pairs which can be sorted by key.
+*/
+type keyValueList struct {
+	keys [][]byte
+	vals []interface{}
+}
+
+func (l *keyValueList) Len() int           { return len(l.keys) }
+func (l *keyValueList) Less(i, j int) bool { return bytes.Compare(l.keys[i], l.keys[j]) < 0 }
+func (l *keyValueList) Swap(i, j int) {
+	l.keys[i], l.keys[j] = l.keys[j], l.keys[i]
+	l.vals[i], l.vals[j] = l.vals[j], l.vals[i]
+}
+
+/*
+hashKeyValueList is a list of key / value pairs which can be sorted by the
+hash codes of the keys and then by key.
+*/
+type hashKeyValueList struct {
+	hashes []uint32
+	keys   [][]byte
+	vals   []interface{}
+}
+
+func (l *hashKeyValueList) Len() int { return len(l.keys) }
+func (l *hashKeyValueList) Less(i, j int) bool {
+	if l.hashes[i] != l.hashes[j] {
+		return l.hashes[i] < l.hashes[j]
+	}
+	return bytes.Compare(l.keys[i], l.keys[j]) < 0
+}
+func (l *hashKeyValueList) Swap(i, j int) {
+	l.hashes[i], l.hashes[j] = l.hashes[j], l.hashes[i]
+	l.keys[i], l.keys[j] = l.keys[j], l.keys[i]
+	l.vals[i], l.vals[j] = l.vals[j], l.vals[i]
+}
+
 /*
 Exists checks if an element exists.
 */
@@ -179,3 +179,348 @@ func TestHTree(t *testing.T) {
 		return
 	}
 }
+
+func TestHTreeBucketSize(t *testing.T) {
+
+	for _, size := range []int{0, 256} {
+		if _, err := NewHTreeWithBucketSize(storage.NewMemoryStorageManager("testsm"), size); err != ErrInvalidBucketSize {
+			t.Error("Unexpected result:", err)
+			return
+		}
+	}
+
+	// Small buckets are converted into pages earlier
+
+	smSmall := storage.NewMemoryStorageManager("testsm")
+	smLarge := storage.NewMemoryStorageManager("testsm")
+
+	htreeSmall, _ := NewHTreeWithBucketSize(smSmall, 1)
+	htreeLarge, _ := NewHTreeWithBucketSize(smLarge, 32)
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprint("testkey", i))
+		htreeSmall.Put(key, i)
+		htreeLarge.Put(key, i)
+	}
+
+	_, pagesSmall, _ := countNodes(smSmall)
+	_, pagesLarge, _ := countNodes(smLarge)
+
+	if pagesSmall <= pagesLarge {
+		t.Error("Unexpected page counts:", pagesSmall, pagesLarge)
+		return
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprint("testkey", i))
+		if res, err := htreeSmall.Get(key); res != i || err != nil {
+			t.Error("Unexpected result:", res, err)
+			return
+		}
+		if res, err := htreeLarge.Get(key); res != i || err != nil {
+			t.Error("Unexpected result:", res, err)
+			return
+		}
+	}
+
+	// The bucket size is stored with the tree
+
+	sm := storage.NewDiskStorageManager(DBDIR+"/test2", false, false, false, false)
+
+	htree, _ := NewHTreeWithBucketSize(sm, 32)
+	loc := htree.Location()
+
+	for i := 0; i < 500; i++ {
+		htree.Put([]byte(fmt.Sprint("testkey", i)), fmt.Sprint("testvalue", i))
+	}
+
+	sm.Close()
+
+	sm2 := storage.NewDiskStorageManager(DBDIR+"/test2", false, false, false, false)
+
+	htree2, err := LoadHTree(sm2, loc)
+	if err != nil || htree2.BucketSize() != 32 {
+		t.Error("Unexpected result:", htree2.BucketSize(), err)
+		return
+	}
+
+	for i := 500; i < 1000; i++ {
+		htree2.Put([]byte(fmt.Sprint("testkey", i)), fmt.Sprint("testvalue", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if res, err := htree2.Get([]byte(fmt.Sprint("testkey", i))); res != fmt.Sprint("testvalue", i) || err != nil {
+			t.Error("Unexpected result:", res, err)
+			return
+		}
+	}
+
+	sm2.Close()
+
+	// Trees which were stored without a bucket size use the default
+
+	htree3, _ := NewHTree(smSmall)
+	htree3.Root.MaxBucket = 0
+	smSmall.Update(htree3.Location(), htree3.Root.htreeNode)
+
+	htree3, _ = LoadHTree(smSmall, htree3.Location())
+
+	if res := htree3.BucketSize(); res != MaxBucketElements {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	htree3.Put([]byte("testkey"), "test")
+
+	if res, err := htree3.Get([]byte("testkey")); res != "test" || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func BenchmarkHTreePut(b *testing.B) {
+	for _, size := range []int{MaxBucketElements, 32, 128} {
+		b.Run(fmt.Sprint("BucketSize", size), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				htree, _ := NewHTreeWithBucketSize(storage.NewMemoryStorageManager("testsm"), size)
+
+				for i := 0; i < 300000; i++ {
+					htree.Put([]byte(fmt.Sprint("testkey", i)), i)
+				}
+			}
+		})
+	}
+}
+
+func TestGetValuesAndLocations(t *testing.T) {
+	sm := storage.NewMemoryStorageManager("testsm")
+
+	htree, _ := NewHTree(sm)
+
+	for i := 0; i < 1000; i++ {
+		htree.Put([]byte(fmt.Sprint("testkey", i)), i)
+	}
+
+	keys := [][]byte{[]byte("testkey5"), []byte("testkey999"), []byte("testkey1000"),
+		[]byte("testkey5"), []byte("testkey0")}
+
+	vals, locs, err := htree.GetValuesAndLocations(keys)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := fmt.Sprint(vals); res != "[5 999 <nil> 5 0]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	for i, key := range keys {
+		val, loc, _ := htree.GetValueAndLocation(key)
+
+		if val == nil {
+			loc = 0
+		}
+
+		if locs[i] != loc {
+			t.Error("Unexpected location:", string(key), locs[i], loc)
+			return
+		}
+	}
+
+	if vals, locs, err := htree.GetValuesAndLocations(nil); len(vals) != 0 || len(locs) != 0 || err != nil {
+		t.Error("Unexpected result:", vals, locs, err)
+		return
+	}
+
+	// Test error handling
+
+	_, loc, _ := htree.GetValueAndLocation([]byte("testkey5"))
+
+	sm.AccessMap[loc] = storage.AccessCacheAndFetchError
+
+	if _, _, err := htree.GetValuesAndLocations(keys); err != storage.ErrSlotNotFound {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	delete(sm.AccessMap, loc)
+}
+
+func benchmarkLookupKeys(b *testing.B) (*HTree, [][]byte) {
+	sm := storage.NewDiskStorageManager(DBDIR+"/"+b.Name(), false, false, true, true)
+
+	htree, _ := NewHTree(sm)
+
+	for i := 0; i < 10000; i++ {
+		htree.Put([]byte(fmt.Sprint("testkey", i)), i)
+	}
+
+	var keys [][]byte
+
+	for i := 0; i < 10000; i += 4 {
+		keys = append(keys, []byte(fmt.Sprint("testkey", i)))
+	}
+
+	return htree, keys
+}
+
+func BenchmarkGetValueAndLocation(b *testing.B) {
+	htree, keys := benchmarkLookupKeys(b)
+	defer htree.Root.sm.Close()
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for _, key := range keys {
+			htree.GetValueAndLocation(key)
+		}
+	}
+}
+
+func BenchmarkGetValuesAndLocations(b *testing.B) {
+	htree, keys := benchmarkLookupKeys(b)
+	defer htree.Root.sm.Close()
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		htree.GetValuesAndLocations(keys)
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	sm := storage.NewMemoryStorageManager("testsm")
+
+	htree, _ := NewHTree(sm)
+
+	for i := 0; i < 100; i++ {
+		htree.Put([]byte(fmt.Sprintf("\x01%03d", i)), i)
+		htree.Put([]byte(fmt.Sprintf("\x02%03dname", i)), fmt.Sprint("name", i))
+		htree.Put([]byte(fmt.Sprintf("\x02%03dkind", i)), "kind")
+	}
+
+	keys, vals, err := htree.ScanPrefix([]byte("\x02012"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := fmt.Sprintf("%q %v", keys, vals); res != `["\x02012kind" "\x02012name"] [kind name12]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if keys, _, _ := htree.ScanPrefix([]byte("\x0109")); fmt.Sprintf("%q", keys) !=
+		`["\x01090" "\x01091" "\x01092" "\x01093" "\x01094" "\x01095" "\x01096" "\x01097" "\x01098" "\x01099"]` {
+		t.Error("Unexpected result:", keys)
+		return
+	}
+
+	if keys, vals, err := htree.ScanPrefix([]byte("\x03")); len(keys) != 0 || len(vals) != 0 || err != nil {
+		t.Error("Unexpected result:", keys, vals, err)
+		return
+	}
+
+	if keys, _, _ := htree.ScanPrefix(nil); len(keys) != 300 {
+		t.Error("Unexpected result:", len(keys))
+		return
+	}
+
+	// Test error handling
+
+	_, loc, _ := htree.GetValueAndLocation([]byte("\x01050"))
+
+	sm.AccessMap[loc] = storage.AccessCacheAndFetchSeriousError
+
+	if keys, vals, err := htree.ScanPrefix([]byte("\x02")); keys != nil || vals != nil || err != file.ErrAlreadyInUse {
+		t.Error("Unexpected result:", keys, vals, err)
+		return
+	}
+
+	delete(sm.AccessMap, loc)
+}
+
+func TestScanAfter(t *testing.T) {
+	sm := storage.NewMemoryStorageManager("testsm")
+
+	htree, _ := NewHTree(sm)
+
+	for i := 0; i < 1000; i++ {
+		htree.Put([]byte(fmt.Sprintf("key%03d", i)), i)
+	}
+
+	allKeys, allVals, err := htree.ScanAfter(nil, 0)
+	if len(allKeys) != 1000 || len(allVals) != 1000 || err != nil {
+		t.Error("Unexpected result:", len(allKeys), len(allVals), err)
+		return
+	}
+
+	// Iterating in chunks must return all keys in the same order
+
+	var chunkKeys [][]byte
+	var last []byte
+
+	for {
+		keys, vals, err := htree.ScanAfter(last, 7)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if len(keys) > 7 || len(keys) != len(vals) {
+			t.Error("Unexpected result:", len(keys), len(vals))
+			return
+		}
+
+		if len(keys) == 0 {
+			break
+		}
+
+		chunkKeys = append(chunkKeys, keys...)
+		last = keys[len(keys)-1]
+	}
+
+	if fmt.Sprintf("%q", chunkKeys) != fmt.Sprintf("%q", allKeys) {
+		t.Error("Unexpected result:", chunkKeys)
+		return
+	}
+
+	// Removing keys (including the last returned key) does not change the
+	// position of the following keys
+
+	keys, _, _ := htree.ScanAfter(nil, 10)
+	next, _, _ := htree.ScanAfter(keys[9], 5)
+
+	for _, k := range keys {
+		htree.Remove(k)
+	}
+
+	if res, _, _ := htree.ScanAfter(keys[9], 5); fmt.Sprintf("%q", res) != fmt.Sprintf("%q", next) {
+		t.Error("Unexpected result:", res, next)
+		return
+	}
+
+	if res, _, _ := htree.ScanAfter(nil, 5); fmt.Sprintf("%q", res) != fmt.Sprintf("%q", allKeys[10:15]) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, vals, err := htree.ScanAfter(allKeys[999], 5); len(res) != 0 || len(vals) != 0 || err != nil {
+		t.Error("Unexpected result:", res, vals, err)
+		return
+	}
+
+	// Test error handling
+
+	_, loc, _ := htree.GetValueAndLocation(allKeys[500])
+
+	sm.AccessMap[loc] = storage.AccessCacheAndFetchSeriousError
+
+	if keys, vals, err := htree.ScanAfter(nil, 0); keys != nil || vals != nil || err != file.ErrAlreadyInUse {
+		t.Error("Unexpected result:", keys, vals, err)
+		return
+	}
+
+	delete(sm.AccessMap, loc)
+}
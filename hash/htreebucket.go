@@ -25,12 +25,13 @@ type htreeBucket struct {
 }
 
 /*
-htreeBucket creates a new bucket for the HTree.
+htreeBucket creates a new bucket for the HTree which can hold up to maxBucket
+elements unless it is a leaf.
 */
-func newHTreeBucket(tree *HTree, depth byte) *htreeBucket {
+func newHTreeBucket(tree *HTree, depth byte, maxBucket byte) *htreeBucket {
 	return &htreeBucket{&htreeNode{tree, 0, nil, depth, nil,
-		make([][]byte, MaxBucketElements),
-		make([]interface{}, MaxBucketElements), 0}}
+		make([][]byte, maxBucket),
+		make([]interface{}, maxBucket), 0, maxBucket}}
 }
 
 /*
@@ -54,7 +55,7 @@ func (b *htreeBucket) HasRoom() bool {
 	if b.IsLeaf() {
 		return true
 	}
-	return b.BucketSize < MaxBucketElements
+	return b.BucketSize < b.maxBucketSize()
 }
 
 /*
@@ -81,7 +82,7 @@ func (b *htreeBucket) Put(key []byte, value interface{}) interface{} {
 		panic("Bucket has no more room")
 	}
 
-	if b.BucketSize >= MaxBucketElements {
+	if int(b.BucketSize) >= len(b.Keys) {
 		b.Keys = append(b.Keys, key)
 		b.Values = append(b.Values, value)
 		b.BucketSize++
@@ -20,7 +20,7 @@ func TestHashBucket(t *testing.T) {
 
 	// Create a top level bucket
 
-	treebucket := newHTreeBucket(tree, 1)
+	treebucket := newHTreeBucket(tree, 1, MaxBucketElements)
 
 	if treebucket.Size() != 0 {
 		t.Error("Newly created bucket should be empty")
@@ -13,6 +13,7 @@ package hash
 import (
 	"bytes"
 	"fmt"
+	"sort"
 )
 
 /*
@@ -23,10 +24,11 @@ type htreePage struct {
 }
 
 /*
-newHTreePage creates a new page for the HTree.
+newHTreePage creates a new page for the HTree. Buckets below the page can hold
+up to maxBucket elements.
 */
-func newHTreePage(tree *HTree, depth byte) *htreePage {
-	return &htreePage{&htreeNode{tree, 0, nil, depth, make([]uint64, MaxPageChildren), nil, nil, 0}}
+func newHTreePage(tree *HTree, depth byte, maxBucket byte) *htreePage {
+	return &htreePage{&htreeNode{tree, 0, nil, depth, make([]uint64, MaxPageChildren), nil, nil, 0, maxBucket}}
 }
 
 /*
@@ -88,6 +90,192 @@ func (p *htreePage) Get(key []byte) (interface{}, *htreeBucket, error) {
 	return nil, nil, nil
 }
 
+/*
+GetMulti looks up the values for several keys. The keys to look up are given
+by their indices in the list of all keys. The indices must be sorted by the
+hash codes of their keys so keys which share a path in the tree are next to
+each other. Found values and the locations of their buckets are written to the
+given result lists.
+*/
+func (p *htreePage) GetMulti(keys [][]byte, hashes []uint32, idx []int,
+	vals []interface{}, locs []uint64) error {
+
+	shift := (MaxTreeDepth - p.Depth) * PageLevelBits
+
+	for start := 0; start < len(idx); {
+		hash := (hashes[idx[start]] >> shift) % MaxPageChildren
+
+		// Find all keys which hash to the same child
+
+		end := start + 1
+		for end < len(idx) && (hashes[idx[end]]>>shift)%MaxPageChildren == hash {
+			end++
+		}
+
+		group := idx[start:end]
+		start = end
+
+		loc := p.Children[hash]
+
+		if loc == 0 {
+			continue
+		}
+
+		node, err := p.fetchNode(loc)
+		if err != nil {
+			return err
+		}
+
+		if node.Children != nil {
+
+			// If another page was found deligate the request
+
+			page := &htreePage{node}
+
+			page.loc = loc
+			page.sm = p.sm
+
+			if err := page.GetMulti(keys, hashes, group, vals, locs); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// If a Bucket was found lookup all keys in it
+
+		bucket := &htreeBucket{node}
+
+		for _, i := range group {
+			if val := bucket.Get(keys[i]); val != nil {
+				vals[i] = val
+				locs[i] = loc
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Scan calls a given function for every key / value pair below this page.
+The tree is traversed depth-first.
+*/
+func (p *htreePage) Scan(f func(key []byte, value interface{})) error {
+
+	for _, loc := range p.Children {
+
+		if loc == 0 {
+			continue
+		}
+
+		node, err := p.fetchNode(loc)
+		if err != nil {
+			return err
+		}
+
+		if node.Children != nil {
+
+			// If another page was found deligate the request
+
+			page := &htreePage{node}
+
+			page.loc = loc
+			page.sm = p.sm
+
+			if err := page.Scan(f); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		bucket := &htreeBucket{node}
+
+		for i := 0; i < int(bucket.BucketSize); i++ {
+			f(bucket.Keys[i], bucket.Values[i])
+		}
+	}
+
+	return nil
+}
+
+/*
+ScanAfter adds key / value pairs below this page to a given result list until
+the list holds limit pairs. Only pairs which follow a given hash code and key
+are added if the page is on the path of the given hash code. Children are
+visited in the order of their hash codes.
+*/
+func (p *htreePage) ScanAfter(hash uint32, key []byte, onPath bool, limit int,
+	res *hashKeyValueList) error {
+
+	var start uint32
+
+	if onPath {
+		start = (hash >> ((MaxTreeDepth - p.Depth) * PageLevelBits)) % MaxPageChildren
+	}
+
+	for i := start; i < MaxPageChildren && (limit < 1 || len(res.keys) < limit); i++ {
+		loc := p.Children[i]
+
+		if loc == 0 {
+			continue
+		}
+
+		node, err := p.fetchNode(loc)
+		if err != nil {
+			return err
+		}
+
+		// Only the first visited child can be on the path of the given hash
+
+		childOnPath := onPath && i == start
+
+		if node.Children != nil {
+
+			// If another page was found deligate the request
+
+			page := &htreePage{node}
+
+			page.loc = loc
+			page.sm = p.sm
+
+			if err := page.ScanAfter(hash, key, childOnPath, limit, res); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// Keys in a bucket are stored in insertion order
+
+		bucket := &htreeBucket{node}
+		found := &hashKeyValueList{}
+
+		for j := 0; j < int(bucket.BucketSize); j++ {
+			k := bucket.Keys[j]
+			h, _ := MurMurHashData(k, 0, len(k)-1, 42)
+
+			if childOnPath && (h < hash || (h == hash && bytes.Compare(k, key) <= 0)) {
+				continue
+			}
+
+			found.hashes = append(found.hashes, h)
+			found.keys = append(found.keys, k)
+			found.vals = append(found.vals, bucket.Values[j])
+		}
+
+		sort.Sort(found)
+
+		for j := 0; j < found.Len() && (limit < 1 || len(res.keys) < limit); j++ {
+			res.keys = append(res.keys, found.keys[j])
+			res.vals = append(res.vals, found.vals[j])
+		}
+	}
+
+	return nil
+}
+
 /*
 Exists checks if an element exists.
 */
@@ -142,7 +330,7 @@ func (p *htreePage) Put(key []byte, value interface{}) (interface{}, error) {
 
 		// If nothing exists yet for the hash code then create a new bucket
 
-		bucket := newHTreeBucket(p.tree, p.Depth+1)
+		bucket := newHTreeBucket(p.tree, p.Depth+1, p.maxBucketSize())
 
 		existing := bucket.Put(key, value)
 
@@ -205,7 +393,7 @@ func (p *htreePage) Put(key []byte, value interface{}) (interface{}, error) {
 		panic("Max depth of HTree exceeded")
 	}
 
-	page := newHTreePage(p.tree, p.Depth+1)
+	page := newHTreePage(p.tree, p.Depth+1, p.maxBucketSize())
 
 	ploc, err := p.sm.Insert(page.htreeNode)
 	if err != nil {
@@ -413,9 +413,9 @@ func TestHTreePageRemove(t *testing.T) {
 }
 
 func testMaxDepthExceededPanic(t *testing.T, page *htreePage, sm *storage.MemoryStorageManager) {
-	fn := &htreeNode{}
-	fn.sm = sm
-	node, _ := fn.fetchNode(8)
+	// Change the stored node - fetched nodes are copies
+
+	node := sm.Data[8].(*htreeNode)
 
 	defer func() {
 		if r := recover(); r == nil {
@@ -220,3 +220,63 @@ func (it *HTreeIterator) String() string {
 	return fmt.Sprintf("HTree Iterator (tree: %v)\n  path: %v\n  indices: %v\n  next: %v / %v\n",
 		it.tree.Root.Location(), it.nodePath, it.indices, it.nextKey, it.nextValue)
 }
+
+/*
+HTreeSyncIterator data structure. The iterator holds the lock of the tree
+only while it moves to the next item so other readers and writers can access
+the tree between calls. The tree is traversed depth-first.
+*/
+type HTreeSyncIterator struct {
+	it  *HTreeIterator // Underlying iterator
+	err error          // Error which has not been returned yet
+}
+
+/*
+Iterator returns an iterator over all key / value pairs of this tree which
+can be used while other goroutines access the tree.
+*/
+func (t *HTree) Iterator() *HTreeSyncIterator {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	it := NewHTreeIterator(t)
+
+	return &HTreeSyncIterator{it, it.LastError}
+}
+
+/*
+HasNext returns if there is a next key / value pair or an error which
+should be returned by Next.
+*/
+func (it *HTreeSyncIterator) HasNext() bool {
+	return it.it.HasNext() || it.err != nil
+}
+
+/*
+Next returns the next key / value pair. Returns ErrNoMoreItems if there
+are no more items. An error from the storage (e.g. if a record is in use)
+terminates the iterator and is returned once.
+*/
+func (it *HTreeSyncIterator) Next() ([]byte, interface{}, error) {
+
+	if it.err != nil {
+		err := it.err
+		it.err = nil
+		return nil, nil, err
+	}
+
+	if !it.it.HasNext() {
+		return nil, nil, ErrNoMoreItems
+	}
+
+	it.it.tree.mutex.Lock()
+	defer it.it.tree.mutex.Unlock()
+
+	key, value := it.it.Next()
+
+	// Keep an error which happened while fetching the following item
+
+	it.err = it.it.LastError
+
+	return key, value, nil
+}
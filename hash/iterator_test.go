@@ -12,6 +12,7 @@ package hash
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 
 	"devt.de/krotik/eliasdb/storage"
@@ -222,3 +223,106 @@ func TestIterator(t *testing.T) {
 		return
 	}
 }
+
+func TestSyncIterator(t *testing.T) {
+	sm := storage.NewMemoryStorageManager("testsm")
+	htree, _ := NewHTree(sm)
+
+	expected := make(map[string]interface{})
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint("testkey", i)
+		htree.Put([]byte(key), i)
+		expected[key] = i
+	}
+
+	// Read the tree concurrently while iterating
+
+	var wg sync.WaitGroup
+
+	done := make(chan bool)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+					htree.Get([]byte(fmt.Sprint("testkey", j%1000)))
+				}
+			}
+		}()
+	}
+
+	found := make(map[string]interface{})
+
+	it := htree.Iterator()
+
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			t.Error(err)
+			break
+		}
+
+		if _, ok := found[string(k)]; ok {
+			t.Error("Key was returned twice:", string(k))
+			break
+		}
+
+		found[string(k)] = v
+	}
+
+	close(done)
+	wg.Wait()
+
+	if fmt.Sprint(found) != fmt.Sprint(expected) {
+		t.Error("Unexpected result:", len(found), len(expected))
+		return
+	}
+
+	if k, v, err := it.Next(); k != nil || v != nil || err != ErrNoMoreItems {
+		t.Error("Unexpected result:", k, v, err)
+		return
+	}
+
+	// Test error case
+
+	sm = storage.NewMemoryStorageManager("testsm")
+	htree, _ = NewHTree(sm)
+
+	htree.Put([]byte("testkey1"), "test1")
+
+	sm.AccessMap[2] = storage.AccessCacheAndFetchSeriousError
+
+	it = htree.Iterator()
+
+	if !it.HasNext() {
+		t.Error("Iterator should report the error")
+		return
+	}
+
+	if k, v, err := it.Next(); k != nil || v != nil || err != file.ErrAlreadyInUse {
+		t.Error("Unexpected result:", k, v, err)
+		return
+	}
+
+	if k, v, err := it.Next(); k != nil || v != nil || err != ErrNoMoreItems || it.HasNext() {
+		t.Error("Unexpected result:", k, v, err)
+		return
+	}
+
+	delete(sm.AccessMap, 2)
+
+	it = htree.Iterator()
+
+	if k, v, err := it.Next(); string(k) != "testkey1" || v != "test1" || err != nil || it.HasNext() {
+		t.Error("Unexpected result:", k, v, err)
+		return
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,8 +42,10 @@ import (
 	"devt.de/krotik/eliasdb/cluster"
 	"devt.de/krotik/eliasdb/cluster/manager"
 	"devt.de/krotik/eliasdb/config"
+	"devt.de/krotik/eliasdb/eql"
 	"devt.de/krotik/eliasdb/graph"
 	"devt.de/krotik/eliasdb/graph/graphstorage"
+	"devt.de/krotik/eliasdb/graph/util"
 )
 
 /*
@@ -120,6 +124,24 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 			fatal(err)
 			return
 		}
+
+		// Set the cache sizes and the page format of the storage managers
+
+		dgs := gs.(*graphstorage.DiskGraphStorage)
+
+		dgs.SetChecksums(config.Bool(config.StorageChecksums))
+
+		dgs.SetCacheSize(int(config.Int(config.StorageCacheSize)))
+
+		if cp, ok := config.Config[config.StorageCachePartitions].(map[string]interface{}); ok {
+			for part, size := range cp {
+				if size, err := strconv.Atoi(fmt.Sprint(size)); err == nil {
+					dgs.SetPartitionCacheSize(part, size)
+				} else {
+					print("Ignoring invalid cache size for partition ", part)
+				}
+			}
+		}
 	}
 
 	// Check if clustering is enabled
@@ -184,6 +206,13 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 	api.GS = gs
 	api.GM = graph.NewGraphManager(gs)
 
+	if !config.Bool(config.MemoryOnlyStorage) && config.Bool(config.EnableReadOnly) {
+
+		// Reject all mutations early rather than failing in the storage layer
+
+		api.GM.SetReadOnly("", true)
+	}
+
 	defer func() {
 
 		print("Closing datastore")
@@ -213,9 +242,81 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 	user.UserSessionManager.Provider.(*user.MemorySessionProvider).SetExpiry(cookieMaxAge)
 
 	api.APIHost = config.Str(config.HTTPSHost) + ":" + config.Str(config.HTTPSPort)
+	api.APIRootPath = strings.TrimSuffix("/"+strings.Trim(config.Str(config.APIRootPath), "/"), "/")
+	api.CompressionMinSize = int(config.Int(config.CompressionMinSize))
+	api.CORSAllowedOrigins = config.StrList(config.CORSAllowedOrigins)
+	api.CORSAllowedMethods = config.StrList(config.CORSAllowedMethods)
+	api.CORSAllowedHeaders = config.StrList(config.CORSAllowedHeaders)
+	api.CORSAllowCredentials = config.Bool(config.CORSAllowCredentials)
+	api.CORSExposedHeaders = []string{v1.HTTPHeaderTotalCount, v1.HTTPHeaderCacheID, v1.HTTPHeaderNextCursor, "ETag"}
+
+	if tokens := config.StrList(config.AuthTokens); len(tokens) > 0 {
+		api.AuthTokenStore = api.NewStaticTokenStore(tokens)
+	}
+	api.AuthTokenOpenRead = config.Bool(config.AuthTokenOpenRead)
+
+	if rate := config.Int(config.RateLimitRead); rate > 0 {
+		api.RateLimitRead = api.NewRateLimiter(float64(rate), int(config.Int(config.RateLimitBurst)),
+			int(config.Int(config.RateLimitMaxClients)))
+	}
+	if rate := config.Int(config.RateLimitWrite); rate > 0 {
+		api.RateLimitWrite = api.NewRateLimiter(float64(rate), int(config.Int(config.RateLimitBurst)),
+			int(config.Int(config.RateLimitMaxClients)))
+	}
+	api.RateLimitClientHeader = config.Str(config.RateLimitClientHeader)
+
+	if config.Bool(config.EnableRequestLog) {
+		api.RequestLogger = api.NewRequestLogWriter(os.Stderr, config.Str(config.RequestLogFormat) == "json")
+	}
+	api.RequestLogBodies = config.Bool(config.RequestLogBodies)
+
 	v1.ResultCacheMaxSize = uint64(config.Int(config.ResultCacheMaxSize))
 	v1.ResultCacheMaxAge = config.Int(config.ResultCacheMaxAgeSeconds)
 
+	eql.ResultSpillRows = int(config.Int(config.ResultSpillRows))
+	if loc := config.Str(config.LocationResultSpill); loc != "" {
+		eql.ResultSpillDir = filepath.Join(basepath, loc)
+		ensurePath(eql.ResultSpillDir)
+	}
+	eql.ScanWorkers = int(config.Int(config.QueryScanWorkers))
+
+	if ff := config.Str(config.OutputFloatFormat); v1.ValidOutputFloatFormat(ff) {
+		v1.OutputFloatFormat = ff[0]
+	} else {
+		print("Ignoring invalid output float format ", ff)
+		v1.OutputFloatFormat = config.DefaultConfig[config.OutputFloatFormat].(string)[0]
+	}
+	v1.OutputFloatPrecision = int(config.Int(config.OutputFloatPrecision))
+	v1.MaxAttributeValueSize = config.Int(config.MaxAttributeValueSize)
+	v1.MaxPathKeyLength = int(config.Int(config.MaxPathKeyLength))
+	v1.EmptyListNoContent = config.Bool(config.EnableEmptyListNoContent)
+	v1.MaxShortestPathDepth = int(config.Int(config.MaxShortestPathDepth))
+	v1.InfoSchemaSampleSize = int(config.Int(config.InfoSchemaSampleSize))
+
+	if da, ok := config.Config[config.DerivedAttributes].(map[string]interface{}); ok {
+		for kind, attrs := range da {
+			if attrs, ok := attrs.(map[string]interface{}); ok {
+				v1.DerivedAttributes[kind] = make(map[string]string)
+				for attr, expr := range attrs {
+					v1.DerivedAttributes[kind][attr] = fmt.Sprint(expr)
+				}
+			}
+		}
+	}
+
+	if am, ok := config.Config[config.AttributeNameMapping].(map[string]interface{}); ok {
+		for attr, name := range am {
+			v1.AttributeNameMapping[attr] = fmt.Sprint(name)
+		}
+	}
+
+	v1.AttributeNameCasing = config.Str(config.AttributeNameCasing)
+
+	if _, ok := util.CaseConverters[v1.AttributeNameCasing]; !ok && v1.AttributeNameCasing != "" {
+		print("Ignoring unknown attribute name casing ", v1.AttributeNameCasing)
+		v1.AttributeNameCasing = ""
+	}
+
 	// Check if HTTPS key and certificate are in place
 
 	keyPath := filepath.Join(basepath, config.Str(config.LocationHTTPS), config.Str(config.HTTPSKey))
@@ -269,7 +370,7 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 			// control lists) object
 
 			if ok, err = fileutil.PathExists(filepath.Join(basepath, config.Str(config.LocationAccessDB))); !ok && err == nil {
-				err = ioutil.WriteFile(filepath.Join(basepath, config.Str(config.LocationAccessDB)), ac.DefaultAccessDB, 0600)
+				err = ioutil.WriteFile(filepath.Join(basepath, config.Str(config.LocationAccessDB)), []byte(withAPIRootPath(string(ac.DefaultAccessDB))), 0600)
 			}
 
 			if err == nil {
@@ -292,9 +393,10 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 			}
 
 			// Setup the AuthHandler object which provides cookie based authentication
-			// for endpoints which are registered with its HandleFunc
+			// for endpoints which are registered with its HandleFunc - CORS preflight
+			// requests are answered before authentication
 
-			ac.AuthHandler = auth.NewCookieAuthHandleFuncWrapper(http.HandleFunc)
+			ac.AuthHandler = auth.NewCookieAuthHandleFuncWrapper(api.CORSHandleFunc(http.HandleFunc))
 
 			// Connect the UserDB object to the AuthHandler - this provides authentication for users
 
@@ -336,9 +438,10 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 	}
 
 	// Register EliasDB API endpoints - depending on if access control has been enabled
-	// these will require authentication and authorization for a given user
+	// these will require authentication and authorization for a given user. If
+	// tokens are configured requests also need a valid bearer token.
 
-	api.RegisterRestEndpoints(v1.V1EndpointMap)
+	api.RegisterRestEndpoints(api.TokenAuthEndpointMap(v1.V1EndpointMap))
 
 	// Register normal web server
 
@@ -362,7 +465,7 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 			print("Ensuring login page: ", loginFile)
 
 			if res, _ := fileutil.PathExists(loginFile); !res {
-				errorutil.AssertOk(ioutil.WriteFile(loginFile, []byte(LoginSRC[1:]), 0644))
+				errorutil.AssertOk(ioutil.WriteFile(loginFile, []byte(withAPIRootPath(LoginSRC[1:])), 0644))
 			}
 		}
 
@@ -370,22 +473,22 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 
 		if config.Bool(config.EnableWebTerminal) {
 
-			ensurePath(filepath.Join(webFolder, api.APIRoot))
+			ensurePath(filepath.Join(webFolder, api.APIRootPath))
 
-			termFile := filepath.Join(webFolder, api.APIRoot, "term.html")
+			termFile := filepath.Join(webFolder, api.APIRootPath, "term.html")
 
 			print("Ensuring web terminal: ", termFile)
 
 			if res, _ := fileutil.PathExists(termFile); !res {
-				errorutil.AssertOk(ioutil.WriteFile(termFile, []byte(TermSRC[1:]), 0644))
+				errorutil.AssertOk(ioutil.WriteFile(termFile, []byte(withAPIRootPath(TermSRC[1:])), 0644))
 			}
 		}
 
 		if config.Bool(config.EnableClusterTerminal) {
 
-			ensurePath(filepath.Join(webFolder, api.APIRoot))
+			ensurePath(filepath.Join(webFolder, api.APIRootPath))
 
-			termFile := filepath.Join(webFolder, api.APIRoot, "cluster.html")
+			termFile := filepath.Join(webFolder, api.APIRootPath, "cluster.html")
 
 			if config.Bool(config.EnableCluster) {
 
@@ -393,13 +496,13 @@ func StartServerWithSingleOp(singleOperation func(*graph.Manager) bool) {
 
 				api.DD.MemberManager.MemberInfo()[manager.MemberInfoTermURL] =
 					fmt.Sprintf("https://%v:%v%v/%v", config.Str(config.HTTPSHost),
-						config.Str(config.HTTPSPort), api.APIRoot, "cluster.html")
+						config.Str(config.HTTPSPort), api.APIRootPath, "cluster.html")
 			}
 
 			print("Ensuring cluster terminal: ", termFile)
 
 			if res, _ := fileutil.PathExists(termFile); !res {
-				errorutil.AssertOk(ioutil.WriteFile(termFile, []byte(ClusterTermSRC[1:]), 0644))
+				errorutil.AssertOk(ioutil.WriteFile(termFile, []byte(withAPIRootPath(ClusterTermSRC[1:])), 0644))
 			}
 		}
 	}
@@ -494,9 +597,17 @@ ensurePath ensures that a given relative path exists.
 */
 func ensurePath(path string) {
 	if res, _ := fileutil.PathExists(path); !res {
-		if err := os.Mkdir(path, 0770); err != nil {
+		if err := os.MkdirAll(path, 0770); err != nil {
 			fatal("Could not create directory:", err.Error())
 			return
 		}
 	}
 }
+
+/*
+withAPIRootPath replaces all references to the default API root in a given
+source with the configured API root path.
+*/
+func withAPIRootPath(src string) string {
+	return strings.Replace(src, `"`+api.APIRoot, `"`+api.APIRootPath, -1)
+}
@@ -29,6 +29,8 @@ The CachedDiskStorageManager is a cache wrapper for the DiskStorageManager. Its
 purpose is to intercept calls and to maintain a cache of stored objects. The cache
 is limited in size by the number of total objects it references. Once the cache
 is full it will forget the objects which have been requested the least.
+Forgetting an object only drops the cached reference - the cache never holds
+file records so an eviction cannot interfere with records which are in use.
 
 MemoryStorageManager
 
@@ -37,7 +39,11 @@ error simulation facilities.
 */
 package storage
 
-import "sync"
+import (
+	"sync"
+
+	"devt.de/krotik/eliasdb/storage/slotting"
+)
 
 /*
 CachedDiskStorageManager data structure
@@ -49,6 +55,7 @@ type CachedDiskStorageManager struct {
 	maxObjects         int                    // Max number of objects which should be held in the cache
 	firstentry         *cacheEntry            // Pointer to first entry in cacheEntry linked list
 	lastentry          *cacheEntry            // Pointer to last entry in cacheEntry linked list
+	stats              *CacheStats            // Cache counters
 }
 
 /*
@@ -71,7 +78,7 @@ NewCachedDiskStorageManager creates a new cache wrapper for a DiskStorageManger.
 */
 func NewCachedDiskStorageManager(diskstoragemanager *DiskStorageManager, maxObjects int) *CachedDiskStorageManager {
 	return &CachedDiskStorageManager{diskstoragemanager, &sync.Mutex{}, make(map[uint64]*cacheEntry),
-		maxObjects, nil, nil}
+		maxObjects, nil, nil, &CacheStats{}}
 }
 
 /*
@@ -81,6 +88,13 @@ func (cdsm *CachedDiskStorageManager) Name() string {
 	return cdsm.diskstoragemanager.Name()
 }
 
+/*
+MaxObjects returns the maximum number of objects which are held in the cache.
+*/
+func (cdsm *CachedDiskStorageManager) MaxObjects() int {
+	return cdsm.maxObjects
+}
+
 /*
 Root returns a root value.
 */
@@ -193,9 +207,12 @@ func (cdsm *CachedDiskStorageManager) FetchCached(loc uint64) (interface{}, erro
 	defer cdsm.mutex.Unlock()
 
 	if entry, ok := cdsm.cache[loc]; ok {
+		cdsm.stats.hit()
 		return entry.object, nil
 	}
 
+	cdsm.stats.miss()
+
 	return nil, ErrNotInCache
 }
 
@@ -236,6 +253,27 @@ func (cdsm *CachedDiskStorageManager) Flush() error {
 	return cdsm.diskstoragemanager.Flush()
 }
 
+/*
+FreeSlotStats returns statistics on free physical slots.
+*/
+func (cdsm *CachedDiskStorageManager) FreeSlotStats() (*slotting.FreePhysicalSlotStats, error) {
+	return cdsm.diskstoragemanager.FreeSlotStats()
+}
+
+/*
+Compact relocates stored data to fill free space and truncates unused pages.
+*/
+func (cdsm *CachedDiskStorageManager) Compact() error {
+	return cdsm.diskstoragemanager.Compact()
+}
+
+/*
+CacheStats returns the cache counters of the storage manager.
+*/
+func (cdsm *CachedDiskStorageManager) CacheStats() *CacheStats {
+	return cdsm.stats
+}
+
 /*
 addToCache adds an entry to the cache.
 */
@@ -247,6 +285,9 @@ func (cdsm *CachedDiskStorageManager) addToCache(loc uint64, o interface{}) {
 	// linked list if the list is full
 
 	if len(cdsm.cache) >= cdsm.maxObjects {
+		if cdsm.firstentry != nil {
+			cdsm.stats.evict()
+		}
 		entry = cdsm.removeOldestFromCache()
 	} else {
 		entry = entryPool.Get().(*cacheEntry)
@@ -399,3 +399,44 @@ func TestCachedDiskStorageManagerCacheManagement(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCachedDiskStorageManagerCacheStats(t *testing.T) {
+	var sm Manager
+
+	dsm := NewDiskStorageManager(DBDIR+"/ctest4", false, false, true, true)
+	cdsm := NewCachedDiskStorageManager(dsm, 2)
+	defer cdsm.Close()
+
+	sm = cdsm
+
+	stats := sm.(CacheStatsManager).CacheStats()
+
+	loc1, _ := cdsm.Insert(&cachetestobj{1, "test1"})
+	loc2, _ := cdsm.Insert(&cachetestobj{2, "test2"})
+
+	cdsm.FetchCached(loc1)
+	cdsm.FetchCached(loc2)
+
+	// Inserting a third object evicts the oldest object
+
+	loc3, _ := cdsm.Insert(&cachetestobj{3, "test3"})
+
+	if _, err := cdsm.FetchCached(loc1); err != ErrNotInCache {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	cdsm.FetchCached(loc3)
+
+	if stats.Hits() != 3 || stats.Misses() != 1 || stats.Evictions() != 1 {
+		t.Error("Unexpected counters:", stats.Hits(), stats.Misses(), stats.Evictions())
+		return
+	}
+
+	stats.Reset()
+
+	if stats.Hits() != 0 || stats.Misses() != 0 || stats.Evictions() != 0 {
+		t.Error("Unexpected counters:", stats.Hits(), stats.Misses(), stats.Evictions())
+		return
+	}
+}
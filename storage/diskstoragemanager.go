@@ -16,6 +16,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"sync"
 	"time"
 
@@ -102,6 +103,19 @@ func NewDiskStorageManager(filename string, readonly bool, onlyAppend bool,
 		onlyAppend, transDisabled, lockfileDisabled)}
 }
 
+/*
+NewChecksumDiskStorageManager creates a new disk storage manager which stores
+a CRC32 checksum with every page of newly created files (see
+file.NewChecksumStorageFile). Existing files are opened with the format they
+were created with.
+*/
+func NewChecksumDiskStorageManager(filename string, readonly bool, onlyAppend bool,
+	transDisabled bool, lockfileDisabled bool) *DiskStorageManager {
+
+	return &DiskStorageManager{newByteDiskStorageManager(filename, readonly,
+		onlyAppend, transDisabled, lockfileDisabled, true)}
+}
+
 /*
 Name returns the name of the StorageManager instance.
 */
@@ -191,6 +205,7 @@ type ByteDiskStorageManager struct {
 	readonly      bool        // Flag to make the storage readonly
 	onlyAppend    bool        // Flag for append-only mode
 	transDisabled bool        // Flag if transactions are enabled
+	checksums     bool        // Flag if new files store pages with checksums
 	mutex         *sync.Mutex // Mutex to protect actual file operations
 
 	physicalSlotsSf        *file.StorageFile        // StorageFile for physical slots
@@ -220,6 +235,17 @@ manager will not support transactions.
 func NewByteDiskStorageManager(filename string, readonly bool, onlyAppend bool,
 	transDisabled bool, lockfileDisabled bool) *ByteDiskStorageManager {
 
+	return newByteDiskStorageManager(filename, readonly, onlyAppend,
+		transDisabled, lockfileDisabled, false)
+}
+
+/*
+newByteDiskStorageManager creates a new disk storage manager which can only
+store byte slices.
+*/
+func newByteDiskStorageManager(filename string, readonly bool, onlyAppend bool,
+	transDisabled bool, lockfileDisabled bool, checksums bool) *ByteDiskStorageManager {
+
 	var lf *lockutil.LockFile
 
 	// Create a lockfile which is checked every 50 milliseconds
@@ -229,7 +255,7 @@ func NewByteDiskStorageManager(filename string, readonly bool, onlyAppend bool,
 			time.Duration(50)*time.Millisecond)
 	}
 
-	bdsm := &ByteDiskStorageManager{filename, readonly, onlyAppend, transDisabled, &sync.Mutex{}, nil, nil,
+	bdsm := &ByteDiskStorageManager{filename, readonly, onlyAppend, transDisabled, checksums, &sync.Mutex{}, nil, nil,
 		nil, nil, nil, nil, nil, nil, nil, nil, lf}
 
 	err := initByteDiskStorageManager(bdsm)
@@ -465,6 +491,41 @@ func (bdsm *ByteDiskStorageManager) Free(loc uint64) error {
 	return bdsm.logicalSlotManager.Free(loc)
 }
 
+/*
+FreeSlotStats returns statistics on free physical slots. These can be used
+to determine the fragmentation of the storage.
+*/
+func (bdsm *ByteDiskStorageManager) FreeSlotStats() (*slotting.FreePhysicalSlotStats, error) {
+	bdsm.checkFileOpen()
+
+	bdsm.mutex.Lock()
+	defer bdsm.mutex.Unlock()
+
+	return bdsm.physicalSlotManager.FreeSlotStats()
+}
+
+/*
+Compact relocates stored data to fill free space and truncates unused pages
+at the end of the physical slots files. Locations which were returned by
+Insert stay valid. Should only be called while the storage is not in use.
+*/
+func (bdsm *ByteDiskStorageManager) Compact() error {
+	bdsm.checkFileOpen()
+
+	// Fail operation if readonly
+
+	if bdsm.readonly {
+		return ErrReadonly
+	}
+
+	// Continue single threaded from here on
+
+	bdsm.mutex.Lock()
+	defer bdsm.mutex.Unlock()
+
+	return slotting.Compact(bdsm.logicalSlotManager, bdsm.physicalSlotManager)
+}
+
 /*
 Flush writes all pending changes to disk.
 */
@@ -756,7 +817,15 @@ createFileAndPager creates a storagefile and a pager.
 func createFileAndPager(filename string, recordSize uint32,
 	bdsm *ByteDiskStorageManager) (*file.StorageFile, *paging.PagedStorageFile, error) {
 
-	sf, err := file.NewStorageFile(filename, recordSize, bdsm.transDisabled)
+	var sf *file.StorageFile
+	var err error
+
+	if fileChecksums(filename, bdsm.checksums) {
+		sf, err = file.NewChecksumStorageFile(filename, recordSize, bdsm.transDisabled)
+	} else {
+		sf, err = file.NewStorageFile(filename, recordSize, bdsm.transDisabled)
+	}
+
 	if err != nil {
 		return nil, nil, err
 	}
@@ -765,3 +834,30 @@ func createFileAndPager(filename string, recordSize uint32,
 
 	return sf, pager, err
 }
+
+/*
+fileChecksums checks if the pages of an existing file are stored with
+checksums. The given default is returned if the file has no header yet.
+*/
+func fileChecksums(filename string, def bool) bool {
+	magic := make([]byte, 2)
+
+	f, err := os.Open(fmt.Sprintf("%v.0", filename))
+	if err != nil {
+		return def
+	}
+	defer f.Close()
+
+	if n, _ := f.ReadAt(magic, 0); n != len(magic) {
+		return def
+	}
+
+	switch uint16(magic[0])<<8 | uint16(magic[1]) {
+	case 0:
+		return def
+	case paging.PageHeaderChecksums:
+		return true
+	}
+
+	return false
+}
@@ -13,7 +13,9 @@ package storage
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -21,6 +23,8 @@ import (
 	"devt.de/krotik/common/lockutil"
 	"devt.de/krotik/common/testutil"
 	"devt.de/krotik/eliasdb/storage/file"
+	"devt.de/krotik/eliasdb/storage/paging"
+	"devt.de/krotik/eliasdb/storage/paging/view"
 	"devt.de/krotik/eliasdb/storage/slotting/pageview"
 	"devt.de/krotik/eliasdb/storage/util"
 )
@@ -593,7 +597,7 @@ const InvalidFileName = "**" + "\x00"
 
 func TestDiskStorageManagerInit(t *testing.T) {
 	lockfile := lockutil.NewLockFile(DBDIR+"/"+"lock0.lck", time.Duration(50)*time.Millisecond)
-	dsm := &DiskStorageManager{&ByteDiskStorageManager{DBDIR + "/" + InvalidFileName, false, true, true, &sync.Mutex{},
+	dsm := &DiskStorageManager{&ByteDiskStorageManager{DBDIR + "/" + InvalidFileName, false, true, true, false, &sync.Mutex{},
 		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, lockfile}}
 
 	err := initByteDiskStorageManager(dsm.ByteDiskStorageManager)
@@ -604,7 +608,7 @@ func TestDiskStorageManagerInit(t *testing.T) {
 
 	testCannotInitPanic(t)
 
-	dsm = &DiskStorageManager{&ByteDiskStorageManager{DBDIR + "/test999", false, true, true, &sync.Mutex{},
+	dsm = &DiskStorageManager{&ByteDiskStorageManager{DBDIR + "/test999", false, true, true, false, &sync.Mutex{},
 		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}}
 
 	err = initByteDiskStorageManager(dsm.ByteDiskStorageManager)
@@ -645,7 +649,7 @@ func testClosedPanic(t *testing.T, dsm *DiskStorageManager) {
 }
 
 func testVersionCheckPanic(t *testing.T) {
-	dsm := &DiskStorageManager{&ByteDiskStorageManager{DBDIR + "/test999", false, true, true, &sync.Mutex{},
+	dsm := &DiskStorageManager{&ByteDiskStorageManager{DBDIR + "/test999", false, true, true, false, &sync.Mutex{},
 		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}}
 
 	defer func() {
@@ -664,3 +668,153 @@ func checkLocation(t *testing.T, loc uint64, record uint64, offset uint16) {
 		t.Error("Unexpected location. Expected:", record, offset, "Got:", lrecord, loffset)
 	}
 }
+
+func TestDiskStorageManagerFreeSlotStats(t *testing.T) {
+	dsm := NewDiskStorageManager(DBDIR+"/test_stats", false, false, true, true)
+	cdsm := NewCachedDiskStorageManager(dsm, 10)
+
+	var sm FreeSlotStatsManager = cdsm
+
+	var locs []uint64
+
+	for i := 0; i < 5; i++ {
+		loc, err := cdsm.Insert(bytes.Repeat([]byte("x"), 100))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		locs = append(locs, loc)
+	}
+
+	if stats, err := sm.FreeSlotStats(); err != nil || stats.Slots != 0 {
+		t.Error("Unexpected result:", stats, err)
+		return
+	}
+
+	cdsm.Free(locs[1])
+	cdsm.Free(locs[3])
+
+	if err := cdsm.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	stats, err := sm.FreeSlotStats()
+	if err != nil || stats.Slots != 2 || stats.Bytes != 2*uint64(stats.LargestSlot) ||
+		stats.LargestSlot < 100 {
+		t.Error("Unexpected result:", stats, err)
+		return
+	}
+
+	if err := cdsm.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestDiskStorageManagerCompact(t *testing.T) {
+	dsm := NewDiskStorageManager(DBDIR+"/test_compact", false, false, false, true)
+	cdsm := NewCachedDiskStorageManager(dsm, 10)
+
+	var cm CompactionManager = cdsm
+
+	data := make(map[uint64]string)
+	var locs []uint64
+
+	for i := 0; i < 400; i++ {
+		val := strings.Repeat(fmt.Sprint(i%10), (i%7+1)*100)
+
+		loc, err := cdsm.Insert(val)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		data[loc] = val
+		locs = append(locs, loc)
+	}
+
+	// Free every other object and everything at the end
+
+	for i, loc := range locs {
+		if i%2 == 0 || i >= 320 {
+			if err := cdsm.Free(loc); err != nil {
+				t.Error(err)
+				return
+			}
+			delete(data, loc)
+		}
+	}
+
+	if err := cdsm.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	pagesBefore, _ := paging.CountPages(dsm.physicalSlotsPager, view.TypeDataPage)
+
+	if err := cm.Compact(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	pagesAfter, _ := paging.CountPages(dsm.physicalSlotsPager, view.TypeDataPage)
+
+	if pagesAfter >= pagesBefore*2/3 {
+		t.Error("Unexpected page count:", pagesBefore, pagesAfter)
+		return
+	}
+
+	if size, _ := paging.CountPages(dsm.physicalSlotsPager, view.TypeFreePage); size != 0 {
+		t.Error("Unexpected free pages:", size)
+		return
+	}
+
+	checkData := func(sm Manager) {
+		for loc, val := range data {
+			var res string
+
+			if err := sm.Fetch(loc, &res); err != nil || res != val {
+				t.Error("Unexpected fetch result:", loc, len(res), len(val), err)
+				return
+			}
+		}
+	}
+
+	checkData(cdsm)
+
+	// Inserts after compaction should still work
+
+	for i := 0; i < 10; i++ {
+		val := strings.Repeat("x", (i+1)*80)
+
+		loc, err := cdsm.Insert(val)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		data[loc] = val
+	}
+
+	checkData(cdsm)
+
+	if err := cdsm.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	dsm = NewDiskStorageManager(DBDIR+"/test_compact", true, false, false, true)
+
+	checkData(dsm)
+
+	if err := dsm.Compact(); err != ErrReadonly {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := dsm.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
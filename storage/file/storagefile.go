@@ -12,7 +12,9 @@ package file
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 
@@ -26,12 +28,13 @@ If two StorageFile objects throw an error at the same time both errors
 will appear to come from the same instance.
 */
 var (
-	ErrAlreadyInUse  = newStorageFileError("Record is already in-use")
-	ErrNotInUse      = newStorageFileError("Record was not in-use")
-	ErrInUse         = newStorageFileError("Records are still in-use")
-	ErrTransDisabled = newStorageFileError("Transactions are disabled")
-	ErrInTrans       = newStorageFileError("Records are still in a transaction")
-	ErrNilData       = newStorageFileError("Record has nil data")
+	ErrAlreadyInUse     = newStorageFileError("Record is already in-use")
+	ErrNotInUse         = newStorageFileError("Record was not in-use")
+	ErrInUse            = newStorageFileError("Records are still in-use")
+	ErrTransDisabled    = newStorageFileError("Transactions are disabled")
+	ErrInTrans          = newStorageFileError("Records are still in a transaction")
+	ErrNilData          = newStorageFileError("Record has nil data")
+	ErrChecksumMismatch = newStorageFileError("Record checksum mismatch")
 )
 
 /*
@@ -50,6 +53,7 @@ StorageFile data structure
 type StorageFile struct {
 	name          string // Name of the storage file
 	transDisabled bool   // Flag if transactions are disabled
+	checksums     bool   // Flag if records are stored with checksums
 	recordSize    uint32 // Size of a record
 	maxFileSize   uint64 // Max size of a storage file on disk
 
@@ -75,9 +79,30 @@ func NewDefaultStorageFile(name string, transDisabled bool) (*StorageFile, error
 NewStorageFile creates a new storage file and returns a reference to it.
 */
 func NewStorageFile(name string, recordSize uint32, transDisabled bool) (*StorageFile, error) {
-	maxFileSize := DefaultFileSize - DefaultFileSize%uint64(recordSize)
+	return newStorageFile(name, recordSize, transDisabled, false)
+}
+
+/*
+NewChecksumStorageFile creates a new storage file which stores a CRC32
+checksum with every record and returns a reference to it. The checksum
+is verified when a record is read from disk.
+*/
+func NewChecksumStorageFile(name string, recordSize uint32, transDisabled bool) (*StorageFile, error) {
+	return newStorageFile(name, recordSize, transDisabled, true)
+}
 
-	ret := &StorageFile{name, transDisabled, recordSize, maxFileSize,
+/*
+newStorageFile creates a new storage file and returns a reference to it.
+*/
+func newStorageFile(name string, recordSize uint32, transDisabled bool, checksums bool) (*StorageFile, error) {
+	diskRecordSize := uint64(recordSize)
+	if checksums {
+		diskRecordSize += SizeInt
+	}
+
+	maxFileSize := DefaultFileSize - DefaultFileSize%diskRecordSize
+
+	ret := &StorageFile{name, transDisabled, checksums, recordSize, maxFileSize,
 		make(map[uint64]*Record), make(map[uint64]*Record), make(map[uint64]*Record),
 		make(map[uint64]*Record), make([]*os.File, 0), nil}
 
@@ -112,6 +137,23 @@ func (s *StorageFile) RecordSize() uint32 {
 	return s.recordSize
 }
 
+/*
+Checksums returns if records are stored with checksums.
+*/
+func (s *StorageFile) Checksums() bool {
+	return s.checksums
+}
+
+/*
+diskRecordSize returns the size of a record on disk.
+*/
+func (s *StorageFile) diskRecordSize() uint64 {
+	if s.checksums {
+		return uint64(s.recordSize) + SizeInt
+	}
+	return uint64(s.recordSize)
+}
+
 /*
 Get returns a record from the file. Other components can write to this record.
 Any write operation should set the dirty flag on the record. Dirty records will
@@ -236,13 +278,24 @@ func (s *StorageFile) writeRecord(record *Record) error {
 
 	if data != nil {
 
-		offset := record.ID() * uint64(s.recordSize)
+		offset := record.ID() * s.diskRecordSize()
 
 		file, err := s.getFile(offset)
 		if err != nil {
 			return err
 		}
 
+		if s.checksums {
+
+			// Write the data and its checksum in one operation
+
+			buf := make([]byte, len(data)+SizeInt)
+			copy(buf, data)
+			binary.LittleEndian.PutUint32(buf[len(data):], crc32.ChecksumIEEE(data))
+
+			data = buf
+		}
+
 		file.WriteAt(data, int64(offset%s.maxFileSize))
 
 		return nil
@@ -260,7 +313,7 @@ func (s *StorageFile) readRecord(record *Record) error {
 		return ErrNilData.fireError(s, fmt.Sprintf("Record %v", record.ID()))
 	}
 
-	offset := record.ID() * uint64(s.recordSize)
+	offset := record.ID() * s.diskRecordSize()
 
 	file, err := s.getFile(offset)
 	if err != nil {
@@ -276,6 +329,18 @@ func (s *StorageFile) readRecord(record *Record) error {
 		// We just allocate a new array here which seems to be the
 		// quickest way to get an empty array.
 		record.ClearData()
+	} else if s.checksums {
+
+		checksum := make([]byte, SizeInt)
+
+		n, err = file.ReadAt(checksum, int64(offset%s.maxFileSize)+int64(s.recordSize))
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if !checkRecordChecksum(record.Data(), checksum[:n]) {
+			return ErrChecksumMismatch.fireError(s, fmt.Sprintf("Record %v", record.ID()))
+		}
 	}
 
 	if err == io.EOF {
@@ -285,6 +350,32 @@ func (s *StorageFile) readRecord(record *Record) error {
 	return err
 }
 
+/*
+checkRecordChecksum checks the data of a record against its stored checksum.
+Records which were never written (e.g. gaps in a file) consist only of zeros.
+*/
+func checkRecordChecksum(data []byte, checksum []byte) bool {
+
+	if len(checksum) == SizeInt &&
+		binary.LittleEndian.Uint32(checksum) == crc32.ChecksumIEEE(data) {
+		return true
+	}
+
+	for _, b := range checksum {
+		if b != 0 {
+			return false
+		}
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
 Discard a given record.
 */
@@ -431,6 +522,83 @@ func (s *StorageFile) Rollback() error {
 	return nil
 }
 
+/*
+Checkpoint writes all committed transactions from the transaction log to the
+physical files and clears the transaction log. Does nothing if transactions
+are disabled.
+*/
+func (s *StorageFile) Checkpoint() error {
+
+	if s.transDisabled {
+		return nil
+	}
+
+	return s.tm.syncLogFromMemory()
+}
+
+/*
+Truncate removes all records starting from a given record id from the physical
+files. The removed records must not be in use or dirty. If transactions are
+enabled then all pending transactions are written to disk first.
+*/
+func (s *StorageFile) Truncate(id uint64) error {
+
+	for _, recordMap := range []map[uint64]*Record{s.inUse, s.dirty} {
+		for rid := range recordMap {
+			if rid >= id {
+				return ErrInUse.fireError(s, fmt.Sprintf("Record %v", rid))
+			}
+		}
+	}
+
+	if !s.transDisabled {
+
+		// Make sure no pending transaction writes a removed record
+		// after the files were truncated
+
+		if err := s.tm.syncLogFromMemory(); err != nil {
+			return err
+		}
+
+		for rid := range s.inTrans {
+			if rid >= id {
+				return ErrInTrans.fireError(s, fmt.Sprintf("Record %v", rid))
+			}
+		}
+	}
+
+	// Forget all cached records which were removed
+
+	for rid := range s.free {
+		if rid >= id {
+			delete(s.free, rid)
+		}
+	}
+
+	// Truncate all physical files which contain removed records
+
+	offset := id * s.diskRecordSize()
+
+	for i, file := range s.files {
+		start := uint64(i) * s.maxFileSize
+
+		if file == nil || start+s.maxFileSize <= offset {
+			continue
+		}
+
+		size := int64(0)
+		if offset > start {
+			size = int64(offset - start)
+		}
+
+		if err := file.Truncate(size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 /*
 Sync syncs all physical files.
 */
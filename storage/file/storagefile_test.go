@@ -94,7 +94,7 @@ func TestStorageFileInitialisation(t *testing.T) {
 }
 
 func TestGetFile(t *testing.T) {
-	sf := &StorageFile{DBDir + "/test2", true, 10, 10, nil, nil, nil, nil,
+	sf := &StorageFile{DBDir + "/test2", true, false, 10, 10, nil, nil, nil, nil,
 		make([]*os.File, 0), nil}
 	defer sf.Close()
 
@@ -665,3 +665,83 @@ func testReleasePanic(t *testing.T, sf *StorageFile, r *Record) {
 	}()
 	sf.ReleaseInUse(r)
 }
+
+func TestChecksums(t *testing.T) {
+
+	sf, err := NewChecksumStorageFile(DBDir+"/test_checksums", 10, false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if !sf.Checksums() {
+		t.Error("Checksums should be enabled")
+		return
+	}
+
+	for i := uint64(1); i < 4; i++ {
+		record, err := sf.Get(i)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		record.WriteSingleByte(5, byte(i))
+		sf.ReleaseInUseID(i, true)
+	}
+
+	if err := sf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Records are stored with their checksum
+
+	if res, _ := fileutil.PathExists(DBDir + "/test_checksums.0"); !res {
+		t.Error("Expected db file test_checksums.0 does not exist")
+		return
+	}
+
+	fi, _ := os.Stat(DBDir + "/test_checksums.0")
+	if fi.Size() != 4*14 {
+		t.Error("Unexpected file size:", fi.Size())
+		return
+	}
+
+	// Corrupt record 2 on disk
+
+	f, _ := os.OpenFile(DBDir+"/test_checksums.0", os.O_RDWR, 0660)
+	f.WriteAt([]byte{0xFF}, 2*14+5)
+	f.Close()
+
+	sf, err = NewChecksumStorageFile(DBDir+"/test_checksums", 10, false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	// Records which were never written are valid
+
+	if _, err := sf.Get(0); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if record, err := sf.Get(3); err != nil || record.ReadSingleByte(5) != 3 {
+		t.Error("Unexpected result:", record, err)
+		return
+	}
+
+	if _, err := sf.Get(2); err != ErrChecksumMismatch ||
+		err.Error() != "Record checksum mismatch (storagefiletest/test_checksums - Record 2)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	sf.ReleaseInUseID(0, false)
+	sf.ReleaseInUseID(3, false)
+
+	if err := sf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
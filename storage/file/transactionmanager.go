@@ -14,6 +14,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 )
@@ -44,9 +45,28 @@ per transaction)
 const DefaultTransSize = 10
 
 /*
-TransactionLogHeader is the magic number to identify transaction log files
+DefaultMaxLogSize is the default size in bytes of the transaction log on disk
+after which all transactions are written to the storage file (checkpoint).
 */
-var TransactionLogHeader = []byte{0x66, 0x42}
+const DefaultMaxLogSize = 16 * 1024 * 1024
+
+/*
+logRecordHeaderSize is the size of a record header in the transaction log
+(id, dirty flag, transaction count and data length).
+*/
+const logRecordHeaderSize = SizeLong + 1 + SizeLong + SizeLong
+
+/*
+TransactionLogHeader is the magic number to identify transaction log files.
+Each transaction in the log is followed by a CRC32 checksum.
+*/
+var TransactionLogHeader = []byte{0x66, 0x43}
+
+/*
+TransactionLogHeaderNoChecksum is the magic number to identify transaction log
+files which were written without checksums.
+*/
+var TransactionLogHeaderNoChecksum = []byte{0x66, 0x42}
 
 /*
 LogFile is the abstract interface for an transaction log file.
@@ -57,6 +77,14 @@ type LogFile interface {
 	Sync() error
 }
 
+/*
+truncatableLogFile is a log file from which partially written data can be removed.
+*/
+type truncatableLogFile interface {
+	io.Seeker
+	Truncate(size int64) error
+}
+
 /*
 TransactionManager data structure
 */
@@ -66,7 +94,10 @@ type TransactionManager struct {
 	curTrans  int          // Current transaction pointer
 	transList [][]*Record  // List of storage files
 	maxTrans  int          // Maximal number of transaction before log is written
+	logSize   int64        // Current size of the log file
+	maxSize   int64        // Maximal size of the log file before log is written
 	owner     *StorageFile // Owner of this manager
+	failed    error        // Error which left the log file in an unknown state
 }
 
 /*
@@ -104,10 +135,10 @@ func NewTransactionManager(owner *StorageFile, doRecover bool) (*TransactionMana
 	name := fmt.Sprintf("%s.%s", owner.Name(), LogFileSuffix)
 
 	ret := &TransactionManager{name, nil, -1, make([][]*Record, DefaultTransInLog),
-		DefaultTransInLog, owner}
+		DefaultTransInLog, 0, DefaultMaxLogSize, owner, nil}
 
 	if doRecover {
-		err := ret.recover()
+		err := ret.Recover()
 		if err != nil && err != ErrBadMagic {
 			return nil, err
 		}
@@ -122,9 +153,11 @@ func NewTransactionManager(owner *StorageFile, doRecover bool) (*TransactionMana
 }
 
 /*
-recover tries to recover pending transactions from the physical transaction log.
+Recover writes all complete transactions of the physical transaction log to
+the storage file. A transaction which was not completely written (e.g. the
+process died while writing it) and all following data is discarded.
 */
-func (t *TransactionManager) recover() error {
+func (t *TransactionManager) Recover() error {
 	file, err := os.OpenFile(t.name, os.O_RDONLY, 0660)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -140,31 +173,30 @@ func (t *TransactionManager) recover() error {
 	i, _ := file.Read(magic)
 
 	if i != 2 || magic[0] != TransactionLogHeader[0] ||
-		magic[1] != TransactionLogHeader[1] {
+		(magic[1] != TransactionLogHeader[1] && magic[1] != TransactionLogHeaderNoChecksum[1]) {
 		return ErrBadMagic.fireError(t.owner, "")
 	}
 
+	checksums := magic[1] == TransactionLogHeader[1]
+
 	for true {
-		var numRecords int64
-		if err := binary.Read(file, binary.LittleEndian, &numRecords); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
+		recMap, err := t.readTransaction(file, checksums)
 
-		recMap := make(map[uint64]*Record)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 
-		for i := int64(0); i < numRecords; i++ {
-			record, err := ReadRecord(file)
-			if err != nil {
-				return err
-			}
+			// The end of the log was reached or the last transaction
+			// is incomplete
+
+			break
+
+		} else if err != nil {
+			return err
+
+		} else if recMap == nil {
 
-			// Any duplicated records will only be synced once
-			// using the latest version
+			// The checksum of the transaction does not match - discard the rest
 
-			recMap[record.ID()] = record
+			break
 		}
 
 		// If something goes wrong here ignore and try to do the rest
@@ -175,6 +207,70 @@ func (t *TransactionManager) recover() error {
 	return nil
 }
 
+/*
+readTransaction reads a single transaction from the physical transaction log.
+Returns nil if the transaction is corrupted.
+*/
+func (t *TransactionManager) readTransaction(r io.Reader, checksums bool) (map[uint64]*Record, error) {
+	crc := crc32.NewIEEE()
+
+	if checksums {
+		r = io.TeeReader(r, crc)
+	}
+
+	var numRecords int64
+	if err := binary.Read(r, binary.LittleEndian, &numRecords); err != nil {
+		return nil, err
+	}
+
+	recMap := make(map[uint64]*Record)
+
+	header := make([]byte, logRecordHeaderSize)
+
+	for i := int64(0); i < numRecords; i++ {
+
+		// Check the data length of the record before the data is read -
+		// a corrupted length must not cause a large allocation
+
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		length := binary.LittleEndian.Uint64(header[logRecordHeaderSize-SizeLong:])
+		if length != uint64(t.owner.recordSize) {
+			return nil, nil
+		}
+
+		record, err := ReadRecord(io.MultiReader(bytes.NewReader(header), r))
+		if err != nil {
+			return nil, err
+		}
+
+		// Any duplicated records will only be synced once
+		// using the latest version
+
+		recMap[record.ID()] = record
+	}
+
+	if checksums {
+		sum := crc.Sum32()
+
+		var logSum uint32
+		if err := binary.Read(r, binary.LittleEndian, &logSum); err != nil {
+			return nil, err
+		}
+
+		if sum != logSum {
+			return nil, nil
+		}
+	}
+
+	return recMap, nil
+}
+
 /*
 Open opens the transaction log for writing.
 */
@@ -191,6 +287,8 @@ func (t *TransactionManager) open() error {
 	t.logFile.Write(TransactionLogHeader)
 	t.logFile.Sync()
 	t.curTrans = -1
+	t.logSize = int64(len(TransactionLogHeader))
+	t.failed = nil
 
 	return nil
 }
@@ -200,7 +298,7 @@ Start starts a new transaction.
 */
 func (t *TransactionManager) start() {
 	t.curTrans++
-	if t.curTrans >= t.maxTrans {
+	if t.curTrans >= t.maxTrans || t.logSize >= t.maxSize {
 		t.syncLogFromMemory()
 		t.curTrans = 0
 	}
@@ -217,25 +315,50 @@ func (t *TransactionManager) add(record *Record) {
 
 /*
 Commit commits the memory transaction log to the physical transaction log.
+The transaction is written with a single write operation and is followed by
+a checksum so an incomplete transaction can be detected during recovery.
+A failed write is removed from the log so following transactions can still
+be recovered.
 */
 func (t *TransactionManager) commit() error {
+	var buf bytes.Buffer
 
-	// Write how many records will be stored
+	if t.failed != nil {
+		return t.failed
+	}
 
-	if err := binary.Write(t.logFile, binary.LittleEndian,
-		int64(len(t.transList[t.curTrans]))); err != nil {
+	// Write how many records will be stored
 
-		return err
-	}
+	binary.Write(&buf, binary.LittleEndian, int64(len(t.transList[t.curTrans])))
 
-	// Write records to log file
+	// Write records
 
 	for _, record := range t.transList[t.curTrans] {
-		if err := record.WriteRecord(t.logFile); err != nil {
+		if err := record.WriteRecord(&buf); err != nil {
 			return err
 		}
 	}
 
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+
+	if _, err := t.logFile.Write(buf.Bytes()); err != nil {
+
+		// Remove any partially written data - if this is not possible the
+		// log cannot take any further transactions
+
+		if tf, ok := t.logFile.(truncatableLogFile); !ok {
+			t.failed = err
+		} else if terr := tf.Truncate(t.logSize); terr != nil {
+			t.failed = terr
+		} else if _, serr := tf.Seek(t.logSize, io.SeekStart); serr != nil {
+			t.failed = serr
+		}
+
+		return err
+	}
+
+	t.logSize += int64(buf.Len())
+
 	t.syncFile()
 
 	// Clear all dirty flags
@@ -330,7 +453,7 @@ func (t *TransactionManager) syncLogFromDisk() error {
 		t.transList[i] = nil
 	}
 
-	if err := t.recover(); err != nil {
+	if err := t.Recover(); err != nil {
 		return err
 	}
 
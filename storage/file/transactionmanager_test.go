@@ -11,7 +11,10 @@
 package file
 
 import (
+	"bytes"
+	"encoding/binary"
 	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
@@ -146,10 +149,13 @@ func TestTransactionManagerInitialisation(t *testing.T) {
 	file.WriteString("*")
 	file.Close()
 
-	if _, err = NewTransactionManager(sf, true); err != io.ErrUnexpectedEOF {
-		t.Error("Corrupted transaction logs should get an unexpected EOF", err)
+	// Incomplete transactions are discarded
+
+	if tm, err = NewTransactionManager(sf, true); err != nil {
+		t.Error("Incomplete transactions should be discarded", err)
 		return
 	}
+	tm.close()
 
 	file, err = os.OpenFile(tmName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0660)
 	if err != nil {
@@ -160,10 +166,11 @@ func TestTransactionManagerInitialisation(t *testing.T) {
 	file.Write([]byte{0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
 	file.WriteString("HalloTEST")
 	file.Close()
-	if _, err = NewTransactionManager(sf, true); err != io.ErrUnexpectedEOF {
-		t.Error("Corrupted transaction logs should get an unexpected EOF", err)
+	if tm, err = NewTransactionManager(sf, true); err != nil {
+		t.Error("Incomplete transactions should be discarded", err)
 		return
 	}
+	tm.close()
 }
 
 func TestTMSimpleHighLevelGetRelease(t *testing.T) {
@@ -608,3 +615,365 @@ func TestRollbackFail(t *testing.T) {
 
 	sf.Close()
 }
+
+/*
+crashingLogFile is a log file which stops writing after a given number of bytes.
+*/
+type crashingLogFile struct {
+	*os.File
+	limit        int
+	failTruncate bool
+}
+
+func (f *crashingLogFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return io.ErrClosedPipe
+	}
+	return f.File.Truncate(size)
+}
+
+func (f *crashingLogFile) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		n, _ := f.File.Write(p[:f.limit])
+		f.limit = 0
+		return n, io.ErrShortWrite
+	}
+	f.limit -= len(p)
+	return f.File.Write(p)
+}
+
+func TestRecoverIncompleteTransaction(t *testing.T) {
+
+	writeByte := func(sf *StorageFile, id uint64, pos int) {
+		record, err := sf.Get(id)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		record.WriteSingleByte(pos, 0x42)
+		sf.ReleaseInUse(record)
+	}
+
+	checkBytes := func(sf *StorageFile, expected map[uint64]byte) {
+		for id, val := range expected {
+			record, err := sf.Get(id)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if record.ReadSingleByte(5) != val {
+				t.Error("Unexpected data in record:", record)
+			}
+			sf.ReleaseInUse(record)
+		}
+	}
+
+	// Simulate a process which dies while writing a transaction
+
+	sf, err := NewDefaultStorageFile(DBDir+"/trans_test7", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	writeByte(sf, 1, 5)
+
+	if err := sf.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	sf.tm.logFile = &crashingLogFile{sf.tm.logFile.(*os.File), 20, false}
+
+	writeByte(sf, 2, 5)
+	writeByte(sf, 3, 5)
+
+	if err := sf.Flush(); err != io.ErrShortWrite {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	for _, file := range sf.files {
+		file.Close()
+	}
+	sf.tm.logFile.Close()
+
+	// The first transaction should be recovered - the incomplete transaction
+	// should be discarded
+
+	sf, err = NewDefaultStorageFile(DBDir+"/trans_test7", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	checkBytes(sf, map[uint64]byte{1: 0x42, 2: 0, 3: 0})
+
+	// Write two transactions and corrupt the second one in the log
+
+	writeByte(sf, 2, 5)
+	sf.Flush()
+
+	logName := sf.tm.name
+	stat, _ := os.Stat(logName)
+	logSize := stat.Size()
+
+	writeByte(sf, 3, 5)
+	sf.Flush()
+
+	for _, file := range sf.files {
+		file.Close()
+	}
+	sf.tm.logFile.Close()
+
+	file, err := os.OpenFile(logName, os.O_RDWR, 0660)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	file.WriteAt([]byte{0x01}, logSize+SizeLong+logRecordHeaderSize+100)
+	file.Close()
+
+	sf, err = NewDefaultStorageFile(DBDir+"/trans_test7", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	checkBytes(sf, map[uint64]byte{1: 0x42, 2: 0x42, 3: 0})
+
+	if err := sf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Logs without checksums can still be recovered
+
+	var buf bytes.Buffer
+
+	buf.Write(TransactionLogHeaderNoChecksum)
+	binary.Write(&buf, binary.LittleEndian, int64(1))
+	record := NewRecord(3, make([]byte, DefaultRecordSize))
+	record.WriteSingleByte(5, 0x42)
+	record.WriteRecord(&buf)
+
+	if err := ioutil.WriteFile(logName, buf.Bytes(), 0660); err != nil {
+		t.Error(err)
+		return
+	}
+
+	sf, err = NewDefaultStorageFile(DBDir+"/trans_test7", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	checkBytes(sf, map[uint64]byte{1: 0x42, 2: 0x42, 3: 0x42})
+
+	if err := sf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestRecoverAfterFailedCommit(t *testing.T) {
+
+	writeByte := func(sf *StorageFile, id uint64, val byte) {
+		record, err := sf.Get(id)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		record.WriteSingleByte(5, val)
+		sf.ReleaseInUse(record)
+	}
+
+	sf, err := NewDefaultStorageFile(DBDir+"/trans_test9", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	writeByte(sf, 1, 0x42)
+
+	if err := sf.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	stat, _ := os.Stat(sf.tm.name)
+	logSize := stat.Size()
+
+	// Fail a commit half way through writing the transaction
+
+	logFile := sf.tm.logFile.(*os.File)
+	sf.tm.logFile = &crashingLogFile{logFile, 20, false}
+
+	writeByte(sf, 2, 0x42)
+
+	if err := sf.Flush(); err != io.ErrShortWrite {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// The partially written transaction should have been removed
+
+	if stat, _ := os.Stat(sf.tm.name); stat.Size() != logSize || sf.tm.logSize != logSize {
+		t.Error("Unexpected log size:", stat.Size(), sf.tm.logSize, logSize)
+		return
+	}
+
+	// The next commit succeeds
+
+	sf.tm.logFile = logFile
+
+	writeByte(sf, 3, 0x42)
+
+	if err := sf.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, file := range sf.files {
+		file.Close()
+	}
+	sf.tm.logFile.Close()
+
+	// The transaction which was committed after the failed one is recovered
+
+	sf, err = NewDefaultStorageFile(DBDir+"/trans_test9", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	for id, val := range map[uint64]byte{1: 0x42, 2: 0, 3: 0x42} {
+		record, err := sf.Get(id)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if record.ReadSingleByte(5) != val {
+			t.Error("Unexpected data in record:", record)
+		}
+		sf.ReleaseInUse(record)
+	}
+
+	// If the partial write cannot be removed no further commits are accepted
+
+	sf.tm.logFile = &crashingLogFile{sf.tm.logFile.(*os.File), 20, true}
+
+	writeByte(sf, 2, 0x43)
+
+	if err := sf.Flush(); err != io.ErrShortWrite {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	writeByte(sf, 3, 0x43)
+
+	if err := sf.Flush(); err != io.ErrClosedPipe {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// A checkpoint starts a new log
+
+	if err := sf.Checkpoint(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	writeByte(sf, 3, 0x44)
+
+	if err := sf.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := sf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+
+	sf, err := NewDefaultStorageFile(DBDir+"/trans_test8", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	logSize := func() int64 {
+		stat, _ := os.Stat(sf.tm.name)
+		return stat.Size()
+	}
+
+	record, _ := sf.Get(1)
+	record.WriteSingleByte(5, 0x42)
+	sf.ReleaseInUse(record)
+	sf.Flush()
+
+	if size := logSize(); size <= int64(len(TransactionLogHeader)) || len(sf.inTrans) != 1 {
+		t.Error("Unexpected log size:", size, sf.inTrans)
+		return
+	}
+
+	if err := sf.Checkpoint(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if size := logSize(); size != int64(len(TransactionLogHeader)) || len(sf.inTrans) != 0 {
+		t.Error("Unexpected log size:", size, sf.inTrans)
+		return
+	}
+
+	// The log is written once it exceeds its maximum size
+
+	sf.tm.maxSize = 100
+
+	for i := 0; i < 3; i++ {
+		record, _ = sf.Get(uint64(i + 2))
+		record.WriteSingleByte(5, 0x42)
+		sf.ReleaseInUse(record)
+		sf.Flush()
+
+		if size := logSize(); size > 100+int64(len(TransactionLogHeader)+DefaultRecordSize+50) {
+			t.Error("Unexpected log size:", size)
+			return
+		}
+	}
+
+	if sf.tm.curTrans != 0 {
+		t.Error("Unexpected transaction pointer:", sf.tm.curTrans)
+		return
+	}
+
+	if err := sf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	sf, err = NewDefaultStorageFile(DBDir+"/trans_test8", true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if err := sf.Checkpoint(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 1; i < 5; i++ {
+		record, _ = sf.Get(uint64(i))
+		if record.ReadSingleByte(5) != 0x42 {
+			t.Error("Unexpected data in record:", record)
+		}
+		sf.ReleaseInUse(record)
+	}
+
+	sf.Close()
+}
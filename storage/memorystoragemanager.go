@@ -73,6 +73,7 @@ type MemoryStorageManager struct {
 
 	LocCount  uint64         // Counter for locations - Must start > 0
 	AccessMap map[uint64]int // Special map to simulate access issues
+	stats     *CacheStats    // Cache counters
 }
 
 /*
@@ -85,7 +86,7 @@ func NewMemoryStorageManager(name string) *MemoryStorageManager {
 	// keep creating new HTrees if a Root is 0.
 
 	return &MemoryStorageManager{name, make(map[int]uint64),
-		make(map[uint64]interface{}), &sync.Mutex{}, 1, make(map[uint64]int)}
+		make(map[uint64]interface{}), &sync.Mutex{}, 1, make(map[uint64]int), &CacheStats{}}
 }
 
 /*
@@ -192,11 +193,30 @@ func (msm *MemoryStorageManager) FetchCached(loc uint64) (interface{}, error) {
 	defer msm.mutex.Unlock()
 
 	if msm.AccessMap[loc] == AccessNotInCache || msm.AccessMap[loc] == AccessCacheAndFetchError {
+		msm.stats.miss()
 		return nil, ErrNotInCache
 	} else if msm.AccessMap[loc] == AccessCacheAndFetchSeriousError {
 		return nil, file.ErrAlreadyInUse
 	}
-	return msm.Data[loc], nil
+
+	// All stored objects are held in memory - only unknown locations are misses
+
+	obj, ok := msm.Data[loc]
+	if ok {
+		msm.stats.hit()
+	} else {
+		msm.stats.miss()
+	}
+
+	return obj, nil
+}
+
+/*
+CacheStats returns the cache counters of the storage manager. A memory
+storage manager never evicts objects.
+*/
+func (msm *MemoryStorageManager) CacheStats() *CacheStats {
+	return msm.stats
 }
 
 /*
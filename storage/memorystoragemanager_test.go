@@ -128,3 +128,30 @@ func TestMemoryStorageManager(t *testing.T) {
 	msm.Rollback()
 	msm.Close()
 }
+
+func TestMemoryStorageManagerCacheStats(t *testing.T) {
+	msm := NewMemoryStorageManager("test")
+
+	stats := msm.CacheStats()
+
+	loc, _ := msm.Insert("test")
+
+	msm.FetchCached(loc)
+	msm.FetchCached(loc)
+	msm.FetchCached(loc + 1)
+
+	msm.AccessMap[loc] = AccessNotInCache
+	msm.FetchCached(loc)
+
+	if stats.Hits() != 2 || stats.Misses() != 2 || stats.Evictions() != 0 {
+		t.Error("Unexpected counters:", stats.Hits(), stats.Misses(), stats.Evictions())
+		return
+	}
+
+	stats.Reset()
+
+	if stats.Hits() != 0 || stats.Misses() != 0 {
+		t.Error("Unexpected counters:", stats.Hits(), stats.Misses())
+		return
+	}
+}
@@ -21,10 +21,18 @@ import (
 Common paged storage file related errors
 */
 var (
-	ErrFreePage = errors.New("Cannot allocate/free a free page")
-	ErrHeader   = errors.New("Cannot modify header record")
+	ErrFreePage  = errors.New("Cannot allocate/free a free page")
+	ErrHeader    = errors.New("Cannot modify header record")
+	ErrPageSize  = errors.New("Page size of file does not match record size")
+	ErrChecksums = errors.New("Checksum setting of file does not match")
 )
 
+/*
+ErrChecksumMismatch is returned if a page which is read from disk does not
+match its checksum. The error names the page number.
+*/
+var ErrChecksumMismatch = file.ErrChecksumMismatch
+
 /*
 PagedStorageFile data structure
 */
@@ -35,6 +43,9 @@ type PagedStorageFile struct {
 
 /*
 NewPagedStorageFile wraps a given StorageFile and returns a PagedStorageFile.
+The record size of the StorageFile is the page size. The page size and if
+pages are stored with checksums (see file.NewChecksumStorageFile) is stored
+when the file is created and must match when the file is opened again.
 */
 func NewPagedStorageFile(storagefile *file.StorageFile) (*PagedStorageFile, error) {
 	var header *PagedStorageFileHeader
@@ -48,11 +59,34 @@ func NewPagedStorageFile(storagefile *file.StorageFile) (*PagedStorageFile, erro
 
 	isnew := record.ReadInt16(0) == 0
 
-	header = NewPagedStorageFileHeader(record, isnew)
+	header = newHeader(storagefile, record, isnew)
+
+	if pageSize := header.PageSize(); pageSize != 0 && pageSize != storagefile.RecordSize() {
+		storagefile.ReleaseInUse(record)
+		return nil, ErrPageSize
+	}
+
+	if header.Checksums() != storagefile.Checksums() {
+		storagefile.ReleaseInUse(record)
+		return nil, ErrChecksums
+	}
 
 	return &PagedStorageFile{storagefile, header}, nil
 }
 
+/*
+newHeader creates the header object for the header record of a given StorageFile.
+*/
+func newHeader(storagefile *file.StorageFile, record *file.Record, isnew bool) *PagedStorageFileHeader {
+	header := NewPagedStorageFileHeader(record, isnew)
+
+	if isnew {
+		header.SetChecksums(storagefile.Checksums())
+	}
+
+	return header
+}
+
 /*
 StorageFile returns the wrapped StorageFile.
 */
@@ -67,6 +101,13 @@ func (psf *PagedStorageFile) Header() *PagedStorageFileHeader {
 	return psf.header
 }
 
+/*
+PageSize returns the size of pages in this PagedStorageFile.
+*/
+func (psf *PagedStorageFile) PageSize() uint32 {
+	return psf.storagefile.RecordSize()
+}
+
 /*
 AllocatePage allocates a new page of a specific type.
 */
@@ -245,6 +286,88 @@ func (psf *PagedStorageFile) FreePage(id uint64) error {
 	return nil
 }
 
+/*
+Compact removes all free pages from the end of the file and truncates the
+file. All pending changes are flushed. Returns the number of removed pages.
+*/
+func (psf *PagedStorageFile) Compact() (int, error) {
+
+	next := psf.header.LastListElement(view.TypeFreePage)
+
+	// Collect all free pages
+
+	var freePages []uint64
+	isFree := make(map[uint64]bool)
+
+	for page := psf.header.FirstListElement(view.TypeFreePage); page != 0; {
+		freePages = append(freePages, page)
+		isFree[page] = true
+
+		var err error
+		if page, err = psf.Next(page); err != nil {
+			return 0, err
+		}
+	}
+
+	// Find the first page of the free pages at the end of the file
+
+	newNext := next
+	for newNext > 1 && isFree[newNext-1] {
+		newNext--
+	}
+
+	if newNext == next {
+		return 0, nil
+	}
+
+	// Relink the free list without the removed pages
+
+	var last uint64
+
+	for _, page := range freePages {
+
+		if page >= newNext {
+			continue
+		}
+
+		if last == 0 {
+			psf.header.SetFirstListElement(view.TypeFreePage, page)
+
+		} else {
+			record, err := psf.storagefile.Get(last)
+			if err != nil {
+				return 0, err
+			}
+			view.GetPageView(record).SetNextPage(page)
+			psf.storagefile.ReleaseInUse(record)
+		}
+
+		last = page
+	}
+
+	if last == 0 {
+		psf.header.SetFirstListElement(view.TypeFreePage, 0)
+
+	} else {
+		record, err := psf.storagefile.Get(last)
+		if err != nil {
+			return 0, err
+		}
+		view.GetPageView(record).SetNextPage(0)
+		psf.storagefile.ReleaseInUse(record)
+	}
+
+	// The last list element of the free list points to the next new page
+
+	psf.header.SetLastListElement(view.TypeFreePage, newNext)
+
+	if err := psf.Flush(); err != nil {
+		return 0, err
+	}
+
+	return int(next - newNext), psf.storagefile.Truncate(newNext)
+}
+
 /*
 First returns the first page of a list of a given type.
 */
@@ -336,7 +459,7 @@ func (psf *PagedStorageFile) Rollback() error {
 	// it should succeed if the previous Rollback was successful.
 
 	record, _ := psf.storagefile.Get(0)
-	psf.header = NewPagedStorageFileHeader(record, record.ReadInt16(0) == 0)
+	psf.header = newHeader(psf.storagefile, record, record.ReadInt16(0) == 0)
 
 	return nil
 }
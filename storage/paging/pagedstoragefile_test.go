@@ -368,6 +368,168 @@ func TestPagedStorageFilePageManagement(t *testing.T) {
 
 }
 
+func TestPagedStorageFilePageSize(t *testing.T) {
+
+	sf, err := file.NewStorageFile(DBDIR+"/test_pagesize", 1024, true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err := NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if psf.PageSize() != 1024 || psf.Header().PageSize() != 1024 {
+		t.Error("Unexpected page size:", psf.PageSize(), psf.Header().PageSize())
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := psf.AllocatePage(view.TypeDataPage); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Opening the file with a different page size fails
+
+	for _, pageSize := range []uint32{512, 2048} {
+
+		sf, err = file.NewStorageFile(DBDIR+"/test_pagesize", pageSize, true)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+
+		if _, err = NewPagedStorageFile(sf); err != ErrPageSize {
+			t.Error("Unexpected result:", err)
+			return
+		}
+
+		if err := sf.Close(); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// Opening the file with the stored page size succeeds
+
+	sf, err = file.NewStorageFile(DBDIR+"/test_pagesize", 1024, true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err = NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := CountPages(psf, view.TypeDataPage); res != 3 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestPagedStorageFileCompact(t *testing.T) {
+
+	sf, err := file.NewStorageFile(DBDIR+"/test_compact", 1024, false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err := NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Nothing to do for a new file
+
+	if res, err := psf.Compact(); res != 0 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	for i := 0; i < 6; i++ {
+		if _, err := psf.AllocatePage(view.TypeDataPage); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// Free a hole in the middle and the pages at the end
+
+	for _, page := range []uint64{2, 6, 4, 5} {
+		if err := psf.FreePage(page); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if err := psf.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := psf.Compact(); res != 3 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := CountPages(psf, view.TypeFreePage); res != 1 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := CountPages(psf, view.TypeDataPage); res != 2 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if fi, err := os.Stat(DBDIR + "/test_compact.0"); err != nil || fi.Size() != 4*1024 {
+		t.Error("Unexpected file size:", fi.Size(), err)
+		return
+	}
+
+	// Free pages are reused before new pages are added at the end
+
+	if page, err := psf.AllocatePage(view.TypeDataPage); page != 2 || err != nil {
+		t.Error("Unexpected result:", page, err)
+		return
+	}
+
+	if page, err := psf.AllocatePage(view.TypeDataPage); page != 4 || err != nil {
+		t.Error("Unexpected result:", page, err)
+		return
+	}
+
+	if res, err := psf.Compact(); res != 0 || err != nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
 func TestPagedStorageFileTransactionPageManagement(t *testing.T) {
 	sf, err := file.NewDefaultStorageFile(DBDIR+"/test3", false)
 	if err != nil {
@@ -13,10 +13,23 @@ package paging
 import "devt.de/krotik/eliasdb/storage/file"
 
 /*
-PageHeader is the magic number to identify page headers
+PageHeader is the magic number to identify page headers which do not store
+the page size
 */
 const PageHeader = 0x1980
 
+/*
+PageHeaderSized is the magic number to identify page headers which store the
+page size
+*/
+const PageHeaderSized = 0x1981
+
+/*
+PageHeaderChecksums is the magic number to identify page headers which store
+the page size and whose pages are stored with checksums
+*/
+const PageHeaderChecksums = 0x1982
+
 /*
 TotalLists is the number of lists which can be stored in this header
 */
@@ -28,33 +41,51 @@ OffsetLists is the offset for list entries in this header
 const OffsetLists = 2
 
 /*
-OffsetRoots is the number of lists which can be stored in this header
+OffsetRoots is the offset for root values in headers which do not store
+the page size
 */
 const OffsetRoots = OffsetLists + (2 * TotalLists * file.SizeLong)
 
+/*
+OffsetPageSize is the offset for the page size in headers which store the
+page size. Root values follow the page size in these headers.
+*/
+const OffsetPageSize = OffsetRoots
+
 /*
 PagedStorageFileHeader data structure
 */
 type PagedStorageFileHeader struct {
-	record     *file.Record // Record which is being used for the header information
-	totalRoots int          // Number of root values which can be stored
+	record      *file.Record // Record which is being used for the header information
+	offsetRoots int          // Offset for root values
+	totalRoots  int          // Number of root values which can be stored
 }
 
 /*
-NewPagedStorageFileHeader creates a new NewPagedStorageFileHeader.
+NewPagedStorageFileHeader creates a new NewPagedStorageFileHeader. New headers
+store the size of the given record as page size.
 */
 func NewPagedStorageFileHeader(record *file.Record, isnew bool) *PagedStorageFileHeader {
-	totalRoots := (len(record.Data()) - OffsetRoots) / file.SizeLong
+
+	if !isnew {
+		checkMagic(record)
+	}
+
+	offsetRoots := OffsetRoots
+	if magic := record.ReadUInt16(0); isnew || magic == PageHeaderSized || magic == PageHeaderChecksums {
+		offsetRoots = OffsetPageSize + file.SizeInt
+	}
+
+	totalRoots := (len(record.Data()) - offsetRoots) / file.SizeLong
 	if totalRoots < 1 {
 		panic("Cannot store any roots - record is too small")
 	}
 
-	ret := &PagedStorageFileHeader{record, totalRoots}
+	ret := &PagedStorageFileHeader{record, offsetRoots, totalRoots}
 
 	if isnew {
-		record.WriteUInt16(0, PageHeader)
-	} else {
-		ret.CheckMagic()
+		record.WriteUInt16(0, PageHeaderSized)
+		record.WriteUInt32(OffsetPageSize, uint32(len(record.Data())))
 	}
 
 	return ret
@@ -64,11 +95,48 @@ func NewPagedStorageFileHeader(record *file.Record, isnew bool) *PagedStorageFil
 CheckMagic checks the header magic value of this header.
 */
 func (psfh *PagedStorageFileHeader) CheckMagic() {
-	if psfh.record.ReadUInt16(0) != PageHeader {
+	checkMagic(psfh.record)
+}
+
+/*
+checkMagic checks the header magic value of a given header record.
+*/
+func checkMagic(record *file.Record) {
+	if magic := record.ReadUInt16(0); magic != PageHeader && magic != PageHeaderSized &&
+		magic != PageHeaderChecksums {
 		panic("Unexpected header found in PagedStorageFileHeader")
 	}
 }
 
+/*
+PageSize returns the page size which is stored in this header. Returns 0 if
+the header was written before the page size was stored.
+*/
+func (psfh *PagedStorageFileHeader) PageSize() uint32 {
+	if psfh.record.ReadUInt16(0) == PageHeader {
+		return 0
+	}
+	return psfh.record.ReadUInt32(OffsetPageSize)
+}
+
+/*
+Checksums returns if the pages of the file are stored with checksums.
+*/
+func (psfh *PagedStorageFileHeader) Checksums() bool {
+	return psfh.record.ReadUInt16(0) == PageHeaderChecksums
+}
+
+/*
+SetChecksums sets if the pages of the file are stored with checksums.
+*/
+func (psfh *PagedStorageFileHeader) SetChecksums(checksums bool) {
+	if checksums {
+		psfh.record.WriteUInt16(0, PageHeaderChecksums)
+	} else {
+		psfh.record.WriteUInt16(0, PageHeaderSized)
+	}
+}
+
 /*
 Roots returns the number of possible root values which can be set.
 */
@@ -80,21 +148,21 @@ func (psfh *PagedStorageFileHeader) Roots() int {
 Root returns a root value.
 */
 func (psfh *PagedStorageFileHeader) Root(root int) uint64 {
-	return psfh.record.ReadUInt64(offsetRoot(root))
+	return psfh.record.ReadUInt64(psfh.offsetRoot(root))
 }
 
 /*
 SetRoot sets a root value.
 */
 func (psfh *PagedStorageFileHeader) SetRoot(root int, val uint64) {
-	psfh.record.WriteUInt64(offsetRoot(root), val)
+	psfh.record.WriteUInt64(psfh.offsetRoot(root), val)
 }
 
 /*
 offsetRoot calculates the offset of a root in the header record.
 */
-func offsetRoot(root int) int {
-	return OffsetRoots + root*file.SizeLong
+func (psfh *PagedStorageFileHeader) offsetRoot(root int) int {
+	return psfh.offsetRoots + root*file.SizeLong
 }
 
 /*
@@ -21,7 +21,7 @@ func TestPagedStorageFileHeader(t *testing.T) {
 	record := file.NewRecord(5, make([]byte, 5, 5))
 	testPagedStorageFileInitPanic1(t, record)
 
-	record = file.NewRecord(5, make([]byte, 100, 100))
+	record = file.NewRecord(5, make([]byte, 104, 104))
 	testPagedStorageFileInitPanic2(t, record)
 
 	NewPagedStorageFileHeader(record, true)
@@ -31,6 +31,10 @@ func TestPagedStorageFileHeader(t *testing.T) {
 		t.Error("Unexpected number of roots:", psfh.Roots())
 	}
 
+	if psfh.PageSize() != 104 {
+		t.Error("Unexpected page size:", psfh.PageSize())
+	}
+
 	psfh.SetRoot(1, 0x42)
 	if psfh.Root(1) != 0x42 {
 		t.Error("Unexpected root value:", psfh.Root(1))
@@ -50,6 +54,30 @@ func TestPagedStorageFileHeader(t *testing.T) {
 	}
 }
 
+func TestPagedStorageFileHeaderWithoutPageSize(t *testing.T) {
+
+	// Headers which were written before the page size was stored
+	// have their roots directly after the lists
+
+	record := file.NewRecord(5, make([]byte, 100, 100))
+	record.WriteUInt16(0, PageHeader)
+	record.WriteUInt64(OffsetRoots+file.SizeLong, 0x42)
+
+	psfh := NewPagedStorageFileHeader(record, false)
+
+	if psfh.Roots() != 2 {
+		t.Error("Unexpected number of roots:", psfh.Roots())
+	}
+
+	if psfh.PageSize() != 0 {
+		t.Error("Unexpected page size:", psfh.PageSize())
+	}
+
+	if psfh.Root(1) != 0x42 {
+		t.Error("Unexpected root value:", psfh.Root(1))
+	}
+}
+
 func testPagedStorageFileInitPanic1(t *testing.T, r *file.Record) {
 	defer func() {
 		if r := recover(); r == nil {
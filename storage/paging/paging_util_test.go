@@ -11,6 +11,7 @@
 package paging
 
 import (
+	"os"
 	"testing"
 
 	"devt.de/krotik/eliasdb/storage/file"
@@ -79,3 +80,112 @@ func TestFreePhysicalSlotManagerScale(t *testing.T) {
 		return
 	}
 }
+
+func TestPageChecksums(t *testing.T) {
+	sf, err := file.NewChecksumStorageFile(DBDIR+"/test_checksums", 1024, true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err := NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !psf.Header().Checksums() {
+		t.Error("Checksums should be enabled")
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := psf.AllocatePage(view.TypeDataPage); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// The checksum setting must match when opening the file again
+
+	sf, err = file.NewStorageFile(DBDIR+"/test_checksums", 1024, true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	if _, err = NewPagedStorageFile(sf); err != ErrChecksums {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	sf.Close()
+
+	// Reading unmodified pages succeeds
+
+	sf, err = file.NewChecksumStorageFile(DBDIR+"/test_checksums", 1024, true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err = NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if pc, err := CountPages(psf, view.TypeDataPage); pc != 3 || err != nil {
+		t.Error("Unexpected page count result:", pc, err)
+		return
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Flip a byte in the data area of page 2
+
+	f, err := os.OpenFile(DBDIR+"/test_checksums.0", os.O_RDWR, 0660)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	pos := int64(2*(1024+file.SizeInt) + 100)
+
+	b := make([]byte, 1)
+	f.ReadAt(b, pos)
+	b[0] ^= 0xFF
+	f.WriteAt(b, pos)
+	f.Close()
+
+	sf, err = file.NewChecksumStorageFile(DBDIR+"/test_checksums", 1024, true)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err = NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if pc, err := CountPages(psf, view.TypeDataPage); pc != -1 || err != ErrChecksumMismatch ||
+		err.Error() != "Record checksum mismatch (pagingtest/test_checksums - Record 2)" {
+		t.Error("Unexpected page count result:", pc, err)
+		return
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
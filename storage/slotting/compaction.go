@@ -0,0 +1,370 @@
+/*
+ * EliasDB
+ *
+ * Copyright 2016 Matthias Ladkau. All rights reserved.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package slotting
+
+import (
+	"bytes"
+	"sort"
+
+	"devt.de/krotik/eliasdb/storage/paging"
+	"devt.de/krotik/eliasdb/storage/paging/view"
+	"devt.de/krotik/eliasdb/storage/slotting/pageview"
+	"devt.de/krotik/eliasdb/storage/util"
+)
+
+/*
+compactionSlot is a physical slot which is examined during compaction.
+*/
+type compactionSlot struct {
+	logical  uint64 // Logical slot which points to the physical slot (0 for free slots)
+	location uint64 // Location of the physical slot
+	size     uint32 // Current size of the physical slot
+	avail    uint32 // Available size of the physical slot
+}
+
+/*
+Compact relocates live physical slots into free slots closer to the start of
+the physical slots file and removes data pages at the end of the file which
+no longer contain any live slots. Logical slots are updated to point to the
+relocated physical slots - references to logical slots stay valid.
+
+The compaction must only run while no other operations are performed. Changes
+are flushed in several steps in an order which ensures that an interruption
+leaves all files readable. An interruption may leave some space unused which
+is not reported as free.
+*/
+func Compact(lsm *LogicalSlotManager, psm *PhysicalSlotManager) error {
+
+	// Write all pending changes
+
+	if err := flushPagers(psm.Flush, lsm.Flush, psm.freeManager.pager.Flush,
+		lsm.freeManager.pager.Flush, psm.pager.Flush, lsm.pager.Flush); err != nil {
+		return err
+	}
+
+	// Index the data pages - slots which span multiple pages continue
+	// on the following page of the data page list
+
+	var dataPages []uint64
+	pagePos := make(map[uint64]int)
+
+	cursor := paging.NewPageCursor(psm.pager, view.TypeDataPage, 0)
+
+	for page, err := cursor.Next(); page != 0; page, err = cursor.Next() {
+		if err != nil {
+			return err
+		}
+		pagePos[page] = len(dataPages)
+		dataPages = append(dataPages, page)
+	}
+
+	live, err := compactionLiveSlots(lsm, psm)
+	if err != nil {
+		return err
+	}
+
+	// Take all free slots from the free slot manager - the free slot pages
+	// are written before any data is moved so a free slot which receives
+	// data can never be handed out again
+
+	holes, err := psm.freeManager.takeAll()
+	if err != nil {
+		return err
+	}
+
+	if err := flushPagers(psm.freeManager.pager.Flush); err != nil {
+		return err
+	}
+
+	sort.Slice(holes, func(i, j int) bool {
+		return holes[i].location < holes[j].location
+	})
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].location > live[j].location
+	})
+
+	// Copy live slots starting from the end of the file into the first free
+	// slots which can hold them
+
+	var moved []*compactionSlot
+	var targets []*compactionSlot
+	var buf bytes.Buffer
+
+	for _, slot := range live {
+
+		for i, hole := range holes {
+
+			if hole.location > slot.location {
+				break
+			}
+
+			if hole.avail < slot.size || hole.avail-slot.size > util.MaxAvailableSizeDifference {
+				continue
+			}
+
+			buf.Reset()
+
+			if err := psm.Fetch(slot.location, &buf); err != nil {
+				return err
+			}
+
+			if err := psm.write(hole.location, buf.Bytes(), 0, slot.size); err != nil {
+				return err
+			}
+
+			holes = append(holes[:i], holes[i+1:]...)
+			moved = append(moved, slot)
+			targets = append(targets, hole)
+
+			break
+		}
+	}
+
+	if err := flushPagers(psm.pager.Flush); err != nil {
+		return err
+	}
+
+	// Point the logical slots to the copies
+
+	for i, slot := range moved {
+		if err := lsm.Update(slot.logical, targets[i].location); err != nil {
+			return err
+		}
+	}
+
+	if err := flushPagers(lsm.pager.Flush); err != nil {
+		return err
+	}
+
+	// Free the old slots - live slots are now at their new locations
+
+	for i, slot := range moved {
+		record, err := psm.storagefile.Get(util.LocationRecord(slot.location))
+		if err != nil {
+			return err
+		}
+
+		util.SetCurrentSize(record, int(util.LocationOffset(slot.location)), 0)
+
+		psm.storagefile.ReleaseInUseID(record.ID(), true)
+
+		holes = append(holes, &compactionSlot{0, slot.location, 0, slot.avail})
+
+		slot.location = targets[i].location
+	}
+
+	// Find the end of the last live slot
+
+	endPos, endOffset := -1, uint32(0)
+
+	for _, slot := range live {
+		pos, offset := psm.slotEnd(pagePos[util.LocationRecord(slot.location)],
+			uint32(util.LocationOffset(slot.location)), slot.avail)
+
+		if pos > endPos || pos == endPos && offset > endOffset {
+			endPos, endOffset = pos, offset
+		}
+	}
+
+	// Mark the end of the slot chain after the last live slot
+
+	if endPos != -1 && endOffset <= psm.recordSize-util.SizeInfoSize {
+
+		record, err := psm.storagefile.Get(dataPages[endPos])
+		if err != nil {
+			return err
+		}
+
+		util.SetAvailableSize(record, int(endOffset), 0)
+		util.SetCurrentSize(record, int(endOffset), 0)
+
+		psm.storagefile.ReleaseInUseID(record.ID(), true)
+	}
+
+	// Remove all data pages after the last live slot starting from the end
+
+	for pos := len(dataPages) - 1; pos > endPos; pos-- {
+		if err := psm.pager.FreePage(dataPages[pos]); err != nil {
+			return err
+		}
+	}
+
+	if err := flushPagers(psm.pager.Flush); err != nil {
+		return err
+	}
+
+	// Give all remaining free slots before the end of the last live slot
+	// back to the free slot manager
+
+	for _, hole := range holes {
+		pos := pagePos[util.LocationRecord(hole.location)]
+
+		if pos < endPos || pos == endPos && uint32(util.LocationOffset(hole.location)) < endOffset {
+			psm.freeManager.Add(hole.location, hole.avail)
+		}
+	}
+
+	if err := flushPagers(psm.Flush, psm.freeManager.pager.Flush); err != nil {
+		return err
+	}
+
+	// Truncate free pages at the end of the files
+
+	for _, pager := range []*paging.PagedStorageFile{psm.pager, psm.freeManager.pager} {
+		if _, err := pager.Compact(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+compactionLiveSlots returns all physical slots which are referenced by a logical slot.
+*/
+func compactionLiveSlots(lsm *LogicalSlotManager, psm *PhysicalSlotManager) ([]*compactionSlot, error) {
+	var live []*compactionSlot
+
+	cursor := paging.NewPageCursor(lsm.pager, view.TypeTranslationPage, 0)
+
+	for page, err := cursor.Next(); page != 0; page, err = cursor.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := lsm.storagefile.Get(page)
+		if err != nil {
+			return nil, err
+		}
+
+		tp := pageview.NewTransPage(record)
+		offset := uint16(pageview.OffsetTransData)
+
+		for i := uint16(0); i < lsm.elementsPerPage; i++ {
+
+			if loc := util.PackLocation(tp.SlotInfoRecord(offset), tp.SlotInfoOffset(offset)); loc != 0 {
+				live = append(live, &compactionSlot{util.PackLocation(page, offset), loc, 0, 0})
+			}
+
+			offset += util.LocationSize
+		}
+
+		lsm.storagefile.ReleaseInUseID(page, false)
+	}
+
+	// Read the sizes of all live slots
+
+	for _, slot := range live {
+
+		record, err := psm.storagefile.Get(util.LocationRecord(slot.location))
+		if err != nil {
+			return nil, err
+		}
+
+		offset := int(util.LocationOffset(slot.location))
+
+		slot.size = util.CurrentSize(record, offset)
+		slot.avail = util.AvailableSize(record, offset)
+
+		psm.storagefile.ReleaseInUseID(record.ID(), false)
+	}
+
+	return live, nil
+}
+
+/*
+slotEnd calculates the end of a slot given by the position of its page in the
+data page list, its offset and its available size. Returns the position of the
+page and the offset on the page after the end of the slot.
+*/
+func (psm *PhysicalSlotManager) slotEnd(pos int, offset uint32, avail uint32) (int, uint32) {
+
+	firstPageSpace := psm.recordSize - offset - util.SizeInfoSize
+
+	if avail <= firstPageSpace {
+		return pos, offset + util.SizeInfoSize + avail
+	}
+
+	rest := avail - firstPageSpace
+	pages := int(rest / psm.availableRecordSize)
+
+	if rest%psm.availableRecordSize == 0 {
+		return pos + pages, psm.recordSize
+	}
+
+	return pos + pages + 1, pageview.OffsetData + rest%psm.availableRecordSize
+}
+
+/*
+takeAll removes all free slots from this manager and frees all free physical
+slot pages.
+*/
+func (fpsm *FreePhysicalSlotManager) takeAll() ([]*compactionSlot, error) {
+	var ret []*compactionSlot
+	var pages []uint64
+
+	for i, loc := range fpsm.slots {
+		ret = append(ret, &compactionSlot{0, loc, 0, fpsm.sizes[i]})
+	}
+
+	cursor := paging.NewPageCursor(fpsm.pager, view.TypeFreePhysicalSlotPage, 0)
+
+	for page, err := cursor.Next(); page != 0; page, err = cursor.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := fpsm.storagefile.Get(page)
+		if err != nil {
+			return nil, err
+		}
+
+		fpsp := pageview.NewFreePhysicalSlotPage(record)
+
+		for i := uint16(0); i < fpsp.MaxSlots(); i++ {
+			offset := pageview.OffsetData + i*pageview.SlotInfoSize
+
+			if size := fpsp.FreeSlotSize(offset); size != 0 {
+				ret = append(ret, &compactionSlot{0, util.PackLocation(fpsp.SlotInfoRecord(offset),
+					fpsp.SlotInfoOffset(offset)), 0, size})
+			}
+		}
+
+		fpsm.storagefile.ReleaseInUseID(page, false)
+
+		pages = append(pages, page)
+	}
+
+	for _, page := range pages {
+		if err := fpsm.pager.FreePage(page); err != nil {
+			return nil, err
+		}
+	}
+
+	fpsm.slots = make([]uint64, 0)
+	fpsm.sizes = make([]uint32, 0)
+	fpsm.lastMaxSlotSize = 0
+
+	return ret, nil
+}
+
+/*
+flushPagers calls a list of flush functions and stops at the first error.
+*/
+func flushPagers(flushFuncs ...func() error) error {
+	for _, f := range flushFuncs {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
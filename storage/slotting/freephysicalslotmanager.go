@@ -121,6 +121,65 @@ func (fpsm *FreePhysicalSlotManager) Get(size uint32) (uint64, error) {
 	return 0, nil
 }
 
+/*
+FreePhysicalSlotStats contains statistics on free physical slots.
+*/
+type FreePhysicalSlotStats struct {
+	Slots       int    // Total number of free slots
+	Bytes       uint64 // Total number of free bytes
+	LargestSlot uint32 // Size of the largest free slot
+}
+
+/*
+Stats returns statistics on all free slots including slots which have not
+yet been flushed. All visited pages are released before the next page is
+visited.
+*/
+func (fpsm *FreePhysicalSlotManager) Stats() (*FreePhysicalSlotStats, error) {
+
+	stats := &FreePhysicalSlotStats{}
+
+	add := func(size uint32) {
+		stats.Slots++
+		stats.Bytes += uint64(size)
+		if size > stats.LargestSlot {
+			stats.LargestSlot = size
+		}
+	}
+
+	for _, size := range fpsm.sizes {
+		add(size)
+	}
+
+	cursor := paging.NewPageCursor(fpsm.pager, view.TypeFreePhysicalSlotPage, 0)
+
+	// No need for error checking on cursor next since all pages will be opened
+	// via Get calls in the loop.
+
+	page, _ := cursor.Next()
+	for page != 0 {
+
+		record, err := fpsm.storagefile.Get(page)
+		if err != nil {
+			return nil, err
+		}
+
+		fpsp := pageview.NewFreePhysicalSlotPage(record)
+
+		for i := uint16(0); i < fpsp.MaxSlots(); i++ {
+			if size := fpsp.FreeSlotSize(pageview.OffsetData + i*pageview.SlotInfoSize); size != 0 {
+				add(size)
+			}
+		}
+
+		fpsm.storagefile.ReleaseInUseID(page, false)
+
+		page, _ = cursor.Next()
+	}
+
+	return stats, nil
+}
+
 /*
 Add adds a slotinfo to the free slot set.
 */
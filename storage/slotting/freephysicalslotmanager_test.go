@@ -386,3 +386,73 @@ func TestFreePhysicalSlotManagerScale(t *testing.T) {
 		return
 	}
 }
+
+func TestFreePhysicalSlotManagerStats(t *testing.T) {
+	sf, err := file.NewDefaultStorageFile(DBDIR+"/test_stats", false)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+
+	psf, err := paging.NewPagedStorageFile(sf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	fpsm := NewFreePhysicalSlotManager(psf, false)
+
+	if stats, err := fpsm.Stats(); err != nil || *stats != (FreePhysicalSlotStats{}) {
+		t.Error("Unexpected result:", stats, err)
+		return
+	}
+
+	// Flushed and pending slots are counted
+
+	for i := 0; i < 1000; i++ {
+		fpsm.Add(util.PackLocation(uint64(i+1), 0), uint32(10+i%20))
+	}
+
+	if err := fpsm.Flush(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	fpsm.Add(util.PackLocation(2000, 0), 500)
+
+	stats, err := fpsm.Stats()
+	if err != nil || *stats != (FreePhysicalSlotStats{1001, 19500 + 500, 500}) {
+		t.Error("Unexpected result:", stats, err)
+		return
+	}
+
+	// Gathering statistics releases all pages
+
+	if pc, err := paging.CountPages(psf, view.TypeFreePhysicalSlotPage); pc != 3 || err != nil {
+		t.Error("Unexpected page count result:", pc, err)
+		return
+	}
+
+	r, err := sf.Get(2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := fpsm.Stats(); err != file.ErrAlreadyInUse {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	sf.ReleaseInUse(r)
+
+	if pc, err := paging.CountPages(psf, view.TypeFreePhysicalSlotPage); pc != 3 || err != nil {
+		t.Error("Unexpected page count result:", pc, err)
+		return
+	}
+
+	if err := psf.Close(); err != nil {
+		t.Error(err)
+		return
+	}
+}
@@ -223,6 +223,13 @@ func (psm *PhysicalSlotManager) Flush() error {
 	return psm.freeManager.Flush()
 }
 
+/*
+FreeSlotStats returns statistics on free physical slots.
+*/
+func (psm *PhysicalSlotManager) FreeSlotStats() (*FreePhysicalSlotStats, error) {
+	return psm.freeManager.Stats()
+}
+
 /*
 write writes data to a location. Should an error occurs, then the already written data
 is not cleaned up.
@@ -10,6 +10,12 @@
 
 package storage
 
+import (
+	"sync/atomic"
+
+	"devt.de/krotik/eliasdb/storage/slotting"
+)
+
 /*
 RootIDVersion is the root id holding the version.
 */
@@ -77,3 +83,100 @@ type Manager interface {
 	*/
 	Close() error
 }
+
+/*
+FreeSlotStatsManager describes a storage manager which can report statistics
+on its free space.
+*/
+type FreeSlotStatsManager interface {
+
+	/*
+		FreeSlotStats returns statistics on free physical slots.
+	*/
+	FreeSlotStats() (*slotting.FreePhysicalSlotStats, error)
+}
+
+/*
+CompactionManager describes a storage manager which can compact its files.
+*/
+type CompactionManager interface {
+
+	/*
+		Compact relocates stored data to fill free space and truncates unused pages.
+	*/
+	Compact() error
+}
+
+/*
+CacheStatsManager describes a storage manager which can report statistics
+on its object cache.
+*/
+type CacheStatsManager interface {
+
+	/*
+		CacheStats returns the cache counters of the storage manager.
+	*/
+	CacheStats() *CacheStats
+}
+
+/*
+CacheStats data structure which counts cache hits, misses and evictions.
+All counters are updated atomically.
+*/
+type CacheStats struct {
+	hits      uint64 // Number of requests which were answered from the cache
+	misses    uint64 // Number of requests which could not be answered from the cache
+	evictions uint64 // Number of objects which were removed to make room
+}
+
+/*
+Hits returns the number of requests which were answered from the cache.
+*/
+func (cs *CacheStats) Hits() uint64 {
+	return atomic.LoadUint64(&cs.hits)
+}
+
+/*
+Misses returns the number of requests which could not be answered from the cache.
+*/
+func (cs *CacheStats) Misses() uint64 {
+	return atomic.LoadUint64(&cs.misses)
+}
+
+/*
+Evictions returns the number of objects which were removed from the cache to
+make room for other objects.
+*/
+func (cs *CacheStats) Evictions() uint64 {
+	return atomic.LoadUint64(&cs.evictions)
+}
+
+/*
+Reset sets all counters to 0.
+*/
+func (cs *CacheStats) Reset() {
+	atomic.StoreUint64(&cs.hits, 0)
+	atomic.StoreUint64(&cs.misses, 0)
+	atomic.StoreUint64(&cs.evictions, 0)
+}
+
+/*
+hit counts a cache hit.
+*/
+func (cs *CacheStats) hit() {
+	atomic.AddUint64(&cs.hits, 1)
+}
+
+/*
+miss counts a cache miss.
+*/
+func (cs *CacheStats) miss() {
+	atomic.AddUint64(&cs.misses, 1)
+}
+
+/*
+evict counts an eviction.
+*/
+func (cs *CacheStats) evict() {
+	atomic.AddUint64(&cs.evictions, 1)
+}